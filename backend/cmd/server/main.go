@@ -96,6 +96,7 @@ func main() {
 	db := client.Database(cfg.DBName)
 	if err := config.EnsureIndexes(ctx, db); err != nil { logger.Fatal("ensure indexes failed", zap.Error(err)) }
 	if err := seedDefaults(ctx, db, logger); err != nil { logger.Fatal("seed defaults failed", zap.Error(err)) }
+	if err := config.RunMigrations(ctx, db); err != nil { logger.Fatal("migrations failed", zap.Error(err)) }
 
 	app := fiber.New(fiber.Config{ ErrorHandler: utils.FiberErrorHandler(logger), BodyLimit: 200 * 1024 * 1024 })
 	app.Use(requestid.New())
@@ -177,11 +178,35 @@ func main() {
 	transferRepo := repositories.NewTransferRepository(db)
 	priceTagRepo := repositories.NewPriceTagRepository(db)
 	exchangeRateRepo := repositories.NewExchangeRateRepository(db)
+	refreshTokenRepo := repositories.NewRefreshTokenRepository(db)
+	stockRepo := repositories.NewStockRepository(db)
+	stockMovementRepo := repositories.NewStockMovementRepository(db)
+	costLayerRepo := repositories.NewCostLayerRepository(db)
+	stockLotRepo := repositories.NewStockLotRepository(db)
+	serialNumberRepo := repositories.NewSerialNumberRepository(db)
+	measureUnitRepo := repositories.NewMeasureUnitRepository(db)
+	reservationRepo := repositories.NewReservationRepository(db)
+	kitAssemblyRepo := repositories.NewKitAssemblyRepository(db)
+	consignmentBatchRepo := repositories.NewConsignmentBatchRepository(db)
+	consignmentMovementRepo := repositories.NewConsignmentMovementRepository(db)
+	consignmentSettlementRepo := repositories.NewConsignmentSettlementRepository(db)
+	coreStockRepo := repositories.NewCoreStockRepository(db)
+	saleRepo := repositories.NewSaleRepository(db)
+	warehouseLocationRepo := repositories.NewWarehouseLocationRepository(db)
+	binStockRepo := repositories.NewBinStockRepository(db)
+	putawayRepo := repositories.NewPutawayRepository(db)
+	cycleCountPlanRepo := repositories.NewCycleCountPlanRepository(db)
+	saleReturnRepo := repositories.NewSaleReturnRepository(db)
+	customerDebtRepo := repositories.NewCustomerDebtRepository(db)
+	customerDebtEntryRepo := repositories.NewCustomerDebtEntryRepository(db)
+	cashShiftRepo := repositories.NewCashShiftRepository(db)
+	cashOperationRepo := repositories.NewCashOperationRepository(db)
+	supplierLedgerRepo := repositories.NewSupplierLedgerRepository(db)
 
 	roleSvc := services.NewRoleService(roleRepo)
 	userSvc := services.NewUserService(userRepo, roleRepo)
 	supplierSvc := services.NewSupplierService(supplierRepo)
-	authSvc := services.NewAuthService(userRepo, roleRepo)
+	authSvc := services.NewAuthService(userRepo, roleRepo, tenantRepo, refreshTokenRepo, cfg.JWTSecret, cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
 	tenantSvc := services.NewTenantService(tenantRepo)
 	companySvc := services.NewCompanyService(companyRepo)
 	storeSvc := services.NewStoreService(storeRepo)
@@ -191,27 +216,50 @@ func main() {
 	brandSvc := services.NewBrandService(brandRepo)
 	warehouseSvc := services.NewWarehouseService(warehouseRepo)
 	parameterSvc := services.NewParameterService(parameterRepo)
-	productSvc := services.NewProductService(productRepo, categoryRepo, brandRepo, supplierRepo, importHistoryRepo)
+	tx := repositories.NewTx(ctx, db)
+	if !tx.Enabled() { logger.Warn("mongo is not a replica set; document approvals run without transactions: status changes stay exclusive, but an approval failing halfway can leave its stock effects partly applied") }
+	costingSvc := services.NewCostingService(stockRepo, costLayerRepo, stockMovementRepo, productRepo, tenantRepo)
+	lotSvc := services.NewLotService(stockLotRepo, productRepo)
+	serialSvc := services.NewSerialService(serialNumberRepo, productRepo)
+	measureUnitSvc := services.NewMeasureUnitService(measureUnitRepo, productRepo)
+	reservationSvc := services.NewReservationService(reservationRepo, stockRepo, productRepo, tenantRepo, measureUnitSvc)
+	consignmentSvc := services.NewConsignmentService(consignmentBatchRepo, consignmentMovementRepo)
+	binSvc := services.NewBinService(warehouseLocationRepo, binStockRepo, warehouseRepo, storeRepo, productRepo)
+	stockSvc := services.NewStockService(stockRepo, stockMovementRepo, productRepo, costingSvc, lotSvc, serialSvc, measureUnitSvc, reservationSvc, consignmentSvc, binSvc, tx)
+	productSvc := services.NewProductService(productRepo, categoryRepo, brandRepo, supplierRepo, importHistoryRepo, stockSvc, measureUnitSvc)
 	leadSvc := services.NewLeadService(leadRepo)
 	customerSvc := services.NewCustomerService(customerRepo)
-	orderSvc := services.NewOrderService(orderRepo, productRepo, supplierRepo, storeRepo, writeOffRepo)
+	supplierLedgerSvc := services.NewSupplierLedgerService(supplierLedgerRepo, supplierRepo, tx)
+	coreStockSvc := services.NewCoreStockService(coreStockRepo)
+	putawaySvc := services.NewPutawayService(putawayRepo, productRepo, binSvc, stockSvc)
+	orderSvc := services.NewOrderService(orderRepo, productRepo, supplierRepo, storeRepo, writeOffRepo, supplierLedgerSvc, exchangeRateRepo, coreStockSvc, putawaySvc, stockSvc)
 	// set singleton for cross-handler use (supplier stats)
 	services.SetOrderService(orderSvc)
+	replenishmentSvc := services.NewReplenishmentService(productRepo, orderRepo, stockRepo, stockMovementRepo, supplierRepo, storeRepo, measureUnitSvc, orderSvc, stockSvc)
+	consignmentSettlementSvc := services.NewConsignmentSettlementService(consignmentSettlementRepo, consignmentBatchRepo, consignmentMovementRepo, productRepo, supplierRepo, storeRepo, supplierLedgerSvc, orderSvc, stockSvc)
+	coreSvc := services.NewCoreService(coreStockRepo, saleRepo, productRepo, supplierRepo, orderSvc)
+	pickListSvc := services.NewPickListService(saleRepo, transferRepo, productRepo, binSvc, stockSvc)
 
 	shopCustomerSvc := services.NewShopCustomerService(shopCustomerRepo, shopContactRepo)
 	shopUnitSvc := services.NewShopUnitService(shopUnitRepo)
 	shopVendorSvc := services.NewShopVendorService(shopVendorRepo)
-	shopServiceSvc := services.NewShopServiceService(shopServiceRepo, shopCustomerRepo, shopUnitRepo)
+	shopServiceSvc := services.NewShopServiceService(shopServiceRepo, shopCustomerRepo, shopUnitRepo, stockSvc)
 	shopContactSvc := services.NewShopContactService(shopContactRepo)
 	importHistorySvc := services.NewImportHistoryService(importHistoryRepo)
 	paymentSvc := services.NewPaymentService(paymentRepo)
 	statsSvc := services.NewStatsService(statsRepo)
-	inventorySvc := services.NewInventoryService(inventoryRepo, storeRepo, productRepo, importHistoryRepo)
-	writeOffSvc := services.NewWriteOffService(writeOffRepo, storeRepo, productRepo)
-	repricingSvc := services.NewRepricingService(repricingRepo, storeRepo, productRepo)
-	transferSvc := services.NewTransferService(transferRepo, storeRepo, productRepo)
+	inventorySvc := services.NewInventoryService(inventoryRepo, storeRepo, productRepo, importHistoryRepo, stockSvc)
+	cycleCountSvc := services.NewCycleCountService(cycleCountPlanRepo, inventoryRepo, stockMovementRepo, stockRepo, productRepo, storeRepo, binSvc, inventorySvc)
+	writeOffSvc := services.NewWriteOffService(writeOffRepo, storeRepo, productRepo, stockSvc)
+	kitAssemblySvc := services.NewKitAssemblyService(kitAssemblyRepo, storeRepo, productRepo, stockSvc)
+	repricingSvc := services.NewRepricingService(repricingRepo, storeRepo, productRepo, stockSvc)
+	transferSvc := services.NewTransferService(transferRepo, storeRepo, productRepo, stockSvc, writeOffRepo, importHistoryRepo)
 	priceTagSvc := services.NewPriceTagService(priceTagRepo)
 	exchangeRateSvc := services.NewExchangeRateService(exchangeRateRepo)
+	customerDebtSvc := services.NewCustomerDebtService(customerDebtRepo, customerDebtEntryRepo, customerRepo, saleRepo, cashShiftRepo, tx)
+	saleSvc := services.NewSaleService(saleRepo, productRepo, customerRepo, storeRepo, cashShiftRepo, customerDebtSvc, stockSvc)
+	cashboxSvc := services.NewCashboxService(cashShiftRepo, cashOperationRepo, saleRepo, saleReturnRepo, customerDebtEntryRepo, storeRepo, tx)
+	saleReturnSvc := services.NewSaleReturnService(saleReturnRepo, saleRepo, cashShiftRepo, writeOffRepo, cashboxSvc, customerDebtSvc, coreStockSvc, stockSvc)
 
 	roleHandler := handlers.NewRoleHandler(roleSvc)
 	userHandler := handlers.NewUserHandler(userSvc)
@@ -245,8 +293,21 @@ func main() {
 	transferHandler := handlers.NewTransferHandler(transferSvc)
 	priceTagHandler := handlers.NewPriceTagHandler(priceTagSvc)
 	exchangeRateHandler := handlers.NewExchangeRateHandler(exchangeRateSvc)
+	stockHandler := handlers.NewStockHandler(stockSvc, reservationSvc)
+	saleHandler := handlers.NewSaleHandler(saleSvc)
+	cashboxHandler := handlers.NewCashboxHandler(cashboxSvc)
+	saleReturnHandler := handlers.NewSaleReturnHandler(saleReturnSvc)
+	customerDebtHandler := handlers.NewCustomerDebtHandler(customerDebtSvc)
+	supplierLedgerHandler := handlers.NewSupplierLedgerHandler(supplierLedgerSvc)
+	measureUnitHandler := handlers.NewMeasureUnitHandler(measureUnitSvc)
+	replenishmentHandler := handlers.NewReplenishmentHandler(replenishmentSvc)
+	kitAssemblyHandler := handlers.NewKitAssemblyHandler(kitAssemblySvc)
+	consignmentHandler := handlers.NewConsignmentHandler(consignmentSettlementSvc)
+	coreHandler := handlers.NewCoreHandler(coreSvc)
+	binHandler := handlers.NewBinHandler(binSvc, putawaySvc, pickListSvc)
+	cycleCountHandler := handlers.NewCycleCountHandler(cycleCountSvc)
 
-	_ = middleware.NewAuthz(userRepo, roleRepo)
+	_ = middleware.NewAuthz(userRepo, roleRepo, cfg.JWTSecret)
 	tenantResolver := middleware.NewTenantResolver(tenantRepo)
 
 	// Superadmin global endpoints (no tenant middleware required)
@@ -254,7 +315,10 @@ func main() {
 	// global stats
 	statsHandler.Register(app.Group("/api"))
 	// Tenant-scoped endpoints
-	routes.RegisterWithTenant(app, roleHandler, userHandler, authHandler, supplierHandler, tenantHandler, tenantResolver, companyHandler, storeHandler, categoryHandler, attributeHandler, characteristicHandler, brandHandler, warehouseHandler, parameterHandler, productHandler, uploadHandler, leadHandler, customerHandler, orderHandler, shopCustomerHandler, shopUnitHandler, shopVendorHandler, shopServiceHandler, shopContactHandler, importHistoryHandler, inventoryHandler, writeOffHandler, repricingHandler, transferHandler, priceTagHandler, exchangeRateHandler)
+	routes.RegisterWithTenant(app, roleHandler, userHandler, authHandler, supplierHandler, tenantHandler, tenantResolver, companyHandler, storeHandler, categoryHandler, attributeHandler, characteristicHandler, brandHandler, warehouseHandler, parameterHandler, productHandler, uploadHandler, leadHandler, customerHandler, orderHandler, shopCustomerHandler, shopUnitHandler, shopVendorHandler, shopServiceHandler, shopContactHandler, importHistoryHandler, inventoryHandler, writeOffHandler, repricingHandler, transferHandler, priceTagHandler, exchangeRateHandler, stockHandler, saleHandler, cashboxHandler, saleReturnHandler, customerDebtHandler, supplierLedgerHandler, measureUnitHandler, replenishmentHandler, kitAssemblyHandler, consignmentHandler, coreHandler, binHandler, cycleCountHandler)
+
+	// cycle count plans generate their inventories hourly
+	go cycleCountSvc.Schedule(context.Background(), time.Hour, func(err error) { logger.Warn("cycle count run failed", zap.Error(err)) })
 
 	addr := ":" + cfg.Port
 	logger.Info("starting http server", zap.String("addr", addr), zap.String("env", cfg.Env))
@@ -1,25 +1,38 @@
 package config
 
-import "os"
+import (
+	"os"
+	"time"
+)
 
 type Config struct {
-	Env         string
-	Port        string
-	MongoURI    string
-	DBName      string
-	JWTSecret   string
-	FrontendURL string
+	Env             string
+	Port            string
+	MongoURI        string
+	DBName          string
+	JWTSecret       string
+	FrontendURL     string
+	// AccessTokenTTL bounds how long a signed access token is accepted; RefreshTokenTTL bounds a login session.
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
 }
 
 func Load() *Config {
 	return &Config{
-		Env:         getenv("ENV", "development"),
-		Port:        getenv("PORT", "8081"),
-		MongoURI:    getenv("MONGO_URI", "mongodb://localhost:27018"),
-		DBName:      getenv("DB_NAME", "shop"),
-		JWTSecret:   getenv("JWT_SECRET", "devsecret"),
-		FrontendURL: getenv("FRONTEND_URL", "http://localhost:5174"),
+		Env:             getenv("ENV", "development"),
+		Port:            getenv("PORT", "8081"),
+		MongoURI:        getenv("MONGO_URI", "mongodb://localhost:27018"),
+		DBName:          getenv("DB_NAME", "shop"),
+		JWTSecret:       getenv("JWT_SECRET", "devsecret"),
+		FrontendURL:     getenv("FRONTEND_URL", "http://localhost:5174"),
+		AccessTokenTTL:  getduration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getduration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
 	}
 }
 
-func getenv(k, d string) string { if v := os.Getenv(k); v != "" { return v }; return d } 
\ No newline at end of file
+func getenv(k, d string) string { if v := os.Getenv(k); v != "" { return v }; return d }
+
+func getduration(k string, d time.Duration) time.Duration {
+	if v := os.Getenv(k); v != "" { if dur, err := time.ParseDuration(v); err == nil && dur > 0 { return dur } }
+	return d
+}
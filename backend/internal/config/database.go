@@ -30,10 +30,11 @@ func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
 	})
 	if err != nil { return err }
 	suppliers := db.Collection("suppliers")
+	for _, name := range []string{"ix_suppliers_name", "ix_suppliers_email", "ix_suppliers_createdat"} { _, _ = suppliers.Indexes().DropOne(ctx, name) }
 	_, err = suppliers.Indexes().CreateMany(ctx, []mongo.IndexModel{
-		{ Keys: bson.D{{Key: "name", Value: 1}}, Options: options.Index().SetName("ix_suppliers_name") },
-		{ Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetName("ix_suppliers_email") },
-		{ Keys: bson.D{{Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_suppliers_createdat") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "name", Value: 1}}, Options: options.Index().SetName("ix_suppliers_tenant_name") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "email", Value: 1}}, Options: options.Index().SetName("ix_suppliers_tenant_email") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_suppliers_tenant_createdat") },
 	})
 	if err != nil { return err }
 	tenants := db.Collection("tenants")
@@ -49,28 +50,32 @@ func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
 		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "company_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_stores_tenant_company_createdat") },
 	})
 	if err != nil { return err }
+	// categories, attributes and characteristics are tenant-scoped; drop the pre-tenant indexes they replace
 	categories := db.Collection("categories")
+	for _, name := range []string{"ix_categories_name", "ix_categories_parent_level", "ix_categories_active_createdat", "ix_categories_deleted"} { _, _ = categories.Indexes().DropOne(ctx, name) }
 	_, err = categories.Indexes().CreateMany(ctx, []mongo.IndexModel{
-		{ Keys: bson.D{{Key: "name", Value: 1}}, Options: options.Index().SetName("ix_categories_name") },
-		{ Keys: bson.D{{Key: "parent_id", Value: 1}, {Key: "level", Value: 1}}, Options: options.Index().SetName("ix_categories_parent_level") },
-		{ Keys: bson.D{{Key: "is_active", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_categories_active_createdat") },
-		{ Keys: bson.D{{Key: "is_deleted", Value: 1}}, Options: options.Index().SetName("ix_categories_deleted") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "name", Value: 1}}, Options: options.Index().SetName("ix_categories_tenant_name") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "parent_id", Value: 1}, {Key: "level", Value: 1}}, Options: options.Index().SetName("ix_categories_tenant_parent_level") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "is_active", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_categories_tenant_active_createdat") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "is_deleted", Value: 1}}, Options: options.Index().SetName("ix_categories_tenant_deleted") },
 	})
 	if err != nil { return err }
 	attributes := db.Collection("attributes")
+	for _, name := range []string{"ix_attributes_name", "ix_attributes_value", "ix_attributes_active_createdat", "ix_attributes_deleted"} { _, _ = attributes.Indexes().DropOne(ctx, name) }
 	_, err = attributes.Indexes().CreateMany(ctx, []mongo.IndexModel{
-		{ Keys: bson.D{{Key: "name", Value: 1}}, Options: options.Index().SetName("ix_attributes_name") },
-		{ Keys: bson.D{{Key: "value", Value: 1}}, Options: options.Index().SetName("ix_attributes_value") },
-		{ Keys: bson.D{{Key: "is_active", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_attributes_active_createdat") },
-		{ Keys: bson.D{{Key: "is_deleted", Value: 1}}, Options: options.Index().SetName("ix_attributes_deleted") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "name", Value: 1}}, Options: options.Index().SetName("ix_attributes_tenant_name") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "values", Value: 1}}, Options: options.Index().SetName("ix_attributes_tenant_values") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "is_active", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_attributes_tenant_active_createdat") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "is_deleted", Value: 1}}, Options: options.Index().SetName("ix_attributes_tenant_deleted") },
 	})
 	if err != nil { return err }
 	characteristics := db.Collection("characteristics")
+	for _, name := range []string{"ix_characteristics_name", "ix_characteristics_type", "ix_characteristics_active_createdat", "ix_characteristics_deleted"} { _, _ = characteristics.Indexes().DropOne(ctx, name) }
 	_, err = characteristics.Indexes().CreateMany(ctx, []mongo.IndexModel{
-		{ Keys: bson.D{{Key: "name", Value: 1}}, Options: options.Index().SetName("ix_characteristics_name") },
-		{ Keys: bson.D{{Key: "type", Value: 1}}, Options: options.Index().SetName("ix_characteristics_type") },
-		{ Keys: bson.D{{Key: "is_active", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_characteristics_active_createdat") },
-		{ Keys: bson.D{{Key: "is_deleted", Value: 1}}, Options: options.Index().SetName("ix_characteristics_deleted") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "name", Value: 1}}, Options: options.Index().SetName("ix_characteristics_tenant_name") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "type", Value: 1}}, Options: options.Index().SetName("ix_characteristics_tenant_type") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "is_active", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_characteristics_tenant_active_createdat") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "is_deleted", Value: 1}}, Options: options.Index().SetName("ix_characteristics_tenant_deleted") },
 	})
 	if err != nil { return err }
 	
@@ -141,6 +146,7 @@ func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
 		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_inventories_tenant_createdat") },
 		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "status_id", Value: 1}}, Options: options.Index().SetName("ix_inventories_tenant_status") },
 		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "shop_id", Value: 1}}, Options: options.Index().SetName("ix_inventories_tenant_shop") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "status_id", Value: 1}, {Key: "finished_at", Value: 1}}, Options: options.Index().SetName("ix_inventories_tenant_status_finishedat") },
 	})
 	if err != nil { return err }
 	
@@ -248,5 +254,194 @@ func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
 	})
 	if err != nil { return err }
 
+	// refresh_tokens (expired tokens are removed by the TTL index)
+	refreshTokens := db.Collection("refresh_tokens")
+	_, err = refreshTokens.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true).SetName("ux_refreshtokens_hash") },
+		{ Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetName("ix_refreshtokens_user") },
+		{ Keys: bson.D{{Key: "family_id", Value: 1}}, Options: options.Index().SetName("ix_refreshtokens_family") },
+		{ Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0).SetName("ttl_refreshtokens_expires") },
+	})
+	if err != nil { return err }
+
+	stockBalances := db.Collection("stock_balances")
+	_, err = stockBalances.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "product_id", Value: 1}, {Key: "shop_id", Value: 1}}, Options: options.Index().SetUnique(true).SetName("ux_stockbalances_tenant_product_shop") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "shop_id", Value: 1}}, Options: options.Index().SetName("ix_stockbalances_tenant_shop") },
+	})
+	if err != nil { return err }
+
+	stockMovements := db.Collection("stock_movements")
+	_, err = stockMovements.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "product_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_stockmovements_tenant_product_createdat") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "product_id", Value: 1}, {Key: "shop_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_stockmovements_tenant_product_shop_createdat") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "source_type", Value: 1}, {Key: "source_id", Value: 1}}, Options: options.Index().SetName("ix_stockmovements_tenant_source") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: 1}}, Options: options.Index().SetName("ix_stockmovements_tenant_createdat") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "shop_id", Value: 1}, {Key: "source_type", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_stockmovements_tenant_shop_source_createdat") },
+	})
+	if err != nil { return err }
+
+	// cost_layers: FIFO consumes a product's open layers in a store oldest first
+	costLayers := db.Collection("cost_layers")
+	_, err = costLayers.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "product_id", Value: 1}, {Key: "shop_id", Value: 1}, {Key: "created_at", Value: 1}}, Options: options.Index().SetName("ix_costlayers_open").SetPartialFilterExpression(bson.M{"left": bson.M{"$gt": 0}}) },
+	})
+	if err != nil { return err }
+
+	// stock_lots: a receiving adds to its lot by number, expiry and order; FEFO and the expiry report read open lots
+	stockLots := db.Collection("stock_lots")
+	_, err = stockLots.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "product_id", Value: 1}, {Key: "shop_id", Value: 1}, {Key: "number", Value: 1}, {Key: "expiration_date", Value: 1}, {Key: "order_id", Value: 1}}, Options: options.Index().SetName("ux_stocklots_lot").SetUnique(true) },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "expiration_date", Value: 1}}, Options: options.Index().SetName("ix_stocklots_tenant_expiry_open").SetPartialFilterExpression(bson.M{"qty": bson.M{"$gt": 0}}) },
+	})
+	if err != nil { return err }
+
+	// serial_numbers: one unit per product and serial; the lookup searches by serial across products
+	serials := db.Collection("serial_numbers")
+	_, err = serials.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "product_id", Value: 1}, {Key: "serial", Value: 1}}, Options: options.Index().SetName("ux_serialnumbers_tenant_product_serial").SetUnique(true) },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "serial", Value: 1}}, Options: options.Index().SetName("ix_serialnumbers_tenant_serial") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "product_id", Value: 1}, {Key: "status", Value: 1}, {Key: "shop_id", Value: 1}}, Options: options.Index().SetName("ix_serialnumbers_tenant_product_status") },
+	})
+	if err != nil { return err }
+
+	measureUnits := db.Collection("measure_units")
+	_, err = measureUnits.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "code", Value: 1}}, Options: options.Index().SetName("ux_measureunits_tenant_code").SetUnique(true) },
+	})
+	if err != nil { return err }
+
+	reservations := db.Collection("stock_reservations")
+	_, err = reservations.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "product_id", Value: 1}, {Key: "shop_id", Value: 1}}, Options: options.Index().SetName("ix_stockreservations_tenant_product_shop") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "source_type", Value: 1}, {Key: "source_id", Value: 1}}, Options: options.Index().SetName("ix_stockreservations_tenant_source") },
+		// lapsed reservations are removed by the TTL monitor; reads also skip them until it runs
+		{ Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetName("ix_stockreservations_expiresat_ttl").SetExpireAfterSeconds(0) },
+	})
+	if err != nil { return err }
+
+	kitAssemblies := db.Collection("kit_assemblies")
+	_, err = kitAssemblies.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_kitassemblies_tenant_createdat") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "shop_id", Value: 1}, {Key: "status", Value: 1}}, Options: options.Index().SetName("ix_kitassemblies_tenant_shop_status") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "product_id", Value: 1}}, Options: options.Index().SetName("ix_kitassemblies_tenant_product") },
+	})
+	if err != nil { return err }
+
+	consignmentBatches := db.Collection("consignment_batches")
+	_, err = consignmentBatches.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "product_id", Value: 1}, {Key: "received_at", Value: 1}}, Options: options.Index().SetName("ix_consignmentbatches_tenant_product_receivedat") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "supplier_id", Value: 1}, {Key: "shop_id", Value: 1}, {Key: "due_date", Value: 1}}, Options: options.Index().SetName("ix_consignmentbatches_tenant_supplier_shop_due") },
+	})
+	if err != nil { return err }
+
+	consignmentMovements := db.Collection("consignment_movements")
+	_, err = consignmentMovements.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "source_type", Value: 1}, {Key: "source_id", Value: 1}, {Key: "product_id", Value: 1}}, Options: options.Index().SetName("ix_consignmentmovements_tenant_source_product") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "supplier_id", Value: 1}, {Key: "settlement_id", Value: 1}, {Key: "created_at", Value: 1}}, Options: options.Index().SetName("ix_consignmentmovements_tenant_supplier_settlement_createdat") },
+	})
+	if err != nil { return err }
+
+	consignmentSettlements := db.Collection("consignment_settlements")
+	_, err = consignmentSettlements.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_consignmentsettlements_tenant_createdat") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "supplier_id", Value: 1}, {Key: "status", Value: 1}}, Options: options.Index().SetName("ix_consignmentsettlements_tenant_supplier_status") },
+	})
+	if err != nil { return err }
+
+	sales := db.Collection("sales")
+	_, err = sales.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_sales_tenant_createdat") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "shop_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_sales_tenant_shop_createdat") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "customer_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_sales_tenant_customer_createdat") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "receipt_number", Value: 1}}, Options: options.Index().SetUnique(true).SetName("ux_sales_tenant_receipt").SetPartialFilterExpression(bson.M{"receipt_number": bson.M{"$gt": 0}}) },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "shift_id", Value: 1}}, Options: options.Index().SetName("ix_sales_tenant_shift") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "cores_outstanding", Value: 1}}, Options: options.Index().SetName("ix_sales_tenant_coresoutstanding").SetPartialFilterExpression(bson.M{"cores_outstanding": bson.M{"$gt": 0}}) },
+	})
+	if err != nil { return err }
+
+	// core_stock: one balance of dirty cores per product and store
+	coreStock := db.Collection("core_stock")
+	_, err = coreStock.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "shop_id", Value: 1}, {Key: "product_id", Value: 1}}, Options: options.Index().SetUnique(true).SetName("ux_corestock_tenant_shop_product") },
+	})
+	if err != nil { return err }
+
+	// warehouse_locations: paths are unique within a store or warehouse, which keeps sibling codes unique
+	warehouseLocations := db.Collection("warehouse_locations")
+	_, err = warehouseLocations.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "warehouse_id", Value: 1}, {Key: "path", Value: 1}}, Options: options.Index().SetUnique(true).SetName("ux_warehouselocations_tenant_warehouse_path") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "parent_id", Value: 1}}, Options: options.Index().SetName("ix_warehouselocations_tenant_parent") },
+	})
+	if err != nil { return err }
+
+	// bin_stock: one row per product and bin
+	binStock := db.Collection("bin_stock")
+	_, err = binStock.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "bin_id", Value: 1}, {Key: "product_id", Value: 1}}, Options: options.Index().SetUnique(true).SetName("ux_binstock_tenant_bin_product") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "shop_id", Value: 1}, {Key: "product_id", Value: 1}, {Key: "bin_path", Value: 1}}, Options: options.Index().SetName("ix_binstock_tenant_shop_product_path") },
+	})
+	if err != nil { return err }
+
+	putaways := db.Collection("putaways")
+	_, err = putaways.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_putaways_tenant_createdat") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "shop_id", Value: 1}, {Key: "status", Value: 1}}, Options: options.Index().SetName("ix_putaways_tenant_shop_status") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "order_id", Value: 1}}, Options: options.Index().SetName("ix_putaways_tenant_order") },
+	})
+	if err != nil { return err }
+
+	cycleCountPlans := db.Collection("cycle_count_plans")
+	_, err = cycleCountPlans.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_cyclecountplans_tenant_createdat") },
+		{ Keys: bson.D{{Key: "is_active", Value: 1}, {Key: "next_run_at", Value: 1}}, Options: options.Index().SetName("ix_cyclecountplans_active_nextrun") },
+	})
+	if err != nil { return err }
+
+	// cash_shifts: one open shift per cashbox and per cashier in a store
+	cashShifts := db.Collection("cash_shifts")
+	_, err = cashShifts.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "shop_id", Value: 1}, {Key: "cashbox_id", Value: 1}}, Options: options.Index().SetUnique(true).SetName("ux_cashshifts_open_cashbox").SetPartialFilterExpression(bson.M{"status": "OPEN"}) },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "shop_id", Value: 1}, {Key: "opened_by.id", Value: 1}}, Options: options.Index().SetUnique(true).SetName("ux_cashshifts_open_user").SetPartialFilterExpression(bson.M{"status": "OPEN"}) },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "opened_at", Value: -1}}, Options: options.Index().SetName("ix_cashshifts_tenant_openedat") },
+	})
+	if err != nil { return err }
+
+	cashOperations := db.Collection("cash_operations")
+	_, err = cashOperations.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "shift_id", Value: 1}, {Key: "created_at", Value: 1}}, Options: options.Index().SetName("ix_cashoperations_tenant_shift") },
+	})
+	if err != nil { return err }
+
+	saleReturns := db.Collection("sale_returns")
+	_, err = saleReturns.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "sale_id", Value: 1}, {Key: "created_at", Value: 1}}, Options: options.Index().SetName("ix_salereturns_tenant_sale") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "shift_id", Value: 1}}, Options: options.Index().SetName("ix_salereturns_tenant_shift") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_salereturns_tenant_createdat") },
+	})
+	if err != nil { return err }
+
+	customerDebts := db.Collection("customer_debts")
+	_, err = customerDebts.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "sale_id", Value: 1}}, Options: options.Index().SetName("ux_customerdebts_tenant_sale").SetUnique(true) },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "customer_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: 1}}, Options: options.Index().SetName("ix_customerdebts_tenant_customer_status") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "status", Value: 1}, {Key: "shop_id", Value: 1}, {Key: "due_date", Value: 1}}, Options: options.Index().SetName("ix_customerdebts_tenant_status_shop_due") },
+	})
+	if err != nil { return err }
+
+	debtEntries := db.Collection("customer_debt_entries")
+	_, err = debtEntries.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "customer_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("ix_debtentries_tenant_customer_createdat") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "shift_id", Value: 1}}, Options: options.Index().SetName("ix_debtentries_tenant_shift").SetPartialFilterExpression(bson.M{"shift_id": bson.M{"$exists": true}}) },
+	})
+	if err != nil { return err }
+
+	supplierLedger := db.Collection("supplier_ledger")
+	_, err = supplierLedger.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "supplier_id", Value: 1}, {Key: "date", Value: 1}}, Options: options.Index().SetName("ix_supplierledger_tenant_supplier_date") },
+		{ Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "supplier_id", Value: 1}, {Key: "type", Value: 1}}, Options: options.Index().SetName("ix_supplierledger_tenant_supplier_type") },
+	})
+	if err != nil { return err }
+
 	return err
 } 
\ No newline at end of file
@@ -0,0 +1,397 @@
+package config
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type migration struct {
+	ID  string
+	Run func(ctx context.Context, db *mongo.Database) error
+}
+
+// migrations run once each, in order; applied ids are recorded in the "migrations" collection.
+var migrations = []migration{
+	{ID: "20241001_catalog_tenant_scope", Run: migrateCatalogTenantScope},
+	{ID: "20241008_stock_balances", Run: migrateStockBalances},
+	{ID: "20241015_stock_ledger_opening", Run: migrateStockLedgerOpening},
+	{ID: "20241029_supplier_ledger", Run: migrateSupplierLedger},
+	{ID: "20241105_stock_costing", Run: migrateStockCosting},
+}
+
+func RunMigrations(ctx context.Context, db *mongo.Database) error {
+	col := db.Collection("migrations")
+	for _, m := range migrations {
+		n, err := col.CountDocuments(ctx, bson.M{"_id": m.ID})
+		if err != nil { return err }
+		if n > 0 { continue }
+		if err := m.Run(ctx, db); err != nil { return err }
+		if _, err := col.InsertOne(ctx, bson.M{"_id": m.ID, "applied_at": time.Now().UTC()}); err != nil { return err }
+	}
+	return nil
+}
+
+// migrateCatalogTenantScope assigns categories, attributes, characteristics and suppliers created before they were
+// tenant-scoped to the tenant whose products reference them. A document referenced by several tenants stays with the
+// tenant that uses it most and is copied for every other tenant, whose products are re-pointed at the copy.
+// Unreferenced documents go to the parent category's tenant or, failing that, the demo tenant.
+func migrateCatalogTenantScope(ctx context.Context, db *mongo.Database) error {
+	fallback := ""
+	var demo struct{ ID primitive.ObjectID `bson:"_id"` }
+	if err := db.Collection("tenants").FindOne(ctx, bson.M{"subdomain": "demo"}).Decode(&demo); err == nil { fallback = demo.ID.Hex() }
+
+	if err := migrateCategories(ctx, db, fallback); err != nil { return err }
+	if err := migrateFlatCatalog(ctx, db, "attributes", "catalog_attributes", "attribute_id", fallback); err != nil { return err }
+	if err := migrateFlatCatalog(ctx, db, "characteristics", "catalog_characteristics", "characteristic_id", fallback); err != nil { return err }
+	return migrateSuppliers(ctx, db, fallback)
+}
+
+var unscoped = bson.M{"$or": bson.A{bson.M{"tenant_id": bson.M{"$exists": false}}, bson.M{"tenant_id": ""}}}
+
+type tenantRefs map[primitive.ObjectID]map[string]int64
+
+// productRefs counts, per referenced id and tenant, how many products reference it. keys is an aggregation
+// expression that evaluates to the array of referenced ids of one product.
+func productRefs(ctx context.Context, db *mongo.Database, keys interface{}) (tenantRefs, error) {
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$project", Value: bson.M{"tenant_id": 1, "keys": keys}}},
+		bson.D{{Key: "$unwind", Value: "$keys"}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": bson.M{"ref": "$keys", "tenant": "$tenant_id"}, "count": bson.M{"$sum": 1}}}},
+	}
+	cur, err := db.Collection("products").Aggregate(ctx, pipeline)
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	out := tenantRefs{}
+	for cur.Next(ctx) {
+		var row struct {
+			ID struct {
+				Ref    primitive.ObjectID `bson:"ref"`
+				Tenant string             `bson:"tenant"`
+			} `bson:"_id"`
+			Count int64 `bson:"count"`
+		}
+		if err := cur.Decode(&row); err != nil || row.ID.Tenant == "" { continue }
+		if out[row.ID.Ref] == nil { out[row.ID.Ref] = map[string]int64{} }
+		out[row.ID.Ref][row.ID.Tenant] += row.Count
+	}
+	return out, cur.Err()
+}
+
+// rankTenants orders tenants by reference count, most used first.
+func rankTenants(counts map[string]int64) []string {
+	out := make([]string, 0, len(counts))
+	for t := range counts { out = append(out, t) }
+	sort.Slice(out, func(i, j int) bool {
+		if counts[out[i]] != counts[out[j]] { return counts[out[i]] > counts[out[j]] }
+		return out[i] < out[j]
+	})
+	return out
+}
+
+// cloneFor inserts a copy of doc owned by tenantID and returns the new id.
+func cloneFor(ctx context.Context, col *mongo.Collection, doc bson.M, tenantID string, set bson.M) (primitive.ObjectID, error) {
+	cp := bson.M{}
+	for k, v := range doc { cp[k] = v }
+	id := primitive.NewObjectID()
+	cp["_id"] = id
+	cp["tenant_id"] = tenantID
+	for k, v := range set { cp[k] = v }
+	_, err := col.InsertOne(ctx, cp)
+	return id, err
+}
+
+func migrateCategories(ctx context.Context, db *mongo.Database, fallback string) error {
+	col := db.Collection("categories")
+	refs, err := productRefs(ctx, db, bson.M{"$setUnion": bson.A{
+		bson.M{"$cond": bson.A{bson.M{"$ifNull": bson.A{"$category_id", false}}, bson.A{"$category_id"}, bson.A{}}},
+		bson.M{"$ifNull": bson.A{"$category_ids", bson.A{}}},
+	}})
+	if err != nil { return err }
+	cur, err := col.Find(ctx, unscoped, options.Find().SetSort(bson.D{{Key: "level", Value: 1}}))
+	if err != nil { return err }
+	var docs []bson.M
+	if err := cur.All(ctx, &docs); err != nil { return err }
+
+	// a parent must exist in every tenant any of its descendants is used in, so roll counts up the tree
+	parentOf := map[primitive.ObjectID]primitive.ObjectID{}
+	for _, d := range docs {
+		if pid, ok := d["parent_id"].(primitive.ObjectID); ok { parentOf[d["_id"].(primitive.ObjectID)] = pid }
+	}
+	rolled := tenantRefs{}
+	for id, counts := range refs {
+		for node, seen := id, map[primitive.ObjectID]bool{}; !seen[node]; {
+			seen[node] = true
+			if rolled[node] == nil { rolled[node] = map[string]int64{} }
+			for t, n := range counts { rolled[node][t] += n }
+			pid, ok := parentOf[node]
+			if !ok { break }
+			node = pid
+		}
+	}
+
+	// top-down (docs are sorted by level) so each parent is placed before its children
+	placed := map[primitive.ObjectID]map[string]primitive.ObjectID{}
+	primary := map[primitive.ObjectID]string{}
+	for _, d := range docs {
+		id := d["_id"].(primitive.ObjectID)
+		pid, hasParent := d["parent_id"].(primitive.ObjectID)
+		tenants := rankTenants(rolled[id])
+		if len(tenants) == 0 {
+			if hasParent && primary[pid] != "" { tenants = []string{primary[pid]} } else if fallback != "" { tenants = []string{fallback} } else { continue }
+		}
+		placed[id] = map[string]primitive.ObjectID{}
+		for i, t := range tenants {
+			set := bson.M{}
+			if hasParent {
+				if p, ok := placed[pid][t]; ok { set["parent_id"] = p }
+			}
+			if i == 0 {
+				set["tenant_id"] = t
+				if _, err := col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set}); err != nil { return err }
+				placed[id][t] = id
+				primary[id] = t
+				continue
+			}
+			newID, err := cloneFor(ctx, col, d, t, set)
+			if err != nil { return err }
+			placed[id][t] = newID
+			products := db.Collection("products")
+			if _, err := products.UpdateMany(ctx, bson.M{"tenant_id": t, "category_id": id}, bson.M{"$set": bson.M{"category_id": newID}}); err != nil { return err }
+			if _, err := products.UpdateMany(ctx, bson.M{"tenant_id": t, "category_ids": id}, bson.M{"$set": bson.M{"category_ids.$[c]": newID}}, options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"c": id}}})); err != nil { return err }
+		}
+	}
+	return nil
+}
+
+// migrateFlatCatalog handles attributes and characteristics, which products reference from an array of
+// {<idField>: ObjectID} entries stored under arrayField.
+func migrateFlatCatalog(ctx context.Context, db *mongo.Database, collection, arrayField, idField, fallback string) error {
+	col := db.Collection(collection)
+	refs, err := productRefs(ctx, db, bson.M{"$ifNull": bson.A{"$" + arrayField + "." + idField, bson.A{}}})
+	if err != nil { return err }
+	cur, err := col.Find(ctx, unscoped)
+	if err != nil { return err }
+	var docs []bson.M
+	if err := cur.All(ctx, &docs); err != nil { return err }
+	for _, d := range docs {
+		id := d["_id"].(primitive.ObjectID)
+		tenants := rankTenants(refs[id])
+		if len(tenants) == 0 {
+			if fallback == "" { continue }
+			tenants = []string{fallback}
+		}
+		if _, err := col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"tenant_id": tenants[0]}}); err != nil { return err }
+		for _, t := range tenants[1:] {
+			newID, err := cloneFor(ctx, col, d, t, nil)
+			if err != nil { return err }
+			path := arrayField + ".$[e]." + idField
+			filter := bson.M{"tenant_id": t, arrayField + "." + idField: id}
+			if _, err := db.Collection("products").UpdateMany(ctx, filter, bson.M{"$set": bson.M{path: newID}}, options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"e." + idField: id}}})); err != nil { return err }
+		}
+	}
+	return nil
+}
+
+// migrateSuppliers only fills a missing tenant_id; orders and payments reference suppliers by id string, so a
+// supplier shared between tenants is not split and stays with the tenant that uses it most.
+func migrateSuppliers(ctx context.Context, db *mongo.Database, fallback string) error {
+	col := db.Collection("suppliers")
+	refs, err := productRefs(ctx, db, bson.M{"$cond": bson.A{bson.M{"$ifNull": bson.A{"$supplier_id", false}}, bson.A{"$supplier_id"}, bson.A{}}})
+	if err != nil { return err }
+	cur, err := col.Find(ctx, unscoped, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil { return err }
+	var docs []struct{ ID primitive.ObjectID `bson:"_id"` }
+	if err := cur.All(ctx, &docs); err != nil { return err }
+	for _, d := range docs {
+		tenant := fallback
+		if ranked := rankTenants(refs[d.ID]); len(ranked) > 0 { tenant = ranked[0] }
+		if tenant == "" { continue }
+		if _, err := col.UpdateOne(ctx, bson.M{"_id": d.ID}, bson.M{"$set": bson.M{"tenant_id": tenant}}); err != nil { return err }
+	}
+	return nil
+}
+
+// migrateStockBalances moves the single Product.Stock counter into a per-store balance. The stock is assumed to be in
+// the product's own store or, when it has none, the tenant's first store. Products that already have balances are
+// left alone, as are products of tenants without stores.
+func migrateStockBalances(ctx context.Context, db *mongo.Database) error {
+	balances := db.Collection("stock_balances")
+	cur, err := db.Collection("products").Find(ctx, bson.M{"stock": bson.M{"$gt": 0}}, options.Find().SetProjection(bson.M{"tenant_id": 1, "store_id": 1, "stock": 1}))
+	if err != nil { return err }
+	var products []struct {
+		ID       primitive.ObjectID `bson:"_id"`
+		TenantID string             `bson:"tenant_id"`
+		StoreID  primitive.ObjectID `bson:"store_id"`
+		Stock    float64            `bson:"stock"`
+	}
+	if err := cur.All(ctx, &products); err != nil { return err }
+
+	firstStore := map[string]string{}
+	now := time.Now().UTC()
+	for _, p := range products {
+		n, err := balances.CountDocuments(ctx, bson.M{"tenant_id": p.TenantID, "product_id": p.ID})
+		if err != nil { return err }
+		if n > 0 { continue }
+		shopID := ""
+		if p.StoreID != primitive.NilObjectID { shopID = p.StoreID.Hex() }
+		if shopID == "" {
+			if _, ok := firstStore[p.TenantID]; !ok {
+				firstStore[p.TenantID] = ""
+				if tid, err := primitive.ObjectIDFromHex(p.TenantID); err == nil {
+					var st struct{ ID primitive.ObjectID `bson:"_id"` }
+					if err := db.Collection("stores").FindOne(ctx, bson.M{"tenant_id": tid}, options.FindOne().SetSort(bson.D{{Key: "created_at", Value: 1}})).Decode(&st); err == nil { firstStore[p.TenantID] = st.ID.Hex() }
+				}
+			}
+			shopID = firstStore[p.TenantID]
+		}
+		if shopID == "" { continue }
+		if _, err := balances.InsertOne(ctx, bson.M{"tenant_id": p.TenantID, "product_id": p.ID, "shop_id": shopID, "qty": p.Stock, "created_at": now, "updated_at": now}); err != nil { return err }
+	}
+	return nil
+}
+
+// migrateStockLedgerOpening records an opening movement for every balance that predates the stock ledger, so that
+// each balance equals the sum of its movements from the start.
+func migrateStockLedgerOpening(ctx context.Context, db *mongo.Database) error {
+	movements := db.Collection("stock_movements")
+	cur, err := db.Collection("stock_balances").Find(ctx, bson.M{"qty": bson.M{"$ne": 0}})
+	if err != nil { return err }
+	var balances []bson.M
+	if err := cur.All(ctx, &balances); err != nil { return err }
+	now := time.Now().UTC()
+	for _, b := range balances {
+		n, err := movements.CountDocuments(ctx, bson.M{"tenant_id": b["tenant_id"], "product_id": b["product_id"], "shop_id": b["shop_id"]})
+		if err != nil { return err }
+		if n > 0 { continue }
+		var p struct{ CostPrice float64 `bson:"cost_price"` }
+		_ = db.Collection("products").FindOne(ctx, bson.M{"_id": b["product_id"]}, options.FindOne().SetProjection(bson.M{"cost_price": 1})).Decode(&p)
+		opening := bson.M{"tenant_id": b["tenant_id"], "product_id": b["product_id"], "shop_id": b["shop_id"], "delta": b["qty"], "balance": b["qty"], "unit_cost": p.CostPrice, "source_type": "opening", "source_id": "", "actor": bson.M{"id": "", "name": ""}, "created_at": now}
+		if _, err := movements.InsertOne(ctx, opening); err != nil { return err }
+	}
+	return nil
+}
+
+// migrateSupplierLedger posts the orders accepted and the payments made before the supplier ledger existed, dated
+// when they happened, and sets each supplier's balance from them. Orders of suppliers that are not on file are skipped.
+// Entries are posted in date order so each carries the supplier's running balance at its date. They are marked with
+// source "migration": a rerun replaces them and leaves alone the acceptances and payments the ledger has posted itself.
+func migrateSupplierLedger(ctx context.Context, db *mongo.Database) error {
+	ledger := db.Collection("supplier_ledger")
+	suppliers := db.Collection("suppliers")
+	if _, err := ledger.DeleteMany(ctx, bson.M{"source": "migration"}); err != nil { return err }
+	// orders and payments the ledger already holds are not posted again
+	cur, err := ledger.Find(ctx, bson.M{"order_id": bson.M{"$nin": bson.A{"", nil}}}, options.Find().SetProjection(bson.M{"type": 1, "order_id": 1, "payment_id": 1}))
+	if err != nil { return err }
+	var posted []models.SupplierLedgerEntry
+	if err := cur.All(ctx, &posted); err != nil { return err }
+	accepted, paid := map[string]bool{}, map[string]bool{}
+	for _, e := range posted {
+		switch e.Type {
+		case models.SupplierEntryOrder, models.SupplierEntryReturn: accepted[e.OrderID] = true
+		case models.SupplierEntryPayment: paid[e.PaymentID] = true
+		}
+	}
+
+	cur, err = db.Collection("orders").Find(ctx, bson.M{})
+	if err != nil { return err }
+	var orders []models.Order
+	if err := cur.All(ctx, &orders); err != nil { return err }
+	type posting struct {
+		sid   primitive.ObjectID
+		date  time.Time
+		entry bson.M
+	}
+	postings := []posting{}
+	now := time.Now().UTC()
+	for _, o := range orders {
+		sid, err := primitive.ObjectIDFromHex(o.SupplierID)
+		if err != nil { continue }
+		if n, err := suppliers.CountDocuments(ctx, bson.M{"_id": sid, "tenant_id": o.TenantID}); err != nil || n == 0 { continue }
+		isReturn := strings.ToLower(o.Type) == "return_order"
+		post := func(typ string, amount float64, date time.Time, extra bson.M) {
+			if amount == 0 { return }
+			e := bson.M{"tenant_id": o.TenantID, "supplier_id": o.SupplierID, "shop_id": o.ShopID, "type": typ, "amount": math.Round(amount*100) / 100, "order_id": o.ID.Hex(), "order_name": o.Name, "comment": "", "date": date, "created_by": bson.M{"id": "", "name": ""}, "source": "migration", "created_at": now}
+			for k, v := range extra { e[k] = v }
+			postings = append(postings, posting{sid: sid, date: date, entry: e})
+		}
+		if strings.ToLower(o.StatusID) == "accepted" && !accepted[o.ID.Hex()] {
+			date := o.UpdatedAt
+			if t, err := time.Parse(time.RFC3339, o.AcceptingDate); err == nil { date = t }
+			var amount float64
+			for _, it := range o.Items {
+				price := it.SupplyPrice
+				if price <= 0 { price = it.UnitPrice }
+				qty := it.Quantity
+				if isReturn && it.ReturnedQuantity > 0 && it.ReturnedQuantity <= it.Quantity { qty = it.ReturnedQuantity }
+				amount += qty * price
+			}
+			if amount == 0 { amount = o.TotalSupplyPrice }
+			if amount == 0 { amount = o.TotalPrice }
+			typ := "order"
+			if isReturn { typ, amount = "return", -amount }
+			post(typ, amount, date, nil)
+		}
+		for _, p := range o.Payments {
+			if paid[p.ID.Hex()] { continue }
+			amount := -p.Amount
+			if isReturn { amount = p.Amount }
+			post("payment", amount, p.PaymentDate, bson.M{"payment_id": p.ID.Hex(), "method": p.PaymentMethod, "comment": p.Description})
+		}
+	}
+	// an order comes before payments dated the same
+	sort.SliceStable(postings, func(i, j int) bool { return postings[i].date.Before(postings[j].date) })
+
+	balances := map[primitive.ObjectID]float64{}
+	tenants := map[primitive.ObjectID]string{}
+	for _, p := range postings {
+		balances[p.sid] += p.entry["amount"].(float64)
+		tenants[p.sid] = p.entry["tenant_id"].(string)
+		p.entry["balance"] = math.Round(balances[p.sid]*100) / 100
+		if _, err := ledger.InsertOne(ctx, p.entry); err != nil { return err }
+	}
+	for sid, balance := range balances {
+		// entries the ledger posted itself still count toward the balance
+		cur, err := ledger.Aggregate(ctx, mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"tenant_id": tenants[sid], "supplier_id": sid.Hex(), "source": bson.M{"$ne": "migration"}}}},
+			{{Key: "$group", Value: bson.M{"_id": nil, "amount": bson.M{"$sum": "$amount"}}}},
+		})
+		if err != nil { return err }
+		var own []struct{ Amount float64 `bson:"amount"` }
+		if err := cur.All(ctx, &own); err != nil { return err }
+		if len(own) > 0 { balance += own[0].Amount }
+		if _, err := suppliers.UpdateOne(ctx, bson.M{"_id": sid, "tenant_id": tenants[sid]}, bson.M{"$set": bson.M{"balance": math.Round(balance*100) / 100}}); err != nil { return err }
+	}
+	return nil
+}
+
+// migrateStockCosting gives every balance that predates the costing engine its product's cost price as the average
+// cost and one opening cost layer for the quantity on hand.
+func migrateStockCosting(ctx context.Context, db *mongo.Database) error {
+	balances := db.Collection("stock_balances")
+	layers := db.Collection("cost_layers")
+	cur, err := balances.Find(ctx, bson.M{"avg_cost": bson.M{"$exists": false}})
+	if err != nil { return err }
+	var items []models.StockBalance
+	if err := cur.All(ctx, &items); err != nil { return err }
+	now := time.Now().UTC()
+	for _, b := range items {
+		var p struct{ CostPrice float64 `bson:"cost_price"` }
+		_ = db.Collection("products").FindOne(ctx, bson.M{"_id": b.ProductID}, options.FindOne().SetProjection(bson.M{"cost_price": 1})).Decode(&p)
+		value := math.Round(b.Qty*p.CostPrice*100) / 100
+		if _, err := balances.UpdateOne(ctx, bson.M{"_id": b.ID}, bson.M{"$set": bson.M{"avg_cost": p.CostPrice, "value": value}}); err != nil { return err }
+		if b.Qty <= 0 { continue }
+		layer := models.CostLayer{TenantID: b.TenantID, ProductID: b.ProductID, ShopID: b.ShopID, Qty: b.Qty, Left: b.Qty, UnitCost: p.CostPrice, SourceType: models.StockSourceOpening, CreatedAt: now}
+		if _, err := layers.InsertOne(ctx, layer); err != nil { return err }
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -18,11 +19,11 @@ func NewAttributeHandler(svc *services.AttributeService) *AttributeHandler {
 }
 
 func (h *AttributeHandler) Register(r fiber.Router) {
-	r.Get("/attributes", h.List)
-	r.Get("/attributes/:id", h.Get)
-	r.Post("/attributes", h.Create)
-	r.Patch("/attributes/:id", h.Update)
-	r.Delete("/attributes/:id", h.Delete)
+	r.Get("/attributes", middleware.RequirePermission("products.attributes.access"), h.List)
+	r.Get("/attributes/:id", middleware.RequirePermission("products.attributes.access"), h.Get)
+	r.Post("/attributes", middleware.RequirePermission("products.attributes.create"), h.Create)
+	r.Patch("/attributes/:id", middleware.RequirePermission("products.attributes.update"), h.Update)
+	r.Delete("/attributes/:id", middleware.RequirePermission("products.attributes.delete"), h.Delete)
 }
 
 func (h *AttributeHandler) List(c *fiber.Ctx) error {
@@ -36,7 +37,8 @@ func (h *AttributeHandler) List(c *fiber.Ctx) error {
 		isActivePtr = &b
 	}
 
-	items, total, err := h.svc.List(c.Context(), page, limit, search, isActivePtr)
+	tenantID := c.Locals("tenant_id").(string)
+	items, total, err := h.svc.List(c.Context(), page, limit, search, isActivePtr, tenantID)
 	if err != nil {
 		return err
 	}
@@ -51,7 +53,9 @@ func (h *AttributeHandler) List(c *fiber.Ctx) error {
 
 func (h *AttributeHandler) Get(c *fiber.Ctx) error {
 	id := c.Params("id")
-	item, err := h.svc.Get(c.Context(), id)
+	tenantID := c.Locals("tenant_id").(string)
+
+	item, err := h.svc.Get(c.Context(), id, tenantID)
 	if err != nil {
 		return err
 	}
@@ -64,7 +68,8 @@ func (h *AttributeHandler) Create(c *fiber.Ctx) error {
 		return utils.BadRequest("INVALID_BODY", "Invalid request body", err)
 	}
 
-	item, err := h.svc.Create(c.Context(), body)
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Create(c.Context(), body, tenantID)
 	if err != nil {
 		return err
 	}
@@ -81,7 +86,8 @@ func (h *AttributeHandler) Update(c *fiber.Ctx) error {
 		return utils.BadRequest("INVALID_BODY", "Invalid request body", err)
 	}
 
-	item, err := h.svc.Update(c.Context(), id, body)
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Update(c.Context(), id, body, tenantID)
 	if err != nil {
 		return err
 	}
@@ -91,7 +97,9 @@ func (h *AttributeHandler) Update(c *fiber.Ctx) error {
 
 func (h *AttributeHandler) Delete(c *fiber.Ctx) error {
 	id := c.Params("id")
-	if err := h.svc.Delete(c.Context(), id); err != nil {
+	tenantID := c.Locals("tenant_id").(string)
+
+	if err := h.svc.Delete(c.Context(), id, tenantID); err != nil {
 		return err
 	}
 	return utils.NoContent(c)
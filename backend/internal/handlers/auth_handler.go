@@ -12,7 +12,11 @@ type AuthHandler struct { svc *services.AuthService }
 func NewAuthHandler(svc *services.AuthService) *AuthHandler { return &AuthHandler{svc: svc} }
 
 // Register only public endpoints
-func (h *AuthHandler) Register(r fiber.Router) { r.Post("/auth/login", h.Login) }
+func (h *AuthHandler) Register(r fiber.Router) {
+	r.Post("/auth/login", h.Login)
+	r.Post("/auth/refresh", h.Refresh)
+	r.Post("/auth/logout", h.Logout)
+}
 
 // RegisterProtected attaches endpoints that require auth middleware
 func (h *AuthHandler) RegisterProtected(r fiber.Router) { r.Get("/auth/me", h.Me) }
@@ -20,11 +24,26 @@ func (h *AuthHandler) RegisterProtected(r fiber.Router) { r.Get("/auth/me", h.Me
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	var req models.LoginRequest
 	if err := c.BodyParser(&req); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
-	resp, err := h.svc.Login(c.Context(), req.Email, req.Password)
+	resp, err := h.svc.Login(c.Context(), req.Email, req.Password, c.Get("X-Tenant-ID"))
 	if err != nil { return err }
 	return utils.Success(c, resp)
 }
 
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req models.RefreshRequest
+	if err := c.BodyParser(&req); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	resp, err := h.svc.Refresh(c.Context(), req.RefreshToken)
+	if err != nil { return err }
+	return utils.Success(c, resp)
+}
+
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	var req models.LogoutRequest
+	if err := c.BodyParser(&req); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	if err := h.svc.Logout(c.Context(), req.RefreshToken, req.All); err != nil { return err }
+	return utils.NoContent(c)
+}
+
 func (h *AuthHandler) Me(c *fiber.Ctx) error {
 	v := c.Locals("user")
 	if v == nil { return utils.Unauthorized("UNAUTHORIZED", "Unauthorized", nil) }
@@ -3,6 +3,7 @@ package handlers
 import (
 	"strconv"
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -13,8 +14,8 @@ type BillingHandler struct { payments *services.PaymentService }
 func NewBillingHandler(pay *services.PaymentService) *BillingHandler { return &BillingHandler{ payments: pay } }
 
 func (h *BillingHandler) Register(r fiber.Router) {
-	r.Get("/billing/payments", h.ListPayments)
-	r.Post("/billing/payments", h.CreatePayment)
+	r.Get("/billing/payments", middleware.RequirePermission(middleware.PermissionAll), h.ListPayments)
+	r.Post("/billing/payments", middleware.RequirePermission(middleware.PermissionAll), h.CreatePayment)
 }
 
 func (h *BillingHandler) ListPayments(c *fiber.Ctx) error {
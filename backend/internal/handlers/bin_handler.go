@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
+	"shop/backend/internal/models"
+	"shop/backend/internal/services"
+	"shop/backend/internal/utils"
+)
+
+// BinHandler serves the location hierarchy of stores and warehouses, the stock in their bins, putaways and pick
+// lists. Locations hang off /warehouses/:id, where id is a warehouse or a store.
+type BinHandler struct {
+	bins     *services.BinService
+	putaways *services.PutawayService
+	picks    *services.PickListService
+}
+
+func NewBinHandler(bins *services.BinService, putaways *services.PutawayService, picks *services.PickListService) *BinHandler {
+	return &BinHandler{bins: bins, putaways: putaways, picks: picks}
+}
+
+func (h *BinHandler) Register(r fiber.Router) {
+	r.Get("/warehouses/:id/locations", middleware.RequirePermission("products.warehouses.access"), h.Locations)
+	r.Post("/warehouses/:id/locations", middleware.RequirePermission("products.warehouses.create"), h.CreateLocation)
+	r.Get("/warehouses/:id/bin-stock", middleware.RequirePermission("products.warehouses.access"), h.Stock)
+	r.Get("/locations/:id", middleware.RequirePermission("products.warehouses.access"), h.Location)
+	r.Patch("/locations/:id", middleware.RequirePermission("products.warehouses.update"), h.UpdateLocation)
+	r.Delete("/locations/:id", middleware.RequirePermission("products.warehouses.delete"), h.DeleteLocation)
+
+	r.Get("/putaways", middleware.RequirePermission("products.putaway.access"), h.ListPutaways)
+	r.Get("/putaways/:id", middleware.RequirePermission("products.putaway.access"), h.GetPutaway)
+	r.Post("/putaways", middleware.RequirePermission("products.putaway.create"), h.CreatePutaway)
+	r.Patch("/putaways/:id", middleware.RequirePermission("products.putaway.update"), h.UpdatePutaway)
+	r.Delete("/putaways/:id", middleware.RequirePermission("products.putaway.delete"), h.DeletePutaway)
+
+	r.Get("/pick-lists/sales/:id", middleware.RequirePermission("products.putaway.access"), h.SalePickList)
+	r.Get("/pick-lists/transfers/:id", middleware.RequirePermission("products.putaway.access"), h.TransferPickList)
+}
+
+// Locations lists the locations of a warehouse or store in path order, optionally of one kind.
+func (h *BinHandler) Locations(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	items, err := h.bins.Locations(c.Context(), tenantID, c.Params("id"), c.Query("kind", ""))
+	if err != nil { return err }
+	return utils.Success(c, items)
+}
+
+func (h *BinHandler) CreateLocation(c *fiber.Ctx) error {
+	var body models.CreateWarehouseLocationRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.bins.CreateLocation(c.Context(), tenantID, c.Params("id"), body)
+	if err != nil { return err }
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse[models.WarehouseLocation]{Data: *m})
+}
+
+// Stock reports what the bins hold, optionally under one location_id and of one product_id.
+func (h *BinHandler) Stock(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	items, err := h.bins.Stock(c.Context(), tenantID, c.Params("id"), c.Query("location_id", ""), c.Query("product_id", ""))
+	if err != nil { return err }
+	return utils.Success(c, items)
+}
+
+func (h *BinHandler) Location(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.bins.Location(c.Context(), tenantID, c.Params("id"))
+	if err != nil { return err }
+	return utils.Success(c, m)
+}
+
+func (h *BinHandler) UpdateLocation(c *fiber.Ctx) error {
+	var body models.UpdateWarehouseLocationRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.bins.UpdateLocation(c.Context(), tenantID, c.Params("id"), body)
+	if err != nil { return err }
+	return utils.Success(c, m)
+}
+
+func (h *BinHandler) DeleteLocation(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	if err := h.bins.DeleteLocation(c.Context(), tenantID, c.Params("id")); err != nil { return err }
+	return utils.NoContent(c)
+}
+
+func (h *BinHandler) ListPutaways(c *fiber.Ctx) error {
+	var f models.PutawayFilterRequest
+	_ = c.QueryParser(&f)
+	if f.ShopID == "" { f.ShopID = c.Query("shop_id", "") }
+	if f.OrderID == "" { f.OrderID = c.Query("order_id", "") }
+	tenantID := c.Locals("tenant_id").(string)
+	items, total, err := h.putaways.List(c.Context(), f, tenantID)
+	if err != nil { return err }
+	return c.JSON(utils.SuccessResponse[utils.Paginated[models.Putaway]]{Data: utils.Paginated[models.Putaway]{Items: items, Total: total}})
+}
+
+func (h *BinHandler) GetPutaway(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.putaways.Get(c.Context(), c.Params("id"), tenantID)
+	if err != nil { return err }
+	return utils.Success(c, m)
+}
+
+func (h *BinHandler) CreatePutaway(c *fiber.Ctx) error {
+	var body models.CreatePutawayRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.putaways.Create(c.Context(), body, tenantID, saleActor(c))
+	if err != nil { return err }
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse[models.Putaway]{Data: *m})
+}
+
+// UpdatePutaway changes the bins or comment of a NEW putaway, or completes or cancels it with "action".
+func (h *BinHandler) UpdatePutaway(c *fiber.Ctx) error {
+	var body models.UpdatePutawayRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.putaways.Update(c.Context(), c.Params("id"), body, tenantID, saleActor(c))
+	if err != nil { return err }
+	return utils.Success(c, m)
+}
+
+func (h *BinHandler) DeletePutaway(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	if err := h.putaways.Delete(c.Context(), c.Params("id"), tenantID); err != nil { return err }
+	return utils.NoContent(c)
+}
+
+func (h *BinHandler) SalePickList(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.picks.Sale(c.Context(), c.Params("id"), tenantID)
+	if err != nil { return err }
+	return utils.Success(c, m)
+}
+
+func (h *BinHandler) TransferPickList(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.picks.Transfer(c.Context(), c.Params("id"), tenantID)
+	if err != nil { return err }
+	return utils.Success(c, m)
+}
@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -18,11 +19,11 @@ func NewBrandHandler(svc *services.BrandService) *BrandHandler {
 }
 
 func (h *BrandHandler) Register(r fiber.Router) {
-	r.Get("/brands", h.List)
-	r.Get("/brands/:id", h.Get)
-	r.Post("/brands", h.Create)
-	r.Patch("/brands/:id", h.Update)
-	r.Delete("/brands/:id", h.Delete)
+	r.Get("/brands", middleware.RequirePermission("products.brands.access"), h.List)
+	r.Get("/brands/:id", middleware.RequirePermission("products.brands.access"), h.Get)
+	r.Post("/brands", middleware.RequirePermission("products.brands.create"), h.Create)
+	r.Patch("/brands/:id", middleware.RequirePermission("products.brands.update"), h.Update)
+	r.Delete("/brands/:id", middleware.RequirePermission("products.brands.delete"), h.Delete)
 }
 
 func (h *BrandHandler) List(c *fiber.Ctx) error {
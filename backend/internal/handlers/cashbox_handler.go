@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"strconv"
+	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
+	"shop/backend/internal/models"
+	"shop/backend/internal/services"
+	"shop/backend/internal/utils"
+)
+
+type CashboxHandler struct { svc *services.CashboxService }
+
+func NewCashboxHandler(svc *services.CashboxService) *CashboxHandler { return &CashboxHandler{ svc: svc } }
+
+func (h *CashboxHandler) Register(r fiber.Router) {
+	r.Get("/cashbox/shifts", middleware.RequirePermission("sales.cashbox.shifts.access"), h.List)
+	r.Get("/cashbox/shifts/current", middleware.RequirePermission("sales.cashbox.shifts.access"), h.Current)
+	r.Get("/cashbox/shifts/:id", middleware.RequirePermission("sales.cashbox.shifts.access"), h.Get)
+	r.Post("/cashbox/shifts", middleware.RequirePermission("sales.cashbox.shifts.create"), h.Open)
+	r.Post("/cashbox/shifts/:id/close", middleware.RequirePermission("sales.cashbox.shifts.update"), h.Close)
+	r.Get("/cashbox/shifts/:id/x-report", middleware.RequirePermission("sales.cashbox.shifts.access"), h.XReport)
+	r.Get("/cashbox/shifts/:id/z-report", middleware.RequirePermission("sales.cashbox.shifts.access"), h.ZReport)
+	r.Get("/cashbox/shifts/:id/operations", middleware.RequirePermission("sales.cashbox.operations.access"), h.Operations)
+	r.Post("/cashbox/shifts/:id/operations", middleware.RequirePermission("sales.cashbox.operations.create"), h.AddOperation)
+}
+
+func (h *CashboxHandler) List(c *fiber.Ctx) error {
+	var f models.CashShiftFilterRequest
+	f.ShopID = c.Query("shop_id", "")
+	f.CashboxID = c.Query("cashbox_id", "")
+	f.Status = c.Query("status", "")
+	f.UserID = c.Query("user_id", "")
+	f.DateFrom = c.Query("date_from", "")
+	f.DateTo = c.Query("date_to", "")
+	if p, err := strconv.Atoi(c.Query("page", "1")); err == nil { f.Page = p }
+	if l, err := strconv.Atoi(c.Query("limit", "20")); err == nil { f.Limit = l }
+	tenantID := c.Locals("tenant_id").(string)
+	items, total, err := h.svc.List(c.Context(), f, tenantID)
+	if err != nil { return err }
+	return c.JSON(utils.SuccessResponse[utils.Paginated[models.CashShift]]{ Data: utils.Paginated[models.CashShift]{ Items: items, Total: total } })
+}
+
+func (h *CashboxHandler) Current(c *fiber.Ctx) error {
+	shopID := c.Query("shop_id", "")
+	if shopID == "" { if s, ok := c.Locals("store_id").(string); ok { shopID = s } }
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Current(c.Context(), shopID, tenantID, saleActor(c))
+	if err != nil { return err }
+	return utils.Success(c, item)
+}
+
+func (h *CashboxHandler) Get(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Get(c.Context(), c.Params("id"), tenantID)
+	if err != nil { return err }
+	return utils.Success(c, item)
+}
+
+func (h *CashboxHandler) Open(c *fiber.Ctx) error {
+	var body models.OpenCashShiftRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	if body.ShopID == "" { if s, ok := c.Locals("store_id").(string); ok { body.ShopID = s } }
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Open(c.Context(), body, tenantID, saleActor(c))
+	if err != nil { return err }
+	return utils.Created(c, item)
+}
+
+func (h *CashboxHandler) Close(c *fiber.Ctx) error {
+	var body models.CloseCashShiftRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Close(c.Context(), c.Params("id"), body, tenantID, saleActor(c))
+	if err != nil { return err }
+	return utils.Success(c, item)
+}
+
+func (h *CashboxHandler) XReport(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.XReport(c.Context(), c.Params("id"), tenantID)
+	if err != nil { return err }
+	return utils.Success(c, item)
+}
+
+func (h *CashboxHandler) ZReport(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.ZReport(c.Context(), c.Params("id"), tenantID)
+	if err != nil { return err }
+	return utils.Success(c, item)
+}
+
+func (h *CashboxHandler) Operations(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	items, err := h.svc.Operations(c.Context(), c.Params("id"), tenantID)
+	if err != nil { return err }
+	return utils.Success(c, items)
+}
+
+func (h *CashboxHandler) AddOperation(c *fiber.Ctx) error {
+	var body models.CreateCashOperationRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.AddOperation(c.Context(), c.Params("id"), body, tenantID, saleActor(c))
+	if err != nil { return err }
+	return utils.Created(c, item)
+}
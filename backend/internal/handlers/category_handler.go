@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -18,12 +19,12 @@ func NewCategoryHandler(svc *services.CategoryService) *CategoryHandler {
 }
 
 func (h *CategoryHandler) Register(r fiber.Router) {
-	r.Get("/categories", h.List)
-	r.Get("/categories/tree", h.GetTree)
-	r.Get("/categories/:id", h.Get)
-	r.Post("/categories", h.Create)
-	r.Patch("/categories/:id", h.Update)
-	r.Delete("/categories/:id", h.Delete)
+	r.Get("/categories", middleware.RequirePermission("products.categories.access"), h.List)
+	r.Get("/categories/tree", middleware.RequirePermission("products.categories.access"), h.GetTree)
+	r.Get("/categories/:id", middleware.RequirePermission("products.categories.access"), h.Get)
+	r.Post("/categories", middleware.RequirePermission("products.categories.create"), h.Create)
+	r.Patch("/categories/:id", middleware.RequirePermission("products.categories.update"), h.Update)
+	r.Delete("/categories/:id", middleware.RequirePermission("products.categories.delete"), h.Delete)
 }
 
 func (h *CategoryHandler) List(c *fiber.Ctx) error {
@@ -51,7 +52,8 @@ func (h *CategoryHandler) List(c *fiber.Ctx) error {
 		}
 	}
 
-	items, total, err := h.svc.List(c.Context(), page, limit, search, isActivePtr, parentIDPtr, levelPtr)
+	tenantID := c.Locals("tenant_id").(string)
+	items, total, err := h.svc.List(c.Context(), page, limit, search, isActivePtr, parentIDPtr, levelPtr, tenantID)
 	if err != nil {
 		return err
 	}
@@ -65,7 +67,8 @@ func (h *CategoryHandler) List(c *fiber.Ctx) error {
 }
 
 func (h *CategoryHandler) GetTree(c *fiber.Ctx) error {
-	tree, err := h.svc.GetTree(c.Context())
+	tenantID := c.Locals("tenant_id").(string)
+	tree, err := h.svc.GetTree(c.Context(), tenantID)
 	if err != nil {
 		return err
 	}
@@ -74,7 +77,9 @@ func (h *CategoryHandler) GetTree(c *fiber.Ctx) error {
 
 func (h *CategoryHandler) Get(c *fiber.Ctx) error {
 	id := c.Params("id")
-	item, err := h.svc.Get(c.Context(), id)
+	tenantID := c.Locals("tenant_id").(string)
+
+	item, err := h.svc.Get(c.Context(), id, tenantID)
 	if err != nil {
 		return err
 	}
@@ -87,7 +92,8 @@ func (h *CategoryHandler) Create(c *fiber.Ctx) error {
 		return utils.BadRequest("INVALID_BODY", "Invalid request body", err)
 	}
 
-	item, err := h.svc.Create(c.Context(), body)
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Create(c.Context(), body, tenantID)
 	if err != nil {
 		return err
 	}
@@ -104,7 +110,8 @@ func (h *CategoryHandler) Update(c *fiber.Ctx) error {
 		return utils.BadRequest("INVALID_BODY", "Invalid request body", err)
 	}
 
-	item, err := h.svc.Update(c.Context(), id, body)
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Update(c.Context(), id, body, tenantID)
 	if err != nil {
 		return err
 	}
@@ -114,7 +121,9 @@ func (h *CategoryHandler) Update(c *fiber.Ctx) error {
 
 func (h *CategoryHandler) Delete(c *fiber.Ctx) error {
 	id := c.Params("id")
-	if err := h.svc.Delete(c.Context(), id); err != nil {
+	tenantID := c.Locals("tenant_id").(string)
+
+	if err := h.svc.Delete(c.Context(), id, tenantID); err != nil {
 		return err
 	}
 	return utils.NoContent(c)
@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -18,11 +19,11 @@ func NewCharacteristicHandler(svc *services.CharacteristicService) *Characterist
 }
 
 func (h *CharacteristicHandler) Register(r fiber.Router) {
-	r.Get("/characteristics", h.List)
-	r.Get("/characteristics/:id", h.Get)
-	r.Post("/characteristics", h.Create)
-	r.Patch("/characteristics/:id", h.Update)
-	r.Delete("/characteristics/:id", h.Delete)
+	r.Get("/characteristics", middleware.RequirePermission("products.characteristics.access"), h.List)
+	r.Get("/characteristics/:id", middleware.RequirePermission("products.characteristics.access"), h.Get)
+	r.Post("/characteristics", middleware.RequirePermission("products.characteristics.create"), h.Create)
+	r.Patch("/characteristics/:id", middleware.RequirePermission("products.characteristics.update"), h.Update)
+	r.Delete("/characteristics/:id", middleware.RequirePermission("products.characteristics.delete"), h.Delete)
 }
 
 func (h *CharacteristicHandler) List(c *fiber.Ctx) error {
@@ -37,7 +38,8 @@ func (h *CharacteristicHandler) List(c *fiber.Ctx) error {
 		isActivePtr = &b
 	}
 
-	items, total, err := h.svc.List(c.Context(), page, limit, search, isActivePtr, characteristicType)
+	tenantID := c.Locals("tenant_id").(string)
+	items, total, err := h.svc.List(c.Context(), page, limit, search, isActivePtr, characteristicType, tenantID)
 	if err != nil {
 		return err
 	}
@@ -52,7 +54,9 @@ func (h *CharacteristicHandler) List(c *fiber.Ctx) error {
 
 func (h *CharacteristicHandler) Get(c *fiber.Ctx) error {
 	id := c.Params("id")
-	item, err := h.svc.Get(c.Context(), id)
+	tenantID := c.Locals("tenant_id").(string)
+
+	item, err := h.svc.Get(c.Context(), id, tenantID)
 	if err != nil {
 		return err
 	}
@@ -65,7 +69,8 @@ func (h *CharacteristicHandler) Create(c *fiber.Ctx) error {
 		return utils.BadRequest("INVALID_BODY", "Invalid request body", err)
 	}
 
-	item, err := h.svc.Create(c.Context(), body)
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Create(c.Context(), body, tenantID)
 	if err != nil {
 		return err
 	}
@@ -82,7 +87,8 @@ func (h *CharacteristicHandler) Update(c *fiber.Ctx) error {
 		return utils.BadRequest("INVALID_BODY", "Invalid request body", err)
 	}
 
-	item, err := h.svc.Update(c.Context(), id, body)
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Update(c.Context(), id, body, tenantID)
 	if err != nil {
 		return err
 	}
@@ -92,7 +98,9 @@ func (h *CharacteristicHandler) Update(c *fiber.Ctx) error {
 
 func (h *CharacteristicHandler) Delete(c *fiber.Ctx) error {
 	id := c.Params("id")
-	if err := h.svc.Delete(c.Context(), id); err != nil {
+	tenantID := c.Locals("tenant_id").(string)
+
+	if err := h.svc.Delete(c.Context(), id, tenantID); err != nil {
 		return err
 	}
 	return utils.NoContent(c)
@@ -3,6 +3,7 @@ package handlers
 import (
 	"strconv"
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -16,11 +17,11 @@ func NewCompanyHandler(svc *services.CompanyService) *CompanyHandler { return &C
 func NewStoreHandler(svc *services.StoreService) *StoreHandler { return &StoreHandler{svc: svc} }
 
 func (h *CompanyHandler) Register(r fiber.Router) {
-	r.Get("/companies", h.List)
-	r.Get("/companies/:id", h.Get)
-	r.Post("/companies", h.Create)
-	r.Patch("/companies/:id", h.Update)
-	r.Delete("/companies/:id", h.Delete)
+	r.Get("/companies", middleware.RequirePermission("settings.company.access"), h.List)
+	r.Get("/companies/:id", middleware.RequirePermission("settings.company.access"), h.Get)
+	r.Post("/companies", middleware.RequirePermission("settings.company.create"), h.Create)
+	r.Patch("/companies/:id", middleware.RequirePermission("settings.company.update"), h.Update)
+	r.Delete("/companies/:id", middleware.RequirePermission("settings.company.delete"), h.Delete)
 }
 
 func (h *CompanyHandler) List(c *fiber.Ctx) error {
@@ -63,11 +64,11 @@ func (h *CompanyHandler) Delete(c *fiber.Ctx) error {
 }
 
 func (h *StoreHandler) Register(r fiber.Router) {
-	r.Get("/stores", h.List)
-	r.Get("/stores/:id", h.Get)
-	r.Post("/stores", h.Create)
-	r.Patch("/stores/:id", h.Update)
-	r.Delete("/stores/:id", h.Delete)
+	r.Get("/stores", middleware.RequirePermission("settings.stores.access"), h.List)
+	r.Get("/stores/:id", middleware.RequirePermission("settings.stores.access"), h.Get)
+	r.Post("/stores", middleware.RequirePermission("settings.stores.create"), h.Create)
+	r.Patch("/stores/:id", middleware.RequirePermission("settings.stores.update"), h.Update)
+	r.Delete("/stores/:id", middleware.RequirePermission("settings.stores.delete"), h.Delete)
 }
 
 func (h *StoreHandler) List(c *fiber.Ctx) error {
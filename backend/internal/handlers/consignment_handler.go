@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
+	"shop/backend/internal/models"
+	"shop/backend/internal/services"
+	"shop/backend/internal/utils"
+)
+
+type ConsignmentHandler struct { svc *services.ConsignmentSettlementService }
+
+func NewConsignmentHandler(svc *services.ConsignmentSettlementService) *ConsignmentHandler { return &ConsignmentHandler{svc: svc} }
+
+func (h *ConsignmentHandler) Register(r fiber.Router) {
+	r.Get("/consignment/stock", middleware.RequirePermission("products.consignment.access"), h.Stock)
+	r.Post("/consignment/returns", middleware.RequirePermission("products.consignment.create"), h.CreateReturn)
+	r.Get("/consignment/settlements", middleware.RequirePermission("products.consignment.access"), h.List)
+	r.Get("/consignment/settlements/:id", middleware.RequirePermission("products.consignment.access"), h.Get)
+	r.Post("/consignment/settlements", middleware.RequirePermission("products.consignment.create"), h.Create)
+	r.Patch("/consignment/settlements/:id", middleware.RequirePermission("products.consignment.update"), h.Update)
+	r.Delete("/consignment/settlements/:id", middleware.RequirePermission("products.consignment.delete"), h.Delete)
+}
+
+func (h *ConsignmentHandler) List(c *fiber.Ctx) error {
+	var f models.ConsignmentSettlementFilterRequest
+	_ = c.QueryParser(&f)
+	if f.SupplierID == "" { f.SupplierID = c.Query("supplier_id", "") }
+	tenantID := c.Locals("tenant_id").(string)
+	items, total, err := h.svc.List(c.Context(), f, tenantID)
+	if err != nil { return err }
+	return c.JSON(utils.SuccessResponse[utils.Paginated[models.ConsignmentSettlement]]{Data: utils.Paginated[models.ConsignmentSettlement]{Items: items, Total: total}})
+}
+
+func (h *ConsignmentHandler) Get(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.svc.Get(c.Context(), c.Params("id"), tenantID)
+	if err != nil { return err }
+	return utils.Success(c, m)
+}
+
+func (h *ConsignmentHandler) Create(c *fiber.Ctx) error {
+	var body models.CreateConsignmentSettlementRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.svc.Create(c.Context(), body, tenantID, saleActor(c))
+	if err != nil { return err }
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse[models.ConsignmentSettlement]{Data: *m})
+}
+
+// Update changes the comment of a NEW settlement, or approves or rejects it with "action".
+func (h *ConsignmentHandler) Update(c *fiber.Ctx) error {
+	var body models.UpdateConsignmentSettlementRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.svc.Update(c.Context(), c.Params("id"), body, tenantID, saleActor(c))
+	if err != nil { return err }
+	return utils.Success(c, m)
+}
+
+func (h *ConsignmentHandler) Delete(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	if err := h.svc.Delete(c.Context(), c.Params("id"), tenantID); err != nil { return err }
+	return utils.NoContent(c)
+}
+
+// Stock reports the consignment goods on hand per supplier, optionally of one supplier_id or shop_id.
+func (h *ConsignmentHandler) Stock(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	r, err := h.svc.Stock(c.Context(), tenantID, c.Query("supplier_id", ""), c.Query("shop_id", ""))
+	if err != nil { return err }
+	return utils.Success(c, r)
+}
+
+// CreateReturn drafts return orders, one per settlement type, of a supplier's consignment goods past their return
+// date in a store.
+func (h *ConsignmentHandler) CreateReturn(c *fiber.Ctx) error {
+	var body models.CreateConsignmentReturnRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	user := models.OrderUser{}
+	if u, ok := c.Locals("user").(*models.User); ok { user = models.OrderUser{ID: u.ID.Hex(), Name: u.Name} }
+	items, err := h.svc.CreateReturn(c.Context(), body, tenantID, user)
+	if err != nil { return err }
+	return utils.Created(c, items)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
+	"shop/backend/internal/models"
+	"shop/backend/internal/services"
+	"shop/backend/internal/utils"
+)
+
+type CoreHandler struct { svc *services.CoreService }
+
+func NewCoreHandler(svc *services.CoreService) *CoreHandler { return &CoreHandler{svc: svc} }
+
+func (h *CoreHandler) Register(r fiber.Router) {
+	r.Get("/cores/stock", middleware.RequirePermission("products.cores.access"), h.Stock)
+	r.Get("/cores/customers", middleware.RequirePermission("products.cores.access"), h.Customers)
+	r.Get("/cores/suppliers", middleware.RequirePermission("products.cores.access"), h.Suppliers)
+	r.Post("/cores/returns", middleware.RequirePermission("products.cores.create"), h.CreateReturn)
+}
+
+// Stock lists the dirty cores in the core inventory, optionally of one shop_id.
+func (h *CoreHandler) Stock(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	items, err := h.svc.Stock(c.Context(), tenantID, c.Query("shop_id", ""))
+	if err != nil { return err }
+	return utils.Success(c, items)
+}
+
+// Customers reports the cores customers still owe back, optionally of one customer_id.
+func (h *CoreHandler) Customers(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	items, err := h.svc.Customers(c.Context(), tenantID, c.Query("customer_id", ""))
+	if err != nil { return err }
+	return utils.Success(c, items)
+}
+
+// Suppliers reports the cores owed back to suppliers, optionally of one supplier_id.
+func (h *CoreHandler) Suppliers(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	items, err := h.svc.Suppliers(c.Context(), tenantID, c.Query("supplier_id", ""))
+	if err != nil { return err }
+	return utils.Success(c, items)
+}
+
+// CreateReturn bundles a supplier's dirty cores in a store into a return order for credit.
+func (h *CoreHandler) CreateReturn(c *fiber.Ctx) error {
+	var body models.CreateCoreReturnRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	user := models.OrderUser{}
+	if u, ok := c.Locals("user").(*models.User); ok { user = models.OrderUser{ID: u.ID.Hex(), Name: u.Name} }
+	m, err := h.svc.CreateReturn(c.Context(), body, tenantID, user)
+	if err != nil { return err }
+	return utils.Created(c, m)
+}
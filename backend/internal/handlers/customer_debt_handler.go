@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"strconv"
+	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
+	"shop/backend/internal/models"
+	"shop/backend/internal/services"
+	"shop/backend/internal/utils"
+)
+
+type CustomerDebtHandler struct { svc *services.CustomerDebtService }
+
+func NewCustomerDebtHandler(svc *services.CustomerDebtService) *CustomerDebtHandler { return &CustomerDebtHandler{ svc: svc } }
+
+func (h *CustomerDebtHandler) Register(r fiber.Router) {
+	r.Get("/customer-debts", middleware.RequirePermission("customers.debts.access"), h.List)
+	r.Get("/customer-debts/debtors", middleware.RequirePermission("customers.debts.access"), h.Debtors)
+	r.Get("/customer-debts/aging", middleware.RequirePermission("customers.debts.access"), h.Aging)
+	r.Get("/customer-debts/reminders", middleware.RequirePermission("customers.debts.access"), h.Reminders)
+	r.Get("/customer-debts/:id", middleware.RequirePermission("customers.debts.access"), h.Get)
+	r.Put("/customer-debts/:id/installments", middleware.RequirePermission("customers.debts.update"), h.Reschedule)
+	r.Get("/customers/:id/debt-ledger", middleware.RequirePermission("customers.debts.access"), h.Ledger)
+	r.Post("/customers/:id/debt-repayments", middleware.RequirePermission("customers.debts.create"), h.Repay)
+}
+
+func (h *CustomerDebtHandler) List(c *fiber.Ctx) error {
+	var f models.CustomerDebtFilterRequest
+	f.CustomerID = c.Query("customer_id", "")
+	f.ShopID = c.Query("shop_id", "")
+	f.Status = c.Query("status", "")
+	f.Overdue = c.QueryBool("overdue", false)
+	if p, err := strconv.Atoi(c.Query("page", "1")); err == nil { f.Page = p }
+	if l, err := strconv.Atoi(c.Query("limit", "20")); err == nil { f.Limit = l }
+
+	tenantID := c.Locals("tenant_id").(string)
+	items, total, err := h.svc.List(c.Context(), f, tenantID)
+	if err != nil { return err }
+	return c.JSON(utils.SuccessResponse[utils.Paginated[models.CustomerDebt]]{ Data: utils.Paginated[models.CustomerDebt]{ Items: items, Total: total } })
+}
+
+func (h *CustomerDebtHandler) Get(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Get(c.Context(), c.Params("id"), tenantID)
+	if err != nil { return err }
+	return utils.Success(c, item)
+}
+
+// Debtors lists customers with open debt in a store, defaulting to the store selected in the client.
+func (h *CustomerDebtHandler) Debtors(c *fiber.Ctx) error {
+	page, _ := strconv.ParseInt(c.Query("page", "1"), 10, 64)
+	limit, _ := strconv.ParseInt(c.Query("limit", "20"), 10, 64)
+	tenantID := c.Locals("tenant_id").(string)
+	items, total, err := h.svc.Debtors(c.Context(), debtShop(c), page, limit, tenantID)
+	if err != nil { return err }
+	return c.JSON(utils.SuccessResponse[utils.Paginated[models.Debtor]]{ Data: utils.Paginated[models.Debtor]{ Items: items, Total: total } })
+}
+
+func (h *CustomerDebtHandler) Aging(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Aging(c.Context(), c.Query("shop_id", ""), c.Query("customer_id", ""), tenantID)
+	if err != nil { return err }
+	return utils.Success(c, item)
+}
+
+func (h *CustomerDebtHandler) Reminders(c *fiber.Ctx) error {
+	days, _ := strconv.Atoi(c.Query("days", "3"))
+	tenantID := c.Locals("tenant_id").(string)
+	items, err := h.svc.Reminders(c.Context(), debtShop(c), days, tenantID)
+	if err != nil { return err }
+	return utils.Success(c, items)
+}
+
+func (h *CustomerDebtHandler) Reschedule(c *fiber.Ctx) error {
+	var body models.RescheduleDebtRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Reschedule(c.Context(), c.Params("id"), body, tenantID)
+	if err != nil { return err }
+	return utils.Success(c, item)
+}
+
+func (h *CustomerDebtHandler) Ledger(c *fiber.Ctx) error {
+	page, _ := strconv.ParseInt(c.Query("page", "1"), 10, 64)
+	limit, _ := strconv.ParseInt(c.Query("limit", "20"), 10, 64)
+	tenantID := c.Locals("tenant_id").(string)
+	items, total, err := h.svc.Ledger(c.Context(), c.Params("id"), page, limit, tenantID)
+	if err != nil { return err }
+	return c.JSON(utils.SuccessResponse[utils.Paginated[models.CustomerDebtEntry]]{ Data: utils.Paginated[models.CustomerDebtEntry]{ Items: items, Total: total } })
+}
+
+func (h *CustomerDebtHandler) Repay(c *fiber.Ctx) error {
+	var body models.CreateDebtRepaymentRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	if body.ShopID == "" { if s, ok := c.Locals("store_id").(string); ok { body.ShopID = s } }
+	tenantID := c.Locals("tenant_id").(string)
+	items, err := h.svc.Repay(c.Context(), c.Params("id"), body, tenantID, saleActor(c))
+	if err != nil { return err }
+	return utils.Created(c, items)
+}
+
+func debtShop(c *fiber.Ctx) string {
+	if s := c.Query("shop_id", ""); s != "" { return s }
+	s, _ := c.Locals("store_id").(string)
+	return s
+}
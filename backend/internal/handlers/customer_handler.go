@@ -3,6 +3,7 @@ package handlers
 import (
 	"strconv"
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -13,11 +14,11 @@ type CustomerHandler struct { svc *services.CustomerService }
 func NewCustomerHandler(svc *services.CustomerService) *CustomerHandler { return &CustomerHandler{ svc: svc } }
 
 func (h *CustomerHandler) Register(r fiber.Router) {
-	r.Get("/customers", h.List)
-	r.Get("/customers/:id", h.Get)
-	r.Post("/customers", h.Create)
-	r.Patch("/customers/:id", h.Update)
-	r.Delete("/customers/:id", h.Delete)
+	r.Get("/customers", middleware.RequirePermission("customers.list.access"), h.List)
+	r.Get("/customers/:id", middleware.RequirePermission("customers.list.access"), h.Get)
+	r.Post("/customers", middleware.RequirePermission("customers.list.create"), h.Create)
+	r.Patch("/customers/:id", middleware.RequirePermission("customers.list.update"), h.Update)
+	r.Delete("/customers/:id", middleware.RequirePermission("customers.list.delete"), h.Delete)
 }
 
 func (h *CustomerHandler) List(c *fiber.Ctx) error {
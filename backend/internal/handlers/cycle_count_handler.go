@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
+	"shop/backend/internal/models"
+	"shop/backend/internal/services"
+	"shop/backend/internal/utils"
+)
+
+// CycleCountHandler serves cycle count plans, the PARTIAL inventories they generate and count accuracy KPIs.
+type CycleCountHandler struct {
+	svc *services.CycleCountService
+}
+
+func NewCycleCountHandler(svc *services.CycleCountService) *CycleCountHandler {
+	return &CycleCountHandler{svc: svc}
+}
+
+func (h *CycleCountHandler) Register(r fiber.Router) {
+	r.Get("/cycle-counts/plans", middleware.RequirePermission("products.cyclecount.access"), h.List)
+	r.Get("/cycle-counts/plans/:id", middleware.RequirePermission("products.cyclecount.access"), h.Get)
+	r.Post("/cycle-counts/plans", middleware.RequirePermission("products.cyclecount.create"), h.Create)
+	r.Patch("/cycle-counts/plans/:id", middleware.RequirePermission("products.cyclecount.update"), h.Update)
+	r.Delete("/cycle-counts/plans/:id", middleware.RequirePermission("products.cyclecount.delete"), h.Delete)
+	r.Post("/cycle-counts/plans/:id/generate", middleware.RequirePermission("products.inventory.create"), h.Generate)
+	r.Post("/cycle-counts/run-due", middleware.RequirePermission("products.inventory.create"), h.RunDue)
+	r.Get("/cycle-counts/accuracy", middleware.RequirePermission("products.cyclecount.access"), h.Accuracy)
+}
+
+func (h *CycleCountHandler) List(c *fiber.Ctx) error {
+	f := models.CycleCountPlanFilterRequest{ ShopID: c.Query("shop_id", ""), Basis: c.Query("basis", ""), Page: c.QueryInt("page", 1), Limit: c.QueryInt("limit", 20) }
+	tenantID := c.Locals("tenant_id").(string)
+	items, total, err := h.svc.List(c.Context(), f, tenantID)
+	if err != nil { return err }
+	return c.JSON(utils.SuccessResponse[utils.Paginated[models.CycleCountPlan]]{Data: utils.Paginated[models.CycleCountPlan]{Items: items, Total: total}})
+}
+
+func (h *CycleCountHandler) Get(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.svc.Get(c.Context(), c.Params("id"), tenantID)
+	if err != nil { return err }
+	return utils.Success(c, m)
+}
+
+func (h *CycleCountHandler) Create(c *fiber.Ctx) error {
+	var body models.CreateCycleCountPlanRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.svc.Create(c.Context(), body, tenantID, saleActor(c))
+	if err != nil { return err }
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse[models.CycleCountPlan]{Data: *m})
+}
+
+func (h *CycleCountHandler) Update(c *fiber.Ctx) error {
+	var body models.UpdateCycleCountPlanRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.svc.Update(c.Context(), c.Params("id"), body, tenantID)
+	if err != nil { return err }
+	return utils.Success(c, m)
+}
+
+func (h *CycleCountHandler) Delete(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	if err := h.svc.Delete(c.Context(), c.Params("id"), tenantID); err != nil { return err }
+	return utils.NoContent(c)
+}
+
+// Generate creates the plan's next PARTIAL inventory now, whether it is due or not.
+func (h *CycleCountHandler) Generate(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.svc.Generate(c.Context(), c.Params("id"), tenantID, saleActor(c))
+	if err != nil { return err }
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse[models.Inventory]{Data: *m})
+}
+
+// RunDue runs the tenant's due plans without waiting for the hourly schedule.
+func (h *CycleCountHandler) RunDue(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	items, err := h.svc.RunDue(c.Context(), tenantID)
+	if err != nil { return err }
+	return utils.Success(c, items)
+}
+
+// Accuracy reports count accuracy per store and per counter over from..to, by period (day, week or month).
+func (h *CycleCountHandler) Accuracy(c *fiber.Ctx) error {
+	f := models.CountAccuracyRequest{
+		ShopID: c.Query("shop_id", ""), From: c.Query("from", ""), To: c.Query("to", ""), Period: c.Query("period", ""),
+		TolerancePct: c.QueryFloat("tolerance_pct", 0), CycleOnly: c.QueryBool("cycle_only", false),
+	}
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.svc.Accuracy(c.Context(), f, tenantID)
+	if err != nil { return err }
+	return utils.Success(c, m)
+}
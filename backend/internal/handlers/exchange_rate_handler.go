@@ -4,6 +4,7 @@ import (
 	"strconv"
 	"time"
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -14,8 +15,9 @@ type ExchangeRateHandler struct { svc *services.ExchangeRateService }
 func NewExchangeRateHandler(svc *services.ExchangeRateService) *ExchangeRateHandler { return &ExchangeRateHandler{svc: svc} }
 
 func (h *ExchangeRateHandler) Register(r fiber.Router) {
-	r.Get("/exchange-rates", h.List)
-	r.Post("/exchange-rates", h.Create)
+	r.Get("/exchange-rates", middleware.RequirePermission("settings.exchange_rates.access"), h.List)
+	r.Post("/exchange-rates", middleware.RequirePermission("settings.exchange_rates.create"), h.Create)
+	// available to any authenticated user
 	r.Get("/exchange-rates/at", h.GetAt)
 }
 
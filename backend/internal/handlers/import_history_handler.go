@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -14,9 +15,9 @@ func NewImportHistoryHandler(svc *services.ImportHistoryService) *ImportHistoryH
 
 func (h *ImportHistoryHandler) Register(protected fiber.Router) {
 	grp := protected.Group("/import-history")
-	grp.Get("/products", h.List)
-	grp.Post("/products", h.Create)
-	grp.Get("/products/:id", h.Get)
+	grp.Get("/products", middleware.RequirePermission("products.import.access"), h.List)
+	grp.Post("/products", middleware.RequirePermission("products.import.create"), h.Create)
+	grp.Get("/products/:id", middleware.RequirePermission("products.import.access"), h.Get)
 }
 
 func (h *ImportHistoryHandler) List(c *fiber.Ctx) error {
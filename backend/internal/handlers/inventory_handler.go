@@ -3,6 +3,7 @@ package handlers
 import (
 	"strconv"
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -13,11 +14,11 @@ type InventoryHandler struct { svc *services.InventoryService }
 func NewInventoryHandler(svc *services.InventoryService) *InventoryHandler { return &InventoryHandler{ svc: svc } }
 
 func (h *InventoryHandler) Register(r fiber.Router) {
-	r.Get("/inventories", h.List)
-	r.Get("/inventories/:id", h.Get)
-	r.Post("/inventories", h.Create)
-	r.Patch("/inventories/:id", h.Update)
-	r.Delete("/inventories/:id", h.Delete)
+	r.Get("/inventories", middleware.RequirePermission("products.inventory.access"), h.List)
+	r.Get("/inventories/:id", middleware.RequirePermission("products.inventory.access"), h.Get)
+	r.Post("/inventories", middleware.RequirePermission("products.inventory.create"), h.Create)
+	r.Patch("/inventories/:id", middleware.RequirePermission("products.inventory.update"), h.Update)
+	r.Delete("/inventories/:id", middleware.RequirePermission("products.inventory.delete"), h.Delete)
 }
 
 func (h *InventoryHandler) List(c *fiber.Ctx) error {
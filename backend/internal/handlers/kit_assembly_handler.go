@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
+	"shop/backend/internal/models"
+	"shop/backend/internal/services"
+	"shop/backend/internal/utils"
+)
+
+type KitAssemblyHandler struct { svc *services.KitAssemblyService }
+
+func NewKitAssemblyHandler(svc *services.KitAssemblyService) *KitAssemblyHandler { return &KitAssemblyHandler{svc: svc} }
+
+func (h *KitAssemblyHandler) Register(r fiber.Router) {
+	r.Get("/kit-assemblies", middleware.RequirePermission("products.kits.access"), h.List)
+	r.Get("/kit-assemblies/:id", middleware.RequirePermission("products.kits.access"), h.Get)
+	r.Post("/kit-assemblies", middleware.RequirePermission("products.kits.create"), h.Create)
+	r.Patch("/kit-assemblies/:id", middleware.RequirePermission("products.kits.update"), h.Update)
+	r.Delete("/kit-assemblies/:id", middleware.RequirePermission("products.kits.delete"), h.Delete)
+}
+
+func (h *KitAssemblyHandler) List(c *fiber.Ctx) error {
+	var f models.KitAssemblyFilterRequest
+	_ = c.QueryParser(&f)
+	if f.ShopID == "" { f.ShopID = c.Query("shop_id", "") }
+	if f.ProductID == "" { f.ProductID = c.Query("product_id", "") }
+	tenantID := c.Locals("tenant_id").(string)
+	items, total, err := h.svc.List(c.Context(), f, tenantID)
+	if err != nil { return err }
+	return c.JSON(utils.SuccessResponse[utils.Paginated[models.KitAssembly]]{Data: utils.Paginated[models.KitAssembly]{Items: items, Total: total}})
+}
+
+func (h *KitAssemblyHandler) Get(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.svc.Get(c.Context(), c.Params("id"), tenantID)
+	if err != nil { return err }
+	return utils.Success(c, m)
+}
+
+func (h *KitAssemblyHandler) Create(c *fiber.Ctx) error {
+	var body models.CreateKitAssemblyRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.svc.Create(c.Context(), body, tenantID, saleActor(c))
+	if err != nil { return err }
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse[models.KitAssembly]{Data: *m})
+}
+
+// Update changes the quantity or comment of a NEW document, or approves or rejects it with "action".
+func (h *KitAssemblyHandler) Update(c *fiber.Ctx) error {
+	var body models.UpdateKitAssemblyRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.svc.Update(c.Context(), c.Params("id"), body, tenantID, saleActor(c))
+	if err != nil { return err }
+	return utils.Success(c, m)
+}
+
+func (h *KitAssemblyHandler) Delete(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	if err := h.svc.Delete(c.Context(), c.Params("id"), tenantID); err != nil { return err }
+	return utils.NoContent(c)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -16,16 +17,16 @@ type LeadHandler struct { svc *services.LeadService }
 func NewLeadHandler(svc *services.LeadService) *LeadHandler { return &LeadHandler{ svc: svc } }
 
 func (h *LeadHandler) Register(r fiber.Router) {
-	r.Get("/leads", h.List)
-	r.Get("/leads/:id", h.Get)
-	r.Post("/leads", h.Create)
-	r.Patch("/leads/:id", h.Update)
-	r.Put("/leads/:id/status", h.UpdateStatus)
-	r.Post("/leads/bulk-update", h.BulkUpdate)
-	r.Get("/pipeline/stages", h.GetStages)
-	r.Post("/pipeline/stages", h.CreateStage)
-	r.Put("/pipeline/stages/reorder", h.ReorderStages)
-	r.Delete("/pipeline/stages/:key", h.DeleteStage)
+	r.Get("/leads", middleware.RequirePermission("crm.leads.access"), h.List)
+	r.Get("/leads/:id", middleware.RequirePermission("crm.leads.access"), h.Get)
+	r.Post("/leads", middleware.RequirePermission("crm.leads.create"), h.Create)
+	r.Patch("/leads/:id", middleware.RequirePermission("crm.leads.update"), h.Update)
+	r.Put("/leads/:id/status", middleware.RequirePermission("crm.leads.update"), h.UpdateStatus)
+	r.Post("/leads/bulk-update", middleware.RequirePermission("crm.leads.update"), h.BulkUpdate)
+	r.Get("/pipeline/stages", middleware.RequirePermission("crm.leads.access"), h.GetStages)
+	r.Post("/pipeline/stages", middleware.RequirePermission("crm.leads.update"), h.CreateStage)
+	r.Put("/pipeline/stages/reorder", middleware.RequirePermission("crm.leads.update"), h.ReorderStages)
+	r.Delete("/pipeline/stages/:key", middleware.RequirePermission("crm.leads.update"), h.DeleteStage)
 }
 
 func (h *LeadHandler) List(c *fiber.Ctx) error {
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
+	"shop/backend/internal/models"
+	"shop/backend/internal/services"
+	"shop/backend/internal/utils"
+)
+
+type MeasureUnitHandler struct { svc *services.MeasureUnitService }
+
+func NewMeasureUnitHandler(svc *services.MeasureUnitService) *MeasureUnitHandler { return &MeasureUnitHandler{svc: svc} }
+
+func (h *MeasureUnitHandler) Register(r fiber.Router) {
+	r.Get("/measure-units", middleware.RequirePermission("products.units.access"), h.List)
+	r.Post("/measure-units", middleware.RequirePermission("products.units.create"), h.Create)
+	r.Patch("/measure-units/:id", middleware.RequirePermission("products.units.update"), h.Update)
+	r.Delete("/measure-units/:id", middleware.RequirePermission("products.units.delete"), h.Delete)
+}
+
+// List returns the built-in units followed by the tenant's own.
+func (h *MeasureUnitHandler) List(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	items, err := h.svc.List(c.Context(), tenantID)
+	if err != nil { return err }
+	return utils.Success(c, items)
+}
+
+func (h *MeasureUnitHandler) Create(c *fiber.Ctx) error {
+	var body models.MeasureUnitInput
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Create(c.Context(), tenantID, body)
+	if err != nil { return err }
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse[models.MeasureUnit]{ Data: *item })
+}
+
+func (h *MeasureUnitHandler) Update(c *fiber.Ctx) error {
+	var body models.MeasureUnitInput
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Update(c.Context(), c.Params("id"), tenantID, body)
+	if err != nil { return err }
+	return utils.Success(c, item)
+}
+
+func (h *MeasureUnitHandler) Delete(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	if err := h.svc.Delete(c.Context(), c.Params("id"), tenantID); err != nil { return err }
+	return utils.NoContent(c)
+}
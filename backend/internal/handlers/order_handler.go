@@ -3,6 +3,7 @@ package handlers
 import (
 	"strconv"
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -13,12 +14,12 @@ type OrderHandler struct { svc *services.OrderService }
 func NewOrderHandler(svc *services.OrderService) *OrderHandler { return &OrderHandler{ svc: svc } }
 
 func (h *OrderHandler) Register(r fiber.Router) {
-	r.Get("/orders", h.List)
-	r.Get("/orders/:id", h.Get)
-	r.Post("/orders", h.Create)
-	r.Patch("/orders/:id", h.Update)
-	r.Delete("/orders/:id", h.Delete)
-	r.Post("/orders/:id/payments", h.AddPayment)
+	r.Get("/orders", middleware.RequirePermission("products.orders.access"), h.List)
+	r.Get("/orders/:id", middleware.RequirePermission("products.orders.access"), h.Get)
+	r.Post("/orders", middleware.RequirePermission("products.orders.create"), h.Create)
+	r.Patch("/orders/:id", middleware.RequirePermission("products.orders.update"), h.Update)
+	r.Delete("/orders/:id", middleware.RequirePermission("products.orders.delete"), h.Delete)
+	r.Post("/orders/:id/payments", middleware.RequirePermission("products.orders.update"), h.AddPayment)
 }
 
 func (h *OrderHandler) List(c *fiber.Ctx) error {
@@ -89,7 +90,9 @@ func (h *OrderHandler) AddPayment(c *fiber.Ctx) error {
 	tenantID := c.Locals("tenant_id").(string)
 	var body models.AddOrderPaymentRequest
 	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
-	item, err := h.svc.AddPayment(c.Context(), id, tenantID, body)
+	user := models.OrderUser{}
+	if u, ok := c.Locals("user").(*models.User); ok { user = models.OrderUser{ ID: u.ID.Hex(), Name: u.Name } }
+	item, err := h.svc.AddPayment(c.Context(), id, tenantID, body, user)
 	if err != nil { return err }
 	return utils.Success(c, item)
 } 
\ No newline at end of file
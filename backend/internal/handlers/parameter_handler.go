@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -18,11 +19,11 @@ func NewParameterHandler(svc *services.ParameterService) *ParameterHandler {
 }
 
 func (h *ParameterHandler) Register(r fiber.Router) {
-	r.Get("/parameters", h.List)
-	r.Get("/parameters/:id", h.Get)
-	r.Post("/parameters", h.Create)
-	r.Patch("/parameters/:id", h.Update)
-	r.Delete("/parameters/:id", h.Delete)
+	r.Get("/parameters", middleware.RequirePermission("products.parameters.access"), h.List)
+	r.Get("/parameters/:id", middleware.RequirePermission("products.parameters.access"), h.Get)
+	r.Post("/parameters", middleware.RequirePermission("products.parameters.create"), h.Create)
+	r.Patch("/parameters/:id", middleware.RequirePermission("products.parameters.update"), h.Update)
+	r.Delete("/parameters/:id", middleware.RequirePermission("products.parameters.delete"), h.Delete)
 }
 
 func (h *ParameterHandler) List(c *fiber.Ctx) error {
@@ -3,6 +3,7 @@ package handlers
 import (
 	"strconv"
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -13,11 +14,11 @@ type PriceTagHandler struct { svc *services.PriceTagService }
 func NewPriceTagHandler(svc *services.PriceTagService) *PriceTagHandler { return &PriceTagHandler{ svc: svc } }
 
 func (h *PriceTagHandler) Register(r fiber.Router) {
-	r.Get("/pricetags", h.List)
-	r.Get("/pricetags/:id", h.Get)
-	r.Post("/pricetags", h.Create)
-	r.Patch("/pricetags/:id", h.Update)
-	r.Delete("/pricetags/:id", h.Delete)
+	r.Get("/pricetags", middleware.RequirePermission("settings.pricetags.access"), h.List)
+	r.Get("/pricetags/:id", middleware.RequirePermission("settings.pricetags.access"), h.Get)
+	r.Post("/pricetags", middleware.RequirePermission("settings.pricetags.create"), h.Create)
+	r.Patch("/pricetags/:id", middleware.RequirePermission("settings.pricetags.update"), h.Update)
+	r.Delete("/pricetags/:id", middleware.RequirePermission("settings.pricetags.delete"), h.Delete)
 }
 
 func (h *PriceTagHandler) List(c *fiber.Ctx) error {
@@ -5,6 +5,7 @@ import (
 	"context"
 
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -19,20 +20,20 @@ func NewProductHandler(svc *services.ProductService) *ProductHandler {
 }
 
 func (h *ProductHandler) Register(r fiber.Router) {
-	r.Get("/products", h.List)
-	r.Get("/products/stats", h.Stats)
-	r.Get("/products/summary", h.Summary)
-	r.Get("/products/:id", h.Get)
-	r.Post("/products", h.Create)
-	r.Post("/products/bulk/variants", h.BulkCreateVariants)
-	r.Patch("/products/:id", h.Update)
-	r.Delete("/products/:id", h.Delete)
-	r.Patch("/products/:id/stock", h.UpdateStock)
+	r.Get("/products", middleware.RequirePermission("products.catalog.access"), h.List)
+	r.Get("/products/stats", middleware.RequirePermission("products.catalog.access"), h.Stats)
+	r.Get("/products/summary", middleware.RequirePermission("products.catalog.access"), h.Summary)
+	r.Get("/products/:id", middleware.RequirePermission("products.catalog.access"), h.Get)
+	r.Post("/products", middleware.RequirePermission("products.catalog.create"), h.Create)
+	r.Post("/products/bulk/variants", middleware.RequirePermission("products.catalog.create"), h.BulkCreateVariants)
+	r.Patch("/products/:id", middleware.RequirePermission("products.catalog.update"), h.Update)
+	r.Delete("/products/:id", middleware.RequirePermission("products.catalog.delete"), h.Delete)
+	r.Patch("/products/:id/stock", middleware.RequirePermission("products.catalog.update"), h.UpdateStock)
 	// bulk operations
-	r.Post("/products/bulk/delete", h.BulkDelete)
-	r.Post("/products/bulk/edit-properties", h.BulkEditProperties)
-	r.Post("/products/bulk/archive", h.BulkArchive)
-	r.Post("/products/bulk/unarchive", h.BulkUnarchive)
+	r.Post("/products/bulk/delete", middleware.RequirePermission("products.catalog.delete"), h.BulkDelete)
+	r.Post("/products/bulk/edit-properties", middleware.RequirePermission("products.catalog.update"), h.BulkEditProperties)
+	r.Post("/products/bulk/archive", middleware.RequirePermission("products.catalog.update"), h.BulkArchive)
+	r.Post("/products/bulk/unarchive", middleware.RequirePermission("products.catalog.update"), h.BulkUnarchive)
 }
 
 func (h *ProductHandler) List(c *fiber.Ctx) error {
@@ -183,13 +184,18 @@ func (h *ProductHandler) UpdateStock(c *fiber.Ctx) error {
 	tenantID := c.Locals("tenant_id").(string)
 	
 	var body struct {
-		Stock int `json:"stock" binding:"required,min=0"`
+		Stock  float64 `json:"stock" binding:"required,min=0"`
+		ShopID string  `json:"shop_id"`
 	}
 	if err := c.BodyParser(&body); err != nil {
 		return utils.BadRequest("INVALID_BODY", "Invalid request body", err)
 	}
+	// store defaults to the X-Store-ID header, then to the product's own store
+	if body.ShopID == "" {
+		if s, ok := c.Locals("store_id").(string); ok { body.ShopID = s }
+	}
 
-	if err := h.svc.UpdateStock(c.Context(), id, body.Stock, tenantID); err != nil {
+	if err := h.svc.UpdateStock(c.Context(), id, body.Stock, body.ShopID, tenantID); err != nil {
 		return err
 	}
 
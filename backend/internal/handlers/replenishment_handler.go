@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
+	"shop/backend/internal/models"
+	"shop/backend/internal/services"
+	"shop/backend/internal/utils"
+)
+
+type ReplenishmentHandler struct { svc *services.ReplenishmentService }
+
+func NewReplenishmentHandler(svc *services.ReplenishmentService) *ReplenishmentHandler { return &ReplenishmentHandler{svc: svc} }
+
+func (h *ReplenishmentHandler) Register(r fiber.Router) {
+	r.Get("/replenishment/suggestions", middleware.RequirePermission("products.orders.access"), h.Suggestions)
+	r.Post("/replenishment/orders", middleware.RequirePermission("products.orders.create"), h.CreateOrders)
+}
+
+// Suggestions takes ?shop_id=, ?sales_days= (default 30) and ?cover_days= (default 0).
+func (h *ReplenishmentHandler) Suggestions(c *fiber.Ctx) error {
+	req := models.ReplenishmentRequest{ShopID: c.Query("shop_id", ""), SalesDays: c.QueryInt("sales_days", 0), CoverDays: c.QueryInt("cover_days", 0)}
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.svc.Suggestions(c.Context(), tenantID, req)
+	if err != nil { return err }
+	return utils.Success(c, m)
+}
+
+// CreateOrders turns the current suggestions into draft supplier orders, one per supplier.
+func (h *ReplenishmentHandler) CreateOrders(c *fiber.Ctx) error {
+	var body models.ReplenishmentRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	createdBy := models.OrderUser{}
+	if u, ok := c.Locals("user").(*models.User); ok { createdBy = models.OrderUser{ID: u.ID.Hex(), Name: u.Name} }
+	m, err := h.svc.CreateOrders(c.Context(), tenantID, body, createdBy)
+	if err != nil { return err }
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse[models.ReplenishmentOrders]{Data: *m})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/repositories"
 	"shop/backend/internal/services"
@@ -13,11 +14,11 @@ type RepricingHandler struct { svc *services.RepricingService }
 func NewRepricingHandler(svc *services.RepricingService) *RepricingHandler { return &RepricingHandler{ svc: svc } }
 
 func (h *RepricingHandler) Register(r fiber.Router) {
-	r.Get("/repricings", h.List)
-	r.Get("/repricings/:id", h.Get)
-	r.Post("/repricings", h.Create)
-	r.Patch("/repricings/:id", h.Update)
-	r.Delete("/repricings/:id", h.Delete)
+	r.Get("/repricings", middleware.RequirePermission("products.repricing.access"), h.List)
+	r.Get("/repricings/:id", middleware.RequirePermission("products.repricing.access"), h.Get)
+	r.Post("/repricings", middleware.RequirePermission("products.repricing.create"), h.Create)
+	r.Patch("/repricings/:id", middleware.RequirePermission("products.repricing.update"), h.Update)
+	r.Delete("/repricings/:id", middleware.RequirePermission("products.repricing.delete"), h.Delete)
 }
 
 func (h *RepricingHandler) List(c *fiber.Ctx) error {
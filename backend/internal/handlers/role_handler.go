@@ -3,6 +3,7 @@ package handlers
 import (
 	"strconv"
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -13,12 +14,12 @@ type RoleHandler struct { svc *services.RoleService }
 func NewRoleHandler(svc *services.RoleService) *RoleHandler { return &RoleHandler{svc: svc} }
 
 func (h *RoleHandler) Register(r fiber.Router) { 
-	r.Get("/roles", h.List)
-	r.Get("/roles/:id", h.Get)
-	r.Post("/roles", h.Create)
-	r.Patch("/roles/:id", h.Update)
-	r.Delete("/roles/:id", h.Delete)
-	r.Get("/permissions", h.Permissions)
+	r.Get("/roles", middleware.RequirePermission("hr.roles.access"), h.List)
+	r.Get("/roles/:id", middleware.RequirePermission("hr.roles.access"), h.Get)
+	r.Post("/roles", middleware.RequirePermission("hr.roles.create"), h.Create)
+	r.Patch("/roles/:id", middleware.RequirePermission("hr.roles.update"), h.Update)
+	r.Delete("/roles/:id", middleware.RequirePermission("hr.roles.delete"), h.Delete)
+	r.Get("/permissions", middleware.RequirePermission("hr.roles.access"), h.Permissions)
 }
 
 func (h *RoleHandler) List(c *fiber.Ctx) error {
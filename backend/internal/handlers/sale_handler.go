@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"strconv"
+	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
+	"shop/backend/internal/models"
+	"shop/backend/internal/services"
+	"shop/backend/internal/utils"
+)
+
+type SaleHandler struct { svc *services.SaleService }
+
+func NewSaleHandler(svc *services.SaleService) *SaleHandler { return &SaleHandler{ svc: svc } }
+
+func (h *SaleHandler) Register(r fiber.Router) {
+	r.Get("/sales", middleware.RequirePermission("sales.all.access"), h.List)
+	r.Get("/sales/:id", middleware.RequirePermission("sales.all.access"), h.Get)
+	r.Get("/sales/:id/receipt", middleware.RequirePermission("sales.all.access"), h.Receipt)
+	r.Post("/sales", middleware.RequirePermission("sales.new.create"), h.Create)
+	r.Patch("/sales/:id", middleware.RequirePermission("sales.new.update"), h.Update)
+	r.Delete("/sales/:id", middleware.RequirePermission("sales.all.delete"), h.Delete)
+}
+
+// checkPriceOverride lets a line carry its own unit price, instead of the catalog or variant price, only for users
+// granted sales.prices.update; everyone else sells at catalog prices less discounts.
+func checkPriceOverride(c *fiber.Ctx, items []models.SaleItemInput) error {
+	for _, it := range items {
+		if it.UnitPrice > 0 { return middleware.Current.CheckPermission(c, "sales.prices.update") }
+	}
+	return nil
+}
+
+func (h *SaleHandler) List(c *fiber.Ctx) error {
+	var f models.SaleFilterRequest
+	f.Search = c.Query("search", "")
+	f.StatusID = c.Query("status_id", "")
+	f.CustomerID = c.Query("customer_id", "")
+	f.ShopID = c.Query("shop_id", "")
+	f.CreatedBy = c.Query("created_by", "")
+	f.DateFrom = c.Query("date_from", "")
+	f.DateTo = c.Query("date_to", "")
+	f.PaymentStatus = c.Query("payment_status", "")
+	f.Type = c.Query("type", "")
+	f.SortBy = c.Query("sort_by", "created_at")
+	f.SortOrder = c.Query("sort_order", "desc")
+	if p, err := strconv.Atoi(c.Query("page", "1")); err == nil { f.Page = p }
+	if l, err := strconv.Atoi(c.Query("limit", "20")); err == nil { f.Limit = l }
+
+	tenantID := c.Locals("tenant_id").(string)
+	items, total, err := h.svc.List(c.Context(), f, tenantID)
+	if err != nil { return err }
+	return c.JSON(utils.SuccessResponse[utils.Paginated[models.Sale]]{ Data: utils.Paginated[models.Sale]{ Items: items, Total: total } })
+}
+
+func (h *SaleHandler) Get(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Get(c.Context(), c.Params("id"), tenantID)
+	if err != nil { return err }
+	return utils.Success(c, item)
+}
+
+func (h *SaleHandler) Receipt(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Receipt(c.Context(), c.Params("id"), tenantID)
+	if err != nil { return err }
+	return utils.Success(c, item)
+}
+
+func (h *SaleHandler) Create(c *fiber.Ctx) error {
+	var body models.CreateSaleRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	// default to the store selected in the client
+	if body.ShopID == "" { if s, ok := c.Locals("store_id").(string); ok { body.ShopID = s } }
+	if err := checkPriceOverride(c, body.Items); err != nil { return err }
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Create(c.Context(), body, tenantID, saleActor(c))
+	if err != nil { return err }
+	return utils.Created(c, item)
+}
+
+func (h *SaleHandler) Update(c *fiber.Ctx) error {
+	var body models.UpdateSaleRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	if err := checkPriceOverride(c, body.Items); err != nil { return err }
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Update(c.Context(), c.Params("id"), body, tenantID, saleActor(c))
+	if err != nil { return err }
+	return utils.Success(c, item)
+}
+
+func (h *SaleHandler) Delete(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	if err := h.svc.Delete(c.Context(), c.Params("id"), tenantID); err != nil { return err }
+	return utils.NoContent(c)
+}
+
+func saleActor(c *fiber.Ctx) models.InventoryUser {
+	if u, ok := c.Locals("user").(*models.User); ok && u != nil { return models.InventoryUser{ ID: u.ID.Hex(), Name: u.Name } }
+	return models.InventoryUser{}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"strconv"
+	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
+	"shop/backend/internal/models"
+	"shop/backend/internal/services"
+	"shop/backend/internal/utils"
+)
+
+type SaleReturnHandler struct { svc *services.SaleReturnService }
+
+func NewSaleReturnHandler(svc *services.SaleReturnService) *SaleReturnHandler { return &SaleReturnHandler{ svc: svc } }
+
+func (h *SaleReturnHandler) Register(r fiber.Router) {
+	r.Get("/sale-returns", middleware.RequirePermission("sales.returns.access"), h.List)
+	r.Get("/sale-returns/:id", middleware.RequirePermission("sales.returns.access"), h.Get)
+	r.Get("/sales/:id/returns", middleware.RequirePermission("sales.returns.access"), h.ListBySale)
+	r.Post("/sales/:id/returns", middleware.RequirePermission("sales.returns.create"), h.Create)
+}
+
+func (h *SaleReturnHandler) List(c *fiber.Ctx) error {
+	var f models.SaleReturnFilterRequest
+	f.SaleID = c.Query("sale_id", "")
+	f.ShopID = c.Query("shop_id", "")
+	f.ShiftID = c.Query("shift_id", "")
+	f.Reason = c.Query("reason", "")
+	f.DateFrom = c.Query("date_from", "")
+	f.DateTo = c.Query("date_to", "")
+	if p, err := strconv.Atoi(c.Query("page", "1")); err == nil { f.Page = p }
+	if l, err := strconv.Atoi(c.Query("limit", "20")); err == nil { f.Limit = l }
+
+	tenantID := c.Locals("tenant_id").(string)
+	items, total, err := h.svc.List(c.Context(), f, tenantID)
+	if err != nil { return err }
+	return c.JSON(utils.SuccessResponse[utils.Paginated[models.SaleReturn]]{ Data: utils.Paginated[models.SaleReturn]{ Items: items, Total: total } })
+}
+
+func (h *SaleReturnHandler) Get(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Get(c.Context(), c.Params("id"), tenantID)
+	if err != nil { return err }
+	return utils.Success(c, item)
+}
+
+func (h *SaleReturnHandler) ListBySale(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	items, err := h.svc.ListBySale(c.Context(), c.Params("id"), tenantID)
+	if err != nil { return err }
+	return utils.Success(c, items)
+}
+
+func (h *SaleReturnHandler) Create(c *fiber.Ctx) error {
+	var body models.CreateSaleReturnRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Create(c.Context(), c.Params("id"), body, tenantID, saleActor(c))
+	if err != nil { return err }
+	return utils.Created(c, item)
+}
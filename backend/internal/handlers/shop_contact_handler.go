@@ -3,6 +3,7 @@ package handlers
 import (
 	"strconv"
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -13,11 +14,11 @@ type ShopContactHandler struct { svc *services.ShopContactService }
 func NewShopContactHandler(svc *services.ShopContactService) *ShopContactHandler { return &ShopContactHandler{ svc: svc } }
 
 func (h *ShopContactHandler) Register(r fiber.Router) {
-	r.Get("/shop/contacts", h.List)
-	r.Get("/shop/contacts/:id", h.Get)
-	r.Post("/shop/contacts", h.Create)
-	r.Patch("/shop/contacts/:id", h.Update)
-	r.Delete("/shop/contacts/:id", h.Delete)
+	r.Get("/shop/contacts", middleware.RequirePermission("shop.customer.access"), h.List)
+	r.Get("/shop/contacts/:id", middleware.RequirePermission("shop.customer.access"), h.Get)
+	r.Post("/shop/contacts", middleware.RequirePermission("shop.customer.create"), h.Create)
+	r.Patch("/shop/contacts/:id", middleware.RequirePermission("shop.customer.update"), h.Update)
+	r.Delete("/shop/contacts/:id", middleware.RequirePermission("shop.customer.delete"), h.Delete)
 }
 
 func (h *ShopContactHandler) List(c *fiber.Ctx) error {
@@ -3,6 +3,7 @@ package handlers
 import (
 	"strconv"
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -13,12 +14,12 @@ type ShopCustomerHandler struct { svc *services.ShopCustomerService }
 func NewShopCustomerHandler(svc *services.ShopCustomerService) *ShopCustomerHandler { return &ShopCustomerHandler{ svc: svc } }
 
 func (h *ShopCustomerHandler) Register(r fiber.Router) {
-	r.Get("/shop/customers", h.List)
-	r.Get("/shop/customers/:id", h.Get)
-	r.Post("/shop/customers", h.Create)
-	r.Patch("/shop/customers/:id", h.Update)
-	r.Delete("/shop/customers/:id", h.Delete)
-	r.Get("/shop/labor-rates", h.LaborRates)
+	r.Get("/shop/customers", middleware.RequirePermission("shop.customer.access"), h.List)
+	r.Get("/shop/customers/:id", middleware.RequirePermission("shop.customer.access"), h.Get)
+	r.Post("/shop/customers", middleware.RequirePermission("shop.customer.create"), h.Create)
+	r.Patch("/shop/customers/:id", middleware.RequirePermission("shop.customer.update"), h.Update)
+	r.Delete("/shop/customers/:id", middleware.RequirePermission("shop.customer.delete"), h.Delete)
+	r.Get("/shop/labor-rates", middleware.RequirePermission("shop.customer.access"), h.LaborRates)
 }
 
 func (h *ShopCustomerHandler) List(c *fiber.Ctx) error {
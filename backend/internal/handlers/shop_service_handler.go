@@ -3,6 +3,7 @@ package handlers
 import (
 	"strconv"
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -13,11 +14,11 @@ type ShopServiceHandler struct { svc *services.ShopServiceService }
 func NewShopServiceHandler(svc *services.ShopServiceService) *ShopServiceHandler { return &ShopServiceHandler{ svc: svc } }
 
 func (h *ShopServiceHandler) Register(r fiber.Router) {
-	r.Get("/shop/services", h.List)
-	r.Get("/shop/services/:id", h.Get)
-	r.Post("/shop/services", h.Create)
-	r.Patch("/shop/services/:id", h.Update)
-	r.Delete("/shop/services/:id", h.Delete)
+	r.Get("/shop/services", middleware.RequirePermission("shop.service.access"), h.List)
+	r.Get("/shop/services/:id", middleware.RequirePermission("shop.service.access"), h.Get)
+	r.Post("/shop/services", middleware.RequirePermission("shop.service.create"), h.Create)
+	r.Patch("/shop/services/:id", middleware.RequirePermission("shop.service.update"), h.Update)
+	r.Delete("/shop/services/:id", middleware.RequirePermission("shop.service.delete"), h.Delete)
 }
 
 func (h *ShopServiceHandler) List(c *fiber.Ctx) error {
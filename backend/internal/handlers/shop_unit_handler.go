@@ -3,6 +3,7 @@ package handlers
 import (
 	"strconv"
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -13,11 +14,11 @@ type ShopUnitHandler struct { svc *services.ShopUnitService }
 func NewShopUnitHandler(svc *services.ShopUnitService) *ShopUnitHandler { return &ShopUnitHandler{ svc: svc } }
 
 func (h *ShopUnitHandler) Register(r fiber.Router) {
-	r.Get("/shop/units", h.List)
-	r.Get("/shop/units/:id", h.Get)
-	r.Post("/shop/units", h.Create)
-	r.Patch("/shop/units/:id", h.Update)
-	r.Delete("/shop/units/:id", h.Delete)
+	r.Get("/shop/units", middleware.RequirePermission("shop.unit.access"), h.List)
+	r.Get("/shop/units/:id", middleware.RequirePermission("shop.unit.access"), h.Get)
+	r.Post("/shop/units", middleware.RequirePermission("shop.unit.create"), h.Create)
+	r.Patch("/shop/units/:id", middleware.RequirePermission("shop.unit.update"), h.Update)
+	r.Delete("/shop/units/:id", middleware.RequirePermission("shop.unit.delete"), h.Delete)
 }
 
 func (h *ShopUnitHandler) List(c *fiber.Ctx) error {
@@ -3,6 +3,7 @@ package handlers
 import (
 	"strconv"
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -13,11 +14,11 @@ type ShopVendorHandler struct { svc *services.ShopVendorService }
 func NewShopVendorHandler(svc *services.ShopVendorService) *ShopVendorHandler { return &ShopVendorHandler{ svc: svc } }
 
 func (h *ShopVendorHandler) Register(r fiber.Router) {
-	r.Get("/shop/vendors", h.List)
-	r.Get("/shop/vendors/:id", h.Get)
-	r.Post("/shop/vendors", h.Create)
-	r.Patch("/shop/vendors/:id", h.Update)
-	r.Delete("/shop/vendors/:id", h.Delete)
+	r.Get("/shop/vendors", middleware.RequirePermission("shop.vendor.access"), h.List)
+	r.Get("/shop/vendors/:id", middleware.RequirePermission("shop.vendor.access"), h.Get)
+	r.Post("/shop/vendors", middleware.RequirePermission("shop.vendor.create"), h.Create)
+	r.Patch("/shop/vendors/:id", middleware.RequirePermission("shop.vendor.update"), h.Update)
+	r.Delete("/shop/vendors/:id", middleware.RequirePermission("shop.vendor.delete"), h.Delete)
 }
 
 func (h *ShopVendorHandler) List(c *fiber.Ctx) error {
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
+	"shop/backend/internal/models"
+	"shop/backend/internal/services"
+	"shop/backend/internal/utils"
+)
+
+type StockHandler struct {
+	svc          *services.StockService
+	reservations *services.ReservationService
+}
+
+func NewStockHandler(svc *services.StockService, reservations *services.ReservationService) *StockHandler { return &StockHandler{ svc: svc, reservations: reservations } }
+
+func (h *StockHandler) Register(r fiber.Router) {
+	r.Get("/products/:id/stock/movements", middleware.RequirePermission("products.catalog.access"), h.Movements)
+	r.Get("/products/:id/stock/as-of", middleware.RequirePermission("products.catalog.access"), h.AsOf)
+	r.Get("/stock/consistency", middleware.RequirePermission("products.catalog.access"), h.Consistency)
+	r.Get("/stock/valuation", middleware.RequirePermission("products.catalog.access"), h.Valuation)
+	r.Get("/products/:id/lots", middleware.RequirePermission("products.catalog.access"), h.Lots)
+	r.Get("/stock/lots/expiring", middleware.RequirePermission("products.catalog.access"), h.ExpiringLots)
+	r.Get("/products/:id/serials", middleware.RequirePermission("products.catalog.access"), h.Serials)
+	r.Get("/serials/:serial", middleware.RequirePermission("products.catalog.access"), h.SerialLookup)
+	r.Get("/stock/reservations", middleware.RequirePermission("products.catalog.access"), h.Reservations)
+	r.Post("/stock/reservations", middleware.RequirePermission("products.catalog.update"), h.Reserve)
+	r.Delete("/stock/reservations/:id", middleware.RequirePermission("products.catalog.update"), h.DeleteReservation)
+}
+
+func (h *StockHandler) Movements(c *fiber.Ctx) error {
+	var f models.StockMovementFilterRequest
+	_ = c.QueryParser(&f)
+	// fallback when snake_case not bound
+	if f.ShopID == "" { f.ShopID = c.Query("shop_id", "") }
+	if f.SourceType == "" { f.SourceType = c.Query("source_type", "") }
+	if f.DateFrom == "" { f.DateFrom = c.Query("date_from", "") }
+	if f.DateTo == "" { f.DateTo = c.Query("date_to", "") }
+	tenantID := c.Locals("tenant_id").(string)
+	items, total, err := h.svc.Movements(c.Context(), c.Params("id"), f, tenantID)
+	if err != nil { return err }
+	return c.JSON(utils.SuccessResponse[utils.Paginated[models.StockMovement]]{ Data: utils.Paginated[models.StockMovement]{ Items: items, Total: total } })
+}
+
+// AsOf takes ?date= as RFC3339 or YYYY-MM-DD (end of that day); it defaults to now.
+func (h *StockHandler) AsOf(c *fiber.Ctx) error {
+	at, err := asOfDate(c)
+	if err != nil { return err }
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.svc.AsOf(c.Context(), c.Params("id"), at, tenantID)
+	if err != nil { return err }
+	return utils.Success(c, m)
+}
+
+func (h *StockHandler) Consistency(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.svc.CheckConsistency(c.Context(), tenantID)
+	if err != nil { return err }
+	return utils.Success(c, m)
+}
+
+// Valuation takes ?date= like AsOf and an optional ?shop_id=.
+func (h *StockHandler) Valuation(c *fiber.Ctx) error {
+	at, err := asOfDate(c)
+	if err != nil { return err }
+	tenantID := c.Locals("tenant_id").(string)
+	m, err := h.svc.Valuation(c.Context(), tenantID, c.Query("shop_id", ""), at)
+	if err != nil { return err }
+	return utils.Success(c, m)
+}
+
+// Lots takes an optional ?shop_id= and ?open=true to leave out lots with nothing left.
+func (h *StockHandler) Lots(c *fiber.Ctx) error {
+	page, _ := strconv.ParseInt(c.Query("page", "1"), 10, 64)
+	limit, _ := strconv.ParseInt(c.Query("limit", "50"), 10, 64)
+	tenantID := c.Locals("tenant_id").(string)
+	items, total, err := h.svc.Lots(c.Context(), tenantID, c.Params("id"), c.Query("shop_id", ""), c.QueryBool("open", false), page, limit)
+	if err != nil { return err }
+	return c.JSON(utils.SuccessResponse[utils.Paginated[models.StockLot]]{ Data: utils.Paginated[models.StockLot]{ Items: items, Total: total } })
+}
+
+// ExpiringLots takes ?days= (default 30) and an optional ?shop_id=.
+func (h *StockHandler) ExpiringLots(c *fiber.Ctx) error {
+	days, err := strconv.Atoi(c.Query("days", "30"))
+	if err != nil { return utils.BadRequest("VALIDATION_ERROR", "Invalid days", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	items, err := h.svc.ExpiringLots(c.Context(), tenantID, c.Query("shop_id", ""), days)
+	if err != nil { return err }
+	return utils.Success(c, items)
+}
+
+// Serials takes optional ?shop_id= and ?status= (in_stock, in_transit, sold, written_off, returned_to_supplier).
+func (h *StockHandler) Serials(c *fiber.Ctx) error {
+	page, _ := strconv.ParseInt(c.Query("page", "1"), 10, 64)
+	limit, _ := strconv.ParseInt(c.Query("limit", "50"), 10, 64)
+	tenantID := c.Locals("tenant_id").(string)
+	items, total, err := h.svc.Serials(c.Context(), tenantID, c.Params("id"), c.Query("shop_id", ""), c.Query("status", ""), page, limit)
+	if err != nil { return err }
+	return c.JSON(utils.SuccessResponse[utils.Paginated[models.SerialNumber]]{ Data: utils.Paginated[models.SerialNumber]{ Items: items, Total: total } })
+}
+
+// SerialLookup returns every unit carrying the serial with its history: received, moved, sold, returned.
+func (h *StockHandler) SerialLookup(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	items, err := h.svc.SerialLookup(c.Context(), tenantID, c.Params("serial"))
+	if err != nil { return err }
+	return utils.Success(c, items)
+}
+
+// asOfDate reads ?date= as RFC3339 or YYYY-MM-DD (end of that day); it defaults to now.
+func asOfDate(c *fiber.Ctx) (time.Time, error) {
+	v := c.Query("date", "")
+	if v == "" { return time.Now().UTC(), nil }
+	if t, err := time.Parse(time.RFC3339, v); err == nil { return t, nil }
+	d, err := time.Parse("2006-01-02", v)
+	if err != nil { return time.Time{}, utils.BadRequest("INVALID_DATE", "Invalid date format", err) }
+	return d.Add(24*time.Hour - time.Nanosecond), nil
+}
+
+// Reservations lists the live reservations; it takes optional ?product_id=, ?shop_id=, ?source_type= and ?source_id=.
+func (h *StockHandler) Reservations(c *fiber.Ctx) error {
+	f := models.ReservationFilterRequest{ ProductID: c.Query("product_id", ""), ShopID: c.Query("shop_id", ""), SourceType: c.Query("source_type", ""), SourceID: c.Query("source_id", ""), Page: c.QueryInt("page", 1), Limit: c.QueryInt("limit", 20) }
+	tenantID := c.Locals("tenant_id").(string)
+	items, total, err := h.reservations.List(c.Context(), tenantID, f)
+	if err != nil { return err }
+	return c.JSON(utils.SuccessResponse[utils.Paginated[models.StockReservation]]{ Data: utils.Paginated[models.StockReservation]{ Items: items, Total: total } })
+}
+
+// Reserve holds stock for a service work order or by hand; sales and transfers reserve by themselves.
+func (h *StockHandler) Reserve(c *fiber.Ctx) error {
+	var body models.CreateReservationRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	actor := models.InventoryUser{}
+	if u, ok := c.Locals("user").(*models.User); ok { actor = models.InventoryUser{ ID: u.ID.Hex(), Name: u.Name } }
+	items, err := h.reservations.Create(c.Context(), tenantID, body, actor)
+	if err != nil { return err }
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse[[]models.StockReservation]{ Data: items })
+}
+
+func (h *StockHandler) DeleteReservation(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenant_id").(string)
+	if err := h.reservations.Delete(c.Context(), tenantID, c.Params("id")); err != nil { return err }
+	return utils.NoContent(c)
+}
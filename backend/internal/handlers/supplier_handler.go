@@ -3,6 +3,7 @@ package handlers
 import (
 	"strconv"
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -14,29 +15,31 @@ type SupplierHandler struct { svc *services.SupplierService }
 func NewSupplierHandler(svc *services.SupplierService) *SupplierHandler { return &SupplierHandler{svc: svc} }
 
 func (h *SupplierHandler) Register(r fiber.Router) {
-	r.Get("/suppliers", h.List)
-	r.Get("/suppliers/:id", h.Get)
-	r.Get("/suppliers/:id/stats", h.Stats)
-	r.Get("/suppliers/:id/payments", h.Payments)
+	r.Get("/suppliers", middleware.RequirePermission("products.suppliers.access"), h.List)
+	r.Get("/suppliers/:id", middleware.RequirePermission("products.suppliers.access"), h.Get)
+	r.Get("/suppliers/:id/stats", middleware.RequirePermission("products.suppliers.access"), h.Stats)
+	r.Get("/suppliers/:id/payments", middleware.RequirePermission("products.suppliers.access"), h.Payments)
 	// new products endpoint
-	r.Get("/suppliers/:id/products", h.Products)
-	r.Post("/suppliers", h.Create)
-	r.Patch("/suppliers/:id", h.Update)
-	r.Delete("/suppliers/:id", h.Delete)
+	r.Get("/suppliers/:id/products", middleware.RequirePermission("products.suppliers.access"), h.Products)
+	r.Post("/suppliers", middleware.RequirePermission("products.suppliers.create"), h.Create)
+	r.Patch("/suppliers/:id", middleware.RequirePermission("products.suppliers.update"), h.Update)
+	r.Delete("/suppliers/:id", middleware.RequirePermission("products.suppliers.delete"), h.Delete)
 }
 
 func (h *SupplierHandler) List(c *fiber.Ctx) error {
 	page, _ := strconv.ParseInt(c.Query("page", "1"), 10, 64)
 	limit, _ := strconv.ParseInt(c.Query("limit", "10"), 10, 64)
 	search := c.Query("search", "")
-	items, total, err := h.svc.List(c.Context(), page, limit, search)
+	tenantID := c.Locals("tenant_id").(string)
+	items, total, err := h.svc.List(c.Context(), page, limit, search, tenantID)
 	if err != nil { return err }
 	return c.JSON(utils.SuccessResponse[utils.Paginated[models.SupplierDTO]]{ Data: utils.Paginated[models.SupplierDTO]{ Items: items, Total: total } })
 }
 
 func (h *SupplierHandler) Get(c *fiber.Ctx) error {
 	id := c.Params("id")
-	item, err := h.svc.Get(c.Context(), id)
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Get(c.Context(), id, tenantID)
 	if err != nil { return err }
 	return utils.Success(c, item)
 }
@@ -88,7 +91,8 @@ func (h *SupplierHandler) Products(c *fiber.Ctx) error {
 func (h *SupplierHandler) Create(c *fiber.Ctx) error {
 	var body models.SupplierCreate
 	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
-	item, err := h.svc.Create(c.Context(), body)
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Create(c.Context(), body, tenantID)
 	if err != nil { return err }
 	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse[models.SupplierDTO]{ Data: *item })
 }
@@ -97,13 +101,15 @@ func (h *SupplierHandler) Update(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var body models.SupplierUpdate
 	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
-	item, err := h.svc.Update(c.Context(), id, body)
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Update(c.Context(), id, body, tenantID)
 	if err != nil { return err }
 	return utils.Success(c, item)
 }
 
 func (h *SupplierHandler) Delete(c *fiber.Ctx) error {
 	id := c.Params("id")
-	if err := h.svc.Delete(c.Context(), id); err != nil { return err }
+	tenantID := c.Locals("tenant_id").(string)
+	if err := h.svc.Delete(c.Context(), id, tenantID); err != nil { return err }
 	return utils.NoContent(c)
 } 
\ No newline at end of file
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
+	"shop/backend/internal/models"
+	"shop/backend/internal/services"
+	"shop/backend/internal/utils"
+)
+
+type SupplierLedgerHandler struct { svc *services.SupplierLedgerService }
+
+func NewSupplierLedgerHandler(svc *services.SupplierLedgerService) *SupplierLedgerHandler { return &SupplierLedgerHandler{ svc: svc } }
+
+func (h *SupplierLedgerHandler) Register(r fiber.Router) {
+	r.Get("/suppliers/:id/ledger", middleware.RequirePermission("products.suppliers.access"), h.Ledger)
+	r.Get("/suppliers/:id/statement", middleware.RequirePermission("products.suppliers.access"), h.Statement)
+	r.Post("/suppliers/:id/ledger/opening", middleware.RequirePermission("products.suppliers.update"), h.Opening)
+	r.Post("/suppliers/:id/ledger/adjustments", middleware.RequirePermission("products.suppliers.update"), h.Adjust)
+}
+
+func (h *SupplierLedgerHandler) Ledger(c *fiber.Ctx) error {
+	page, _ := strconv.ParseInt(c.Query("page", "1"), 10, 64)
+	limit, _ := strconv.ParseInt(c.Query("limit", "20"), 10, 64)
+	tenantID := c.Locals("tenant_id").(string)
+	items, total, err := h.svc.Ledger(c.Context(), c.Params("id"), c.Query("shop_id", ""), page, limit, tenantID)
+	if err != nil { return err }
+	return c.JSON(utils.SuccessResponse[utils.Paginated[models.SupplierLedgerEntry]]{ Data: utils.Paginated[models.SupplierLedgerEntry]{ Items: items, Total: total } })
+}
+
+// Statement returns the reconciliation statement as JSON, or as a CSV download with format=csv.
+func (h *SupplierLedgerHandler) Statement(c *fiber.Ctx) error {
+	var fromPtr, toPtr *time.Time
+	if v := strings.TrimSpace(c.Query("date_from", "")); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil { return utils.BadRequest("INVALID_DATE", "date_from must be RFC3339", err) }
+		fromPtr = &t
+	}
+	if v := strings.TrimSpace(c.Query("date_to", "")); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil { return utils.BadRequest("INVALID_DATE", "date_to must be RFC3339", err) }
+		toPtr = &t
+	}
+	tenantID := c.Locals("tenant_id").(string)
+	st, err := h.svc.Statement(c.Context(), c.Params("id"), c.Query("shop_id", ""), fromPtr, toPtr, tenantID)
+	if err != nil { return err }
+	if c.Query("format", "") != "csv" { return utils.Success(c, st) }
+	var buf bytes.Buffer
+	if err := services.WriteSupplierStatementCSV(&buf, st); err != nil { return utils.Internal("SUPPLIER_STATEMENT_EXPORT_FAILED", "Unable to export statement", err) }
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="statement-`+st.SupplierID+`.csv"`)
+	return c.Send(buf.Bytes())
+}
+
+func (h *SupplierLedgerHandler) Opening(c *fiber.Ctx) error {
+	var body models.SupplierLedgerPostRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Opening(c.Context(), c.Params("id"), body, tenantID, saleActor(c))
+	if err != nil { return err }
+	return utils.Created(c, item)
+}
+
+func (h *SupplierLedgerHandler) Adjust(c *fiber.Ctx) error {
+	var body models.SupplierLedgerPostRequest
+	if err := c.BodyParser(&body); err != nil { return utils.BadRequest("INVALID_BODY", "Invalid request body", err) }
+	tenantID := c.Locals("tenant_id").(string)
+	item, err := h.svc.Adjust(c.Context(), c.Params("id"), body, tenantID, saleActor(c))
+	if err != nil { return err }
+	return utils.Created(c, item)
+}
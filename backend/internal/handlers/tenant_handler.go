@@ -3,6 +3,7 @@ package handlers
 import (
 	"strconv"
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -13,21 +14,22 @@ type TenantHandler struct { svc *services.TenantService }
 func NewTenantHandler(svc *services.TenantService) *TenantHandler { return &TenantHandler{svc: svc} }
 
 func (h *TenantHandler) Register(r fiber.Router) {
-	r.Get("/tenants", h.List)
-	r.Get("/tenants/:id", h.Get)
-	r.Post("/tenants", h.Create)
-	r.Patch("/tenants/:id", h.Update)
+	r.Get("/tenants", middleware.RequirePermission(middleware.PermissionAll), h.List)
+	r.Get("/tenants/:id", middleware.RequirePermission(middleware.PermissionAll), h.Get)
+	r.Post("/tenants", middleware.RequirePermission(middleware.PermissionAll), h.Create)
+	r.Patch("/tenants/:id", middleware.RequirePermission(middleware.PermissionAll), h.Update)
 	// management actions
-	r.Post("/tenants/:id/freeze", h.Freeze)
-	r.Post("/tenants/:id/unfreeze", h.Unfreeze)
+	r.Post("/tenants/:id/freeze", middleware.RequirePermission(middleware.PermissionAll), h.Freeze)
+	r.Post("/tenants/:id/unfreeze", middleware.RequirePermission(middleware.PermissionAll), h.Unfreeze)
 	// stats
-	r.Get("/tenants/:id/stats", h.Stats)
+	r.Get("/tenants/:id/stats", middleware.RequirePermission(middleware.PermissionAll), h.Stats)
 }
 
 // Register tenant-scoped endpoints (require tenant middleware)
 func (h *TenantHandler) RegisterCurrent(r fiber.Router) {
+	// available to any authenticated user
 	r.Get("/tenant/current", h.GetCurrent)
-	r.Patch("/tenant/current", h.UpdateCurrent)
+	r.Patch("/tenant/current", middleware.RequirePermission("settings.company.update"), h.UpdateCurrent)
 }
 
 func (h *TenantHandler) List(c *fiber.Ctx) error {
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -12,11 +13,11 @@ type TransferHandler struct { svc *services.TransferService }
 func NewTransferHandler(svc *services.TransferService) *TransferHandler { return &TransferHandler{ svc: svc } }
 
 func (h *TransferHandler) Register(r fiber.Router) {
-	r.Get("/transfers", h.List)
-	r.Get("/transfers/:id", h.Get)
-	r.Post("/transfers", h.Create)
-	r.Patch("/transfers/:id", h.Update)
-	r.Delete("/transfers/:id", h.Delete)
+	r.Get("/transfers", middleware.RequirePermission("products.transfer.access"), h.List)
+	r.Get("/transfers/:id", middleware.RequirePermission("products.transfer.access"), h.Get)
+	r.Post("/transfers", middleware.RequirePermission("products.transfer.create"), h.Create)
+	r.Patch("/transfers/:id", middleware.RequirePermission("products.transfer.update"), h.Update)
+	r.Delete("/transfers/:id", middleware.RequirePermission("products.transfer.delete"), h.Delete)
 }
 
 func (h *TransferHandler) List(c *fiber.Ctx) error {
@@ -19,6 +19,7 @@ func NewUploadHandler() *UploadHandler {
 }
 
 func (h *UploadHandler) Register(r fiber.Router) {
+	// available to any authenticated user
 	r.Post("/upload/images", h.UploadImages)
 	r.Static("/uploads", "/data/uploads")
 }
@@ -3,6 +3,7 @@ package handlers
 import (
 	"strconv"
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -13,11 +14,11 @@ type UserHandler struct { svc *services.UserService }
 func NewUserHandler(svc *services.UserService) *UserHandler { return &UserHandler{svc: svc} }
 
 func (h *UserHandler) Register(r fiber.Router) { 
-	r.Get("/users", h.List)
-	r.Get("/users/:id", h.Get)
-	r.Post("/users", h.Create)
-	r.Patch("/users/:id", h.Update)
-	r.Delete("/users/:id", h.Delete)
+	r.Get("/users", middleware.RequirePermission("hr.users.access"), h.List)
+	r.Get("/users/:id", middleware.RequirePermission("hr.users.access"), h.Get)
+	r.Post("/users", middleware.RequirePermission("hr.users.create"), h.Create)
+	r.Patch("/users/:id", middleware.RequirePermission("hr.users.update"), h.Update)
+	r.Delete("/users/:id", middleware.RequirePermission("hr.users.delete"), h.Delete)
 }
 
 func (h *UserHandler) List(c *fiber.Ctx) error {
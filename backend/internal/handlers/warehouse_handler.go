@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -18,11 +19,11 @@ func NewWarehouseHandler(svc *services.WarehouseService) *WarehouseHandler {
 }
 
 func (h *WarehouseHandler) Register(r fiber.Router) {
-	r.Get("/warehouses", h.List)
-	r.Get("/warehouses/:id", h.Get)
-	r.Post("/warehouses", h.Create)
-	r.Patch("/warehouses/:id", h.Update)
-	r.Delete("/warehouses/:id", h.Delete)
+	r.Get("/warehouses", middleware.RequirePermission("products.warehouses.access"), h.List)
+	r.Get("/warehouses/:id", middleware.RequirePermission("products.warehouses.access"), h.Get)
+	r.Post("/warehouses", middleware.RequirePermission("products.warehouses.create"), h.Create)
+	r.Patch("/warehouses/:id", middleware.RequirePermission("products.warehouses.update"), h.Update)
+	r.Delete("/warehouses/:id", middleware.RequirePermission("products.warehouses.delete"), h.Delete)
 }
 
 func (h *WarehouseHandler) List(c *fiber.Ctx) error {
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"shop/backend/internal/middleware"
 	"shop/backend/internal/models"
 	"shop/backend/internal/services"
 	"shop/backend/internal/utils"
@@ -12,11 +13,11 @@ type WriteOffHandler struct { svc *services.WriteOffService }
 func NewWriteOffHandler(svc *services.WriteOffService) *WriteOffHandler { return &WriteOffHandler{ svc: svc } }
 
 func (h *WriteOffHandler) Register(r fiber.Router) {
-	r.Get("/writeoffs", h.List)
-	r.Get("/writeoffs/:id", h.Get)
-	r.Post("/writeoffs", h.Create)
-	r.Patch("/writeoffs/:id", h.Update)
-	r.Delete("/writeoffs/:id", h.Delete)
+	r.Get("/writeoffs", middleware.RequirePermission("products.writeoff.access"), h.List)
+	r.Get("/writeoffs/:id", middleware.RequirePermission("products.writeoff.access"), h.Get)
+	r.Post("/writeoffs", middleware.RequirePermission("products.writeoff.create"), h.Create)
+	r.Patch("/writeoffs/:id", middleware.RequirePermission("products.writeoff.update"), h.Update)
+	r.Delete("/writeoffs/:id", middleware.RequirePermission("products.writeoff.delete"), h.Delete)
 }
 
 func (h *WriteOffHandler) List(c *fiber.Ctx) error {
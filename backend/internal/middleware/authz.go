@@ -9,18 +9,20 @@ import (
 )
 
 type Authz struct {
-	users *repositories.UserRepository
-	roles *repositories.RoleRepository
+	users  *repositories.UserRepository
+	roles  *repositories.RoleRepository
+	secret string
 }
 
 var Current *Authz
 
-func NewAuthz(users *repositories.UserRepository, roles *repositories.RoleRepository) *Authz {
-	Current = &Authz{users: users, roles: roles}
+func NewAuthz(users *repositories.UserRepository, roles *repositories.RoleRepository, secret string) *Authz {
+	Current = &Authz{users: users, roles: roles, secret: secret}
 	return Current
 }
 
-// AuthRequired parses a very simple token of the form "<userId>.<ts>" and loads the user.
+// AuthRequired verifies the signed access token and loads the user. The token's tenant claim is exposed as
+// "token_tenant_id" so TenantResolver can keep the request inside the tenant the session was opened for.
 func (a *Authz) AuthRequired() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authz := c.Get("Authorization")
@@ -28,26 +30,53 @@ func (a *Authz) AuthRequired() fiber.Handler {
 			return utils.Unauthorized("UNAUTHORIZED", "Missing token", nil)
 		}
 		tok := strings.TrimSpace(authz[len("Bearer "):])
-		parts := strings.Split(tok, ".")
-		if len(parts) < 1 || len(parts[0]) == 0 {
-			return utils.Unauthorized("UNAUTHORIZED", "Invalid token", nil)
-		}
-		uidStr := parts[0]
-		user, err := a.users.GetByIDHex(c.Context(), uidStr)
+		claims, err := utils.ParseAccessToken(a.secret, tok)
+		if err != nil { return utils.Unauthorized("UNAUTHORIZED", "Invalid token", err) }
+		user, err := a.users.GetByIDHex(c.Context(), claims.UserID)
 		if err != nil { return utils.Unauthorized("UNAUTHORIZED", "User not found", err) }
+		if !user.IsActive || user.IsDeleted { return utils.Unauthorized("USER_INACTIVE", "User is inactive", nil) }
 		c.Locals("user", user)
+		c.Locals("claims", claims)
+		c.Locals("token_tenant_id", claims.TenantID)
 		return c.Next()
 	}
 }
 
+// PermissionAll is granted to the superadmin role and satisfies every permission check.
+const PermissionAll = "*"
+
+// CheckPermission reports whether the current user's role grants perm. Role permissions are loaded once
+// per request and cached in c.Locals("permissions").
 func (a *Authz) CheckPermission(c *fiber.Ctx, perm string) error {
+	perms, err := a.permissionSet(c)
+	if err != nil { return err }
+	if _, ok := perms[PermissionAll]; ok { return nil }
+	if _, ok := perms[perm]; !ok { return utils.Forbidden("PERMISSION_DENIED", "Permission required: "+perm, nil) }
+	return nil
+}
+
+func (a *Authz) permissionSet(c *fiber.Ctx) (map[string]struct{}, error) {
+	if cached, ok := c.Locals("permissions").(map[string]struct{}); ok { return cached, nil }
 	vu := c.Locals("user")
-	if vu == nil { return utils.Unauthorized("UNAUTHORIZED", "Unauthorized", nil) }
+	if vu == nil { return nil, utils.Unauthorized("UNAUTHORIZED", "Unauthorized", nil) }
 	u := vu.(*models.User)
 	role, err := a.roles.Get(c.Context(), u.RoleID)
-	if err != nil { return utils.Forbidden("PERMISSION_DENIED", "Role not found", err) }
+	if err != nil { return nil, utils.Forbidden("PERMISSION_DENIED", "Role not found", err) }
 	perms := map[string]struct{}{}
-	for _, p := range role.Permissions { perms[p] = struct{}{} }
-	if _, ok := perms[perm]; !ok { return utils.Forbidden("PERMISSION_DENIED", "Permission required", nil) }
-	return nil
-} 
\ No newline at end of file
+	if role.IsActive {
+		for _, p := range role.Permissions { perms[p] = struct{}{} }
+	}
+	c.Locals("permissions", perms)
+	return perms, nil
+}
+
+// RequirePermission guards a route with a permission from RoleService.Permissions, e.g.
+// r.Patch("/transfers/:id", middleware.RequirePermission("products.transfer.update"), h.Update).
+// Current is looked up per request so handlers can register routes before NewAuthz runs.
+func RequirePermission(perm string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if Current == nil { return utils.Internal("AUTHZ_NOT_CONFIGURED", "Authorization is not configured", nil) }
+		if err := Current.CheckPermission(c, perm); err != nil { return err }
+		return c.Next()
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"shop/backend/internal/models"
 	"shop/backend/internal/repositories"
 	"shop/backend/internal/utils"
 )
@@ -14,49 +15,58 @@ type TenantResolver struct{ tenants *repositories.TenantRepository }
 
 func NewTenantResolver(repo *repositories.TenantRepository) *TenantResolver { return &TenantResolver{ tenants: repo } }
 
+// Resolve binds the request to a tenant. When the access token carries a tenant claim the request may not
+// switch to a different tenant via X-Tenant-ID, and the claim wins over host/demo fallbacks.
 func (t *TenantResolver) Resolve() fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		tokenTenant, _ := c.Locals("token_tenant_id").(string)
 		// 1) Header first: X-Tenant-ID may be objectId or subdomain key
 		if tid := c.Get("X-Tenant-ID"); tid != "" {
 			// try as ObjectID
 			if oid, err := primitive.ObjectIDFromHex(tid); err == nil {
 				if tenant, err2 := t.tenants.Get(c.Context(), oid); err2 == nil {
-					c.Locals("tenant", tenant)
-					c.Locals("tenant_id", tenant.ID.Hex())
-					if s := c.Get("X-Store-ID"); s != "" { c.Locals("store_id", s) }
-					return c.Next()
+					return bindTenant(c, tenant, tokenTenant)
 				}
 			}
 			// fallback as subdomain key
 			if tenant, err := t.tenants.GetBySubdomain(c.Context(), tid); err == nil {
-				c.Locals("tenant", tenant)
-				c.Locals("tenant_id", tenant.ID.Hex())
-				if s := c.Get("X-Store-ID"); s != "" { c.Locals("store_id", s) }
-				return c.Next()
+				return bindTenant(c, tenant, tokenTenant)
 			}
 		}
-		// 2) Derive from request hostname subdomain (dev/prod with subdomains)
+		// 2) Tenant the session was opened for
+		if tokenTenant != "" {
+			if oid, err := primitive.ObjectIDFromHex(tokenTenant); err == nil {
+				if tenant, err2 := t.tenants.Get(c.Context(), oid); err2 == nil {
+					return bindTenant(c, tenant, tokenTenant)
+				}
+			}
+		}
+		// 3) Derive from request hostname subdomain (dev/prod with subdomains)
 		host := c.Hostname()
 		sub := extractSubdomain(host)
 		if sub != "" {
 			if tenant, err := t.tenants.GetBySubdomain(c.Context(), sub); err == nil {
-				c.Locals("tenant", tenant)
-				c.Locals("tenant_id", tenant.ID.Hex())
-				if s := c.Get("X-Store-ID"); s != "" { c.Locals("store_id", s) }
-				return c.Next()
+				return bindTenant(c, tenant, tokenTenant)
 			}
 		}
-		// 3) Optional dev fallback to "demo" if seeded
+		// 4) Optional dev fallback to "demo" if seeded
 		if tenant, err := t.tenants.GetBySubdomain(c.Context(), "demo"); err == nil {
-			c.Locals("tenant", tenant)
-			c.Locals("tenant_id", tenant.ID.Hex())
-			if s := c.Get("X-Store-ID"); s != "" { c.Locals("store_id", s) }
-			return c.Next()
+			return bindTenant(c, tenant, tokenTenant)
 		}
 		return utils.BadRequest("TENANT_REQUIRED", "Tenant not resolved", nil)
 	}
 }
 
+func bindTenant(c *fiber.Ctx, tenant *models.Tenant, tokenTenant string) error {
+	if tokenTenant != "" && tenant.ID.Hex() != tokenTenant {
+		return utils.Forbidden("TENANT_MISMATCH", "Token was issued for another tenant", nil)
+	}
+	c.Locals("tenant", tenant)
+	c.Locals("tenant_id", tenant.ID.Hex())
+	if s := c.Get("X-Store-ID"); s != "" { c.Locals("store_id", s) }
+	return c.Next()
+}
+
 func extractSubdomain(host string) string {
 	// remove port if present
 	if h, _, err := net.SplitHostPort(host); err == nil { host = h }
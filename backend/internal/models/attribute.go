@@ -7,6 +7,7 @@ import (
 
 type Attribute struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	TenantID  string             `bson:"tenant_id"`
 	Name      string             `bson:"name"`
 	Values    []string           `bson:"values"`
 	IsActive  bool               `bson:"is_active"`
@@ -17,6 +18,7 @@ type Attribute struct {
 
 type AttributeDTO struct {
 	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
 	Name      string    `json:"name"`
 	Values    []string  `json:"values"`
 	IsActive  bool      `json:"is_active"`
@@ -39,6 +41,7 @@ type AttributeUpdate struct {
 func ToAttributeDTO(m Attribute) AttributeDTO {
 	return AttributeDTO{
 		ID:        m.ID.Hex(),
+		TenantID:  m.TenantID,
 		Name:      m.Name,
 		Values:    m.Values,
 		IsActive:  m.IsActive,
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
@@ -7,6 +9,19 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token string  `json:"token"`
-	User  UserDTO `json:"user"`
-} 
\ No newline at end of file
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	User             UserDTO   `json:"user"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	// All revokes every session of the user instead of only the current one.
+	All bool `json:"all"`
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"time"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CashShift is a cashier's working session at one cashbox of a store. Sales are completed against the open shift;
+// closing it compares the money expected from those sales and the cash operations with the money counted.
+
+type CashShift struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID string             `bson:"tenant_id" json:"tenant_id"`
+
+	Number    int64  `bson:"number" json:"number"` // sequential per tenant
+	ShopID    string `bson:"shop_id" json:"shop_id"`
+	ShopName  string `bson:"shop_name" json:"shop_name"`
+	CashboxID string `bson:"cashbox_id" json:"cashbox_id"` // cash register within the store
+	Status    string `bson:"status" json:"status"`         // OPEN | CLOSED
+
+	OpeningFloat float64 `bson:"opening_float" json:"opening_float"`
+	// Running counters, also used to detect sales, returns or operations recorded while the shift is being closed
+	SalesCount      int     `bson:"sales_count" json:"sales_count"`
+	SalesTotal      float64 `bson:"sales_total" json:"sales_total"`
+	ReturnsCount    int     `bson:"returns_count" json:"returns_count"`
+	ReturnsTotal    float64 `bson:"returns_total" json:"returns_total"` // money refunded
+	OperationsCount int     `bson:"operations_count" json:"operations_count"`
+
+	OpenedBy  InventoryUser `bson:"opened_by" json:"opened_by"`
+	OpenedAt  time.Time     `bson:"opened_at" json:"opened_at"`
+	ClosedBy  InventoryUser `bson:"closed_by" json:"closed_by"`
+	ClosedAt  *time.Time    `bson:"closed_at,omitempty" json:"closed_at,omitempty"`
+	Comment   string        `bson:"comment" json:"comment"`
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time     `bson:"updated_at" json:"updated_at"`
+
+	ZReport *CashShiftReport `bson:"z_report,omitempty" json:"z_report,omitempty"`
+}
+
+// Cash operation types
+const (
+	CashOperationIn  = "cash_in"
+	CashOperationOut = "cash_out"
+)
+
+// CashOperation is money put into or taken out of the drawer outside of sales
+type CashOperation struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID  string             `bson:"tenant_id" json:"tenant_id"`
+	ShiftID   primitive.ObjectID `bson:"shift_id" json:"shift_id"`
+	ShopID    string             `bson:"shop_id" json:"shop_id"`
+	Type      string             `bson:"type" json:"type"` // cash_in | cash_out
+	Amount    float64            `bson:"amount" json:"amount"`
+	Reason    string             `bson:"reason" json:"reason"`
+	CreatedBy InventoryUser      `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// CashShiftReport is an X report (interim, shift stays open) or a Z report (final, issued when the shift closes)
+type CashShiftReport struct {
+	Type          string              `bson:"type" json:"type"` // X | Z
+	ShiftID       string              `bson:"shift_id" json:"shift_id"`
+	ShiftNumber   int64               `bson:"shift_number" json:"shift_number"`
+	ShopName      string              `bson:"shop_name" json:"shop_name"`
+	CashboxID     string              `bson:"cashbox_id" json:"cashbox_id"`
+	Cashier       InventoryUser       `bson:"cashier" json:"cashier"`
+	OpenedAt      time.Time           `bson:"opened_at" json:"opened_at"`
+	GeneratedAt   time.Time           `bson:"generated_at" json:"generated_at"`
+	SalesCount    int                 `bson:"sales_count" json:"sales_count"`
+	SalesTotal    float64             `bson:"sales_total" json:"sales_total"`
+	DiscountTotal float64             `bson:"discount_total" json:"discount_total"`
+	ReturnsCount  int                 `bson:"returns_count" json:"returns_count"`
+	RefundsTotal  float64             `bson:"refunds_total" json:"refunds_total"`
+	Repayments    float64             `bson:"repayments" json:"repayments"` // customer debt repayments
+	OpeningFloat  float64             `bson:"opening_float" json:"opening_float"`
+	CashIn        float64             `bson:"cash_in" json:"cash_in"`
+	CashOut       float64             `bson:"cash_out" json:"cash_out"`
+	Methods       []CashMethodSummary `bson:"methods" json:"methods"`
+	ExpectedTotal float64             `bson:"expected_total" json:"expected_total"`
+	CountedTotal  float64             `bson:"counted_total" json:"counted_total"`
+	Difference    float64             `bson:"difference" json:"difference"` // counted - expected
+}
+
+// CashMethodSummary is the money taken by one payment method from sales and debt repayments, net of refunds. For cash, Expected also includes the
+// opening float and the cash operations.
+type CashMethodSummary struct {
+	Method     string  `bson:"method" json:"method"`
+	Sales      float64 `bson:"sales" json:"sales"`
+	Refunds    float64 `bson:"refunds" json:"refunds"`
+	Repayments float64 `bson:"repayments" json:"repayments"`
+	Expected   float64 `bson:"expected" json:"expected"`
+	Counted    float64 `bson:"counted" json:"counted"`
+	Difference float64 `bson:"difference" json:"difference"`
+}
+
+type OpenCashShiftRequest struct {
+	ShopID       string  `json:"shop_id"`
+	CashboxID    string  `json:"cashbox_id"`
+	OpeningFloat float64 `json:"opening_float"`
+}
+
+type CashCountInput struct {
+	Method string  `json:"method"`
+	Amount float64 `json:"amount"`
+}
+
+type CloseCashShiftRequest struct {
+	Counted []CashCountInput `json:"counted"`
+	Comment string           `json:"comment"`
+}
+
+type CreateCashOperationRequest struct {
+	Type   string  `json:"type"`
+	Amount float64 `json:"amount"`
+	Reason string  `json:"reason"`
+}
+
+type CashShiftFilterRequest struct {
+	ShopID    string `json:"shop_id"`
+	CashboxID string `json:"cashbox_id"`
+	Status    string `json:"status"`
+	UserID    string `json:"user_id"`
+	DateFrom  string `json:"date_from"`
+	DateTo    string `json:"date_to"`
+	Page      int    `json:"page"`
+	Limit     int    `json:"limit"`
+}
@@ -7,6 +7,7 @@ import (
 
 type Category struct {
 	ID          primitive.ObjectID  `bson:"_id,omitempty"`
+	TenantID    string              `bson:"tenant_id"`
 	Name        string              `bson:"name"`
 	ParentID    *primitive.ObjectID `bson:"parent_id,omitempty"`
 	Level       int                 `bson:"level"` // 0=root, 1=sub, 2=sub-sub, ... unlimited
@@ -19,6 +20,7 @@ type Category struct {
 
 type CategoryDTO struct {
 	ID           string        `json:"id"`
+	TenantID     string        `json:"tenant_id"`
 	Name         string        `json:"name"`
 	ParentID     *string       `json:"parent_id,omitempty"`
 	Level        int           `json:"level"`
@@ -44,6 +46,7 @@ type CategoryUpdate struct {
 func ToCategoryDTO(m Category) CategoryDTO {
 	dto := CategoryDTO{
 		ID:        m.ID.Hex(),
+		TenantID:  m.TenantID,
 		Name:      m.Name,
 		Level:     m.Level,
 		IsActive:  m.IsActive,
@@ -14,6 +14,7 @@ const (
 
 type Characteristic struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	TenantID  string             `bson:"tenant_id"`
 	Name      string             `bson:"name"`
 	Type      string             `bson:"type"`
 	Values    []string           `bson:"values,omitempty"`
@@ -25,6 +26,7 @@ type Characteristic struct {
 
 type CharacteristicDTO struct {
 	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
 	Name      string    `json:"name"`
 	Type      string    `json:"type"`
 	Values    []string  `json:"values,omitempty"`
@@ -50,6 +52,7 @@ type CharacteristicUpdate struct {
 func ToCharacteristicDTO(m Characteristic) CharacteristicDTO {
 	return CharacteristicDTO{
 		ID:        m.ID.Hex(),
+		TenantID:  m.TenantID,
 		Name:      m.Name,
 		Type:      m.Type,
 		Values:    m.Values,
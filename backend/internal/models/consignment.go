@@ -0,0 +1,158 @@
+package models
+
+import (
+	"time"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Order settlement types. Goods of a konsignatsiya or realizatsiya order stay the supplier's until they are sold:
+// receiving them owes the supplier nothing, a consignment settlement later posts what was sold.
+const (
+	OrderSettlementPurchase    = "purchase"
+	OrderSettlementConsignment = "konsignatsiya"
+	OrderSettlementRealization = "realizatsiya"
+)
+
+// Consignment reports whether the order brings in, or returns, goods held on consignment.
+func (o *Order) Consignment() bool {
+	return o.SettlementType == OrderSettlementConsignment || o.SettlementType == OrderSettlementRealization
+}
+
+// ConsignmentRef marks a stock change as consignment goods of a supplier: received on a consignment order, or sent
+// back to the supplier unsold.
+type ConsignmentRef struct {
+	SupplierID     string
+	OrderName      string
+	SettlementType string
+	SupplyPrice    float64    // owed per unit once sold
+	DueDate        *time.Time // when unsold units are to go back, the product's KonsignatsiyaDate
+}
+
+// ConsignmentBatch is the supplier's share of one consignment receiving. OnHand counts the units neither sold nor
+// returned yet; sales anywhere in the tenant draw on the oldest batches of the product first.
+type ConsignmentBatch struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID       string             `bson:"tenant_id" json:"tenant_id"`
+	SupplierID     string             `bson:"supplier_id" json:"supplier_id"`
+	ProductID      primitive.ObjectID `bson:"product_id" json:"product_id"`
+	ShopID         string             `bson:"shop_id" json:"shop_id"` // the store it was received into
+	OrderID        string             `bson:"order_id" json:"order_id"`
+	OrderName      string             `bson:"order_name" json:"order_name"`
+	SettlementType string             `bson:"settlement_type" json:"settlement_type"`
+	SupplyPrice    float64            `bson:"supply_price" json:"supply_price"`
+	Received       float64            `bson:"received" json:"received"`
+	OnHand         float64            `bson:"on_hand" json:"on_hand"`
+	Sold           float64            `bson:"sold" json:"sold"` // net of sale returns
+	Returned       float64            `bson:"returned" json:"returned"`
+	DueDate        *time.Time         `bson:"due_date,omitempty" json:"due_date,omitempty"`
+	ReceivedAt     time.Time          `bson:"received_at" json:"received_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// ConsignmentMovement is one draw on, or give-back to, a consignment batch. Amount is what the movement adds to the
+// sum owed to the supplier: positive for a sale, negative for a sale return, zero for units sent back unsold.
+type ConsignmentMovement struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID     string             `bson:"tenant_id" json:"tenant_id"`
+	SupplierID   string             `bson:"supplier_id" json:"supplier_id"`
+	BatchID      primitive.ObjectID `bson:"batch_id" json:"batch_id"`
+	ProductID    primitive.ObjectID `bson:"product_id" json:"product_id"`
+	ShopID       string             `bson:"shop_id" json:"shop_id"`
+	SourceType   string             `bson:"source_type" json:"source_type"` // sale | sale_return | return_order
+	SourceID     string             `bson:"source_id" json:"source_id"`
+	OriginID     string             `bson:"origin_id,omitempty" json:"origin_id,omitempty"` // for a sale return, the sale it gives back to
+	Qty          float64            `bson:"qty" json:"qty"`
+	SupplyPrice  float64            `bson:"supply_price" json:"supply_price"`
+	Amount       float64            `bson:"amount" json:"amount"`
+	SettlementID string             `bson:"settlement_id" json:"settlement_id"` // empty until settled
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// ConsignmentSettlement lists a supplier's consignment goods sold in a period at their supply price. Approving it
+// posts the total to the supplier's payables.
+type ConsignmentSettlement struct {
+	ID           primitive.ObjectID          `bson:"_id,omitempty" json:"id"`
+	TenantID     string                      `bson:"tenant_id" json:"tenant_id"`
+	ExternalID   int64                       `bson:"external_id" json:"external_id"`
+	SupplierID   string                      `bson:"supplier_id" json:"supplier_id"`
+	SupplierName string                      `bson:"supplier_name" json:"supplier_name"`
+	ShopID       string                      `bson:"shop_id,omitempty" json:"shop_id,omitempty"` // empty settles every store
+	From         time.Time                   `bson:"from" json:"from"`
+	To           time.Time                   `bson:"to" json:"to"`
+	Status       string                      `bson:"status" json:"status"` // NEW | APPROVED | REJECTED
+	Lines        []ConsignmentSettlementLine `bson:"lines" json:"lines"`
+	TotalQty     float64                     `bson:"total_qty" json:"total_qty"`
+	TotalAmount  float64                     `bson:"total_amount" json:"total_amount"`
+	Comment      string                      `bson:"comment" json:"comment"`
+	MovementIDs  []primitive.ObjectID        `bson:"movement_ids" json:"-"` // the sales settled, claimed on approval
+	CreatedBy    InventoryUser               `bson:"created_by" json:"created_by"`
+	FinishedBy   InventoryUser               `bson:"finished_by" json:"finished_by"`
+	CreatedAt    time.Time                   `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time                   `bson:"updated_at" json:"updated_at"`
+	FinishedAt   *time.Time                  `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+type ConsignmentSettlementLine struct {
+	ProductID   primitive.ObjectID `bson:"product_id" json:"product_id"`
+	ProductName string             `bson:"product_name" json:"product_name"`
+	ProductSKU  string             `bson:"product_sku" json:"product_sku"`
+	Unit        string             `bson:"unit" json:"unit"`
+	Qty         float64            `bson:"qty" json:"qty"` // sold less returned in the period
+	SupplyPrice float64            `bson:"supply_price" json:"supply_price"`
+	Amount      float64            `bson:"amount" json:"amount"`
+}
+
+// ConsignmentStockLine is a product a supplier still has on consignment.
+type ConsignmentStockLine struct {
+	SupplierID   string     `json:"supplier_id"`
+	SupplierName string     `json:"supplier_name"`
+	ProductID    string     `json:"product_id"`
+	ProductName  string     `json:"product_name"`
+	ProductSKU   string     `json:"product_sku"`
+	Unit         string     `json:"unit"`
+	OnHand       float64    `json:"on_hand"`
+	Value        float64    `json:"value"` // at supply price
+	DueDate      *time.Time `json:"due_date,omitempty"` // the earliest return date of its batches
+	Overdue      bool       `json:"overdue"`
+}
+
+// ConsignmentStockReport groups the consignment stock on hand per supplier.
+type ConsignmentStockReport struct {
+	Suppliers  []ConsignmentStockSupplier `json:"suppliers"`
+	TotalValue float64                    `json:"total_value"`
+}
+
+type ConsignmentStockSupplier struct {
+	SupplierID   string                 `json:"supplier_id"`
+	SupplierName string                 `json:"supplier_name"`
+	Items        []ConsignmentStockLine `json:"items"`
+	TotalQty     float64                `json:"total_qty"`
+	TotalValue   float64                `json:"total_value"`
+}
+
+type CreateConsignmentSettlementRequest struct {
+	SupplierID string    `json:"supplier_id"`
+	ShopID     string    `json:"shop_id"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	Comment    string    `json:"comment"`
+}
+
+type UpdateConsignmentSettlementRequest struct {
+	Comment *string `json:"comment"`
+	Action  string  `json:"action"` // approve | reject | ""
+}
+
+type ConsignmentSettlementFilterRequest struct {
+	SupplierID string `json:"supplier_id"`
+	Status     string `json:"status"`
+	Page       int    `json:"page"`
+	Limit      int    `json:"limit"`
+}
+
+// CreateConsignmentReturnRequest drafts a return order of the supplier's unsold consignment stock in a store whose
+// return date has passed.
+type CreateConsignmentReturnRequest struct {
+	SupplierID string `json:"supplier_id"`
+	ShopID     string `json:"shop_id"`
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SaleItemCore is the product type of the refundable core charge a sale adds after each line of a core-bearing part
+// (IsDirtyCore with a CoreCharge). Returning the line against the sale refunds the charge and takes the dirty core into
+// the store's core inventory.
+const SaleItemCore = "CORE"
+
+// CoreStock counts the dirty cores of a product customers brought back to a store. Cores are kept apart from the
+// sellable stock until a core return order sends them back to the supplier for credit.
+type CoreStock struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID  string             `bson:"tenant_id" json:"tenant_id"`
+	ShopID    string             `bson:"shop_id" json:"shop_id"`
+	ProductID primitive.ObjectID `bson:"product_id" json:"product_id"`
+	OnHand    float64            `bson:"on_hand" json:"on_hand"`
+	Received  float64            `bson:"received" json:"received"`
+	Shipped   float64            `bson:"shipped" json:"shipped"` // sent back to the supplier
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// CoreStockLine is a product's dirty cores in a store.
+type CoreStockLine struct {
+	ShopID       string  `json:"shop_id"`
+	ProductID    string  `json:"product_id"`
+	ProductName  string  `json:"product_name"`
+	ProductSKU   string  `json:"product_sku"`
+	SupplierID   string  `json:"supplier_id,omitempty"`
+	SupplierName string  `json:"supplier_name,omitempty"`
+	OnHand       float64 `json:"on_hand"`
+	CoreCharge   float64 `json:"core_charge"`
+	Value        float64 `json:"value"` // at the core charge
+}
+
+// CoreCustomerOutstanding is what a customer still owes in cores: the core charges of their sales not yet returned.
+type CoreCustomerOutstanding struct {
+	CustomerID   string         `json:"customer_id"` // empty for sales without a customer
+	CustomerName string         `json:"customer_name"`
+	Phone        string         `json:"phone"`
+	Qty          float64        `json:"qty"`
+	Amount       float64        `json:"amount"` // core charges to refund when the cores come back
+	Lines        []CoreSaleLine `json:"lines"`
+}
+
+// CoreSaleLine is a sale's core charge line with cores still to come back.
+type CoreSaleLine struct {
+	SaleID        string     `json:"sale_id"`
+	ReceiptNumber int64      `json:"receipt_number"`
+	ShopID        string     `json:"shop_id"`
+	Line          int        `json:"line"` // index of the core charge line, to return it against the sale
+	ProductID     string     `json:"product_id"`
+	ProductName   string     `json:"product_name"`
+	Qty           float64    `json:"qty"`
+	UnitCharge    float64    `json:"unit_charge"`
+	Amount        float64    `json:"amount"`
+	SoldAt        *time.Time `json:"sold_at,omitempty"`
+}
+
+// CoreSupplierOutstanding is what a supplier is still owed in cores of its parts: the dirty cores waiting in the core
+// inventory and the cores still out with customers.
+type CoreSupplierOutstanding struct {
+	SupplierID       string             `json:"supplier_id"` // empty for parts without a supplier
+	SupplierName     string             `json:"supplier_name"`
+	OnHand           float64            `json:"on_hand"`
+	OnHandValue      float64            `json:"on_hand_value"`
+	AtCustomers      float64            `json:"at_customers"`
+	AtCustomersValue float64            `json:"at_customers_value"`
+	Items            []CoreSupplierItem `json:"items"`
+}
+
+type CoreSupplierItem struct {
+	ProductID   string  `json:"product_id"`
+	ProductName string  `json:"product_name"`
+	ProductSKU  string  `json:"product_sku"`
+	CoreCharge  float64 `json:"core_charge"`
+	OnHand      float64 `json:"on_hand"`
+	AtCustomers float64 `json:"at_customers"`
+}
+
+// CreateCoreReturnRequest bundles the dirty cores of a supplier's parts in a store into a core return order.
+type CreateCoreReturnRequest struct {
+	SupplierID string `json:"supplier_id"`
+	ShopID     string `json:"shop_id"`
+}
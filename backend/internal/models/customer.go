@@ -30,6 +30,7 @@ type Customer struct {
 	Telegram        string             `bson:"telegram,omitempty" json:"telegram,omitempty"`
 	Facebook        string             `bson:"facebook,omitempty" json:"facebook,omitempty"`
 	Instagram       string             `bson:"instagram,omitempty" json:"instagram,omitempty"`
+	DebtBalance     float64            `bson:"debt_balance" json:"debt_balance"` // unpaid credit sales, kept by the debts ledger
 	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
 }
@@ -50,6 +51,7 @@ type CustomerDTO struct {
 	Telegram        string           `json:"telegram,omitempty"`
 	Facebook        string           `json:"facebook,omitempty"`
 	Instagram       string           `json:"instagram,omitempty"`
+	Debt            float64          `json:"debt"`
 	CreatedAt       time.Time        `json:"created_at"`
 	UpdatedAt       time.Time        `json:"updated_at"`
 }
@@ -59,7 +61,7 @@ func ToCustomerDTO(m Customer) CustomerDTO {
 		ID: m.ID.Hex(), TenantID: m.TenantID, FirstName: m.FirstName, LastName: m.LastName, MiddleName: m.MiddleName,
 		DateOfBirth: m.DateOfBirth, Gender: m.Gender, PhoneNumber: m.PhoneNumber, PrimaryLanguage: m.PrimaryLanguage,
 		Address: m.Address, Email: m.Email, Telegram: m.Telegram, Facebook: m.Facebook, Instagram: m.Instagram,
-		CreatedAt: m.CreatedAt, UpdatedAt: m.UpdatedAt,
+		Debt: m.DebtBalance, CreatedAt: m.CreatedAt, UpdatedAt: m.UpdatedAt,
 	}
 }
 
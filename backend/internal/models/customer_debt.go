@@ -0,0 +1,132 @@
+package models
+
+import (
+	"time"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CustomerDebt is the unpaid part of a sale made on credit ("nasiya"). It is paid off by repayments following an
+// installment schedule; every change to a customer's balance is also posted to the customer_debt_entries ledger.
+
+type CustomerDebt struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID string             `bson:"tenant_id" json:"tenant_id"`
+
+	CustomerID string       `bson:"customer_id" json:"customer_id"`
+	Customer   SaleCustomer `bson:"customer" json:"customer"`
+	ShopID     string       `bson:"shop_id" json:"shop_id"`
+	ShopName   string       `bson:"shop_name" json:"shop_name"`
+
+	SaleID            string `bson:"sale_id" json:"sale_id"`
+	SaleReceiptNumber int64  `bson:"sale_receipt_number" json:"sale_receipt_number"`
+
+	Status   string     `bson:"status" json:"status"` // OPEN | PAID
+	Amount   float64    `bson:"amount" json:"amount"` // taken on credit
+	Paid     float64    `bson:"paid" json:"paid"`     // repaid
+	Credited float64    `bson:"credited" json:"credited"` // settled by returned goods
+	Balance  float64    `bson:"balance" json:"balance"`
+	DueDate  *time.Time `bson:"due_date,omitempty" json:"due_date,omitempty"` // earliest unpaid installment
+
+	Installments []DebtInstallment `bson:"installments" json:"installments"`
+
+	CreatedBy InventoryUser `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time     `bson:"updated_at" json:"updated_at"`
+	ClosedAt  *time.Time    `bson:"closed_at,omitempty" json:"closed_at,omitempty"`
+}
+
+type DebtInstallment struct {
+	DueDate time.Time `bson:"due_date" json:"due_date"`
+	Amount  float64   `bson:"amount" json:"amount"`
+	Paid    float64   `bson:"paid" json:"paid"`
+}
+
+// Customer debt ledger entry types
+const (
+	DebtEntryCharge    = "charge"    // sale on credit
+	DebtEntryRepayment = "repayment" // money received
+	DebtEntryReturn    = "return"    // returned goods settle the debt
+)
+
+// CustomerDebtEntry is one posting to a customer's receivables ledger. Amount increases the debt when positive;
+// Balance is the customer's total debt after the posting.
+type CustomerDebtEntry struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID   string             `bson:"tenant_id" json:"tenant_id"`
+	CustomerID string             `bson:"customer_id" json:"customer_id"`
+	DebtID     string             `bson:"debt_id" json:"debt_id"`
+	ShopID     string             `bson:"shop_id" json:"shop_id"`
+	Type       string             `bson:"type" json:"type"` // charge | repayment | return
+	Amount     float64            `bson:"amount" json:"amount"`
+	Balance    float64            `bson:"balance" json:"balance"`
+	Method     string             `bson:"method,omitempty" json:"method,omitempty"` // repayments: cash | card | transfer
+	ShiftID    string             `bson:"shift_id,omitempty" json:"shift_id,omitempty"`
+	SaleID     string             `bson:"sale_id,omitempty" json:"sale_id,omitempty"`
+	ReturnID   string             `bson:"return_id,omitempty" json:"return_id,omitempty"`
+	Comment    string             `bson:"comment" json:"comment"`
+	CreatedBy  InventoryUser      `bson:"created_by" json:"created_by"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Debtor is a customer's open debt in a store
+type Debtor struct {
+	CustomerID    string       `bson:"_id" json:"customer_id"`
+	Customer      SaleCustomer `bson:"customer" json:"customer"`
+	Balance       float64      `bson:"balance" json:"balance"`
+	Overdue       float64      `bson:"overdue" json:"overdue"`
+	DebtsCount    int          `bson:"debts_count" json:"debts_count"`
+	OldestDueDate *time.Time   `bson:"oldest_due_date,omitempty" json:"oldest_due_date,omitempty"`
+}
+
+// DebtAging splits unpaid installments by how many days they are overdue
+type DebtAging struct {
+	AsOf      time.Time         `json:"as_of"`
+	Buckets   []DebtAgingBucket `json:"buckets"`
+	Total     float64           `json:"total"`
+	Overdue   float64           `json:"overdue"`
+	Customers int               `json:"customers"`
+}
+
+type DebtAgingBucket struct {
+	Key       string  `json:"key"` // current | 0-30 | 31-60 | 61-90 | 90+
+	Amount    float64 `json:"amount"`
+	Customers int     `json:"customers"`
+}
+
+// DebtReminder is an unpaid installment that is overdue or falls due soon
+type DebtReminder struct {
+	DebtID            string       `json:"debt_id"`
+	CustomerID        string       `json:"customer_id"`
+	Customer          SaleCustomer `json:"customer"`
+	ShopID            string       `json:"shop_id"`
+	SaleReceiptNumber int64        `json:"sale_receipt_number"`
+	DueDate           time.Time    `json:"due_date"`
+	AmountDue         float64      `json:"amount_due"`
+	DaysOverdue       int          `json:"days_overdue"` // negative = days until due
+}
+
+type DebtInstallmentInput struct {
+	DueDate time.Time `json:"due_date"`
+	Amount  float64   `json:"amount"` // all zero = split the debt evenly
+}
+
+type CreateDebtRepaymentRequest struct {
+	ShopID  string  `json:"shop_id"`
+	DebtID  string  `json:"debt_id"` // empty = oldest debts first
+	Amount  float64 `json:"amount"`
+	Method  string  `json:"method"` // cash | card | transfer
+	Comment string  `json:"comment"`
+}
+
+type RescheduleDebtRequest struct {
+	Installments []DebtInstallmentInput `json:"installments"`
+}
+
+type CustomerDebtFilterRequest struct {
+	CustomerID string `json:"customer_id"`
+	ShopID     string `json:"shop_id"`
+	Status     string `json:"status"`
+	Overdue    bool   `json:"overdue"`
+	Page       int    `json:"page"`
+	Limit      int    `json:"limit"`
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"time"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Cycle count plan bases: how a plan picks what each count covers.
+const (
+	CycleCountBasisABC      = "abc"      // products due by the count frequency of their ABC class
+	CycleCountBasisCategory = "category" // the next categories in rotation
+	CycleCountBasisBin      = "bin"      // the next bins in rotation
+)
+
+// CycleCountPlan generates PARTIAL inventories of a store every IntervalDays, prefilled with the current stock of what
+// the run covers. ABC classes rank the products by the cost of what they sold over SalesDays: A up to 80% of it,
+// B up to 95%, C the rest and products that did not sell.
+type CycleCountPlan struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID     string             `bson:"tenant_id" json:"tenant_id"`
+	Name         string             `bson:"name" json:"name"`
+	ShopID       string             `bson:"shop_id" json:"shop_id"`
+	ShopName     string             `bson:"shop_name" json:"shop_name"`
+	Basis        string             `bson:"basis" json:"basis"` // abc | category | bin
+	IntervalDays int                `bson:"interval_days" json:"interval_days"`
+	// abc: days between counts of a product per class, the sales window and the most products per count
+	ClassDays    CycleCountClassDays `bson:"class_days" json:"class_days"`
+	SalesDays    int                 `bson:"sales_days" json:"sales_days"`
+	MaxLines     int                 `bson:"max_lines" json:"max_lines"`
+	// category and bin: what is rotated through, PerRun of them per count; empty rotates every bin of the store
+	CategoryIDs  []string           `bson:"category_ids,omitempty" json:"category_ids,omitempty"`
+	LocationIDs  []string           `bson:"location_ids,omitempty" json:"location_ids,omitempty"`
+	PerRun       int                `bson:"per_run" json:"per_run"`
+	Cursor       int                `bson:"cursor" json:"cursor"` // where the next run starts in the rotation
+	IsActive     bool               `bson:"is_active" json:"is_active"`
+	NextRunAt    time.Time          `bson:"next_run_at" json:"next_run_at"`
+	LastRunAt    *time.Time         `bson:"last_run_at,omitempty" json:"last_run_at,omitempty"`
+	LastInventoryID string          `bson:"last_inventory_id,omitempty" json:"last_inventory_id,omitempty"`
+	CreatedBy    InventoryUser      `bson:"created_by" json:"created_by"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type CycleCountClassDays struct {
+	A int `bson:"a" json:"a"`
+	B int `bson:"b" json:"b"`
+	C int `bson:"c" json:"c"`
+}
+
+type CreateCycleCountPlanRequest struct {
+	Name         string              `json:"name"`
+	ShopID       string              `json:"shop_id"`
+	Basis        string              `json:"basis"`
+	IntervalDays int                 `json:"interval_days"`
+	ClassDays    CycleCountClassDays `json:"class_days"`
+	SalesDays    int                 `json:"sales_days"`
+	MaxLines     int                 `json:"max_lines"`
+	CategoryIDs  []string            `json:"category_ids"`
+	LocationIDs  []string            `json:"location_ids"`
+	PerRun       int                 `json:"per_run"`
+	StartAt      *time.Time          `json:"start_at"` // first run; empty runs it now
+}
+
+type UpdateCycleCountPlanRequest struct {
+	Name         *string              `json:"name"`
+	IntervalDays *int                 `json:"interval_days"`
+	ClassDays    *CycleCountClassDays `json:"class_days"`
+	SalesDays    *int                 `json:"sales_days"`
+	MaxLines     *int                 `json:"max_lines"`
+	CategoryIDs  []string             `json:"category_ids"`
+	LocationIDs  []string             `json:"location_ids"`
+	PerRun       *int                 `json:"per_run"`
+	IsActive     *bool                `json:"is_active"`
+	NextRunAt    *time.Time           `json:"next_run_at"`
+}
+
+type CycleCountPlanFilterRequest struct {
+	ShopID string `json:"shop_id"`
+	Basis  string `json:"basis"`
+	Page   int    `json:"page"`
+	Limit  int    `json:"limit"`
+}
+
+// CountAccuracyRequest selects the finished inventories the accuracy KPIs are computed over.
+type CountAccuracyRequest struct {
+	ShopID       string  `json:"shop_id"`
+	From         string  `json:"from"` // RFC3339; empty is 90 days ago
+	To           string  `json:"to"`   // RFC3339; empty is now
+	Period       string  `json:"period"` // day | week | month
+	TolerancePct float64 `json:"tolerance_pct"` // a line within this share of its declared quantity is accurate
+	CycleOnly    bool    `json:"cycle_only"` // only inventories generated by cycle count plans
+}
+
+// CountAccuracy measures a set of counts. LineAccuracy is the share of lines counted as declared (within the
+// tolerance); QtyAccuracy is 100% less the absolute variance as a share of the declared quantity.
+type CountAccuracy struct {
+	Counts         int     `json:"counts"`
+	Lines          int     `json:"lines"`
+	AccurateLines  int     `json:"accurate_lines"`
+	LineAccuracy   float64 `json:"line_accuracy"`
+	DeclaredQty    float64 `json:"declared_qty"`
+	AbsVarianceQty float64 `json:"abs_variance_qty"`
+	QtyAccuracy    float64 `json:"qty_accuracy"`
+	VarianceValue  float64 `json:"variance_value"` // net value of the differences found
+}
+
+type CountAccuracyPeriod struct {
+	Period string `json:"period"`
+	CountAccuracy
+}
+
+// CountAccuracyGroup is the accuracy of a store, or of a counter (the user who finished the counts), over time.
+type CountAccuracyGroup struct {
+	ID      string                `json:"id"`
+	Name    string                `json:"name"`
+	CountAccuracy
+	Periods []CountAccuracyPeriod `json:"periods"`
+}
+
+type CountAccuracyReport struct {
+	From     time.Time            `json:"from"`
+	To       time.Time            `json:"to"`
+	Period   string               `json:"period"`
+	Total    CountAccuracy        `json:"total"`
+	Stores   []CountAccuracyGroup `json:"stores"`
+	Counters []CountAccuracyGroup `json:"counters"`
+}
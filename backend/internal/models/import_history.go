@@ -29,7 +29,7 @@ type ImportHistoryItem struct {
 	ProductName string             `bson:"product_name" json:"product_name"`
 	ProductSKU  string             `bson:"product_sku" json:"product_sku"`
 	Barcode     string             `bson:"barcode" json:"barcode"`
-	Qty         int                `bson:"qty" json:"qty"`
+	Qty         float64            `bson:"qty" json:"qty"`
 	Unit        string             `bson:"unit" json:"unit"`
 }
 
@@ -75,6 +75,6 @@ type ImportHistoryItemInput struct {
 	ProductName string `json:"product_name"`
 	ProductSKU  string `json:"product_sku"`
 	Barcode     string `json:"barcode"`
-	Qty         int    `json:"qty"`
+	Qty         float64 `json:"qty"`
 	Unit        string `json:"unit"`
 } 
\ No newline at end of file
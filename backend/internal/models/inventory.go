@@ -49,6 +49,10 @@ type Inventory struct {
 	CompanyID string `bson:"company_id" json:"company_id"`
 
 	Items       []InventoryItem `bson:"items" json:"items"`
+	// BinIDs limits the count to these bins; its lines were prefilled from their stock
+	BinIDs      []string        `bson:"bin_ids,omitempty" json:"bin_ids,omitempty"`
+	// CycleCountPlanID is the plan that generated the count
+	CycleCountPlanID string     `bson:"cycle_count_plan_id,omitempty" json:"cycle_count_plan_id,omitempty"`
 	ProcessID   string          `bson:"process_id" json:"process_id"`
 	ProcessType int             `bson:"process_type" json:"process_type"`
 }
@@ -67,8 +71,15 @@ type InventoryItem struct {
 	Declared    float64            `bson:"declared" json:"declared"`
 	Scanned     float64            `bson:"scanned" json:"scanned"`
 	Unit        string             `bson:"unit" json:"unit"`
+	InputUnit   string             `bson:"input_unit,omitempty" json:"input_unit,omitempty"` // unit the count was entered in
 	Price       float64            `bson:"price" json:"price"`
 	CostPrice   float64            `bson:"cost_price" json:"cost_price"`
+	// LotID limits the count to one lot of the product; finishing then corrects only that lot
+	LotID       string             `bson:"lot_id,omitempty" json:"lot_id,omitempty"`
+	LotNumber   string             `bson:"lot_number,omitempty" json:"lot_number,omitempty"`
+	// BinID limits the count to one bin; finishing then corrects only that bin
+	BinID       string             `bson:"bin_id,omitempty" json:"bin_id,omitempty"`
+	BinPath     string             `bson:"bin_path,omitempty" json:"bin_path,omitempty"`
 }
 
 // Input-friendly item for create/update
@@ -83,6 +94,8 @@ type InventoryItemInput struct {
 	Unit        string  `json:"unit"`
 	Price       float64 `json:"price"`
 	CostPrice   float64 `json:"cost_price"`
+	LotID       string  `json:"lot_id"`
+	BinID       string  `json:"bin_id"`
 }
 
 type InventoryFilterRequest struct {
@@ -102,6 +115,11 @@ type CreateInventoryRequest struct {
 	Name   string `json:"name"`
 	ShopID string `json:"shop_id"`
 	Type   string `json:"type"`
+	// LocationIDs counts only the bins in these locations of the store; the count is then PARTIAL
+	LocationIDs []string `json:"location_ids"`
+	// ProductIDs counts only these products, prefilled with their stock in the store; the count is then PARTIAL
+	ProductIDs  []string `json:"product_ids"`
+	CycleCountPlanID string `json:"-"`
 }
 
 type UpdateInventoryRequest struct {
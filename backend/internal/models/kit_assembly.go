@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// KitAssembly turns components into units of an assembled SET in a store, or a disassembly the reverse. On
+// approval the components are taken and the kits put in (or the other way round), the cost of the side taken
+// out carried over to the side put in.
+type KitAssembly struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID   string             `bson:"tenant_id" json:"tenant_id"`
+	ExternalID int64              `bson:"external_id" json:"external_id"`
+	Type       string             `bson:"type" json:"type"` // assembly | disassembly
+
+	ShopID   string `bson:"shop_id" json:"shop_id"`
+	ShopName string `bson:"shop_name" json:"shop_name"`
+
+	ProductID   primitive.ObjectID `bson:"product_id" json:"product_id"` // the kit, an assembled SET
+	ProductName string             `bson:"product_name" json:"product_name"`
+	ProductSKU  string             `bson:"product_sku" json:"product_sku"`
+	Unit        string             `bson:"unit" json:"unit"`
+	Qty         float64            `bson:"qty" json:"qty"` // kits assembled or taken apart
+	Comment     string             `bson:"comment" json:"comment"`
+
+	Status     string         `bson:"status" json:"status"` // NEW | APPROVED | REJECTED
+	Components []KitComponent `bson:"components" json:"components"`
+	UnitCost   float64        `bson:"unit_cost" json:"unit_cost"`   // cost of one kit, set on approval
+	CostTotal  float64        `bson:"cost_total" json:"cost_total"` // cost of all the kits, set on approval
+	Lots       []StockLotPick `bson:"lots,omitempty" json:"lots,omitempty"` // lots a disassembly took the kits from
+
+	CreatedBy  InventoryUser `bson:"created_by" json:"created_by"`
+	FinishedBy InventoryUser `bson:"finished_by" json:"finished_by"`
+	CreatedAt  time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time     `bson:"updated_at" json:"updated_at"`
+	FinishedAt *time.Time    `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+// KitComponent is one product of the kit's composition, snapshotted when the document is created.
+type KitComponent struct {
+	ProductID   primitive.ObjectID `bson:"product_id" json:"product_id"`
+	ProductName string             `bson:"product_name" json:"product_name"`
+	ProductSKU  string             `bson:"product_sku" json:"product_sku"`
+	Unit        string             `bson:"unit" json:"unit"`
+	PerKit      float64            `bson:"per_kit" json:"per_kit"` // in the component's base unit
+	Qty         float64            `bson:"qty" json:"qty"`         // for all the kits
+	UnitCost    float64            `bson:"unit_cost" json:"unit_cost"`
+	CostTotal   float64            `bson:"cost_total" json:"cost_total"`
+	Lots        []StockLotPick     `bson:"lots,omitempty" json:"lots,omitempty"` // lots an assembly took the component from
+}
+
+// Kit document types
+const (
+	KitAssemblyTypeAssembly    = "assembly"
+	KitAssemblyTypeDisassembly = "disassembly"
+)
+
+type CreateKitAssemblyRequest struct {
+	Type      string  `json:"type"` // assembly (default) | disassembly
+	ShopID    string  `json:"shop_id"`
+	ProductID string  `json:"product_id"`
+	Qty       float64 `json:"qty"`
+	Unit      string  `json:"unit"` // any of the kit's units; empty is the base unit
+	Comment   string  `json:"comment"`
+}
+
+type UpdateKitAssemblyRequest struct {
+	Qty     *float64 `json:"qty"`
+	Unit    string   `json:"unit"`
+	Comment *string  `json:"comment"`
+	Action  string   `json:"action"` // approve | reject | ""
+}
+
+type KitAssemblyFilterRequest struct {
+	Type      string `json:"type"`
+	ShopID    string `json:"shop_id"`
+	ProductID string `json:"product_id"`
+	Status    string `json:"status"`
+	Page      int    `json:"page"`
+	Limit     int    `json:"limit"`
+	SortBy    string `json:"sort_by"`
+	SortOrder string `json:"sort_order"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StockLot is one batch of a product held in one store. The lots of a product and store never hold more than its
+// stock balance; the rest of the balance is stock received without a lot.
+type StockLot struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID       string             `bson:"tenant_id" json:"tenant_id"`
+	ProductID      primitive.ObjectID `bson:"product_id" json:"product_id"`
+	ShopID         string             `bson:"shop_id" json:"shop_id"`
+	Number         string             `bson:"number" json:"number"`
+	ExpirationDate *time.Time         `bson:"expiration_date" json:"expiration_date"`
+	Qty            float64            `bson:"qty" json:"qty"`           // on hand
+	Received       float64            `bson:"received" json:"received"` // ever received into the lot
+	OrderID        string             `bson:"order_id" json:"order_id"`
+	OrderName      string             `bson:"order_name" json:"order_name"`
+	SupplierID     string             `bson:"supplier_id" json:"supplier_id"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// StockLotRef attaches a stock change to a lot: an existing lot by ID, or the lot identified by number and expiry,
+// which an increase creates when it does not exist yet.
+type StockLotRef struct {
+	ID             primitive.ObjectID
+	Number         string
+	ExpirationDate *time.Time
+	OrderID        string
+	OrderName      string
+	SupplierID     string
+}
+
+// StockLotPick is the part of a stock movement that went into or out of one lot.
+type StockLotPick struct {
+	LotID          primitive.ObjectID `bson:"lot_id" json:"lot_id"`
+	Number         string             `bson:"number" json:"number"`
+	ExpirationDate *time.Time         `bson:"expiration_date" json:"expiration_date"`
+	Qty            float64            `bson:"qty" json:"qty"`
+}
+
+// StockTaken is what StockService.Take removed: the units' cost and the lots they came from, earliest expiry first.
+type StockTaken struct {
+	UnitCost float64
+	Lots     []StockLotPick
+}
+
+// ExpiringLot is a lot with stock left that expires within the requested window
+type ExpiringLot struct {
+	StockLot
+	ProductName string `json:"product_name"`
+	ProductSKU  string `json:"product_sku"`
+	DaysLeft    int    `json:"days_left"`
+}
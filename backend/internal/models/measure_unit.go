@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MeasureUnit is a unit of measure goods are counted in. Precision is the number of decimals a quantity in the unit
+// may carry, from 0 (pieces) to QtyDecimals (kilograms). The built-in units are always there; a tenant adds its own.
+type MeasureUnit struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID  string             `bson:"tenant_id" json:"tenant_id"`
+	Code      string             `bson:"code" json:"code"`
+	Name      string             `bson:"name" json:"name"`
+	Precision int                `bson:"precision" json:"precision"`
+	BuiltIn   bool               `bson:"-" json:"built_in"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// MeasureUnitInput creates a unit or, with nil fields left alone, updates one. The code of a unit is fixed.
+type MeasureUnitInput struct {
+	Code      string  `json:"code"`
+	Name      *string `json:"name"`
+	Precision *int    `json:"precision"`
+}
+
+// BuiltInMeasureUnits are the units every tenant has.
+var BuiltInMeasureUnits = []MeasureUnit{
+	{Code: "pcs", Name: "Piece", Precision: 0},
+	{Code: "kg", Name: "Kilogram", Precision: 3},
+	{Code: "g", Name: "Gram", Precision: 0},
+	{Code: "l", Name: "Litre", Precision: 3},
+	{Code: "ml", Name: "Millilitre", Precision: 0},
+	{Code: "m", Name: "Metre", Precision: 3},
+	{Code: "m2", Name: "Square metre", Precision: 3},
+	{Code: "box", Name: "Box", Precision: 0},
+	{Code: "pack", Name: "Pack", Precision: 0},
+	{Code: "pallet", Name: "Pallet", Precision: 0},
+}
+
+// ProductUnit is another unit a product is bought, moved or sold in. Factor is how many of the product's base
+// units (Product.Unit) one of it holds: 12 for a box of twelve pieces. Document lines given in it are converted to
+// the base unit, which is what stock is kept in.
+type ProductUnit struct {
+	Unit    string  `bson:"unit" json:"unit"`
+	Factor  float64 `bson:"factor" json:"factor"`
+	Barcode string  `bson:"barcode,omitempty" json:"barcode,omitempty"`
+}
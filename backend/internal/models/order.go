@@ -24,6 +24,8 @@ type Order struct {
 	IsFromFile            bool    `bson:"is_from_file" json:"is_from_file"`
 	SaleProgress          float64 `bson:"sale_progress" json:"sale_progress"`
 	SettlementType        string  `bson:"settlement_type" json:"settlement_type"`
+	// Cores marks a return order of dirty cores: approving it ships them from the core inventory for credit
+	Cores                 bool    `bson:"cores,omitempty" json:"cores,omitempty"`
 	RetailPriceChangeType string  `bson:"retail_price_change_type" json:"retail_price_change_type"`
 
 	SupplierID string        `bson:"supplier_id" json:"supplier_id"`
@@ -61,8 +63,43 @@ type Order struct {
 	ReturnedSupplierOrderID   string `bson:"returned_supplier_order_id" json:"returned_supplier_order_id"`
 	ReturnedSupplierOrderName string `bson:"returned_supplier_order_name" json:"returned_supplier_order_name"`
 
-	Payments []OrderPayment `bson:"payments" json:"payments"`
-	Items    []OrderItem    `bson:"items" json:"items"`
+	Payments   []OrderPayment   `bson:"payments" json:"payments"`
+	Items      []OrderItem      `bson:"items" json:"items"`
+	Receivings []OrderReceiving `bson:"receivings" json:"receivings"`
+
+	// Freight, customs, broker fees and the like, spread into the lines' landed unit cost
+	AdditionalCosts     []OrderAdditionalCost `bson:"additional_costs" json:"additional_costs"`
+	CostAllocations     []OrderCostAllocation `bson:"cost_allocations" json:"cost_allocations"`
+	TotalAdditionalCost float64               `bson:"total_additional_cost" json:"total_additional_cost"`
+}
+
+const (
+	CostAllocationByValue    = "value"
+	CostAllocationByQuantity = "quantity"
+	CostAllocationByWeight   = "weight"
+)
+
+// OrderAdditionalCost is a cost of bringing the goods in that is not paid to the supplier.
+// Amount is in Currency; Rate converts it to the base currency and BaseAmount is the result.
+type OrderAdditionalCost struct {
+	ID         primitive.ObjectID `bson:"_id" json:"id"`
+	Name       string             `bson:"name" json:"name"` // freight, customs, broker...
+	Amount     float64            `bson:"amount" json:"amount"`
+	Currency   string             `bson:"currency" json:"currency"`
+	Rate       float64            `bson:"rate" json:"rate"`
+	BaseAmount float64            `bson:"base_amount" json:"base_amount"`
+	Method     string             `bson:"method" json:"method"` // value, quantity, weight
+}
+
+// OrderCostAllocation is the share of one additional cost that went to one order line.
+type OrderCostAllocation struct {
+	CostID      primitive.ObjectID `bson:"cost_id" json:"cost_id"`
+	Line        int                `bson:"line" json:"line"`
+	ProductID   primitive.ObjectID `bson:"product_id" json:"product_id"`
+	ProductName string             `bson:"product_name" json:"product_name"`
+	Basis       float64            `bson:"basis" json:"basis"` // the line's value, quantity or weight
+	Amount      float64            `bson:"amount" json:"amount"`
+	PerUnit     float64            `bson:"per_unit" json:"per_unit"`
 }
 
 type OrderSupplier struct {
@@ -106,15 +143,51 @@ type OrderItem struct {
 	ProductID        primitive.ObjectID `bson:"product_id" json:"product_id"`
 	ProductName      string             `bson:"product_name" json:"product_name"`
 	ProductSKU       string             `bson:"product_sku" json:"product_sku"`
-	Quantity         int                `bson:"quantity" json:"quantity"`
+	Quantity         float64            `bson:"quantity" json:"quantity"`
 	UnitPrice        float64            `bson:"unit_price" json:"unit_price"`
 	TotalPrice       float64            `bson:"total_price" json:"total_price"`
 	SupplyPrice      float64            `bson:"supply_price" json:"supply_price"`
 	RetailPrice      float64            `bson:"retail_price" json:"retail_price"`
-	AcceptedQuantity int                `bson:"accepted_quantity" json:"accepted_quantity"`
-	ReturnedQuantity int                `bson:"returned_quantity" json:"returned_quantity"`
+	AcceptedQuantity float64            `bson:"accepted_quantity" json:"accepted_quantity"`
+	ReturnedQuantity float64            `bson:"returned_quantity" json:"returned_quantity"`
+	// CancelledQuantity is the part of the line that will not be delivered any more
+	CancelledQuantity float64           `bson:"cancelled_quantity" json:"cancelled_quantity"`
+	// LandedCost is the additional cost per unit allocated to the line; stock is valued at SupplyPrice + LandedCost
+	LandedCost       float64            `bson:"landed_cost" json:"landed_cost"`
+	// LotNumber and ExpirationDate are the lot the line is expected in; a receiving may name another one
+	LotNumber        string             `bson:"lot_number,omitempty" json:"lot_number,omitempty"`
+	ExpirationDate   *time.Time         `bson:"expiration_date,omitempty" json:"expiration_date,omitempty"`
+	// Serials are the units of a serial-tracked product: delivered on approval, or sent back by a return order
+	Serials          []string           `bson:"serials,omitempty" json:"serials,omitempty"`
 	MeasurementValue float64            `bson:"measurement_value" json:"measurement_value"`
 	Unit             string             `bson:"unit" json:"unit"`
+	// InputQty and InputUnit are the quantity as ordered when it was given in another unit than the base one;
+	// Quantity and the prices are per base unit
+	InputQty         float64            `bson:"input_qty,omitempty" json:"input_qty,omitempty"`
+	InputUnit        string             `bson:"input_unit,omitempty" json:"input_unit,omitempty"`
+}
+
+// OrderReceiving is one delivery accepted against a supplier order.
+type OrderReceiving struct {
+	ID         primitive.ObjectID   `bson:"_id" json:"id"`
+	Items      []OrderReceivingItem `bson:"items" json:"items"`
+	Comment    string               `bson:"comment" json:"comment"`
+	ReceivedBy OrderUser            `bson:"received_by" json:"received_by"`
+	ReceivedAt time.Time            `bson:"received_at" json:"received_at"`
+	PutawayID  string               `bson:"putaway_id,omitempty" json:"putaway_id,omitempty"` // drafted when the store has bins
+}
+
+type OrderReceivingItem struct {
+	Line        int                `bson:"line" json:"line"` // index into Order.Items
+	ProductID   primitive.ObjectID `bson:"product_id" json:"product_id"`
+	ProductName string             `bson:"product_name" json:"product_name"`
+	ProductSKU  string             `bson:"product_sku" json:"product_sku"`
+	Quantity    float64            `bson:"quantity" json:"quantity"`
+	SupplyPrice float64            `bson:"supply_price" json:"supply_price"`
+	Unit        string             `bson:"unit" json:"unit"`
+	LotNumber      string          `bson:"lot_number,omitempty" json:"lot_number,omitempty"`
+	ExpirationDate *time.Time      `bson:"expiration_date,omitempty" json:"expiration_date,omitempty"`
+	Serials        []string        `bson:"serials,omitempty" json:"serials,omitempty"`
 }
 
 // Payload-friendly input for items (string ids)
@@ -124,12 +197,15 @@ type OrderItemInput struct {
 	ProductID   string  `json:"product_id"`
 	ProductName string  `json:"product_name"`
 	ProductSKU  string  `json:"product_sku"`
-	Quantity    int     `json:"quantity"`
+	Quantity    float64 `json:"quantity"`
 	UnitPrice   float64 `json:"unit_price"`
 	SupplyPrice float64 `json:"supply_price"`
 	RetailPrice float64 `json:"retail_price"`
 	Unit        string  `json:"unit"`
-	ReturnedQuantity int `json:"returned_quantity"`
+	ReturnedQuantity float64 `json:"returned_quantity"`
+	LotNumber      string     `json:"lot_number"`
+	ExpirationDate *time.Time `json:"expiration_date"`
+	Serials        []string   `json:"serials"`
 }
 
 type OrderStatus struct {
@@ -175,6 +251,8 @@ type CreateOrderRequest struct {
 	TotalSupplyPrice float64          `json:"total_supply_price"`
 	TotalRetailPrice float64          `json:"total_retail_price"`
 	ReturnedSupplierOrderID string    `json:"returned_supplier_order_id"`
+	AdditionalCosts  []OrderAdditionalCostInput `json:"additional_costs"`
+	Cores            bool             `json:"cores"` // a return order of dirty cores
 }
 
 type UpdateOrderRequest struct {
@@ -190,7 +268,31 @@ type UpdateOrderRequest struct {
 	TotalPaidAmount  float64          `json:"total_paid_amount"`
 	IsFinished       bool             `json:"is_finished"`
 	SaleProgress     float64          `json:"sale_progress"`
-	Action           string           `json:"action"`
+	Action           string           `json:"action"` // approve, receive, cancel_remaining, reject
+	// Receive lists the quantities delivered in this receiving (action=receive)
+	Receive          []OrderReceiveInput `json:"receive"`
+	// AdditionalCosts replaces the order's additional costs when not nil
+	AdditionalCosts  *[]OrderAdditionalCostInput `json:"additional_costs"`
+}
+
+type OrderAdditionalCostInput struct {
+	Name     string  `json:"name"`
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+	Rate     float64 `json:"rate"` // optional; USD costs default to the exchange rate in effect
+	Method   string  `json:"method"`
+}
+
+// OrderReceiveInput addresses an order line by its index or, failing that, by product. LotNumber and
+// ExpirationDate default to the line's; Serials name the received units of a serial-tracked product.
+type OrderReceiveInput struct {
+	Line           *int       `json:"line"`
+	ProductID      string     `json:"product_id"`
+	Quantity       float64    `json:"quantity"`
+	Unit           string     `json:"unit"` // any of the product's units; empty is the base unit
+	LotNumber      string     `json:"lot_number"`
+	ExpirationDate *time.Time `json:"expiration_date"`
+	Serials        []string   `json:"serials"`
 }
 
 type OrderFilterRequest struct {
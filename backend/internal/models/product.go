@@ -15,10 +15,12 @@ type Product struct {
 	Description string             `bson:"description" json:"description"`
 	Price       float64            `bson:"price" json:"price" binding:"required,min=0"`
 	CostPrice   float64            `bson:"cost_price" json:"cost_price" binding:"min=0"`
-	Stock       int                `bson:"stock" json:"stock" binding:"min=0"`
-	MinStock    int                `bson:"min_stock" json:"min_stock"`
-	MaxStock    int                `bson:"max_stock" json:"max_stock"`
-	Unit        string             `bson:"unit" json:"unit"`
+	Stock       float64            `bson:"stock" json:"stock" binding:"min=0"`
+	MinStock    float64            `bson:"min_stock" json:"min_stock"`
+	MaxStock    float64            `bson:"max_stock" json:"max_stock"`
+	Unit        string             `bson:"unit" json:"unit"` // base unit stock is kept in
+	// Units are the other units the product is bought, moved or sold in, with their size in base units
+	Units       []ProductUnit      `bson:"units,omitempty" json:"units,omitempty"`
 	Weight      float64            `bson:"weight" json:"weight"`
 	Dimensions  ProductDimensions  `bson:"dimensions" json:"dimensions"`
 	CategoryID  primitive.ObjectID `bson:"category_id,omitempty" json:"category_id"`
@@ -48,10 +50,16 @@ type Product struct {
 	ProductType string `bson:"product_type" json:"product_type"`
 	// Composition for SET kind
 	SetItems    []SetItem `bson:"set_items,omitempty" json:"set_items,omitempty"`
+	// SetMode says how a SET is stocked: virtual (the default) sells its components, assembled holds its own stock
+	SetMode     string    `bson:"set_mode,omitempty" json:"set_mode,omitempty"`
 
 	Barcode              string                 `bson:"barcode" json:"barcode"`
 	ExpirationDate       *time.Time             `bson:"expiration_date,omitempty" json:"expiration_date,omitempty"`
 	IsDirtyCore          bool                   `bson:"is_dirty_core" json:"is_dirty_core"`
+	// CoreCharge is the refundable deposit a sale of a core-bearing part charges per unit until the dirty core comes back
+	CoreCharge           float64                `bson:"core_charge" json:"core_charge"`
+	// SerialTracked products move by serial number: every unit received, moved, sold or written off is named
+	SerialTracked        bool                   `bson:"serial_tracked" json:"serial_tracked"`
 	IsRealizatsiya       bool                   `bson:"is_realizatsiya" json:"is_realizatsiya"`
 	IsKonsignatsiya      bool                   `bson:"is_konsignatsiya" json:"is_konsignatsiya"`
 	KonsignatsiyaDate    *time.Time             `bson:"konsignatsiya_date,omitempty" json:"konsignatsiya_date,omitempty"`
@@ -87,7 +95,7 @@ type ProductVariant struct {
 	SKU         string             `bson:"sku" json:"sku"`
 	Price       float64            `bson:"price" json:"price"`
 	CostPrice   float64            `bson:"cost_price" json:"cost_price"`
-	Stock       int                `bson:"stock" json:"stock"`
+	Stock       float64            `bson:"stock" json:"stock"`
 	Barcode     string             `bson:"barcode" json:"barcode"`
 	Images      []string           `bson:"images" json:"images"`
 	Attributes  []ProductAttribute `bson:"attributes" json:"attributes"`
@@ -98,9 +106,9 @@ type ProductVariant struct {
 
 type ProductWarehouse struct {
 	WarehouseID primitive.ObjectID `bson:"warehouse_id" json:"warehouse_id"`
-	Stock       int                `bson:"stock" json:"stock"`
-	MinStock    int                `bson:"min_stock" json:"min_stock"`
-	MaxStock    int                `bson:"max_stock" json:"max_stock"`
+	Stock       float64            `bson:"stock" json:"stock"`
+	MinStock    float64            `bson:"min_stock" json:"min_stock"`
+	MaxStock    float64            `bson:"max_stock" json:"max_stock"`
 	Location    string             `bson:"location" json:"location"` // shelf, zone, etc.
 }
 
@@ -123,16 +131,19 @@ type ProductCatalogParameter struct {
 // Bundle items
 type BundleItem struct {
 	ProductID primitive.ObjectID `bson:"product_id" json:"product_id"`
-	Quantity  int                `bson:"quantity" json:"quantity"`
+	Quantity  float64            `bson:"quantity" json:"quantity"`
 	Price     *float64           `bson:"price,omitempty" json:"price,omitempty"` // override price if needed
 }
 
 // Set item for SET kind
 type SetItem struct {
 	ProductID primitive.ObjectID `bson:"product_id" json:"product_id"`
-	Quantity  int                `bson:"quantity" json:"quantity"`
+	Quantity  float64            `bson:"quantity" json:"quantity"` // in the component's base unit
 }
 
+// VirtualSet reports whether p is a SET sold as its components rather than stocked as an item of its own.
+func (p *Product) VirtualSet() bool { return p.ProductType == ProductKindSet && p.SetMode != ProductSetAssembled }
+
 // Product status and type constants
 const (
 	ProductStatusActive   = "active"
@@ -143,6 +154,9 @@ const (
 	ProductKindProduct = "PRODUCT"
 	ProductKindSet     = "SET"
 	ProductKindService = "SERVICE"
+
+	ProductSetVirtual   = "virtual"
+	ProductSetAssembled = "assembled"
 )
 
 // DTO structs
@@ -155,10 +169,11 @@ type ProductDTO struct {
 	Description string             `json:"description"`
 	Price       float64            `json:"price"`
 	CostPrice   float64            `json:"cost_price"`
-	Stock       int                `json:"stock"`
-	MinStock    int                `json:"min_stock"`
-	MaxStock    int                `json:"max_stock"`
+	Stock       float64            `json:"stock"`
+	MinStock    float64            `json:"min_stock"`
+	MaxStock    float64            `json:"max_stock"`
 	Unit        string             `json:"unit"`
+	Units       []ProductUnit      `json:"units,omitempty"`
 	Weight      float64            `json:"weight"`
 	Dimensions  ProductDimensions  `json:"dimensions"`
 	CategoryID  string             `json:"category_id,omitempty"`
@@ -175,6 +190,11 @@ type ProductDTO struct {
 	Attributes  []ProductAttribute `json:"attributes"`
 	Variants    []ProductVariant   `json:"variants"`
 	Warehouses  []ProductWarehouse `json:"warehouses"`
+	// Quantities per store; Stock is their total
+	StoreStocks []ProductStoreStock `json:"store_stocks"`
+	// Reserved is held by pending documents over all stores; Available is Stock less Reserved
+	Reserved    float64             `json:"reserved"`
+	Available   float64             `json:"available"`
 
 	// Catalog management relationships
 	CatalogAttributes      []ProductCatalogAttribute      `json:"catalog_attributes,omitempty"`
@@ -189,10 +209,13 @@ type ProductDTO struct {
 
 	ProductType string                 `json:"product_type"`
 	SetItems    []SetItem              `json:"set_items,omitempty"`
+	SetMode     string                 `json:"set_mode,omitempty"`
 
 	Barcode              string                 `json:"barcode"`
 	ExpirationDate       *time.Time             `json:"expiration_date,omitempty"`
 	IsDirtyCore          bool                   `json:"is_dirty_core"`
+	CoreCharge           float64                `json:"core_charge"`
+	SerialTracked        bool                   `json:"serial_tracked"`
 	IsRealizatsiya       bool                   `json:"is_realizatsiya"`
 	IsKonsignatsiya      bool                   `json:"is_konsignatsiya"`
 	KonsignatsiyaDate    *time.Time             `json:"konsignatsiya_date,omitempty"`
@@ -216,10 +239,11 @@ type ProductCreate struct {
 	Description string             `json:"description"`
 	Price       float64            `json:"price" binding:"required,min=0"`
 	CostPrice   float64            `json:"cost_price" binding:"min=0"`
-	Stock       int                `json:"stock" binding:"min=0"`
-	MinStock    int                `json:"min_stock"`
-	MaxStock    int                `json:"max_stock"`
+	Stock       float64            `json:"stock" binding:"min=0"`
+	MinStock    float64            `json:"min_stock"`
+	MaxStock    float64            `json:"max_stock"`
 	Unit        string             `json:"unit"`
+	Units       []ProductUnit      `json:"units,omitempty"`
 	Weight      float64            `json:"weight"`
 	Dimensions  ProductDimensions  `json:"dimensions"`
 	CategoryID  string             `json:"category_id,omitempty"`
@@ -246,10 +270,13 @@ type ProductCreate struct {
 
 	ProductType string                 `json:"product_type"`
 	SetItems    []SetItem              `json:"set_items,omitempty"`
+	SetMode     string                 `json:"set_mode,omitempty"`
 
 	Barcode              string                 `json:"barcode"`
 	ExpirationDate       *time.Time             `json:"expiration_date,omitempty"`
 	IsDirtyCore          bool                   `json:"is_dirty_core"`
+	CoreCharge           float64                `json:"core_charge"`
+	SerialTracked        bool                   `json:"serial_tracked"`
 	IsRealizatsiya       bool                   `json:"is_realizatsiya"`
 	IsKonsignatsiya      bool                   `json:"is_konsignatsiya"`
 	KonsignatsiyaDate    *time.Time             `json:"konsignatsiya_date,omitempty"`
@@ -290,10 +317,11 @@ type ProductUpdate struct {
 	Description *string             `json:"description"`
 	Price       *float64            `json:"price"`
 	CostPrice   *float64            `json:"cost_price"`
-	Stock       *int                `json:"stock"`
-	MinStock    *int                `json:"min_stock"`
-	MaxStock    *int                `json:"max_stock"`
+	Stock       *float64            `json:"stock"`
+	MinStock    *float64            `json:"min_stock"`
+	MaxStock    *float64            `json:"max_stock"`
 	Unit        *string             `json:"unit"`
+	Units       []ProductUnit       `json:"units"`
 	Weight      *float64            `json:"weight"`
 	Dimensions  *ProductDimensions  `json:"dimensions"`
 	CategoryID  *string             `json:"category_id"`
@@ -320,10 +348,13 @@ type ProductUpdate struct {
 
 	ProductType *string                `json:"product_type"`
 	SetItems    []SetItem              `json:"set_items"`
+	SetMode     *string                `json:"set_mode"` // virtual | assembled, SET only
 
 	Barcode              *string                `json:"barcode"`
 	ExpirationDate       *time.Time             `json:"expiration_date"`
 	IsDirtyCore          *bool                  `json:"is_dirty_core"`
+	CoreCharge           *float64               `json:"core_charge"`
+	SerialTracked        *bool                  `json:"serial_tracked"`
 	IsRealizatsiya       *bool                  `json:"is_realizatsiya"`
 	IsKonsignatsiya      *bool                  `json:"is_konsignatsiya"`
 	KonsignatsiyaDate    *time.Time             `json:"konsignatsiya_date"`
@@ -347,12 +378,14 @@ func ToProductDTO(m Product) ProductDTO {
 		MinStock:    m.MinStock,
 		MaxStock:    m.MaxStock,
 		Unit:        m.Unit,
+		Units:       m.Units,
 		Weight:      m.Weight,
 		Dimensions:  m.Dimensions,
 		Images:      m.Images,
 		Attributes:  m.Attributes,
 		Variants:    m.Variants,
 		Warehouses:  m.Warehouses,
+		StoreStocks: []ProductStoreStock{},
 
 		CatalogAttributes:      m.CatalogAttributes,
 		CatalogCharacteristics: m.CatalogCharacteristics,
@@ -365,10 +398,13 @@ func ToProductDTO(m Product) ProductDTO {
 
 		ProductType: m.ProductType,
 		SetItems:    m.SetItems,
+		SetMode:     m.SetMode,
 
 		Barcode:              m.Barcode,
 		ExpirationDate:       m.ExpirationDate,
 		IsDirtyCore:          m.IsDirtyCore,
+		CoreCharge:           m.CoreCharge,
+		SerialTracked:        m.SerialTracked,
 		IsRealizatsiya:       m.IsRealizatsiya,
 		IsKonsignatsiya:      m.IsKonsignatsiya,
 		KonsignatsiyaDate:    m.KonsignatsiyaDate,
@@ -0,0 +1,11 @@
+package models
+
+import "math"
+
+// QtyDecimals is the precision stock quantities are kept at. Quantities are float64 everywhere, and every stored
+// quantity is rounded to it (RoundQty in Go, $round in update pipelines), so goods sold by weight or length add up
+// without float drift.
+const QtyDecimals = 3
+
+// RoundQty rounds a quantity to QtyDecimals.
+func RoundQty(v float64) float64 { return math.Round(v*1000) / 1000 }
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshToken is the server-side record of an issued refresh token. Only the hash is stored.
+// Tokens issued by rotating one login share a FamilyID so reuse of a rotated token revokes the whole chain.
+type RefreshToken struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	TenantID   string              `bson:"tenant_id" json:"tenant_id"`
+	FamilyID   primitive.ObjectID  `bson:"family_id" json:"family_id"`
+	TokenHash  string              `bson:"token_hash" json:"-"`
+	ExpiresAt  time.Time           `bson:"expires_at" json:"expires_at"`
+	RevokedAt  *time.Time          `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	ReplacedBy *primitive.ObjectID `bson:"replaced_by,omitempty" json:"replaced_by,omitempty"`
+	CreatedAt  time.Time           `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,50 @@
+package models
+
+// ReplenishmentLine is the suggested reorder of one product for one store. Quantities are in the product's base unit.
+type ReplenishmentLine struct {
+	ProductID    string  `json:"product_id"`
+	ProductName  string  `json:"product_name"`
+	ProductSKU   string  `json:"product_sku"`
+	Unit         string  `json:"unit"`
+	Stock        float64 `json:"stock"`    // on hand in the store
+	OnOrder      float64 `json:"on_order"` // still to arrive on the store's open supplier orders
+	MinStock     float64 `json:"min_stock"`
+	MaxStock     float64 `json:"max_stock"`
+	DailySales   float64 `json:"daily_sales"`   // net of sale returns, averaged over the sales window
+	ReorderPoint float64 `json:"reorder_point"` // the level at or below which the product is reordered
+	TargetStock  float64 `json:"target_stock"`  // the level a reorder fills up to
+	SuggestedQty float64 `json:"suggested_qty"`
+	SupplyPrice  float64 `json:"supply_price"`
+	Amount       float64 `json:"amount"`
+}
+
+// ReplenishmentGroup gathers the suggestions of one supplier; SupplierID is empty for products without a supplier.
+type ReplenishmentGroup struct {
+	SupplierID   string              `json:"supplier_id"`
+	SupplierName string              `json:"supplier_name"`
+	Items        []ReplenishmentLine `json:"items"`
+	TotalAmount  float64             `json:"total_amount"`
+}
+
+// ReplenishmentSuggestions are the reorders a store needs, per supplier.
+type ReplenishmentSuggestions struct {
+	ShopID    string               `json:"shop_id"`
+	SalesDays int                  `json:"sales_days"`
+	CoverDays int                  `json:"cover_days"`
+	Groups    []ReplenishmentGroup `json:"groups"`
+}
+
+// ReplenishmentRequest sets up the calculation. SalesDays is the window daily sales are averaged over (30 by
+// default); CoverDays is how many days of those sales the stock should last (0 keeps to min/max alone).
+type ReplenishmentRequest struct {
+	ShopID    string `json:"shop_id" query:"shop_id"`
+	SalesDays int    `json:"sales_days" query:"sales_days"`
+	CoverDays int    `json:"cover_days" query:"cover_days"`
+	// SupplierIDs limits the draft orders created to these suppliers; empty means all of them
+	SupplierIDs []string `json:"supplier_ids" query:"-"`
+}
+
+// ReplenishmentOrders lists the draft supplier orders created from the suggestions.
+type ReplenishmentOrders struct {
+	Orders []Order `json:"orders"`
+}
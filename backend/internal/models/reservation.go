@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StockReservation holds units of a product in a store for a pending document, so that two drafts cannot promise
+// the same unit. It lapses at ExpiresAt and is released when the document is approved, rejected or deleted.
+type StockReservation struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID   string             `bson:"tenant_id" json:"tenant_id"`
+	ProductID  primitive.ObjectID `bson:"product_id" json:"product_id"`
+	ShopID     string             `bson:"shop_id" json:"shop_id"`
+	Qty        float64            `bson:"qty" json:"qty"` // in the product's base unit
+	SourceType string             `bson:"source_type" json:"source_type"`
+	SourceID   string             `bson:"source_id" json:"source_id"`
+	SourceName string             `bson:"source_name" json:"source_name"`
+	CreatedBy  InventoryUser      `bson:"created_by" json:"created_by"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"expires_at"`
+}
+
+// Reservation sources besides the stock movement sources of the documents that reserve automatically
+const (
+	ReservationSourceServiceOrder = "service_order"
+	ReservationSourceManual       = "manual"
+)
+
+// DefaultReservationTTLHours applies when the tenant has not set TenantSettings.ReservationTTLHours
+const DefaultReservationTTLHours = 24
+
+// ReservationLine is one product a document reserves, in its base unit.
+type ReservationLine struct {
+	ProductID primitive.ObjectID
+	Qty       float64
+}
+
+// CreateReservationRequest reserves stock for a document that does not reserve by itself, such as a service work
+// order. Reserving again for the same source replaces its earlier reservation.
+type CreateReservationRequest struct {
+	ShopID     string                 `json:"shop_id"`
+	SourceType string                 `json:"source_type"` // service_order | manual
+	SourceID   string                 `json:"source_id"`
+	SourceName string                 `json:"source_name"`
+	Items      []ReservationItemInput `json:"items"`
+}
+
+type ReservationItemInput struct {
+	ProductID string  `json:"product_id"`
+	Qty       float64 `json:"qty"`
+	Unit      string  `json:"unit"` // any of the product's units; empty is the base unit
+}
+
+type ReservationFilterRequest struct {
+	ProductID  string `json:"product_id"`
+	ShopID     string `json:"shop_id"`
+	SourceType string `json:"source_type"`
+	SourceID   string `json:"source_id"`
+	Page       int    `json:"page"`
+	Limit      int    `json:"limit"`
+}
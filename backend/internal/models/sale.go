@@ -0,0 +1,187 @@
+package models
+
+import (
+	"time"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Sale is a point-of-sale document. While NEW it is a cart that can be edited; completing it takes the goods out of
+// the store's stock, fixes the cost of goods sold and issues a receipt.
+
+type Sale struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID string             `bson:"tenant_id" json:"tenant_id"`
+
+	ExternalID    int64  `bson:"external_id" json:"external_id"`
+	ReceiptNumber int64  `bson:"receipt_number" json:"receipt_number"` // sequential per tenant, set on completion
+	Type          string `bson:"type" json:"type"`                     // sale
+	Status        string `bson:"status" json:"status"`                 // NEW | COMPLETED | CANCELLED
+	PaymentStatus string `bson:"payment_status" json:"payment_status"` // unpaid | partially_paid | paid
+	Comment       string `bson:"comment" json:"comment"`
+
+	ShopID   string `bson:"shop_id" json:"shop_id"`
+	ShopName string `bson:"shop_name" json:"shop_name"`
+
+	// Cash shift the sale was completed in
+	ShiftID     string `bson:"shift_id,omitempty" json:"shift_id,omitempty"`
+	ShiftNumber int64  `bson:"shift_number,omitempty" json:"shift_number,omitempty"`
+
+	CustomerID string       `bson:"customer_id,omitempty" json:"customer_id,omitempty"`
+	Customer   SaleCustomer `bson:"customer" json:"customer"`
+
+	Items    []SaleItem    `bson:"items" json:"items"`
+	Payments []SalePayment `bson:"payments" json:"payments"`
+
+	Subtotal      float64 `bson:"subtotal" json:"subtotal"` // before discounts
+	DiscountTotal float64 `bson:"discount_total" json:"discount_total"`
+	Total         float64 `bson:"total" json:"total"`
+	PaidAmount    float64 `bson:"paid_amount" json:"paid_amount"` // payments applied to the total, change excluded
+	ChangeAmount  float64 `bson:"change_amount" json:"change_amount"`
+	CostTotal     float64 `bson:"cost_total" json:"cost_total"` // cost of goods sold
+	GrossProfit   float64 `bson:"gross_profit" json:"gross_profit"`
+
+	// Customer returns against this sale
+	ReturnStatus   string  `bson:"return_status,omitempty" json:"return_status,omitempty"` // partially_returned | returned
+	ReturnedAmount float64 `bson:"returned_amount" json:"returned_amount"`
+	RefundedAmount float64 `bson:"refunded_amount" json:"refunded_amount"`
+	// Cores still to come back for the sale's core charge lines
+	CoresOutstanding float64 `bson:"cores_outstanding,omitempty" json:"cores_outstanding,omitempty"`
+
+	CreatedBy   InventoryUser `bson:"created_by" json:"created_by"`
+	CompletedBy InventoryUser `bson:"completed_by" json:"completed_by"`
+	CreatedAt   time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time     `bson:"updated_at" json:"updated_at"`
+	CompletedAt *time.Time    `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+
+	Receipt *SaleReceipt `bson:"receipt,omitempty" json:"receipt,omitempty"`
+	DebtID  string       `bson:"debt_id,omitempty" json:"debt_id,omitempty"` // credit sale
+}
+
+type SaleCustomer struct {
+	ID    string `bson:"id" json:"id"`
+	Name  string `bson:"name" json:"name"`
+	Phone string `bson:"phone" json:"phone"`
+}
+
+// SaleItem is one sold line. A SET line takes its components out of stock; a SERVICE line and a CORE charge line
+// take nothing.
+type SaleItem struct {
+	ProductID       primitive.ObjectID `bson:"product_id" json:"product_id"`
+	VariantID       primitive.ObjectID `bson:"variant_id,omitempty" json:"variant_id,omitempty"`
+	VariantName     string             `bson:"variant_name,omitempty" json:"variant_name,omitempty"`
+	ProductName     string             `bson:"product_name" json:"product_name"`
+	ProductSKU      string             `bson:"product_sku" json:"product_sku"`
+	Barcode         string             `bson:"barcode" json:"barcode"`
+	ProductType     string             `bson:"product_type" json:"product_type"` // PRODUCT | SET | SERVICE | CORE
+	Qty             float64            `bson:"qty" json:"qty"`
+	Unit            string             `bson:"unit" json:"unit"`
+	InputQty        float64            `bson:"input_qty,omitempty" json:"input_qty,omitempty"` // as entered in InputUnit
+	InputUnit       string             `bson:"input_unit,omitempty" json:"input_unit,omitempty"`
+	UnitPrice       float64            `bson:"unit_price" json:"unit_price"`
+	CatalogPrice    float64            `bson:"catalog_price,omitempty" json:"catalog_price,omitempty"` // product or variant price, set when UnitPrice overrides it
+	DiscountPercent float64            `bson:"discount_percent" json:"discount_percent"`
+	DiscountAmount  float64            `bson:"discount_amount" json:"discount_amount"` // total line discount
+	Total           float64            `bson:"total" json:"total"`                     // qty * unit_price - discount_amount
+	UnitCost        float64            `bson:"unit_cost" json:"unit_cost"`
+	CostTotal       float64            `bson:"cost_total" json:"cost_total"`
+	ReturnedQty     float64            `bson:"returned_qty" json:"returned_qty"`
+	Components      []SaleComponent    `bson:"components,omitempty" json:"components,omitempty"`
+	Assembled       bool               `bson:"assembled,omitempty" json:"assembled,omitempty"` // a SET sold from its own stock, not as its components
+	Lots            []StockLotPick     `bson:"lots,omitempty" json:"lots,omitempty"` // lots the units were picked from
+	Serials         []string           `bson:"serials,omitempty" json:"serials,omitempty"` // units sold of a serial-tracked product
+}
+
+// SaleComponent is a product taken out of stock for a SET line
+type SaleComponent struct {
+	ProductID   primitive.ObjectID `bson:"product_id" json:"product_id"`
+	ProductName string             `bson:"product_name" json:"product_name"`
+	Qty         float64            `bson:"qty" json:"qty"` // for the whole line
+	UnitCost    float64            `bson:"unit_cost" json:"unit_cost"`
+	Lots        []StockLotPick     `bson:"lots,omitempty" json:"lots,omitempty"`
+}
+
+// Sale payment methods
+const (
+	SalePaymentCash     = "cash"
+	SalePaymentCard     = "card"
+	SalePaymentTransfer = "transfer"
+)
+
+type SalePayment struct {
+	Method    string  `bson:"method" json:"method"` // cash | card | transfer
+	Amount    float64 `bson:"amount" json:"amount"`
+	Reference string  `bson:"reference,omitempty" json:"reference,omitempty"` // card slip, transfer number
+}
+
+// SaleReceipt is the printable receipt issued when a sale is completed
+type SaleReceipt struct {
+	Number        int64             `bson:"number" json:"number"`
+	IssuedAt      time.Time         `bson:"issued_at" json:"issued_at"`
+	ShopName      string            `bson:"shop_name" json:"shop_name"`
+	CashierName   string            `bson:"cashier_name" json:"cashier_name"`
+	CustomerName  string            `bson:"customer_name,omitempty" json:"customer_name,omitempty"`
+	Lines         []SaleReceiptLine `bson:"lines" json:"lines"`
+	Subtotal      float64           `bson:"subtotal" json:"subtotal"`
+	DiscountTotal float64           `bson:"discount_total" json:"discount_total"`
+	Total         float64           `bson:"total" json:"total"`
+	Payments      []SalePayment     `bson:"payments" json:"payments"`
+	PaidAmount    float64           `bson:"paid_amount" json:"paid_amount"`
+	ChangeAmount  float64           `bson:"change_amount" json:"change_amount"`
+}
+
+type SaleReceiptLine struct {
+	Name      string  `bson:"name" json:"name"`
+	Qty       float64 `bson:"qty" json:"qty"`
+	Unit      string  `bson:"unit" json:"unit"`
+	UnitPrice float64 `bson:"unit_price" json:"unit_price"`
+	Discount  float64 `bson:"discount" json:"discount"`
+	Total     float64 `bson:"total" json:"total"`
+	Serials   []string `bson:"serials,omitempty" json:"serials,omitempty"`
+}
+
+type SaleItemInput struct {
+	ProductID       string  `json:"product_id"`
+	VariantID       string  `json:"variant_id"`
+	Qty             float64 `json:"qty"`
+	Unit            string  `json:"unit"`       // any of the product's units; empty is the base unit
+	UnitPrice       float64 `json:"unit_price"` // per Unit; 0 = product or variant price, else needs sales.prices.update
+	DiscountPercent float64 `json:"discount_percent"`
+	DiscountAmount  float64 `json:"discount_amount"`
+	Serials         []string `json:"serials"` // one per unit of a serial-tracked product
+}
+
+type CreateSaleRequest struct {
+	ShopID     string          `json:"shop_id"`
+	CustomerID string          `json:"customer_id"`
+	Comment    string          `json:"comment"`
+	Items      []SaleItemInput `json:"items"`
+	Payments   []SalePayment   `json:"payments"`
+	Complete   bool            `json:"complete"` // complete right away (one-step checkout)
+	// Repayment schedule of the unpaid part when selling on credit; default is one installment in 30 days
+	Installments []DebtInstallmentInput `json:"installments"`
+}
+
+type UpdateSaleRequest struct {
+	CustomerID *string         `json:"customer_id"` // "" unlinks the customer
+	Comment    string          `json:"comment"`
+	Items      []SaleItemInput `json:"items"`
+	Payments   []SalePayment   `json:"payments"`
+	Action     string          `json:"action"` // complete | cancel | ""
+	Installments []DebtInstallmentInput `json:"installments"`
+}
+
+type SaleFilterRequest struct {
+	Search        string `json:"search"`
+	StatusID      string `json:"status_id"`
+	CustomerID    string `json:"customer_id"`
+	ShopID        string `json:"shop_id"`
+	CreatedBy     string `json:"created_by"`
+	DateFrom      string `json:"date_from"`
+	DateTo        string `json:"date_to"`
+	PaymentStatus string `json:"payment_status"` // all, unpaid, partially_paid, paid
+	Type          string `json:"type"`
+	Page          int    `json:"page"`
+	Limit         int    `json:"limit"`
+	SortBy        string `json:"sort_by"`
+	SortOrder     string `json:"sort_order"`
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SaleReturn takes goods back from a customer against a completed sale. Returned goods go back into the store's
+// stock, or into a write-off when the reason is "defective"; the money is refunded within the cashier's open shift.
+
+type SaleReturn struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID string             `bson:"tenant_id" json:"tenant_id"`
+
+	ExternalID        int64  `bson:"external_id" json:"external_id"`
+	SaleID            string `bson:"sale_id" json:"sale_id"`
+	SaleReceiptNumber int64  `bson:"sale_receipt_number" json:"sale_receipt_number"`
+	Reason            string `bson:"reason" json:"reason"` // defective | any free-form reason
+	Comment           string `bson:"comment" json:"comment"`
+
+	ShopID      string `bson:"shop_id" json:"shop_id"`
+	ShopName    string `bson:"shop_name" json:"shop_name"`
+	ShiftID     string `bson:"shift_id" json:"shift_id"`
+	ShiftNumber int64  `bson:"shift_number" json:"shift_number"`
+
+	CustomerID string       `bson:"customer_id,omitempty" json:"customer_id,omitempty"`
+	Customer   SaleCustomer `bson:"customer" json:"customer"`
+
+	Items   []SaleReturnItem `bson:"items" json:"items"`
+	Refunds []SalePayment    `bson:"refunds" json:"refunds"`
+
+	Total        float64 `bson:"total" json:"total"`                 // value of the returned goods
+	RefundTotal  float64 `bson:"refund_total" json:"refund_total"`   // money paid back
+	CreditAmount float64 `bson:"credit_amount" json:"credit_amount"` // part settled against the customer's debt for the sale
+	CostTotal    float64 `bson:"cost_total" json:"cost_total"`
+	Restocked    bool    `bson:"restocked" json:"restocked"`
+	WriteOffID   string  `bson:"writeoff_id,omitempty" json:"writeoff_id,omitempty"` // defective goods
+
+	CreatedBy InventoryUser `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+}
+
+type SaleReturnItem struct {
+	Line        int                `bson:"line" json:"line"` // index of the sale line
+	ProductID   primitive.ObjectID `bson:"product_id" json:"product_id"`
+	VariantID   primitive.ObjectID `bson:"variant_id,omitempty" json:"variant_id,omitempty"`
+	ProductName string             `bson:"product_name" json:"product_name"`
+	ProductSKU  string             `bson:"product_sku" json:"product_sku"`
+	ProductType string             `bson:"product_type" json:"product_type"`
+	Qty         float64            `bson:"qty" json:"qty"`
+	Unit        string             `bson:"unit" json:"unit"`
+	UnitPrice   float64            `bson:"unit_price" json:"unit_price"` // net of the line discount
+	Amount      float64            `bson:"amount" json:"amount"`
+	UnitCost    float64            `bson:"unit_cost" json:"unit_cost"`
+	CostTotal   float64            `bson:"cost_total" json:"cost_total"`
+	Serials     []string           `bson:"serials,omitempty" json:"serials,omitempty"`
+	// CoresReceived counts the dirty cores a returned core charge line took into the core inventory; cores of parts
+	// returned unused come back inside the parts
+	CoresReceived float64 `bson:"cores_received,omitempty" json:"cores_received,omitempty"`
+}
+
+// SaleReturnReasonDefective routes the returned goods to a write-off instead of back into stock
+const SaleReturnReasonDefective = "defective"
+
+type SaleReturnItemInput struct {
+	Line      *int    `json:"line"` // sale line index; otherwise matched by product and variant
+	ProductID string  `json:"product_id"`
+	VariantID string  `json:"variant_id"`
+	Qty       float64 `json:"qty"`
+	Serials   []string `json:"serials"` // units returned of a serial-tracked product, from those sold on the line
+	Core      bool     `json:"core"`    // matched by product: return the part's core charge line, the dirty core came back
+}
+
+type CreateSaleReturnRequest struct {
+	Items        []SaleReturnItemInput `json:"items"`
+	Reason       string                `json:"reason"`
+	Comment      string                `json:"comment"`
+	RefundMethod string                `json:"refund_method"` // cash | card | transfer; empty = the sale's own methods
+}
+
+type SaleReturnFilterRequest struct {
+	SaleID   string `json:"sale_id"`
+	ShopID   string `json:"shop_id"`
+	ShiftID  string `json:"shift_id"`
+	Reason   string `json:"reason"`
+	DateFrom string `json:"date_from"`
+	DateTo   string `json:"date_to"`
+	Page     int    `json:"page"`
+	Limit    int    `json:"limit"`
+}
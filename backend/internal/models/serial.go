@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Serial number states
+const (
+	SerialInStock    = "in_stock"
+	SerialInTransit  = "in_transit"
+	SerialSold       = "sold"
+	SerialWrittenOff = "written_off"
+	SerialReturned   = "returned_to_supplier"
+)
+
+// SerialNumber is one physical unit of a serial-tracked product. History lists every movement of the unit, oldest
+// first.
+type SerialNumber struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID    string             `bson:"tenant_id" json:"tenant_id"`
+	ProductID   primitive.ObjectID `bson:"product_id" json:"product_id"`
+	ProductName string             `bson:"product_name" json:"product_name"`
+	ProductSKU  string             `bson:"product_sku" json:"product_sku"`
+	Serial      string             `bson:"serial" json:"serial"`
+	Status      string             `bson:"status" json:"status"`
+	ShopID      string             `bson:"shop_id" json:"shop_id"` // store holding the unit, or the one it left last
+	Party       string             `bson:"party,omitempty" json:"party,omitempty"` // customer it was sold to
+	History     []SerialEvent      `bson:"history" json:"history"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// SerialEvent is one movement of a serial number: the state it entered, the store and the document that moved it.
+type SerialEvent struct {
+	Status     string        `bson:"status" json:"status"`
+	ShopID     string        `bson:"shop_id" json:"shop_id"`
+	SourceType string        `bson:"source_type" json:"source_type"`
+	SourceID   string        `bson:"source_id" json:"source_id"`
+	Party      string        `bson:"party,omitempty" json:"party,omitempty"` // supplier or customer on the other side
+	Actor      InventoryUser `bson:"actor" json:"actor"`
+	At         time.Time     `bson:"at" json:"at"`
+}
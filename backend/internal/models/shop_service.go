@@ -10,7 +10,7 @@ import (
 type ShopServicePart struct {
 	Name       string  `bson:"name" json:"name"`
 	PartNumber string  `bson:"part_number" json:"part_number"`
-	Quantity   int     `bson:"quantity" json:"quantity"`
+	Quantity   float64 `bson:"quantity" json:"quantity"`
 	Cost       float64 `bson:"cost" json:"cost"`
 	Price      float64 `bson:"price" json:"price"`
 }
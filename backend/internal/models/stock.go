@@ -0,0 +1,150 @@
+package models
+
+import (
+	"time"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StockBalance is the quantity of one product on hand in one store or warehouse.
+// Product.Stock is kept equal to the sum of the product's balances.
+type StockBalance struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID  string             `bson:"tenant_id" json:"tenant_id"`
+	ProductID primitive.ObjectID `bson:"product_id" json:"product_id"`
+	ShopID    string             `bson:"shop_id" json:"shop_id"` // store or warehouse id
+	Qty       float64            `bson:"qty" json:"qty"`
+	AvgCost   float64            `bson:"avg_cost" json:"avg_cost"` // moving weighted average unit cost
+	Value     float64            `bson:"value" json:"value"`       // cost of the quantity on hand under the tenant's costing method
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// ProductStoreStock is the per-store quantity exposed on ProductDTO. Qty is on hand; Available is Qty less what
+// pending documents have Reserved.
+type ProductStoreStock struct {
+	ShopID    string  `json:"shop_id"`
+	Qty       float64 `json:"qty"`
+	Reserved  float64 `json:"reserved"`
+	Available float64 `json:"available"`
+}
+
+// Stock movement sources
+const (
+	StockSourceSupplierOrder = "supplier_order"
+	StockSourceReturnOrder   = "return_order"
+	StockSourceWriteOff      = "writeoff"
+	StockSourceTransfer      = "transfer"
+	StockSourceInventory     = "inventory"
+	StockSourceProduct       = "product"
+	StockSourceOpening       = "opening"
+	StockSourceSale          = "sale"
+	StockSourceSaleReturn    = "sale_return"
+	StockSourceAssembly      = "assembly"
+	StockSourceDisassembly   = "disassembly"
+)
+
+// StockMovement is one immutable entry of the stock ledger. Summing Delta per product and store gives its balance.
+type StockMovement struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID   string             `bson:"tenant_id" json:"tenant_id"`
+	ProductID  primitive.ObjectID `bson:"product_id" json:"product_id"`
+	ShopID     string             `bson:"shop_id" json:"shop_id"`
+	Delta      float64            `bson:"delta" json:"delta"`
+	Balance    float64            `bson:"balance" json:"balance"` // store balance after the movement
+	UnitCost   float64            `bson:"unit_cost" json:"unit_cost"`
+	CostAmount   float64          `bson:"cost_amount" json:"cost_amount"`     // signed cost of the units moved
+	BalanceValue float64          `bson:"balance_value" json:"balance_value"` // store stock value after the movement
+	Lots       []StockLotPick     `bson:"lots,omitempty" json:"lots,omitempty"`
+	Serials    []string           `bson:"serials,omitempty" json:"serials,omitempty"`
+	SourceType string             `bson:"source_type" json:"source_type"`
+	SourceID   string             `bson:"source_id" json:"source_id"`
+	Actor      InventoryUser      `bson:"actor" json:"actor"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// StockSource identifies the document and user behind a stock change
+type StockSource struct {
+	Type  string
+	ID    string
+	Actor InventoryUser
+	Lot   *StockLotRef // optional; decreases without one pick lots by earliest expiry
+	// Serials names the units moved of a serial-tracked product, one per unit
+	Serials []string
+	Party   string // supplier or customer on the other side, kept in the serials' history
+	Origin  string // for a sale return, the sale the goods come back from
+	// Consignment marks consignment goods received from, or returned to, a supplier
+	Consignment *ConsignmentRef
+	// Bin is the bin units go into or, for a decrease, are taken from first; increases without one stay unbinned
+	Bin primitive.ObjectID
+}
+
+type StockMovementFilterRequest struct {
+	ShopID     string `json:"shop_id"`
+	SourceType string `json:"source_type"`
+	DateFrom   string `json:"date_from"`
+	DateTo     string `json:"date_to"`
+	Page       int    `json:"page"`
+	Limit      int    `json:"limit"`
+}
+
+// StockAsOf is a product's stock reconstructed from the ledger at a point in time
+type StockAsOf struct {
+	ProductID   string              `json:"product_id"`
+	Date        time.Time           `json:"date"`
+	Total       float64             `json:"total"`
+	StoreStocks []ProductStoreStock `json:"store_stocks"`
+}
+
+type StockMismatch struct {
+	ProductID string `json:"product_id"`
+	ShopID    string `json:"shop_id"`
+	Balance   float64 `json:"balance"`
+	Ledger    float64 `json:"ledger"`
+}
+
+// StockConsistencyReport lists the balances that differ from the sum of their ledger entries
+type StockConsistencyReport struct {
+	Checked    int             `json:"checked"`
+	Mismatches []StockMismatch `json:"mismatches"`
+}
+
+// Costing methods, chosen per tenant in TenantSettings.CostingMethod
+const (
+	CostingAverage = "average"
+	CostingFIFO    = "fifo"
+)
+
+// CostLayer is one receipt of a product into a store that still has units left; FIFO costing consumes the oldest
+// layers first.
+type CostLayer struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID   string             `bson:"tenant_id" json:"tenant_id"`
+	ProductID  primitive.ObjectID `bson:"product_id" json:"product_id"`
+	ShopID     string             `bson:"shop_id" json:"shop_id"`
+	Qty        float64            `bson:"qty" json:"qty"`
+	Left       float64            `bson:"left" json:"left"`
+	UnitCost   float64            `bson:"unit_cost" json:"unit_cost"`
+	SourceType string             `bson:"source_type" json:"source_type"`
+	SourceID   string             `bson:"source_id" json:"source_id"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// InventoryValuation is the cost of the stock on hand at a point in time, reconstructed from the stock ledger
+type InventoryValuation struct {
+	Date       time.Time                `json:"date"`
+	Method     string                   `json:"method"`
+	ShopID     string                   `json:"shop_id,omitempty"`
+	TotalQty   float64                  `json:"total_qty"`
+	TotalValue float64                  `json:"total_value"`
+	Items      []InventoryValuationLine `json:"items"`
+}
+
+type InventoryValuationLine struct {
+	ProductID   string  `json:"product_id"`
+	ProductName string  `json:"product_name"`
+	ProductSKU  string  `json:"product_sku"`
+	ShopID      string  `json:"shop_id"`
+	Qty         float64 `json:"qty"`
+	UnitCost    float64 `json:"unit_cost"`
+	Value       float64 `json:"value"`
+}
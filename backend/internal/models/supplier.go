@@ -27,6 +27,7 @@ type Supplier struct {
 	INN                     string             `bson:"inn" json:"inn"`
 	MFO                     string             `bson:"mfo" json:"mfo"`
 	Documents               []string           `bson:"documents" json:"documents"`
+	Balance                 float64            `bson:"balance" json:"balance"` // accounts payable, kept by the supplier ledger
 	CreatedAt               time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt               time.Time          `bson:"updated_at" json:"updated_at"`
 }
@@ -45,6 +46,7 @@ type SupplierDTO struct {
 	INN                     string          `json:"inn"`
 	MFO                     string          `json:"mfo"`
 	Documents               []string        `json:"documents"`
+	Balance                 float64         `json:"balance"`
 	CreatedAt               time.Time       `json:"created_at"`
 	UpdatedAt               time.Time       `json:"updated_at"`
 }
@@ -53,7 +55,7 @@ func ToSupplierDTO(m Supplier) SupplierDTO {
 	return SupplierDTO{
 		ID: m.ID.Hex(), TenantID: m.TenantID, Name: m.Name, DefaultMarkupPercentage: m.DefaultMarkupPercentage,
 		Phone: m.Phone, Email: m.Email, Notes: m.Notes, LegalAddress: m.LegalAddress, BankAccount: m.BankAccount,
-		BankNameBranch: m.BankNameBranch, INN: m.INN, MFO: m.MFO, Documents: m.Documents, CreatedAt: m.CreatedAt, UpdatedAt: m.UpdatedAt,
+		BankNameBranch: m.BankNameBranch, INN: m.INN, MFO: m.MFO, Documents: m.Documents, Balance: m.Balance, CreatedAt: m.CreatedAt, UpdatedAt: m.UpdatedAt,
 	}
 }
 
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Supplier ledger entry types
+const (
+	SupplierEntryOpening     = "opening"
+	SupplierEntryOrder       = "order"       // accepted supplier order
+	SupplierEntryReturn      = "return"      // accepted return order
+	SupplierEntryPayment     = "payment"     // payment against an order
+	SupplierEntryAdjustment  = "adjustment"  // manual correction
+	SupplierEntryConsignment = "consignment" // approved consignment settlement
+)
+
+// SupplierLedgerEntry is one posting to the accounts payable ledger of a supplier. Amount is positive when it
+// raises what the tenant owes the supplier (goods received) and negative when it lowers it (payments, returns).
+type SupplierLedgerEntry struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID   string             `bson:"tenant_id" json:"tenant_id"`
+	SupplierID string             `bson:"supplier_id" json:"supplier_id"`
+	ShopID     string             `bson:"shop_id,omitempty" json:"shop_id,omitempty"`
+	Type       string             `bson:"type" json:"type"` // opening | order | return | payment | adjustment | consignment
+	Amount     float64            `bson:"amount" json:"amount"`
+	Balance    float64            `bson:"balance" json:"balance"` // supplier balance after the posting
+	OrderID    string             `bson:"order_id,omitempty" json:"order_id,omitempty"`
+	OrderName  string             `bson:"order_name,omitempty" json:"order_name,omitempty"`
+	PaymentID  string             `bson:"payment_id,omitempty" json:"payment_id,omitempty"`
+	Method     string             `bson:"method,omitempty" json:"method,omitempty"`
+	Comment    string             `bson:"comment" json:"comment"`
+	Date       time.Time          `bson:"date" json:"date"` // when the event happened, used by statements
+	CreatedBy  InventoryUser      `bson:"created_by" json:"created_by"`
+	Source     string             `bson:"source,omitempty" json:"source,omitempty"` // "migration" for postings of orders that predate the ledger
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// SupplierStatement is a reconciliation statement (akt sverki) for a period. Debit lowers the payable (payments,
+// returns), credit raises it (goods received); balances are what the tenant owes the supplier.
+type SupplierStatement struct {
+	SupplierID     string                  `json:"supplier_id"`
+	SupplierName   string                  `json:"supplier_name"`
+	SupplierINN    string                  `json:"supplier_inn"`
+	ShopID         string                  `json:"shop_id,omitempty"`
+	From           *time.Time              `json:"from,omitempty"`
+	To             *time.Time              `json:"to,omitempty"`
+	OpeningBalance float64                 `json:"opening_balance"`
+	Lines          []SupplierStatementLine `json:"lines"`
+	TotalDebit     float64                 `json:"total_debit"`
+	TotalCredit    float64                 `json:"total_credit"`
+	ClosingBalance float64                 `json:"closing_balance"`
+}
+
+type SupplierStatementLine struct {
+	Date     time.Time `json:"date"`
+	Type     string    `json:"type"`
+	Document string    `json:"document"`
+	Comment  string    `json:"comment"`
+	Debit    float64   `json:"debit"`
+	Credit   float64   `json:"credit"`
+	Balance  float64   `json:"balance"`
+}
+
+type SupplierLedgerPostRequest struct {
+	Amount  float64    `json:"amount"` // opening: owed to the supplier (negative = prepaid); adjustment: signed change
+	Date    *time.Time `json:"date"`
+	ShopID  string     `json:"shop_id"`
+	Comment string     `json:"comment"`
+}
@@ -45,6 +45,9 @@ type TenantSettings struct {
 	Currency      string               `bson:"currency" json:"currency"`
 	ExchangeRate  float64              `bson:"exchange_rate" json:"exchange_rate"`
 	RateMode      string               `bson:"rate_mode" json:"rate_mode"` // UZS_PER_USD | USD_PER_UZS
+	CostingMethod string               `bson:"costing_method" json:"costing_method"` // average | fifo
+	// ReservationTTLHours is how long pending documents hold stock; 0 is DefaultReservationTTLHours
+	ReservationTTLHours int             `bson:"reservation_ttl_hours" json:"reservation_ttl_hours"`
 	DateFormat    string               `bson:"date_format" json:"date_format"`
 	Logo          string               `bson:"logo" json:"logo"`
 	BrandColors   BrandColors          `bson:"brand_colors" json:"brand_colors"`
@@ -104,6 +107,7 @@ func GetDefaultTenantSettings() TenantSettings {
 		Currency:   "UZS",
 		ExchangeRate: 12000,
 		RateMode:   "UZS_PER_USD",
+		CostingMethod: "average",
 		DateFormat: "DD.MM.YYYY",
 		BrandColors: BrandColors{ Primary: "#3b82f6", Secondary: "#10b981", Accent: "#f59e0b" },
 		Features: []string{"products","customers","sales","reports"},
@@ -6,7 +6,8 @@ import (
 )
 
 // Transfer represents a stock movement between stores
-// When approved, it logically moves quantities from a departure store to an arrival store
+// Sending it takes the quantities out of the departure store (goods are then in transit); receiving it credits the
+// arrival store with the quantities actually received. Shortages become a write-off, surpluses an import record.
 
 type Transfer struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
@@ -21,17 +22,24 @@ type Transfer struct {
 	ArrivalShopName   string `bson:"arrival_shop_name" json:"arrival_shop_name"`
 
 	FromFile bool   `bson:"from_file" json:"from_file"`
-	Status   string `bson:"status" json:"status"` // NEW | APPROVED | REJECTED
+	Status   string `bson:"status" json:"status"` // NEW | SENT | RECEIVED | REJECTED (APPROVED for transfers finished in one step)
 
 	TotalQty   float64   `bson:"total_qty" json:"total_qty"`
+	TotalReceivedQty float64 `bson:"total_received_qty" json:"total_received_qty"`
 	TotalPrice float64   `bson:"total_price" json:"total_price"` // by retail price
 	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
 	UpdatedAt  time.Time `bson:"updated_at" json:"updated_at"`
 	FinishedAt *time.Time `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+	SentAt     *time.Time `bson:"sent_at,omitempty" json:"sent_at,omitempty"`
 
 	CreatedBy  InventoryUser `bson:"created_by" json:"created_by"`
+	SentBy     InventoryUser `bson:"sent_by" json:"sent_by"`
 	FinishedBy InventoryUser `bson:"finished_by" json:"finished_by"`
 
+	// Documents created for receiving discrepancies
+	ShortageWriteOffID string `bson:"shortage_writeoff_id,omitempty" json:"shortage_writeoff_id,omitempty"`
+	SurplusImportID    string `bson:"surplus_import_id,omitempty" json:"surplus_import_id,omitempty"`
+
 	Items []TransferItem `bson:"items" json:"items"`
 }
 
@@ -42,9 +50,25 @@ type TransferItem struct {
 	ProductSKU  string             `bson:"product_sku" json:"product_sku"`
 	Barcode     string             `bson:"barcode" json:"barcode"`
 	Qty         float64            `bson:"qty" json:"qty"`
+	ReceivedQty float64            `bson:"received_qty" json:"received_qty"`
+	Discrepancy float64            `bson:"discrepancy" json:"discrepancy"` // received - sent
 	Unit        string             `bson:"unit" json:"unit"`
+	InputQty    float64            `bson:"input_qty,omitempty" json:"input_qty,omitempty"` // as entered in InputUnit
+	InputUnit   string             `bson:"input_unit,omitempty" json:"input_unit,omitempty"`
 	SupplyPrice float64            `bson:"supply_price" json:"supply_price"`
 	RetailPrice float64            `bson:"retail_price" json:"retail_price"`
+	UnitCost    float64            `bson:"unit_cost" json:"unit_cost"` // cost the goods left the departure store at
+	Lots        []StockLotPick     `bson:"lots,omitempty" json:"lots,omitempty"` // departure lots the goods were picked from
+	Serials         []string       `bson:"serials,omitempty" json:"serials,omitempty"` // units sent of a serial-tracked product
+	ReceivedSerials []string       `bson:"received_serials,omitempty" json:"received_serials,omitempty"`
+}
+
+// TransferReceivedInput is the quantity of a product counted at the arrival store
+type TransferReceivedInput struct {
+	ProductID string  `json:"product_id"`
+	Qty       float64 `json:"qty"`
+	Unit      string  `json:"unit"` // any of the product's units; empty is the base unit
+	Serials   []string `json:"serials"` // units that arrived of a serial-tracked product; Qty follows their count
 }
 
 type TransferItemInput struct {
@@ -56,6 +80,7 @@ type TransferItemInput struct {
 	Unit        string  `json:"unit"`
 	SupplyPrice float64 `json:"supply_price"`
 	RetailPrice float64 `json:"retail_price"`
+	Serials     []string `json:"serials"`
 }
 
 type TransferFilterRequest struct {
@@ -81,5 +106,7 @@ type CreateTransferRequest struct {
 type UpdateTransferRequest struct {
 	Name   string               `json:"name"`
 	Items  []TransferItemInput  `json:"items"`
-	Action string               `json:"action"` // approve | reject | ""
+	Action string               `json:"action"` // send | receive | approve (send and receive in full) | reject | ""
+	// Received quantities for the receive action; lines not listed are taken as received in full
+	Received []TransferReceivedInput `json:"received"`
 } 
\ No newline at end of file
@@ -0,0 +1,176 @@
+package models
+
+import (
+	"time"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Warehouse location kinds, outermost first. A location sits under one of a lower level; stock is kept in bins only.
+const (
+	LocationZone  = "zone"
+	LocationAisle = "aisle"
+	LocationRack  = "rack"
+	LocationBin   = "bin"
+)
+
+// LocationLevel orders the location kinds, zone first; it is 0 for an unknown kind.
+func LocationLevel(kind string) int {
+	switch kind {
+	case LocationZone:
+		return 1
+	case LocationAisle:
+		return 2
+	case LocationRack:
+		return 3
+	case LocationBin:
+		return 4
+	}
+	return 0
+}
+
+// WarehouseLocation is one level of the location hierarchy of a store or warehouse. Path joins the codes from the
+// top level down, e.g. "A/01/03/2"; pick lists walk the bins in path order, so codes are best zero-padded.
+type WarehouseLocation struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	TenantID    string              `bson:"tenant_id" json:"tenant_id"`
+	WarehouseID string              `bson:"warehouse_id" json:"warehouse_id"` // the store or warehouse id stock is kept under
+	ParentID    *primitive.ObjectID `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+	Kind        string              `bson:"kind" json:"kind"` // zone | aisle | rack | bin
+	Code        string              `bson:"code" json:"code"`
+	Name        string              `bson:"name" json:"name"`
+	Path        string              `bson:"path" json:"path"`
+	IsActive    bool                `bson:"is_active" json:"is_active"` // inactive bins get no putaway suggestions
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// BinStock is the quantity of a product in one bin. The bins of a store hold part of its stock balance; the rest is
+// unbinned, received but not put away yet.
+type BinStock struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID  string             `bson:"tenant_id" json:"tenant_id"`
+	ShopID    string             `bson:"shop_id" json:"shop_id"`
+	BinID     primitive.ObjectID `bson:"bin_id" json:"bin_id"`
+	BinPath   string             `bson:"bin_path" json:"bin_path"`
+	ProductID primitive.ObjectID `bson:"product_id" json:"product_id"`
+	Qty       float64            `bson:"qty" json:"qty"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// BinStockLine is a product in a bin, for the bin stock report.
+type BinStockLine struct {
+	BinID       string  `json:"bin_id"`
+	BinPath     string  `json:"bin_path"`
+	ProductID   string  `json:"product_id"`
+	ProductName string  `json:"product_name"`
+	ProductSKU  string  `json:"product_sku"`
+	Unit        string  `json:"unit"`
+	Qty         float64 `json:"qty"`
+}
+
+// BinPick is where units of a product are taken from: a bin, or the unbinned stock when BinID is empty.
+type BinPick struct {
+	BinID   string  `json:"bin_id,omitempty"`
+	BinPath string  `json:"bin_path,omitempty"`
+	Qty     float64 `json:"qty"`
+}
+
+type CreateWarehouseLocationRequest struct {
+	ParentID string `json:"parent_id"`
+	Kind     string `json:"kind"`
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+}
+
+type UpdateWarehouseLocationRequest struct {
+	Code     *string `json:"code"`
+	Name     *string `json:"name"`
+	IsActive *bool   `json:"is_active"`
+}
+
+// Putaway moves received, unbinned units of a store into bins. Receiving a supplier order into a store with bins
+// drafts one with a suggested bin per line; completing it places the units.
+type Putaway struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TenantID    string             `bson:"tenant_id" json:"tenant_id"`
+	ExternalID  int64              `bson:"external_id" json:"external_id"`
+	ShopID      string             `bson:"shop_id" json:"shop_id"`
+	OrderID     string             `bson:"order_id,omitempty" json:"order_id,omitempty"`
+	OrderName   string             `bson:"order_name,omitempty" json:"order_name,omitempty"`
+	Status      string             `bson:"status" json:"status"` // NEW | COMPLETED | CANCELLED
+	Lines       []PutawayLine      `bson:"lines" json:"lines"`
+	TotalQty    float64            `bson:"total_qty" json:"total_qty"`
+	Comment     string             `bson:"comment" json:"comment"`
+	CreatedBy   InventoryUser      `bson:"created_by" json:"created_by"`
+	FinishedBy  InventoryUser      `bson:"finished_by" json:"finished_by"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	FinishedAt  *time.Time         `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+type PutawayLine struct {
+	ProductID   primitive.ObjectID `bson:"product_id" json:"product_id"`
+	ProductName string             `bson:"product_name" json:"product_name"`
+	ProductSKU  string             `bson:"product_sku" json:"product_sku"`
+	Unit        string             `bson:"unit" json:"unit"`
+	Qty         float64            `bson:"qty" json:"qty"`
+	// the bin suggested, or chosen, for the units; empty leaves the line unbinned
+	BinID       string             `bson:"bin_id,omitempty" json:"bin_id,omitempty"`
+	BinPath     string             `bson:"bin_path,omitempty" json:"bin_path,omitempty"`
+}
+
+type PutawayItemInput struct {
+	ProductID string  `json:"product_id"`
+	Qty       float64 `json:"qty"`
+	Unit      string  `json:"unit"` // any of the product's units; empty is the base unit
+	BinID     string  `json:"bin_id"` // empty takes the suggested bin
+}
+
+type CreatePutawayRequest struct {
+	ShopID  string             `json:"shop_id"`
+	Items   []PutawayItemInput `json:"items"`
+	Comment string             `json:"comment"`
+}
+
+// PutawayLineInput changes the bin of a line before the putaway is completed.
+type PutawayLineInput struct {
+	Line  int    `json:"line"`
+	BinID string `json:"bin_id"`
+}
+
+type UpdatePutawayRequest struct {
+	Lines   []PutawayLineInput `json:"lines"`
+	Comment *string            `json:"comment"`
+	Action  string             `json:"action"` // complete | cancel | ""
+}
+
+type PutawayFilterRequest struct {
+	ShopID  string `json:"shop_id"`
+	OrderID string `json:"order_id"`
+	Status  string `json:"status"`
+	Page    int    `json:"page"`
+	Limit   int    `json:"limit"`
+}
+
+// PickList is the walk through a store's bins that gathers the goods of a pending sale or transfer, in bin path
+// order. Units not in any bin come last.
+type PickList struct {
+	SourceType string         `json:"source_type"` // sale | transfer
+	SourceID   string         `json:"source_id"`
+	SourceName string         `json:"source_name"`
+	ShopID     string         `json:"shop_id"`
+	Lines      []PickListLine `json:"lines"`
+	TotalQty   float64        `json:"total_qty"`
+	// Short is what the store does not have on hand at all
+	Short      []PickListLine `json:"short"`
+}
+
+type PickListLine struct {
+	BinID       string  `json:"bin_id,omitempty"`
+	BinPath     string  `json:"bin_path,omitempty"`
+	ProductID   string  `json:"product_id"`
+	ProductName string  `json:"product_name"`
+	ProductSKU  string  `json:"product_sku"`
+	Unit        string  `json:"unit"`
+	Qty         float64 `json:"qty"`
+}
@@ -27,6 +27,7 @@ type WriteOff struct {
 	TotalQty         float64   `bson:"total_qty" json:"total_qty"`
 	TotalSupplyPrice float64   `bson:"total_supply_price" json:"total_supply_price"`
 	TotalRetailPrice float64   `bson:"total_retail_price" json:"total_retail_price"`
+	TotalCost        float64   `bson:"total_cost" json:"total_cost"` // cost of the written-off stock, set on approval
 	CreatedAt        time.Time `bson:"created_at" json:"created_at"`
 	UpdatedAt        time.Time `bson:"updated_at" json:"updated_at"`
 	FinishedAt       *time.Time `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
@@ -45,8 +46,15 @@ type WriteOffItem struct {
 	Barcode     string             `bson:"barcode" json:"barcode"`
 	Qty         float64            `bson:"qty" json:"qty"`
 	Unit        string             `bson:"unit" json:"unit"`
+	InputQty    float64            `bson:"input_qty,omitempty" json:"input_qty,omitempty"` // as entered in InputUnit
+	InputUnit   string             `bson:"input_unit,omitempty" json:"input_unit,omitempty"`
 	SupplyPrice float64            `bson:"supply_price" json:"supply_price"`
 	RetailPrice float64            `bson:"retail_price" json:"retail_price"`
+	UnitCost    float64            `bson:"unit_cost" json:"unit_cost"`
+	CostTotal   float64            `bson:"cost_total" json:"cost_total"`
+	LotID       string             `bson:"lot_id,omitempty" json:"lot_id,omitempty"` // writes off from this lot only
+	LotNumber   string             `bson:"lot_number,omitempty" json:"lot_number,omitempty"`
+	Serials     []string           `bson:"serials,omitempty" json:"serials,omitempty"` // units written off of a serial-tracked product
 }
 
 type WriteOffItemInput struct {
@@ -58,6 +66,8 @@ type WriteOffItemInput struct {
 	Unit        string  `json:"unit"`
 	SupplyPrice float64 `json:"supply_price"`
 	RetailPrice float64 `json:"retail_price"`
+	LotID       string  `json:"lot_id"`
+	Serials     []string `json:"serials"`
 }
 
 type WriteOffFilterRequest struct {
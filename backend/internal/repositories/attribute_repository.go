@@ -18,6 +18,7 @@ type AttributeListParams struct {
 	Sort     bson.D
 	Search   string
 	IsActive *bool
+	TenantID string
 }
 
 type AttributeRepository struct {
@@ -33,7 +34,7 @@ func (r *AttributeRepository) List(ctx context.Context, p AttributeListParams) (
 	if p.Limit < 1 || p.Limit > 200 { p.Limit = 20 }
 	if p.Sort == nil { p.Sort = bson.D{{Key: "created_at", Value: -1}} }
 
-	filter := bson.M{"is_deleted": false}
+	filter := bson.M{"tenant_id": p.TenantID, "is_deleted": false}
 	if p.Search != "" {
 		filter["$or"] = []bson.M{
 			{"name": bson.M{"$regex": p.Search, "$options": "i"}},
@@ -56,16 +57,16 @@ func (r *AttributeRepository) List(ctx context.Context, p AttributeListParams) (
 	return items, total, nil
 }
 
-func (r *AttributeRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Attribute, error) {
+func (r *AttributeRepository) Get(ctx context.Context, id primitive.ObjectID, tenantID string) (*models.Attribute, error) {
 	var m models.Attribute
-	if err := r.col.FindOne(ctx, bson.M{"_id": id, "is_deleted": false}).Decode(&m); err != nil { return nil, err }
+	if err := r.col.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID, "is_deleted": false}).Decode(&m); err != nil { return nil, err }
 	return &m, nil
 }
 
-func (r *AttributeRepository) GetByIDHex(ctx context.Context, id string) (*models.Attribute, error) {
+func (r *AttributeRepository) GetByIDHex(ctx context.Context, id string, tenantID string) (*models.Attribute, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil { return nil, err }
-	return r.Get(ctx, oid)
+	return r.Get(ctx, oid, tenantID)
 }
 
 func (r *AttributeRepository) Create(ctx context.Context, m *models.Attribute) (*models.Attribute, error) {
@@ -81,21 +82,21 @@ func (r *AttributeRepository) Create(ctx context.Context, m *models.Attribute) (
 	return m, nil
 }
 
-func (r *AttributeRepository) Update(ctx context.Context, id primitive.ObjectID, update bson.M) (*models.Attribute, error) {
+func (r *AttributeRepository) Update(ctx context.Context, id primitive.ObjectID, tenantID string, update bson.M) (*models.Attribute, error) {
 	if update == nil { update = bson.M{} }
 	update["updated_at"] = time.Now().UTC()
 
-	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{"$set": update})
 	if err != nil { return nil, err }
-	return r.Get(ctx, id)
+	return r.Get(ctx, id, tenantID)
 }
 
-func (r *AttributeRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+func (r *AttributeRepository) Delete(ctx context.Context, id primitive.ObjectID, tenantID string) error {
 	now := time.Now().UTC()
 	update := bson.M{
 		"is_deleted": true,
 		"updated_at": now,
 	}
-	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{"$set": update})
 	return err
 } 
\ No newline at end of file
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type BinStockRepository struct { col *mongo.Collection }
+
+func NewBinStockRepository(db *mongo.Database) *BinStockRepository { return &BinStockRepository{col: db.Collection("bin_stock")} }
+
+// Inc adds units of a product to a bin, creating its row when missing.
+func (r *BinStockRepository) Inc(ctx context.Context, tenantID, shopID string, bin *models.WarehouseLocation, productID primitive.ObjectID, qty float64) error {
+	update := mongo.Pipeline{bson.D{{Key: "$set", Value: bson.M{
+		"shop_id":    shopID,
+		"bin_path":   bin.Path,
+		"qty":        roundQty(bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$qty", 0}}, qty}}),
+		"updated_at": time.Now().UTC(),
+	}}}}
+	_, err := r.col.UpdateOne(ctx, bson.M{"tenant_id": tenantID, "bin_id": bin.ID, "product_id": productID}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// Take removes units of a product from a bin. It only applies when the bin holds that many; ok is false otherwise.
+func (r *BinStockRepository) Take(ctx context.Context, tenantID string, binID, productID primitive.ObjectID, qty float64) (bool, error) {
+	filter := bson.M{"tenant_id": tenantID, "bin_id": binID, "product_id": productID, "qty": bson.M{"$gte": qty}}
+	update := mongo.Pipeline{bson.D{{Key: "$set", Value: bson.M{
+		"qty":        roundQty(bson.M{"$subtract": bson.A{"$qty", qty}}),
+		"updated_at": time.Now().UTC(),
+	}}}}
+	res, err := r.col.UpdateOne(ctx, filter, update)
+	if err != nil { return false, err }
+	return res.MatchedCount == 1, nil
+}
+
+// ForProduct returns the bins of a store holding a product, in bin path order.
+func (r *BinStockRepository) ForProduct(ctx context.Context, tenantID, shopID string, productID primitive.ObjectID) ([]models.BinStock, error) {
+	return r.find(ctx, bson.M{"tenant_id": tenantID, "shop_id": shopID, "product_id": productID, "qty": bson.M{"$gt": 0}})
+}
+
+// List returns what the bins of a store hold, optionally only the given bins and one product.
+func (r *BinStockRepository) List(ctx context.Context, tenantID, shopID string, binIDs []primitive.ObjectID, productID *primitive.ObjectID) ([]models.BinStock, error) {
+	filter := bson.M{"tenant_id": tenantID, "shop_id": shopID, "qty": bson.M{"$gt": 0}}
+	if binIDs != nil { filter["bin_id"] = bson.M{"$in": binIDs} }
+	if productID != nil { filter["product_id"] = *productID }
+	return r.find(ctx, filter)
+}
+
+// Qty returns the quantity of a product in a bin.
+func (r *BinStockRepository) Qty(ctx context.Context, tenantID string, binID, productID primitive.ObjectID) (float64, error) {
+	var m models.BinStock
+	err := r.col.FindOne(ctx, bson.M{"tenant_id": tenantID, "bin_id": binID, "product_id": productID}).Decode(&m)
+	if err == mongo.ErrNoDocuments { return 0, nil }
+	if err != nil { return 0, err }
+	return m.Qty, nil
+}
+
+// Binned sums what the bins of a store hold of a product.
+func (r *BinStockRepository) Binned(ctx context.Context, tenantID, shopID string, productID primitive.ObjectID) (float64, error) {
+	cur, err := r.col.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"tenant_id": tenantID, "shop_id": shopID, "product_id": productID}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "qty": bson.M{"$sum": "$qty"}}}},
+	})
+	if err != nil { return 0, err }
+	defer cur.Close(ctx)
+	var rows []struct{ Qty float64 `bson:"qty"` }
+	if err := cur.All(ctx, &rows); err != nil { return 0, err }
+	if len(rows) == 0 { return 0, nil }
+	return models.RoundQty(rows[0].Qty), nil
+}
+
+// Occupied returns the ids of the bins of a store that hold anything.
+func (r *BinStockRepository) Occupied(ctx context.Context, tenantID, shopID string) (map[primitive.ObjectID]bool, error) {
+	ids, err := r.col.Distinct(ctx, "bin_id", bson.M{"tenant_id": tenantID, "shop_id": shopID, "qty": bson.M{"$gt": 0}})
+	if err != nil { return nil, err }
+	out := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		if oid, ok := id.(primitive.ObjectID); ok { out[oid] = true }
+	}
+	return out, nil
+}
+
+// HasStock reports whether a bin holds anything.
+func (r *BinStockRepository) HasStock(ctx context.Context, tenantID string, binID primitive.ObjectID) (bool, error) {
+	n, err := r.col.CountDocuments(ctx, bson.M{"tenant_id": tenantID, "bin_id": binID, "qty": bson.M{"$gt": 0}}, options.Count().SetLimit(1))
+	return n > 0, err
+}
+
+// SetPath follows a bin's new path on its rows.
+func (r *BinStockRepository) SetPath(ctx context.Context, tenantID string, binID primitive.ObjectID, path string) error {
+	_, err := r.col.UpdateMany(ctx, bson.M{"tenant_id": tenantID, "bin_id": binID}, bson.M{"$set": bson.M{"bin_path": path}})
+	return err
+}
+
+// DeleteBin drops the emptied rows of a deleted bin.
+func (r *BinStockRepository) DeleteBin(ctx context.Context, tenantID string, binID primitive.ObjectID) error {
+	_, err := r.col.DeleteMany(ctx, bson.M{"tenant_id": tenantID, "bin_id": binID})
+	return err
+}
+
+func (r *BinStockRepository) find(ctx context.Context, filter bson.M) ([]models.BinStock, error) {
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "bin_path", Value: 1}, {Key: "product_id", Value: 1}}))
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	items := []models.BinStock{}
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
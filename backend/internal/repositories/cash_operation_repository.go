@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CashOperationRepository struct { col *mongo.Collection }
+
+func NewCashOperationRepository(db *mongo.Database) *CashOperationRepository { return &CashOperationRepository{ col: db.Collection("cash_operations") } }
+
+func (r *CashOperationRepository) Create(ctx context.Context, m *models.CashOperation) (*models.CashOperation, error) {
+	m.CreatedAt = time.Now().UTC()
+	res, err := r.col.InsertOne(ctx, m)
+	if err != nil { return nil, err }
+	m.ID = res.InsertedID.(primitive.ObjectID)
+	return m, nil
+}
+
+func (r *CashOperationRepository) ListByShift(ctx context.Context, tenantID string, shiftID primitive.ObjectID) ([]models.CashOperation, error) {
+	cur, err := r.col.Find(ctx, bson.M{"tenant_id": tenantID, "shift_id": shiftID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	items := []models.CashOperation{}
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
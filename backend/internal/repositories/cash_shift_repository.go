@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CashShiftListParams struct {
+	TenantID  string
+	Page      int64
+	Limit     int64
+	ShopID    string
+	CashboxID string
+	Status    string
+	UserID    string
+	DateFrom  *time.Time
+	DateTo    *time.Time
+}
+
+type CashShiftRepository struct {
+	col      *mongo.Collection
+	counters *mongo.Collection
+}
+
+func NewCashShiftRepository(db *mongo.Database) *CashShiftRepository {
+	return &CashShiftRepository{ col: db.Collection("cash_shifts"), counters: db.Collection("counters") }
+}
+
+func (r *CashShiftRepository) List(ctx context.Context, p CashShiftListParams) ([]models.CashShift, int64, error) {
+	if p.Page < 1 { p.Page = 1 }
+	if p.Limit < 1 || p.Limit > 200 { p.Limit = 20 }
+	filter := bson.M{"tenant_id": p.TenantID}
+	if p.ShopID != "" { filter["shop_id"] = p.ShopID }
+	if p.CashboxID != "" { filter["cashbox_id"] = p.CashboxID }
+	if p.Status != "" { filter["status"] = p.Status }
+	if p.UserID != "" { filter["opened_by.id"] = p.UserID }
+	if p.DateFrom != nil || p.DateTo != nil {
+		dt := bson.M{}
+		if p.DateFrom != nil { dt["$gte"] = *p.DateFrom }
+		if p.DateTo != nil { dt["$lte"] = *p.DateTo }
+		filter["opened_at"] = dt
+	}
+	opts := options.Find().SetSkip((p.Page-1)*p.Limit).SetLimit(p.Limit).SetSort(bson.D{{Key: "opened_at", Value: -1}})
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil { return nil, 0, err }
+	defer cur.Close(ctx)
+	var items []models.CashShift
+	if err := cur.All(ctx, &items); err != nil { return nil, 0, err }
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil { return nil, 0, err }
+	return items, total, nil
+}
+
+func (r *CashShiftRepository) Get(ctx context.Context, id primitive.ObjectID, tenantID string) (*models.CashShift, error) {
+	var m models.CashShift
+	if err := r.col.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}).Decode(&m); err != nil { return nil, err }
+	return &m, nil
+}
+
+// GetOpen returns the user's open shift in a store.
+func (r *CashShiftRepository) GetOpen(ctx context.Context, tenantID, shopID, userID string) (*models.CashShift, error) {
+	var m models.CashShift
+	filter := bson.M{"tenant_id": tenantID, "shop_id": shopID, "opened_by.id": userID, "status": "OPEN"}
+	if err := r.col.FindOne(ctx, filter).Decode(&m); err != nil { return nil, err }
+	return &m, nil
+}
+
+// Create numbers and inserts a shift. The unique indexes on open shifts reject a second open shift for the same
+// cashbox or the same cashier in a store.
+func (r *CashShiftRepository) Create(ctx context.Context, m *models.CashShift) (*models.CashShift, error) {
+	n, err := nextSequence(ctx, r.counters, "cash_shift:"+m.TenantID)
+	if err != nil { return nil, err }
+	now := time.Now().UTC()
+	m.Number = n
+	m.CreatedAt = now
+	m.UpdatedAt = now
+	res, err := r.col.InsertOne(ctx, m)
+	if err != nil { return nil, err }
+	m.ID = res.InsertedID.(primitive.ObjectID)
+	return m, nil
+}
+
+// AttachSale counts a completed sale into an open shift. It reports false when the shift is no longer open.
+func (r *CashShiftRepository) AttachSale(ctx context.Context, id primitive.ObjectID, tenantID string, total float64) (bool, error) {
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID, "status": "OPEN"}, bson.M{
+		"$inc": bson.M{"sales_count": 1, "sales_total": total},
+		"$set": bson.M{"updated_at": time.Now().UTC()},
+	})
+	if err != nil { return false, err }
+	return res.MatchedCount == 1, nil
+}
+
+// AttachReturn counts a customer return and the money refunded into an open shift. It reports false when the shift
+// is no longer open.
+func (r *CashShiftRepository) AttachReturn(ctx context.Context, id primitive.ObjectID, tenantID string, refunded float64) (bool, error) {
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID, "status": "OPEN"}, bson.M{
+		"$inc": bson.M{"returns_count": 1, "returns_total": refunded},
+		"$set": bson.M{"updated_at": time.Now().UTC()},
+	})
+	if err != nil { return false, err }
+	return res.MatchedCount == 1, nil
+}
+
+// CountOperation counts a cash operation into an open shift. It reports false when the shift is no longer open.
+func (r *CashShiftRepository) CountOperation(ctx context.Context, id primitive.ObjectID, tenantID string) (bool, error) {
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID, "status": "OPEN"}, bson.M{
+		"$inc": bson.M{"operations_count": 1},
+		"$set": bson.M{"updated_at": time.Now().UTC()},
+	})
+	if err != nil { return false, err }
+	return res.MatchedCount == 1, nil
+}
+
+// Close closes the shift only if it is open and no sale, return or operation was added since the caller read it, i.e. the
+// counters still match. It reports false otherwise.
+func (r *CashShiftRepository) Close(ctx context.Context, cur *models.CashShift, set bson.M) (bool, error) {
+	filter := bson.M{"_id": cur.ID, "tenant_id": cur.TenantID, "status": "OPEN", "sales_count": cur.SalesCount, "returns_count": cur.ReturnsCount, "operations_count": cur.OperationsCount}
+	set["status"] = "CLOSED"
+	set["updated_at"] = time.Now().UTC()
+	res, err := r.col.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil { return false, err }
+	return res.MatchedCount == 1, nil
+}
@@ -20,6 +20,7 @@ type CategoryListParams struct {
 	IsActive *bool
 	ParentID *primitive.ObjectID
 	Level    *int
+	TenantID string
 }
 
 type CategoryRepository struct {
@@ -45,7 +46,7 @@ func (r *CategoryRepository) List(ctx context.Context, p CategoryListParams) ([]
 		p.Sort = bson.D{{Key: "created_at", Value: -1}}
 	}
 
-	filter := bson.M{"is_deleted": false}
+	filter := bson.M{"tenant_id": p.TenantID, "is_deleted": false}
 	if p.Search != "" {
 		filter["name"] = bson.M{"$regex": p.Search, "$options": "i"}
 	}
@@ -81,24 +82,24 @@ func (r *CategoryRepository) List(ctx context.Context, p CategoryListParams) ([]
 	return items, total, nil
 }
 
-func (r *CategoryRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Category, error) {
+func (r *CategoryRepository) Get(ctx context.Context, id primitive.ObjectID, tenantID string) (*models.Category, error) {
 	var m models.Category
-	if err := r.col.FindOne(ctx, bson.M{"_id": id, "is_deleted": false}).Decode(&m); err != nil {
+	if err := r.col.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID, "is_deleted": false}).Decode(&m); err != nil {
 		return nil, err
 	}
 	return &m, nil
 }
 
-func (r *CategoryRepository) GetByIDHex(ctx context.Context, id string) (*models.Category, error) {
+func (r *CategoryRepository) GetByIDHex(ctx context.Context, id string, tenantID string) (*models.Category, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, err
 	}
-	return r.Get(ctx, oid)
+	return r.Get(ctx, oid, tenantID)
 }
 
-func (r *CategoryRepository) GetChildren(ctx context.Context, parentID primitive.ObjectID) ([]models.Category, error) {
-	filter := bson.M{"parent_id": parentID, "is_deleted": false}
+func (r *CategoryRepository) GetChildren(ctx context.Context, parentID primitive.ObjectID, tenantID string) ([]models.Category, error) {
+	filter := bson.M{"parent_id": parentID, "tenant_id": tenantID, "is_deleted": false}
 	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
 	if err != nil {
 		return nil, err
@@ -112,8 +113,8 @@ func (r *CategoryRepository) GetChildren(ctx context.Context, parentID primitive
 	return items, nil
 }
 
-func (r *CategoryRepository) GetRootCategories(ctx context.Context) ([]models.Category, error) {
-	filter := bson.M{"parent_id": bson.M{"$exists": false}, "is_deleted": false}
+func (r *CategoryRepository) GetRootCategories(ctx context.Context, tenantID string) ([]models.Category, error) {
+	filter := bson.M{"parent_id": bson.M{"$exists": false}, "tenant_id": tenantID, "is_deleted": false}
 	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
 	if err != nil {
 		return nil, err
@@ -143,39 +144,39 @@ func (r *CategoryRepository) Create(ctx context.Context, m *models.Category) (*m
 	return m, nil
 }
 
-func (r *CategoryRepository) Update(ctx context.Context, id primitive.ObjectID, update bson.M) (*models.Category, error) {
+func (r *CategoryRepository) Update(ctx context.Context, id primitive.ObjectID, tenantID string, update bson.M) (*models.Category, error) {
 	if update == nil {
 		update = bson.M{}
 	}
 	update["updated_at"] = time.Now().UTC()
 
-	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{"$set": update})
 	if err != nil {
 		return nil, err
 	}
-	return r.Get(ctx, id)
+	return r.Get(ctx, id, tenantID)
 }
 
-func (r *CategoryRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+func (r *CategoryRepository) Delete(ctx context.Context, id primitive.ObjectID, tenantID string) error {
 	now := time.Now().UTC()
 	update := bson.M{
 		"is_deleted": true,
 		"updated_at": now,
 	}
-	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{"$set": update})
 	return err
 }
 
-func (r *CategoryRepository) HasChildren(ctx context.Context, id primitive.ObjectID) (bool, error) {
-	count, err := r.col.CountDocuments(ctx, bson.M{"parent_id": id, "is_deleted": false})
+func (r *CategoryRepository) HasChildren(ctx context.Context, id primitive.ObjectID, tenantID string) (bool, error) {
+	count, err := r.col.CountDocuments(ctx, bson.M{"parent_id": id, "tenant_id": tenantID, "is_deleted": false})
 	if err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
-func (r *CategoryRepository) ListAll(ctx context.Context) ([]models.Category, error) {
-	cur, err := r.col.Find(ctx, bson.M{"is_deleted": false}, options.Find().SetSort(bson.D{{Key: "level", Value: 1}, {Key: "name", Value: 1}}))
+func (r *CategoryRepository) ListAll(ctx context.Context, tenantID string) ([]models.Category, error) {
+	cur, err := r.col.Find(ctx, bson.M{"tenant_id": tenantID, "is_deleted": false}, options.Find().SetSort(bson.D{{Key: "level", Value: 1}, {Key: "name", Value: 1}}))
 	if err != nil { return nil, err }
 	defer cur.Close(ctx)
 	var items []models.Category
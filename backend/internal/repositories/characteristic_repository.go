@@ -19,6 +19,7 @@ type CharacteristicListParams struct {
 	Search   string
 	IsActive *bool
 	Type     string
+	TenantID string
 }
 
 type CharacteristicRepository struct {
@@ -40,7 +41,7 @@ func (r *CharacteristicRepository) List(ctx context.Context, p CharacteristicLis
 		p.Sort = bson.D{{Key: "created_at", Value: -1}}
 	}
 
-	filter := bson.M{"is_deleted": false}
+	filter := bson.M{"tenant_id": p.TenantID, "is_deleted": false}
 	if p.Search != "" {
 		filter["name"] = bson.M{"$regex": p.Search, "$options": "i"}
 	}
@@ -71,20 +72,20 @@ func (r *CharacteristicRepository) List(ctx context.Context, p CharacteristicLis
 	return items, total, nil
 }
 
-func (r *CharacteristicRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Characteristic, error) {
+func (r *CharacteristicRepository) Get(ctx context.Context, id primitive.ObjectID, tenantID string) (*models.Characteristic, error) {
 	var m models.Characteristic
-	if err := r.col.FindOne(ctx, bson.M{"_id": id, "is_deleted": false}).Decode(&m); err != nil {
+	if err := r.col.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID, "is_deleted": false}).Decode(&m); err != nil {
 		return nil, err
 	}
 	return &m, nil
 }
 
-func (r *CharacteristicRepository) GetByIDHex(ctx context.Context, id string) (*models.Characteristic, error) {
+func (r *CharacteristicRepository) GetByIDHex(ctx context.Context, id string, tenantID string) (*models.Characteristic, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, err
 	}
-	return r.Get(ctx, oid)
+	return r.Get(ctx, oid, tenantID)
 }
 
 func (r *CharacteristicRepository) Create(ctx context.Context, m *models.Characteristic) (*models.Characteristic, error) {
@@ -103,25 +104,25 @@ func (r *CharacteristicRepository) Create(ctx context.Context, m *models.Charact
 	return m, nil
 }
 
-func (r *CharacteristicRepository) Update(ctx context.Context, id primitive.ObjectID, update bson.M) (*models.Characteristic, error) {
+func (r *CharacteristicRepository) Update(ctx context.Context, id primitive.ObjectID, tenantID string, update bson.M) (*models.Characteristic, error) {
 	if update == nil {
 		update = bson.M{}
 	}
 	update["updated_at"] = time.Now().UTC()
 
-	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{"$set": update})
 	if err != nil {
 		return nil, err
 	}
-	return r.Get(ctx, id)
+	return r.Get(ctx, id, tenantID)
 }
 
-func (r *CharacteristicRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+func (r *CharacteristicRepository) Delete(ctx context.Context, id primitive.ObjectID, tenantID string) error {
 	now := time.Now().UTC()
 	update := bson.M{
 		"is_deleted": true,
 		"updated_at": now,
 	}
-	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{"$set": update})
 	return err
 } 
\ No newline at end of file
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ConsignmentBatchRepository struct { col *mongo.Collection }
+
+func NewConsignmentBatchRepository(db *mongo.Database) *ConsignmentBatchRepository { return &ConsignmentBatchRepository{col: db.Collection("consignment_batches")} }
+
+func (r *ConsignmentBatchRepository) Create(ctx context.Context, m *models.ConsignmentBatch) (*models.ConsignmentBatch, error) {
+	now := time.Now().UTC()
+	m.ReceivedAt, m.UpdatedAt = now, now
+	res, err := r.col.InsertOne(ctx, m)
+	if err != nil { return nil, err }
+	m.ID = res.InsertedID.(primitive.ObjectID)
+	return m, nil
+}
+
+func (r *ConsignmentBatchRepository) Get(ctx context.Context, id primitive.ObjectID, tenantID string) (*models.ConsignmentBatch, error) {
+	var m models.ConsignmentBatch
+	if err := r.col.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}).Decode(&m); err != nil { return nil, err }
+	return &m, nil
+}
+
+// Open returns the batches of a product with units on hand, oldest first; supplierID may be empty for every supplier.
+func (r *ConsignmentBatchRepository) Open(ctx context.Context, tenantID string, productID primitive.ObjectID, supplierID string) ([]models.ConsignmentBatch, error) {
+	filter := bson.M{"tenant_id": tenantID, "product_id": productID, "on_hand": bson.M{"$gt": 0}}
+	if supplierID != "" { filter["supplier_id"] = supplierID }
+	return r.find(ctx, filter)
+}
+
+// OnHand returns the batches with units on hand, optionally of one supplier and received into one store.
+func (r *ConsignmentBatchRepository) OnHand(ctx context.Context, tenantID, supplierID, shopID string) ([]models.ConsignmentBatch, error) {
+	filter := bson.M{"tenant_id": tenantID, "on_hand": bson.M{"$gt": 0}}
+	if supplierID != "" { filter["supplier_id"] = supplierID }
+	if shopID != "" { filter["shop_id"] = shopID }
+	return r.find(ctx, filter)
+}
+
+// Due returns a supplier's batches received into a store with units on hand whose return date is at or before at.
+func (r *ConsignmentBatchRepository) Due(ctx context.Context, tenantID, supplierID, shopID string, at time.Time) ([]models.ConsignmentBatch, error) {
+	return r.find(ctx, bson.M{"tenant_id": tenantID, "supplier_id": supplierID, "shop_id": shopID, "on_hand": bson.M{"$gt": 0}, "due_date": bson.M{"$lte": at}})
+}
+
+// Inc shifts a batch's on-hand, sold and returned quantities. It only applies when neither on hand nor sold would
+// go below zero; ok is false otherwise.
+func (r *ConsignmentBatchRepository) Inc(ctx context.Context, id primitive.ObjectID, tenantID string, onHand, sold, returned float64) (bool, error) {
+	filter := bson.M{"_id": id, "tenant_id": tenantID}
+	if onHand < 0 { filter["on_hand"] = bson.M{"$gte": -onHand} }
+	if sold < 0 { filter["sold"] = bson.M{"$gte": -sold} }
+	set := bson.M{
+		"on_hand":    roundQty(bson.M{"$add": bson.A{"$on_hand", onHand}}),
+		"sold":       roundQty(bson.M{"$add": bson.A{"$sold", sold}}),
+		"returned":   roundQty(bson.M{"$add": bson.A{"$returned", returned}}),
+		"updated_at": time.Now().UTC(),
+	}
+	res, err := r.col.UpdateOne(ctx, filter, mongo.Pipeline{bson.D{{Key: "$set", Value: set}}})
+	if err != nil { return false, err }
+	return res.MatchedCount == 1, nil
+}
+
+func (r *ConsignmentBatchRepository) find(ctx context.Context, filter bson.M) ([]models.ConsignmentBatch, error) {
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "received_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	items := []models.ConsignmentBatch{}
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
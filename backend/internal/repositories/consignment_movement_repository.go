@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ConsignmentMovementRepository is the append-only log of draws on consignment batches; a movement is only ever
+// stamped with the settlement that paid for it.
+type ConsignmentMovementRepository struct { col *mongo.Collection }
+
+func NewConsignmentMovementRepository(db *mongo.Database) *ConsignmentMovementRepository { return &ConsignmentMovementRepository{col: db.Collection("consignment_movements")} }
+
+func (r *ConsignmentMovementRepository) Create(ctx context.Context, m *models.ConsignmentMovement) (*models.ConsignmentMovement, error) {
+	m.CreatedAt = time.Now().UTC()
+	res, err := r.col.InsertOne(ctx, m)
+	if err != nil { return nil, err }
+	m.ID = res.InsertedID.(primitive.ObjectID)
+	return m, nil
+}
+
+// BySource returns the movements of one document and product, oldest first.
+func (r *ConsignmentMovementRepository) BySource(ctx context.Context, tenantID, sourceType, sourceID string, productID primitive.ObjectID) ([]models.ConsignmentMovement, error) {
+	return r.find(ctx, bson.M{"tenant_id": tenantID, "source_type": sourceType, "source_id": sourceID, "product_id": productID})
+}
+
+// ReturnsOf returns the sale return movements of one sale and product, oldest first. Returns logged before they
+// carried their own id were logged under the sale's.
+func (r *ConsignmentMovementRepository) ReturnsOf(ctx context.Context, tenantID, saleID string, productID primitive.ObjectID) ([]models.ConsignmentMovement, error) {
+	return r.find(ctx, bson.M{"tenant_id": tenantID, "source_type": models.StockSourceSaleReturn, "product_id": productID, "$or": bson.A{bson.M{"origin_id": saleID}, bson.M{"source_id": saleID}}})
+}
+
+// Unsettled returns a supplier's sales and sale returns in [from, to) that no settlement has paid for yet; shopID
+// may be empty for every store.
+func (r *ConsignmentMovementRepository) Unsettled(ctx context.Context, tenantID, supplierID, shopID string, from, to time.Time) ([]models.ConsignmentMovement, error) {
+	filter := bson.M{
+		"tenant_id": tenantID, "supplier_id": supplierID, "settlement_id": "",
+		"source_type": bson.M{"$in": bson.A{models.StockSourceSale, models.StockSourceSaleReturn}},
+		"created_at":  bson.M{"$gte": from, "$lt": to},
+	}
+	if shopID != "" { filter["shop_id"] = shopID }
+	return r.find(ctx, filter)
+}
+
+// Settle stamps the unsettled movements among ids with a settlement and returns how many it stamped.
+func (r *ConsignmentMovementRepository) Settle(ctx context.Context, tenantID string, ids []primitive.ObjectID, settlementID string) (int64, error) {
+	if len(ids) == 0 { return 0, nil }
+	res, err := r.col.UpdateMany(ctx, bson.M{"tenant_id": tenantID, "_id": bson.M{"$in": ids}, "settlement_id": ""}, bson.M{"$set": bson.M{"settlement_id": settlementID}})
+	if err != nil { return 0, err }
+	return res.ModifiedCount, nil
+}
+
+func (r *ConsignmentMovementRepository) find(ctx context.Context, filter bson.M) ([]models.ConsignmentMovement, error) {
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	items := []models.ConsignmentMovement{}
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ConsignmentSettlementListParams struct {
+	Page       int64
+	Limit      int64
+	SupplierID string
+	Status     string
+	TenantID   string
+}
+
+type ConsignmentSettlementRepository struct { col *mongo.Collection }
+
+func NewConsignmentSettlementRepository(db *mongo.Database) *ConsignmentSettlementRepository { return &ConsignmentSettlementRepository{col: db.Collection("consignment_settlements")} }
+
+func (r *ConsignmentSettlementRepository) List(ctx context.Context, p ConsignmentSettlementListParams) ([]models.ConsignmentSettlement, int64, error) {
+	if p.Page < 1 { p.Page = 1 }
+	if p.Limit < 1 || p.Limit > 200 { p.Limit = 20 }
+	filter := bson.M{"tenant_id": p.TenantID}
+	if p.SupplierID != "" { filter["supplier_id"] = p.SupplierID }
+	if p.Status != "" { filter["status"] = p.Status }
+	opts := options.Find().SetSkip((p.Page-1)*p.Limit).SetLimit(p.Limit).SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil { return nil, 0, err }
+	defer cur.Close(ctx)
+	var items []models.ConsignmentSettlement
+	if err := cur.All(ctx, &items); err != nil { return nil, 0, err }
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil { return nil, 0, err }
+	return items, total, nil
+}
+
+func (r *ConsignmentSettlementRepository) Get(ctx context.Context, id primitive.ObjectID, tenantID string) (*models.ConsignmentSettlement, error) {
+	var m models.ConsignmentSettlement
+	if err := r.col.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}).Decode(&m); err != nil { return nil, err }
+	return &m, nil
+}
+
+func (r *ConsignmentSettlementRepository) Create(ctx context.Context, m *models.ConsignmentSettlement) (*models.ConsignmentSettlement, error) {
+	now := time.Now().UTC()
+	m.CreatedAt = now
+	m.UpdatedAt = now
+	if m.Status == "" { m.Status = "NEW" }
+	res, err := r.col.InsertOne(ctx, m)
+	if err != nil { return nil, err }
+	m.ID = res.InsertedID.(primitive.ObjectID)
+	return m, nil
+}
+
+func (r *ConsignmentSettlementRepository) Update(ctx context.Context, id primitive.ObjectID, tenantID string, update bson.M) (*models.ConsignmentSettlement, error) {
+	if update == nil { update = bson.M{} }
+	update["updated_at"] = time.Now().UTC()
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{"$set": update})
+	if err != nil { return nil, err }
+	return r.Get(ctx, id, tenantID)
+}
+
+func (r *ConsignmentSettlementRepository) Delete(ctx context.Context, id primitive.ObjectID, tenantID string) error {
+	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
+	return err
+}
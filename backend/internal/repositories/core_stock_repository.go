@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CoreStockRepository struct { col *mongo.Collection }
+
+func NewCoreStockRepository(db *mongo.Database) *CoreStockRepository { return &CoreStockRepository{col: db.Collection("core_stock")} }
+
+// Receive adds dirty cores of a product to a store's core inventory, creating its balance when missing.
+func (r *CoreStockRepository) Receive(ctx context.Context, tenantID, shopID string, productID primitive.ObjectID, qty float64) error {
+	now := time.Now().UTC()
+	update := mongo.Pipeline{bson.D{{Key: "$set", Value: bson.M{
+		"on_hand":    roundQty(bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$on_hand", 0}}, qty}}),
+		"received":   roundQty(bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$received", 0}}, qty}}),
+		"shipped":    bson.M{"$ifNull": bson.A{"$shipped", 0}},
+		"updated_at": now,
+		"created_at": bson.M{"$ifNull": bson.A{"$created_at", now}},
+	}}}}
+	_, err := r.col.UpdateOne(ctx, bson.M{"tenant_id": tenantID, "shop_id": shopID, "product_id": productID}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// Ship takes cores out of a store's core inventory. It only applies when enough are on hand; ok is false otherwise.
+func (r *CoreStockRepository) Ship(ctx context.Context, tenantID, shopID string, productID primitive.ObjectID, qty float64) (bool, error) {
+	filter := bson.M{"tenant_id": tenantID, "shop_id": shopID, "product_id": productID, "on_hand": bson.M{"$gte": qty}}
+	update := mongo.Pipeline{bson.D{{Key: "$set", Value: bson.M{
+		"on_hand":    roundQty(bson.M{"$subtract": bson.A{"$on_hand", qty}}),
+		"shipped":    roundQty(bson.M{"$add": bson.A{"$shipped", qty}}),
+		"updated_at": time.Now().UTC(),
+	}}}}
+	res, err := r.col.UpdateOne(ctx, filter, update)
+	if err != nil { return false, err }
+	return res.MatchedCount == 1, nil
+}
+
+// OnHand returns the balances with cores on hand, optionally of one store.
+func (r *CoreStockRepository) OnHand(ctx context.Context, tenantID, shopID string) ([]models.CoreStock, error) {
+	filter := bson.M{"tenant_id": tenantID, "on_hand": bson.M{"$gt": 0}}
+	if shopID != "" { filter["shop_id"] = shopID }
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "shop_id", Value: 1}, {Key: "product_id", Value: 1}}))
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	items := []models.CoreStock{}
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
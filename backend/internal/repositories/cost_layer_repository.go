@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CostLayerRepository struct { col *mongo.Collection }
+
+func NewCostLayerRepository(db *mongo.Database) *CostLayerRepository { return &CostLayerRepository{col: db.Collection("cost_layers")} }
+
+func (r *CostLayerRepository) Create(ctx context.Context, m *models.CostLayer) (*models.CostLayer, error) {
+	if m.CreatedAt.IsZero() { m.CreatedAt = time.Now().UTC() }
+	res, err := r.col.InsertOne(ctx, m)
+	if err != nil { return nil, err }
+	m.ID = res.InsertedID.(primitive.ObjectID)
+	return m, nil
+}
+
+func openLayers(tenantID string, productID primitive.ObjectID, shopID string) bson.M {
+	return bson.M{"tenant_id": tenantID, "product_id": productID, "shop_id": shopID, "left": bson.M{"$gt": 0}}
+}
+
+// Consume takes qty units from the oldest open layers and returns their cost and the number of units the layers
+// covered, which is less than qty when they run out.
+func (r *CostLayerRepository) Consume(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, qty float64) (float64, float64, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cur, err := r.col.Find(ctx, openLayers(tenantID, productID, shopID), opts)
+	if err != nil { return 0, 0, err }
+	var layers []models.CostLayer
+	if err := cur.All(ctx, &layers); err != nil { return 0, 0, err }
+	var cost, covered float64
+	for _, l := range layers {
+		if covered >= qty { break }
+		n := l.Left
+		if n > qty-covered { n = models.RoundQty(qty - covered) }
+		update := mongo.Pipeline{bson.D{{Key: "$set", Value: bson.M{"left": roundQty(bson.M{"$subtract": bson.A{"$left", n}})}}}}
+		if _, err := r.col.UpdateOne(ctx, bson.M{"_id": l.ID}, update); err != nil { return 0, 0, err }
+		cost += n * l.UnitCost
+		covered = models.RoundQty(covered + n)
+	}
+	return cost, covered, nil
+}
+
+// Open sums the units left and their cost over a product's open layers in a store.
+func (r *CostLayerRepository) Open(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string) (float64, float64, error) {
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: openLayers(tenantID, productID, shopID)}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": nil, "qty": bson.M{"$sum": "$left"}, "value": bson.M{"$sum": bson.M{"$multiply": bson.A{"$left", "$unit_cost"}}}}}},
+	}
+	cur, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil { return 0, 0, err }
+	defer cur.Close(ctx)
+	var row struct {
+		Qty   float64 `bson:"qty"`
+		Value float64 `bson:"value"`
+	}
+	if cur.Next(ctx) { if err := cur.Decode(&row); err != nil { return 0, 0, err } }
+	return row.Qty, row.Value, cur.Err()
+}
@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// nextSequence increments the named counter in the counters collection and returns its new value, starting at 1.
+func nextSequence(ctx context.Context, counters *mongo.Collection, key string) (int64, error) {
+	var c struct{ Seq int64 `bson:"seq"` }
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	if err := counters.FindOneAndUpdate(ctx, bson.M{"_id": key}, bson.M{"$inc": bson.M{"seq": 1}}, opts).Decode(&c); err != nil { return 0, err }
+	return c.Seq, nil
+}
@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CustomerDebtEntryRepository is the append-only receivables ledger; entries are never updated or deleted.
+type CustomerDebtEntryRepository struct { col *mongo.Collection }
+
+func NewCustomerDebtEntryRepository(db *mongo.Database) *CustomerDebtEntryRepository { return &CustomerDebtEntryRepository{ col: db.Collection("customer_debt_entries") } }
+
+func (r *CustomerDebtEntryRepository) Create(ctx context.Context, m *models.CustomerDebtEntry) (*models.CustomerDebtEntry, error) {
+	m.CreatedAt = time.Now().UTC()
+	res, err := r.col.InsertOne(ctx, m)
+	if err != nil { return nil, err }
+	m.ID = res.InsertedID.(primitive.ObjectID)
+	return m, nil
+}
+
+// ListByCustomer returns a customer's postings, newest first.
+func (r *CustomerDebtEntryRepository) ListByCustomer(ctx context.Context, tenantID, customerID string, page, limit int64) ([]models.CustomerDebtEntry, int64, error) {
+	if page < 1 { page = 1 }
+	if limit < 1 || limit > 200 { limit = 20 }
+	filter := bson.M{"tenant_id": tenantID, "customer_id": customerID}
+	opts := options.Find().SetSkip((page-1)*limit).SetLimit(limit).SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil { return nil, 0, err }
+	defer cur.Close(ctx)
+	items := []models.CustomerDebtEntry{}
+	if err := cur.All(ctx, &items); err != nil { return nil, 0, err }
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil { return nil, 0, err }
+	return items, total, nil
+}
+
+// ListRepaymentsByShift returns the repayments taken in a cash shift.
+func (r *CustomerDebtEntryRepository) ListRepaymentsByShift(ctx context.Context, tenantID string, shiftID string) ([]models.CustomerDebtEntry, error) {
+	cur, err := r.col.Find(ctx, bson.M{"tenant_id": tenantID, "shift_id": shiftID, "type": models.DebtEntryRepayment})
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	items := []models.CustomerDebtEntry{}
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CustomerDebtListParams struct {
+	TenantID   string
+	Page       int64
+	Limit      int64
+	CustomerID string
+	ShopID     string
+	Status     string
+	OverdueAt  *time.Time // only debts with an installment due before this time
+}
+
+type CustomerDebtRepository struct { col *mongo.Collection }
+
+func NewCustomerDebtRepository(db *mongo.Database) *CustomerDebtRepository { return &CustomerDebtRepository{ col: db.Collection("customer_debts") } }
+
+func (r *CustomerDebtRepository) List(ctx context.Context, p CustomerDebtListParams) ([]models.CustomerDebt, int64, error) {
+	if p.Page < 1 { p.Page = 1 }
+	if p.Limit < 1 || p.Limit > 200 { p.Limit = 20 }
+	filter := bson.M{"tenant_id": p.TenantID}
+	if p.CustomerID != "" { filter["customer_id"] = p.CustomerID }
+	if p.ShopID != "" { filter["shop_id"] = p.ShopID }
+	if p.Status != "" { filter["status"] = p.Status }
+	if p.OverdueAt != nil { filter["status"] = "OPEN"; filter["due_date"] = bson.M{"$lt": *p.OverdueAt} }
+	opts := options.Find().SetSkip((p.Page-1)*p.Limit).SetLimit(p.Limit).SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil { return nil, 0, err }
+	defer cur.Close(ctx)
+	var items []models.CustomerDebt
+	if err := cur.All(ctx, &items); err != nil { return nil, 0, err }
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil { return nil, 0, err }
+	return items, total, nil
+}
+
+func (r *CustomerDebtRepository) Get(ctx context.Context, id primitive.ObjectID, tenantID string) (*models.CustomerDebt, error) {
+	var m models.CustomerDebt
+	if err := r.col.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}).Decode(&m); err != nil { return nil, err }
+	return &m, nil
+}
+
+func (r *CustomerDebtRepository) GetBySale(ctx context.Context, tenantID string, saleID string) (*models.CustomerDebt, error) {
+	var m models.CustomerDebt
+	if err := r.col.FindOne(ctx, bson.M{"tenant_id": tenantID, "sale_id": saleID}).Decode(&m); err != nil { return nil, err }
+	return &m, nil
+}
+
+func (r *CustomerDebtRepository) Create(ctx context.Context, m *models.CustomerDebt) (*models.CustomerDebt, error) {
+	now := time.Now().UTC()
+	m.CreatedAt = now
+	m.UpdatedAt = now
+	if m.Installments == nil { m.Installments = []models.DebtInstallment{} }
+	res, err := r.col.InsertOne(ctx, m)
+	if err != nil { return nil, err }
+	m.ID = res.InsertedID.(primitive.ObjectID)
+	return m, nil
+}
+
+func (r *CustomerDebtRepository) Update(ctx context.Context, id primitive.ObjectID, tenantID string, update bson.M) (*models.CustomerDebt, error) {
+	if update == nil { update = bson.M{} }
+	update["updated_at"] = time.Now().UTC()
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{"$set": update})
+	if err != nil { return nil, err }
+	return r.Get(ctx, id, tenantID)
+}
+
+// ListOpen returns the unpaid debts, oldest first. Empty customerID or shopID match all.
+func (r *CustomerDebtRepository) ListOpen(ctx context.Context, tenantID, customerID, shopID string) ([]models.CustomerDebt, error) {
+	filter := bson.M{"tenant_id": tenantID, "status": "OPEN"}
+	if customerID != "" { filter["customer_id"] = customerID }
+	if shopID != "" { filter["shop_id"] = shopID }
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	items := []models.CustomerDebt{}
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
+
+// Debtors sums the open debts per customer, largest balance first. Overdue counts the unpaid part of installments
+// due before now.
+func (r *CustomerDebtRepository) Debtors(ctx context.Context, tenantID, shopID string, now time.Time, page, limit int64) ([]models.Debtor, int64, error) {
+	if page < 1 { page = 1 }
+	if limit < 1 || limit > 200 { limit = 20 }
+	match := bson.M{"tenant_id": tenantID, "status": "OPEN"}
+	if shopID != "" { match["shop_id"] = shopID }
+	overdue := bson.M{"$sum": bson.M{"$map": bson.M{
+		"input": bson.M{"$filter": bson.M{"input": "$installments", "as": "i", "cond": bson.M{"$lt": bson.A{"$$i.due_date", now}}}},
+		"as": "i",
+		"in": bson.M{"$subtract": bson.A{"$$i.amount", "$$i.paid"}},
+	}}}
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id": "$customer_id",
+			"customer": bson.M{"$first": "$customer"},
+			"balance": bson.M{"$sum": "$balance"},
+			"overdue": bson.M{"$sum": overdue},
+			"debts_count": bson.M{"$sum": 1},
+			"oldest_due_date": bson.M{"$min": "$due_date"},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "balance", Value: -1}, {Key: "_id", Value: 1}}}},
+		bson.D{{Key: "$facet", Value: bson.M{
+			"items": mongo.Pipeline{
+				bson.D{{Key: "$skip", Value: (page-1)*limit}},
+				bson.D{{Key: "$limit", Value: limit}},
+			},
+			"total": mongo.Pipeline{
+				bson.D{{Key: "$count", Value: "count"}},
+			},
+		}}},
+	}
+	cur, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil { return nil, 0, err }
+	defer cur.Close(ctx)
+	var out []struct{ Items []models.Debtor `bson:"items"`; Total []struct{ Count int64 `bson:"count"` } `bson:"total"` }
+	if err := cur.All(ctx, &out); err != nil { return nil, 0, err }
+	if len(out) == 0 { return []models.Debtor{}, 0, nil }
+	var total int64
+	if len(out[0].Total) > 0 { total = out[0].Total[0].Count }
+	return out[0].Items, total, nil
+}
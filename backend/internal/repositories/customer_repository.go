@@ -76,4 +76,12 @@ func (r *CustomerRepository) Update(ctx context.Context, id primitive.ObjectID,
 func (r *CustomerRepository) Delete(ctx context.Context, id primitive.ObjectID, tenantID string) error {
 	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
 	return err
-} 
\ No newline at end of file
+} 
+// IncDebt moves a customer's debt balance by delta and returns the new balance.
+func (r *CustomerRepository) IncDebt(ctx context.Context, id primitive.ObjectID, tenantID string, delta float64) (float64, error) {
+	var m models.Customer
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.col.FindOneAndUpdate(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{"$inc": bson.M{"debt_balance": delta}, "$set": bson.M{"updated_at": time.Now().UTC()}}, opts).Decode(&m)
+	if err != nil { return 0, err }
+	return m.DebtBalance, nil
+}
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CycleCountPlanListParams struct {
+	Page      int64
+	Limit     int64
+	Sort      bson.D
+	ShopID    string
+	Basis     string
+	TenantID  string
+}
+
+type CycleCountPlanRepository struct { col *mongo.Collection }
+
+func NewCycleCountPlanRepository(db *mongo.Database) *CycleCountPlanRepository { return &CycleCountPlanRepository{ col: db.Collection("cycle_count_plans") } }
+
+func (r *CycleCountPlanRepository) List(ctx context.Context, p CycleCountPlanListParams) ([]models.CycleCountPlan, int64, error) {
+	if p.Page < 1 { p.Page = 1 }
+	if p.Limit < 1 || p.Limit > 200 { p.Limit = 20 }
+	if p.Sort == nil { p.Sort = bson.D{{Key: "created_at", Value: -1}} }
+
+	filter := bson.M{"tenant_id": p.TenantID}
+	if p.ShopID != "" { filter["shop_id"] = p.ShopID }
+	if p.Basis != "" { filter["basis"] = p.Basis }
+
+	opts := options.Find().SetSkip((p.Page-1)*p.Limit).SetLimit(p.Limit).SetSort(p.Sort)
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil { return nil, 0, err }
+	defer cur.Close(ctx)
+
+	var items []models.CycleCountPlan
+	if err := cur.All(ctx, &items); err != nil { return nil, 0, err }
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil { return nil, 0, err }
+	return items, total, nil
+}
+
+func (r *CycleCountPlanRepository) Get(ctx context.Context, id primitive.ObjectID, tenantID string) (*models.CycleCountPlan, error) {
+	var m models.CycleCountPlan
+	if err := r.col.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}).Decode(&m); err != nil { return nil, err }
+	return &m, nil
+}
+
+func (r *CycleCountPlanRepository) Create(ctx context.Context, m *models.CycleCountPlan) (*models.CycleCountPlan, error) {
+	now := time.Now().UTC()
+	m.CreatedAt = now
+	m.UpdatedAt = now
+	res, err := r.col.InsertOne(ctx, m)
+	if err != nil { return nil, err }
+	m.ID = res.InsertedID.(primitive.ObjectID)
+	return m, nil
+}
+
+func (r *CycleCountPlanRepository) Update(ctx context.Context, id primitive.ObjectID, tenantID string, update bson.M) (*models.CycleCountPlan, error) {
+	if update == nil { update = bson.M{} }
+	update["updated_at"] = time.Now().UTC()
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{"$set": update})
+	if err != nil { return nil, err }
+	return r.Get(ctx, id, tenantID)
+}
+
+func (r *CycleCountPlanRepository) Delete(ctx context.Context, id primitive.ObjectID, tenantID string) error {
+	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
+	return err
+}
+
+// Due returns the active plans whose next run has come, of every tenant when tenantID is empty.
+func (r *CycleCountPlanRepository) Due(ctx context.Context, tenantID string, now time.Time) ([]models.CycleCountPlan, error) {
+	filter := bson.M{"is_active": true, "next_run_at": bson.M{"$lte": now}}
+	if tenantID != "" { filter["tenant_id"] = tenantID }
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "next_run_at", Value: 1}}))
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	items := []models.CycleCountPlan{}
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
+
+// Claim moves a plan's next run from the given time on, so that one run at a time generates its count. ok is false
+// when another run got there first.
+func (r *CycleCountPlanRepository) Claim(ctx context.Context, id primitive.ObjectID, tenantID string, at, next time.Time) (bool, error) {
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID, "next_run_at": at}, bson.M{"$set": bson.M{"next_run_at": next, "updated_at": time.Now().UTC()}})
+	if err != nil { return false, err }
+	return res.MatchedCount == 1, nil
+}
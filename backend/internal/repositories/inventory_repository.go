@@ -89,7 +89,49 @@ func (r *InventoryRepository) Update(ctx context.Context, id primitive.ObjectID,
 	return r.Get(ctx, id, tenantID)
 }
 
+// UpdateIf updates the inventory only while it still matches expect, e.g. is not finished yet. It reports false
+// otherwise.
+func (r *InventoryRepository) UpdateIf(ctx context.Context, id primitive.ObjectID, tenantID string, expect bson.M, update bson.M) (bool, error) {
+	return updateIf(ctx, r.col, id, tenantID, expect, update)
+}
+
 func (r *InventoryRepository) Delete(ctx context.Context, id primitive.ObjectID, tenantID string) error {
 	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
 	return err
-} 
\ No newline at end of file
+}
+
+// Finished returns the finished inventories in a time range, optionally of one store and only those generated by
+// cycle count plans, oldest first.
+func (r *InventoryRepository) Finished(ctx context.Context, tenantID, shopID string, from, to time.Time, cycleOnly bool) ([]models.Inventory, error) {
+	filter := bson.M{"tenant_id": tenantID, "status_id": "finished", "finished_at": bson.M{"$gte": from, "$lte": to}}
+	if shopID != "" { filter["shop_id"] = shopID }
+	if cycleOnly { filter["cycle_count_plan_id"] = bson.M{"$exists": true, "$ne": ""} }
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "finished_at", Value: 1}}))
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	items := []models.Inventory{}
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
+
+// LastCounted returns when each product was last counted in a store by a finished inventory.
+func (r *InventoryRepository) LastCounted(ctx context.Context, tenantID, shopID string) (map[primitive.ObjectID]time.Time, error) {
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"tenant_id": tenantID, "shop_id": shopID, "status_id": "finished"}}},
+		bson.D{{Key: "$unwind", Value: "$items"}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": "$items.product_id", "at": bson.M{"$max": "$finished_at"}}}},
+	}
+	cur, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	out := map[primitive.ObjectID]time.Time{}
+	for cur.Next(ctx) {
+		var row struct {
+			ID primitive.ObjectID `bson:"_id"`
+			At time.Time          `bson:"at"`
+		}
+		if err := cur.Decode(&row); err != nil { return nil, err }
+		out[row.ID] = row.At
+	}
+	return out, cur.Err()
+}
@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type KitAssemblyListParams struct {
+	Page      int64
+	Limit     int64
+	Sort      bson.D
+	Type      string
+	ShopID    string
+	ProductID *primitive.ObjectID
+	Status    string
+	TenantID  string
+}
+
+type KitAssemblyRepository struct { col *mongo.Collection }
+
+func NewKitAssemblyRepository(db *mongo.Database) *KitAssemblyRepository { return &KitAssemblyRepository{ col: db.Collection("kit_assemblies") } }
+
+func (r *KitAssemblyRepository) List(ctx context.Context, p KitAssemblyListParams) ([]models.KitAssembly, int64, error) {
+	if p.Page < 1 { p.Page = 1 }
+	if p.Limit < 1 || p.Limit > 200 { p.Limit = 20 }
+	if p.Sort == nil { p.Sort = bson.D{{Key: "created_at", Value: -1}} }
+
+	filter := bson.M{"tenant_id": p.TenantID}
+	if p.Type != "" { filter["type"] = p.Type }
+	if p.ShopID != "" { filter["shop_id"] = p.ShopID }
+	if p.ProductID != nil { filter["product_id"] = *p.ProductID }
+	if p.Status != "" { filter["status"] = p.Status }
+
+	opts := options.Find().SetSkip((p.Page-1)*p.Limit).SetLimit(p.Limit).SetSort(p.Sort)
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil { return nil, 0, err }
+	defer cur.Close(ctx)
+
+	var items []models.KitAssembly
+	if err := cur.All(ctx, &items); err != nil { return nil, 0, err }
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil { return nil, 0, err }
+	return items, total, nil
+}
+
+func (r *KitAssemblyRepository) Get(ctx context.Context, id primitive.ObjectID, tenantID string) (*models.KitAssembly, error) {
+	var m models.KitAssembly
+	if err := r.col.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}).Decode(&m); err != nil { return nil, err }
+	return &m, nil
+}
+
+func (r *KitAssemblyRepository) Create(ctx context.Context, m *models.KitAssembly) (*models.KitAssembly, error) {
+	now := time.Now().UTC()
+	m.CreatedAt = now
+	m.UpdatedAt = now
+	if m.Status == "" { m.Status = "NEW" }
+	res, err := r.col.InsertOne(ctx, m)
+	if err != nil { return nil, err }
+	m.ID = res.InsertedID.(primitive.ObjectID)
+	return m, nil
+}
+
+func (r *KitAssemblyRepository) Update(ctx context.Context, id primitive.ObjectID, tenantID string, update bson.M) (*models.KitAssembly, error) {
+	if update == nil { update = bson.M{} }
+	update["updated_at"] = time.Now().UTC()
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{"$set": update})
+	if err != nil { return nil, err }
+	return r.Get(ctx, id, tenantID)
+}
+
+// UpdateIf updates the document only while it still matches expect. It reports false otherwise.
+func (r *KitAssemblyRepository) UpdateIf(ctx context.Context, id primitive.ObjectID, tenantID string, expect bson.M, update bson.M) (bool, error) {
+	return updateIf(ctx, r.col, id, tenantID, expect, update)
+}
+
+func (r *KitAssemblyRepository) Delete(ctx context.Context, id primitive.ObjectID, tenantID string) error {
+	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
+	return err
+}
@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MeasureUnitRepository struct { col *mongo.Collection }
+
+func NewMeasureUnitRepository(db *mongo.Database) *MeasureUnitRepository { return &MeasureUnitRepository{col: db.Collection("measure_units")} }
+
+// List returns a tenant's own units by code.
+func (r *MeasureUnitRepository) List(ctx context.Context, tenantID string) ([]models.MeasureUnit, error) {
+	cur, err := r.col.Find(ctx, bson.M{"tenant_id": tenantID}, options.Find().SetSort(bson.D{{Key: "code", Value: 1}}))
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	var items []models.MeasureUnit
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
+
+func (r *MeasureUnitRepository) Get(ctx context.Context, id primitive.ObjectID, tenantID string) (*models.MeasureUnit, error) {
+	var m models.MeasureUnit
+	if err := r.col.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}).Decode(&m); err != nil { return nil, err }
+	return &m, nil
+}
+
+func (r *MeasureUnitRepository) FindByCode(ctx context.Context, tenantID, code string) (*models.MeasureUnit, error) {
+	var m models.MeasureUnit
+	if err := r.col.FindOne(ctx, bson.M{"tenant_id": tenantID, "code": code}).Decode(&m); err != nil { return nil, err }
+	return &m, nil
+}
+
+func (r *MeasureUnitRepository) Create(ctx context.Context, m *models.MeasureUnit) (*models.MeasureUnit, error) {
+	now := time.Now().UTC()
+	m.CreatedAt, m.UpdatedAt = now, now
+	res, err := r.col.InsertOne(ctx, m)
+	if err != nil { return nil, err }
+	m.ID = res.InsertedID.(primitive.ObjectID)
+	return m, nil
+}
+
+func (r *MeasureUnitRepository) Update(ctx context.Context, id primitive.ObjectID, tenantID string, update bson.M) (*models.MeasureUnit, error) {
+	update["updated_at"] = time.Now().UTC()
+	if _, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{"$set": update}); err != nil { return nil, err }
+	return r.Get(ctx, id, tenantID)
+}
+
+func (r *MeasureUnitRepository) Delete(ctx context.Context, id primitive.ObjectID, tenantID string) error {
+	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
+	return err
+}
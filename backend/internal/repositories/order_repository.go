@@ -94,13 +94,19 @@ func (r *OrderRepository) Update(ctx context.Context, id primitive.ObjectID, ten
 	return r.Get(ctx, id, tenantID)
 }
 
+// UpdateIf updates the order only while it still matches expect, such as the status and receivings read before
+// approving or receiving it. It reports false otherwise.
+func (r *OrderRepository) UpdateIf(ctx context.Context, id primitive.ObjectID, tenantID string, expect bson.M, update bson.M) (bool, error) {
+	return updateIf(ctx, r.col, id, tenantID, expect, update)
+}
+
 func (r *OrderRepository) Delete(ctx context.Context, id primitive.ObjectID, tenantID string) error {
 	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
 	return err
 }
 
 func (r *OrderRepository) AddPayment(ctx context.Context, id primitive.ObjectID, tenantID string, p models.OrderPayment) (*models.Order, error) {
-	p.ID = primitive.NewObjectID()
+	if p.ID.IsZero() { p.ID = primitive.NewObjectID() }
 	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{
 		"$push": bson.M{"payments": p},
 		"$inc": bson.M{"total_paid_amount": p.Amount},
@@ -223,7 +229,7 @@ type SupplierProductRow struct {
 	Barcode      string  `bson:"barcode" json:"barcode"`
 	CategoryName string  `bson:"category_name" json:"category_name"`
 	Image        string  `bson:"image" json:"image"`
-	Quantity     int64   `bson:"quantity" json:"quantity"`
+	Quantity     float64 `bson:"quantity" json:"quantity"`
 	SupplyPrice  float64 `bson:"supply_price" json:"supply_price"`
 	RetailPrice  float64 `bson:"retail_price" json:"retail_price"`
 }
@@ -296,4 +302,56 @@ func (r *OrderRepository) ProductsBySupplier(ctx context.Context, tenantID, supp
 	var total int64
 	if len(out[0].Total) > 0 { total = out[0].Total[0].Count }
 	return out[0].Items, total, nil
-} 
\ No newline at end of file
+} 
+
+// OnOrder sums, per product, what the store's open supplier orders still expect: ordered less accepted and cancelled.
+func (r *OrderRepository) OnOrder(ctx context.Context, tenantID, shopID string) (map[primitive.ObjectID]float64, error) {
+	match := bson.M{"tenant_id": tenantID, "shop_id": shopID, "is_finished": false, "type": bson.M{"$in": bson.A{"supplier_order", ""}}, "status_id": bson.M{"$ne": "rejected"}}
+	left := bson.M{"$subtract": bson.A{"$items.quantity", bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$items.accepted_quantity", 0}}, bson.M{"$ifNull": bson.A{"$items.cancelled_quantity", 0}}}}}}
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$unwind", Value: "$items"}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": "$items.product_id", "qty": bson.M{"$sum": bson.M{"$max": bson.A{0, left}}}}}},
+	}
+	cur, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	out := map[primitive.ObjectID]float64{}
+	for cur.Next(ctx) {
+		var row struct {
+			ID  primitive.ObjectID `bson:"_id"`
+			Qty float64            `bson:"qty"`
+		}
+		if err := cur.Decode(&row); err != nil { return nil, err }
+		if row.Qty > 0 { out[row.ID] = models.RoundQty(row.Qty) }
+	}
+	return out, cur.Err()
+}
+
+// PendingReturns sums, per settlement type and product, what a supplier's open consignment return orders from a store
+// are still to send back.
+func (r *OrderRepository) PendingReturns(ctx context.Context, tenantID, supplierID, shopID string) (map[string]map[primitive.ObjectID]float64, error) {
+	match := bson.M{"tenant_id": tenantID, "supplier_id": supplierID, "shop_id": shopID, "is_finished": false, "type": "return_order", "settlement_type": bson.M{"$in": bson.A{models.OrderSettlementConsignment, models.OrderSettlementRealization}}}
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$unwind", Value: "$items"}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": bson.M{"settlement_type": "$settlement_type", "product_id": "$items.product_id"}, "qty": bson.M{"$sum": "$items.quantity"}}}},
+	}
+	cur, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	out := map[string]map[primitive.ObjectID]float64{}
+	for cur.Next(ctx) {
+		var row struct {
+			ID struct {
+				SettlementType string             `bson:"settlement_type"`
+				ProductID      primitive.ObjectID `bson:"product_id"`
+			} `bson:"_id"`
+			Qty float64 `bson:"qty"`
+		}
+		if err := cur.Decode(&row); err != nil { return nil, err }
+		if out[row.ID.SettlementType] == nil { out[row.ID.SettlementType] = map[primitive.ObjectID]float64{} }
+		out[row.ID.SettlementType][row.ID.ProductID] = models.RoundQty(row.Qty)
+	}
+	return out, cur.Err()
+}
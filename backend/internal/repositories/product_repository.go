@@ -243,7 +243,7 @@ func (r *ProductRepository) Delete(ctx context.Context, id primitive.ObjectID, t
 	return err
 }
 
-func (r *ProductRepository) UpdateStock(ctx context.Context, id primitive.ObjectID, tenantID string, stock int) error {
+func (r *ProductRepository) UpdateStock(ctx context.Context, id primitive.ObjectID, tenantID string, stock float64) error {
 	_, err := r.col.UpdateOne(
 		ctx,
 		bson.M{"_id": id, "tenant_id": tenantID},
@@ -252,6 +252,17 @@ func (r *ProductRepository) UpdateStock(ctx context.Context, id primitive.Object
 	return err
 }
 
+// IncStock shifts the cached stock total by delta without reading it first, so concurrent changes cannot overwrite
+// each other.
+func (r *ProductRepository) IncStock(ctx context.Context, id primitive.ObjectID, tenantID string, delta float64) error {
+	_, err := r.col.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "tenant_id": tenantID},
+		mongo.Pipeline{bson.D{{Key: "$set", Value: bson.M{"stock": roundQty(bson.M{"$add": bson.A{"$stock", delta}}), "updated_at": time.Now().UTC()}}}},
+	)
+	return err
+}
+
 func (r *ProductRepository) UpdatePrices(ctx context.Context, id primitive.ObjectID, tenantID string, supply float64, retail float64) error {
 	set := bson.M{"updated_at": time.Now().UTC()}
 	if supply >= 0 { set["cost_price"] = supply }
@@ -331,7 +342,7 @@ func (r *ProductRepository) Stats(ctx context.Context, tenantID string, storeID
 	return &out[0], nil
 } 
 
-type ProductSummary struct { Titles int64 `bson:"titles" json:"titles"`; Units int64 `bson:"units" json:"units"`; Supply float64 `bson:"supply" json:"supply"`; Retail float64 `bson:"retail" json:"retail"` }
+type ProductSummary struct { Titles int64 `bson:"titles" json:"titles"`; Units float64 `bson:"units" json:"units"`; Supply float64 `bson:"supply" json:"supply"`; Retail float64 `bson:"retail" json:"retail"` }
 
 func (r *ProductRepository) Summary(ctx context.Context, tenantID string, storeID string) (*ProductSummary, error) {
 	match := bson.M{"tenant_id": tenantID}
@@ -355,4 +366,44 @@ func (r *ProductRepository) Summary(ctx context.Context, tenantID string, storeI
 	if err := cur.All(ctx, &out); err != nil { return nil, err }
 	if len(out) == 0 { return &ProductSummary{}, nil }
 	return &out[0], nil
-} 
\ No newline at end of file
+} 
+// UsesUnit reports whether any product of the tenant counts in the unit, as its base unit or another one.
+func (r *ProductRepository) UsesUnit(ctx context.Context, tenantID, code string) (bool, error) {
+	n, err := r.col.CountDocuments(ctx, bson.M{"tenant_id": tenantID, "$or": bson.A{bson.M{"unit": code}, bson.M{"units.unit": code}}}, options.Count().SetLimit(1))
+	return n > 0, err
+}
+
+// ListReplenishable returns the tenant's active stocked products that have a min or max stock level or are among ids.
+func (r *ProductRepository) ListReplenishable(ctx context.Context, tenantID string, ids []primitive.ObjectID) ([]models.Product, error) {
+	filter := bson.M{
+		"tenant_id":    tenantID,
+		"archived":     bson.M{"$ne": true},
+		"status":       bson.M{"$ne": models.ProductStatusInactive},
+		"product_type": bson.M{"$nin": bson.A{models.ProductKindService, models.ProductKindSet}},
+		"$or":          bson.A{bson.M{"min_stock": bson.M{"$gt": 0}}, bson.M{"max_stock": bson.M{"$gt": 0}}, bson.M{"_id": bson.M{"$in": ids}}},
+	}
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	var items []models.Product
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
+
+// ListCountable returns the stock-keeping products among ids, optionally only those in the given categories.
+func (r *ProductRepository) ListCountable(ctx context.Context, tenantID string, ids []primitive.ObjectID, categoryIDs []primitive.ObjectID) ([]models.Product, error) {
+	filter := bson.M{
+		"tenant_id":    tenantID,
+		"_id":          bson.M{"$in": ids},
+		"product_type": bson.M{"$ne": models.ProductKindService},
+	}
+	if len(categoryIDs) > 0 {
+		filter["$or"] = bson.A{bson.M{"category_id": bson.M{"$in": categoryIDs}}, bson.M{"category_ids": bson.M{"$in": categoryIDs}}}
+	}
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	var items []models.Product
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
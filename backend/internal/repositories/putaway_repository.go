@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type PutawayListParams struct {
+	Page      int64
+	Limit     int64
+	Sort      bson.D
+	ShopID    string
+	OrderID   string
+	Status    string
+	TenantID  string
+}
+
+type PutawayRepository struct { col *mongo.Collection }
+
+func NewPutawayRepository(db *mongo.Database) *PutawayRepository { return &PutawayRepository{ col: db.Collection("putaways") } }
+
+func (r *PutawayRepository) List(ctx context.Context, p PutawayListParams) ([]models.Putaway, int64, error) {
+	if p.Page < 1 { p.Page = 1 }
+	if p.Limit < 1 || p.Limit > 200 { p.Limit = 20 }
+	if p.Sort == nil { p.Sort = bson.D{{Key: "created_at", Value: -1}} }
+
+	filter := bson.M{"tenant_id": p.TenantID}
+	if p.ShopID != "" { filter["shop_id"] = p.ShopID }
+	if p.OrderID != "" { filter["order_id"] = p.OrderID }
+	if p.Status != "" { filter["status"] = p.Status }
+
+	opts := options.Find().SetSkip((p.Page-1)*p.Limit).SetLimit(p.Limit).SetSort(p.Sort)
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil { return nil, 0, err }
+	defer cur.Close(ctx)
+
+	var items []models.Putaway
+	if err := cur.All(ctx, &items); err != nil { return nil, 0, err }
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil { return nil, 0, err }
+	return items, total, nil
+}
+
+func (r *PutawayRepository) Get(ctx context.Context, id primitive.ObjectID, tenantID string) (*models.Putaway, error) {
+	var m models.Putaway
+	if err := r.col.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}).Decode(&m); err != nil { return nil, err }
+	return &m, nil
+}
+
+func (r *PutawayRepository) Create(ctx context.Context, m *models.Putaway) (*models.Putaway, error) {
+	now := time.Now().UTC()
+	m.CreatedAt = now
+	m.UpdatedAt = now
+	if m.Status == "" { m.Status = "NEW" }
+	res, err := r.col.InsertOne(ctx, m)
+	if err != nil { return nil, err }
+	m.ID = res.InsertedID.(primitive.ObjectID)
+	return m, nil
+}
+
+func (r *PutawayRepository) Update(ctx context.Context, id primitive.ObjectID, tenantID string, update bson.M) (*models.Putaway, error) {
+	if update == nil { update = bson.M{} }
+	update["updated_at"] = time.Now().UTC()
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{"$set": update})
+	if err != nil { return nil, err }
+	return r.Get(ctx, id, tenantID)
+}
+
+// UpdateIf updates the putaway only while it still matches expect, e.g. the status the caller read. It reports false
+// otherwise.
+func (r *PutawayRepository) UpdateIf(ctx context.Context, id primitive.ObjectID, tenantID string, expect bson.M, update bson.M) (bool, error) {
+	return updateIf(ctx, r.col, id, tenantID, expect, update)
+}
+
+func (r *PutawayRepository) Delete(ctx context.Context, id primitive.ObjectID, tenantID string) error {
+	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
+	return err
+}
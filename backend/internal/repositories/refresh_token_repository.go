@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type RefreshTokenRepository struct { col *mongo.Collection }
+
+func NewRefreshTokenRepository(db *mongo.Database) *RefreshTokenRepository { return &RefreshTokenRepository{col: db.Collection("refresh_tokens")} }
+
+func (r *RefreshTokenRepository) Create(ctx context.Context, m *models.RefreshToken) (*models.RefreshToken, error) {
+	m.CreatedAt = time.Now().UTC()
+	res, err := r.col.InsertOne(ctx, m)
+	if err != nil { return nil, err }
+	m.ID = res.InsertedID.(primitive.ObjectID)
+	return m, nil
+}
+
+func (r *RefreshTokenRepository) GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	var m models.RefreshToken
+	if err := r.col.FindOne(ctx, bson.M{"token_hash": hash}).Decode(&m); err != nil { return nil, err }
+	return &m, nil
+}
+
+// Rotate revokes an active token and links it to its replacement. It reports false when the token was already revoked,
+// which lets two concurrent refreshes with the same token be told apart from a legitimate rotation.
+func (r *RefreshTokenRepository) Rotate(ctx context.Context, id, replacedBy primitive.ObjectID) (bool, error) {
+	now := time.Now().UTC()
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"revoked_at": now, "replaced_by": replacedBy}})
+	if err != nil { return false, err }
+	return res.ModifiedCount == 1, nil
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyID primitive.ObjectID) error {
+	_, err := r.col.UpdateMany(ctx, bson.M{"family_id": familyID, "revoked_at": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"revoked_at": time.Now().UTC()}})
+	return err
+}
+
+func (r *RefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.col.UpdateMany(ctx, bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"revoked_at": time.Now().UTC()}})
+	return err
+}
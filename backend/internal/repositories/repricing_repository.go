@@ -83,6 +83,11 @@ func (r *RepricingRepository) Update(ctx context.Context, id primitive.ObjectID,
 	return r.Get(ctx, id, tenantID)
 }
 
+// UpdateIf updates the repricing only while it still matches expect. It reports false otherwise.
+func (r *RepricingRepository) UpdateIf(ctx context.Context, id primitive.ObjectID, tenantID string, expect bson.M, update bson.M) (bool, error) {
+	return updateIf(ctx, r.col, id, tenantID, expect, update)
+}
+
 func (r *RepricingRepository) Delete(ctx context.Context, id primitive.ObjectID, tenantID string) error {
 	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
 	return err
@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ReservationListParams struct {
+	TenantID   string
+	ProductID  *primitive.ObjectID
+	ShopID     string
+	SourceType string
+	SourceID   string
+	Page       int64
+	Limit      int64
+}
+
+// ReservationRepository keeps stock reservations. Lapsed reservations are left out of every read and removed by the
+// TTL index on expires_at.
+type ReservationRepository struct { col *mongo.Collection }
+
+func NewReservationRepository(db *mongo.Database) *ReservationRepository { return &ReservationRepository{col: db.Collection("stock_reservations")} }
+
+func activeReservations(tenantID string) bson.M {
+	return bson.M{"tenant_id": tenantID, "expires_at": bson.M{"$gt": time.Now().UTC()}}
+}
+
+func (r *ReservationRepository) List(ctx context.Context, p ReservationListParams) ([]models.StockReservation, int64, error) {
+	if p.Page < 1 { p.Page = 1 }
+	if p.Limit < 1 || p.Limit > 200 { p.Limit = 20 }
+	filter := activeReservations(p.TenantID)
+	if p.ProductID != nil { filter["product_id"] = *p.ProductID }
+	if p.ShopID != "" { filter["shop_id"] = p.ShopID }
+	if p.SourceType != "" { filter["source_type"] = p.SourceType }
+	if p.SourceID != "" { filter["source_id"] = p.SourceID }
+	opts := options.Find().SetSkip((p.Page-1)*p.Limit).SetLimit(p.Limit).SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil { return nil, 0, err }
+	defer cur.Close(ctx)
+	var items []models.StockReservation
+	if err := cur.All(ctx, &items); err != nil { return nil, 0, err }
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil { return nil, 0, err }
+	return items, total, nil
+}
+
+func (r *ReservationRepository) Get(ctx context.Context, id primitive.ObjectID, tenantID string) (*models.StockReservation, error) {
+	var m models.StockReservation
+	if err := r.col.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}).Decode(&m); err != nil { return nil, err }
+	return &m, nil
+}
+
+// Replace stores items as a document's reservations and then drops the ones they replace. The new ones go in first,
+// so at no point does the document hold less than either set; a concurrent read sees the old set, the new set or
+// both, never neither.
+func (r *ReservationRepository) Replace(ctx context.Context, tenantID, sourceType, sourceID string, items []models.StockReservation) error {
+	ids := make(bson.A, len(items))
+	docs := make([]interface{}, len(items))
+	for i := range items {
+		if items[i].ID.IsZero() { items[i].ID = primitive.NewObjectID() }
+		ids[i], docs[i] = items[i].ID, items[i]
+	}
+	if len(docs) > 0 {
+		if _, err := r.col.InsertMany(ctx, docs); err != nil { return err }
+	}
+	_, err := r.col.DeleteMany(ctx, bson.M{"tenant_id": tenantID, "source_type": sourceType, "source_id": sourceID, "_id": bson.M{"$nin": ids}})
+	return err
+}
+
+// Release drops every reservation of a document.
+func (r *ReservationRepository) Release(ctx context.Context, tenantID, sourceType, sourceID string) error {
+	_, err := r.col.DeleteMany(ctx, bson.M{"tenant_id": tenantID, "source_type": sourceType, "source_id": sourceID})
+	return err
+}
+
+func (r *ReservationRepository) Delete(ctx context.Context, id primitive.ObjectID, tenantID string) error {
+	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
+	return err
+}
+
+// Reserved sums the live reservations of a product in a store, leaving out those of the given document.
+func (r *ReservationRepository) Reserved(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID, exceptType, exceptID string) (float64, error) {
+	match := activeReservations(tenantID)
+	match["product_id"], match["shop_id"] = productID, shopID
+	if exceptType != "" { match["$nor"] = bson.A{bson.M{"source_type": exceptType, "source_id": exceptID}} }
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": nil, "qty": bson.M{"$sum": "$qty"}}}},
+	}
+	cur, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil { return 0, err }
+	defer cur.Close(ctx)
+	var row struct{ Qty float64 `bson:"qty"` }
+	if cur.Next(ctx) { if err := cur.Decode(&row); err != nil { return 0, err } }
+	return models.RoundQty(row.Qty), cur.Err()
+}
+
+// ByProducts sums the live reservations of the given products per product and store.
+func (r *ReservationRepository) ByProducts(ctx context.Context, tenantID string, productIDs []primitive.ObjectID) (map[primitive.ObjectID]map[string]float64, error) {
+	out := map[primitive.ObjectID]map[string]float64{}
+	if len(productIDs) == 0 { return out, nil }
+	match := activeReservations(tenantID)
+	match["product_id"] = bson.M{"$in": productIDs}
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": bson.M{"product_id": "$product_id", "shop_id": "$shop_id"}, "qty": bson.M{"$sum": "$qty"}}}},
+	}
+	cur, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		var row struct {
+			ID struct {
+				ProductID primitive.ObjectID `bson:"product_id"`
+				ShopID    string             `bson:"shop_id"`
+			} `bson:"_id"`
+			Qty float64 `bson:"qty"`
+		}
+		if err := cur.Decode(&row); err != nil { return nil, err }
+		if out[row.ID.ProductID] == nil { out[row.ID.ProductID] = map[string]float64{} }
+		out[row.ID.ProductID][row.ID.ShopID] = models.RoundQty(row.Qty)
+	}
+	return out, cur.Err()
+}
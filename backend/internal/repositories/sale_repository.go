@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SaleListParams struct {
+	TenantID      string
+	Page          int64
+	Limit         int64
+	Search        string
+	Status        string
+	CustomerID    string
+	ShopID        string
+	CreatedBy     string
+	PaymentStatus string
+	Type          string
+	SortBy        string
+	SortOrder     int
+	DateFrom      *time.Time
+	DateTo        *time.Time
+}
+
+type SaleRepository struct {
+	col      *mongo.Collection
+	counters *mongo.Collection
+}
+
+func NewSaleRepository(db *mongo.Database) *SaleRepository {
+	return &SaleRepository{ col: db.Collection("sales"), counters: db.Collection("counters") }
+}
+
+func (r *SaleRepository) List(ctx context.Context, p SaleListParams) ([]models.Sale, int64, error) {
+	if p.Page < 1 { p.Page = 1 }
+	if p.Limit < 1 || p.Limit > 200 { p.Limit = 20 }
+	filter := bson.M{"tenant_id": p.TenantID}
+	if p.Search != "" {
+		filter["$or"] = []bson.M{
+			{"customer.name": bson.M{"$regex": p.Search, "$options": "i"}},
+			{"customer.phone": bson.M{"$regex": p.Search, "$options": "i"}},
+			{"items.product_name": bson.M{"$regex": p.Search, "$options": "i"}},
+			{"items.product_sku": bson.M{"$regex": p.Search, "$options": "i"}},
+			{"items.barcode": p.Search},
+		}
+	}
+	if p.Status != "" { filter["status"] = p.Status }
+	if p.CustomerID != "" { filter["customer_id"] = p.CustomerID }
+	if p.ShopID != "" { filter["shop_id"] = p.ShopID }
+	if p.CreatedBy != "" { filter["created_by.id"] = p.CreatedBy }
+	if p.PaymentStatus != "" && p.PaymentStatus != "all" { filter["payment_status"] = p.PaymentStatus }
+	if p.Type != "" { filter["type"] = p.Type }
+	if p.DateFrom != nil || p.DateTo != nil {
+		dt := bson.M{}
+		if p.DateFrom != nil { dt["$gte"] = *p.DateFrom }
+		if p.DateTo != nil { dt["$lte"] = *p.DateTo }
+		filter["created_at"] = dt
+	}
+	sortKey := "created_at"
+	if p.SortBy != "" { sortKey = p.SortBy }
+	order := -1
+	if p.SortOrder != 0 { order = p.SortOrder }
+	opts := options.Find().SetSkip((p.Page-1)*p.Limit).SetLimit(p.Limit).SetSort(bson.D{{Key: sortKey, Value: order}})
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil { return nil, 0, err }
+	defer cur.Close(ctx)
+	var items []models.Sale
+	if err := cur.All(ctx, &items); err != nil { return nil, 0, err }
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil { return nil, 0, err }
+	return items, total, nil
+}
+
+func (r *SaleRepository) Get(ctx context.Context, id primitive.ObjectID, tenantID string) (*models.Sale, error) {
+	var m models.Sale
+	if err := r.col.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}).Decode(&m); err != nil { return nil, err }
+	return &m, nil
+}
+
+func (r *SaleRepository) Create(ctx context.Context, m *models.Sale) (*models.Sale, error) {
+	now := time.Now().UTC()
+	m.CreatedAt = now
+	m.UpdatedAt = now
+	if m.Items == nil { m.Items = []models.SaleItem{} }
+	if m.Payments == nil { m.Payments = []models.SalePayment{} }
+	res, err := r.col.InsertOne(ctx, m)
+	if err != nil { return nil, err }
+	m.ID = res.InsertedID.(primitive.ObjectID)
+	return m, nil
+}
+
+func (r *SaleRepository) Update(ctx context.Context, id primitive.ObjectID, tenantID string, update bson.M) (*models.Sale, error) {
+	if update == nil { update = bson.M{} }
+	update["updated_at"] = time.Now().UTC()
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{"$set": update})
+	if err != nil { return nil, err }
+	return r.Get(ctx, id, tenantID)
+}
+
+// UpdateIf updates the sale only while it still matches expect, e.g. is still NEW. It reports false otherwise.
+func (r *SaleRepository) UpdateIf(ctx context.Context, id primitive.ObjectID, tenantID string, expect bson.M, update bson.M) (bool, error) {
+	return updateIf(ctx, r.col, id, tenantID, expect, update)
+}
+
+func (r *SaleRepository) Delete(ctx context.Context, id primitive.ObjectID, tenantID string) error {
+	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
+	return err
+}
+
+// NextReceiptNumber hands out the tenant's receipt numbers in sequence, starting at 1.
+func (r *SaleRepository) NextReceiptNumber(ctx context.Context, tenantID string) (int64, error) {
+	return nextSequence(ctx, r.counters, "sale_receipt:"+tenantID)
+}
+
+// ListByShift returns the completed sales of a cash shift.
+func (r *SaleRepository) ListByShift(ctx context.Context, tenantID string, shiftID string) ([]models.Sale, error) {
+	cur, err := r.col.Find(ctx, bson.M{"tenant_id": tenantID, "shift_id": shiftID, "status": "COMPLETED"})
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	var items []models.Sale
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
+
+// WithCoresOutstanding returns the completed sales whose core charges still wait for cores, optionally of one
+// customer, oldest first.
+func (r *SaleRepository) WithCoresOutstanding(ctx context.Context, tenantID string, customerID string) ([]models.Sale, error) {
+	filter := bson.M{"tenant_id": tenantID, "status": "COMPLETED", "cores_outstanding": bson.M{"$gt": 0}}
+	if customerID != "" { filter["customer_id"] = customerID }
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "completed_at", Value: 1}}))
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	items := []models.Sale{}
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
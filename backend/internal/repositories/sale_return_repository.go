@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SaleReturnListParams struct {
+	TenantID string
+	Page     int64
+	Limit    int64
+	SaleID   string
+	ShopID   string
+	ShiftID  string
+	Reason   string
+	DateFrom *time.Time
+	DateTo   *time.Time
+}
+
+type SaleReturnRepository struct { col *mongo.Collection }
+
+func NewSaleReturnRepository(db *mongo.Database) *SaleReturnRepository { return &SaleReturnRepository{ col: db.Collection("sale_returns") } }
+
+func (r *SaleReturnRepository) List(ctx context.Context, p SaleReturnListParams) ([]models.SaleReturn, int64, error) {
+	if p.Page < 1 { p.Page = 1 }
+	if p.Limit < 1 || p.Limit > 200 { p.Limit = 20 }
+	filter := bson.M{"tenant_id": p.TenantID}
+	if p.SaleID != "" { filter["sale_id"] = p.SaleID }
+	if p.ShopID != "" { filter["shop_id"] = p.ShopID }
+	if p.ShiftID != "" { filter["shift_id"] = p.ShiftID }
+	if p.Reason != "" { filter["reason"] = p.Reason }
+	if p.DateFrom != nil || p.DateTo != nil {
+		dt := bson.M{}
+		if p.DateFrom != nil { dt["$gte"] = *p.DateFrom }
+		if p.DateTo != nil { dt["$lte"] = *p.DateTo }
+		filter["created_at"] = dt
+	}
+	opts := options.Find().SetSkip((p.Page-1)*p.Limit).SetLimit(p.Limit).SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil { return nil, 0, err }
+	defer cur.Close(ctx)
+	var items []models.SaleReturn
+	if err := cur.All(ctx, &items); err != nil { return nil, 0, err }
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil { return nil, 0, err }
+	return items, total, nil
+}
+
+func (r *SaleReturnRepository) Get(ctx context.Context, id primitive.ObjectID, tenantID string) (*models.SaleReturn, error) {
+	var m models.SaleReturn
+	if err := r.col.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}).Decode(&m); err != nil { return nil, err }
+	return &m, nil
+}
+
+func (r *SaleReturnRepository) Create(ctx context.Context, m *models.SaleReturn) (*models.SaleReturn, error) {
+	m.CreatedAt = time.Now().UTC()
+	if m.Items == nil { m.Items = []models.SaleReturnItem{} }
+	if m.Refunds == nil { m.Refunds = []models.SalePayment{} }
+	res, err := r.col.InsertOne(ctx, m)
+	if err != nil { return nil, err }
+	m.ID = res.InsertedID.(primitive.ObjectID)
+	return m, nil
+}
+
+// ListBySale returns the returns made against a sale, oldest first.
+func (r *SaleReturnRepository) ListBySale(ctx context.Context, tenantID string, saleID string) ([]models.SaleReturn, error) {
+	return r.find(ctx, bson.M{"tenant_id": tenantID, "sale_id": saleID})
+}
+
+// ListByShift returns the returns refunded in a cash shift.
+func (r *SaleReturnRepository) ListByShift(ctx context.Context, tenantID string, shiftID string) ([]models.SaleReturn, error) {
+	return r.find(ctx, bson.M{"tenant_id": tenantID, "shift_id": shiftID})
+}
+
+func (r *SaleReturnRepository) find(ctx context.Context, filter bson.M) ([]models.SaleReturn, error) {
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	items := []models.SaleReturn{}
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
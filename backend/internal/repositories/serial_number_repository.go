@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SerialNumberListParams struct {
+	TenantID  string
+	ProductID primitive.ObjectID
+	ShopID    string
+	Status    string
+	Page      int64
+	Limit     int64
+}
+
+type SerialNumberRepository struct { col *mongo.Collection }
+
+func NewSerialNumberRepository(db *mongo.Database) *SerialNumberRepository { return &SerialNumberRepository{col: db.Collection("serial_numbers")} }
+
+// Enter brings a serial into a store. The serial must currently be in one of the from states; with allowNew it may
+// also not exist yet. ok is false when the serial is elsewhere.
+func (r *SerialNumberRepository) Enter(ctx context.Context, p *models.Product, serial string, from []string, allowNew bool, ev models.SerialEvent) (bool, error) {
+	filter := bson.M{"tenant_id": p.TenantID, "product_id": p.ID, "serial": serial, "status": bson.M{"$in": from}}
+	update := bson.M{
+		"$set":  bson.M{"status": ev.Status, "shop_id": ev.ShopID, "party": "", "updated_at": ev.At},
+		"$push": bson.M{"history": ev},
+	}
+	opts := options.Update()
+	if allowNew {
+		update["$setOnInsert"] = bson.M{"product_name": p.Name, "product_sku": p.SKU, "created_at": ev.At}
+		opts.SetUpsert(true)
+	}
+	res, err := r.col.UpdateOne(ctx, filter, update, opts)
+	// the upsert collides with the unique key when the serial exists in another state
+	if mongo.IsDuplicateKeyError(err) { return false, nil }
+	if err != nil { return false, err }
+	return res.MatchedCount+res.UpsertedCount > 0, nil
+}
+
+// Leave moves a serial that is in stock in the given store to the event's state. ok is false when it is not there.
+func (r *SerialNumberRepository) Leave(ctx context.Context, p *models.Product, serial, shopID string, ev models.SerialEvent) (bool, error) {
+	filter := bson.M{"tenant_id": p.TenantID, "product_id": p.ID, "serial": serial, "status": models.SerialInStock, "shop_id": shopID}
+	update := bson.M{
+		"$set":  bson.M{"status": ev.Status, "party": ev.Party, "updated_at": ev.At},
+		"$push": bson.M{"history": ev},
+	}
+	res, err := r.col.UpdateOne(ctx, filter, update)
+	if err != nil { return false, err }
+	return res.MatchedCount > 0, nil
+}
+
+// Move shifts a serial from one state to another without it entering or leaving stock. ok is false when the serial
+// is not in the from state.
+func (r *SerialNumberRepository) Move(ctx context.Context, tenantID string, productID primitive.ObjectID, serial, from string, ev models.SerialEvent) (bool, error) {
+	filter := bson.M{"tenant_id": tenantID, "product_id": productID, "serial": serial, "status": from}
+	update := bson.M{
+		"$set":  bson.M{"status": ev.Status, "updated_at": time.Now().UTC()},
+		"$push": bson.M{"history": ev},
+	}
+	res, err := r.col.UpdateOne(ctx, filter, update)
+	if err != nil { return false, err }
+	return res.MatchedCount > 0, nil
+}
+
+// FindBySerial returns every product's unit carrying the serial.
+func (r *SerialNumberRepository) FindBySerial(ctx context.Context, tenantID, serial string) ([]models.SerialNumber, error) {
+	cur, err := r.col.Find(ctx, bson.M{"tenant_id": tenantID, "serial": serial})
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	var items []models.SerialNumber
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
+
+func (r *SerialNumberRepository) List(ctx context.Context, p SerialNumberListParams) ([]models.SerialNumber, int64, error) {
+	if p.Page < 1 { p.Page = 1 }
+	if p.Limit < 1 || p.Limit > 200 { p.Limit = 50 }
+	filter := bson.M{"tenant_id": p.TenantID, "product_id": p.ProductID}
+	if p.ShopID != "" { filter["shop_id"] = p.ShopID }
+	if p.Status != "" { filter["status"] = p.Status }
+	// the history can be long; the list only shows where each unit is
+	opts := options.Find().SetSkip((p.Page-1)*p.Limit).SetLimit(p.Limit).SetSort(bson.D{{Key: "serial", Value: 1}}).SetProjection(bson.M{"history": 0})
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil { return nil, 0, err }
+	defer cur.Close(ctx)
+	var items []models.SerialNumber
+	if err := cur.All(ctx, &items); err != nil { return nil, 0, err }
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil { return nil, 0, err }
+	return items, total, nil
+}
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type StockLotListParams struct {
+	TenantID  string
+	ProductID primitive.ObjectID
+	ShopID    string
+	OnlyOpen  bool
+	Page      int64
+	Limit     int64
+}
+
+type StockLotRepository struct { col *mongo.Collection }
+
+func NewStockLotRepository(db *mongo.Database) *StockLotRepository { return &StockLotRepository{col: db.Collection("stock_lots")} }
+
+func (r *StockLotRepository) Get(ctx context.Context, id primitive.ObjectID, tenantID string) (*models.StockLot, error) {
+	var m models.StockLot
+	if err := r.col.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}).Decode(&m); err != nil { return nil, err }
+	return &m, nil
+}
+
+// Receive adds qty to the lot of a product and store with the given number, expiry and order, creating it when missing.
+func (r *StockLotRepository) Receive(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, ref models.StockLotRef, qty float64) (*models.StockLot, error) {
+	now := time.Now().UTC()
+	filter := bson.M{"tenant_id": tenantID, "product_id": productID, "shop_id": shopID, "number": ref.Number, "expiration_date": ref.ExpirationDate, "order_id": ref.OrderID}
+	update := mongo.Pipeline{bson.D{{Key: "$set", Value: bson.M{
+		"qty":         roundQty(bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$qty", 0}}, qty}}),
+		"received":    roundQty(bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$received", 0}}, qty}}),
+		"updated_at":  now,
+		"order_name":  bson.M{"$ifNull": bson.A{"$order_name", ref.OrderName}},
+		"supplier_id": bson.M{"$ifNull": bson.A{"$supplier_id", ref.SupplierID}},
+		"created_at":  bson.M{"$ifNull": bson.A{"$created_at", now}},
+	}}}}
+	var m models.StockLot
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	if err := r.col.FindOneAndUpdate(ctx, filter, update, opts).Decode(&m); err != nil { return nil, err }
+	return &m, nil
+}
+
+// Inc shifts a lot's quantity by delta. A decrease only applies when the lot holds enough; ok is false otherwise.
+func (r *StockLotRepository) Inc(ctx context.Context, id primitive.ObjectID, tenantID string, delta float64) (*models.StockLot, bool, error) {
+	filter := bson.M{"_id": id, "tenant_id": tenantID}
+	set := bson.M{"qty": roundQty(bson.M{"$add": bson.A{"$qty", delta}}), "updated_at": time.Now().UTC()}
+	if delta < 0 { filter["qty"] = bson.M{"$gte": -delta} } else { set["received"] = roundQty(bson.M{"$add": bson.A{"$received", delta}}) }
+	var m models.StockLot
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.col.FindOneAndUpdate(ctx, filter, mongo.Pipeline{bson.D{{Key: "$set", Value: set}}}, opts).Decode(&m)
+	if err == mongo.ErrNoDocuments { return nil, false, nil }
+	if err != nil { return nil, false, err }
+	return &m, true, nil
+}
+
+// Open returns the lots of a product in a store that still hold stock, earliest expiry first, expired ones
+// included; lots without an expiry date come last, oldest first.
+func (r *StockLotRepository) Open(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string) ([]models.StockLot, error) {
+	filter := bson.M{"tenant_id": tenantID, "product_id": productID, "shop_id": shopID, "qty": bson.M{"$gt": 0}}
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil { return nil, err }
+	var items []models.StockLot
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].ExpirationDate, items[j].ExpirationDate
+		if a == nil || b == nil { return a != nil && b == nil }
+		return a.Before(*b)
+	})
+	return items, nil
+}
+
+func (r *StockLotRepository) List(ctx context.Context, p StockLotListParams) ([]models.StockLot, int64, error) {
+	if p.Page < 1 { p.Page = 1 }
+	if p.Limit < 1 || p.Limit > 200 { p.Limit = 50 }
+	filter := bson.M{"tenant_id": p.TenantID, "product_id": p.ProductID}
+	if p.ShopID != "" { filter["shop_id"] = p.ShopID }
+	if p.OnlyOpen { filter["qty"] = bson.M{"$gt": 0} }
+	opts := options.Find().SetSkip((p.Page-1)*p.Limit).SetLimit(p.Limit).SetSort(bson.D{{Key: "expiration_date", Value: 1}, {Key: "created_at", Value: 1}})
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil { return nil, 0, err }
+	defer cur.Close(ctx)
+	var items []models.StockLot
+	if err := cur.All(ctx, &items); err != nil { return nil, 0, err }
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil { return nil, 0, err }
+	return items, total, nil
+}
+
+// Expiring lists the lots with stock left that expire at or before the given time, soonest first. shopID may be
+// empty for all stores.
+func (r *StockLotRepository) Expiring(ctx context.Context, tenantID, shopID string, before time.Time) ([]models.StockLot, error) {
+	filter := bson.M{"tenant_id": tenantID, "qty": bson.M{"$gt": 0}, "expiration_date": bson.M{"$ne": nil, "$lte": before}}
+	if shopID != "" { filter["shop_id"] = shopID }
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "shop_id", Value: 1}, {Key: "expiration_date", Value: 1}}))
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	var items []models.StockLot
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type StockMovementListParams struct {
+	TenantID   string
+	ProductID  primitive.ObjectID
+	ShopID     string
+	SourceType string
+	DateFrom   *time.Time
+	DateTo     *time.Time
+	Page       int64
+	Limit      int64
+}
+
+// StockMovementRepository is append-only: movements are never updated or deleted.
+type StockMovementRepository struct { col *mongo.Collection }
+
+func NewStockMovementRepository(db *mongo.Database) *StockMovementRepository { return &StockMovementRepository{col: db.Collection("stock_movements")} }
+
+func (r *StockMovementRepository) Create(ctx context.Context, m *models.StockMovement) (*models.StockMovement, error) {
+	if m.CreatedAt.IsZero() { m.CreatedAt = time.Now().UTC() }
+	res, err := r.col.InsertOne(ctx, m)
+	if err != nil { return nil, err }
+	m.ID = res.InsertedID.(primitive.ObjectID)
+	return m, nil
+}
+
+func (r *StockMovementRepository) List(ctx context.Context, p StockMovementListParams) ([]models.StockMovement, int64, error) {
+	if p.Page < 1 { p.Page = 1 }
+	if p.Limit < 1 || p.Limit > 200 { p.Limit = 20 }
+	filter := bson.M{"tenant_id": p.TenantID, "product_id": p.ProductID}
+	if p.ShopID != "" { filter["shop_id"] = p.ShopID }
+	if p.SourceType != "" { filter["source_type"] = p.SourceType }
+	if p.DateFrom != nil || p.DateTo != nil {
+		d := bson.M{}
+		if p.DateFrom != nil { d["$gte"] = *p.DateFrom }
+		if p.DateTo != nil { d["$lte"] = *p.DateTo }
+		filter["created_at"] = d
+	}
+	opts := options.Find().SetSkip((p.Page-1)*p.Limit).SetLimit(p.Limit).SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil { return nil, 0, err }
+	defer cur.Close(ctx)
+	var items []models.StockMovement
+	if err := cur.All(ctx, &items); err != nil { return nil, 0, err }
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil { return nil, 0, err }
+	return items, total, nil
+}
+
+// ShopSum is the ledger quantity of one product in one store.
+type ShopSum struct {
+	ProductID primitive.ObjectID `bson:"product_id"`
+	ShopID    string             `bson:"shop_id"`
+	Qty       float64            `bson:"qty"`
+}
+
+// SumByShop adds up movements per product and store. productID may be nil for all products; asOf limits the sum to
+// movements recorded at or before that time.
+func (r *StockMovementRepository) SumByShop(ctx context.Context, tenantID string, productID *primitive.ObjectID, asOf *time.Time) ([]ShopSum, error) {
+	match := bson.M{"tenant_id": tenantID}
+	if productID != nil { match["product_id"] = *productID }
+	if asOf != nil { match["created_at"] = bson.M{"$lte": *asOf} }
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": bson.M{"product_id": "$product_id", "shop_id": "$shop_id"}, "qty": bson.M{"$sum": "$delta"}}}},
+		bson.D{{Key: "$project", Value: bson.M{"_id": 0, "product_id": "$_id.product_id", "shop_id": "$_id.shop_id", "qty": 1}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "shop_id", Value: 1}}}},
+	}
+	cur, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	var out []ShopSum
+	if err := cur.All(ctx, &out); err != nil { return nil, err }
+	return out, nil
+}
+
+// ValuationRow is the last state of one product in one store at a point in time. BalanceValue is nil for
+// movements recorded before stock was valued.
+type ValuationRow struct {
+	ProductID    primitive.ObjectID `bson:"product_id"`
+	ShopID       string             `bson:"shop_id"`
+	Balance      float64            `bson:"balance"`
+	UnitCost     float64            `bson:"unit_cost"`
+	BalanceValue *float64           `bson:"balance_value"`
+}
+
+// LastByShop returns, per product and store, the balance and stock value left by the last movement at or before
+// asOf. shopID may be empty for all stores; stores with nothing on hand are skipped.
+func (r *StockMovementRepository) LastByShop(ctx context.Context, tenantID, shopID string, asOf time.Time) ([]ValuationRow, error) {
+	match := bson.M{"tenant_id": tenantID, "created_at": bson.M{"$lte": asOf}}
+	if shopID != "" { match["shop_id"] = shopID }
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":           bson.M{"product_id": "$product_id", "shop_id": "$shop_id"},
+			"balance":       bson.M{"$last": "$balance"},
+			"unit_cost":     bson.M{"$last": "$unit_cost"},
+			"balance_value": bson.M{"$last": "$balance_value"},
+		}}},
+		bson.D{{Key: "$match", Value: bson.M{"balance": bson.M{"$ne": 0}}}},
+		bson.D{{Key: "$project", Value: bson.M{"_id": 0, "product_id": "$_id.product_id", "shop_id": "$_id.shop_id", "balance": 1, "unit_cost": 1, "balance_value": 1}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "shop_id", Value: 1}, {Key: "product_id", Value: 1}}}},
+	}
+	cur, err := r.col.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	var out []ValuationRow
+	if err := cur.All(ctx, &out); err != nil { return nil, err }
+	return out, nil
+}
+
+// SumBySource adds up, per product, a store's movements of the given sources recorded since the given time.
+func (r *StockMovementRepository) SumBySource(ctx context.Context, tenantID, shopID string, sourceTypes []string, since time.Time) (map[primitive.ObjectID]float64, error) {
+	match := bson.M{"tenant_id": tenantID, "shop_id": shopID, "source_type": bson.M{"$in": sourceTypes}, "created_at": bson.M{"$gte": since}}
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": "$product_id", "qty": bson.M{"$sum": "$delta"}}}},
+	}
+	cur, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	out := map[primitive.ObjectID]float64{}
+	for cur.Next(ctx) {
+		var row struct {
+			ID  primitive.ObjectID `bson:"_id"`
+			Qty float64            `bson:"qty"`
+		}
+		if err := cur.Decode(&row); err != nil { return nil, err }
+		out[row.ID] = models.RoundQty(row.Qty)
+	}
+	return out, cur.Err()
+}
+
+// CostBySource adds up, per product, the cost of a store's movements of the given sources recorded since the given
+// time; units going out count negative.
+func (r *StockMovementRepository) CostBySource(ctx context.Context, tenantID, shopID string, sourceTypes []string, since time.Time) (map[primitive.ObjectID]float64, error) {
+	match := bson.M{"tenant_id": tenantID, "shop_id": shopID, "source_type": bson.M{"$in": sourceTypes}, "created_at": bson.M{"$gte": since}}
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": "$product_id", "amount": bson.M{"$sum": "$cost_amount"}}}},
+	}
+	cur, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	out := map[primitive.ObjectID]float64{}
+	for cur.Next(ctx) {
+		var row struct {
+			ID     primitive.ObjectID `bson:"_id"`
+			Amount float64            `bson:"amount"`
+		}
+		if err := cur.Decode(&row); err != nil { return nil, err }
+		out[row.ID] = row.Amount
+	}
+	return out, cur.Err()
+}
@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type StockRepository struct { col *mongo.Collection }
+
+func NewStockRepository(db *mongo.Database) *StockRepository { return &StockRepository{col: db.Collection("stock_balances")} }
+
+func balanceKey(tenantID string, productID primitive.ObjectID, shopID string) bson.M {
+	return bson.M{"tenant_id": tenantID, "product_id": productID, "shop_id": shopID}
+}
+
+// roundQty is the update pipeline expression rounding a quantity to models.QtyDecimals.
+func roundQty(expr interface{}) bson.M { return bson.M{"$round": bson.A{expr, models.QtyDecimals}} }
+
+// Get returns the quantity of a product in a store; a missing balance is zero.
+func (r *StockRepository) Get(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string) (float64, error) {
+	var m models.StockBalance
+	err := r.col.FindOne(ctx, balanceKey(tenantID, productID, shopID)).Decode(&m)
+	if err == mongo.ErrNoDocuments { return 0, nil }
+	if err != nil { return 0, err }
+	return m.Qty, nil
+}
+
+// GetBalance returns the balance document of a product in a store; a missing balance is returned empty.
+func (r *StockRepository) GetBalance(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string) (*models.StockBalance, error) {
+	var m models.StockBalance
+	err := r.col.FindOne(ctx, balanceKey(tenantID, productID, shopID)).Decode(&m)
+	if err == mongo.ErrNoDocuments { return &models.StockBalance{TenantID: tenantID, ProductID: productID, ShopID: shopID}, nil }
+	if err != nil { return nil, err }
+	return &m, nil
+}
+
+// SetCost stores the average unit cost and the stock value of a balance.
+func (r *StockRepository) SetCost(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, avgCost, value float64) error {
+	_, err := r.col.UpdateOne(ctx, balanceKey(tenantID, productID, shopID), bson.M{"$set": bson.M{"avg_cost": avgCost, "value": value}})
+	return err
+}
+
+// ProductValue sums a product's quantity and stock value over all stores.
+func (r *StockRepository) ProductValue(ctx context.Context, tenantID string, productID primitive.ObjectID) (float64, float64, error) {
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"tenant_id": tenantID, "product_id": productID}}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": nil, "qty": bson.M{"$sum": "$qty"}, "value": bson.M{"$sum": "$value"}}}},
+	}
+	cur, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil { return 0, 0, err }
+	defer cur.Close(ctx)
+	var row struct {
+		Qty   float64 `bson:"qty"`
+		Value float64 `bson:"value"`
+	}
+	if cur.Next(ctx) { if err := cur.Decode(&row); err != nil { return 0, 0, err } }
+	return row.Qty, row.Value, cur.Err()
+}
+
+// Adjust adds delta to the balance in a single update, creating it when missing. A decrease stops at zero. It
+// returns the quantities before and after the update.
+func (r *StockRepository) Adjust(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, delta float64) (float64, float64, error) {
+	now := time.Now().UTC()
+	var prev models.StockBalance
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+	qty := bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$qty", 0}}, delta}}
+	update := mongo.Pipeline{bson.D{{Key: "$set", Value: bson.M{
+		"qty":        bson.M{"$max": bson.A{0, roundQty(qty)}},
+		"updated_at": now,
+		"created_at": bson.M{"$ifNull": bson.A{"$created_at", now}},
+	}}}}
+	err := r.col.FindOneAndUpdate(ctx, balanceKey(tenantID, productID, shopID), update, opts).Decode(&prev)
+	if err != nil && err != mongo.ErrNoDocuments { return 0, 0, err }
+	next := models.RoundQty(prev.Qty + delta)
+	if next < 0 { next = 0 }
+	return prev.Qty, next, nil
+}
+
+// Take removes qty from the balance only when at least qty is on hand. ok is false, and nothing changes, when the
+// store has less.
+func (r *StockRepository) Take(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, qty float64) (left float64, ok bool, err error) {
+	filter := balanceKey(tenantID, productID, shopID)
+	filter["qty"] = bson.M{"$gte": qty}
+	var m models.StockBalance
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	update := mongo.Pipeline{bson.D{{Key: "$set", Value: bson.M{"qty": roundQty(bson.M{"$subtract": bson.A{"$qty", qty}}), "updated_at": time.Now().UTC()}}}}
+	err = r.col.FindOneAndUpdate(ctx, filter, update, opts).Decode(&m)
+	if err == mongo.ErrNoDocuments { return 0, false, nil }
+	if err != nil { return 0, false, err }
+	return m.Qty, true, nil
+}
+
+// Lock claims the balance of a product in a store for holder until the given time, so that reservations against it
+// are checked and written one at a time. ok is false while another holder's claim is live, or when the store has no
+// balance for the product.
+func (r *StockRepository) Lock(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID, holder string, until time.Time) (bool, error) {
+	filter := balanceKey(tenantID, productID, shopID)
+	filter["$or"] = bson.A{bson.M{"reserve_lock": bson.M{"$exists": false}}, bson.M{"reserve_lock.until": bson.M{"$lt": time.Now().UTC()}}}
+	res, err := r.col.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"reserve_lock": bson.M{"holder": holder, "until": until}}})
+	if err != nil { return false, err }
+	return res.MatchedCount == 1, nil
+}
+
+// Unlock drops holder's claim on the balance.
+func (r *StockRepository) Unlock(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID, holder string) error {
+	filter := balanceKey(tenantID, productID, shopID)
+	filter["reserve_lock.holder"] = holder
+	_, err := r.col.UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"reserve_lock": ""}})
+	return err
+}
+
+// Set overwrites the balance and returns the quantity it replaced.
+func (r *StockRepository) Set(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, qty float64) (float64, error) {
+	now := time.Now().UTC()
+	var prev models.StockBalance
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+	update := bson.M{"$set": bson.M{"qty": qty, "updated_at": now}, "$setOnInsert": bson.M{"created_at": now}}
+	err := r.col.FindOneAndUpdate(ctx, balanceKey(tenantID, productID, shopID), update, opts).Decode(&prev)
+	if err == mongo.ErrNoDocuments { return 0, nil }
+	if err != nil { return 0, err }
+	return prev.Qty, nil
+}
+
+// Total sums a product's balances over all stores.
+func (r *StockRepository) Total(ctx context.Context, tenantID string, productID primitive.ObjectID) (float64, error) {
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"tenant_id": tenantID, "product_id": productID}}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": nil, "qty": bson.M{"$sum": "$qty"}}}},
+	}
+	cur, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil { return 0, err }
+	defer cur.Close(ctx)
+	var row struct{ Qty float64 `bson:"qty"` }
+	if cur.Next(ctx) { if err := cur.Decode(&row); err != nil { return 0, err } }
+	return row.Qty, cur.Err()
+}
+
+// ListByProducts returns the non-zero balances of the given products keyed by product id.
+func (r *StockRepository) ListByProducts(ctx context.Context, tenantID string, productIDs []primitive.ObjectID) (map[primitive.ObjectID][]models.StockBalance, error) {
+	out := map[primitive.ObjectID][]models.StockBalance{}
+	if len(productIDs) == 0 { return out, nil }
+	filter := bson.M{"tenant_id": tenantID, "product_id": bson.M{"$in": productIDs}, "qty": bson.M{"$ne": 0}}
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "shop_id", Value: 1}}))
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	var items []models.StockBalance
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	for _, b := range items { out[b.ProductID] = append(out[b.ProductID], b) }
+	return out, nil
+}
+
+// ListAll returns every balance of a tenant.
+func (r *StockRepository) ListAll(ctx context.Context, tenantID string) ([]models.StockBalance, error) {
+	cur, err := r.col.Find(ctx, bson.M{"tenant_id": tenantID})
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	var items []models.StockBalance
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
+
+// ByShop returns the quantities on hand in a store keyed by product id.
+func (r *StockRepository) ByShop(ctx context.Context, tenantID, shopID string) (map[primitive.ObjectID]float64, error) {
+	cur, err := r.col.Find(ctx, bson.M{"tenant_id": tenantID, "shop_id": shopID})
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	var items []models.StockBalance
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	out := make(map[primitive.ObjectID]float64, len(items))
+	for _, b := range items { out[b.ProductID] = b.Qty }
+	return out, nil
+}
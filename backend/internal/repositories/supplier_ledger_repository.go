@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SupplierLedgerRepository is the append-only accounts payable ledger; entries are never updated or deleted.
+type SupplierLedgerRepository struct { col *mongo.Collection }
+
+func NewSupplierLedgerRepository(db *mongo.Database) *SupplierLedgerRepository { return &SupplierLedgerRepository{ col: db.Collection("supplier_ledger") } }
+
+func (r *SupplierLedgerRepository) Create(ctx context.Context, m *models.SupplierLedgerEntry) (*models.SupplierLedgerEntry, error) {
+	m.CreatedAt = time.Now().UTC()
+	if m.Date.IsZero() { m.Date = m.CreatedAt }
+	res, err := r.col.InsertOne(ctx, m)
+	if err != nil { return nil, err }
+	m.ID = res.InsertedID.(primitive.ObjectID)
+	return m, nil
+}
+
+// List returns a supplier's postings, newest first. Empty shopID matches all stores.
+func (r *SupplierLedgerRepository) List(ctx context.Context, tenantID, supplierID, shopID string, page, limit int64) ([]models.SupplierLedgerEntry, int64, error) {
+	if page < 1 { page = 1 }
+	if limit < 1 || limit > 200 { limit = 20 }
+	filter := bson.M{"tenant_id": tenantID, "supplier_id": supplierID}
+	if shopID != "" { filter["shop_id"] = shopID }
+	opts := options.Find().SetSkip((page-1)*limit).SetLimit(limit).SetSort(bson.D{{Key: "date", Value: -1}, {Key: "_id", Value: -1}})
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil { return nil, 0, err }
+	defer cur.Close(ctx)
+	items := []models.SupplierLedgerEntry{}
+	if err := cur.All(ctx, &items); err != nil { return nil, 0, err }
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil { return nil, 0, err }
+	return items, total, nil
+}
+
+// Range returns the postings dated within [from, to], oldest first. Nil bounds are open.
+func (r *SupplierLedgerRepository) Range(ctx context.Context, tenantID, supplierID, shopID string, from, to *time.Time) ([]models.SupplierLedgerEntry, error) {
+	filter := bson.M{"tenant_id": tenantID, "supplier_id": supplierID}
+	if shopID != "" { filter["shop_id"] = shopID }
+	if from != nil || to != nil {
+		dt := bson.M{}
+		if from != nil { dt["$gte"] = *from }
+		if to != nil { dt["$lte"] = *to }
+		filter["date"] = dt
+	}
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	items := []models.SupplierLedgerEntry{}
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
+
+// SumBefore is the supplier balance from the postings dated before the given time.
+func (r *SupplierLedgerRepository) SumBefore(ctx context.Context, tenantID, supplierID, shopID string, before time.Time) (float64, error) {
+	match := bson.M{"tenant_id": tenantID, "supplier_id": supplierID, "date": bson.M{"$lt": before}}
+	if shopID != "" { match["shop_id"] = shopID }
+	cur, err := r.col.Aggregate(ctx, mongo.Pipeline{
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": nil, "sum": bson.M{"$sum": "$amount"}}}},
+	})
+	if err != nil { return 0, err }
+	defer cur.Close(ctx)
+	var rows []struct{ Sum float64 `bson:"sum"` }
+	if err := cur.All(ctx, &rows); err != nil { return 0, err }
+	if len(rows) == 0 { return 0, nil }
+	return rows[0].Sum, nil
+}
+
+// HasEntry reports whether a posting of the type exists for the supplier, e.g. an opening balance.
+func (r *SupplierLedgerRepository) HasEntry(ctx context.Context, tenantID, supplierID, typ string) (bool, error) {
+	n, err := r.col.CountDocuments(ctx, bson.M{"tenant_id": tenantID, "supplier_id": supplierID, "type": typ}, options.Count().SetLimit(1))
+	if err != nil { return false, err }
+	return n > 0, nil
+}
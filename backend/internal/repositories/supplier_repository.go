@@ -13,10 +13,11 @@ import (
 )
 
 type SupplierListParams struct {
-	Page   int64
-	Limit  int64
-	Sort   bson.D
-	Search string
+	Page     int64
+	Limit    int64
+	Sort     bson.D
+	Search   string
+	TenantID string
 }
 
 type SupplierRepository struct { col *mongo.Collection }
@@ -27,7 +28,7 @@ func (r *SupplierRepository) List(ctx context.Context, p SupplierListParams) ([]
 	if p.Page < 1 { p.Page = 1 }
 	if p.Limit < 1 || p.Limit > 200 { p.Limit = 20 }
 	if p.Sort == nil { p.Sort = bson.D{{Key: "created_at", Value: -1}} }
-	filter := bson.M{}
+	filter := bson.M{"tenant_id": p.TenantID}
 	if p.Search != "" {
 		filter["$or"] = []bson.M{
 			{"name": bson.M{"$regex": p.Search, "$options": "i"}},
@@ -46,9 +47,9 @@ func (r *SupplierRepository) List(ctx context.Context, p SupplierListParams) ([]
 	return items, total, nil
 }
 
-func (r *SupplierRepository) Get(ctx context.Context, id primitive.ObjectID) (*models.Supplier, error) {
+func (r *SupplierRepository) Get(ctx context.Context, id primitive.ObjectID, tenantID string) (*models.Supplier, error) {
 	var m models.Supplier
-	if err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&m); err != nil { return nil, err }
+	if err := r.col.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}).Decode(&m); err != nil { return nil, err }
 	return &m, nil
 }
 
@@ -62,15 +63,23 @@ func (r *SupplierRepository) Create(ctx context.Context, m *models.Supplier) (*m
 	return m, nil
 }
 
-func (r *SupplierRepository) Update(ctx context.Context, id primitive.ObjectID, update bson.M) (*models.Supplier, error) {
+func (r *SupplierRepository) Update(ctx context.Context, id primitive.ObjectID, tenantID string, update bson.M) (*models.Supplier, error) {
 	if update == nil { update = bson.M{} }
 	update["updated_at"] = time.Now().UTC()
-	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{"$set": update})
 	if err != nil { return nil, err }
-	return r.Get(ctx, id)
+	return r.Get(ctx, id, tenantID)
 }
 
-func (r *SupplierRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
-	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
+func (r *SupplierRepository) Delete(ctx context.Context, id primitive.ObjectID, tenantID string) error {
+	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
 	return err
-} 
\ No newline at end of file
+} 
+// IncBalance moves a supplier's payable balance by delta and returns the new balance.
+func (r *SupplierRepository) IncBalance(ctx context.Context, id primitive.ObjectID, tenantID string, delta float64) (float64, error) {
+	var m models.Supplier
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.col.FindOneAndUpdate(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{"$inc": bson.M{"balance": delta}, "$set": bson.M{"updated_at": time.Now().UTC()}}, opts).Decode(&m)
+	if err != nil { return 0, err }
+	return m.Balance, nil
+}
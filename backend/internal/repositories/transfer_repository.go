@@ -93,6 +93,11 @@ func (r *TransferRepository) Update(ctx context.Context, id primitive.ObjectID,
 	return r.Get(ctx, id, tenantID)
 }
 
+// UpdateIf moves the transfer on only while it is still in the expected state. It reports false otherwise.
+func (r *TransferRepository) UpdateIf(ctx context.Context, id primitive.ObjectID, tenantID string, expect bson.M, update bson.M) (bool, error) {
+	return updateIf(ctx, r.col, id, tenantID, expect, update)
+}
+
 func (r *TransferRepository) Delete(ctx context.Context, id primitive.ObjectID, tenantID string) error {
 	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
 	return err
@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Tx runs a unit of work in a MongoDB session transaction so that document status changes commit together with
+// their stock effects. A standalone server cannot run transactions; there the work runs without one and only the
+// single-document atomic updates protect it.
+type Tx struct {
+	client  *mongo.Client
+	enabled bool
+}
+
+// NewTx checks once whether the deployment is a replica set or a sharded cluster, the two topologies that support
+// transactions.
+func NewTx(ctx context.Context, db *mongo.Database) *Tx {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	enabled := false
+	if err := db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err == nil {
+		enabled = hello.SetName != "" || hello.Msg == "isdbgrid"
+	}
+	return &Tx{client: db.Client(), enabled: enabled}
+}
+
+// Enabled reports whether Run uses real transactions.
+func (t *Tx) Enabled() bool { return t != nil && t.enabled }
+
+// Run calls fn inside a transaction and commits when it returns nil. Transient conflicts, such as two requests
+// approving the same document, are retried from the start, so fn must re-read whatever state it depends on.
+// Calls nested in an open transaction join it.
+func (t *Tx) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !t.Enabled() || mongo.SessionFromContext(ctx) != nil { return fn(ctx) }
+	sess, err := t.client.StartSession()
+	if err != nil { return err }
+	defer sess.EndSession(ctx)
+	_, err = sess.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) { return nil, fn(sc) })
+	return err
+}
+
+// updateIf sets update on a tenant's document only while it still matches expect, e.g. the status the caller read.
+// Without a transaction this is what keeps two concurrent status changes from both going through: the second
+// matches nothing and reports false.
+func updateIf(ctx context.Context, col *mongo.Collection, id primitive.ObjectID, tenantID string, expect bson.M, update bson.M) (bool, error) {
+	filter := bson.M{"_id": id, "tenant_id": tenantID}
+	for k, v := range expect { filter[k] = v }
+	if update == nil { update = bson.M{} }
+	update["updated_at"] = time.Now().UTC()
+	res, err := col.UpdateOne(ctx, filter, bson.M{"$set": update})
+	if err != nil { return false, err }
+	return res.MatchedCount == 1, nil
+}
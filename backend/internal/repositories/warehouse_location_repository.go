@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"shop/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type WarehouseLocationRepository struct { col *mongo.Collection }
+
+func NewWarehouseLocationRepository(db *mongo.Database) *WarehouseLocationRepository { return &WarehouseLocationRepository{col: db.Collection("warehouse_locations")} }
+
+// List returns the locations of a store or warehouse in path order, optionally of one kind.
+func (r *WarehouseLocationRepository) List(ctx context.Context, tenantID, warehouseID, kind string) ([]models.WarehouseLocation, error) {
+	filter := bson.M{"tenant_id": tenantID, "warehouse_id": warehouseID}
+	if kind != "" { filter["kind"] = kind }
+	return r.find(ctx, filter)
+}
+
+// Bins returns the bins at or under the given paths, in path order.
+func (r *WarehouseLocationRepository) Bins(ctx context.Context, tenantID, warehouseID string, paths []string) ([]models.WarehouseLocation, error) {
+	or := make(bson.A, 0, len(paths))
+	for _, p := range paths {
+		or = append(or, bson.M{"path": bson.M{"$regex": "^" + regexp.QuoteMeta(p) + "(/|$)"}})
+	}
+	if len(or) == 0 { return []models.WarehouseLocation{}, nil }
+	return r.find(ctx, bson.M{"tenant_id": tenantID, "warehouse_id": warehouseID, "kind": models.LocationBin, "$or": or})
+}
+
+// FindBin looks a bin up by its path or, failing that, its code.
+func (r *WarehouseLocationRepository) FindBin(ctx context.Context, tenantID, warehouseID, ref string) (*models.WarehouseLocation, error) {
+	filter := bson.M{"tenant_id": tenantID, "warehouse_id": warehouseID, "kind": models.LocationBin, "$or": bson.A{bson.M{"path": ref}, bson.M{"code": ref}}}
+	var m models.WarehouseLocation
+	if err := r.col.FindOne(ctx, filter, options.FindOne().SetSort(bson.D{{Key: "path", Value: 1}})).Decode(&m); err != nil { return nil, err }
+	return &m, nil
+}
+
+// HasBins reports whether a store or warehouse has any bins.
+func (r *WarehouseLocationRepository) HasBins(ctx context.Context, tenantID, warehouseID string) (bool, error) {
+	n, err := r.col.CountDocuments(ctx, bson.M{"tenant_id": tenantID, "warehouse_id": warehouseID, "kind": models.LocationBin}, options.Count().SetLimit(1))
+	return n > 0, err
+}
+
+// HasChildren reports whether any location sits under the given one.
+func (r *WarehouseLocationRepository) HasChildren(ctx context.Context, id primitive.ObjectID, tenantID string) (bool, error) {
+	n, err := r.col.CountDocuments(ctx, bson.M{"tenant_id": tenantID, "parent_id": id}, options.Count().SetLimit(1))
+	return n > 0, err
+}
+
+func (r *WarehouseLocationRepository) Get(ctx context.Context, id primitive.ObjectID, tenantID string) (*models.WarehouseLocation, error) {
+	var m models.WarehouseLocation
+	if err := r.col.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}).Decode(&m); err != nil { return nil, err }
+	return &m, nil
+}
+
+func (r *WarehouseLocationRepository) Create(ctx context.Context, m *models.WarehouseLocation) (*models.WarehouseLocation, error) {
+	now := time.Now().UTC()
+	m.CreatedAt = now
+	m.UpdatedAt = now
+	res, err := r.col.InsertOne(ctx, m)
+	if err != nil { return nil, err }
+	m.ID = res.InsertedID.(primitive.ObjectID)
+	return m, nil
+}
+
+func (r *WarehouseLocationRepository) Update(ctx context.Context, id primitive.ObjectID, tenantID string, update bson.M) (*models.WarehouseLocation, error) {
+	if update == nil { update = bson.M{} }
+	update["updated_at"] = time.Now().UTC()
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{"$set": update})
+	if err != nil { return nil, err }
+	return r.Get(ctx, id, tenantID)
+}
+
+func (r *WarehouseLocationRepository) Delete(ctx context.Context, id primitive.ObjectID, tenantID string) error {
+	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
+	return err
+}
+
+func (r *WarehouseLocationRepository) find(ctx context.Context, filter bson.M) ([]models.WarehouseLocation, error) {
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "path", Value: 1}}))
+	if err != nil { return nil, err }
+	defer cur.Close(ctx)
+	items := []models.WarehouseLocation{}
+	if err := cur.All(ctx, &items); err != nil { return nil, err }
+	return items, nil
+}
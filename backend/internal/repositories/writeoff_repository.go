@@ -92,6 +92,11 @@ func (r *WriteOffRepository) Update(ctx context.Context, id primitive.ObjectID,
 	return r.Get(ctx, id, tenantID)
 }
 
+// UpdateIf updates the write-off only while it still matches expect. It reports false otherwise.
+func (r *WriteOffRepository) UpdateIf(ctx context.Context, id primitive.ObjectID, tenantID string, expect bson.M, update bson.M) (bool, error) {
+	return updateIf(ctx, r.col, id, tenantID, expect, update)
+}
+
 func (r *WriteOffRepository) Delete(ctx context.Context, id primitive.ObjectID, tenantID string) error {
 	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
 	return err
@@ -24,7 +24,7 @@ func Register(app *fiber.App, roles *handlers.RoleHandler, users *handlers.UserH
 	app.Static("/uploads", "/data/uploads")
 }
 
-func RegisterWithTenant(app *fiber.App, roles *handlers.RoleHandler, users *handlers.UserHandler, auth *handlers.AuthHandler, suppliers *handlers.SupplierHandler, tenants *handlers.TenantHandler, tenantResolver *middleware.TenantResolver, companies *handlers.CompanyHandler, stores *handlers.StoreHandler, categories *handlers.CategoryHandler, attributes *handlers.AttributeHandler, characteristics *handlers.CharacteristicHandler, brands *handlers.BrandHandler, warehouses *handlers.WarehouseHandler, parameters *handlers.ParameterHandler, products *handlers.ProductHandler, upload *handlers.UploadHandler, leads *handlers.LeadHandler, customers *handlers.CustomerHandler, orders *handlers.OrderHandler, shopCustomers *handlers.ShopCustomerHandler, shopUnits *handlers.ShopUnitHandler, shopVendors *handlers.ShopVendorHandler, shopServices *handlers.ShopServiceHandler, shopContacts *handlers.ShopContactHandler, importHistory *handlers.ImportHistoryHandler, inventories *handlers.InventoryHandler, writeoffs *handlers.WriteOffHandler, repricings *handlers.RepricingHandler, transfers *handlers.TransferHandler, pricetags *handlers.PriceTagHandler, exchangeRates *handlers.ExchangeRateHandler) {
+func RegisterWithTenant(app *fiber.App, roles *handlers.RoleHandler, users *handlers.UserHandler, auth *handlers.AuthHandler, suppliers *handlers.SupplierHandler, tenants *handlers.TenantHandler, tenantResolver *middleware.TenantResolver, companies *handlers.CompanyHandler, stores *handlers.StoreHandler, categories *handlers.CategoryHandler, attributes *handlers.AttributeHandler, characteristics *handlers.CharacteristicHandler, brands *handlers.BrandHandler, warehouses *handlers.WarehouseHandler, parameters *handlers.ParameterHandler, products *handlers.ProductHandler, upload *handlers.UploadHandler, leads *handlers.LeadHandler, customers *handlers.CustomerHandler, orders *handlers.OrderHandler, shopCustomers *handlers.ShopCustomerHandler, shopUnits *handlers.ShopUnitHandler, shopVendors *handlers.ShopVendorHandler, shopServices *handlers.ShopServiceHandler, shopContacts *handlers.ShopContactHandler, importHistory *handlers.ImportHistoryHandler, inventories *handlers.InventoryHandler, writeoffs *handlers.WriteOffHandler, repricings *handlers.RepricingHandler, transfers *handlers.TransferHandler, pricetags *handlers.PriceTagHandler, exchangeRates *handlers.ExchangeRateHandler, stock *handlers.StockHandler, sales *handlers.SaleHandler, cashbox *handlers.CashboxHandler, saleReturns *handlers.SaleReturnHandler, customerDebts *handlers.CustomerDebtHandler, supplierLedger *handlers.SupplierLedgerHandler, measureUnits *handlers.MeasureUnitHandler, replenishment *handlers.ReplenishmentHandler, kitAssemblies *handlers.KitAssemblyHandler, consignments *handlers.ConsignmentHandler, cores *handlers.CoreHandler, bins *handlers.BinHandler, cycleCounts *handlers.CycleCountHandler) {
 	api := app.Group("/api")
 	auth.Register(api)
 	protected := api.Group("", middleware.Current.AuthRequired(), tenantResolver.Resolve())
@@ -60,6 +60,19 @@ func RegisterWithTenant(app *fiber.App, roles *handlers.RoleHandler, users *hand
 	transfers.Register(protected)
 	pricetags.Register(protected)
 	exchangeRates.Register(protected)
+	stock.Register(protected)
+	sales.Register(protected)
+	cashbox.Register(protected)
+	saleReturns.Register(protected)
+	customerDebts.Register(protected)
+	supplierLedger.Register(protected)
+	measureUnits.Register(protected)
+	replenishment.Register(protected)
+	kitAssemblies.Register(protected)
+	consignments.Register(protected)
+	cores.Register(protected)
+	bins.Register(protected)
+	cycleCounts.Register(protected)
 	// Public static uploads (needed for <img src>)
 	app.Static("/uploads", "/data/uploads")
 } 
\ No newline at end of file
@@ -0,0 +1,87 @@
+package routes
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+
+	"shop/backend/internal/handlers"
+	"shop/backend/internal/middleware"
+	"shop/backend/internal/services"
+	"shop/backend/internal/utils"
+)
+
+// open lists the protected routes that deliberately take no permission: any signed-in user of the tenant may call them.
+var open = map[string]string{
+	"GET /api/auth/me":           "the signed-in user's own profile",
+	"GET /api/exchange-rates/at": "the rate a document is priced at, needed by every document form",
+	"GET /api/tenant/current":    "the tenant the session works in",
+	"POST /api/upload/images":    "images attached to any document the user may edit",
+}
+
+// TestProtectedRoutesRequireCatalogPermissions walks the routes RegisterWithTenant builds and checks that every
+// protected one is guarded by RequirePermission with a permission whose base is in the RoleService catalog, so a
+// route cannot be added that no role can be granted.
+func TestProtectedRoutesRequireCatalogPermissions(t *testing.T) {
+	app := fiber.New()
+	middleware.NewAuthz(nil, nil, "")
+	// the handlers only hand out their method values while registering, so zero values do
+	fn := reflect.ValueOf(RegisterWithTenant)
+	args := []reflect.Value{reflect.ValueOf(app)}
+	for i := 1; i < fn.Type().NumIn(); i++ { args = append(args, reflect.New(fn.Type().In(i).Elem())) }
+	fn.Call(args)
+
+	public := map[string]bool{}
+	pub := fiber.New()
+	(&handlers.AuthHandler{}).Register(pub.Group("/api"))
+	for _, r := range pub.GetRoutes(true) { public[r.Method+" "+r.Path] = true }
+
+	groups, err := (&services.RoleService{}).Permissions(context.Background())
+	if err != nil { t.Fatalf("permissions: %v", err) }
+	catalog := map[string]bool{}
+	for _, g := range groups {
+		for _, it := range g.Items { catalog[it.Key] = true }
+	}
+
+	guard := reflect.ValueOf(middleware.RequirePermission("")).Pointer()
+	seen := 0
+	for _, r := range app.GetRoutes(true) {
+		key := r.Method + " " + r.Path
+		if r.Method == fiber.MethodHead || !strings.HasPrefix(r.Path, "/api/") || strings.HasPrefix(r.Path, "/api/uploads") || public[key] { continue }
+		seen++
+		perms := []string{}
+		for _, h := range r.Handlers {
+			if reflect.ValueOf(h).Pointer() == guard { perms = append(perms, permissionOf(app, h)) }
+		}
+		if _, ok := open[key]; ok {
+			if len(perms) > 0 { t.Errorf("%s is listed as open but requires %v", key, perms) }
+			continue
+		}
+		if len(perms) == 0 {
+			t.Errorf("%s has no RequirePermission", key)
+			continue
+		}
+		for _, p := range perms {
+			base := p
+			if i := strings.LastIndex(p, "."); i > 0 { base = p[:i] }
+			if !catalog[base] { t.Errorf("%s requires %q, whose base %q is not in the permission catalog", key, p, base) }
+		}
+	}
+	if seen == 0 { t.Fatal("no protected routes found") }
+}
+
+// permissionOf runs a RequirePermission guard for a user holding no permissions and reads the permission it asks for
+// from the refusal.
+func permissionOf(app *fiber.App, h fiber.Handler) string {
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Locals("permissions", map[string]struct{}{})
+	err := h(c)
+	ae, ok := err.(*utils.AppError)
+	if !ok { return "" }
+	return strings.TrimPrefix(ae.Message, "Permission required: ")
+}
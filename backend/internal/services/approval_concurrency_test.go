@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"shop/backend/internal/config"
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The approval tests run against a real MongoDB named by MONGO_TEST_URI, each in a database of its own. Against a
+// replica set they exercise the transactions; against a standalone server, the conditional status updates alone.
+// Without MONGO_TEST_URI they are skipped. The compose file's backend-test service runs them against its replica set:
+//
+//	docker compose run --rm backend-test
+//
+// or, from the host, with the compose mongo running:
+//
+//	MONGO_TEST_URI='mongodb://localhost:27018/?directConnection=true' go test ./internal/services/
+
+type approvalFixture struct {
+	ctx         context.Context
+	tenantID    string
+	shopID      string
+	products    *repositories.ProductRepository
+	orders      *repositories.OrderRepository
+	writeOffs   *repositories.WriteOffRepository
+	transfers   *repositories.TransferRepository
+	repricings  *repositories.RepricingRepository
+	inventories *repositories.InventoryRepository
+	imports     *repositories.ImportHistoryRepository
+	stock       *StockService
+	orderSvc    *OrderService
+	writeOffSvc *WriteOffService
+	transferSvc *TransferService
+	repriceSvc  *RepricingService
+	countSvc    *InventoryService
+}
+
+func newApprovalFixture(t *testing.T) *approvalFixture {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" { t.Skip("MONGO_TEST_URI is not set") }
+	ctx := context.Background()
+	client, err := config.NewMongoClient(ctx, uri)
+	if err != nil { t.Fatalf("connect: %v", err) }
+	db := client.Database(fmt.Sprintf("shop_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() { _ = db.Drop(ctx); _ = client.Disconnect(ctx) })
+	if err := config.EnsureIndexes(ctx, db); err != nil { t.Fatalf("indexes: %v", err) }
+
+	stockRepo := repositories.NewStockRepository(db)
+	movementRepo := repositories.NewStockMovementRepository(db)
+	productRepo := repositories.NewProductRepository(db)
+	tenantRepo := repositories.NewTenantRepository(db)
+	storeRepo := repositories.NewStoreRepository(db)
+	writeOffRepo := repositories.NewWriteOffRepository(db)
+	transferRepo := repositories.NewTransferRepository(db)
+	orderRepo := repositories.NewOrderRepository(db)
+	supplierRepo := repositories.NewSupplierRepository(db)
+	repricingRepo := repositories.NewRepricingRepository(db)
+	inventoryRepo := repositories.NewInventoryRepository(db)
+	importRepo := repositories.NewImportHistoryRepository(db)
+	tx := repositories.NewTx(ctx, db)
+	units := NewMeasureUnitService(repositories.NewMeasureUnitRepository(db), productRepo)
+	bins := NewBinService(repositories.NewWarehouseLocationRepository(db), repositories.NewBinStockRepository(db), repositories.NewWarehouseRepository(db), storeRepo, productRepo)
+	stock := NewStockService(stockRepo, movementRepo, productRepo,
+		NewCostingService(stockRepo, repositories.NewCostLayerRepository(db), movementRepo, productRepo, tenantRepo),
+		NewLotService(repositories.NewStockLotRepository(db), productRepo),
+		NewSerialService(repositories.NewSerialNumberRepository(db), productRepo),
+		units,
+		NewReservationService(repositories.NewReservationRepository(db), stockRepo, productRepo, tenantRepo, units),
+		NewConsignmentService(repositories.NewConsignmentBatchRepository(db), repositories.NewConsignmentMovementRepository(db)),
+		bins, tx)
+	coreStockRepo := repositories.NewCoreStockRepository(db)
+	orderSvc := NewOrderService(orderRepo, productRepo, supplierRepo, storeRepo, writeOffRepo,
+		NewSupplierLedgerService(repositories.NewSupplierLedgerRepository(db), supplierRepo, tx),
+		repositories.NewExchangeRateRepository(db), NewCoreStockService(coreStockRepo),
+		NewPutawayService(repositories.NewPutawayRepository(db), productRepo, bins, stock), stock)
+
+	return &approvalFixture{
+		ctx: ctx, tenantID: primitive.NewObjectID().Hex(), shopID: primitive.NewObjectID().Hex(),
+		products: productRepo, orders: orderRepo, writeOffs: writeOffRepo, transfers: transferRepo, stock: stock,
+		repricings: repricingRepo, inventories: inventoryRepo, imports: importRepo,
+		orderSvc: orderSvc,
+		writeOffSvc: NewWriteOffService(writeOffRepo, storeRepo, productRepo, stock),
+		transferSvc: NewTransferService(transferRepo, storeRepo, productRepo, stock, writeOffRepo, importRepo),
+		repriceSvc: NewRepricingService(repricingRepo, storeRepo, productRepo, stock),
+		countSvc: NewInventoryService(inventoryRepo, storeRepo, productRepo, importRepo, stock),
+	}
+}
+
+// product creates a product with qty on hand in the fixture's store.
+func (f *approvalFixture) product(t *testing.T, qty float64) *models.Product {
+	p, err := f.products.Create(f.ctx, &models.Product{ TenantID: f.tenantID, Name: "Test product", SKU: primitive.NewObjectID().Hex(), Unit: "pcs", CostPrice: 10, Price: 15 })
+	if err != nil { t.Fatalf("create product: %v", err) }
+	if qty > 0 {
+		if err := f.stock.Adjust(f.ctx, f.tenantID, p.ID, f.shopID, qty, 10, models.StockSource{ Type: models.StockSourceInventory, ID: "seed" }); err != nil { t.Fatalf("seed stock: %v", err) }
+	}
+	return p
+}
+
+func (f *approvalFixture) onHand(t *testing.T, productID primitive.ObjectID, shopID string) float64 {
+	qty, err := f.stock.Available(f.ctx, f.tenantID, productID, shopID)
+	if err != nil { t.Fatalf("read stock: %v", err) }
+	return qty
+}
+
+// parallel runs fn n times at once. A run may lose a race with a conflict or find the document already finished
+// (the codes in finished); any other failure fails the test.
+func parallel(t *testing.T, n int, fn func(i int) error, finished ...string) {
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs <- fn(i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err == nil { continue }
+		if ae, ok := err.(*utils.AppError); ok && (ae.Status == http.StatusConflict || slices.Contains(finished, ae.Code)) { continue }
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestConcurrentWriteOffApprovalsTakeStockOnce(t *testing.T) {
+	f := newApprovalFixture(t)
+	p := f.product(t, 10)
+	wo, err := f.writeOffs.Create(f.ctx, &models.WriteOff{ TenantID: f.tenantID, ShopID: f.shopID, Name: "Damaged", Items: []models.WriteOffItem{{ ProductID: p.ID, ProductName: p.Name, Qty: 3, Unit: "pcs" }} })
+	if err != nil { t.Fatalf("create write-off: %v", err) }
+
+	parallel(t, 8, func(int) error {
+		_, err := f.writeOffSvc.Update(f.ctx, wo.ID.Hex(), models.UpdateWriteOffRequest{ Action: "approve" }, f.tenantID, models.InventoryUser{ Name: "tester" })
+		return err
+	})
+
+	if got := f.onHand(t, p.ID, f.shopID); got != 7 {
+		t.Fatalf("stock after approving a write-off of 3 out of 10 eight times at once = %v, want 7", got)
+	}
+}
+
+func TestConcurrentWriteOffsLoseNoUpdates(t *testing.T) {
+	f := newApprovalFixture(t)
+	p := f.product(t, 10)
+	ids := make([]string, 10)
+	for i := range ids {
+		wo, err := f.writeOffs.Create(f.ctx, &models.WriteOff{ TenantID: f.tenantID, ShopID: f.shopID, Name: "Damaged", Items: []models.WriteOffItem{{ ProductID: p.ID, ProductName: p.Name, Qty: 1, Unit: "pcs" }} })
+		if err != nil { t.Fatalf("create write-off: %v", err) }
+		ids[i] = wo.ID.Hex()
+	}
+
+	parallel(t, len(ids), func(i int) error {
+		_, err := f.writeOffSvc.Update(f.ctx, ids[i], models.UpdateWriteOffRequest{ Action: "approve" }, f.tenantID, models.InventoryUser{ Name: "tester" })
+		return err
+	})
+
+	if got := f.onHand(t, p.ID, f.shopID); got != 0 {
+		t.Fatalf("stock after ten concurrent write-offs of 1 out of 10 = %v, want 0", got)
+	}
+	for _, id := range ids {
+		wo, err := f.writeOffSvc.Get(f.ctx, id, f.tenantID)
+		if err != nil { t.Fatalf("get write-off: %v", err) }
+		if wo.Status != "APPROVED" { t.Errorf("write-off %s is %s, want APPROVED", id, wo.Status) }
+	}
+}
+
+func TestConcurrentOrderApprovalsReceiveOnce(t *testing.T) {
+	f := newApprovalFixture(t)
+	p := f.product(t, 0)
+	o, err := f.orders.Create(f.ctx, &models.Order{ TenantID: f.tenantID, Name: "Supplier order", Type: "supplier_order", ShopID: f.shopID, Items: []models.OrderItem{{ ProductID: p.ID, ProductName: p.Name, Quantity: 5, SupplyPrice: 10, Unit: "pcs" }} })
+	if err != nil { t.Fatalf("create order: %v", err) }
+
+	parallel(t, 8, func(int) error {
+		_, err := f.orderSvc.Update(f.ctx, o.ID.Hex(), models.UpdateOrderRequest{ Action: "approve" }, f.tenantID, models.OrderUser{ Name: "tester" })
+		return err
+	}, "ORDER_LOCKED")
+
+	if got := f.onHand(t, p.ID, f.shopID); got != 5 {
+		t.Fatalf("stock after approving an order of 5 eight times at once = %v, want 5", got)
+	}
+	got, err := f.orders.Get(f.ctx, o.ID, f.tenantID)
+	if err != nil { t.Fatalf("get order: %v", err) }
+	if len(got.Receivings) != 1 { t.Fatalf("order has %d receivings, want 1", len(got.Receivings)) }
+}
+
+func TestConcurrentTransferApprovalsMoveStockOnce(t *testing.T) {
+	f := newApprovalFixture(t)
+	p := f.product(t, 10)
+	arrival := primitive.NewObjectID().Hex()
+	tr, err := f.transfers.Create(f.ctx, &models.Transfer{ TenantID: f.tenantID, Name: "To the branch", DepartureShopID: f.shopID, ArrivalShopID: arrival, Items: []models.TransferItem{{ ProductID: p.ID, ProductName: p.Name, Qty: 4, Unit: "pcs" }} })
+	if err != nil { t.Fatalf("create transfer: %v", err) }
+
+	parallel(t, 8, func(int) error {
+		_, err := f.transferSvc.Update(f.ctx, tr.ID.Hex(), models.UpdateTransferRequest{ Action: "approve" }, f.tenantID, models.InventoryUser{ Name: "tester" })
+		return err
+	})
+
+	if got := f.onHand(t, p.ID, f.shopID); got != 6 {
+		t.Errorf("departure stock = %v, want 6", got)
+	}
+	if got := f.onHand(t, p.ID, arrival); got != 4 {
+		t.Errorf("arrival stock = %v, want 4", got)
+	}
+}
+
+func TestConcurrentRepricingApproveAndRejectFinishOnce(t *testing.T) {
+	f := newApprovalFixture(t)
+	p := f.product(t, 10)
+	rp, err := f.repricings.Create(f.ctx, &models.Repricing{ TenantID: f.tenantID, Name: "New prices", ShopID: f.shopID, Type: "price_change", Items: []models.RepricingItem{{ ProductID: p.ID, ProductName: p.Name, RetailPrice: 20, Qty: 10 }} })
+	if err != nil { t.Fatalf("create repricing: %v", err) }
+
+	// half the requests approve and half reject; whichever claims the repricing first decides the price
+	parallel(t, 8, func(i int) error {
+		action := "approve"
+		if i%2 == 1 { action = "reject" }
+		_, err := f.repriceSvc.Update(f.ctx, rp.ID.Hex(), models.UpdateRepricingRequest{ Action: action }, f.tenantID, models.InventoryUser{ Name: fmt.Sprintf("tester %d", i) })
+		return err
+	})
+
+	got, err := f.repricings.Get(f.ctx, rp.ID, f.tenantID)
+	if err != nil { t.Fatalf("get repricing: %v", err) }
+	after, err := f.products.Get(f.ctx, p.ID, f.tenantID)
+	if err != nil { t.Fatalf("get product: %v", err) }
+	want := map[string]float64{ "APPROVED": 20, "REJECTED": 15 }
+	price, ok := want[got.Status]
+	if !ok { t.Fatalf("repricing is %s, want APPROVED or REJECTED", got.Status) }
+	if after.Price != price { t.Fatalf("price after a %s repricing = %v, want %v", got.Status, after.Price, price) }
+}
+
+func TestConcurrentInventoryFinishesApplyCountOnce(t *testing.T) {
+	f := newApprovalFixture(t)
+	p := f.product(t, 10)
+	inv, err := f.inventories.Create(f.ctx, &models.Inventory{ TenantID: f.tenantID, Name: "Monthly count", ShopID: f.shopID, Type: "PARTIAL", Items: []models.InventoryItem{{ ProductID: p.ID, ProductName: p.Name, Declared: 10, Scanned: 12, Unit: "pcs", Price: 15, CostPrice: 10 }} })
+	if err != nil { t.Fatalf("create inventory: %v", err) }
+
+	parallel(t, 8, func(int) error {
+		_, err := f.countSvc.Update(f.ctx, inv.ID.Hex(), models.UpdateInventoryRequest{ Finished: true }, f.tenantID, models.InventoryUser{ Name: "tester" })
+		return err
+	})
+
+	if got := f.onHand(t, p.ID, f.shopID); got != 12 {
+		t.Errorf("stock after finishing a count of 12 eight times at once = %v, want 12", got)
+	}
+	// the surplus is recorded once, by the finish that applied the count
+	_, n, err := f.imports.List(f.ctx, repositories.ImportHistoryListParams{ TenantID: mustObjectID(t, f.tenantID) })
+	if err != nil { t.Fatalf("list import history: %v", err) }
+	if n != 1 { t.Errorf("inventory surplus recorded %d times, want 1", n) }
+}
+
+func mustObjectID(t *testing.T, hex string) primitive.ObjectID {
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil { t.Fatalf("object id %q: %v", hex, err) }
+	return id
+}
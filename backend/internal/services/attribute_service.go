@@ -19,13 +19,14 @@ func NewAttributeService(repo *repositories.AttributeRepository) *AttributeServi
 	return &AttributeService{repo: repo}
 }
 
-func (s *AttributeService) List(ctx context.Context, page, limit int64, search string, isActive *bool) ([]models.AttributeDTO, int64, error) {
+func (s *AttributeService) List(ctx context.Context, page, limit int64, search string, isActive *bool, tenantID string) ([]models.AttributeDTO, int64, error) {
 	items, total, err := s.repo.List(ctx, repositories.AttributeListParams{
 		Page:     page,
 		Limit:    limit,
 		Sort:     bson.D{{Key: "name", Value: 1}},
 		Search:   search,
 		IsActive: isActive,
+		TenantID: tenantID,
 	})
 	if err != nil {
 		return nil, 0, utils.Internal("ATTRIBUTE_LIST_FAILED", "Unable to list attributes", err)
@@ -39,13 +40,13 @@ func (s *AttributeService) List(ctx context.Context, page, limit int64, search s
 	return out, total, nil
 }
 
-func (s *AttributeService) Get(ctx context.Context, id string) (*models.AttributeDTO, error) {
+func (s *AttributeService) Get(ctx context.Context, id string, tenantID string) (*models.AttributeDTO, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, utils.BadRequest("INVALID_ID", "Invalid attribute id", nil)
 	}
 
-	m, err := s.repo.Get(ctx, oid)
+	m, err := s.repo.Get(ctx, oid, tenantID)
 	if err != nil {
 		return nil, utils.NotFound("ATTRIBUTE_NOT_FOUND", "Attribute not found", err)
 	}
@@ -54,7 +55,7 @@ func (s *AttributeService) Get(ctx context.Context, id string) (*models.Attribut
 	return &dto, nil
 }
 
-func (s *AttributeService) Create(ctx context.Context, body models.AttributeCreate) (*models.AttributeDTO, error) {
+func (s *AttributeService) Create(ctx context.Context, body models.AttributeCreate, tenantID string) (*models.AttributeDTO, error) {
 	if body.Name == "" {
 		return nil, utils.BadRequest("VALIDATION_ERROR", "Attribute name is required", nil)
 	}
@@ -63,6 +64,7 @@ func (s *AttributeService) Create(ctx context.Context, body models.AttributeCrea
 	}
 
 	m := &models.Attribute{
+		TenantID:  tenantID,
 		Name:      body.Name,
 		Values:    body.Values,
 		IsActive:  true,
@@ -78,7 +80,7 @@ func (s *AttributeService) Create(ctx context.Context, body models.AttributeCrea
 	return &dto, nil
 }
 
-func (s *AttributeService) Update(ctx context.Context, id string, body models.AttributeUpdate) (*models.AttributeDTO, error) {
+func (s *AttributeService) Update(ctx context.Context, id string, body models.AttributeUpdate, tenantID string) (*models.AttributeDTO, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, utils.BadRequest("INVALID_ID", "Invalid attribute id", nil)
@@ -95,7 +97,7 @@ func (s *AttributeService) Update(ctx context.Context, id string, body models.At
 	}
 	if body.IsActive != nil { update["is_active"] = *body.IsActive }
 
-	updated, err := s.repo.Update(ctx, oid, update)
+	updated, err := s.repo.Update(ctx, oid, tenantID, update)
 	if err != nil {
 		return nil, utils.Internal("ATTRIBUTE_UPDATE_FAILED", "Unable to update attribute", err)
 	}
@@ -104,13 +106,13 @@ func (s *AttributeService) Update(ctx context.Context, id string, body models.At
 	return &dto, nil
 }
 
-func (s *AttributeService) Delete(ctx context.Context, id string) error {
+func (s *AttributeService) Delete(ctx context.Context, id string, tenantID string) error {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return utils.BadRequest("INVALID_ID", "Invalid attribute id", nil)
 	}
 
-	if err := s.repo.Delete(ctx, oid); err != nil {
+	if err := s.repo.Delete(ctx, oid, tenantID); err != nil {
 		return utils.Internal("ATTRIBUTE_DELETE_FAILED", "Unable to delete attribute", err)
 	}
 
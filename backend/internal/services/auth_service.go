@@ -8,11 +8,23 @@ import (
 	"shop/backend/internal/models"
 	"shop/backend/internal/repositories"
 	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-type AuthService struct { users *repositories.UserRepository; roles *repositories.RoleRepository }
+type AuthService struct {
+	users      *repositories.UserRepository
+	roles      *repositories.RoleRepository
+	tenants    *repositories.TenantRepository
+	tokens     *repositories.RefreshTokenRepository
+	secret     string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
 
-func NewAuthService(users *repositories.UserRepository, roles *repositories.RoleRepository) *AuthService { return &AuthService{users: users, roles: roles} }
+func NewAuthService(users *repositories.UserRepository, roles *repositories.RoleRepository, tenants *repositories.TenantRepository, tokens *repositories.RefreshTokenRepository, secret string, accessTTL, refreshTTL time.Duration) *AuthService {
+	return &AuthService{users: users, roles: roles, tenants: tenants, tokens: tokens, secret: secret, accessTTL: accessTTL, refreshTTL: refreshTTL}
+}
 
 func (s *AuthService) BuildUserDTO(ctx context.Context, u *models.User) (models.UserDTO, []string, error) {
 	roleName := ""
@@ -27,12 +39,76 @@ func (s *AuthService) BuildUserDTO(ctx context.Context, u *models.User) (models.
 	return dto, perms, nil
 }
 
-func (s *AuthService) Login(ctx context.Context, email, password string) (*models.LoginResponse, error) {
+// Login checks credentials and starts a new session. tenantKey is the raw X-Tenant-ID value (tenant id or subdomain);
+// when present the tenant is embedded in the access token and the session stays bound to it.
+func (s *AuthService) Login(ctx context.Context, email, password, tenantKey string) (*models.LoginResponse, error) {
 	u, err := s.users.GetByEmail(ctx, email)
 	if err != nil { return nil, utils.Unauthorized("INVALID_CREDENTIALS", "Invalid email or password", err) }
 	if !utils.CheckPasswordHash(password, u.PasswordHash) { return nil, utils.Unauthorized("INVALID_CREDENTIALS", "Invalid email or password", errors.New("bad password")) }
-	// token embeds user id for middleware
-	token := u.ID.Hex() + "." + time.Now().UTC().Format("20060102150405")
-	dto := models.UserDTO{ ID: u.ID.Hex(), Name: u.Name, Email: u.Email, RoleID: u.RoleID.Hex(), RoleName: "", Phone: u.Phone, Gender: u.Gender, DateOfBirth: u.DateOfBirth, IsActive: u.IsActive, IsDeleted: u.IsDeleted, PrefServiceMode: u.PrefServiceMode, PrefLanguage: u.PrefLanguage, CreatedAt: u.CreatedAt, UpdatedAt: u.UpdatedAt }
-	return &models.LoginResponse{ Token: token, User: dto }, nil
-} 
\ No newline at end of file
+	if !u.IsActive || u.IsDeleted { return nil, utils.Unauthorized("USER_INACTIVE", "User is inactive", nil) }
+	tenantID, err := s.resolveTenantID(ctx, tenantKey)
+	if err != nil { return nil, err }
+	resp, _, err := s.issue(ctx, u, tenantID, primitive.NewObjectID())
+	return resp, err
+}
+
+// Refresh exchanges a refresh token for a new access/refresh pair. The presented token is revoked (rotation);
+// presenting an already rotated token is treated as theft and revokes the whole session.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*models.LoginResponse, error) {
+	if refreshToken == "" { return nil, utils.BadRequest("VALIDATION_ERROR", "refresh_token is required", nil) }
+	rec, err := s.tokens.GetByHash(ctx, utils.HashOpaqueToken(refreshToken))
+	if err != nil { return nil, utils.Unauthorized("INVALID_REFRESH_TOKEN", "Invalid refresh token", err) }
+	if rec.RevokedAt != nil {
+		_ = s.tokens.RevokeFamily(ctx, rec.FamilyID)
+		return nil, utils.Unauthorized("REFRESH_TOKEN_REUSED", "Refresh token has already been used", nil)
+	}
+	if time.Now().UTC().After(rec.ExpiresAt) { return nil, utils.Unauthorized("REFRESH_TOKEN_EXPIRED", "Refresh token expired", nil) }
+	u, err := s.users.Get(ctx, rec.UserID)
+	if err != nil { return nil, utils.Unauthorized("UNAUTHORIZED", "User not found", err) }
+	if !u.IsActive || u.IsDeleted {
+		_ = s.tokens.RevokeAllForUser(ctx, u.ID)
+		return nil, utils.Unauthorized("USER_INACTIVE", "User is inactive", nil)
+	}
+	resp, newRec, err := s.issue(ctx, u, rec.TenantID, rec.FamilyID)
+	if err != nil { return nil, err }
+	ok, err := s.tokens.Rotate(ctx, rec.ID, newRec.ID)
+	if err != nil { return nil, utils.Internal("TOKEN_ISSUE_FAILED", "Unable to issue token", err) }
+	if !ok {
+		// lost a race with another refresh of the same token
+		_ = s.tokens.RevokeFamily(ctx, rec.FamilyID)
+		return nil, utils.Unauthorized("REFRESH_TOKEN_REUSED", "Refresh token has already been used", nil)
+	}
+	return resp, nil
+}
+
+// Logout revokes the session the refresh token belongs to, or every session of its user when all is set.
+// Unknown tokens are ignored so logout is idempotent.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string, all bool) error {
+	if refreshToken == "" { return utils.BadRequest("VALIDATION_ERROR", "refresh_token is required", nil) }
+	rec, err := s.tokens.GetByHash(ctx, utils.HashOpaqueToken(refreshToken))
+	if err != nil { return nil }
+	if all { err = s.tokens.RevokeAllForUser(ctx, rec.UserID) } else { err = s.tokens.RevokeFamily(ctx, rec.FamilyID) }
+	if err != nil { return utils.Internal("LOGOUT_FAILED", "Unable to revoke session", err) }
+	return nil
+}
+
+func (s *AuthService) issue(ctx context.Context, u *models.User, tenantID string, familyID primitive.ObjectID) (*models.LoginResponse, *models.RefreshToken, error) {
+	access, exp, err := utils.SignAccessToken(s.secret, utils.AccessClaims{UserID: u.ID.Hex(), RoleID: u.RoleID.Hex(), TenantID: tenantID}, s.accessTTL)
+	if err != nil { return nil, nil, utils.Internal("TOKEN_ISSUE_FAILED", "Unable to issue token", err) }
+	refresh, hash, err := utils.NewOpaqueToken()
+	if err != nil { return nil, nil, utils.Internal("TOKEN_ISSUE_FAILED", "Unable to issue token", err) }
+	rec := &models.RefreshToken{UserID: u.ID, TenantID: tenantID, FamilyID: familyID, TokenHash: hash, ExpiresAt: time.Now().UTC().Add(s.refreshTTL)}
+	if _, err := s.tokens.Create(ctx, rec); err != nil { return nil, nil, utils.Internal("TOKEN_ISSUE_FAILED", "Unable to issue token", err) }
+	dto, _, _ := s.BuildUserDTO(ctx, u)
+	return &models.LoginResponse{ Token: access, ExpiresAt: exp, RefreshToken: refresh, RefreshExpiresAt: rec.ExpiresAt, User: dto }, rec, nil
+}
+
+func (s *AuthService) resolveTenantID(ctx context.Context, key string) (string, error) {
+	if key == "" { return "", nil }
+	if oid, err := primitive.ObjectIDFromHex(key); err == nil {
+		if t, err := s.tenants.Get(ctx, oid); err == nil { return t.ID.Hex(), nil }
+	}
+	t, err := s.tenants.GetBySubdomain(ctx, key)
+	if err != nil { return "", utils.BadRequest("TENANT_NOT_FOUND", "Tenant not found", err) }
+	return t.ID.Hex(), nil
+}
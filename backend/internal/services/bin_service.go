@@ -0,0 +1,328 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// BinService keeps the location hierarchy of stores and warehouses (zone / aisle / rack / bin) and the stock in each
+// bin. StockService posts every balance change to it: increases that name a bin go into it, others stay unbinned
+// until a putaway places them; decreases come out of the named bin, then the other bins in path order, then the
+// unbinned stock. The bins of a store never hold more than its balance.
+type BinService struct {
+	locations  *repositories.WarehouseLocationRepository
+	repo       *repositories.BinStockRepository
+	warehouses *repositories.WarehouseRepository
+	stores     *repositories.StoreRepository
+	products   *repositories.ProductRepository
+}
+
+func NewBinService(locations *repositories.WarehouseLocationRepository, repo *repositories.BinStockRepository, warehouses *repositories.WarehouseRepository, stores *repositories.StoreRepository, products *repositories.ProductRepository) *BinService {
+	return &BinService{locations: locations, repo: repo, warehouses: warehouses, stores: stores, products: products}
+}
+
+// Locations lists the locations of a store or warehouse in path order, optionally of one kind.
+func (s *BinService) Locations(ctx context.Context, tenantID, warehouseID, kind string) ([]models.WarehouseLocation, error) {
+	items, err := s.locations.List(ctx, tenantID, warehouseID, strings.ToLower(kind))
+	if err != nil { return nil, utils.Internal("LOCATION_LIST_FAILED", "Unable to list locations", err) }
+	return items, nil
+}
+
+func (s *BinService) Location(ctx context.Context, tenantID, id string) (*models.WarehouseLocation, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid location id", err) }
+	m, err := s.locations.Get(ctx, oid, tenantID)
+	if err != nil { return nil, utils.NotFound("LOCATION_NOT_FOUND", "Location not found", err) }
+	return m, nil
+}
+
+// CreateLocation adds a location to a store or warehouse, under a parent of a higher level. Its code must be unique
+// among its siblings and may not contain "/", which separates the levels of a path.
+func (s *BinService) CreateLocation(ctx context.Context, tenantID, warehouseID string, body models.CreateWarehouseLocationRequest) (*models.WarehouseLocation, error) {
+	if err := s.place(ctx, tenantID, warehouseID); err != nil { return nil, err }
+	kind := strings.ToLower(strings.TrimSpace(body.Kind))
+	if models.LocationLevel(kind) == 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Kind must be zone, aisle, rack or bin", nil) }
+	code, err := locationCode(body.Code)
+	if err != nil { return nil, err }
+	m := &models.WarehouseLocation{ TenantID: tenantID, WarehouseID: warehouseID, Kind: kind, Code: code, Name: ifEmpty(strings.TrimSpace(body.Name), code), Path: code, IsActive: true }
+	if strings.TrimSpace(body.ParentID) != "" {
+		parent, err := s.Location(ctx, tenantID, body.ParentID)
+		if err != nil { return nil, err }
+		if parent.WarehouseID != warehouseID { return nil, utils.BadRequest("LOCATION_NOT_FOUND", "Parent location belongs to another warehouse", nil) }
+		if models.LocationLevel(parent.Kind) >= models.LocationLevel(kind) { return nil, utils.BadRequest("INVALID_LOCATION_PARENT", "A "+kind+" cannot sit under a "+parent.Kind, nil) }
+		m.ParentID, m.Path = &parent.ID, parent.Path+"/"+code
+	}
+	created, err := s.locations.Create(ctx, m)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) { return nil, utils.Conflict("LOCATION_EXISTS", "A location with this code already exists here", err) }
+		return nil, utils.Internal("LOCATION_CREATE_FAILED", "Unable to create location", err)
+	}
+	return created, nil
+}
+
+// UpdateLocation renames or (de)activates a location. Its code can only change while nothing sits under it and, for
+// a bin, while it is empty.
+func (s *BinService) UpdateLocation(ctx context.Context, tenantID, id string, body models.UpdateWarehouseLocationRequest) (*models.WarehouseLocation, error) {
+	m, err := s.Location(ctx, tenantID, id)
+	if err != nil { return nil, err }
+	update := bson.M{}
+	if body.Name != nil { update["name"] = strings.TrimSpace(*body.Name) }
+	if body.IsActive != nil { update["is_active"] = *body.IsActive }
+	if body.Code != nil {
+		code, err := locationCode(*body.Code)
+		if err != nil { return nil, err }
+		if code != m.Code {
+			if err := s.unused(ctx, m); err != nil { return nil, err }
+			update["code"], update["path"] = code, strings.TrimSuffix(m.Path, m.Code)+code
+		}
+	}
+	out, err := s.locations.Update(ctx, m.ID, tenantID, update)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) { return nil, utils.Conflict("LOCATION_EXISTS", "A location with this code already exists here", err) }
+		return nil, utils.Internal("LOCATION_UPDATE_FAILED", "Unable to update location", err)
+	}
+	if path, ok := update["path"].(string); ok && m.Kind == models.LocationBin {
+		if err := s.repo.SetPath(ctx, tenantID, m.ID, path); err != nil { return nil, utils.Internal("LOCATION_UPDATE_FAILED", "Unable to update bin stock", err) }
+	}
+	return out, nil
+}
+
+// DeleteLocation removes a location that has nothing under it and, for a bin, holds no stock.
+func (s *BinService) DeleteLocation(ctx context.Context, tenantID, id string) error {
+	m, err := s.Location(ctx, tenantID, id)
+	if err != nil { return err }
+	if err := s.unused(ctx, m); err != nil { return err }
+	if err := s.locations.Delete(ctx, m.ID, tenantID); err != nil { return utils.Internal("LOCATION_DELETE_FAILED", "Unable to delete location", err) }
+	if m.Kind == models.LocationBin {
+		if err := s.repo.DeleteBin(ctx, tenantID, m.ID); err != nil { return utils.Internal("LOCATION_DELETE_FAILED", "Unable to delete bin stock", err) }
+	}
+	return nil
+}
+
+// Stock reports what the bins of a store or warehouse hold, in bin path order, optionally only under one location
+// and of one product.
+func (s *BinService) Stock(ctx context.Context, tenantID, warehouseID, locationID, productID string) ([]models.BinStockLine, error) {
+	var binIDs []primitive.ObjectID
+	if strings.TrimSpace(locationID) != "" {
+		bins, err := s.Under(ctx, tenantID, warehouseID, []string{locationID})
+		if err != nil { return nil, err }
+		binIDs = make([]primitive.ObjectID, 0, len(bins))
+		for _, b := range bins { binIDs = append(binIDs, b.ID) }
+	}
+	var pid *primitive.ObjectID
+	if strings.TrimSpace(productID) != "" {
+		oid, err := primitive.ObjectIDFromHex(productID)
+		if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid product id", err) }
+		pid = &oid
+	}
+	rows, err := s.repo.List(ctx, tenantID, warehouseID, binIDs, pid)
+	if err != nil { return nil, utils.Internal("BIN_STOCK_READ_FAILED", "Unable to read bin stock", err) }
+	products := map[primitive.ObjectID]*models.Product{}
+	out := make([]models.BinStockLine, 0, len(rows))
+	for _, r := range rows {
+		l := models.BinStockLine{ BinID: r.BinID.Hex(), BinPath: r.BinPath, ProductID: r.ProductID.Hex(), Qty: r.Qty }
+		p, ok := products[r.ProductID]
+		if !ok {
+			p, _ = s.products.Get(ctx, r.ProductID, tenantID)
+			products[r.ProductID] = p
+		}
+		if p != nil { l.ProductName, l.ProductSKU, l.Unit = p.Name, p.SKU, p.Unit }
+		out = append(out, l)
+	}
+	return out, nil
+}
+
+// Get loads a bin by id, checking that it belongs to the given store.
+func (s *BinService) Get(ctx context.Context, tenantID, binID, shopID string) (*models.WarehouseLocation, error) {
+	oid, err := primitive.ObjectIDFromHex(binID)
+	if err != nil { return nil, utils.BadRequest("INVALID_BIN_ID", "Invalid bin id", err) }
+	return s.bin(ctx, tenantID, oid, shopID)
+}
+
+// Under returns the bins at or under the given locations of a store, in path order.
+func (s *BinService) Under(ctx context.Context, tenantID, shopID string, locationIDs []string) ([]models.WarehouseLocation, error) {
+	paths := make([]string, 0, len(locationIDs))
+	for _, id := range locationIDs {
+		l, err := s.Location(ctx, tenantID, id)
+		if err != nil { return nil, err }
+		if l.WarehouseID != shopID { return nil, utils.BadRequest("LOCATION_NOT_FOUND", "Location belongs to another warehouse", nil) }
+		paths = append(paths, l.Path)
+	}
+	bins, err := s.locations.Bins(ctx, tenantID, shopID, paths)
+	if err != nil { return nil, utils.Internal("LOCATION_LIST_FAILED", "Unable to list bins", err) }
+	return bins, nil
+}
+
+// Contents returns what the given bins hold, in bin path order.
+func (s *BinService) Contents(ctx context.Context, tenantID, shopID string, bins []models.WarehouseLocation) ([]models.BinStock, error) {
+	ids := make([]primitive.ObjectID, 0, len(bins))
+	for _, b := range bins { ids = append(ids, b.ID) }
+	rows, err := s.repo.List(ctx, tenantID, shopID, ids, nil)
+	if err != nil { return nil, utils.Internal("BIN_STOCK_READ_FAILED", "Unable to read bin stock", err) }
+	return rows, nil
+}
+
+// Qty returns the quantity of a product in a bin.
+func (s *BinService) Qty(ctx context.Context, tenantID string, binID, productID primitive.ObjectID) (float64, error) {
+	qty, err := s.repo.Qty(ctx, tenantID, binID, productID)
+	if err != nil { return 0, utils.Internal("BIN_STOCK_READ_FAILED", "Unable to read bin stock", err) }
+	return qty, nil
+}
+
+// HasBins reports whether a store keeps its stock in bins.
+func (s *BinService) HasBins(ctx context.Context, tenantID, shopID string) (bool, error) {
+	ok, err := s.locations.HasBins(ctx, tenantID, shopID)
+	if err != nil { return false, utils.Internal("LOCATION_LIST_FAILED", "Unable to list bins", err) }
+	return ok, nil
+}
+
+// Plan says where qty units of a product are picked from in a store without taking them: its bins in path order,
+// then the unbinned stock. It returns what the store does not have on top.
+func (s *BinService) Plan(ctx context.Context, tenantID, shopID string, productID primitive.ObjectID, qty, onHand float64) ([]models.BinPick, float64, error) {
+	rows, err := s.repo.ForProduct(ctx, tenantID, shopID, productID)
+	if err != nil { return nil, 0, utils.Internal("BIN_STOCK_READ_FAILED", "Unable to read bin stock", err) }
+	picks := []models.BinPick{}
+	binned := 0.0
+	for _, r := range rows {
+		binned += r.Qty
+		if qty <= 0 { continue }
+		n := r.Qty
+		if n > qty { n = qty }
+		picks = append(picks, models.BinPick{ BinID: r.BinID.Hex(), BinPath: r.BinPath, Qty: n })
+		qty = models.RoundQty(qty - n)
+	}
+	if unbinned := models.RoundQty(onHand - binned); qty > 0 && unbinned > 0 {
+		n := unbinned
+		if n > qty { n = qty }
+		picks = append(picks, models.BinPick{ Qty: n })
+		qty = models.RoundQty(qty - n)
+	}
+	return picks, qty, nil
+}
+
+// Suggest picks the bin a product is best put away into in a store: the bin named by the product's location there,
+// else the bin already holding the most of it, else the first empty bin. occupied holds the store's non-empty bins
+// (see Occupied); an empty bin suggested is added to it, so the next product gets another. It returns nil when the
+// store has no active bin to offer.
+func (s *BinService) Suggest(ctx context.Context, tenantID, shopID string, p *models.Product, occupied map[primitive.ObjectID]bool) (*models.WarehouseLocation, error) {
+	for _, w := range p.Warehouses {
+		if w.WarehouseID.Hex() != shopID || strings.TrimSpace(w.Location) == "" { continue }
+		if b, err := s.locations.FindBin(ctx, tenantID, shopID, strings.TrimSpace(w.Location)); err == nil && b.IsActive { return b, nil }
+	}
+	rows, err := s.repo.ForProduct(ctx, tenantID, shopID, p.ID)
+	if err != nil { return nil, utils.Internal("BIN_STOCK_READ_FAILED", "Unable to read bin stock", err) }
+	var best *models.BinStock
+	for i := range rows {
+		if best == nil || rows[i].Qty > best.Qty { best = &rows[i] }
+	}
+	if best != nil {
+		if b, err := s.locations.Get(ctx, best.BinID, tenantID); err == nil && b.IsActive { return b, nil }
+	}
+	bins, err := s.locations.List(ctx, tenantID, shopID, models.LocationBin)
+	if err != nil { return nil, utils.Internal("LOCATION_LIST_FAILED", "Unable to list bins", err) }
+	for i := range bins {
+		if bins[i].IsActive && !occupied[bins[i].ID] {
+			occupied[bins[i].ID] = true
+			return &bins[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Occupied returns the ids of the bins of a store that hold anything.
+func (s *BinService) Occupied(ctx context.Context, tenantID, shopID string) (map[primitive.ObjectID]bool, error) {
+	out, err := s.repo.Occupied(ctx, tenantID, shopID)
+	if err != nil { return nil, utils.Internal("BIN_STOCK_READ_FAILED", "Unable to read bin stock", err) }
+	return out, nil
+}
+
+// Binned sums what the bins of a store hold of a product; the rest of its balance is unbinned.
+func (s *BinService) Binned(ctx context.Context, tenantID, shopID string, productID primitive.ObjectID) (float64, error) {
+	qty, err := s.repo.Binned(ctx, tenantID, shopID, productID)
+	if err != nil { return 0, utils.Internal("BIN_STOCK_READ_FAILED", "Unable to read bin stock", err) }
+	return qty, nil
+}
+
+// put places unbinned units of a product into a bin; the store's balance does not change.
+func (s *BinService) put(ctx context.Context, tenantID, shopID string, bin *models.WarehouseLocation, productID primitive.ObjectID, qty float64) error {
+	if err := s.repo.Inc(ctx, tenantID, shopID, bin, productID, qty); err != nil { return utils.Internal("BIN_STOCK_UPDATE_FAILED", "Unable to update bin stock", err) }
+	return nil
+}
+
+// post applies a change of delta units of a product in a store to its bins. It runs inside the stock transaction.
+func (s *BinService) post(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, delta float64, src models.StockSource) error {
+	switch {
+	case delta > 0 && !src.Bin.IsZero():
+		b, err := s.bin(ctx, tenantID, src.Bin, shopID)
+		if err != nil { return err }
+		return s.put(ctx, tenantID, shopID, b, productID, delta)
+	case delta < 0:
+		return s.draw(ctx, tenantID, productID, shopID, -delta, src.Bin)
+	}
+	return nil
+}
+
+// draw takes up to qty units out of the bins of a store holding the product, the preferred bin first and the others
+// in path order. Units the bins do not cover come out of the unbinned stock.
+func (s *BinService) draw(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, qty float64, prefer primitive.ObjectID) error {
+	rows, err := s.repo.ForProduct(ctx, tenantID, shopID, productID)
+	if err != nil { return utils.Internal("BIN_STOCK_UPDATE_FAILED", "Unable to read bin stock", err) }
+	if !prefer.IsZero() {
+		for i, r := range rows {
+			if r.BinID == prefer { rows = append(append([]models.BinStock{r}, rows[:i]...), rows[i+1:]...); break }
+		}
+	}
+	for _, r := range rows {
+		if qty <= 0 { break }
+		n := r.Qty
+		if n > qty { n = qty }
+		ok, err := s.repo.Take(ctx, tenantID, r.BinID, productID, n)
+		if err != nil { return utils.Internal("BIN_STOCK_UPDATE_FAILED", "Unable to update bin stock", err) }
+		if !ok { return utils.Conflict("BIN_STOCK_CHANGED", "Bin stock changed concurrently, please retry", nil) }
+		qty = models.RoundQty(qty - n)
+	}
+	return nil
+}
+
+func (s *BinService) bin(ctx context.Context, tenantID string, id primitive.ObjectID, shopID string) (*models.WarehouseLocation, error) {
+	b, err := s.locations.Get(ctx, id, tenantID)
+	if err != nil { return nil, utils.NotFound("BIN_NOT_FOUND", "Bin not found", err) }
+	if b.Kind != models.LocationBin { return nil, utils.BadRequest("NOT_A_BIN", "Stock is kept in bins only, not in a "+b.Kind, nil) }
+	if b.WarehouseID != shopID { return nil, utils.BadRequest("BIN_NOT_FOUND", "Bin belongs to another store", nil) }
+	return b, nil
+}
+
+// place checks that a store or warehouse of the tenant has the given id.
+func (s *BinService) place(ctx context.Context, tenantID, warehouseID string) error {
+	if strings.TrimSpace(warehouseID) == "" { return utils.BadRequest("STORE_REQUIRED", "Store is required", nil) }
+	if _, err := s.warehouses.GetByIDHex(ctx, warehouseID, tenantID); err == nil { return nil }
+	if _, err := s.stores.GetByIDHex(ctx, warehouseID, tenantID); err == nil { return nil }
+	return utils.NotFound("WAREHOUSE_NOT_FOUND", "Store or warehouse not found", nil)
+}
+
+// unused refuses changes to a location that has locations under it or, for a bin, holds stock.
+func (s *BinService) unused(ctx context.Context, m *models.WarehouseLocation) error {
+	children, err := s.locations.HasChildren(ctx, m.ID, m.TenantID)
+	if err != nil { return utils.Internal("LOCATION_READ_FAILED", "Unable to read locations", err) }
+	if children { return utils.Conflict("LOCATION_IN_USE", "Move or delete the locations under it first", nil) }
+	if m.Kind != models.LocationBin { return nil }
+	stocked, err := s.repo.HasStock(ctx, m.TenantID, m.ID)
+	if err != nil { return utils.Internal("LOCATION_READ_FAILED", "Unable to read bin stock", err) }
+	if stocked { return utils.Conflict("LOCATION_IN_USE", "The bin still holds stock", nil) }
+	return nil
+}
+
+func locationCode(code string) (string, error) {
+	code = strings.TrimSpace(code)
+	if code == "" { return "", utils.BadRequest("VALIDATION_ERROR", "Code is required", nil) }
+	if strings.Contains(code, "/") { return "", utils.BadRequest("VALIDATION_ERROR", "Code cannot contain \"/\"", nil) }
+	return code, nil
+}
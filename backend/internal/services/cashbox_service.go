@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// cashMethods are the payment methods reported per shift, in report order
+var cashMethods = []string{models.SalePaymentCash, models.SalePaymentCard, models.SalePaymentTransfer}
+
+type CashboxService struct {
+	shifts     *repositories.CashShiftRepository
+	operations *repositories.CashOperationRepository
+	sales      *repositories.SaleRepository
+	returns    *repositories.SaleReturnRepository
+	debts      *repositories.CustomerDebtEntryRepository
+	stores     *repositories.StoreRepository
+	tx         *repositories.Tx
+}
+
+func NewCashboxService(shifts *repositories.CashShiftRepository, operations *repositories.CashOperationRepository, sales *repositories.SaleRepository, returns *repositories.SaleReturnRepository, debts *repositories.CustomerDebtEntryRepository, stores *repositories.StoreRepository, tx *repositories.Tx) *CashboxService {
+	return &CashboxService{shifts: shifts, operations: operations, sales: sales, returns: returns, debts: debts, stores: stores, tx: tx}
+}
+
+func (s *CashboxService) List(ctx context.Context, f models.CashShiftFilterRequest, tenantID string) ([]models.CashShift, int64, error) {
+	var fromPtr, toPtr *time.Time
+	if strings.TrimSpace(f.DateFrom) != "" { if t, err := time.Parse(time.RFC3339, f.DateFrom); err == nil { fromPtr = &t } }
+	if strings.TrimSpace(f.DateTo) != "" { if t, err := time.Parse(time.RFC3339, f.DateTo); err == nil { toPtr = &t } }
+	items, total, err := s.shifts.List(ctx, repositories.CashShiftListParams{
+		TenantID: tenantID, Page: int64(ifZero(f.Page, 1)), Limit: int64(ifZero(f.Limit, 20)),
+		ShopID: f.ShopID, CashboxID: f.CashboxID, Status: strings.ToUpper(f.Status), UserID: f.UserID, DateFrom: fromPtr, DateTo: toPtr,
+	})
+	if err != nil { return nil, 0, utils.Internal("CASH_SHIFT_LIST_FAILED", "Unable to list cash shifts", err) }
+	return items, total, nil
+}
+
+func (s *CashboxService) Get(ctx context.Context, id string, tenantID string) (*models.CashShift, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid shift id", err) }
+	m, err := s.shifts.Get(ctx, oid, tenantID)
+	if err != nil { return nil, utils.NotFound("CASH_SHIFT_NOT_FOUND", "Cash shift not found", err) }
+	return m, nil
+}
+
+// Current returns the user's open shift in a store.
+func (s *CashboxService) Current(ctx context.Context, shopID string, tenantID string, actor models.InventoryUser) (*models.CashShift, error) {
+	if strings.TrimSpace(shopID) == "" { return nil, utils.BadRequest("STORE_REQUIRED", "Store is required", nil) }
+	m, err := s.shifts.GetOpen(ctx, tenantID, shopID, actor.ID)
+	if err != nil { return nil, utils.NotFound("SHIFT_NOT_OPEN", "No open cash shift", err) }
+	return m, nil
+}
+
+func (s *CashboxService) Open(ctx context.Context, body models.OpenCashShiftRequest, tenantID string, actor models.InventoryUser) (*models.CashShift, error) {
+	if strings.TrimSpace(body.ShopID) == "" { return nil, utils.BadRequest("STORE_REQUIRED", "Store is required", nil) }
+	if body.OpeningFloat < 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Opening float cannot be negative", nil) }
+	now := time.Now().UTC()
+	m := &models.CashShift{ TenantID: tenantID, ShopID: body.ShopID, CashboxID: ifEmpty(strings.TrimSpace(body.CashboxID), "main"), Status: "OPEN", OpeningFloat: roundMoney(body.OpeningFloat), OpenedBy: actor, OpenedAt: now }
+	if st, err := s.stores.GetByIDHex(ctx, body.ShopID, tenantID); err == nil { m.ShopName = st.Title }
+	created, err := s.shifts.Create(ctx, m)
+	if mongo.IsDuplicateKeyError(err) { return nil, utils.Conflict("SHIFT_ALREADY_OPEN", "This cashbox or cashier already has an open shift", err) }
+	if err != nil { return nil, utils.Internal("CASH_SHIFT_OPEN_FAILED", "Unable to open cash shift", err) }
+	return created, nil
+}
+
+// AddOperation records cash put into or taken out of the drawer of an open shift. A cash-out cannot exceed the
+// cash expected in the drawer.
+func (s *CashboxService) AddOperation(ctx context.Context, shiftID string, body models.CreateCashOperationRequest, tenantID string, actor models.InventoryUser) (*models.CashOperation, error) {
+	if body.Type != models.CashOperationIn && body.Type != models.CashOperationOut { return nil, utils.BadRequest("VALIDATION_ERROR", "Type must be cash_in or cash_out", nil) }
+	if body.Amount <= 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Amount must be greater than 0", nil) }
+	if strings.TrimSpace(body.Reason) == "" { return nil, utils.BadRequest("VALIDATION_ERROR", "Reason is required", nil) }
+	var out *models.CashOperation
+	err := runTx(ctx, s.tx, func(ctx context.Context) error {
+		shift, err := s.Get(ctx, shiftID, tenantID)
+		if err != nil { return err }
+		if shift.Status != "OPEN" { return utils.BadRequest("SHIFT_NOT_OPEN", "Cash shift is closed", nil) }
+		if body.Type == models.CashOperationOut {
+			rep, err := s.report(ctx, shift, "X", nil)
+			if err != nil { return err }
+			if body.Amount > rep.Methods[0].Expected+0.005 { return utils.BadRequest("CASH_INSUFFICIENT", "Not enough cash in the drawer", nil) }
+		}
+		ok, err := s.shifts.CountOperation(ctx, shift.ID, tenantID)
+		if err != nil { return utils.Internal("CASH_OPERATION_FAILED", "Unable to record cash operation", err) }
+		if !ok { return utils.BadRequest("SHIFT_NOT_OPEN", "Cash shift is closed", nil) }
+		out, err = s.operations.Create(ctx, &models.CashOperation{ TenantID: tenantID, ShiftID: shift.ID, ShopID: shift.ShopID, Type: body.Type, Amount: roundMoney(body.Amount), Reason: strings.TrimSpace(body.Reason), CreatedBy: actor })
+		if err != nil { return utils.Internal("CASH_OPERATION_FAILED", "Unable to record cash operation", err) }
+		return nil
+	})
+	return out, err
+}
+
+func (s *CashboxService) Operations(ctx context.Context, shiftID string, tenantID string) ([]models.CashOperation, error) {
+	shift, err := s.Get(ctx, shiftID, tenantID)
+	if err != nil { return nil, err }
+	items, err := s.operations.ListByShift(ctx, tenantID, shift.ID)
+	if err != nil { return nil, utils.Internal("CASH_OPERATION_LIST_FAILED", "Unable to list cash operations", err) }
+	return items, nil
+}
+
+// XReport is an interim report of an open shift; for a closed shift it returns the Z report.
+func (s *CashboxService) XReport(ctx context.Context, shiftID string, tenantID string) (*models.CashShiftReport, error) {
+	shift, err := s.Get(ctx, shiftID, tenantID)
+	if err != nil { return nil, err }
+	if shift.ZReport != nil { return shift.ZReport, nil }
+	return s.report(ctx, shift, "X", nil)
+}
+
+func (s *CashboxService) ZReport(ctx context.Context, shiftID string, tenantID string) (*models.CashShiftReport, error) {
+	shift, err := s.Get(ctx, shiftID, tenantID)
+	if err != nil { return nil, err }
+	if shift.ZReport == nil { return nil, utils.NotFound("SHIFT_NOT_CLOSED", "The Z report is issued when the shift is closed", nil) }
+	return shift.ZReport, nil
+}
+
+// Close compares the counted money with the expected amounts, stores the Z report and closes the shift. A sale,
+// return or operation recorded while closing makes the attempt start over, so the Z report always covers the whole shift.
+func (s *CashboxService) Close(ctx context.Context, shiftID string, body models.CloseCashShiftRequest, tenantID string, actor models.InventoryUser) (*models.CashShift, error) {
+	counted := map[string]float64{}
+	for _, c := range body.Counted {
+		method := strings.ToLower(strings.TrimSpace(c.Method))
+		if c.Amount < 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Counted amount cannot be negative", nil) }
+		counted[method] += c.Amount
+	}
+	for attempt := 0; attempt < 3; attempt++ {
+		shift, err := s.Get(ctx, shiftID, tenantID)
+		if err != nil { return nil, err }
+		if shift.Status != "OPEN" { return shift, nil }
+		rep, err := s.report(ctx, shift, "Z", counted)
+		if err != nil { return nil, err }
+		now := time.Now().UTC()
+		ok, err := s.shifts.Close(ctx, shift, bson.M{"closed_by": actor, "closed_at": now, "comment": body.Comment, "z_report": rep})
+		if err != nil { return nil, utils.Internal("CASH_SHIFT_CLOSE_FAILED", "Unable to close cash shift", err) }
+		if ok { return s.Get(ctx, shiftID, tenantID) }
+	}
+	return nil, utils.Conflict("SHIFT_BUSY", "The shift is still taking sales; try closing again", nil)
+}
+
+// report sums the shift's completed sales, refunds and debt repayments by payment method and its cash operations. counted is nil for an X report.
+func (s *CashboxService) report(ctx context.Context, shift *models.CashShift, typ string, counted map[string]float64) (*models.CashShiftReport, error) {
+	sales, err := s.sales.ListByShift(ctx, shift.TenantID, shift.ID.Hex())
+	if err != nil { return nil, utils.Internal("CASH_REPORT_FAILED", "Unable to build shift report", err) }
+	returns, err := s.returns.ListByShift(ctx, shift.TenantID, shift.ID.Hex())
+	if err != nil { return nil, utils.Internal("CASH_REPORT_FAILED", "Unable to build shift report", err) }
+	repayments, err := s.debts.ListRepaymentsByShift(ctx, shift.TenantID, shift.ID.Hex())
+	if err != nil { return nil, utils.Internal("CASH_REPORT_FAILED", "Unable to build shift report", err) }
+	ops, err := s.operations.ListByShift(ctx, shift.TenantID, shift.ID)
+	if err != nil { return nil, utils.Internal("CASH_REPORT_FAILED", "Unable to build shift report", err) }
+
+	rep := &models.CashShiftReport{ Type: typ, ShiftID: shift.ID.Hex(), ShiftNumber: shift.Number, ShopName: shift.ShopName, CashboxID: shift.CashboxID, Cashier: shift.OpenedBy, OpenedAt: shift.OpenedAt, GeneratedAt: time.Now().UTC(), OpeningFloat: shift.OpeningFloat }
+	byMethod := map[string]float64{}
+	for _, sale := range sales {
+		rep.SalesCount++
+		rep.SalesTotal += sale.Total
+		rep.DiscountTotal += sale.DiscountTotal
+		for _, p := range sale.Payments { byMethod[p.Method] += p.Amount }
+		// change is handed back from the drawer
+		byMethod[models.SalePaymentCash] -= sale.ChangeAmount
+	}
+	refunds := map[string]float64{}
+	for _, r := range returns {
+		rep.ReturnsCount++
+		for _, p := range r.Refunds {
+			refunds[p.Method] += p.Amount
+			rep.RefundsTotal += p.Amount
+		}
+	}
+	repaid := map[string]float64{}
+	for _, e := range repayments {
+		// repayments lower the debt, so their amounts are negative
+		repaid[e.Method] -= e.Amount
+		rep.Repayments -= e.Amount
+	}
+	for _, op := range ops {
+		if op.Type == models.CashOperationIn { rep.CashIn += op.Amount } else { rep.CashOut += op.Amount }
+	}
+
+	rep.Methods = make([]models.CashMethodSummary, 0, len(cashMethods))
+	for _, method := range cashMethods {
+		row := models.CashMethodSummary{ Method: method, Sales: roundMoney(byMethod[method]), Refunds: roundMoney(refunds[method]), Repayments: roundMoney(repaid[method]) }
+		row.Expected = roundMoney(row.Sales - row.Refunds + row.Repayments)
+		if method == models.SalePaymentCash { row.Expected = roundMoney(shift.OpeningFloat + row.Sales - row.Refunds + row.Repayments + rep.CashIn - rep.CashOut) }
+		if counted != nil {
+			row.Counted = roundMoney(counted[method])
+			row.Difference = roundMoney(row.Counted - row.Expected)
+		}
+		rep.ExpectedTotal += row.Expected
+		rep.CountedTotal += row.Counted
+		rep.Methods = append(rep.Methods, row)
+	}
+	rep.SalesTotal = roundMoney(rep.SalesTotal)
+	rep.DiscountTotal = roundMoney(rep.DiscountTotal)
+	rep.RefundsTotal = roundMoney(rep.RefundsTotal)
+	rep.Repayments = roundMoney(rep.Repayments)
+	rep.CashIn = roundMoney(rep.CashIn)
+	rep.CashOut = roundMoney(rep.CashOut)
+	rep.ExpectedTotal = roundMoney(rep.ExpectedTotal)
+	rep.CountedTotal = roundMoney(rep.CountedTotal)
+	if counted != nil { rep.Difference = roundMoney(rep.CountedTotal - rep.ExpectedTotal) }
+	return rep, nil
+}
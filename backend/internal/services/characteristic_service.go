@@ -19,7 +19,7 @@ func NewCharacteristicService(repo *repositories.CharacteristicRepository) *Char
 	return &CharacteristicService{repo: repo}
 }
 
-func (s *CharacteristicService) List(ctx context.Context, page, limit int64, search string, isActive *bool, characteristicType string) ([]models.CharacteristicDTO, int64, error) {
+func (s *CharacteristicService) List(ctx context.Context, page, limit int64, search string, isActive *bool, characteristicType string, tenantID string) ([]models.CharacteristicDTO, int64, error) {
 	items, total, err := s.repo.List(ctx, repositories.CharacteristicListParams{
 		Page:     page,
 		Limit:    limit,
@@ -27,6 +27,7 @@ func (s *CharacteristicService) List(ctx context.Context, page, limit int64, sea
 		Search:   search,
 		IsActive: isActive,
 		Type:     characteristicType,
+		TenantID: tenantID,
 	})
 	if err != nil {
 		return nil, 0, utils.Internal("CHARACTERISTIC_LIST_FAILED", "Unable to list characteristics", err)
@@ -40,13 +41,13 @@ func (s *CharacteristicService) List(ctx context.Context, page, limit int64, sea
 	return out, total, nil
 }
 
-func (s *CharacteristicService) Get(ctx context.Context, id string) (*models.CharacteristicDTO, error) {
+func (s *CharacteristicService) Get(ctx context.Context, id string, tenantID string) (*models.CharacteristicDTO, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, utils.BadRequest("INVALID_ID", "Invalid characteristic id", nil)
 	}
 
-	m, err := s.repo.Get(ctx, oid)
+	m, err := s.repo.Get(ctx, oid, tenantID)
 	if err != nil {
 		return nil, utils.NotFound("CHARACTERISTIC_NOT_FOUND", "Characteristic not found", err)
 	}
@@ -55,7 +56,7 @@ func (s *CharacteristicService) Get(ctx context.Context, id string) (*models.Cha
 	return &dto, nil
 }
 
-func (s *CharacteristicService) Create(ctx context.Context, body models.CharacteristicCreate) (*models.CharacteristicDTO, error) {
+func (s *CharacteristicService) Create(ctx context.Context, body models.CharacteristicCreate, tenantID string) (*models.CharacteristicDTO, error) {
 	if body.Name == "" {
 		return nil, utils.BadRequest("VALIDATION_ERROR", "Characteristic name is required", nil)
 	}
@@ -75,6 +76,7 @@ func (s *CharacteristicService) Create(ctx context.Context, body models.Characte
 	}
 
 	m := &models.Characteristic{
+		TenantID:  tenantID,
 		Name:      body.Name,
 		Type:      characteristicType,
 		Values:    body.Values,
@@ -91,7 +93,7 @@ func (s *CharacteristicService) Create(ctx context.Context, body models.Characte
 	return &dto, nil
 }
 
-func (s *CharacteristicService) Update(ctx context.Context, id string, body models.CharacteristicUpdate) (*models.CharacteristicDTO, error) {
+func (s *CharacteristicService) Update(ctx context.Context, id string, body models.CharacteristicUpdate, tenantID string) (*models.CharacteristicDTO, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, utils.BadRequest("INVALID_ID", "Invalid characteristic id", nil)
@@ -113,7 +115,7 @@ func (s *CharacteristicService) Update(ctx context.Context, id string, body mode
 	if body.Values != nil { update["values"] = body.Values }
 	if body.IsActive != nil { update["is_active"] = *body.IsActive }
 
-	updated, err := s.repo.Update(ctx, oid, update)
+	updated, err := s.repo.Update(ctx, oid, tenantID, update)
 	if err != nil {
 		return nil, utils.Internal("CHARACTERISTIC_UPDATE_FAILED", "Unable to update characteristic", err)
 	}
@@ -122,13 +124,13 @@ func (s *CharacteristicService) Update(ctx context.Context, id string, body mode
 	return &dto, nil
 }
 
-func (s *CharacteristicService) Delete(ctx context.Context, id string) error {
+func (s *CharacteristicService) Delete(ctx context.Context, id string, tenantID string) error {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return utils.BadRequest("INVALID_ID", "Invalid characteristic id", nil)
 	}
 
-	if err := s.repo.Delete(ctx, oid); err != nil {
+	if err := s.repo.Delete(ctx, oid, tenantID); err != nil {
 		return utils.Internal("CHARACTERISTIC_DELETE_FAILED", "Unable to delete characteristic", err)
 	}
 
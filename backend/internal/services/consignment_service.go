@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ConsignmentService follows the units a tenant holds on consignment, which stay the supplier's until sold. A
+// consignment receiving opens a batch; sales draw on the product's oldest batches before any stock of the tenant's
+// own, sale returns give the units back to the batches they came from, and consignment return orders send unsold
+// units back to their supplier. Each draw is logged for the settlement that pays the supplier for what was sold.
+type ConsignmentService struct {
+	batches   *repositories.ConsignmentBatchRepository
+	movements *repositories.ConsignmentMovementRepository
+}
+
+func NewConsignmentService(batches *repositories.ConsignmentBatchRepository, movements *repositories.ConsignmentMovementRepository) *ConsignmentService {
+	return &ConsignmentService{batches: batches, movements: movements}
+}
+
+// post applies a change of delta units of a product in a store to the consignment batches. It runs inside the stock
+// transaction.
+func (s *ConsignmentService) post(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, delta float64, src models.StockSource) error {
+	switch {
+	case delta > 0 && src.Type == models.StockSourceSupplierOrder && src.Consignment != nil:
+		ref := src.Consignment
+		b := &models.ConsignmentBatch{
+			TenantID: tenantID, SupplierID: ref.SupplierID, ProductID: productID, ShopID: shopID, OrderID: src.ID, OrderName: ref.OrderName,
+			SettlementType: ref.SettlementType, SupplyPrice: ref.SupplyPrice, Received: delta, OnHand: delta, DueDate: ref.DueDate,
+		}
+		if _, err := s.batches.Create(ctx, b); err != nil { return utils.Internal("CONSIGNMENT_UPDATE_FAILED", "Unable to record consignment stock", err) }
+	case delta < 0 && src.Type == models.StockSourceReturnOrder && src.Consignment != nil:
+		left, err := s.draw(ctx, tenantID, productID, shopID, -delta, src.Consignment.SupplierID, src)
+		if err != nil { return err }
+		if left > 0 { return utils.Conflict("CONSIGNMENT_NOT_ON_HAND", "Fewer units are held on consignment from this supplier than are returned", nil) }
+	case delta < 0 && src.Type == models.StockSourceSale:
+		_, err := s.draw(ctx, tenantID, productID, shopID, -delta, "", src)
+		return err
+	case delta > 0 && src.Type == models.StockSourceSaleReturn:
+		return s.giveBack(ctx, tenantID, productID, shopID, delta, src)
+	}
+	return nil
+}
+
+// draw takes up to qty units from the product's open batches, oldest first, as sold or, for a return order, as
+// returned. It returns what the batches did not cover.
+func (s *ConsignmentService) draw(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, qty float64, supplierID string, src models.StockSource) (float64, error) {
+	open, err := s.batches.Open(ctx, tenantID, productID, supplierID)
+	if err != nil { return 0, utils.Internal("CONSIGNMENT_UPDATE_FAILED", "Unable to read consignment stock", err) }
+	sale := src.Type == models.StockSourceSale
+	for _, b := range open {
+		if qty <= 0 { break }
+		n := b.OnHand
+		if n > qty { n = qty }
+		sold, returned, amount := n, 0.0, roundMoney(n*b.SupplyPrice)
+		if !sale { sold, returned, amount = 0, n, 0 }
+		ok, err := s.batches.Inc(ctx, b.ID, tenantID, -n, sold, returned)
+		if err != nil { return 0, utils.Internal("CONSIGNMENT_UPDATE_FAILED", "Unable to update consignment stock", err) }
+		if !ok { return 0, utils.Conflict("CONSIGNMENT_CHANGED", "Consignment stock changed concurrently, please retry", nil) }
+		if err := s.log(ctx, &b, shopID, n, amount, src); err != nil { return 0, err }
+		qty = models.RoundQty(qty - n)
+	}
+	return qty, nil
+}
+
+// giveBack returns units of the sale a return comes back from, src.Origin, to the batches the sale drew them from, the
+// last drawn first. Units the sale took from the tenant's own stock have nothing to give back.
+func (s *ConsignmentService) giveBack(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, qty float64, src models.StockSource) error {
+	sold, err := s.movements.BySource(ctx, tenantID, models.StockSourceSale, src.Origin, productID)
+	if err != nil { return utils.Internal("CONSIGNMENT_UPDATE_FAILED", "Unable to read consignment sales", err) }
+	if len(sold) == 0 { return nil }
+	back, err := s.movements.ReturnsOf(ctx, tenantID, src.Origin, productID)
+	if err != nil { return utils.Internal("CONSIGNMENT_UPDATE_FAILED", "Unable to read consignment sales", err) }
+	// what earlier returns gave back per batch
+	given := map[primitive.ObjectID]float64{}
+	for _, m := range back { given[m.BatchID] += m.Qty }
+	for i := len(sold) - 1; i >= 0 && qty > 0; i-- {
+		m := sold[i]
+		n := m.Qty
+		if g := given[m.BatchID]; g > 0 {
+			skip := g
+			if skip > n { skip = n }
+			given[m.BatchID] = models.RoundQty(g - skip)
+			n = models.RoundQty(n - skip)
+		}
+		if n <= 0 { continue }
+		if n > qty { n = qty }
+		b, err := s.batches.Get(ctx, m.BatchID, tenantID)
+		if err != nil { return utils.Internal("CONSIGNMENT_UPDATE_FAILED", "Unable to read consignment stock", err) }
+		ok, err := s.batches.Inc(ctx, b.ID, tenantID, n, -n, 0)
+		if err != nil { return utils.Internal("CONSIGNMENT_UPDATE_FAILED", "Unable to update consignment stock", err) }
+		if !ok { return utils.Conflict("CONSIGNMENT_CHANGED", "Consignment stock changed concurrently, please retry", nil) }
+		if err := s.log(ctx, b, shopID, n, -roundMoney(n*m.SupplyPrice), src); err != nil { return err }
+		qty = models.RoundQty(qty - n)
+	}
+	return nil
+}
+
+func (s *ConsignmentService) log(ctx context.Context, b *models.ConsignmentBatch, shopID string, qty, amount float64, src models.StockSource) error {
+	m := &models.ConsignmentMovement{
+		TenantID: b.TenantID, SupplierID: b.SupplierID, BatchID: b.ID, ProductID: b.ProductID, ShopID: shopID,
+		SourceType: src.Type, SourceID: src.ID, OriginID: src.Origin, Qty: qty, SupplyPrice: b.SupplyPrice, Amount: amount,
+	}
+	if _, err := s.movements.Create(ctx, m); err != nil { return utils.Internal("CONSIGNMENT_UPDATE_FAILED", "Unable to log consignment movement", err) }
+	return nil
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ConsignmentSettlementService settles with consignors: periodic settlements post what was sold of their goods to
+// the payables, the stock report shows what is still held for them, and goods past their return date are drafted
+// into return orders.
+type ConsignmentSettlementService struct {
+	repo      *repositories.ConsignmentSettlementRepository
+	batches   *repositories.ConsignmentBatchRepository
+	movements *repositories.ConsignmentMovementRepository
+	products  *repositories.ProductRepository
+	suppliers *repositories.SupplierRepository
+	stores    *repositories.StoreRepository
+	payables  *SupplierLedgerService
+	orderSvc  *OrderService
+	stock     *StockService
+}
+
+func NewConsignmentSettlementService(repo *repositories.ConsignmentSettlementRepository, batches *repositories.ConsignmentBatchRepository, movements *repositories.ConsignmentMovementRepository, products *repositories.ProductRepository, suppliers *repositories.SupplierRepository, stores *repositories.StoreRepository, payables *SupplierLedgerService, orderSvc *OrderService, stock *StockService) *ConsignmentSettlementService {
+	return &ConsignmentSettlementService{repo: repo, batches: batches, movements: movements, products: products, suppliers: suppliers, stores: stores, payables: payables, orderSvc: orderSvc, stock: stock}
+}
+
+func (s *ConsignmentSettlementService) List(ctx context.Context, f models.ConsignmentSettlementFilterRequest, tenantID string) ([]models.ConsignmentSettlement, int64, error) {
+	items, total, err := s.repo.List(ctx, repositories.ConsignmentSettlementListParams{Page: int64(ifZeroInt(f.Page, 1)), Limit: int64(ifZeroInt(f.Limit, 20)), SupplierID: f.SupplierID, Status: f.Status, TenantID: tenantID})
+	if err != nil { return nil, 0, utils.Internal("CONSIGNMENT_SETTLEMENT_LIST_FAILED", "Unable to list consignment settlements", err) }
+	return items, total, nil
+}
+
+func (s *ConsignmentSettlementService) Get(ctx context.Context, id string, tenantID string) (*models.ConsignmentSettlement, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid consignment settlement id", err) }
+	m, err := s.repo.Get(ctx, oid, tenantID)
+	if err != nil { return nil, utils.NotFound("CONSIGNMENT_SETTLEMENT_NOT_FOUND", "Consignment settlement not found", err) }
+	return m, nil
+}
+
+// Create lists the supplier's consignment goods sold in [from, to), net of sale returns and of what earlier
+// settlements paid for, one line per product and supply price.
+func (s *ConsignmentSettlementService) Create(ctx context.Context, body models.CreateConsignmentSettlementRequest, tenantID string, actor models.InventoryUser) (*models.ConsignmentSettlement, error) {
+	sup, err := s.supplier(ctx, body.SupplierID, tenantID)
+	if err != nil { return nil, err }
+	to := body.To
+	if to.IsZero() { to = time.Now().UTC() }
+	if !body.From.Before(to) { return nil, utils.BadRequest("VALIDATION_ERROR", "from must be before to", nil) }
+	moves, err := s.movements.Unsettled(ctx, tenantID, body.SupplierID, body.ShopID, body.From.UTC(), to.UTC())
+	if err != nil { return nil, utils.Internal("CONSIGNMENT_SETTLEMENT_FAILED", "Unable to read consignment sales", err) }
+	if len(moves) == 0 { return nil, utils.BadRequest("NOTHING_TO_SETTLE", "No consignment goods of this supplier were sold in the period", nil) }
+
+	type key struct {
+		product primitive.ObjectID
+		price   float64
+	}
+	lines := map[key]*models.ConsignmentSettlementLine{}
+	order := []key{}
+	m := &models.ConsignmentSettlement{
+		TenantID: tenantID, ExternalID: generateExternalID(), SupplierID: body.SupplierID, SupplierName: sup.Name, ShopID: body.ShopID,
+		From: body.From.UTC(), To: to.UTC(), Status: "NEW", Comment: strings.TrimSpace(body.Comment), CreatedBy: actor,
+		Lines: []models.ConsignmentSettlementLine{}, MovementIDs: make([]primitive.ObjectID, 0, len(moves)),
+	}
+	for _, mv := range moves {
+		k := key{mv.ProductID, mv.SupplyPrice}
+		l := lines[k]
+		if l == nil {
+			l = &models.ConsignmentSettlementLine{ProductID: mv.ProductID, SupplyPrice: mv.SupplyPrice}
+			if p, err := s.products.Get(ctx, mv.ProductID, tenantID); err == nil { l.ProductName, l.ProductSKU, l.Unit = p.Name, p.SKU, ifEmpty(p.Unit, "pcs") }
+			lines[k] = l
+			order = append(order, k)
+		}
+		// sale returns carry a negative amount
+		if mv.Amount < 0 { l.Qty = models.RoundQty(l.Qty - mv.Qty) } else { l.Qty = models.RoundQty(l.Qty + mv.Qty) }
+		l.Amount = roundMoney(l.Amount + mv.Amount)
+		m.MovementIDs = append(m.MovementIDs, mv.ID)
+	}
+	for _, k := range order {
+		l := lines[k]
+		if l.Qty == 0 && l.Amount == 0 { continue }
+		m.Lines = append(m.Lines, *l)
+		m.TotalQty = models.RoundQty(m.TotalQty + l.Qty)
+		m.TotalAmount = roundMoney(m.TotalAmount + l.Amount)
+	}
+	sort.SliceStable(m.Lines, func(i, j int) bool { return m.Lines[i].ProductName < m.Lines[j].ProductName })
+	m, err = s.repo.Create(ctx, m)
+	if err != nil { return nil, utils.Internal("CONSIGNMENT_SETTLEMENT_CREATE_FAILED", "Unable to create consignment settlement", err) }
+	return m, nil
+}
+
+// Update runs in one transaction: approving claims the settlement's sales and posts the payable together with the
+// APPROVED status.
+func (s *ConsignmentSettlementService) Update(ctx context.Context, id string, body models.UpdateConsignmentSettlementRequest, tenantID string, actor models.InventoryUser) (*models.ConsignmentSettlement, error) {
+	var out *models.ConsignmentSettlement
+	err := s.stock.Atomically(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = s.update(ctx, id, body, tenantID, actor)
+		return err
+	})
+	return out, err
+}
+
+func (s *ConsignmentSettlementService) update(ctx context.Context, id string, body models.UpdateConsignmentSettlementRequest, tenantID string, actor models.InventoryUser) (*models.ConsignmentSettlement, error) {
+	cur, err := s.Get(ctx, id, tenantID)
+	if err != nil { return nil, err }
+	if cur.Status != "NEW" { return nil, utils.Conflict("CONSIGNMENT_SETTLEMENT_FINISHED", "The consignment settlement is already "+cur.Status, nil) }
+	update := bson.M{}
+	if body.Comment != nil { update["comment"] = strings.TrimSpace(*body.Comment) }
+	switch body.Action {
+	case "approve":
+		n, err := s.movements.Settle(ctx, tenantID, cur.MovementIDs, cur.ID.Hex())
+		if err != nil { return nil, utils.Internal("CONSIGNMENT_SETTLEMENT_FAILED", "Unable to settle consignment sales", err) }
+		if n != int64(len(cur.MovementIDs)) {
+			return nil, utils.Conflict("CONSIGNMENT_SETTLEMENT_STALE", "Some of these sales were settled by another document; create the settlement again", nil)
+		}
+		if err := s.payables.consignmentSettled(ctx, cur, actor); err != nil { return nil, err }
+		update["status"], update["finished_at"], update["finished_by"] = "APPROVED", time.Now().UTC(), actor
+	case "reject":
+		update["status"], update["finished_at"], update["finished_by"] = "REJECTED", time.Now().UTC(), actor
+	case "":
+	default:
+		return nil, utils.BadRequest("VALIDATION_ERROR", "action must be approve or reject", nil)
+	}
+	m, err := s.repo.Update(ctx, cur.ID, tenantID, update)
+	if err != nil { return nil, utils.Internal("CONSIGNMENT_SETTLEMENT_UPDATE_FAILED", "Unable to update consignment settlement", err) }
+	return m, nil
+}
+
+func (s *ConsignmentSettlementService) Delete(ctx context.Context, id string, tenantID string) error {
+	m, err := s.Get(ctx, id, tenantID)
+	if err != nil { return err }
+	if m.Status == "APPROVED" { return utils.Conflict("CONSIGNMENT_SETTLEMENT_APPROVED", "An approved consignment settlement cannot be deleted", nil) }
+	if err := s.repo.Delete(ctx, m.ID, tenantID); err != nil { return utils.Internal("CONSIGNMENT_SETTLEMENT_DELETE_FAILED", "Unable to delete consignment settlement", err) }
+	return nil
+}
+
+// Stock reports the consignment goods still on hand per supplier and product, optionally of one supplier or received
+// into one store. A product is overdue once the earliest return date of its batches has passed.
+func (s *ConsignmentSettlementService) Stock(ctx context.Context, tenantID, supplierID, shopID string) (*models.ConsignmentStockReport, error) {
+	batches, err := s.batches.OnHand(ctx, tenantID, supplierID, shopID)
+	if err != nil { return nil, utils.Internal("CONSIGNMENT_STOCK_FAILED", "Unable to read consignment stock", err) }
+	now := time.Now().UTC()
+	out := &models.ConsignmentStockReport{Suppliers: []models.ConsignmentStockSupplier{}}
+	groups := map[string]*models.ConsignmentStockSupplier{}
+	lines := map[string]map[primitive.ObjectID]*models.ConsignmentStockLine{}
+	for _, b := range batches {
+		g := groups[b.SupplierID]
+		if g == nil {
+			g = &models.ConsignmentStockSupplier{SupplierID: b.SupplierID, Items: []models.ConsignmentStockLine{}}
+			if sup, err := s.supplier(ctx, b.SupplierID, tenantID); err == nil { g.SupplierName = sup.Name }
+			groups[b.SupplierID] = g
+			lines[b.SupplierID] = map[primitive.ObjectID]*models.ConsignmentStockLine{}
+		}
+		l := lines[b.SupplierID][b.ProductID]
+		if l == nil {
+			l = &models.ConsignmentStockLine{SupplierID: b.SupplierID, SupplierName: g.SupplierName, ProductID: b.ProductID.Hex()}
+			if p, err := s.products.Get(ctx, b.ProductID, tenantID); err == nil { l.ProductName, l.ProductSKU, l.Unit = p.Name, p.SKU, ifEmpty(p.Unit, "pcs") }
+			lines[b.SupplierID][b.ProductID] = l
+		}
+		l.OnHand = models.RoundQty(l.OnHand + b.OnHand)
+		l.Value = roundMoney(l.Value + b.OnHand*b.SupplyPrice)
+		if b.DueDate != nil && (l.DueDate == nil || b.DueDate.Before(*l.DueDate)) {
+			l.DueDate = b.DueDate
+			l.Overdue = b.DueDate.Before(now)
+		}
+	}
+	for id, g := range groups {
+		for _, l := range lines[id] {
+			g.Items = append(g.Items, *l)
+			g.TotalQty = models.RoundQty(g.TotalQty + l.OnHand)
+			g.TotalValue = roundMoney(g.TotalValue + l.Value)
+		}
+		sort.Slice(g.Items, func(i, j int) bool { return g.Items[i].ProductName < g.Items[j].ProductName })
+		out.Suppliers = append(out.Suppliers, *g)
+		out.TotalValue = roundMoney(out.TotalValue + g.TotalValue)
+	}
+	sort.Slice(out.Suppliers, func(i, j int) bool { return out.Suppliers[i].SupplierName < out.Suppliers[j].SupplierName })
+	return out, nil
+}
+
+// CreateReturn drafts return orders of the supplier's consignment goods in a store whose return date has passed, one
+// per settlement type, since an order settles under a single type. Goods already on an open return order are left
+// out, and the orders are created together or not at all. Approving an order takes its goods out of stock without
+// touching the payables.
+func (s *ConsignmentSettlementService) CreateReturn(ctx context.Context, body models.CreateConsignmentReturnRequest, tenantID string, user models.OrderUser) ([]models.Order, error) {
+	sup, err := s.supplier(ctx, body.SupplierID, tenantID)
+	if err != nil { return nil, err }
+	if body.ShopID == "" { return nil, utils.BadRequest("STORE_REQUIRED", "Store is required", nil) }
+	due, err := s.batches.Due(ctx, tenantID, body.SupplierID, body.ShopID, time.Now().UTC())
+	if err != nil { return nil, utils.Internal("CONSIGNMENT_STOCK_FAILED", "Unable to read consignment stock", err) }
+	if len(due) == 0 { return nil, utils.BadRequest("NOTHING_TO_RETURN", "No consignment goods of this supplier are due back from this store", nil) }
+	pending, err := s.orderSvc.repo.PendingReturns(ctx, tenantID, body.SupplierID, body.ShopID)
+	if err != nil { return nil, utils.Internal("CONSIGNMENT_STOCK_FAILED", "Unable to read open return orders", err) }
+	types := []string{}
+	byType := map[string][]models.ConsignmentBatch{}
+	for _, b := range due {
+		// the oldest batches count as the ones already on a return
+		if left := pending[b.SettlementType][b.ProductID]; left > 0 {
+			n := math.Min(left, b.OnHand)
+			pending[b.SettlementType][b.ProductID] = models.RoundQty(left - n)
+			b.OnHand = models.RoundQty(b.OnHand - n)
+		}
+		if b.OnHand <= 0 { continue }
+		if _, ok := byType[b.SettlementType]; !ok { types = append(types, b.SettlementType) }
+		byType[b.SettlementType] = append(byType[b.SettlementType], b)
+	}
+	if len(types) == 0 { return nil, utils.Conflict("CONSIGNMENT_RETURN_PENDING", "The goods due back are already on an open return order", nil) }
+	name := "Consignment return " + sup.Name + " " + time.Now().UTC().Format("2006-01-02")
+	out := make([]models.Order, 0, len(types))
+	err = s.stock.Atomically(ctx, func(ctx context.Context) error {
+		out = out[:0]
+		for _, typ := range types {
+			req := models.CreateOrderRequest{
+				Name: name, SupplierID: body.SupplierID, ShopID: body.ShopID,
+				Type: "return_order", SettlementType: typ, Comment: "Unsold consignment goods past their return date",
+			}
+			if len(types) > 1 { req.Name += " (" + typ + ")" }
+			at := map[primitive.ObjectID]int{}
+			for _, b := range byType[typ] {
+				i, ok := at[b.ProductID]
+				if !ok {
+					p, err := s.products.Get(ctx, b.ProductID, tenantID)
+					if err != nil { continue }
+					i = len(req.Items)
+					at[b.ProductID] = i
+					req.Items = append(req.Items, models.OrderItemInput{ProductID: p.ID.Hex(), ProductName: p.Name, ProductSKU: p.SKU, Unit: p.Unit})
+				}
+				it := &req.Items[i]
+				// the line's price is the average supply price of the batches it returns
+				value := it.Quantity*it.SupplyPrice + b.OnHand*b.SupplyPrice
+				it.Quantity = models.RoundQty(it.Quantity + b.OnHand)
+				it.SupplyPrice = roundCost(value / it.Quantity)
+				it.UnitPrice = it.SupplyPrice
+			}
+			if len(req.Items) == 0 { continue }
+			o, err := s.orderSvc.Create(ctx, req, tenantID, user)
+			if err != nil { return err }
+			out = append(out, *o)
+		}
+		if len(out) == 0 { return utils.BadRequest("NOTHING_TO_RETURN", "No consignment goods of this supplier are due back from this store", nil) }
+		return nil
+	})
+	if err != nil { return nil, err }
+	return out, nil
+}
+
+func (s *ConsignmentSettlementService) supplier(ctx context.Context, id, tenantID string) (*models.Supplier, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil { return nil, utils.BadRequest("INVALID_SUPPLIER_ID", "Invalid supplier id", err) }
+	sup, err := s.suppliers.Get(ctx, oid, tenantID)
+	if err != nil { return nil, utils.NotFound("SUPPLIER_NOT_FOUND", "Supplier not found", err) }
+	return sup, nil
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CoreService follows the cores of the core-bearing parts sold: what customers still owe back against their core
+// charges, what waits in the core inventory, and the return orders that send dirty cores to the supplier for credit.
+type CoreService struct {
+	stock     *repositories.CoreStockRepository
+	sales     *repositories.SaleRepository
+	products  *repositories.ProductRepository
+	suppliers *repositories.SupplierRepository
+	orderSvc  *OrderService
+}
+
+func NewCoreService(stock *repositories.CoreStockRepository, sales *repositories.SaleRepository, products *repositories.ProductRepository, suppliers *repositories.SupplierRepository, orderSvc *OrderService) *CoreService {
+	return &CoreService{stock: stock, sales: sales, products: products, suppliers: suppliers, orderSvc: orderSvc}
+}
+
+// Stock lists the dirty cores on hand, optionally in one store.
+func (s *CoreService) Stock(ctx context.Context, tenantID, shopID string) ([]models.CoreStockLine, error) {
+	balances, err := s.stock.OnHand(ctx, tenantID, shopID)
+	if err != nil { return nil, utils.Internal("CORE_STOCK_READ_FAILED", "Unable to read the core inventory", err) }
+	c := s.catalog(tenantID)
+	out := make([]models.CoreStockLine, 0, len(balances))
+	for _, b := range balances {
+		l := models.CoreStockLine{ ShopID: b.ShopID, ProductID: b.ProductID.Hex(), OnHand: b.OnHand }
+		if p := c.product(ctx, b.ProductID); p != nil {
+			l.ProductName, l.ProductSKU, l.CoreCharge = p.Name, p.SKU, p.CoreCharge
+			l.SupplierID, l.SupplierName = c.supplier(ctx, p)
+		}
+		l.Value = roundMoney(l.OnHand * l.CoreCharge)
+		out = append(out, l)
+	}
+	return out, nil
+}
+
+// Customers reports the cores customers still owe back, per customer, optionally of one customer. Sales without a
+// customer are grouped together.
+func (s *CoreService) Customers(ctx context.Context, tenantID, customerID string) ([]models.CoreCustomerOutstanding, error) {
+	sales, err := s.sales.WithCoresOutstanding(ctx, tenantID, customerID)
+	if err != nil { return nil, utils.Internal("CORE_REPORT_FAILED", "Unable to read outstanding cores", err) }
+	groups := map[string]*models.CoreCustomerOutstanding{}
+	order := []string{}
+	for _, m := range sales {
+		g := groups[m.CustomerID]
+		if g == nil {
+			g = &models.CoreCustomerOutstanding{ CustomerID: m.CustomerID, CustomerName: m.Customer.Name, Phone: m.Customer.Phone, Lines: []models.CoreSaleLine{} }
+			groups[m.CustomerID] = g
+			order = append(order, m.CustomerID)
+		}
+		for i, it := range m.Items {
+			left := models.RoundQty(it.Qty - it.ReturnedQty)
+			if it.ProductType != models.SaleItemCore || left <= 0 { continue }
+			l := models.CoreSaleLine{ SaleID: m.ID.Hex(), ReceiptNumber: m.ReceiptNumber, ShopID: m.ShopID, Line: i, ProductID: it.ProductID.Hex(), ProductName: strings.TrimSuffix(it.ProductName, " core charge"), Qty: left, UnitCharge: it.UnitPrice, Amount: roundMoney(left * it.UnitPrice), SoldAt: m.CompletedAt }
+			g.Lines = append(g.Lines, l)
+			g.Qty = models.RoundQty(g.Qty + l.Qty)
+			g.Amount = roundMoney(g.Amount + l.Amount)
+		}
+	}
+	out := make([]models.CoreCustomerOutstanding, 0, len(order))
+	for _, id := range order { out = append(out, *groups[id]) }
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Amount > out[j].Amount })
+	return out, nil
+}
+
+// Suppliers reports the cores owed back to each supplier of core-bearing parts: the dirty cores waiting in the core
+// inventory and those still out with customers, optionally of one supplier.
+func (s *CoreService) Suppliers(ctx context.Context, tenantID, supplierID string) ([]models.CoreSupplierOutstanding, error) {
+	balances, err := s.stock.OnHand(ctx, tenantID, "")
+	if err != nil { return nil, utils.Internal("CORE_STOCK_READ_FAILED", "Unable to read the core inventory", err) }
+	sales, err := s.sales.WithCoresOutstanding(ctx, tenantID, "")
+	if err != nil { return nil, utils.Internal("CORE_REPORT_FAILED", "Unable to read outstanding cores", err) }
+	c := s.catalog(tenantID)
+	groups := map[string]*models.CoreSupplierOutstanding{}
+	items := map[string]map[primitive.ObjectID]*models.CoreSupplierItem{}
+	line := func(productID primitive.ObjectID) *models.CoreSupplierItem {
+		p := c.product(ctx, productID)
+		if p == nil { return nil }
+		id, name := c.supplier(ctx, p)
+		if supplierID != "" && id != supplierID { return nil }
+		if groups[id] == nil {
+			groups[id] = &models.CoreSupplierOutstanding{ SupplierID: id, SupplierName: name, Items: []models.CoreSupplierItem{} }
+			items[id] = map[primitive.ObjectID]*models.CoreSupplierItem{}
+		}
+		it := items[id][productID]
+		if it == nil {
+			it = &models.CoreSupplierItem{ ProductID: productID.Hex(), ProductName: p.Name, ProductSKU: p.SKU, CoreCharge: p.CoreCharge }
+			items[id][productID] = it
+		}
+		return it
+	}
+	for _, b := range balances {
+		if it := line(b.ProductID); it != nil { it.OnHand = models.RoundQty(it.OnHand + b.OnHand) }
+	}
+	for _, m := range sales {
+		for _, si := range m.Items {
+			left := models.RoundQty(si.Qty - si.ReturnedQty)
+			if si.ProductType != models.SaleItemCore || left <= 0 { continue }
+			if it := line(si.ProductID); it != nil { it.AtCustomers = models.RoundQty(it.AtCustomers + left) }
+		}
+	}
+	out := make([]models.CoreSupplierOutstanding, 0, len(groups))
+	for id, g := range groups {
+		for _, it := range items[id] {
+			g.Items = append(g.Items, *it)
+			g.OnHand = models.RoundQty(g.OnHand + it.OnHand)
+			g.OnHandValue = roundMoney(g.OnHandValue + it.OnHand*it.CoreCharge)
+			g.AtCustomers = models.RoundQty(g.AtCustomers + it.AtCustomers)
+			g.AtCustomersValue = roundMoney(g.AtCustomersValue + it.AtCustomers*it.CoreCharge)
+		}
+		sort.Slice(g.Items, func(i, j int) bool { return g.Items[i].ProductName < g.Items[j].ProductName })
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].SupplierName < out[j].SupplierName })
+	return out, nil
+}
+
+// CreateReturn bundles the dirty cores of a supplier's parts in a store into a core return order, priced at the
+// parts' core charges. Approving the order ships the cores and credits the supplier.
+func (s *CoreService) CreateReturn(ctx context.Context, body models.CreateCoreReturnRequest, tenantID string, user models.OrderUser) (*models.Order, error) {
+	if strings.TrimSpace(body.SupplierID) == "" { return nil, utils.BadRequest("VALIDATION_ERROR", "Supplier is required", nil) }
+	if strings.TrimSpace(body.ShopID) == "" { return nil, utils.BadRequest("STORE_REQUIRED", "Store is required", nil) }
+	balances, err := s.stock.OnHand(ctx, tenantID, body.ShopID)
+	if err != nil { return nil, utils.Internal("CORE_STOCK_READ_FAILED", "Unable to read the core inventory", err) }
+	c := s.catalog(tenantID)
+	req := models.CreateOrderRequest{ SupplierID: body.SupplierID, ShopID: body.ShopID, Type: "return_order", Cores: true, Comment: "Dirty cores returned for credit" }
+	name := body.SupplierID
+	for _, b := range balances {
+		p := c.product(ctx, b.ProductID)
+		if p == nil { continue }
+		id, sn := c.supplier(ctx, p)
+		if id != body.SupplierID { continue }
+		name = ifEmpty(sn, name)
+		req.Items = append(req.Items, models.OrderItemInput{ ProductID: p.ID.Hex(), ProductName: p.Name, ProductSKU: p.SKU, Quantity: b.OnHand, UnitPrice: p.CoreCharge, SupplyPrice: p.CoreCharge, Unit: p.Unit })
+	}
+	if len(req.Items) == 0 { return nil, utils.BadRequest("NOTHING_TO_RETURN", "No dirty cores of this supplier's parts are in the store", nil) }
+	req.Name = "Core return " + name + " " + time.Now().UTC().Format("2006-01-02")
+	return s.orderSvc.Create(ctx, req, tenantID, user)
+}
+
+// coreCatalog caches the products and suppliers a report looks up.
+type coreCatalog struct {
+	svc       *CoreService
+	tenantID  string
+	products  map[primitive.ObjectID]*models.Product
+	suppliers map[primitive.ObjectID]string
+}
+
+func (s *CoreService) catalog(tenantID string) *coreCatalog {
+	return &coreCatalog{ svc: s, tenantID: tenantID, products: map[primitive.ObjectID]*models.Product{}, suppliers: map[primitive.ObjectID]string{} }
+}
+
+func (c *coreCatalog) product(ctx context.Context, id primitive.ObjectID) *models.Product {
+	if p, ok := c.products[id]; ok { return p }
+	p, err := c.svc.products.Get(ctx, id, c.tenantID)
+	if err != nil { p = nil }
+	c.products[id] = p
+	return p
+}
+
+// supplier returns the id and name of a part's supplier; both are empty for a part without one.
+func (c *coreCatalog) supplier(ctx context.Context, p *models.Product) (string, string) {
+	if p == nil || p.SupplierID == primitive.NilObjectID { return "", "" }
+	name, ok := c.suppliers[p.SupplierID]
+	if !ok {
+		if sup, err := c.svc.suppliers.Get(ctx, p.SupplierID, c.tenantID); err == nil { name = sup.Name }
+		c.suppliers[p.SupplierID] = name
+	}
+	return p.SupplierID.Hex(), name
+}
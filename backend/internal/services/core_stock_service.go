@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CoreStockService keeps the core inventory: the dirty cores customers bring back against the core charges of their
+// sales, held apart from the sellable stock until a core return order ships them to the supplier for credit.
+type CoreStockService struct {
+	repo *repositories.CoreStockRepository
+}
+
+func NewCoreStockService(repo *repositories.CoreStockRepository) *CoreStockService {
+	return &CoreStockService{repo: repo}
+}
+
+func (s *CoreStockService) receive(ctx context.Context, tenantID, shopID string, productID primitive.ObjectID, qty float64) error {
+	if err := s.repo.Receive(ctx, tenantID, shopID, productID, qty); err != nil { return utils.Internal("CORE_STOCK_UPDATE_FAILED", "Unable to receive dirty cores", err) }
+	return nil
+}
+
+// ship takes cores of a product out of a store's core inventory, naming the product when fewer are on hand.
+func (s *CoreStockService) ship(ctx context.Context, tenantID, shopID string, productID primitive.ObjectID, name string, qty float64) error {
+	ok, err := s.repo.Ship(ctx, tenantID, shopID, productID, qty)
+	if err != nil { return utils.Internal("CORE_STOCK_UPDATE_FAILED", "Unable to ship dirty cores", err) }
+	if !ok { return utils.Conflict("CORES_NOT_ON_HAND", "Not enough dirty cores of "+name+" in the core inventory", nil) }
+	return nil
+}
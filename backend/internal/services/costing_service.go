@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CostingService values stock per product and store. A moving average cost and FIFO cost layers are both kept up
+// to date on every movement, so a tenant can switch methods at any time; the tenant's setting decides which one
+// values outgoing units and the stock on hand. Product.CostPrice follows the product's cost over all stores.
+type CostingService struct {
+	balances  *repositories.StockRepository
+	layers    *repositories.CostLayerRepository
+	movements *repositories.StockMovementRepository
+	products  *repositories.ProductRepository
+	tenants   *repositories.TenantRepository
+}
+
+func NewCostingService(balances *repositories.StockRepository, layers *repositories.CostLayerRepository, movements *repositories.StockMovementRepository, products *repositories.ProductRepository, tenants *repositories.TenantRepository) *CostingService {
+	return &CostingService{balances: balances, layers: layers, movements: movements, products: products, tenants: tenants}
+}
+
+// costed is the valuation of one stock movement: the unit cost, the signed cost of the units moved and the store's
+// stock value after it.
+type costed struct {
+	unit   float64
+	amount float64
+	value  float64
+}
+
+// Method returns the tenant's costing method; it is the moving average unless FIFO was chosen.
+func (s *CostingService) Method(ctx context.Context, tenantID string) string {
+	oid, err := primitive.ObjectIDFromHex(tenantID)
+	if err != nil { return models.CostingAverage }
+	t, err := s.tenants.Get(ctx, oid)
+	if err != nil || t.Settings.CostingMethod != models.CostingFIFO { return models.CostingAverage }
+	return models.CostingFIFO
+}
+
+// UnitCost is the current cost of one unit of a product in a store, or fallback when the store has no cost yet.
+func (s *CostingService) UnitCost(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, fallback float64) (float64, error) {
+	b, err := s.balances.GetBalance(ctx, tenantID, productID, shopID)
+	if err != nil { return 0, utils.Internal("STOCK_READ_FAILED", "Unable to read stock balance", err) }
+	if b.Qty > 0 && b.Value > 0 { return b.Value / b.Qty, nil }
+	if b.AvgCost > 0 { return b.AvgCost, nil }
+	return fallback, nil
+}
+
+// post values a change of delta units that left balance on hand. Incoming units cost unitCost, or the store's
+// average when it is unknown, and open a FIFO layer. Outgoing units cost the store's average or its oldest layers;
+// unitCost only prices stock that has no cost history yet. It runs inside the stock transaction.
+func (s *CostingService) post(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, delta, balance float64, unitCost float64, src models.StockSource) (costed, error) {
+	fail := func(err error) (costed, error) { return costed{}, utils.Internal("STOCK_COSTING_FAILED", "Unable to value the stock movement", err) }
+	method := s.Method(ctx, tenantID)
+	b, err := s.balances.GetBalance(ctx, tenantID, productID, shopID)
+	if err != nil { return fail(err) }
+	avg := b.AvgCost
+	var c costed
+	if delta > 0 {
+		cost := unitCost
+		if cost <= 0 { cost = avg }
+		if prev := balance - delta; prev > 0 && avg > 0 {
+			avg = (prev*avg + delta*cost) / balance
+		} else {
+			avg = cost
+		}
+		if _, err := s.layers.Create(ctx, &models.CostLayer{TenantID: tenantID, ProductID: productID, ShopID: shopID, Qty: delta, Left: delta, UnitCost: cost, SourceType: src.Type, SourceID: src.ID}); err != nil { return fail(err) }
+		c.unit, c.amount = cost, delta*cost
+	} else {
+		qty := -delta
+		fallback := avg
+		if fallback <= 0 { fallback = unitCost }
+		layered, covered, err := s.layers.Consume(ctx, tenantID, productID, shopID, qty)
+		if err != nil { return fail(err) }
+		// units the layers do not cover (stock from before costing) go at the average
+		c.amount = -qty * fallback
+		if method == models.CostingFIFO { c.amount = -(layered + (qty-covered)*fallback) }
+		c.unit = -c.amount / qty
+		if avg <= 0 { avg = fallback }
+	}
+	if method == models.CostingFIFO {
+		open, value, err := s.layers.Open(ctx, tenantID, productID, shopID)
+		if err != nil { return fail(err) }
+		if open > balance { open = balance }
+		c.value = value + (balance-open)*avg
+	} else {
+		c.value = balance * avg
+	}
+	c.unit, c.amount, c.value = roundCost(c.unit), roundMoney(c.amount), roundMoney(c.value)
+	if err := s.balances.SetCost(ctx, tenantID, productID, shopID, roundCost(avg), c.value); err != nil { return fail(err) }
+	// the catalog cost is the product's stock value over its quantity in all stores; it keeps the last cost at zero stock
+	qty, value, err := s.balances.ProductValue(ctx, tenantID, productID)
+	if err != nil { return fail(err) }
+	if qty > 0 && value > 0 {
+		if err := s.products.UpdatePrices(ctx, productID, tenantID, roundCost(value/qty), -1); err != nil { return fail(err) }
+	}
+	return c, nil
+}
+
+// Valuation reports the cost of the stock on hand at a point in time, from the stock value each product and store
+// was left with by its last movement. shopID may be empty for all stores.
+func (s *CostingService) Valuation(ctx context.Context, tenantID, shopID string, at time.Time) (*models.InventoryValuation, error) {
+	rows, err := s.movements.LastByShop(ctx, tenantID, shopID, at)
+	if err != nil { return nil, utils.Internal("STOCK_VALUATION_FAILED", "Unable to value the inventory", err) }
+	out := &models.InventoryValuation{Date: at, Method: s.Method(ctx, tenantID), ShopID: shopID, Items: make([]models.InventoryValuationLine, 0, len(rows))}
+	products := map[primitive.ObjectID]*models.Product{}
+	for _, r := range rows {
+		// movements from before stock was valued carry only their unit cost
+		value := r.Balance * r.UnitCost
+		if r.BalanceValue != nil { value = *r.BalanceValue }
+		line := models.InventoryValuationLine{ProductID: r.ProductID.Hex(), ShopID: r.ShopID, Qty: r.Balance, Value: roundMoney(value)}
+		if r.Balance != 0 { line.UnitCost = roundCost(value / r.Balance) }
+		p, ok := products[r.ProductID]
+		if !ok {
+			p, _ = s.products.Get(ctx, r.ProductID, tenantID)
+			products[r.ProductID] = p
+		}
+		if p != nil { line.ProductName, line.ProductSKU = p.Name, p.SKU }
+		out.Items = append(out.Items, line)
+		out.TotalQty += line.Qty
+		out.TotalValue += line.Value
+	}
+	out.TotalQty, out.TotalValue = models.RoundQty(out.TotalQty), roundMoney(out.TotalValue)
+	return out, nil
+}
+
+// roundCost keeps unit costs to four decimals so that averages do not accumulate float noise.
+func roundCost(v float64) float64 { return math.Round(v*10000) / 10000 }
@@ -0,0 +1,330 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// defaultDebtTerm is when a credit sale falls due when no schedule is given
+const defaultDebtTerm = 30 * 24 * time.Hour
+
+type CustomerDebtService struct {
+	repo      *repositories.CustomerDebtRepository
+	entries   *repositories.CustomerDebtEntryRepository
+	customers *repositories.CustomerRepository
+	sales     *repositories.SaleRepository
+	shifts    *repositories.CashShiftRepository
+	tx        *repositories.Tx
+}
+
+func NewCustomerDebtService(repo *repositories.CustomerDebtRepository, entries *repositories.CustomerDebtEntryRepository, customers *repositories.CustomerRepository, sales *repositories.SaleRepository, shifts *repositories.CashShiftRepository, tx *repositories.Tx) *CustomerDebtService {
+	return &CustomerDebtService{repo: repo, entries: entries, customers: customers, sales: sales, shifts: shifts, tx: tx}
+}
+
+func (s *CustomerDebtService) List(ctx context.Context, f models.CustomerDebtFilterRequest, tenantID string) ([]models.CustomerDebt, int64, error) {
+	p := repositories.CustomerDebtListParams{ TenantID: tenantID, Page: int64(ifZero(f.Page, 1)), Limit: int64(ifZero(f.Limit, 20)), CustomerID: f.CustomerID, ShopID: f.ShopID, Status: strings.ToUpper(f.Status) }
+	if f.Overdue { now := time.Now().UTC(); p.OverdueAt = &now }
+	items, total, err := s.repo.List(ctx, p)
+	if err != nil { return nil, 0, utils.Internal("CUSTOMER_DEBT_LIST_FAILED", "Unable to list debts", err) }
+	return items, total, nil
+}
+
+func (s *CustomerDebtService) Get(ctx context.Context, id string, tenantID string) (*models.CustomerDebt, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid debt id", err) }
+	m, err := s.repo.Get(ctx, oid, tenantID)
+	if err != nil { return nil, utils.NotFound("CUSTOMER_DEBT_NOT_FOUND", "Debt not found", err) }
+	return m, nil
+}
+
+// Ledger returns a customer's receivables postings, newest first.
+func (s *CustomerDebtService) Ledger(ctx context.Context, customerID string, page, limit int64, tenantID string) ([]models.CustomerDebtEntry, int64, error) {
+	if _, err := primitive.ObjectIDFromHex(customerID); err != nil { return nil, 0, utils.BadRequest("INVALID_ID", "Invalid customer id", err) }
+	items, total, err := s.entries.ListByCustomer(ctx, tenantID, customerID, page, limit)
+	if err != nil { return nil, 0, utils.Internal("CUSTOMER_DEBT_LEDGER_FAILED", "Unable to read debt ledger", err) }
+	return items, total, nil
+}
+
+func (s *CustomerDebtService) Debtors(ctx context.Context, shopID string, page, limit int64, tenantID string) ([]models.Debtor, int64, error) {
+	items, total, err := s.repo.Debtors(ctx, tenantID, shopID, time.Now().UTC(), page, limit)
+	if err != nil { return nil, 0, utils.Internal("DEBTORS_LIST_FAILED", "Unable to list debtors", err) }
+	return items, total, nil
+}
+
+// Aging splits the unpaid installments of open debts into buckets by days overdue.
+func (s *CustomerDebtService) Aging(ctx context.Context, shopID, customerID string, tenantID string) (*models.DebtAging, error) {
+	debts, err := s.repo.ListOpen(ctx, tenantID, customerID, shopID)
+	if err != nil { return nil, utils.Internal("DEBT_AGING_FAILED", "Unable to build debt aging", err) }
+	now := time.Now().UTC()
+	keys := []string{"current", "0-30", "31-60", "61-90", "90+"}
+	amounts := map[string]float64{}
+	customers := map[string]map[string]bool{}
+	all := map[string]bool{}
+	out := &models.DebtAging{ AsOf: now }
+	for _, d := range debts {
+		all[d.CustomerID] = true
+		for _, in := range d.Installments {
+			due := in.Amount - in.Paid
+			if due <= 0.005 { continue }
+			key := agingBucket(daysOverdue(in.DueDate, now))
+			amounts[key] += due
+			if customers[key] == nil { customers[key] = map[string]bool{} }
+			customers[key][d.CustomerID] = true
+			out.Total += due
+			if key != "current" { out.Overdue += due }
+		}
+	}
+	for _, k := range keys { out.Buckets = append(out.Buckets, models.DebtAgingBucket{ Key: k, Amount: roundMoney(amounts[k]), Customers: len(customers[k]) }) }
+	out.Total = roundMoney(out.Total)
+	out.Overdue = roundMoney(out.Overdue)
+	out.Customers = len(all)
+	return out, nil
+}
+
+// Reminders lists the unpaid installments that are overdue or fall due within the given number of days, most
+// overdue first, so the store can call or message the customers.
+func (s *CustomerDebtService) Reminders(ctx context.Context, shopID string, days int, tenantID string) ([]models.DebtReminder, error) {
+	debts, err := s.repo.ListOpen(ctx, tenantID, "", shopID)
+	if err != nil { return nil, utils.Internal("DEBT_REMINDERS_FAILED", "Unable to list debt reminders", err) }
+	now := time.Now().UTC()
+	out := []models.DebtReminder{}
+	for _, d := range debts {
+		for _, in := range d.Installments {
+			due := in.Amount - in.Paid
+			if due <= 0.005 { continue }
+			late := daysOverdue(in.DueDate, now)
+			if late < -days { continue }
+			out = append(out, models.DebtReminder{ DebtID: d.ID.Hex(), CustomerID: d.CustomerID, Customer: d.Customer, ShopID: d.ShopID, SaleReceiptNumber: d.SaleReceiptNumber, DueDate: in.DueDate, AmountDue: roundMoney(due), DaysOverdue: late })
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].DueDate.Before(out[j].DueDate) })
+	return out, nil
+}
+
+// Charge opens the debt of a credit sale and posts it to the customer's ledger. It must run inside the sale's
+// transaction.
+func (s *CustomerDebtService) Charge(ctx context.Context, sale *models.Sale, schedule []models.DebtInstallment, actor models.InventoryUser) (*models.CustomerDebt, error) {
+	amount := roundMoney(sale.Total - sale.PaidAmount)
+	d := &models.CustomerDebt{ TenantID: sale.TenantID, CustomerID: sale.CustomerID, Customer: sale.Customer, ShopID: sale.ShopID, ShopName: sale.ShopName, SaleID: sale.ID.Hex(), SaleReceiptNumber: sale.ReceiptNumber, Status: "OPEN", Amount: amount, Balance: amount, Installments: schedule, CreatedBy: actor }
+	d.DueDate = nextDue(d.Installments)
+	created, err := s.repo.Create(ctx, d)
+	if err != nil { return nil, utils.Internal("CUSTOMER_DEBT_CREATE_FAILED", "Unable to record the debt", err) }
+	if _, err := s.post(ctx, created, models.DebtEntryCharge, amount, models.CustomerDebtEntry{ SaleID: created.SaleID }, actor); err != nil { return nil, err }
+	return created, nil
+}
+
+// Outstanding is the unpaid balance of a sale's debt; 0 when the sale was not on credit.
+func (s *CustomerDebtService) Outstanding(ctx context.Context, tenantID string, saleID string) (float64, error) {
+	d, err := s.repo.GetBySale(ctx, tenantID, saleID)
+	if err == mongo.ErrNoDocuments { return 0, nil }
+	if err != nil { return 0, utils.Internal("CUSTOMER_DEBT_READ_FAILED", "Unable to read the sale's debt", err) }
+	return d.Balance, nil
+}
+
+// Credit settles part of a sale's debt with returned goods. It must run inside the return's transaction.
+func (s *CustomerDebtService) Credit(ctx context.Context, tenantID string, saleID string, amount float64, returnID string, actor models.InventoryUser) error {
+	if amount <= 0 { return nil }
+	d, err := s.repo.GetBySale(ctx, tenantID, saleID)
+	if err != nil { return utils.Internal("CUSTOMER_DEBT_READ_FAILED", "Unable to read the sale's debt", err) }
+	amount = math.Min(amount, d.Balance)
+	d.Credited = roundMoney(d.Credited + amount)
+	if err := s.settle(ctx, d, amount); err != nil { return err }
+	_, err = s.post(ctx, d, models.DebtEntryReturn, -amount, models.CustomerDebtEntry{ SaleID: saleID, ReturnID: returnID }, actor)
+	return err
+}
+
+// Repay takes money from a customer against one debt or, without a debt, against the oldest open debts in the
+// store. Cash and card repayments are taken in the cashier's open shift and show in its X/Z reports.
+func (s *CustomerDebtService) Repay(ctx context.Context, customerID string, body models.CreateDebtRepaymentRequest, tenantID string, actor models.InventoryUser) ([]models.CustomerDebtEntry, error) {
+	if _, err := primitive.ObjectIDFromHex(customerID); err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid customer id", err) }
+	if body.Amount <= 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Amount must be greater than 0", nil) }
+	method := strings.ToLower(strings.TrimSpace(body.Method))
+	if method != models.SalePaymentCash && method != models.SalePaymentCard && method != models.SalePaymentTransfer { return nil, utils.BadRequest("INVALID_PAYMENT_METHOD", "Payment method must be cash, card or transfer", nil) }
+	amount := roundMoney(body.Amount)
+	out := []models.CustomerDebtEntry{}
+	err := runTx(ctx, s.tx, func(ctx context.Context) error {
+		out = out[:0]
+		var debts []models.CustomerDebt
+		if strings.TrimSpace(body.DebtID) != "" {
+			d, err := s.Get(ctx, body.DebtID, tenantID)
+			if err != nil { return err }
+			if d.CustomerID != customerID { return utils.BadRequest("CUSTOMER_DEBT_MISMATCH", "Debt belongs to another customer", nil) }
+			if d.Status != "OPEN" { return utils.BadRequest("CUSTOMER_DEBT_PAID", "Debt is already paid", nil) }
+			debts = []models.CustomerDebt{*d}
+		} else {
+			var err error
+			debts, err = s.repo.ListOpen(ctx, tenantID, customerID, body.ShopID)
+			if err != nil { return utils.Internal("CUSTOMER_DEBT_READ_FAILED", "Unable to read debts", err) }
+		}
+		var open float64
+		for _, d := range debts { open += d.Balance }
+		if open <= 0.005 { return utils.BadRequest("CUSTOMER_NO_DEBT", "Customer has no open debt", nil) }
+		if amount > roundMoney(open)+0.005 { return utils.BadRequest("DEBT_OVERPAID", "Repayment exceeds the open debt", nil) }
+
+		shopID := ifEmpty(body.ShopID, debts[0].ShopID)
+		var shiftID string
+		shift, err := s.shifts.GetOpen(ctx, tenantID, shopID, actor.ID)
+		switch {
+		case err == nil:
+			ok, err := s.shifts.CountOperation(ctx, shift.ID, tenantID)
+			if err != nil { return utils.Internal("CASH_SHIFT_UPDATE_FAILED", "Unable to attach repayment to cash shift", err) }
+			if ok { shiftID = shift.ID.Hex() }
+		case err != mongo.ErrNoDocuments:
+			return utils.Internal("CASH_SHIFT_READ_FAILED", "Unable to read cash shift", err)
+		}
+		// money handed over at the counter has to land in a drawer
+		if shiftID == "" && method != models.SalePaymentTransfer { return utils.BadRequest("SHIFT_NOT_OPEN", "Open a cash shift to take cash or card repayments", nil) }
+
+		left := amount
+		for i := range debts {
+			if left <= 0.005 { break }
+			d := &debts[i]
+			part := roundMoney(math.Min(left, d.Balance))
+			if part <= 0 { continue }
+			d.Paid = roundMoney(d.Paid + part)
+			if err := s.settle(ctx, d, part); err != nil { return err }
+			if err := s.paySale(ctx, d, part); err != nil { return err }
+			entry := models.CustomerDebtEntry{ ShopID: shopID, Method: method, ShiftID: shiftID, SaleID: d.SaleID, Comment: body.Comment }
+			posted, err := s.post(ctx, d, models.DebtEntryRepayment, -part, entry, actor)
+			if err != nil { return err }
+			out = append(out, *posted)
+			left = roundMoney(left - part)
+		}
+		return nil
+	})
+	if err != nil { return nil, err }
+	return out, nil
+}
+
+// Reschedule replaces the unpaid installments of a debt with a new schedule for its balance.
+func (s *CustomerDebtService) Reschedule(ctx context.Context, id string, body models.RescheduleDebtRequest, tenantID string) (*models.CustomerDebt, error) {
+	var out *models.CustomerDebt
+	err := runTx(ctx, s.tx, func(ctx context.Context) error {
+		d, err := s.Get(ctx, id, tenantID)
+		if err != nil { return err }
+		if d.Status != "OPEN" { return utils.BadRequest("CUSTOMER_DEBT_PAID", "Debt is already paid", nil) }
+		schedule, err := debtSchedule(body.Installments, d.Balance, time.Now().UTC())
+		if err != nil { return err }
+		// settled installments stay on the schedule as paid
+		kept := []models.DebtInstallment{}
+		for _, in := range d.Installments {
+			if in.Paid <= 0 { continue }
+			in.Amount = in.Paid
+			kept = append(kept, in)
+		}
+		d.Installments = append(kept, schedule...)
+		out, err = s.repo.Update(ctx, d.ID, tenantID, bson.M{"installments": d.Installments, "due_date": nextDue(d.Installments)})
+		if err != nil { return utils.Internal("CUSTOMER_DEBT_UPDATE_FAILED", "Unable to reschedule debt", err) }
+		return nil
+	})
+	return out, err
+}
+
+// settle lowers the debt's balance, paying its installments off in due order, and saves it.
+func (s *CustomerDebtService) settle(ctx context.Context, d *models.CustomerDebt, amount float64) error {
+	left := amount
+	for i := range d.Installments {
+		in := &d.Installments[i]
+		due := roundMoney(in.Amount - in.Paid)
+		if due <= 0 || left <= 0 { continue }
+		part := math.Min(due, left)
+		in.Paid = roundMoney(in.Paid + part)
+		left -= part
+	}
+	d.Balance = roundMoney(d.Balance - amount)
+	d.DueDate = nextDue(d.Installments)
+	update := bson.M{"installments": d.Installments, "paid": d.Paid, "credited": d.Credited, "balance": d.Balance, "due_date": d.DueDate}
+	if d.Balance <= 0.005 {
+		now := time.Now().UTC()
+		d.Status, d.ClosedAt = "PAID", &now
+		update["status"], update["closed_at"] = d.Status, now
+	}
+	if _, err := s.repo.Update(ctx, d.ID, d.TenantID, update); err != nil { return utils.Internal("CUSTOMER_DEBT_UPDATE_FAILED", "Unable to update debt", err) }
+	return nil
+}
+
+// paySale carries a repayment over to the credit sale's paid amount and payment status.
+func (s *CustomerDebtService) paySale(ctx context.Context, d *models.CustomerDebt, amount float64) error {
+	oid, err := primitive.ObjectIDFromHex(d.SaleID)
+	if err != nil { return nil }
+	sale, err := s.sales.Get(ctx, oid, d.TenantID)
+	if err != nil { return utils.Internal("SALE_READ_FAILED", "Unable to read the credit sale", err) }
+	paid := roundMoney(sale.PaidAmount + amount)
+	status := "partially_paid"
+	if paid >= sale.Total { status = "paid" }
+	if _, err := s.sales.Update(ctx, oid, d.TenantID, bson.M{"paid_amount": paid, "payment_status": status}); err != nil { return utils.Internal("SALE_UPDATE_FAILED", "Unable to update the credit sale", err) }
+	return nil
+}
+
+// post moves the customer's balance and appends the posting to the ledger.
+func (s *CustomerDebtService) post(ctx context.Context, d *models.CustomerDebt, typ string, amount float64, e models.CustomerDebtEntry, actor models.InventoryUser) (*models.CustomerDebtEntry, error) {
+	cid, err := primitive.ObjectIDFromHex(d.CustomerID)
+	if err != nil { return nil, utils.BadRequest("INVALID_CUSTOMER_ID", "Invalid customer id", err) }
+	balance, err := s.customers.IncDebt(ctx, cid, d.TenantID, roundMoney(amount))
+	if err != nil { return nil, utils.Internal("CUSTOMER_DEBT_POST_FAILED", "Unable to update customer balance", err) }
+	e.TenantID, e.CustomerID, e.DebtID, e.Type, e.Amount, e.Balance, e.CreatedBy = d.TenantID, d.CustomerID, d.ID.Hex(), typ, roundMoney(amount), roundMoney(balance), actor
+	e.ShopID = ifEmpty(e.ShopID, d.ShopID)
+	created, err := s.entries.Create(ctx, &e)
+	if err != nil { return nil, utils.Internal("CUSTOMER_DEBT_POST_FAILED", "Unable to post to debt ledger", err) }
+	return created, nil
+}
+
+// debtSchedule turns the requested installments into a schedule for amount. Without installments the whole amount
+// falls due after defaultDebtTerm; installments without amounts split it evenly.
+func debtSchedule(in []models.DebtInstallmentInput, amount float64, now time.Time) ([]models.DebtInstallment, error) {
+	if len(in) == 0 { return []models.DebtInstallment{{ DueDate: now.Add(defaultDebtTerm), Amount: roundMoney(amount) }}, nil }
+	var sum float64
+	for _, it := range in {
+		if it.DueDate.IsZero() { return nil, utils.BadRequest("VALIDATION_ERROR", "Installment due date is required", nil) }
+		if it.Amount < 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Installment amount cannot be negative", nil) }
+		sum += it.Amount
+	}
+	out := make([]models.DebtInstallment, len(in))
+	if sum == 0 {
+		share := roundMoney(amount / float64(len(in)))
+		for i, it := range in { out[i] = models.DebtInstallment{ DueDate: it.DueDate.UTC(), Amount: share } }
+		// rounding leftovers go to the last installment
+		out[len(out)-1].Amount = roundMoney(amount - share*float64(len(in)-1))
+	} else {
+		if math.Abs(sum-amount) > 0.01 { return nil, utils.BadRequest("DEBT_SCHEDULE_MISMATCH", "Installments must add up to the debt", nil) }
+		for i, it := range in { out[i] = models.DebtInstallment{ DueDate: it.DueDate.UTC(), Amount: roundMoney(it.Amount) } }
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].DueDate.Before(out[j].DueDate) })
+	return out, nil
+}
+
+func nextDue(in []models.DebtInstallment) *time.Time {
+	for _, it := range in {
+		if it.Amount-it.Paid > 0.005 { d := it.DueDate; return &d }
+	}
+	return nil
+}
+
+// daysOverdue is the number of whole days past the due date; negative before it.
+func daysOverdue(due, now time.Time) int { return int(math.Floor(now.Sub(due).Hours() / 24)) }
+
+func agingBucket(days int) string {
+	switch {
+	case days < 0:
+		return "current"
+	case days <= 30:
+		return "0-30"
+	case days <= 60:
+		return "31-60"
+	case days <= 90:
+		return "61-90"
+	default:
+		return "90+"
+	}
+}
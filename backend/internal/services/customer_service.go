@@ -81,6 +81,7 @@ func (s *CustomerService) Update(ctx context.Context, id string, body models.Cus
 func (s *CustomerService) Delete(ctx context.Context, id string, tenantID string) error {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil { return utils.BadRequest("INVALID_ID", "Invalid customer id", err) }
+	if m, err := s.repo.Get(ctx, oid, tenantID); err == nil && m.DebtBalance > 0.005 { return utils.Conflict("CUSTOMER_HAS_DEBT", "Customer still owes money and cannot be deleted", nil) }
 	if err := s.repo.Delete(ctx, oid, tenantID); err != nil { return utils.Internal("CUSTOMER_DELETE_FAILED", "Unable to delete customer", err) }
 	return nil
 } 
\ No newline at end of file
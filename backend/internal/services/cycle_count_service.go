@@ -0,0 +1,410 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CycleCountService runs cycle count plans: every IntervalDays a plan generates a PARTIAL inventory of its store,
+// prefilled with the current stock of the products or bins the run covers, and the regular inventory flow counts and
+// finishes it. It also measures how accurate finished counts were, per store and per counter.
+type CycleCountService struct {
+	repo         *repositories.CycleCountPlanRepository
+	inventories  *repositories.InventoryRepository
+	movements    *repositories.StockMovementRepository
+	balances     *repositories.StockRepository
+	products     *repositories.ProductRepository
+	stores       *repositories.StoreRepository
+	bins         *BinService
+	inventorySvc *InventoryService
+}
+
+func NewCycleCountService(repo *repositories.CycleCountPlanRepository, inventories *repositories.InventoryRepository, movements *repositories.StockMovementRepository, balances *repositories.StockRepository, products *repositories.ProductRepository, stores *repositories.StoreRepository, bins *BinService, inventorySvc *InventoryService) *CycleCountService {
+	return &CycleCountService{repo: repo, inventories: inventories, movements: movements, balances: balances, products: products, stores: stores, bins: bins, inventorySvc: inventorySvc}
+}
+
+func (s *CycleCountService) List(ctx context.Context, f models.CycleCountPlanFilterRequest, tenantID string) ([]models.CycleCountPlan, int64, error) {
+	items, total, err := s.repo.List(ctx, repositories.CycleCountPlanListParams{
+		Page: int64(ifZeroInt(f.Page, 1)), Limit: int64(ifZeroInt(f.Limit, 20)), ShopID: f.ShopID, Basis: f.Basis, TenantID: tenantID,
+	})
+	if err != nil { return nil, 0, utils.Internal("CYCLE_COUNT_PLAN_LIST_FAILED", "Unable to list cycle count plans", err) }
+	return items, total, nil
+}
+
+func (s *CycleCountService) Get(ctx context.Context, id string, tenantID string) (*models.CycleCountPlan, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid cycle count plan id", err) }
+	m, err := s.repo.Get(ctx, oid, tenantID)
+	if err != nil { return nil, utils.NotFound("CYCLE_COUNT_PLAN_NOT_FOUND", "Cycle count plan not found", err) }
+	return m, nil
+}
+
+func (s *CycleCountService) Create(ctx context.Context, body models.CreateCycleCountPlanRequest, tenantID string, createdBy models.InventoryUser) (*models.CycleCountPlan, error) {
+	if strings.TrimSpace(body.ShopID) == "" { return nil, utils.BadRequest("STORE_REQUIRED", "Store is required", nil) }
+	m := &models.CycleCountPlan{
+		TenantID: tenantID, Name: strings.TrimSpace(body.Name), ShopID: body.ShopID, Basis: strings.ToLower(strings.TrimSpace(body.Basis)),
+		IntervalDays: body.IntervalDays, ClassDays: body.ClassDays, SalesDays: body.SalesDays, MaxLines: body.MaxLines,
+		CategoryIDs: body.CategoryIDs, LocationIDs: body.LocationIDs, PerRun: body.PerRun, IsActive: true, NextRunAt: time.Now().UTC(), CreatedBy: createdBy,
+	}
+	if body.StartAt != nil { m.NextRunAt = body.StartAt.UTC() }
+	if st, err := s.stores.GetByIDHex(ctx, body.ShopID, tenantID); err == nil { m.ShopName = st.Title }
+	if err := s.validate(ctx, m); err != nil { return nil, err }
+	created, err := s.repo.Create(ctx, m)
+	if err != nil { return nil, utils.Internal("CYCLE_COUNT_PLAN_CREATE_FAILED", "Unable to create cycle count plan", err) }
+	return created, nil
+}
+
+// Update changes a plan's settings. Changing what it rotates through starts the rotation over.
+func (s *CycleCountService) Update(ctx context.Context, id string, body models.UpdateCycleCountPlanRequest, tenantID string) (*models.CycleCountPlan, error) {
+	m, err := s.Get(ctx, id, tenantID)
+	if err != nil { return nil, err }
+	if body.Name != nil { m.Name = strings.TrimSpace(*body.Name) }
+	if body.IntervalDays != nil { m.IntervalDays = *body.IntervalDays }
+	if body.ClassDays != nil { m.ClassDays = *body.ClassDays }
+	if body.SalesDays != nil { m.SalesDays = *body.SalesDays }
+	if body.MaxLines != nil { m.MaxLines = *body.MaxLines }
+	if body.CategoryIDs != nil { m.CategoryIDs, m.Cursor = body.CategoryIDs, 0 }
+	if body.LocationIDs != nil { m.LocationIDs, m.Cursor = body.LocationIDs, 0 }
+	if body.PerRun != nil { m.PerRun = *body.PerRun }
+	if body.IsActive != nil { m.IsActive = *body.IsActive }
+	if body.NextRunAt != nil { m.NextRunAt = body.NextRunAt.UTC() }
+	if err := s.validate(ctx, m); err != nil { return nil, err }
+	out, err := s.repo.Update(ctx, m.ID, tenantID, bson.M{
+		"name": m.Name, "interval_days": m.IntervalDays, "class_days": m.ClassDays, "sales_days": m.SalesDays, "max_lines": m.MaxLines,
+		"category_ids": m.CategoryIDs, "location_ids": m.LocationIDs, "per_run": m.PerRun, "cursor": m.Cursor, "is_active": m.IsActive, "next_run_at": m.NextRunAt,
+	})
+	if err != nil { return nil, utils.Internal("CYCLE_COUNT_PLAN_UPDATE_FAILED", "Unable to update cycle count plan", err) }
+	return out, nil
+}
+
+// Delete removes a plan; the inventories it generated stay.
+func (s *CycleCountService) Delete(ctx context.Context, id string, tenantID string) error {
+	m, err := s.Get(ctx, id, tenantID)
+	if err != nil { return err }
+	if err := s.repo.Delete(ctx, m.ID, tenantID); err != nil { return utils.Internal("CYCLE_COUNT_PLAN_DELETE_FAILED", "Unable to delete cycle count plan", err) }
+	return nil
+}
+
+// Generate runs a plan now, whether due or not, and schedules its next run IntervalDays from now.
+func (s *CycleCountService) Generate(ctx context.Context, id string, tenantID string, user models.InventoryUser) (*models.Inventory, error) {
+	m, err := s.Get(ctx, id, tenantID)
+	if err != nil { return nil, err }
+	now := time.Now().UTC()
+	inv, err := s.run(ctx, m, user, now)
+	if err != nil { return nil, err }
+	if inv == nil { return nil, utils.Conflict("NOTHING_TO_COUNT", "Nothing is due for counting under this plan", nil) }
+	if _, err := s.repo.Update(ctx, m.ID, tenantID, bson.M{"next_run_at": now.AddDate(0, 0, m.IntervalDays)}); err != nil {
+		return nil, utils.Internal("CYCLE_COUNT_PLAN_UPDATE_FAILED", "Unable to update cycle count plan", err)
+	}
+	return inv, nil
+}
+
+// RunDue runs the plans whose next run has come, of every tenant when tenantID is empty, and returns the inventories
+// generated. A plan with nothing due just moves on to its next run; a plan that fails does not hold up the others,
+// and the first failure is returned.
+func (s *CycleCountService) RunDue(ctx context.Context, tenantID string) ([]models.Inventory, error) {
+	now := time.Now().UTC()
+	plans, err := s.repo.Due(ctx, tenantID, now)
+	if err != nil { return nil, utils.Internal("CYCLE_COUNT_PLAN_LIST_FAILED", "Unable to list due cycle count plans", err) }
+	out := []models.Inventory{}
+	var failed error
+	for i := range plans {
+		m := &plans[i]
+		ok, err := s.repo.Claim(ctx, m.ID, m.TenantID, m.NextRunAt, now.AddDate(0, 0, m.IntervalDays))
+		if err != nil { return out, utils.Internal("CYCLE_COUNT_PLAN_UPDATE_FAILED", "Unable to update cycle count plan", err) }
+		if !ok { continue }
+		inv, err := s.run(ctx, m, m.CreatedBy, now)
+		if err != nil && failed == nil { failed = err }
+		if inv != nil { out = append(out, *inv) }
+	}
+	return out, failed
+}
+
+// Schedule runs the due plans of every tenant every interval until ctx is done. Errors go to onErr and do not stop
+// the schedule.
+func (s *CycleCountService) Schedule(ctx context.Context, every time.Duration, onErr func(error)) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		if _, err := s.RunDue(ctx, ""); err != nil && onErr != nil { onErr(err) }
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// run generates the plan's next count. It returns nil when the run covers nothing.
+func (s *CycleCountService) run(ctx context.Context, m *models.CycleCountPlan, user models.InventoryUser, now time.Time) (*models.Inventory, error) {
+	req := models.CreateInventoryRequest{ Name: m.Name + " " + now.Format("2006-01-02"), ShopID: m.ShopID, Type: "PARTIAL", CycleCountPlanID: m.ID.Hex() }
+	cursor := m.Cursor
+	switch m.Basis {
+	case models.CycleCountBasisABC:
+		ids, err := s.dueProducts(ctx, m, now)
+		if err != nil { return nil, err }
+		for _, id := range ids { req.ProductIDs = append(req.ProductIDs, id.Hex()) }
+	case models.CycleCountBasisCategory:
+		var cats []primitive.ObjectID
+		var picked []int
+		picked, cursor = rotation(len(m.CategoryIDs), m.Cursor, m.PerRun)
+		for _, i := range picked {
+			if oid, err := primitive.ObjectIDFromHex(m.CategoryIDs[i]); err == nil { cats = append(cats, oid) }
+		}
+		onHand, err := s.balances.ByShop(ctx, m.TenantID, m.ShopID)
+		if err != nil { return nil, utils.Internal("STOCK_READ_FAILED", "Unable to read stock", err) }
+		ids := make([]primitive.ObjectID, 0, len(onHand))
+		for pid, qty := range onHand { if qty != 0 { ids = append(ids, pid) } }
+		products, err := s.products.ListCountable(ctx, m.TenantID, ids, cats)
+		if err != nil { return nil, utils.Internal("PRODUCT_LIST_FAILED", "Unable to list products", err) }
+		for _, p := range products { req.ProductIDs = append(req.ProductIDs, p.ID.Hex()) }
+	case models.CycleCountBasisBin:
+		bins, err := s.planBins(ctx, m)
+		if err != nil { return nil, err }
+		var picked []int
+		picked, cursor = rotation(len(bins), m.Cursor, m.PerRun)
+		for _, i := range picked { req.LocationIDs = append(req.LocationIDs, bins[i].ID.Hex()) }
+	}
+	if len(req.ProductIDs) == 0 && len(req.LocationIDs) == 0 { return nil, nil }
+	inv, err := s.inventorySvc.Create(ctx, req, m.TenantID, user)
+	if err != nil { return nil, err }
+	if _, err := s.repo.Update(ctx, m.ID, m.TenantID, bson.M{"cursor": cursor, "last_run_at": now, "last_inventory_id": inv.ID.Hex()}); err != nil {
+		return nil, utils.Internal("CYCLE_COUNT_PLAN_UPDATE_FAILED", "Unable to update cycle count plan", err)
+	}
+	return inv, nil
+}
+
+// dueProducts ranks the store's products into ABC classes by the cost of what they sold over the plan's sales window
+// and returns those whose class frequency has elapsed since they were last counted: A first, then the longest
+// uncounted, at most MaxLines of them.
+func (s *CycleCountService) dueProducts(ctx context.Context, m *models.CycleCountPlan, now time.Time) ([]primitive.ObjectID, error) {
+	onHand, err := s.balances.ByShop(ctx, m.TenantID, m.ShopID)
+	if err != nil { return nil, utils.Internal("STOCK_READ_FAILED", "Unable to read stock", err) }
+	used, err := s.movements.CostBySource(ctx, m.TenantID, m.ShopID, []string{models.StockSourceSale, models.StockSourceSaleReturn}, now.AddDate(0, 0, -m.SalesDays))
+	if err != nil { return nil, utils.Internal("CYCLE_COUNT_FAILED", "Unable to read sales", err) }
+	last, err := s.inventories.LastCounted(ctx, m.TenantID, m.ShopID)
+	if err != nil { return nil, utils.Internal("CYCLE_COUNT_FAILED", "Unable to read past counts", err) }
+
+	type candidate struct {
+		id    primitive.ObjectID
+		value float64
+		class int // 0 A, 1 B, 2 C
+		last  time.Time
+	}
+	list := make([]candidate, 0, len(onHand))
+	total := 0.0
+	for pid, qty := range onHand {
+		if qty == 0 { continue }
+		c := candidate{ id: pid, value: math.Max(0, -used[pid]), class: 2, last: last[pid] }
+		total += c.value
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].value > list[j].value })
+	cum := 0.0
+	for i := range list {
+		if list[i].value <= 0 || total <= 0 { break }
+		switch share := cum / total; {
+		case share < 0.8:
+			list[i].class = 0
+		case share < 0.95:
+			list[i].class = 1
+		}
+		cum += list[i].value
+	}
+	days := []int{m.ClassDays.A, m.ClassDays.B, m.ClassDays.C}
+	due := list[:0]
+	for _, c := range list {
+		if c.last.IsZero() || !c.last.AddDate(0, 0, days[c.class]).After(now) { due = append(due, c) }
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		if due[i].class != due[j].class { return due[i].class < due[j].class }
+		return due[i].last.Before(due[j].last)
+	})
+	if len(due) > m.MaxLines { due = due[:m.MaxLines] }
+	out := make([]primitive.ObjectID, 0, len(due))
+	for _, c := range due { out = append(out, c.id) }
+	return out, nil
+}
+
+// planBins returns the active bins a bin plan rotates through, in path order: those under its locations, or every
+// bin of the store.
+func (s *CycleCountService) planBins(ctx context.Context, m *models.CycleCountPlan) ([]models.WarehouseLocation, error) {
+	var bins []models.WarehouseLocation
+	var err error
+	if len(m.LocationIDs) > 0 {
+		bins, err = s.bins.Under(ctx, m.TenantID, m.ShopID, m.LocationIDs)
+	} else {
+		bins, err = s.bins.Locations(ctx, m.TenantID, m.ShopID, models.LocationBin)
+	}
+	if err != nil { return nil, err }
+	out := bins[:0]
+	for _, b := range bins { if b.IsActive { out = append(out, b) } }
+	return out, nil
+}
+
+// rotation picks per items of n starting at cursor, wrapping around, and returns the cursor of the next run.
+func rotation(n, cursor, per int) ([]int, int) {
+	if n == 0 { return nil, 0 }
+	if per > n { per = n }
+	cursor %= n
+	out := make([]int, 0, per)
+	for i := 0; i < per; i++ { out = append(out, (cursor+i)%n) }
+	return out, (cursor + per) % n
+}
+
+func (s *CycleCountService) validate(ctx context.Context, m *models.CycleCountPlan) error {
+	if m.Name == "" { return utils.BadRequest("VALIDATION_ERROR", "Name is required", nil) }
+	m.IntervalDays = ifZeroInt(m.IntervalDays, 7)
+	if m.IntervalDays < 1 || m.IntervalDays > 365 { return utils.BadRequest("VALIDATION_ERROR", "interval_days must be between 1 and 365", nil) }
+	switch m.Basis {
+	case models.CycleCountBasisABC:
+		m.ClassDays.A, m.ClassDays.B, m.ClassDays.C = ifZeroInt(m.ClassDays.A, 30), ifZeroInt(m.ClassDays.B, 90), ifZeroInt(m.ClassDays.C, 180)
+		if m.ClassDays.A < 1 || m.ClassDays.B < 1 || m.ClassDays.C < 1 { return utils.BadRequest("VALIDATION_ERROR", "Class frequencies must be at least 1 day", nil) }
+		m.SalesDays = ifZeroInt(m.SalesDays, 90)
+		if m.SalesDays < 1 || m.SalesDays > 365 { return utils.BadRequest("VALIDATION_ERROR", "sales_days must be between 1 and 365", nil) }
+		m.MaxLines = ifZeroInt(m.MaxLines, 50)
+		if m.MaxLines < 1 || m.MaxLines > 1000 { return utils.BadRequest("VALIDATION_ERROR", "max_lines must be between 1 and 1000", nil) }
+	case models.CycleCountBasisCategory:
+		if len(m.CategoryIDs) == 0 { return utils.BadRequest("VALIDATION_ERROR", "Categories to rotate through are required", nil) }
+		for _, id := range m.CategoryIDs {
+			if _, err := primitive.ObjectIDFromHex(id); err != nil { return utils.BadRequest("INVALID_ID", "Invalid category id", err) }
+		}
+		m.PerRun = ifZeroInt(m.PerRun, 1)
+	case models.CycleCountBasisBin:
+		bins, err := s.planBins(ctx, m)
+		if err != nil { return err }
+		if len(bins) == 0 { return utils.BadRequest("NO_BINS", "The plan covers no bins", nil) }
+		m.PerRun = ifZeroInt(m.PerRun, 10)
+	default:
+		return utils.BadRequest("VALIDATION_ERROR", "Basis must be abc, category or bin", nil)
+	}
+	if m.PerRun < 0 { return utils.BadRequest("VALIDATION_ERROR", "per_run cannot be negative", nil) }
+	return nil
+}
+
+// Accuracy measures the finished inventories of a period, per store and per counter, overall and by day, week or
+// month.
+func (s *CycleCountService) Accuracy(ctx context.Context, f models.CountAccuracyRequest, tenantID string) (*models.CountAccuracyReport, error) {
+	to := time.Now().UTC()
+	if strings.TrimSpace(f.To) != "" {
+		t, err := time.Parse(time.RFC3339, f.To)
+		if err != nil { return nil, utils.BadRequest("VALIDATION_ERROR", "to must be an RFC3339 time", err) }
+		to = t.UTC()
+	}
+	from := to.AddDate(0, 0, -90)
+	if strings.TrimSpace(f.From) != "" {
+		t, err := time.Parse(time.RFC3339, f.From)
+		if err != nil { return nil, utils.BadRequest("VALIDATION_ERROR", "from must be an RFC3339 time", err) }
+		from = t.UTC()
+	}
+	period := strings.ToLower(ifEmpty(f.Period, "week"))
+	if period != "day" && period != "week" && period != "month" { return nil, utils.BadRequest("VALIDATION_ERROR", "period must be day, week or month", nil) }
+	if f.TolerancePct < 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "tolerance_pct cannot be negative", nil) }
+	items, err := s.inventories.Finished(ctx, tenantID, f.ShopID, from, to, f.CycleOnly)
+	if err != nil { return nil, utils.Internal("CYCLE_COUNT_ACCURACY_FAILED", "Unable to read inventories", err) }
+
+	total := &models.CountAccuracy{}
+	stores, counters := &accuracyGroups{byID: map[string]*accuracyGroup{}}, &accuracyGroups{byID: map[string]*accuracyGroup{}}
+	for _, inv := range items {
+		key := periodKey(*inv.FinishedAt, period)
+		one := &models.CountAccuracy{ Counts: 1, VarianceValue: inv.DifferenceSum }
+		for _, it := range inv.Items {
+			diff := math.Abs(it.Scanned - it.Declared)
+			one.Lines++
+			if models.RoundQty(diff) <= models.RoundQty(math.Abs(it.Declared)*f.TolerancePct/100) { one.AccurateLines++ }
+			one.DeclaredQty += math.Abs(it.Declared)
+			one.AbsVarianceQty += diff
+		}
+		addAccuracy(total, one)
+		stores.add(inv.ShopID, inv.ShopName, key, one)
+		counters.add(inv.FinishedBy.ID, inv.FinishedBy.Name, key, one)
+	}
+	finishAccuracy(total)
+	return &models.CountAccuracyReport{ From: from, To: to, Period: period, Total: *total, Stores: stores.list(), Counters: counters.list() }, nil
+}
+
+type accuracyGroup struct {
+	group   models.CountAccuracyGroup
+	periods map[string]*models.CountAccuracy
+}
+
+type accuracyGroups struct {
+	byID  map[string]*accuracyGroup
+	order []string
+}
+
+func (g *accuracyGroups) add(id, name, period string, one *models.CountAccuracy) {
+	a := g.byID[id]
+	if a == nil {
+		a = &accuracyGroup{ group: models.CountAccuracyGroup{ ID: id, Name: name }, periods: map[string]*models.CountAccuracy{} }
+		g.byID[id] = a
+		g.order = append(g.order, id)
+	}
+	addAccuracy(&a.group.CountAccuracy, one)
+	p := a.periods[period]
+	if p == nil {
+		p = &models.CountAccuracy{}
+		a.periods[period] = p
+	}
+	addAccuracy(p, one)
+}
+
+func (g *accuracyGroups) list() []models.CountAccuracyGroup {
+	out := make([]models.CountAccuracyGroup, 0, len(g.order))
+	for _, id := range g.order {
+		a := g.byID[id]
+		finishAccuracy(&a.group.CountAccuracy)
+		a.group.Periods = make([]models.CountAccuracyPeriod, 0, len(a.periods))
+		for key, p := range a.periods {
+			finishAccuracy(p)
+			a.group.Periods = append(a.group.Periods, models.CountAccuracyPeriod{ Period: key, CountAccuracy: *p })
+		}
+		sort.Slice(a.group.Periods, func(i, j int) bool { return a.group.Periods[i].Period < a.group.Periods[j].Period })
+		out = append(out, a.group)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func addAccuracy(to, one *models.CountAccuracy) {
+	to.Counts += one.Counts
+	to.Lines += one.Lines
+	to.AccurateLines += one.AccurateLines
+	to.DeclaredQty = models.RoundQty(to.DeclaredQty + one.DeclaredQty)
+	to.AbsVarianceQty = models.RoundQty(to.AbsVarianceQty + one.AbsVarianceQty)
+	to.VarianceValue = roundMoney(to.VarianceValue + one.VarianceValue)
+}
+
+// finishAccuracy works out the percentages from the sums.
+func finishAccuracy(a *models.CountAccuracy) {
+	if a.Lines > 0 { a.LineAccuracy = roundMoney(100 * float64(a.AccurateLines) / float64(a.Lines)) }
+	switch {
+	case a.DeclaredQty > 0:
+		a.QtyAccuracy = roundMoney(math.Max(0, 100-100*a.AbsVarianceQty/a.DeclaredQty))
+	case a.Lines > 0 && a.AbsVarianceQty == 0:
+		a.QtyAccuracy = 100
+	}
+}
+
+// periodKey names the day, ISO week or month a time falls in, in an order that sorts as text.
+func periodKey(t time.Time, period string) string {
+	switch period {
+	case "day":
+		return t.Format("2006-01-02")
+	case "month":
+		return t.Format("2006-01")
+	}
+	y, w := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", y, w)
+}
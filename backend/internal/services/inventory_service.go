@@ -19,9 +19,10 @@ type InventoryService struct {
 	stores *repositories.StoreRepository
 	productRepo *repositories.ProductRepository
 	importHistoryRepo *repositories.ImportHistoryRepository
+	stock *StockService
 }
 
-func NewInventoryService(repo *repositories.InventoryRepository, stores *repositories.StoreRepository, productRepo *repositories.ProductRepository, importHistoryRepo *repositories.ImportHistoryRepository) *InventoryService { return &InventoryService{repo: repo, stores: stores, productRepo: productRepo, importHistoryRepo: importHistoryRepo} }
+func NewInventoryService(repo *repositories.InventoryRepository, stores *repositories.StoreRepository, productRepo *repositories.ProductRepository, importHistoryRepo *repositories.ImportHistoryRepository, stock *StockService) *InventoryService { return &InventoryService{repo: repo, stores: stores, productRepo: productRepo, importHistoryRepo: importHistoryRepo, stock: stock} }
 
 func (s *InventoryService) List(ctx context.Context, f models.InventoryFilterRequest, tenantID string) ([]models.Inventory, int64, error) {
 	var fromPtr, toPtr *time.Time
@@ -74,12 +75,51 @@ func (s *InventoryService) Create(ctx context.Context, body models.CreateInvento
 		CreatedBy: createdBy,
 		ProcessPct: 0,
 	}
+	// a count of some locations is prefilled with what their bins hold
+	if len(body.LocationIDs) > 0 && s.stock != nil {
+		bins, rows, err := s.stock.BinContents(ctx, tenantID, body.ShopID, body.LocationIDs)
+		if err != nil { return nil, err }
+		if len(bins) == 0 { return nil, utils.BadRequest("NO_BINS", "The chosen locations hold no bins", nil) }
+		m.Type = "PARTIAL"
+		for _, b := range bins { m.BinIDs = append(m.BinIDs, b.ID.Hex()) }
+		m.Items = make([]models.InventoryItem, 0, len(rows))
+		for _, r := range rows {
+			p, err := s.productRepo.Get(ctx, r.ProductID, tenantID)
+			if err != nil { continue }
+			m.Items = append(m.Items, models.InventoryItem{ ProductID: p.ID, ProductName: p.Name, ProductSKU: p.SKU, Barcode: p.Barcode, Declared: r.Qty, Unit: p.Unit, Price: p.Price, CostPrice: p.CostPrice, BinID: r.BinID.Hex(), BinPath: r.BinPath })
+		}
+	}
+	// so is a count of some products, with what the store has of them
+	if len(body.ProductIDs) > 0 && s.stock != nil {
+		m.Type = "PARTIAL"
+		for _, id := range body.ProductIDs {
+			pid, err := primitive.ObjectIDFromHex(id)
+			if err != nil { return nil, utils.BadRequest("INVALID_PRODUCT_ID", "Invalid product id", err) }
+			p, err := s.productRepo.Get(ctx, pid, tenantID)
+			if err != nil { return nil, utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found", err) }
+			qty, err := s.stock.Available(ctx, tenantID, pid, body.ShopID)
+			if err != nil { return nil, err }
+			m.Items = append(m.Items, models.InventoryItem{ ProductID: p.ID, ProductName: p.Name, ProductSKU: p.SKU, Barcode: p.Barcode, Declared: qty, Unit: p.Unit, Price: p.Price, CostPrice: p.CostPrice })
+		}
+	}
+	m.CycleCountPlanID = body.CycleCountPlanID
 	created, err := s.repo.Create(ctx, m)
 	if err != nil { return nil, utils.Internal("INVENTORY_CREATE_FAILED", "Unable to create inventory", err) }
 	return created, nil
 }
 
+// Update runs in one transaction: finishing an inventory sets the counted stock together with the finished status.
 func (s *InventoryService) Update(ctx context.Context, id string, body models.UpdateInventoryRequest, tenantID string, user models.InventoryUser) (*models.Inventory, error) {
+	var out *models.Inventory
+	err := s.stock.Atomically(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = s.update(ctx, id, body, tenantID, user)
+		return err
+	})
+	return out, err
+}
+
+func (s *InventoryService) update(ctx context.Context, id string, body models.UpdateInventoryRequest, tenantID string, user models.InventoryUser) (*models.Inventory, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid inventory id", nil) }
 	update := bson.M{}
@@ -91,35 +131,85 @@ func (s *InventoryService) Update(ctx context.Context, id string, body models.Up
 		var total float64
 		var shortage, surplus int
 		var differenceSum float64
-		for _, it := range body.Items {
+		cur, err := s.repo.Get(ctx, oid, tenantID)
+		if err != nil { return nil, utils.NotFound("INVENTORY_NOT_FOUND", "Inventory not found", err) }
+		for i := range body.Items {
+			it := &body.Items[i]
 			var pid primitive.ObjectID
 			if it.ProductID != "" { if oid, err := primitive.ObjectIDFromHex(it.ProductID); err == nil { pid = oid } }
-			items = append(items, models.InventoryItem{ ProductID: pid, ProductName: it.ProductName, ProductSKU: it.ProductSKU, Barcode: it.Barcode, Declared: it.Declared, Scanned: it.Scanned, Unit: it.Unit, Price: it.Price, CostPrice: it.CostPrice })
+			// a count entered in another unit of the product is kept, and applied, in the base unit
+			var inputUnit string
+			if pid != primitive.NilObjectID && s.stock != nil {
+				if p, err := s.productRepo.Get(ctx, pid, tenantID); err == nil {
+					scanned, err := s.stock.inUnit(ctx, p, it.Unit, it.Scanned)
+					if err != nil { return nil, err }
+					declared, err := s.stock.inUnit(ctx, p, it.Unit, it.Declared)
+					if err != nil { return nil, err }
+					inputUnit = scanned.inputUnit
+					it.Scanned, it.Declared, it.Unit = scanned.qty, declared.qty, scanned.unit
+					it.Price, it.CostPrice = scanned.price(it.Price), scanned.price(it.CostPrice)
+				}
+			}
+			var lotNumber, binPath string
+			if it.LotID != "" && it.BinID != "" { return nil, utils.BadRequest("VALIDATION_ERROR", "A line counts either a lot or a bin", nil) }
+			if it.LotID != "" && s.stock != nil {
+				lot, err := s.stock.Lot(ctx, tenantID, it.LotID, pid, cur.ShopID)
+				if err != nil { return nil, err }
+				lotNumber = lot.Number
+			}
+			if it.BinID != "" && s.stock != nil {
+				bin, err := s.stock.Bin(ctx, tenantID, it.BinID, cur.ShopID)
+				if err != nil { return nil, err }
+				binPath = bin.Path
+			}
+			items = append(items, models.InventoryItem{ ProductID: pid, ProductName: it.ProductName, ProductSKU: it.ProductSKU, Barcode: it.Barcode, Declared: it.Declared, Scanned: it.Scanned, Unit: it.Unit, InputUnit: inputUnit, Price: it.Price, CostPrice: it.CostPrice, LotID: it.LotID, LotNumber: lotNumber, BinID: it.BinID, BinPath: binPath })
 			// totals
 			total += it.Scanned
 			if it.Scanned < it.Declared { shortage++ }
 			if it.Scanned > it.Declared { surplus++ }
 			diffQty := it.Scanned - it.Declared
-			if diffQty > 0 { differenceSum += diffQty * it.Price } else if diffQty < 0 { differenceSum += diffQty * it.CostPrice }
+			if diffQty > 0 {
+				differenceSum += diffQty * it.Price
+			} else if diffQty < 0 {
+				// a shortage loses the store's stock at its computed cost
+				cost := it.CostPrice
+				if pid != primitive.NilObjectID && s.stock != nil {
+					if cost, err = s.stock.UnitCost(ctx, tenantID, pid, cur.ShopID, it.CostPrice); err != nil { return nil, err }
+				}
+				differenceSum += diffQty * cost
+			}
 		}
 		update["items"] = items
-		update["total_measurement_value"] = total
+		update["total_measurement_value"] = models.RoundQty(total)
 		update["shortage"] = shortage
 		update["surplus"] = surplus
 		update["difference_sum"] = differenceSum
 		bodyItems = body.Items
 	}
-	if body.Finished { now := time.Now().UTC(); update["finished_at"] = now; update["finished_by"] = user; update["status_id"] = "finished" }
+	// stock is applied only on the first finish; finishing again just saves the document
+	applyStock := false
+	if body.Finished {
+		cur, err := s.repo.Get(ctx, oid, tenantID)
+		if err != nil { return nil, utils.NotFound("INVENTORY_NOT_FOUND", "Inventory not found", err) }
+		applyStock = cur.StatusID != "finished"
+		now := time.Now().UTC(); update["finished_at"] = now; update["finished_by"] = user; update["status_id"] = "finished"
+	}
+	if applyStock {
+		// the first finish is claimed before any stock moves, so two concurrent finishes cannot both apply the count
+		ok, err := s.repo.UpdateIf(ctx, oid, tenantID, bson.M{"status_id": bson.M{"$ne": "finished"}}, update)
+		if err != nil { return nil, utils.Internal("INVENTORY_UPDATE_FAILED", "Unable to update inventory", err) }
+		if !ok { return nil, utils.Conflict("INVENTORY_FINISHED", "The inventory was finished by another request", nil) }
+	}
 	m, err := s.repo.Update(ctx, oid, tenantID, update)
 	if err != nil { return nil, utils.Internal("INVENTORY_UPDATE_FAILED", "Unable to update inventory", err) }
-	// On finish: update product stocks to match scanned values
-	if body.Finished && s.productRepo != nil {
+	// On finish: set the shop's stock of each counted product to the scanned value
+	if applyStock && s.stock != nil {
 		itemsToApply := bodyItems
 		if itemsToApply == nil || len(itemsToApply) == 0 {
 			if m2, err2 := s.repo.Get(ctx, oid, tenantID); err2 == nil && m2 != nil {
 				itemsToApply = make([]models.InventoryItemInput, 0, len(m2.Items))
 				for _, it := range m2.Items {
-					itemsToApply = append(itemsToApply, models.InventoryItemInput{ ProductID: it.ProductID.Hex(), ProductName: it.ProductName, ProductSKU: it.ProductSKU, Barcode: it.Barcode, Declared: it.Declared, Scanned: it.Scanned, Unit: it.Unit, Price: it.Price, CostPrice: it.CostPrice })
+					itemsToApply = append(itemsToApply, models.InventoryItemInput{ ProductID: it.ProductID.Hex(), ProductName: it.ProductName, ProductSKU: it.ProductSKU, Barcode: it.Barcode, Declared: it.Declared, Scanned: it.Scanned, Unit: it.Unit, Price: it.Price, CostPrice: it.CostPrice, LotID: it.LotID, BinID: it.BinID })
 				}
 			}
 		}
@@ -127,32 +217,43 @@ func (s *InventoryService) Update(ctx context.Context, id string, body models.Up
 			if it.ProductID == "" { continue }
 			pid, err := primitive.ObjectIDFromHex(it.ProductID)
 			if err != nil { continue }
-			// Set actual stock equal to scanned
-			_ = s.productRepo.UpdateStock(ctx, pid, tenantID, int(it.Scanned))
+			// Set actual stock equal to scanned; a surplus comes in at the store's current cost
+			cost, err := s.stock.UnitCost(ctx, tenantID, pid, m.ShopID, it.CostPrice)
+			if err != nil { return nil, err }
+			src := models.StockSource{ Type: models.StockSourceInventory, ID: m.ID.Hex(), Actor: user }
+			if it.LotID != "" {
+				// a lot line moves the store's stock by the lot's difference only
+				lot, err := s.stock.Lot(ctx, tenantID, it.LotID, pid, m.ShopID)
+				if err != nil { return nil, err }
+				src.Lot = &models.StockLotRef{ ID: lot.ID }
+				if err := s.stock.Adjust(ctx, tenantID, pid, m.ShopID, models.RoundQty(it.Scanned-lot.Qty), cost, src); err != nil { return nil, err }
+				continue
+			}
+			if it.BinID != "" {
+				// so does a bin line, by the bin's difference
+				bin, err := s.stock.Bin(ctx, tenantID, it.BinID, m.ShopID)
+				if err != nil { return nil, err }
+				inBin, err := s.stock.BinQty(ctx, tenantID, bin.ID, pid)
+				if err != nil { return nil, err }
+				src.Bin = bin.ID
+				if err := s.stock.Adjust(ctx, tenantID, pid, m.ShopID, models.RoundQty(it.Scanned-inBin), cost, src); err != nil { return nil, err }
+				continue
+			}
+			if _, err := s.stock.Set(ctx, tenantID, pid, m.ShopID, it.Scanned, cost, src); err != nil { return nil, err }
 		}
-		// Additionally, record surplus to import history
+		// Additionally, record surplus to import history, within the same transaction
 		if s.importHistoryRepo != nil {
-			go func(items []models.InventoryItemInput) {
-				defer func(){ _ = recover() }()
-				if len(items) == 0 { return }
-				var storeName string
-				storeID := ""
-				if inv, err := s.repo.Get(ctx, oid, tenantID); err == nil && inv != nil {
-					storeID = inv.ShopID
-					storeName = inv.ShopName
+			ihItems := make([]models.ImportHistoryItemInput, 0)
+			for _, it := range itemsToApply {
+				qty := models.RoundQty(it.Scanned - it.Declared)
+				if qty > 0 {
+					ihItems = append(ihItems, models.ImportHistoryItemInput{ ProductID: it.ProductID, ProductName: it.ProductName, ProductSKU: it.ProductSKU, Barcode: it.Barcode, Qty: qty, Unit: it.Unit })
 				}
+			}
+			if len(ihItems) > 0 {
 				svc := NewImportHistoryService(s.importHistoryRepo)
-				// build items with surplus only
-				ihItems := make([]models.ImportHistoryItemInput, 0)
-				for _, it := range items {
-					qty := int(it.Scanned - it.Declared)
-					if qty > 0 {
-						ihItems = append(ihItems, models.ImportHistoryItemInput{ ProductID: it.ProductID, ProductName: it.ProductName, ProductSKU: it.ProductSKU, Barcode: it.Barcode, Qty: qty, Unit: it.Unit })
-					}
-				}
-				if len(ihItems) == 0 { return }
-				_, _ = svc.Create(ctx, tenantID, user.ID, models.CreateImportHistoryRequest{ FileName: "Inventory surplus", StoreID: storeID, StoreName: storeName, TotalRows: len(ihItems), SuccessRows: len(ihItems), ErrorRows: 0, Status: "completed", ImportType: "INVENTORY_SURPLUS", Items: ihItems })
-			}(itemsToApply)
+				if _, err := svc.Create(ctx, tenantID, user.ID, models.CreateImportHistoryRequest{ FileName: "Inventory surplus", StoreID: m.ShopID, StoreName: m.ShopName, TotalRows: len(ihItems), SuccessRows: len(ihItems), ErrorRows: 0, Status: "completed", ImportType: "INVENTORY_SURPLUS", Items: ihItems }); err != nil { return nil, err }
+			}
 		}
 	}
 	return m, nil
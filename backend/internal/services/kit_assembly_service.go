@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// KitAssemblyService assembles kits of assembled SETs out of their components and takes them apart again. A NEW
+// document reserves what it will take; approval moves the stock and rolls the cost of what was taken into what
+// was put in.
+type KitAssemblyService struct {
+	repo    *repositories.KitAssemblyRepository
+	store   *repositories.StoreRepository
+	product *repositories.ProductRepository
+	stock   *StockService
+}
+
+func NewKitAssemblyService(repo *repositories.KitAssemblyRepository, store *repositories.StoreRepository, product *repositories.ProductRepository, stock *StockService) *KitAssemblyService {
+	return &KitAssemblyService{repo: repo, store: store, product: product, stock: stock}
+}
+
+func (s *KitAssemblyService) List(ctx context.Context, f models.KitAssemblyFilterRequest, tenantID string) ([]models.KitAssembly, int64, error) {
+	p := repositories.KitAssemblyListParams{
+		Page: int64(ifZeroInt(f.Page, 1)), Limit: int64(ifZeroInt(f.Limit, 20)), Sort: bson.D{{Key: sortField(f.SortBy, "created_at"), Value: sortOrderValue(f.SortOrder)}},
+		Type: f.Type, ShopID: f.ShopID, Status: f.Status, TenantID: tenantID,
+	}
+	if f.ProductID != "" {
+		pid, err := primitive.ObjectIDFromHex(f.ProductID)
+		if err != nil { return nil, 0, utils.BadRequest("INVALID_ID", "Invalid product id", err) }
+		p.ProductID = &pid
+	}
+	items, total, err := s.repo.List(ctx, p)
+	if err != nil { return nil, 0, utils.Internal("KIT_ASSEMBLY_LIST_FAILED", "Unable to list kit assemblies", err) }
+	return items, total, nil
+}
+
+func (s *KitAssemblyService) Get(ctx context.Context, id string, tenantID string) (*models.KitAssembly, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid kit assembly id", err) }
+	m, err := s.repo.Get(ctx, oid, tenantID)
+	if err != nil { return nil, utils.NotFound("KIT_ASSEMBLY_NOT_FOUND", "Kit assembly not found", err) }
+	return m, nil
+}
+
+// Create snapshots the kit's composition and reserves the components to assemble, or the kits to take apart.
+func (s *KitAssemblyService) Create(ctx context.Context, body models.CreateKitAssemblyRequest, tenantID string, createdBy models.InventoryUser) (*models.KitAssembly, error) {
+	kind := ifEmpty(body.Type, models.KitAssemblyTypeAssembly)
+	if kind != models.KitAssemblyTypeAssembly && kind != models.KitAssemblyTypeDisassembly {
+		return nil, utils.BadRequest("VALIDATION_ERROR", "type must be assembly or disassembly", nil)
+	}
+	if body.ShopID == "" { return nil, utils.BadRequest("STORE_REQUIRED", "Store is required", nil) }
+	pid, err := primitive.ObjectIDFromHex(body.ProductID)
+	if err != nil { return nil, utils.BadRequest("INVALID_PRODUCT_ID", "Invalid product id", err) }
+	p, err := s.product.Get(ctx, pid, tenantID)
+	if err != nil { return nil, utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found", err) }
+	if p.ProductType != models.ProductKindSet || p.VirtualSet() {
+		return nil, utils.BadRequest("KIT_NOT_ASSEMBLED", "Only an assembled SET can be assembled or taken apart", nil)
+	}
+	if p.SerialTracked { return nil, utils.BadRequest("KIT_SERIALS_UNSUPPORTED", "Serial-tracked kits cannot be assembled", nil) }
+	comps := make([]models.KitComponent, 0, len(p.SetItems))
+	for _, si := range p.SetItems {
+		if si.Quantity <= 0 { continue }
+		cp, err := s.product.Get(ctx, si.ProductID, tenantID)
+		if err != nil { return nil, utils.BadRequest("PRODUCT_NOT_FOUND", "Component of "+p.Name+" not found", err) }
+		if cp.SerialTracked { return nil, utils.BadRequest("KIT_SERIALS_UNSUPPORTED", "Kits of serial-tracked components such as "+cp.Name+" cannot be assembled", nil) }
+		comps = append(comps, models.KitComponent{ProductID: cp.ID, ProductName: cp.Name, ProductSKU: cp.SKU, Unit: ifEmpty(cp.Unit, "pcs"), PerKit: si.Quantity})
+	}
+	if len(comps) == 0 { return nil, utils.BadRequest("KIT_EMPTY", p.Name+" has no components", nil) }
+	q, err := s.stock.inUnit(ctx, p, body.Unit, body.Qty)
+	if err != nil { return nil, err }
+	if q.qty <= 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Quantity must be greater than 0", nil) }
+
+	shopName := ""
+	if st, err := s.store.GetByIDHex(ctx, body.ShopID, tenantID); err == nil { shopName = st.Title }
+	m := &models.KitAssembly{
+		TenantID: tenantID, ExternalID: generateExternalID(), Type: kind, ShopID: body.ShopID, ShopName: shopName,
+		ProductID: p.ID, ProductName: p.Name, ProductSKU: p.SKU, Unit: ifEmpty(p.Unit, "pcs"), Qty: q.qty, Comment: body.Comment,
+		Status: "NEW", Components: kitComponents(comps, q.qty), CreatedBy: createdBy,
+	}
+	err = s.stock.Atomically(ctx, func(ctx context.Context) error {
+		created, err := s.repo.Create(ctx, m)
+		if err != nil { return utils.Internal("KIT_ASSEMBLY_CREATE_FAILED", "Unable to create kit assembly", err) }
+		m = created
+		return s.reserve(ctx, m, createdBy)
+	})
+	if err != nil { return nil, err }
+	return m, nil
+}
+
+// Update runs in one transaction: an approval's stock movements commit together with the APPROVED status.
+func (s *KitAssemblyService) Update(ctx context.Context, id string, body models.UpdateKitAssemblyRequest, tenantID string, actor models.InventoryUser) (*models.KitAssembly, error) {
+	var out *models.KitAssembly
+	err := s.stock.Atomically(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = s.update(ctx, id, body, tenantID, actor)
+		return err
+	})
+	return out, err
+}
+
+func (s *KitAssemblyService) update(ctx context.Context, id string, body models.UpdateKitAssemblyRequest, tenantID string, actor models.InventoryUser) (*models.KitAssembly, error) {
+	cur, err := s.Get(ctx, id, tenantID)
+	if err != nil { return nil, err }
+	if cur.Status != "NEW" { return nil, utils.Conflict("KIT_ASSEMBLY_FINISHED", "The kit assembly is already "+cur.Status, nil) }
+	update := bson.M{}
+	if body.Comment != nil { update["comment"] = *body.Comment }
+	if body.Qty != nil {
+		p, err := s.product.Get(ctx, cur.ProductID, tenantID)
+		if err != nil { return nil, utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found", err) }
+		q, err := s.stock.inUnit(ctx, p, body.Unit, *body.Qty)
+		if err != nil { return nil, err }
+		if q.qty <= 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Quantity must be greater than 0", nil) }
+		cur.Qty, cur.Components = q.qty, kitComponents(cur.Components, q.qty)
+		update["qty"], update["components"] = cur.Qty, cur.Components
+		if err := s.reserve(ctx, cur, actor); err != nil { return nil, err }
+	}
+
+	// an approval or rejection takes the document out of NEW before any stock moves, and an edit only saves while it
+	// is still NEW, so a concurrent request cannot act on it twice
+	status := map[string]string{"approve": "APPROVED", "reject": "REJECTED"}[body.Action]
+	if body.Action != "" && status == "" { return nil, utils.BadRequest("VALIDATION_ERROR", "action must be approve or reject", nil) }
+	if status != "" { update["status"], update["finished_at"], update["finished_by"] = status, time.Now().UTC(), actor }
+	ok, err := s.repo.UpdateIf(ctx, cur.ID, tenantID, bson.M{"status": "NEW"}, update)
+	if err != nil { return nil, utils.Internal("KIT_ASSEMBLY_UPDATE_FAILED", "Unable to update kit assembly", err) }
+	if !ok { return nil, utils.Conflict("KIT_ASSEMBLY_FINISHED", "The kit assembly was changed by another request", nil) }
+	update = bson.M{}
+
+	switch body.Action {
+	case "approve":
+		src := kitSource(cur, actor)
+		var err error
+		if cur.Type == models.KitAssemblyTypeDisassembly { err = s.disassemble(ctx, cur, src) } else { err = s.assemble(ctx, cur, src) }
+		if err != nil { return nil, err }
+		if err := s.stock.Release(ctx, tenantID, src); err != nil { return nil, err }
+		update["components"], update["lots"] = cur.Components, cur.Lots
+		update["unit_cost"], update["cost_total"] = cur.UnitCost, cur.CostTotal
+	case "reject":
+		if err := s.stock.Release(ctx, tenantID, kitSource(cur, actor)); err != nil { return nil, err }
+	default:
+		return s.Get(ctx, id, tenantID)
+	}
+
+	m, err := s.repo.Update(ctx, cur.ID, tenantID, update)
+	if err != nil { return nil, utils.Internal("KIT_ASSEMBLY_UPDATE_FAILED", "Unable to update kit assembly", err) }
+	return m, nil
+}
+
+// Delete removes a document that never moved stock, releasing what it reserved.
+func (s *KitAssemblyService) Delete(ctx context.Context, id string, tenantID string) error {
+	m, err := s.Get(ctx, id, tenantID)
+	if err != nil { return err }
+	if m.Status == "APPROVED" { return utils.Conflict("KIT_ASSEMBLY_APPROVED", "An approved kit assembly cannot be deleted", nil) }
+	return s.stock.Atomically(ctx, func(ctx context.Context) error {
+		if err := s.stock.Release(ctx, tenantID, kitSource(m, models.InventoryUser{})); err != nil { return err }
+		if err := s.repo.Delete(ctx, m.ID, tenantID); err != nil { return utils.Internal("KIT_ASSEMBLY_DELETE_FAILED", "Unable to delete kit assembly", err) }
+		return nil
+	})
+}
+
+// assemble takes the components out of the store and puts the kits in at the cost of their components.
+func (s *KitAssemblyService) assemble(ctx context.Context, m *models.KitAssembly, src models.StockSource) error {
+	var total float64
+	for i := range m.Components {
+		c := &m.Components[i]
+		taken, err := s.take(ctx, m, c.ProductID, c.Qty, src)
+		if err != nil { return err }
+		c.UnitCost, c.CostTotal, c.Lots = taken.UnitCost, roundMoney(taken.UnitCost*c.Qty), taken.Lots
+		total += c.CostTotal
+	}
+	m.CostTotal, m.UnitCost = roundMoney(total), roundCost(total/m.Qty)
+	return s.stock.Adjust(ctx, m.TenantID, m.ProductID, m.ShopID, m.Qty, m.UnitCost, src)
+}
+
+// disassemble takes the kits out of the store and puts their components in. The cost of the kits is shared out over
+// the components by what they cost in the store now, or by quantity when none of them has a cost.
+func (s *KitAssemblyService) disassemble(ctx context.Context, m *models.KitAssembly, src models.StockSource) error {
+	taken, err := s.take(ctx, m, m.ProductID, m.Qty, src)
+	if err != nil { return err }
+	m.UnitCost, m.CostTotal, m.Lots = taken.UnitCost, roundMoney(taken.UnitCost*m.Qty), taken.Lots
+	weights := make([]float64, len(m.Components))
+	var sum float64
+	for i, c := range m.Components {
+		fallback := 0.0
+		if cp, err := s.product.Get(ctx, c.ProductID, m.TenantID); err == nil { fallback = cp.CostPrice }
+		cost, err := s.stock.UnitCost(ctx, m.TenantID, c.ProductID, m.ShopID, fallback)
+		if err != nil { return err }
+		weights[i] = cost * c.Qty
+		sum += weights[i]
+	}
+	if sum <= 0 {
+		sum = 0
+		for i, c := range m.Components { weights[i] = c.Qty; sum += c.Qty }
+	}
+	left := m.CostTotal
+	for i := range m.Components {
+		c := &m.Components[i]
+		c.CostTotal = roundMoney(m.CostTotal * weights[i] / sum)
+		// the last component takes the rounding remainder so the parts add up to the kits
+		if i == len(m.Components)-1 { c.CostTotal = roundMoney(left) }
+		left -= c.CostTotal
+		c.UnitCost = roundCost(c.CostTotal / c.Qty)
+		if err := s.stock.Adjust(ctx, m.TenantID, c.ProductID, m.ShopID, c.Qty, c.UnitCost, src); err != nil { return err }
+	}
+	return nil
+}
+
+// take removes qty of a product from the document's store, naming the product when it is short. Units other
+// documents reserved cannot be used.
+func (s *KitAssemblyService) take(ctx context.Context, m *models.KitAssembly, productID primitive.ObjectID, qty float64, src models.StockSource) (models.StockTaken, error) {
+	p, err := s.product.Get(ctx, productID, m.TenantID)
+	if err != nil { return models.StockTaken{}, utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found", err) }
+	free, err := s.stock.Free(ctx, m.TenantID, p.ID, m.ShopID, src)
+	if err != nil { return models.StockTaken{}, err }
+	if qty > free { return models.StockTaken{}, utils.Conflict("INSUFFICIENT_STOCK", "Not enough stock of "+p.Name, nil) }
+	taken, err := s.stock.Take(ctx, m.TenantID, p.ID, m.ShopID, qty, p.CostPrice, src)
+	if ae, ok := err.(*utils.AppError); ok && ae.Code == "INSUFFICIENT_STOCK" { return taken, utils.Conflict("INSUFFICIENT_STOCK", "Not enough stock of "+p.Name, nil) }
+	return taken, err
+}
+
+// reserve holds what a NEW document will take: the components of an assembly, the kits of a disassembly.
+func (s *KitAssemblyService) reserve(ctx context.Context, m *models.KitAssembly, actor models.InventoryUser) error {
+	lines := []models.ReservationLine{{ProductID: m.ProductID, Qty: m.Qty}}
+	name := "Disassembly " + strconv.FormatInt(m.ExternalID, 10)
+	if m.Type == models.KitAssemblyTypeAssembly {
+		lines = lines[:0]
+		for _, c := range m.Components { lines = append(lines, models.ReservationLine{ProductID: c.ProductID, Qty: c.Qty}) }
+		name = "Assembly " + strconv.FormatInt(m.ExternalID, 10)
+	}
+	return s.stock.Reserve(ctx, m.TenantID, m.ShopID, kitSource(m, actor), name, lines)
+}
+
+// kitComponents sizes the components for qty kits.
+func kitComponents(comps []models.KitComponent, qty float64) []models.KitComponent {
+	out := make([]models.KitComponent, len(comps))
+	for i, c := range comps {
+		c.Qty = models.RoundQty(c.PerKit * qty)
+		out[i] = c
+	}
+	return out
+}
+
+func kitSource(m *models.KitAssembly, actor models.InventoryUser) models.StockSource {
+	src := models.StockSource{Type: models.StockSourceAssembly, ID: m.ID.Hex(), Actor: actor}
+	if m.Type == models.KitAssemblyTypeDisassembly { src.Type = models.StockSourceDisassembly }
+	return src
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LotService keeps the batches of stock. StockService posts every balance change to it: increases that name a lot
+// go into it, decreases come out of the named lot or, without one, out of the lots expiring first (FEFO).
+type LotService struct {
+	repo     *repositories.StockLotRepository
+	products *repositories.ProductRepository
+}
+
+func NewLotService(repo *repositories.StockLotRepository, products *repositories.ProductRepository) *LotService {
+	return &LotService{repo: repo, products: products}
+}
+
+// List returns a product's lots, optionally in one store and only those with stock left.
+func (s *LotService) List(ctx context.Context, tenantID, productID, shopID string, onlyOpen bool, page, limit int64) ([]models.StockLot, int64, error) {
+	pid, err := primitive.ObjectIDFromHex(productID)
+	if err != nil { return nil, 0, utils.BadRequest("INVALID_ID", "Invalid product id", err) }
+	items, total, err := s.repo.List(ctx, repositories.StockLotListParams{TenantID: tenantID, ProductID: pid, ShopID: shopID, OnlyOpen: onlyOpen, Page: page, Limit: limit})
+	if err != nil { return nil, 0, utils.Internal("LOT_LIST_FAILED", "Unable to list lots", err) }
+	return items, total, nil
+}
+
+// Get loads a lot by id, checking that it holds the given product in the given store.
+func (s *LotService) Get(ctx context.Context, tenantID, lotID string, productID primitive.ObjectID, shopID string) (*models.StockLot, error) {
+	oid, err := primitive.ObjectIDFromHex(lotID)
+	if err != nil { return nil, utils.BadRequest("INVALID_LOT_ID", "Invalid lot id", err) }
+	return s.lot(ctx, oid, tenantID, productID, shopID)
+}
+
+// Expiring reports the lots with stock left that expire within days from now, already expired ones included.
+func (s *LotService) Expiring(ctx context.Context, tenantID, shopID string, days int) ([]models.ExpiringLot, error) {
+	if days < 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Days cannot be negative", nil) }
+	now := time.Now().UTC()
+	lots, err := s.repo.Expiring(ctx, tenantID, shopID, now.AddDate(0, 0, days))
+	if err != nil { return nil, utils.Internal("LOT_LIST_FAILED", "Unable to list expiring lots", err) }
+	out := make([]models.ExpiringLot, 0, len(lots))
+	products := map[primitive.ObjectID]*models.Product{}
+	for _, l := range lots {
+		row := models.ExpiringLot{StockLot: l, DaysLeft: int(math.Ceil(l.ExpirationDate.Sub(now).Hours() / 24))}
+		p, ok := products[l.ProductID]
+		if !ok {
+			p, _ = s.products.Get(ctx, l.ProductID, tenantID)
+			products[l.ProductID] = p
+		}
+		if p != nil { row.ProductName, row.ProductSKU = p.Name, p.SKU }
+		out = append(out, row)
+	}
+	return out, nil
+}
+
+// post moves delta units of a product in a store into or out of its lots and returns the lots touched. It runs
+// inside the stock transaction; balance is the store's quantity after the change. A decrease that names a lot fails
+// with LOT_INSUFFICIENT_STOCK when the lot holds less; one that does not drains lots by earliest expiry, and what the
+// lots do not cover comes from stock without a lot. Sales and transfers never draw expired lots: those leave by a
+// write-off naming the lot, and a sale or transfer that only expired lots could cover fails with LOT_EXPIRED.
+func (s *LotService) post(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, delta, balance float64, src models.StockSource) ([]models.StockLotPick, error) {
+	ref := src.Lot
+	fail := func(err error) ([]models.StockLotPick, error) { return nil, utils.Internal("STOCK_LOT_UPDATE_FAILED", "Unable to update stock lots", err) }
+	if delta > 0 {
+		if ref == nil { return nil, nil }
+		var lot *models.StockLot
+		if !ref.ID.IsZero() {
+			if _, err := s.lot(ctx, ref.ID, tenantID, productID, shopID); err != nil { return nil, err }
+			l, _, err := s.repo.Inc(ctx, ref.ID, tenantID, delta)
+			if err != nil { return fail(err) }
+			lot = l
+		} else {
+			if ref.Number == "" && ref.ExpirationDate == nil { return nil, nil }
+			l, err := s.repo.Receive(ctx, tenantID, productID, shopID, *ref, delta)
+			if err != nil { return fail(err) }
+			lot = l
+		}
+		return []models.StockLotPick{{LotID: lot.ID, Number: lot.Number, ExpirationDate: lot.ExpirationDate, Qty: delta}}, nil
+	}
+
+	qty := -delta
+	if ref != nil && !ref.ID.IsZero() {
+		lot, err := s.lot(ctx, ref.ID, tenantID, productID, shopID)
+		if err != nil { return nil, err }
+		if _, ok, err := s.repo.Inc(ctx, lot.ID, tenantID, -qty); err != nil {
+			return fail(err)
+		} else if !ok {
+			return nil, utils.Conflict("LOT_INSUFFICIENT_STOCK", "Not enough stock in lot "+lot.Number, nil)
+		}
+		return []models.StockLotPick{{LotID: lot.ID, Number: lot.Number, ExpirationDate: lot.ExpirationDate, Qty: qty}}, nil
+	}
+	open, err := s.repo.Open(ctx, tenantID, productID, shopID)
+	if err != nil { return fail(err) }
+	skipExpired := src.Type == models.StockSourceSale || src.Type == models.StockSourceTransfer
+	now := time.Now().UTC()
+	// stock without a lot is what the store held beyond its open lots
+	loose := balance + qty
+	for _, l := range open { loose -= l.Qty }
+	skipped := false
+	picks := []models.StockLotPick{}
+	for _, l := range open {
+		if qty <= 0 { break }
+		if skipExpired && l.ExpirationDate != nil && l.ExpirationDate.Before(now) { skipped = true; continue }
+		n := l.Qty
+		if n > qty { n = qty }
+		if _, ok, err := s.repo.Inc(ctx, l.ID, tenantID, -n); err != nil {
+			return fail(err)
+		} else if !ok {
+			return nil, utils.Conflict("STOCK_LOT_CHANGED", "Lot stock changed concurrently, please retry", nil)
+		}
+		picks = append(picks, models.StockLotPick{LotID: l.ID, Number: l.Number, ExpirationDate: l.ExpirationDate, Qty: n})
+		qty = models.RoundQty(qty - n)
+	}
+	if skipped && qty > models.RoundQty(loose) {
+		return nil, utils.Conflict("LOT_EXPIRED", "Only expired lots are left to cover this quantity; write them off instead", nil)
+	}
+	return picks, nil
+}
+
+// lot loads a lot and checks that it holds the given product in the given store.
+func (s *LotService) lot(ctx context.Context, id primitive.ObjectID, tenantID string, productID primitive.ObjectID, shopID string) (*models.StockLot, error) {
+	lot, err := s.repo.Get(ctx, id, tenantID)
+	if err != nil || lot.ProductID != productID || lot.ShopID != shopID { return nil, utils.BadRequest("LOT_NOT_FOUND", "Lot not found for this product and store", err) }
+	return lot, nil
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MeasureUnitService keeps the catalog of units of measure: the built-in units plus each tenant's own. It also
+// converts document quantities given in a product's other units to the base unit stock is kept in.
+type MeasureUnitService struct {
+	repo     *repositories.MeasureUnitRepository
+	products *repositories.ProductRepository
+}
+
+func NewMeasureUnitService(repo *repositories.MeasureUnitRepository, products *repositories.ProductRepository) *MeasureUnitService {
+	return &MeasureUnitService{repo: repo, products: products}
+}
+
+// List returns the built-in units followed by the tenant's own.
+func (s *MeasureUnitService) List(ctx context.Context, tenantID string) ([]models.MeasureUnit, error) {
+	own, err := s.repo.List(ctx, tenantID)
+	if err != nil { return nil, utils.Internal("UNIT_LIST_FAILED", "Unable to list units of measure", err) }
+	out := make([]models.MeasureUnit, 0, len(models.BuiltInMeasureUnits)+len(own))
+	for _, u := range models.BuiltInMeasureUnits {
+		u.TenantID, u.BuiltIn = tenantID, true
+		out = append(out, u)
+	}
+	return append(out, own...), nil
+}
+
+func (s *MeasureUnitService) Create(ctx context.Context, tenantID string, body models.MeasureUnitInput) (*models.MeasureUnit, error) {
+	code := strings.ToLower(strings.TrimSpace(body.Code))
+	if code == "" { return nil, utils.BadRequest("VALIDATION_ERROR", "Unit code is required", nil) }
+	m := &models.MeasureUnit{TenantID: tenantID, Code: code, Name: code}
+	if body.Name != nil && strings.TrimSpace(*body.Name) != "" { m.Name = strings.TrimSpace(*body.Name) }
+	if body.Precision != nil { m.Precision = *body.Precision }
+	if err := checkPrecision(m.Precision); err != nil { return nil, err }
+	if builtInUnit(code) != nil { return nil, utils.Conflict("UNIT_EXISTS", "Unit "+code+" already exists", nil) }
+	created, err := s.repo.Create(ctx, m)
+	if mongo.IsDuplicateKeyError(err) { return nil, utils.Conflict("UNIT_EXISTS", "Unit "+code+" already exists", err) }
+	if err != nil { return nil, utils.Internal("UNIT_CREATE_FAILED", "Unable to create unit of measure", err) }
+	return created, nil
+}
+
+// Update renames a tenant unit or changes its precision; quantities already recorded keep their decimals.
+func (s *MeasureUnitService) Update(ctx context.Context, id, tenantID string, body models.MeasureUnitInput) (*models.MeasureUnit, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid unit id", err) }
+	if _, err := s.repo.Get(ctx, oid, tenantID); err != nil { return nil, utils.NotFound("UNIT_NOT_FOUND", "Unit of measure not found", err) }
+	update := bson.M{}
+	if body.Name != nil {
+		if strings.TrimSpace(*body.Name) == "" { return nil, utils.BadRequest("VALIDATION_ERROR", "Unit name cannot be empty", nil) }
+		update["name"] = strings.TrimSpace(*body.Name)
+	}
+	if body.Precision != nil {
+		if err := checkPrecision(*body.Precision); err != nil { return nil, err }
+		update["precision"] = *body.Precision
+	}
+	out, err := s.repo.Update(ctx, oid, tenantID, update)
+	if err != nil { return nil, utils.Internal("UNIT_UPDATE_FAILED", "Unable to update unit of measure", err) }
+	return out, nil
+}
+
+// Delete removes a tenant unit no product counts in.
+func (s *MeasureUnitService) Delete(ctx context.Context, id, tenantID string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil { return utils.BadRequest("INVALID_ID", "Invalid unit id", err) }
+	m, err := s.repo.Get(ctx, oid, tenantID)
+	if err != nil { return utils.NotFound("UNIT_NOT_FOUND", "Unit of measure not found", err) }
+	used, err := s.products.UsesUnit(ctx, tenantID, m.Code)
+	if err != nil { return utils.Internal("UNIT_DELETE_FAILED", "Unable to delete unit of measure", err) }
+	if used { return utils.Conflict("UNIT_IN_USE", "Unit "+m.Code+" is used by products", nil) }
+	if err := s.repo.Delete(ctx, oid, tenantID); err != nil { return utils.Internal("UNIT_DELETE_FAILED", "Unable to delete unit of measure", err) }
+	return nil
+}
+
+// unit resolves a code to a built-in or tenant unit; it is nil for codes outside the catalog, which older products
+// may still carry as free text.
+func (s *MeasureUnitService) unit(ctx context.Context, tenantID, code string) (*models.MeasureUnit, error) {
+	if u := builtInUnit(code); u != nil { return u, nil }
+	u, err := s.repo.FindByCode(ctx, tenantID, code)
+	if err == mongo.ErrNoDocuments { return nil, nil }
+	if err != nil { return nil, utils.Internal("UNIT_LOOKUP_FAILED", "Unable to look up the unit of measure", err) }
+	return u, nil
+}
+
+// baseUnit checks that a product's base unit is in the catalog. Products without a unit are left as they are.
+func (s *MeasureUnitService) baseUnit(ctx context.Context, tenantID, code string) error {
+	if code == "" { return nil }
+	u, err := s.unit(ctx, tenantID, code)
+	if err != nil { return err }
+	if u == nil { return utils.BadRequest("UNIT_NOT_FOUND", "Unit "+code+" is not in the catalog", nil) }
+	return nil
+}
+
+// productUnits checks a product's other units: each is in the catalog, differs from the base unit and from the
+// others, and holds a positive number of base units.
+func (s *MeasureUnitService) productUnits(ctx context.Context, tenantID, base string, units []models.ProductUnit) ([]models.ProductUnit, error) {
+	out := make([]models.ProductUnit, 0, len(units))
+	seen := map[string]bool{}
+	for _, pu := range units {
+		pu.Unit, pu.Barcode = strings.TrimSpace(pu.Unit), strings.TrimSpace(pu.Barcode)
+		pu.Factor = models.RoundQty(pu.Factor)
+		if pu.Unit == "" || pu.Unit == base || seen[pu.Unit] { return nil, utils.BadRequest("VALIDATION_ERROR", "Each other unit must differ from the base unit and from the rest", nil) }
+		if pu.Factor <= 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Unit "+pu.Unit+" needs a positive conversion factor", nil) }
+		u, err := s.unit(ctx, tenantID, pu.Unit)
+		if err != nil { return nil, err }
+		if u == nil { return nil, utils.BadRequest("UNIT_NOT_FOUND", "Unit "+pu.Unit+" is not in the catalog", nil) }
+		seen[pu.Unit] = true
+		out = append(out, pu)
+	}
+	return out, nil
+}
+
+// lineQty is a document line quantity in its product's base unit. inputQty and inputUnit keep what was entered when
+// the line was given in another unit; factor converts prices per entered unit to prices per base unit.
+type lineQty struct {
+	qty       float64
+	unit      string
+	factor    float64
+	inputQty  float64
+	inputUnit string
+}
+
+// convert turns qty in unit into p's base unit. An empty unit is the base unit, and so is any unit of a product
+// without other units, whose lines carry the unit as a label only. The quantity must fit the precision of the
+// unit it is given in, and the converted one that of the base unit.
+func (s *MeasureUnitService) convert(ctx context.Context, p *models.Product, unit string, qty float64) (lineQty, error) {
+	unit = strings.TrimSpace(unit)
+	out := lineQty{qty: qty, unit: ifEmpty(p.Unit, unit), factor: 1}
+	if unit != "" && p.Unit != "" && unit != p.Unit && len(p.Units) > 0 {
+		var alt *models.ProductUnit
+		for i := range p.Units {
+			if p.Units[i].Unit == unit { alt = &p.Units[i]; break }
+		}
+		if alt == nil { return lineQty{}, utils.BadRequest("UNIT_NOT_ALLOWED", p.Name+" is not counted in "+unit, nil) }
+		if err := s.fits(ctx, p, unit, qty); err != nil { return lineQty{}, err }
+		out.qty, out.factor, out.inputQty, out.inputUnit = models.RoundQty(qty*alt.Factor), alt.Factor, qty, unit
+	}
+	if err := s.fits(ctx, p, out.unit, out.qty); err != nil { return lineQty{}, err }
+	return out, nil
+}
+
+// fits fails with QTY_PRECISION when qty has more decimals than the unit allows.
+func (s *MeasureUnitService) fits(ctx context.Context, p *models.Product, code string, qty float64) error {
+	precision := models.QtyDecimals
+	if code != "" {
+		u, err := s.unit(ctx, p.TenantID, code)
+		if err != nil { return err }
+		if u != nil { precision = u.Precision }
+	}
+	scaled := qty * math.Pow10(precision)
+	if math.Abs(scaled-math.Round(scaled)) > 1e-6 {
+		return utils.BadRequest("QTY_PRECISION", p.Name+" is counted in "+code+" with at most "+strconv.Itoa(precision)+" decimals", nil)
+	}
+	return nil
+}
+
+func builtInUnit(code string) *models.MeasureUnit {
+	for _, u := range models.BuiltInMeasureUnits {
+		if u.Code == code { u.BuiltIn = true; return &u }
+	}
+	return nil
+}
+
+func checkPrecision(p int) error {
+	if p < 0 || p > models.QtyDecimals { return utils.BadRequest("VALIDATION_ERROR", "Unit precision must be between 0 and "+strconv.Itoa(models.QtyDecimals), nil) }
+	return nil
+}
+
+// price turns a price per entered unit into one per base unit.
+func (q lineQty) price(v float64) float64 {
+	if q.factor == 1 { return v }
+	return roundCost(v / q.factor)
+}
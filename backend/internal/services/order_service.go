@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,10 +20,15 @@ type OrderService struct {
 	supplierRepo *repositories.SupplierRepository
 	storeRepo   *repositories.StoreRepository
 	writeOffRepo *repositories.WriteOffRepository
+	payables    *SupplierLedgerService
+	rates       *repositories.ExchangeRateRepository
+	cores       *CoreStockService
+	putaways    *PutawayService
+	stock       *StockService
 }
 
-func NewOrderService(repo *repositories.OrderRepository, productRepo *repositories.ProductRepository, supplierRepo *repositories.SupplierRepository, storeRepo *repositories.StoreRepository, writeOffRepo *repositories.WriteOffRepository) *OrderService {
-	return &OrderService{repo: repo, productRepo: productRepo, supplierRepo: supplierRepo, storeRepo: storeRepo, writeOffRepo: writeOffRepo}
+func NewOrderService(repo *repositories.OrderRepository, productRepo *repositories.ProductRepository, supplierRepo *repositories.SupplierRepository, storeRepo *repositories.StoreRepository, writeOffRepo *repositories.WriteOffRepository, payables *SupplierLedgerService, rates *repositories.ExchangeRateRepository, cores *CoreStockService, putaways *PutawayService, stock *StockService) *OrderService {
+	return &OrderService{repo: repo, productRepo: productRepo, supplierRepo: supplierRepo, storeRepo: storeRepo, writeOffRepo: writeOffRepo, payables: payables, rates: rates, cores: cores, putaways: putaways, stock: stock}
 }
 
 func (s *OrderService) List(ctx context.Context, f models.OrderFilterRequest, tenantID string) ([]models.Order, int64, error) {
@@ -52,6 +58,12 @@ func (s *OrderService) Get(ctx context.Context, id string, tenantID string) (*mo
 	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid order id", nil) }
 	m, err := s.repo.Get(ctx, oid, tenantID)
 	if err != nil { return nil, utils.NotFound("ORDER_NOT_FOUND", "Order not found", err) }
+	// live payable figures: what this order still owes and the supplier's balance across all orders
+	// consignment goods are owed through settlements, not by their order
+	if strings.ToLower(m.Type) != "return_order" && !m.Consignment() { m.Supplier.DebtValue = roundMoney(orderAmount(m, m.Items) - m.TotalPaidAmount) }
+	if sid, err := primitive.ObjectIDFromHex(m.SupplierID); err == nil {
+		if sup, err := s.supplierRepo.Get(ctx, sid, tenantID); err == nil { m.Supplier.Balance = sup.Balance }
+	}
 	return m, nil
 }
 
@@ -59,14 +71,19 @@ func (s *OrderService) Create(ctx context.Context, body models.CreateOrderReques
 	if strings.TrimSpace(body.Name) == "" { return nil, utils.BadRequest("VALIDATION_ERROR", "Order name is required", nil) }
 	if body.SupplierID == "" { return nil, utils.BadRequest("VALIDATION_ERROR", "Supplier is required", nil) }
 	if body.ShopID == "" { return nil, utils.BadRequest("VALIDATION_ERROR", "Shop is required", nil) }
+	if err := validSettlementType(body.SettlementType); err != nil { return nil, err }
 
-	order := &models.Order{ TenantID: tenantID, Name: body.Name, Comment: body.Comment, Type: ifEmpty(body.Type, "supplier_order"), SupplierID: body.SupplierID, ShopID: body.ShopID, CreatedBy: createdBy, Payments: []models.OrderPayment{}, Items: []models.OrderItem{} }
+	order := &models.Order{ TenantID: tenantID, Name: body.Name, Comment: body.Comment, Type: ifEmpty(body.Type, "supplier_order"), SettlementType: body.SettlementType, SupplierID: body.SupplierID, ShopID: body.ShopID, CreatedBy: createdBy, Payments: []models.OrderPayment{}, Items: []models.OrderItem{} }
 	// generate short external id if not provided
 	if order.ExternalID == 0 { order.ExternalID = time.Now().Unix()%1000000 }
+	if body.Cores {
+		if strings.ToLower(order.Type) != "return_order" { return nil, utils.BadRequest("VALIDATION_ERROR", "Only return orders can send back dirty cores", nil) }
+		order.Cores = true
+	}
 
 	// Enrich supplier/shop minimal data if present
 	if oid, err := primitive.ObjectIDFromHex(body.SupplierID); err == nil {
-		if sup, err := s.supplierRepo.Get(ctx, oid); err == nil {
+		if sup, err := s.supplierRepo.Get(ctx, oid, tenantID); err == nil {
 			order.Supplier = models.OrderSupplier{ ID: sup.ID.Hex(), Name: sup.Name, PhoneNumbers: []string{strings.TrimSpace(sup.Phone)}, ExternalID: 0 }
 		}
 	}
@@ -83,14 +100,20 @@ func (s *OrderService) Create(ctx context.Context, body models.CreateOrderReques
 			if err == nil && strings.ToLower(src.StatusID) == "accepted" {
 				order.ReturnedSupplierOrderID = src.ID.Hex()
 				order.ReturnedSupplierOrderName = src.Name
+				// goods received on consignment go back as consignment
+				if src.Consignment() && body.SettlementType == "" { order.SettlementType = src.SettlementType }
 				for _, it := range src.Items {
+					// orders received in parts return at most what actually arrived
+					qty := it.Quantity
+					if len(src.Receivings) > 0 { qty = it.AcceptedQuantity }
+					if qty <= 0 { continue }
 					order.Items = append(order.Items, models.OrderItem{
 						ProductID: it.ProductID,
 						ProductName: it.ProductName,
 						ProductSKU: it.ProductSKU,
-						Quantity: it.Quantity,
+						Quantity: qty,
 						UnitPrice: it.UnitPrice,
-						TotalPrice: it.UnitPrice*float64(it.Quantity),
+						TotalPrice: it.UnitPrice*qty,
 						SupplyPrice: it.SupplyPrice,
 						RetailPrice: it.RetailPrice,
 						Unit: it.Unit,
@@ -106,20 +129,28 @@ func (s *OrderService) Create(ctx context.Context, body models.CreateOrderReques
 		if it.ProductID != "" { if x, err := primitive.ObjectIDFromHex(it.ProductID); err == nil { pid = x } }
 		name := strings.TrimSpace(it.ProductName)
 		sku := strings.TrimSpace(it.ProductSKU)
-		if pid != primitive.NilObjectID && (name == "" || sku == "") {
-			if p, err := s.productRepo.Get(ctx, pid, tenantID); err == nil {
-				if name == "" { name = p.Name }
-				if sku == "" { sku = p.SKU }
-			}
-		}
-		q := it.Quantity; if q < 1 { q = 1 }
-		unitPrice := it.UnitPrice
-		supply := it.SupplyPrice
-		retail := it.RetailPrice
+		q, err := s.lineQty(ctx, pid, it, tenantID, &name, &sku)
+		if err != nil { return nil, err }
+		unitPrice := q.price(it.UnitPrice)
+		supply := q.price(it.SupplyPrice)
+		retail := q.price(it.RetailPrice)
+		serials, err := cleanSerials(it.Serials)
+		if err != nil { return nil, err }
 		order.Items = append(order.Items, models.OrderItem{
-			ProductID: pid, ProductName: name, ProductSKU: sku, Quantity: q, UnitPrice: unitPrice, TotalPrice: unitPrice*float64(q), SupplyPrice: supply, RetailPrice: retail, Unit: it.Unit,
+			ProductID: pid, ProductName: name, ProductSKU: sku, Quantity: q.qty, UnitPrice: unitPrice, TotalPrice: unitPrice*q.qty, SupplyPrice: supply, RetailPrice: retail, Unit: q.unit,
+			InputQty: q.inputQty, InputUnit: q.inputUnit, LotNumber: strings.TrimSpace(it.LotNumber), ExpirationDate: it.ExpirationDate, Serials: serials,
 		})
 	}
+	if len(body.AdditionalCosts) > 0 {
+		if strings.ToLower(order.Type) == "return_order" { return nil, utils.BadRequest("VALIDATION_ERROR", "Return orders cannot carry additional costs", nil) }
+		costs, err := s.additionalCosts(ctx, tenantID, body.AdditionalCosts)
+		if err != nil { return nil, err }
+		order.Items, order.CostAllocations, order.TotalAdditionalCost, err = s.allocateCosts(ctx, tenantID, order.Items, costs)
+		if err != nil { return nil, err }
+		order.AdditionalCosts = costs
+	}
+	if order.Consignment() && order.Supplier.ID == "" { return nil, utils.BadRequest("SUPPLIER_NOT_FOUND", "Consignment orders need a supplier on file", nil) }
+	if order.Cores && order.Consignment() { return nil, utils.BadRequest("VALIDATION_ERROR", "Dirty cores go back for credit, not on consignment", nil) }
 	// Totals
 	s.computeTotals(order)
 
@@ -128,7 +159,18 @@ func (s *OrderService) Create(ctx context.Context, body models.CreateOrderReques
 	return created, nil
 }
 
+// Update runs in one transaction: the stock effects of an approval commit together with the order's new status.
 func (s *OrderService) Update(ctx context.Context, id string, body models.UpdateOrderRequest, tenantID string, user models.OrderUser) (*models.Order, error) {
+	var out *models.Order
+	err := s.stock.Atomically(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = s.update(ctx, id, body, tenantID, user)
+		return err
+	})
+	return out, err
+}
+
+func (s *OrderService) update(ctx context.Context, id string, body models.UpdateOrderRequest, tenantID string, user models.OrderUser) (*models.Order, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid order id", nil) }
 
@@ -142,13 +184,35 @@ func (s *OrderService) Update(ctx context.Context, id string, body models.Update
 		}
 		return nil, utils.BadRequest("ORDER_LOCKED", "Order already accepted and cannot be changed", nil)
 	}
+	// a rejected order can still be edited, but it is not approved, received or cancelled again
+	if current.IsFinished && strings.TrimSpace(body.Action) != "" {
+		return nil, utils.Conflict("ORDER_FINISHED", "The order is already "+current.StatusID, nil)
+	}
+
+	// lines that have already been (partly) received are fixed
+	if len(current.Receivings) > 0 && len(body.Items) > 0 {
+		return nil, utils.Conflict("ORDER_PARTIALLY_RECEIVED", "Items of a partially received order cannot be changed", nil)
+	}
+	isReturn := strings.ToLower(current.Type) == "return_order"
+	if isReturn && body.AdditionalCosts != nil && len(*body.AdditionalCosts) > 0 {
+		return nil, utils.BadRequest("VALIDATION_ERROR", "Return orders cannot carry additional costs", nil)
+	}
+	if isReturn && (body.Action == "receive" || body.Action == "cancel_remaining") {
+		return nil, utils.BadRequest("VALIDATION_ERROR", "Return orders are approved in full", nil)
+	}
 
 	upd := bson.M{}
 	if strings.TrimSpace(body.Name) != "" { upd["name"] = body.Name }
 	if body.Comment != "" { upd["comment"] = body.Comment }
 	if body.StatusID != "" { upd["status_id"] = body.StatusID }
 	if body.PaymentDate != "" { upd["payment_date"] = body.PaymentDate }
-	if body.SettlementType != "" { upd["settlement_type"] = body.SettlementType }
+	if body.SettlementType != "" && body.SettlementType != current.SettlementType {
+		if err := validSettlementType(body.SettlementType); err != nil { return nil, err }
+		if current.Cores && body.SettlementType != models.OrderSettlementPurchase { return nil, utils.BadRequest("VALIDATION_ERROR", "Dirty cores go back for credit, not on consignment", nil) }
+		if len(current.Receivings) > 0 { return nil, utils.Conflict("ORDER_PARTIALLY_RECEIVED", "The settlement type cannot change after goods were received", nil) }
+		upd["settlement_type"] = body.SettlementType
+		current.SettlementType = body.SettlementType
+	}
 	if body.IsFinished { upd["is_finished"] = true }
 	if body.SaleProgress != 0 { upd["sale_progress"] = body.SaleProgress }
 
@@ -161,17 +225,21 @@ func (s *OrderService) Update(ctx context.Context, id string, body models.Update
 			if it.ProductID != "" { if x, err := primitive.ObjectIDFromHex(it.ProductID); err == nil { pid = x } }
 			name := strings.TrimSpace(it.ProductName)
 			sku := strings.TrimSpace(it.ProductSKU)
-			q := it.Quantity; if q < 1 { q = 1 }
-			unitPrice := it.UnitPrice
-			supply := it.SupplyPrice
-			retail := it.RetailPrice
+			q, err := s.lineQty(ctx, pid, it, tenantID, &name, &sku)
+			if err != nil { return nil, err }
+			unitPrice := q.price(it.UnitPrice)
+			supply := q.price(it.SupplyPrice)
+			retail := q.price(it.RetailPrice)
 			// allow client to send returned_quantity for return orders; keep in ReturnedQuantity field
-			returnedQ := it.ReturnedQuantity
+			returnedQ := models.RoundQty(it.ReturnedQuantity * q.factor)
 			if returnedQ < 0 { returnedQ = 0 }
-			if returnedQ > q { returnedQ = q }
-			rebuilt = append(rebuilt, models.OrderItem{ ProductID: pid, ProductName: name, ProductSKU: sku, Quantity: q, UnitPrice: unitPrice, TotalPrice: unitPrice*float64(q), SupplyPrice: supply, RetailPrice: retail, Unit: it.Unit, ReturnedQuantity: returnedQ })
+			if returnedQ > q.qty { returnedQ = q.qty }
+			serials, err := cleanSerials(it.Serials)
+			if err != nil { return nil, err }
+			rebuilt = append(rebuilt, models.OrderItem{ ProductID: pid, ProductName: name, ProductSKU: sku, Quantity: q.qty, UnitPrice: unitPrice, TotalPrice: unitPrice*q.qty, SupplyPrice: supply, RetailPrice: retail, Unit: q.unit, InputQty: q.inputQty, InputUnit: q.inputUnit, ReturnedQuantity: returnedQ, LotNumber: strings.TrimSpace(it.LotNumber), ExpirationDate: it.ExpirationDate, Serials: serials })
 		}
 		upd["items"] = rebuilt
+		setReceivingProgress(upd, rebuilt)
 	}
 	if body.TotalPrice != 0 { upd["total_price"] = body.TotalPrice }
 	if body.TotalSupplyPrice != 0 { upd["total_supply_price"] = body.TotalSupplyPrice }
@@ -181,68 +249,377 @@ func (s *OrderService) Update(ctx context.Context, id string, body models.Update
 	// Items to use for stock effects (prefer rebuilt from this request)
 	itemsForApply := current.Items
 	if len(rebuilt) > 0 { itemsForApply = rebuilt }
+	// landed costs are recomputed whenever the costs or the lines change; goods already received keep theirs
+	if body.AdditionalCosts != nil || (len(rebuilt) > 0 && len(current.AdditionalCosts) > 0) {
+		if len(current.Receivings) > 0 { return nil, utils.Conflict("ORDER_PARTIALLY_RECEIVED", "Additional costs cannot change after goods were received", nil) }
+		costs := current.AdditionalCosts
+		if body.AdditionalCosts != nil {
+			if costs, err = s.additionalCosts(ctx, tenantID, *body.AdditionalCosts); err != nil { return nil, err }
+		}
+		items, allocations, total, err := s.allocateCosts(ctx, tenantID, itemsForApply, costs)
+		if err != nil { return nil, err }
+		itemsForApply = items
+		upd["items"] = items
+		upd["additional_costs"] = costs
+		upd["cost_allocations"] = allocations
+		upd["total_additional_cost"] = total
+	}
 
 	// Approve/Reject actions
 	if body.Action == "approve" || body.Action == "reject" {
+		src := models.StockSource{ Type: models.StockSourceSupplierOrder, ID: current.ID.Hex(), Actor: models.InventoryUser{ ID: user.ID, Name: user.Name } }
+		if strings.ToLower(current.Type) == "return_order" { src.Type = models.StockSourceReturnOrder }
 		if body.Action == "approve" && !current.IsFinished {
 			if strings.ToLower(current.Type) == "return_order" {
-				// Decrease stock for each item; use ReturnedQuantity if present, else Quantity
+				// Mark accepted, before any stock moves, so that of two concurrent approvals only one returns the goods
+				upd["is_finished"] = true
+				upd["status_id"] = "accepted"
+				upd["accepted_by"] = user
+				upd["accepting_date"] = time.Now().UTC().Format(time.RFC3339)
+				if err := s.claim(ctx, current, upd); err != nil { return nil, err }
+				// Decrease the shop's stock for each item; use ReturnedQuantity if present, else Quantity
 				for _, it := range itemsForApply {
 					if it.ProductID == primitive.NilObjectID { continue }
 					qty := it.ReturnedQuantity
 					if qty <= 0 || qty > it.Quantity { qty = it.Quantity }
+					// dirty cores leave the core inventory, not the sellable stock
+					if current.Cores {
+						if err := s.cores.ship(ctx, tenantID, current.ShopID, it.ProductID, it.ProductName, qty); err != nil { return nil, err }
+						continue
+					}
 					p, err := s.productRepo.Get(ctx, it.ProductID, tenantID)
 					if err != nil { if p2, e2 := s.productRepo.GetByID(ctx, it.ProductID); e2 == nil { p = p2 } else { return nil, utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found for return order", err) } }
-					newStock := p.Stock - int(qty)
-					if newStock < 0 { newStock = 0 }
-					if err := s.productRepo.UpdateStock(ctx, p.ID, p.TenantID, newStock); err != nil { return nil, utils.Internal("PRODUCT_STOCK_UPDATE_FAILED", "Failed to update product stock", err) }
+					// serial-tracked units go back by the serials listed on the line
+					lsrc := src
+					lsrc.Serials, lsrc.Party = it.Serials, current.SupplierID
+					if current.Consignment() { lsrc.Consignment = &models.ConsignmentRef{ SupplierID: current.SupplierID, OrderName: current.Name, SettlementType: current.SettlementType } }
+					if err := s.stock.Adjust(ctx, p.TenantID, p.ID, current.ShopID, -qty, unitCost(it.SupplyPrice, p), lsrc); err != nil { return nil, err }
 				}
-				// Mark accepted
-				upd["is_finished"] = true
-				upd["status_id"] = "accepted"
-				upd["accepted_by"] = user
-				upd["accepting_date"] = time.Now().UTC().Format(time.RFC3339)
-				// Create a write-off document capturing the return with returned quantities
-				if s.writeOffRepo != nil {
-					go func(){
-						defer func(){ _ = recover() }()
-						woSvc := NewWriteOffService(s.writeOffRepo, s.storeRepo, s.productRepo)
-						woItems := make([]models.WriteOffItemInput, 0, len(itemsForApply))
-						for _, it := range itemsForApply {
-							qty := it.ReturnedQuantity; if qty <= 0 || qty > it.Quantity { qty = it.Quantity }
-							woItems = append(woItems, models.WriteOffItemInput{ ProductID: it.ProductID.Hex(), ProductName: it.ProductName, ProductSKU: it.ProductSKU, Barcode: "", Qty: float64(qty), Unit: it.Unit, SupplyPrice: it.SupplyPrice, RetailPrice: it.RetailPrice })
-						}
-						created, err := woSvc.Create(ctx, models.CreateWriteOffRequest{ Name: "Order return write-off", FromFile: false, ShopID: current.ShopID, Reason: "order_return" }, tenantID, models.InventoryUser{ ID: user.ID, Name: user.Name })
-						if err == nil { _, _ = woSvc.Update(ctx, created.ID.Hex(), models.UpdateWriteOffRequest{ Name: created.Name, Items: woItems, Action: "approve" }, tenantID, models.InventoryUser{ ID: user.ID, Name: user.Name }) }
-					}()
+				// Create an already approved write-off document capturing the return with returned quantities.
+				// Stock was decreased above, so it is stored directly instead of going through write-off approval.
+				// It is part of the approval transaction.
+				if s.writeOffRepo != nil && !current.Cores {
+					actor := models.InventoryUser{ ID: user.ID, Name: user.Name }
+					now := time.Now().UTC()
+					wo := &models.WriteOff{ TenantID: tenantID, ExternalID: generateExternalID(), Name: "Order return write-off", ShopID: current.ShopID, ShopName: current.Shop.Name, ReasonName: "order_return", Status: "APPROVED", CreatedBy: actor, FinishedBy: actor, FinishedAt: &now, Items: []models.WriteOffItem{} }
+					for _, it := range itemsForApply {
+						qty := it.ReturnedQuantity; if qty <= 0 || qty > it.Quantity { qty = it.Quantity }
+						unit := it.Unit; if unit == "" { unit = "pcs" }
+						wo.Items = append(wo.Items, models.WriteOffItem{ ProductID: it.ProductID, ProductName: it.ProductName, ProductSKU: it.ProductSKU, Qty: qty, Unit: unit, SupplyPrice: it.SupplyPrice, RetailPrice: it.RetailPrice, Serials: it.Serials })
+						wo.TotalQty = models.RoundQty(wo.TotalQty + qty)
+						wo.TotalSupplyPrice += qty * it.SupplyPrice
+						wo.TotalRetailPrice += qty * it.RetailPrice
+					}
+					if _, err := s.writeOffRepo.Create(ctx, wo); err != nil { return nil, utils.Internal("WRITEOFF_CREATE_FAILED", "Unable to record order return write-off", err) }
 				}
-			} else {
-				// Supplier order: increase stock and optionally update prices
-				for _, it := range itemsForApply {
-					if it.ProductID == primitive.NilObjectID { continue }
-					p, err := s.productRepo.Get(ctx, it.ProductID, tenantID)
-					if err != nil { if p2, e2 := s.productRepo.GetByID(ctx, it.ProductID); e2 == nil { p = p2 } else { return nil, utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found for order", err) } }
-					newStock := p.Stock + int(it.Quantity)
-					if err := s.productRepo.UpdateStock(ctx, p.ID, p.TenantID, newStock); err != nil { return nil, utils.Internal("PRODUCT_STOCK_UPDATE_FAILED", "Failed to update product stock", err) }
-					// update prices if provided (>0)
-					if it.SupplyPrice > 0 || it.RetailPrice > 0 { _ = s.productRepo.UpdatePrices(ctx, p.ID, p.TenantID, it.SupplyPrice, it.RetailPrice) }
+				// the returned goods reduce what is owed to the supplier; unsold consignment goods were never owed
+				if !current.Consignment() {
+					if err := s.payables.orderAccepted(ctx, current, itemsForApply, user); err != nil { return nil, err }
 				}
-				upd["is_finished"] = true
-				upd["status_id"] = "accepted"
-				upd["accepted_by"] = user
-				upd["accepting_date"] = time.Now().UTC().Format(time.RFC3339)
+			} else {
+				// Supplier order: approving receives everything still outstanding
+				if err := s.receive(ctx, current, itemsForApply, fullReceipt(itemsForApply), "", user, upd); err != nil { return nil, err }
 			}
 		} else if body.Action == "reject" && !current.IsFinished {
+			if len(current.Receivings) > 0 { return nil, utils.Conflict("ORDER_PARTIALLY_RECEIVED", "Goods were already received; cancel the remainder instead", nil) }
 			upd["is_finished"] = true
 			upd["status_id"] = "rejected"
+			if err := s.claim(ctx, current, upd); err != nil { return nil, err }
 		}
 	}
+	if body.Action == "receive" {
+		if err := s.receivedInBaseUnit(ctx, itemsForApply, body.Receive, tenantID); err != nil { return nil, err }
+		plan, err := receivingPlan(itemsForApply, body.Receive)
+		if err != nil { return nil, err }
+		if err := s.receive(ctx, current, itemsForApply, plan, body.Comment, user, upd); err != nil { return nil, err }
+	}
+	if body.Action == "cancel_remaining" {
+		// the rest will not be delivered: the order finishes with what was received so far
+		items := append([]models.OrderItem(nil), itemsForApply...)
+		accepted := 0.0
+		for i := range items {
+			items[i].CancelledQuantity = models.RoundQty(items[i].Quantity - items[i].AcceptedQuantity)
+			accepted += items[i].AcceptedQuantity
+		}
+		upd["items"] = items
+		setReceivingProgress(upd, items)
+		upd["is_finished"] = true
+		upd["status_id"] = "accepted"
+		if accepted == 0 { upd["status_id"] = "rejected" }
+		upd["accepted_by"] = user
+		upd["accepting_date"] = time.Now().UTC().Format(time.RFC3339)
+		if err := s.claim(ctx, current, upd); err != nil { return nil, err }
+	}
 
+	if strings.TrimSpace(body.Action) == "" {
+		// a plain edit saves only while the order is as it was read, so it cannot overwrite the lines of a receiving
+		if err := s.claim(ctx, current, upd); err != nil { return nil, err }
+		return s.Get(ctx, id, tenantID)
+	}
 	updated, err := s.repo.Update(ctx, oid, tenantID, upd)
 	if err != nil { return nil, utils.Internal("ORDER_UPDATE_FAILED", "Unable to update order", err) }
 	return updated, nil
 }
 
+// receiptPart is a quantity received on one order line into one lot.
+type receiptPart struct {
+	line       int
+	qty        float64
+	lotNumber  string
+	expiration *time.Time
+	serials    []string
+}
+
+// receive books one receiving: stock and the amount owed to the supplier grow by just the received quantities,
+// and the order finishes on its own once nothing is left outstanding. Parts naming a lot number or expiry date
+// go into that lot of the order's store, and the serials of tracked products enter stock with the units.
+func (s *OrderService) receive(ctx context.Context, o *models.Order, items []models.OrderItem, plan []receiptPart, comment string, user models.OrderUser, upd bson.M) error {
+	if len(plan) == 0 { return utils.BadRequest("NOTHING_TO_RECEIVE", "Nothing is left to receive on this order", nil) }
+	src := models.StockSource{ Type: models.StockSourceSupplierOrder, ID: o.ID.Hex(), Actor: models.InventoryUser{ ID: user.ID, Name: user.Name } }
+	now := time.Now().UTC()
+	items = append([]models.OrderItem(nil), items...)
+	rec := models.OrderReceiving{ ID: primitive.NewObjectID(), Comment: comment, ReceivedBy: user, ReceivedAt: now, Items: []models.OrderReceivingItem{} }
+	received := make([]models.OrderItem, 0, len(plan))
+	for _, part := range plan {
+		i, qty := part.line, part.qty
+		it := &items[i]
+		it.AcceptedQuantity = models.RoundQty(it.AcceptedQuantity + qty)
+		rec.Items = append(rec.Items, models.OrderReceivingItem{ Line: i, ProductID: it.ProductID, ProductName: it.ProductName, ProductSKU: it.ProductSKU, Quantity: qty, SupplyPrice: it.SupplyPrice, Unit: it.Unit, LotNumber: part.lotNumber, ExpirationDate: part.expiration, Serials: part.serials })
+		line := *it
+		line.Quantity = qty
+		received = append(received, line)
+	}
+	left := setReceivingProgress(upd, items)
+	receivings := append(append([]models.OrderReceiving{}, o.Receivings...), rec)
+	upd["items"] = items
+	upd["receivings"] = receivings
+	if left == 0 {
+		upd["is_finished"] = true
+		upd["status_id"] = "accepted"
+		upd["accepted_by"] = user
+		upd["accepting_date"] = now.Format(time.RFC3339)
+	} else {
+		upd["status_id"] = "partially_accepted"
+	}
+	// the receiving is recorded before any stock moves, so a concurrent approval or receiving fails instead of
+	// receiving the same goods twice
+	if err := s.claim(ctx, o, upd); err != nil { return err }
+
+	for _, part := range plan {
+		it := items[part.line]
+		if it.ProductID == primitive.NilObjectID { continue }
+		src.Lot, src.Serials, src.Party, src.Consignment = nil, part.serials, o.SupplierID, nil
+		if part.lotNumber != "" || part.expiration != nil {
+			src.Lot = &models.StockLotRef{ Number: part.lotNumber, ExpirationDate: part.expiration, OrderID: o.ID.Hex(), OrderName: o.Name, SupplierID: o.SupplierID }
+		}
+		p, err := s.productRepo.Get(ctx, it.ProductID, o.TenantID)
+		if err != nil { if p2, e2 := s.productRepo.GetByID(ctx, it.ProductID); e2 == nil { p = p2 } else { return utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found for order", err) } }
+		if o.Consignment() {
+			src.Consignment = &models.ConsignmentRef{ SupplierID: o.SupplierID, OrderName: o.Name, SettlementType: o.SettlementType, SupplyPrice: orderLinePrice(it), DueDate: p.KonsignatsiyaDate }
+		}
+		// stock and the cost price take the landed unit cost, not just the supplier's price
+		landed := unitCost(it.SupplyPrice, p) + it.LandedCost
+		if err := s.stock.Adjust(ctx, p.TenantID, p.ID, o.ShopID, part.qty, landed, src); err != nil { return err }
+		// the cost price follows the costing engine; only a new retail price is applied here
+		if it.RetailPrice > 0 {
+			if err := s.productRepo.UpdatePrices(ctx, p.ID, p.TenantID, -1, it.RetailPrice); err != nil { return utils.Internal("PRODUCT_PRICE_UPDATE_FAILED", "Failed to update product prices", err) }
+		}
+	}
+	// goods received into a store with bins wait unbinned for the putaway drafted here
+	putaway, err := s.putaways.draft(ctx, o, received, user)
+	if err != nil { return err }
+	if putaway != nil { receivings[len(receivings)-1].PutawayID = putaway.ID.Hex() }
+	// the received goods are owed to the supplier, consignment goods only once sold
+	if !o.Consignment() {
+		if err := s.payables.orderReceived(ctx, o, received, len(o.Receivings) == 0 && left == 0, user); err != nil { return err }
+	}
+	return nil
+}
+
+// claim writes set only while the order is still as it was read: finished or not, in the same status and with the
+// same number of receivings. It fails when another request changed the order first. Approvals and receivings go
+// through it before any stock moves, so two concurrent receivings cannot both add the same outstanding quantity.
+func (s *OrderService) claim(ctx context.Context, o *models.Order, set bson.M) error {
+	n := len(o.Receivings)
+	expect := bson.M{"is_finished": o.IsFinished, "status_id": o.StatusID, "receivings." + strconv.Itoa(n): bson.M{"$exists": false}}
+	if n > 0 { expect["receivings."+strconv.Itoa(n-1)] = bson.M{"$exists": true} }
+	ok, err := s.repo.UpdateIf(ctx, o.ID, o.TenantID, expect, set)
+	if err != nil { return utils.Internal("ORDER_UPDATE_FAILED", "Unable to update order", err) }
+	if !ok { return utils.Conflict("ORDER_CHANGED", "The order was changed by another request; reload it and try again", nil) }
+	return nil
+}
+
+// additionalCosts validates cost lines and converts them to the base currency (UZS). USD amounts without an
+// explicit rate use the exchange rate in effect now.
+func (s *OrderService) additionalCosts(ctx context.Context, tenantID string, in []models.OrderAdditionalCostInput) ([]models.OrderAdditionalCost, error) {
+	out := make([]models.OrderAdditionalCost, 0, len(in))
+	for _, c := range in {
+		if strings.TrimSpace(c.Name) == "" { return nil, utils.BadRequest("VALIDATION_ERROR", "Additional cost name is required", nil) }
+		if c.Amount <= 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Additional cost amount must be greater than 0", nil) }
+		method := strings.ToLower(ifEmpty(c.Method, models.CostAllocationByValue))
+		if method != models.CostAllocationByValue && method != models.CostAllocationByQuantity && method != models.CostAllocationByWeight {
+			return nil, utils.BadRequest("VALIDATION_ERROR", "Allocation method must be value, quantity or weight", nil)
+		}
+		currency := strings.ToUpper(ifEmpty(c.Currency, "UZS"))
+		rate := c.Rate
+		if rate <= 0 && currency == "UZS" { rate = 1 }
+		if rate <= 0 && currency == "USD" {
+			if r, err := s.rates.FindActiveAt(ctx, tenantID, time.Now().UTC()); err == nil { rate = float64(r.Rate) }
+		}
+		if rate <= 0 { return nil, utils.BadRequest("EXCHANGE_RATE_REQUIRED", "Exchange rate is required for "+currency+" costs", nil) }
+		out = append(out, models.OrderAdditionalCost{ ID: primitive.NewObjectID(), Name: strings.TrimSpace(c.Name), Amount: c.Amount, Currency: currency, Rate: rate, BaseAmount: roundMoney(c.Amount * rate), Method: method })
+	}
+	return out, nil
+}
+
+// allocateCosts spreads each cost over the order lines in proportion to their value, quantity or weight and sets
+// every line's per-unit LandedCost. Shares are rounded to money and the last line takes the remainder.
+func (s *OrderService) allocateCosts(ctx context.Context, tenantID string, items []models.OrderItem, costs []models.OrderAdditionalCost) ([]models.OrderItem, []models.OrderCostAllocation, float64, error) {
+	items = append([]models.OrderItem(nil), items...)
+	for i := range items { items[i].LandedCost = 0 }
+	allocations := []models.OrderCostAllocation{}
+	var total float64
+	var weights map[int]float64
+	for _, c := range costs {
+		total += c.BaseAmount
+		if c.Method == models.CostAllocationByWeight && weights == nil {
+			weights = map[int]float64{}
+			for i, it := range items {
+				if it.ProductID == primitive.NilObjectID { continue }
+				p, err := s.productRepo.Get(ctx, it.ProductID, tenantID)
+				if err != nil || p.Weight <= 0 { return nil, nil, 0, utils.BadRequest("PRODUCT_WEIGHT_REQUIRED", "Allocation by weight needs a weight on every product of the order", err) }
+				weights[i] = p.Weight
+			}
+		}
+		basis := make([]float64, len(items))
+		var sum float64
+		last := -1
+		for i, it := range items {
+			if it.Quantity <= 0 { continue }
+			switch c.Method {
+			case models.CostAllocationByQuantity: basis[i] = it.Quantity
+			case models.CostAllocationByWeight: basis[i] = it.Quantity * weights[i]
+			default: basis[i] = it.Quantity * orderLinePrice(it)
+			}
+			if basis[i] > 0 { sum += basis[i]; last = i }
+		}
+		if sum <= 0 { return nil, nil, 0, utils.BadRequest("COST_ALLOCATION_IMPOSSIBLE", "Order lines have no "+c.Method+" to allocate "+c.Name+" by", nil) }
+		left := c.BaseAmount
+		for i, it := range items {
+			if basis[i] <= 0 { continue }
+			amount := roundMoney(c.BaseAmount * basis[i] / sum)
+			if i == last { amount = roundMoney(left) }
+			left -= amount
+			perUnit := amount / it.Quantity
+			items[i].LandedCost += perUnit
+			allocations = append(allocations, models.OrderCostAllocation{ CostID: c.ID, Line: i, ProductID: it.ProductID, ProductName: it.ProductName, Basis: basis[i], Amount: amount, PerUnit: perUnit })
+		}
+	}
+	return items, allocations, roundMoney(total), nil
+}
+
+// lineQty converts an order line's quantity to its product's base unit; lines without a catalog product keep the
+// quantity as given. A missing quantity orders one unit. Empty name and sku are filled in from the product.
+func (s *OrderService) lineQty(ctx context.Context, pid primitive.ObjectID, it models.OrderItemInput, tenantID string, name, sku *string) (lineQty, error) {
+	qty := it.Quantity; if qty <= 0 { qty = 1 }
+	if pid == primitive.NilObjectID { return lineQty{qty: qty, unit: it.Unit, factor: 1}, nil }
+	p, err := s.productRepo.Get(ctx, pid, tenantID)
+	if err != nil { return lineQty{qty: qty, unit: it.Unit, factor: 1}, nil }
+	if *name == "" { *name = p.Name }
+	if *sku == "" { *sku = p.SKU }
+	return s.stock.inUnit(ctx, p, it.Unit, qty)
+}
+
+// receivedInBaseUnit converts received quantities given in another unit of the line's product to the base unit.
+func (s *OrderService) receivedInBaseUnit(ctx context.Context, items []models.OrderItem, in []models.OrderReceiveInput, tenantID string) error {
+	for i := range in {
+		r := &in[i]
+		if strings.TrimSpace(r.Unit) == "" { continue }
+		pid, err := primitive.ObjectIDFromHex(r.ProductID)
+		if r.Line != nil && *r.Line >= 0 && *r.Line < len(items) { pid, err = items[*r.Line].ProductID, nil }
+		if err != nil { return utils.BadRequest("INVALID_ID", "Invalid product id", err) }
+		p, err := s.productRepo.Get(ctx, pid, tenantID)
+		if err != nil { return utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found for order", err) }
+		q, err := s.stock.inUnit(ctx, p, r.Unit, r.Quantity)
+		if err != nil { return err }
+		r.Quantity, r.Unit = q.qty, ""
+	}
+	return nil
+}
+
+// receivingPlan resolves the requested quantities to order lines, refusing more than is still outstanding.
+func receivingPlan(items []models.OrderItem, in []models.OrderReceiveInput) ([]receiptPart, error) {
+	if len(in) == 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Received quantities are required", nil) }
+	left := remainingQty(items)
+	taken := map[int]float64{}
+	plan := make([]receiptPart, 0, len(in))
+	for _, r := range in {
+		if r.Quantity <= 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Received quantity must be greater than 0", nil) }
+		line := -1
+		if r.Line != nil {
+			if *r.Line < 0 || *r.Line >= len(items) { return nil, utils.BadRequest("VALIDATION_ERROR", "Invalid order line", nil) }
+			line = *r.Line
+		} else {
+			pid, err := primitive.ObjectIDFromHex(r.ProductID)
+			if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid product id", err) }
+			// the first line of the product that still has something outstanding
+			for i, it := range items {
+				if it.ProductID == pid && left[i]-taken[i] > 0 { line = i; break }
+			}
+			if line < 0 { return nil, utils.BadRequest("ORDER_LINE_NOT_FOUND", "Product has nothing left to receive on this order", nil) }
+		}
+		if models.RoundQty(taken[line]+r.Quantity) > left[line] {
+			return nil, utils.BadRequest("RECEIVE_QTY_EXCEEDS_ORDERED", "Received quantity exceeds what is left on the order line", nil)
+		}
+		taken[line] = models.RoundQty(taken[line] + r.Quantity)
+		part := receiptPart{ line: line, qty: r.Quantity, lotNumber: items[line].LotNumber, expiration: items[line].ExpirationDate }
+		if n := strings.TrimSpace(r.LotNumber); n != "" { part.lotNumber = n }
+		if r.ExpirationDate != nil { part.expiration = r.ExpirationDate }
+		var err error
+		if part.serials, err = cleanSerials(r.Serials); err != nil { return nil, err }
+		plan = append(plan, part)
+	}
+	return plan, nil
+}
+
+// fullReceipt receives everything still outstanding, each line into its expected lot. A line's serials are
+// delivered with it unless part of the line already arrived.
+func fullReceipt(items []models.OrderItem) []receiptPart {
+	left := remainingQty(items)
+	plan := make([]receiptPart, 0, len(left))
+	for i, it := range items {
+		if left[i] <= 0 { continue }
+		part := receiptPart{ line: i, qty: left[i], lotNumber: it.LotNumber, expiration: it.ExpirationDate }
+		if it.AcceptedQuantity == 0 { part.serials = it.Serials }
+		plan = append(plan, part)
+	}
+	return plan
+}
+
+// remainingQty maps each line with something still to deliver to that quantity.
+func remainingQty(items []models.OrderItem) map[int]float64 {
+	out := map[int]float64{}
+	for i, it := range items {
+		if q := models.RoundQty(it.Quantity - it.AcceptedQuantity - it.CancelledQuantity); q > 0 { out[i] = q }
+	}
+	return out
+}
+
+// setReceivingProgress refreshes the order's measurement totals and returns the quantity still outstanding.
+func setReceivingProgress(upd bson.M, items []models.OrderItem) float64 {
+	var total, accepted, left float64
+	for _, it := range items {
+		total += it.Quantity
+		accepted += it.AcceptedQuantity
+		if q := models.RoundQty(it.Quantity - it.AcceptedQuantity - it.CancelledQuantity); q > 0 { left += q }
+	}
+	total, accepted, left = models.RoundQty(total), models.RoundQty(accepted), models.RoundQty(left)
+	upd["total_measurement_value"] = total
+	upd["total_accepted_measurement_value"] = accepted
+	upd["left_measurement_value"] = left
+	return left
+}
+
 func (s *OrderService) Delete(ctx context.Context, id string, tenantID string) error {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil { return utils.BadRequest("INVALID_ID", "Invalid order id", nil) }
@@ -251,18 +628,26 @@ func (s *OrderService) Delete(ctx context.Context, id string, tenantID string) e
 	if current.IsFinished && strings.ToLower(current.StatusID) != "rejected" {
 		return utils.BadRequest("ORDER_LOCKED", "Accepted orders cannot be deleted", nil)
 	}
+	if len(current.Receivings) > 0 { return utils.BadRequest("ORDER_LOCKED", "Partially received orders cannot be deleted", nil) }
 	if err := s.repo.Delete(ctx, oid, tenantID); err != nil { return utils.Internal("ORDER_DELETE_FAILED", "Unable to delete order", err) }
 	return nil
 }
 
-func (s *OrderService) AddPayment(ctx context.Context, id string, tenantID string, req models.AddOrderPaymentRequest) (*models.Order, error) {
+func (s *OrderService) AddPayment(ctx context.Context, id string, tenantID string, req models.AddOrderPaymentRequest, user models.OrderUser) (*models.Order, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid order id", nil) }
 	if req.Amount <= 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Amount must be greater than 0", nil) }
-	p := models.OrderPayment{ Amount: req.Amount, PaymentDate: ifZeroTime(req.PaymentDate, time.Now().UTC()), PaymentMethod: req.PaymentMethod, Description: req.Description, Status: "paid" }
-	m, err := s.repo.AddPayment(ctx, oid, tenantID, p)
-	if err != nil { return nil, utils.Internal("ORDER_ADD_PAYMENT_FAILED", "Unable to add payment", err) }
-	return m, nil
+	p := models.OrderPayment{ ID: primitive.NewObjectID(), Amount: req.Amount, PaymentDate: ifZeroTime(req.PaymentDate, time.Now().UTC()), PaymentMethod: req.PaymentMethod, Description: req.Description, Status: "paid" }
+	// the payment and its supplier ledger posting commit together
+	var out *models.Order
+	err = s.stock.Atomically(ctx, func(ctx context.Context) error {
+		m, err := s.repo.AddPayment(ctx, oid, tenantID, p)
+		if err != nil { return utils.Internal("ORDER_ADD_PAYMENT_FAILED", "Unable to add payment", err) }
+		if err := s.payables.orderPayment(ctx, m, p, user); err != nil { return err }
+		out = m
+		return nil
+	})
+	return out, err
 }
 
 func (s *OrderService) PaymentsBySupplier(ctx context.Context, supplierID, shopID, tenantID string, page, limit int64) ([]repositories.SupplierPayment, int64, error) {
@@ -307,23 +692,37 @@ func (s *OrderService) ProductsBySupplier(ctx context.Context, supplierID, shopI
 }
 
 func (s *OrderService) computeTotals(o *models.Order) {
-	var qtySum int
-	var total, totalSupply, totalRetail float64
+	var qtySum, total, totalSupply, totalRetail float64
 	for i := range o.Items {
 		line := &o.Items[i]
-		line.TotalPrice = line.UnitPrice * float64(line.Quantity)
+		line.TotalPrice = line.UnitPrice * line.Quantity
 		qtySum += line.Quantity
 		total += line.TotalPrice
-		totalSupply += line.SupplyPrice * float64(line.Quantity)
-		totalRetail += line.RetailPrice * float64(line.Quantity)
+		totalSupply += line.SupplyPrice * line.Quantity
+		totalRetail += line.RetailPrice * line.Quantity
 	}
-	o.ItemsCount = qtySum
+	// quantities of different units do not add up to a count, so the count is of lines
+	o.ItemsCount = len(o.Items)
+	o.TotalMeasurementValue = models.RoundQty(qtySum)
+	o.LeftMeasurementValue = models.RoundQty(qtySum)
 	o.TotalPrice = total
 	o.TotalSupplyPrice = totalSupply
 	o.TotalRetailPrice = totalRetail
 }
 
+// validSettlementType accepts an order's settlement type: a purchase (also empty) or goods on konsignatsiya or
+// realizatsiya.
+func validSettlementType(t string) error {
+	switch t {
+	case "", models.OrderSettlementPurchase, models.OrderSettlementConsignment, models.OrderSettlementRealization:
+		return nil
+	}
+	return utils.BadRequest("INVALID_SETTLEMENT_TYPE", "Invalid settlement type. Valid: purchase, konsignatsiya, realizatsiya", nil)
+}
+
 // helpers
+// unitCost is the document's supply price, falling back to the product's cost price
+func unitCost(supply float64, p *models.Product) float64 { if supply > 0 { return supply }; return p.CostPrice }
 func ifZero(v, d int) int { if v == 0 { return d }; return v }
 func ifEmpty(v, d string) string { if strings.TrimSpace(v) == "" { return d }; return v }
 func sortOrderValue(s string) int { if strings.ToLower(s) == "asc" { return 1 }; if strings.ToLower(s) == "desc" { return -1 }; return -1 }
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PickListService lays out where the goods of a pending sale or transfer are picked from in its store, bin by bin.
+// A pick list is computed from the bin stock when asked for; it reserves and moves nothing.
+type PickListService struct {
+	sales     *repositories.SaleRepository
+	transfers *repositories.TransferRepository
+	products  *repositories.ProductRepository
+	bins      *BinService
+	stock     *StockService
+}
+
+func NewPickListService(sales *repositories.SaleRepository, transfers *repositories.TransferRepository, products *repositories.ProductRepository, bins *BinService, stock *StockService) *PickListService {
+	return &PickListService{sales: sales, transfers: transfers, products: products, bins: bins, stock: stock}
+}
+
+// Sale picks a pending sale. Virtual SETs are picked as their components; services and core charges are skipped.
+func (s *PickListService) Sale(ctx context.Context, id, tenantID string) (*models.PickList, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid sale id", err) }
+	m, err := s.sales.Get(ctx, oid, tenantID)
+	if err != nil { return nil, utils.NotFound("SALE_NOT_FOUND", "Sale not found", err) }
+	if m.Status != "NEW" { return nil, utils.Conflict("PICK_LIST_NOT_PENDING", "Only a pending sale can be picked", nil) }
+	need := &pickNeed{qty: map[primitive.ObjectID]float64{}}
+	for _, it := range m.Items {
+		if it.ProductType == models.ProductKindService || it.ProductType == models.SaleItemCore { continue }
+		p, err := s.products.Get(ctx, it.ProductID, tenantID)
+		if err != nil { return nil, utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found", err) }
+		if !p.VirtualSet() { need.add(it.ProductID, it.Qty); continue }
+		for _, si := range p.SetItems { need.add(si.ProductID, models.RoundQty(si.Quantity*it.Qty)) }
+	}
+	return s.plan(ctx, tenantID, m.ShopID, models.StockSourceSale, m.ID.Hex(), "Sale "+strconv.FormatInt(m.ExternalID, 10), need)
+}
+
+// Transfer picks a transfer not sent yet, in its departure store.
+func (s *PickListService) Transfer(ctx context.Context, id, tenantID string) (*models.PickList, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid transfer id", err) }
+	m, err := s.transfers.Get(ctx, oid, tenantID)
+	if err != nil { return nil, utils.NotFound("TRANSFER_NOT_FOUND", "Transfer not found", err) }
+	if m.Status != "NEW" { return nil, utils.Conflict("PICK_LIST_NOT_PENDING", "Only a transfer not sent yet can be picked", nil) }
+	need := &pickNeed{qty: map[primitive.ObjectID]float64{}}
+	for _, it := range m.Items { need.add(it.ProductID, it.Qty) }
+	return s.plan(ctx, tenantID, m.DepartureShopID, models.StockSourceTransfer, m.ID.Hex(), m.Name, need)
+}
+
+// pickNeed sums what a document takes per product, in the order the products first appear.
+type pickNeed struct {
+	order []primitive.ObjectID
+	qty   map[primitive.ObjectID]float64
+}
+
+func (n *pickNeed) add(id primitive.ObjectID, qty float64) {
+	if id == primitive.NilObjectID || qty <= 0 { return }
+	if _, ok := n.qty[id]; !ok { n.order = append(n.order, id) }
+	n.qty[id] = models.RoundQty(n.qty[id] + qty)
+}
+
+func (s *PickListService) plan(ctx context.Context, tenantID, shopID, sourceType, sourceID, name string, need *pickNeed) (*models.PickList, error) {
+	out := &models.PickList{ SourceType: sourceType, SourceID: sourceID, SourceName: name, ShopID: shopID, Lines: []models.PickListLine{}, Short: []models.PickListLine{} }
+	for _, pid := range need.order {
+		p, err := s.products.Get(ctx, pid, tenantID)
+		if err != nil { return nil, utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found", err) }
+		onHand, err := s.stock.Available(ctx, tenantID, pid, shopID)
+		if err != nil { return nil, err }
+		picks, short, err := s.bins.Plan(ctx, tenantID, shopID, pid, need.qty[pid], onHand)
+		if err != nil { return nil, err }
+		line := models.PickListLine{ ProductID: pid.Hex(), ProductName: p.Name, ProductSKU: p.SKU, Unit: ifEmpty(p.Unit, "pcs") }
+		for _, pk := range picks {
+			l := line
+			l.BinID, l.BinPath, l.Qty = pk.BinID, pk.BinPath, pk.Qty
+			out.Lines = append(out.Lines, l)
+			out.TotalQty = models.RoundQty(out.TotalQty + pk.Qty)
+		}
+		if short > 0 {
+			line.Qty = short
+			out.Short = append(out.Short, line)
+		}
+	}
+	// the walk goes through the bins in path order and ends at the unbinned stock
+	sort.SliceStable(out.Lines, func(i, j int) bool {
+		a, b := out.Lines[i], out.Lines[j]
+		if (a.BinPath == "") != (b.BinPath == "") { return b.BinPath == "" }
+		return a.BinPath < b.BinPath
+	})
+	return out, nil
+}
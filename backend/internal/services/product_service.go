@@ -2,6 +2,8 @@ package services
 
 import (
 	"context"
+	"math"
+	"sort"
 	"time"
 
 	"shop/backend/internal/models"
@@ -18,6 +20,8 @@ type ProductService struct {
 	brandRepo    *repositories.BrandRepository
 	supplierRepo *repositories.SupplierRepository
 	importHistoryRepo *repositories.ImportHistoryRepository
+	stock        *StockService
+	units        *MeasureUnitService
 }
 
 func NewProductService(
@@ -26,6 +30,8 @@ func NewProductService(
 	brandRepo *repositories.BrandRepository,
 	supplierRepo *repositories.SupplierRepository,
 	importHistoryRepo *repositories.ImportHistoryRepository,
+	stock *StockService,
+	units *MeasureUnitService,
 ) *ProductService {
 	return &ProductService{
 		repo:         repo,
@@ -33,6 +39,8 @@ func NewProductService(
 		brandRepo:    brandRepo,
 		supplierRepo: supplierRepo,
 		importHistoryRepo: importHistoryRepo,
+		stock:        stock,
+		units:        units,
 	}
 }
 
@@ -72,7 +80,7 @@ func (s *ProductService) List(ctx context.Context, page, limit int64, search, ca
 		
 		// Resolve relationship names
 		if product.CategoryID != primitive.NilObjectID {
-			if category, err := s.categoryRepo.Get(ctx, product.CategoryID); err == nil {
+			if category, err := s.categoryRepo.Get(ctx, product.CategoryID, tenantID); err == nil {
 				dto.CategoryName = category.Name
 			}
 		}
@@ -82,7 +90,7 @@ func (s *ProductService) List(ctx context.Context, page, limit int64, search, ca
 			names := make([]string, 0, len(product.CategoryIDs))
 			for _, cid := range product.CategoryIDs {
 				if cid == primitive.NilObjectID { continue }
-				if cat, err := s.categoryRepo.Get(ctx, cid); err == nil { names = append(names, cat.Name) }
+				if cat, err := s.categoryRepo.Get(ctx, cid, tenantID); err == nil { names = append(names, cat.Name) }
 			}
 			dto.CategoryNames = names
 		}
@@ -94,20 +102,20 @@ func (s *ProductService) List(ctx context.Context, page, limit int64, search, ca
 		}
 		
 		if product.SupplierID != primitive.NilObjectID {
-			if supplier, err := s.supplierRepo.Get(ctx, product.SupplierID); err == nil {
+			if supplier, err := s.supplierRepo.Get(ctx, product.SupplierID, tenantID); err == nil {
 				dto.SupplierName = supplier.Name
 			}
 		}
 
 		// Derived stock for SET = min(floor(component stock / qty))
-		if product.ProductType == models.ProductKindSet && len(product.SetItems) > 0 {
-			minAvail := -1
+		if product.VirtualSet() && len(product.SetItems) > 0 {
+			minAvail := -1.0
 			for _, it := range product.SetItems {
 				if it.Quantity <= 0 { continue }
 				comp, err := s.repo.Get(ctx, it.ProductID, tenantID)
-				avail := 0
+				avail := 0.0
 				if err == nil {
-					avail = comp.Stock / it.Quantity
+					avail = math.Floor(comp.Stock / it.Quantity)
 				} else {
 					avail = 0
 				}
@@ -119,6 +127,9 @@ func (s *ProductService) List(ctx context.Context, page, limit int64, search, ca
 		
 		out[i] = dto
 	}
+	if err := s.attachStoreStocks(ctx, tenantID, items, out); err != nil {
+		return nil, 0, err
+	}
 
 	return out, total, nil
 }
@@ -138,7 +149,7 @@ func (s *ProductService) Get(ctx context.Context, id string, tenantID string) (*
 	
 	// Resolve relationship names
 	if m.CategoryID != primitive.NilObjectID {
-		if category, err := s.categoryRepo.Get(ctx, m.CategoryID); err == nil {
+		if category, err := s.categoryRepo.Get(ctx, m.CategoryID, tenantID); err == nil {
 			dto.CategoryName = category.Name
 		}
 	}
@@ -147,7 +158,7 @@ func (s *ProductService) Get(ctx context.Context, id string, tenantID string) (*
 		names := make([]string, 0, len(m.CategoryIDs))
 		for _, cid := range m.CategoryIDs {
 			if cid == primitive.NilObjectID { continue }
-			if cat, err := s.categoryRepo.Get(ctx, cid); err == nil { names = append(names, cat.Name) }
+			if cat, err := s.categoryRepo.Get(ctx, cid, tenantID); err == nil { names = append(names, cat.Name) }
 		}
 		dto.CategoryNames = names
 	}
@@ -159,26 +170,30 @@ func (s *ProductService) Get(ctx context.Context, id string, tenantID string) (*
 	}
 	
 	if m.SupplierID != primitive.NilObjectID {
-		if supplier, err := s.supplierRepo.Get(ctx, m.SupplierID); err == nil {
+		if supplier, err := s.supplierRepo.Get(ctx, m.SupplierID, tenantID); err == nil {
 			dto.SupplierName = supplier.Name
 		}
 	}
 
 	// Derived stock for SET
-	if m.ProductType == models.ProductKindSet && len(m.SetItems) > 0 {
-		minAvail := -1
+	if m.VirtualSet() && len(m.SetItems) > 0 {
+		minAvail := -1.0
 		for _, it := range m.SetItems {
 			if it.Quantity <= 0 { continue }
 			comp, err := s.repo.Get(ctx, it.ProductID, tenantID)
-			avail := 0
-			if err == nil { avail = comp.Stock / it.Quantity } else { avail = 0 }
+			avail := 0.0
+			if err == nil { avail = math.Floor(comp.Stock / it.Quantity) } else { avail = 0 }
 			if minAvail == -1 || avail < minAvail { minAvail = avail }
 		}
 		if minAvail < 0 { minAvail = 0 }
 		dto.Stock = minAvail
 	}
 
-	return &dto, nil
+	out := []models.ProductDTO{dto}
+	if err := s.attachStoreStocks(ctx, tenantID, []models.Product{*m}, out); err != nil {
+		return nil, err
+	}
+	return &out[0], nil
 }
 
 func (s *ProductService) Create(ctx context.Context, body models.ProductCreate, tenantID string) (*models.ProductDTO, error) {
@@ -203,6 +218,8 @@ func (s *ProductService) Create(ctx context.Context, body models.ProductCreate,
 	if kind == models.ProductKindSet && len(body.Variants) > 0 {
 		return nil, utils.BadRequest("SET_NO_VARIANTS", "SET cannot have variants", nil)
 	}
+	if err := validSetMode(kind, body.SetMode); err != nil { return nil, err }
+	if body.CoreCharge < 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Core charge cannot be negative", nil) }
 
 	// Check if SKU already exists (skip for bulk variant creation where all variants share same SKU)
 	skipSKUCheck := false
@@ -224,7 +241,7 @@ func (s *ProductService) Create(ctx context.Context, body models.ProductCreate,
 	// Validate relationships
 	if body.CategoryID != "" {
 		if oid, err := primitive.ObjectIDFromHex(body.CategoryID); err == nil {
-			if _, err := s.categoryRepo.Get(ctx, oid); err != nil {
+			if _, err := s.categoryRepo.Get(ctx, oid, tenantID); err != nil {
 				return nil, utils.BadRequest("CATEGORY_NOT_FOUND", "Category not found", nil)
 			}
 		} else {
@@ -238,7 +255,7 @@ func (s *ProductService) Create(ctx context.Context, body models.ProductCreate,
 			if id == "" { continue }
 			oid, err := primitive.ObjectIDFromHex(id)
 			if err != nil { return nil, utils.BadRequest("INVALID_CATEGORY_ID", "Invalid category ID in category_ids", err) }
-			if _, err := s.categoryRepo.Get(ctx, oid); err != nil { return nil, utils.BadRequest("CATEGORY_NOT_FOUND", "Category not found in category_ids", nil) }
+			if _, err := s.categoryRepo.Get(ctx, oid, tenantID); err != nil { return nil, utils.BadRequest("CATEGORY_NOT_FOUND", "Category not found in category_ids", nil) }
 			categoryIDs = append(categoryIDs, oid)
 		}
 	}
@@ -255,7 +272,7 @@ func (s *ProductService) Create(ctx context.Context, body models.ProductCreate,
 
 	if body.SupplierID != "" {
 		if oid, err := primitive.ObjectIDFromHex(body.SupplierID); err == nil {
-			if _, err := s.supplierRepo.Get(ctx, oid); err != nil {
+			if _, err := s.supplierRepo.Get(ctx, oid, tenantID); err != nil {
 				return nil, utils.BadRequest("SUPPLIER_NOT_FOUND", "Supplier not found", nil)
 			}
 		} else {
@@ -271,7 +288,6 @@ func (s *ProductService) Create(ctx context.Context, body models.ProductCreate,
 		Description: body.Description,
 		Price:       body.Price,
 		CostPrice:   body.CostPrice,
-		Stock:       body.Stock,
 		MinStock:    body.MinStock,
 		MaxStock:    body.MaxStock,
 		Unit:        body.Unit,
@@ -293,10 +309,13 @@ func (s *ProductService) Create(ctx context.Context, body models.ProductCreate,
 
 		ProductType: kind,
 		SetItems:    body.SetItems,
+		SetMode:     body.SetMode,
 
 		Barcode:              body.Barcode,
 		ExpirationDate:       body.ExpirationDate,
 		IsDirtyCore:          body.IsDirtyCore,
+		CoreCharge:           body.CoreCharge,
+		SerialTracked:        body.SerialTracked,
 		IsRealizatsiya:       body.IsRealizatsiya,
 		IsKonsignatsiya:      body.IsKonsignatsiya,
 		KonsignatsiyaDate:    body.KonsignatsiyaDate,
@@ -337,17 +356,38 @@ func (s *ProductService) Create(ctx context.Context, body models.ProductCreate,
 		if oid, err := primitive.ObjectIDFromHex(body.StoreID); err == nil { m.StoreID = oid }
 	}
 
+	// tracked units enter stock with their serial numbers, which a plain quantity cannot carry
+	if m.SerialTracked && m.ProductType != models.ProductKindProduct {
+		return nil, utils.BadRequest("VALIDATION_ERROR", "Only goods can be tracked by serial number", nil)
+	}
+	if m.SerialTracked && body.Stock > 0 {
+		return nil, utils.BadRequest("SERIALS_REQUIRED", "Receive serial-tracked products through a supplier order", nil)
+	}
+	// stock is kept in the base unit; the other units are converted to it on documents
+	if err := s.units.baseUnit(ctx, tenantID, m.Unit); err != nil { return nil, err }
+	units, err := s.units.productUnits(ctx, tenantID, m.Unit, body.Units)
+	if err != nil { return nil, err }
+	m.Units = units
+	if err := s.units.fits(ctx, m, m.Unit, body.Stock); err != nil { return nil, err }
+
 	created, err := s.repo.Create(ctx, m)
 	if err != nil {
 		return nil, utils.Internal("PRODUCT_CREATE_FAILED", "Unable to create product", err)
 	}
 
+	// Initial stock is received into the product's store
+	if body.Stock > 0 {
+		if _, err := s.stock.Set(ctx, tenantID, created.ID, body.StoreID, body.Stock, created.CostPrice, models.StockSource{ Type: models.StockSourceProduct, ID: created.ID.Hex() }); err != nil {
+			return nil, err
+		}
+	}
+
 	// Record import if initial stock > 0 and not a SET/SERVICE (they are informational)
-	if m.Stock > 0 && s.importHistoryRepo != nil && m.ProductType == models.ProductKindProduct {
+	if body.Stock > 0 && s.importHistoryRepo != nil && m.ProductType == models.ProductKindProduct {
 		go func() {
 			defer func(){ _ = recover() }()
 			svc := NewImportHistoryService(s.importHistoryRepo)
-			item := models.ImportHistoryItemInput{ ProductID: created.ID.Hex(), ProductName: m.Name, ProductSKU: m.SKU, Barcode: m.Barcode, Qty: int(m.Stock), Unit: m.Unit }
+			item := models.ImportHistoryItemInput{ ProductID: created.ID.Hex(), ProductName: m.Name, ProductSKU: m.SKU, Barcode: m.Barcode, Qty: body.Stock, Unit: m.Unit }
 			_, _ = svc.Create(ctx, tenantID, "", models.CreateImportHistoryRequest{ FileName: "Product creation", StoreID: body.StoreID, StoreName: "", TotalRows: 1, SuccessRows: 1, ErrorRows: 0, Status: "completed", ImportType: "PRODUCT_CREATION", Items: []models.ImportHistoryItemInput{ item } })
 		}()
 	}
@@ -362,7 +402,7 @@ func (s *ProductService) Update(ctx context.Context, id string, body models.Prod
 		return nil, utils.BadRequest("INVALID_ID", "Invalid product id", nil)
 	}
 
-	// Read existing to detect stock changes later
+	// Read existing to resolve the store a stock change applies to
 	existing, err := s.repo.Get(ctx, oid, tenantID)
 	if err != nil {
 		return nil, utils.NotFound("PRODUCT_NOT_FOUND", "Product not found", err)
@@ -409,8 +449,9 @@ func (s *ProductService) Update(ctx context.Context, id string, body models.Prod
 	if body.CostPrice != nil {
 		update["cost_price"] = *body.CostPrice
 	}
-	if body.Stock != nil {
-		update["stock"] = *body.Stock
+	// stock is the quantity in the product's own store; it is applied to that store's balance after the update
+	if body.Stock != nil && *body.Stock < 0 {
+		return nil, utils.BadRequest("VALIDATION_ERROR", "Stock cannot be negative", nil)
 	}
 	if body.MinStock != nil {
 		update["min_stock"] = *body.MinStock
@@ -418,8 +459,21 @@ func (s *ProductService) Update(ctx context.Context, id string, body models.Prod
 	if body.MaxStock != nil {
 		update["max_stock"] = *body.MaxStock
 	}
-	if body.Unit != nil {
+	if body.Unit != nil && *body.Unit != existing.Unit {
+		// stock is counted in the base unit, so changing it would change what the quantities on hand mean
+		if existing.Stock != 0 { return nil, utils.Conflict("UNIT_LOCKED", "The base unit can only be changed while the product is out of stock", nil) }
+		if err := s.units.baseUnit(ctx, tenantID, *body.Unit); err != nil { return nil, err }
 		update["unit"] = *body.Unit
+		existing.Unit = *body.Unit
+	}
+	if body.Units != nil || body.Unit != nil {
+		if body.Units == nil { body.Units = existing.Units }
+		units, err := s.units.productUnits(ctx, tenantID, existing.Unit, body.Units)
+		if err != nil { return nil, err }
+		update["units"] = units
+	}
+	if body.Stock != nil {
+		if err := s.units.fits(ctx, existing, existing.Unit, *body.Stock); err != nil { return nil, err }
 	}
 	if body.Weight != nil {
 		update["weight"] = *body.Weight
@@ -434,7 +488,7 @@ func (s *ProductService) Update(ctx context.Context, id string, body models.Prod
 			update["category_id"] = primitive.NilObjectID
 		} else {
 			if oid, err := primitive.ObjectIDFromHex(*body.CategoryID); err == nil {
-				if _, err := s.categoryRepo.Get(ctx, oid); err != nil {
+				if _, err := s.categoryRepo.Get(ctx, oid, tenantID); err != nil {
 					return nil, utils.BadRequest("CATEGORY_NOT_FOUND", "Category not found", nil)
 				}
 				update["category_id"] = oid
@@ -450,7 +504,7 @@ func (s *ProductService) Update(ctx context.Context, id string, body models.Prod
 			if id == "" { continue }
 			cid, err := primitive.ObjectIDFromHex(id)
 			if err != nil { return nil, utils.BadRequest("INVALID_CATEGORY_ID", "Invalid category id in category_ids", err) }
-			if _, err := s.categoryRepo.Get(ctx, cid); err != nil { return nil, utils.BadRequest("CATEGORY_NOT_FOUND", "Category not found in category_ids", nil) }
+			if _, err := s.categoryRepo.Get(ctx, cid, tenantID); err != nil { return nil, utils.BadRequest("CATEGORY_NOT_FOUND", "Category not found in category_ids", nil) }
 			ids = append(ids, cid)
 		}
 		update["category_ids"] = ids
@@ -476,7 +530,7 @@ func (s *ProductService) Update(ctx context.Context, id string, body models.Prod
 			update["supplier_id"] = primitive.NilObjectID
 		} else {
 			if oid, err := primitive.ObjectIDFromHex(*body.SupplierID); err == nil {
-				if _, err := s.supplierRepo.Get(ctx, oid); err != nil {
+				if _, err := s.supplierRepo.Get(ctx, oid, tenantID); err != nil {
 					return nil, utils.BadRequest("SUPPLIER_NOT_FOUND", "Supplier not found", nil)
 				}
 				update["supplier_id"] = oid
@@ -550,6 +604,18 @@ func (s *ProductService) Update(ctx context.Context, id string, body models.Prod
 	if body.SetItems != nil {
 		update["set_items"] = body.SetItems
 	}
+	if body.SetMode != nil || body.ProductType != nil {
+		kind, mode := existing.ProductType, existing.SetMode
+		if body.ProductType != nil { kind = *body.ProductType }
+		if body.SetMode != nil { mode = *body.SetMode }
+		if err := validSetMode(kind, mode); err != nil { return nil, err }
+		// an assembled set's own units would be stranded once it is sold as its components
+		wasAssembled := existing.ProductType == models.ProductKindSet && existing.SetMode == models.ProductSetAssembled
+		if wasAssembled && (kind != models.ProductKindSet || mode != models.ProductSetAssembled) && existing.Stock != 0 {
+			return nil, utils.Conflict("SET_MODE_LOCKED", "An assembled set can only become virtual once its stock is disassembled", nil)
+		}
+		if body.SetMode != nil { update["set_mode"] = mode }
+	}
 
 	// Other fields
 	if body.Barcode != nil {
@@ -561,6 +627,20 @@ func (s *ProductService) Update(ctx context.Context, id string, body models.Prod
 	if body.IsDirtyCore != nil {
 		update["is_dirty_core"] = *body.IsDirtyCore
 	}
+	if body.CoreCharge != nil {
+		if *body.CoreCharge < 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Core charge cannot be negative", nil) }
+		update["core_charge"] = *body.CoreCharge
+	}
+	if body.SerialTracked != nil && *body.SerialTracked != existing.SerialTracked {
+		// units already in stock have no serial numbers to follow
+		if *body.SerialTracked && existing.Stock > 0 {
+			return nil, utils.Conflict("SERIAL_TRACKING_LOCKED", "Serial tracking can only be turned on while the product is out of stock", nil)
+		}
+		update["serial_tracked"] = *body.SerialTracked
+	}
+	if body.Stock != nil && (existing.SerialTracked || (body.SerialTracked != nil && *body.SerialTracked)) {
+		return nil, utils.BadRequest("SERIALS_REQUIRED", "Stock of serial-tracked products moves through documents naming the serials", nil)
+	}
 	if body.IsRealizatsiya != nil {
 		update["is_realizatsiya"] = *body.IsRealizatsiya
 	}
@@ -586,23 +666,37 @@ func (s *ProductService) Update(ctx context.Context, id string, body models.Prod
 		update["is_active"] = *body.IsActive
 	}
 
+	storeHex := ""
+	if existing.StoreID != primitive.NilObjectID { storeHex = existing.StoreID.Hex() }
+	if sid, ok := update["store_id"].(primitive.ObjectID); ok {
+		storeHex = ""
+		if sid != primitive.NilObjectID { storeHex = sid.Hex() }
+	}
+	if body.Stock != nil && storeHex == "" {
+		return nil, utils.BadRequest("STORE_REQUIRED", "Store is required to change stock", nil)
+	}
+
 	updated, err := s.repo.Update(ctx, oid, tenantID, update)
 	if err != nil {
 		return nil, utils.Internal("PRODUCT_UPDATE_FAILED", "Unable to update product", err)
 	}
 
+	oldStock := 0.0
+	if body.Stock != nil {
+		if oldStock, err = s.stock.Set(ctx, tenantID, oid, storeHex, *body.Stock, updated.CostPrice, models.StockSource{ Type: models.StockSourceProduct, ID: updated.ID.Hex() }); err != nil {
+			return nil, err
+		}
+	}
+
 	// Record import if stock increased via update and only for normal products
 	if body.Stock != nil && s.importHistoryRepo != nil && updated.ProductType == models.ProductKindProduct {
-		oldStock := existing.Stock
 		newStock := *body.Stock
 		if newStock > oldStock {
 			delta := newStock - oldStock
 			go func() {
 				defer func(){ _ = recover() }()
 				svc := NewImportHistoryService(s.importHistoryRepo)
-				item := models.ImportHistoryItemInput{ ProductID: updated.ID.Hex(), ProductName: updated.Name, ProductSKU: updated.SKU, Barcode: updated.Barcode, Qty: int(delta), Unit: updated.Unit }
-				storeHex := ""
-				if updated.StoreID != primitive.NilObjectID { storeHex = updated.StoreID.Hex() }
+				item := models.ImportHistoryItemInput{ ProductID: updated.ID.Hex(), ProductName: updated.Name, ProductSKU: updated.SKU, Barcode: updated.Barcode, Qty: delta, Unit: updated.Unit }
 				_, _ = svc.Create(ctx, tenantID, "", models.CreateImportHistoryRequest{ FileName: "Product stock update", StoreID: storeHex, StoreName: "", TotalRows: 1, SuccessRows: 1, ErrorRows: 0, Status: "completed", ImportType: "PRODUCT_STORE", Items: []models.ImportHistoryItemInput{ item } })
 			}()
 		}
@@ -680,7 +774,18 @@ func (s *ProductService) Delete(ctx context.Context, id string, tenantID string)
 	return nil
 }
 
-func (s *ProductService) UpdateStock(ctx context.Context, id string, stock int, tenantID string) error {
+// validSetMode checks a set mode against the product kind: only a SET has one, virtual or assembled.
+func validSetMode(kind, mode string) error {
+	if mode == "" { return nil }
+	if mode != models.ProductSetVirtual && mode != models.ProductSetAssembled {
+		return utils.BadRequest("INVALID_SET_MODE", "Invalid set mode. Valid: virtual, assembled", nil)
+	}
+	if kind != models.ProductKindSet { return utils.BadRequest("INVALID_SET_MODE", "Only a SET has a set mode", nil) }
+	return nil
+}
+
+// UpdateStock sets the quantity of a product in one store; shopID defaults to the product's own store.
+func (s *ProductService) UpdateStock(ctx context.Context, id string, stock float64, shopID string, tenantID string) error {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return utils.BadRequest("INVALID_ID", "Invalid product id", nil)
@@ -690,10 +795,75 @@ func (s *ProductService) UpdateStock(ctx context.Context, id string, stock int,
 		return utils.BadRequest("VALIDATION_ERROR", "Stock cannot be negative", nil)
 	}
 
-	if err := s.repo.UpdateStock(ctx, oid, tenantID, stock); err != nil {
-		return utils.Internal("STOCK_UPDATE_FAILED", "Unable to update product stock", err)
+	m, err := s.repo.Get(ctx, oid, tenantID)
+	if err != nil {
+		return utils.NotFound("PRODUCT_NOT_FOUND", "Product not found", err)
 	}
+	if shopID == "" && m.StoreID != primitive.NilObjectID {
+		shopID = m.StoreID.Hex()
+	}
+
+	if err := s.units.fits(ctx, m, m.Unit, stock); err != nil { return err }
+	_, err = s.stock.Set(ctx, tenantID, oid, shopID, stock, m.CostPrice, models.StockSource{ Type: models.StockSourceProduct, ID: m.ID.Hex() })
+	return err
+}
 
+// attachStoreStocks fills StoreStocks, Reserved and Available on dtos (parallel to items). A virtual SET is on hand
+// and available in a store as many times as its scarcest component allows there; an assembled one has stock of its own.
+func (s *ProductService) attachStoreStocks(ctx context.Context, tenantID string, items []models.Product, dtos []models.ProductDTO) error {
+	ids := make([]primitive.ObjectID, 0, len(items))
+	for _, p := range items {
+		ids = append(ids, p.ID)
+		if p.VirtualSet() {
+			for _, it := range p.SetItems { ids = append(ids, it.ProductID) }
+		}
+	}
+	stocks, err := s.stock.StoreStocks(ctx, tenantID, ids)
+	if err != nil {
+		return err
+	}
+	for i, p := range items {
+		if !p.VirtualSet() {
+			if rows, ok := stocks[p.ID]; ok { dtos[i].StoreStocks = rows }
+			var reserved float64
+			for _, row := range dtos[i].StoreStocks { reserved += row.Reserved }
+			dtos[i].Reserved = models.RoundQty(reserved)
+			dtos[i].Available = math.Max(0, models.RoundQty(dtos[i].Stock-reserved))
+			continue
+		}
+		perShop := map[string]models.ProductStoreStock{}
+		first := true
+		for _, it := range p.SetItems {
+			if it.Quantity <= 0 { continue }
+			avail := map[string]models.ProductStoreStock{}
+			for _, row := range stocks[it.ProductID] {
+				avail[row.ShopID] = models.ProductStoreStock{ShopID: row.ShopID, Qty: math.Floor(row.Qty / it.Quantity), Available: math.Floor(row.Available / it.Quantity)}
+			}
+			if first {
+				perShop = avail
+				first = false
+				continue
+			}
+			for shop, n := range perShop {
+				a := avail[shop]
+				if a.Qty < n.Qty { n.Qty = a.Qty }
+				if a.Available < n.Available { n.Available = a.Available }
+				perShop[shop] = n
+			}
+		}
+		rows := []models.ProductStoreStock{}
+		var reserved, available float64
+		for shop, n := range perShop {
+			if n.Qty <= 0 { continue }
+			n.ShopID, n.Reserved = shop, n.Qty-n.Available
+			rows = append(rows, n)
+			reserved += n.Reserved
+			available += n.Available
+		}
+		sort.Slice(rows, func(a, b int) bool { return rows[a].ShopID < rows[b].ShopID })
+		dtos[i].StoreStocks = rows
+		dtos[i].Reserved, dtos[i].Available = reserved, available
+	}
 	return nil
 }
 
@@ -740,7 +910,7 @@ func (s *ProductService) BulkEditProperties(ctx context.Context, ids []string, p
 	if props.CategoryID != nil {
 		if *props.CategoryID == "" { update["category_id"] = primitive.NilObjectID } else {
 			cid, err := primitive.ObjectIDFromHex(*props.CategoryID); if err != nil { return 0, utils.BadRequest("INVALID_CATEGORY_ID", "Invalid category id", err) }
-			if _, err := s.categoryRepo.Get(ctx, cid); err != nil { return 0, utils.BadRequest("CATEGORY_NOT_FOUND", "Category not found", nil) }
+			if _, err := s.categoryRepo.Get(ctx, cid, tenantID); err != nil { return 0, utils.BadRequest("CATEGORY_NOT_FOUND", "Category not found", nil) }
 			update["category_id"] = cid
 		}
 	}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PutawayService places received, unbinned units of a store into its bins. Receiving a supplier order drafts a
+// putaway with a suggested bin per line; the bins can be changed until it is completed.
+type PutawayService struct {
+	repo     *repositories.PutawayRepository
+	products *repositories.ProductRepository
+	bins     *BinService
+	stock    *StockService
+}
+
+func NewPutawayService(repo *repositories.PutawayRepository, products *repositories.ProductRepository, bins *BinService, stock *StockService) *PutawayService {
+	return &PutawayService{repo: repo, products: products, bins: bins, stock: stock}
+}
+
+func (s *PutawayService) List(ctx context.Context, f models.PutawayFilterRequest, tenantID string) ([]models.Putaway, int64, error) {
+	items, total, err := s.repo.List(ctx, repositories.PutawayListParams{
+		Page: int64(ifZeroInt(f.Page, 1)), Limit: int64(ifZeroInt(f.Limit, 20)),
+		ShopID: f.ShopID, OrderID: f.OrderID, Status: f.Status, TenantID: tenantID,
+	})
+	if err != nil { return nil, 0, utils.Internal("PUTAWAY_LIST_FAILED", "Unable to list putaways", err) }
+	return items, total, nil
+}
+
+func (s *PutawayService) Get(ctx context.Context, id string, tenantID string) (*models.Putaway, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid putaway id", err) }
+	m, err := s.repo.Get(ctx, oid, tenantID)
+	if err != nil { return nil, utils.NotFound("PUTAWAY_NOT_FOUND", "Putaway not found", err) }
+	return m, nil
+}
+
+// Create drafts a putaway of unbinned units, such as goods that came in on a transfer or a sale return. Lines without
+// a bin get the suggested one.
+func (s *PutawayService) Create(ctx context.Context, body models.CreatePutawayRequest, tenantID string, createdBy models.InventoryUser) (*models.Putaway, error) {
+	if strings.TrimSpace(body.ShopID) == "" { return nil, utils.BadRequest("STORE_REQUIRED", "Store is required", nil) }
+	if len(body.Items) == 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Items are required", nil) }
+	has, err := s.bins.HasBins(ctx, tenantID, body.ShopID)
+	if err != nil { return nil, err }
+	if !has { return nil, utils.BadRequest("NO_BINS", "The store has no bins", nil) }
+	m := &models.Putaway{ TenantID: tenantID, ExternalID: generateExternalID(), ShopID: body.ShopID, Status: "NEW", Comment: body.Comment, CreatedBy: createdBy }
+	occupied, err := s.bins.Occupied(ctx, tenantID, body.ShopID)
+	if err != nil { return nil, err }
+	for _, in := range body.Items {
+		pid, err := primitive.ObjectIDFromHex(in.ProductID)
+		if err != nil { return nil, utils.BadRequest("INVALID_PRODUCT_ID", "Invalid product id", err) }
+		p, err := s.products.Get(ctx, pid, tenantID)
+		if err != nil { return nil, utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found", err) }
+		q, err := s.stock.inUnit(ctx, p, in.Unit, in.Qty)
+		if err != nil { return nil, err }
+		if q.qty <= 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Quantity must be greater than 0", nil) }
+		l, err := s.line(ctx, tenantID, body.ShopID, p, q.qty, in.BinID, occupied)
+		if err != nil { return nil, err }
+		m.Lines = append(m.Lines, l)
+		m.TotalQty = models.RoundQty(m.TotalQty + l.Qty)
+	}
+	created, err := s.repo.Create(ctx, m)
+	if err != nil { return nil, utils.Internal("PUTAWAY_CREATE_FAILED", "Unable to create putaway", err) }
+	return created, nil
+}
+
+// draft drafts the putaway of what a receiving brought into a store with bins. It returns nil for a store without
+// bins. It runs inside the receiving's transaction.
+func (s *PutawayService) draft(ctx context.Context, o *models.Order, received []models.OrderItem, user models.OrderUser) (*models.Putaway, error) {
+	has, err := s.bins.HasBins(ctx, o.TenantID, o.ShopID)
+	if err != nil || !has { return nil, err }
+	m := &models.Putaway{
+		TenantID: o.TenantID, ExternalID: generateExternalID(), ShopID: o.ShopID, OrderID: o.ID.Hex(), OrderName: o.Name, Status: "NEW",
+		CreatedBy: models.InventoryUser{ ID: user.ID, Name: user.Name },
+	}
+	occupied, err := s.bins.Occupied(ctx, o.TenantID, o.ShopID)
+	if err != nil { return nil, err }
+	for _, it := range received {
+		if it.ProductID == primitive.NilObjectID || it.Quantity <= 0 { continue }
+		p, err := s.products.Get(ctx, it.ProductID, o.TenantID)
+		if err != nil { return nil, utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found for order", err) }
+		l, err := s.line(ctx, o.TenantID, o.ShopID, p, it.Quantity, "", occupied)
+		if err != nil { return nil, err }
+		m.Lines = append(m.Lines, l)
+		m.TotalQty = models.RoundQty(m.TotalQty + l.Qty)
+	}
+	if len(m.Lines) == 0 { return nil, nil }
+	created, err := s.repo.Create(ctx, m)
+	if err != nil { return nil, utils.Internal("PUTAWAY_CREATE_FAILED", "Unable to create putaway", err) }
+	return created, nil
+}
+
+// Update runs in one transaction: completing a putaway fills the bins together with the COMPLETED status.
+func (s *PutawayService) Update(ctx context.Context, id string, body models.UpdatePutawayRequest, tenantID string, actor models.InventoryUser) (*models.Putaway, error) {
+	var out *models.Putaway
+	err := s.stock.Atomically(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = s.update(ctx, id, body, tenantID, actor)
+		return err
+	})
+	return out, err
+}
+
+func (s *PutawayService) update(ctx context.Context, id string, body models.UpdatePutawayRequest, tenantID string, actor models.InventoryUser) (*models.Putaway, error) {
+	cur, err := s.Get(ctx, id, tenantID)
+	if err != nil { return nil, err }
+	if cur.Status != "NEW" { return nil, utils.Conflict("PUTAWAY_FINISHED", "The putaway is already "+cur.Status, nil) }
+	update := bson.M{}
+	if body.Comment != nil { update["comment"] = *body.Comment }
+	if len(body.Lines) > 0 {
+		for _, in := range body.Lines {
+			if in.Line < 0 || in.Line >= len(cur.Lines) { return nil, utils.BadRequest("VALIDATION_ERROR", "Invalid putaway line", nil) }
+			l := &cur.Lines[in.Line]
+			l.BinID, l.BinPath = "", ""
+			if strings.TrimSpace(in.BinID) == "" { continue }
+			b, err := s.bin(ctx, tenantID, in.BinID, cur.ShopID)
+			if err != nil { return nil, err }
+			l.BinID, l.BinPath = b.ID.Hex(), b.Path
+		}
+		update["lines"] = cur.Lines
+	}
+
+	switch body.Action {
+	case "complete":
+		for _, l := range cur.Lines {
+			if l.BinID == "" { return nil, utils.BadRequest("BIN_REQUIRED", "Choose a bin for "+l.ProductName, nil) }
+		}
+		update["status"], update["finished_at"], update["finished_by"] = "COMPLETED", time.Now().UTC(), actor
+	case "cancel":
+		update["status"], update["finished_at"], update["finished_by"] = "CANCELLED", time.Now().UTC(), actor
+	case "":
+	default:
+		return nil, utils.BadRequest("VALIDATION_ERROR", "action must be complete or cancel", nil)
+	}
+
+	// the putaway is claimed out of NEW before any bin is filled, so of two concurrent completions only one fills them
+	ok, err := s.repo.UpdateIf(ctx, cur.ID, tenantID, bson.M{"status": "NEW"}, update)
+	if err != nil { return nil, utils.Internal("PUTAWAY_UPDATE_FAILED", "Unable to update putaway", err) }
+	if !ok { return nil, utils.Conflict("PUTAWAY_FINISHED", "The putaway was changed by another request; reload it and try again", nil) }
+	if body.Action == "complete" {
+		if err := s.complete(ctx, cur); err != nil { return nil, err }
+	}
+	return s.Get(ctx, id, tenantID)
+}
+
+// Delete removes a putaway that placed nothing.
+func (s *PutawayService) Delete(ctx context.Context, id string, tenantID string) error {
+	m, err := s.Get(ctx, id, tenantID)
+	if err != nil { return err }
+	if m.Status == "COMPLETED" { return utils.Conflict("PUTAWAY_COMPLETED", "A completed putaway cannot be deleted", nil) }
+	if err := s.repo.Delete(ctx, m.ID, tenantID); err != nil { return utils.Internal("PUTAWAY_DELETE_FAILED", "Unable to delete putaway", err) }
+	return nil
+}
+
+// complete moves the units of every line from the unbinned stock into its bin. A line can only place what is still
+// unbinned: the store's balance less what its bins already hold.
+func (s *PutawayService) complete(ctx context.Context, m *models.Putaway) error {
+	need := map[primitive.ObjectID]float64{}
+	for _, l := range m.Lines {
+		if l.BinID == "" { return utils.BadRequest("BIN_REQUIRED", "Choose a bin for "+l.ProductName, nil) }
+		need[l.ProductID] = models.RoundQty(need[l.ProductID] + l.Qty)
+	}
+	for pid, qty := range need {
+		onHand, err := s.stock.Available(ctx, m.TenantID, pid, m.ShopID)
+		if err != nil { return err }
+		binned, err := s.bins.Binned(ctx, m.TenantID, m.ShopID, pid)
+		if err != nil { return err }
+		if unbinned := models.RoundQty(onHand - binned); qty > unbinned {
+			name := pid.Hex()
+			for _, l := range m.Lines { if l.ProductID == pid { name = l.ProductName; break } }
+			return utils.Conflict("PUTAWAY_EXCEEDS_UNBINNED", "Only "+strconv.FormatFloat(unbinned, 'f', -1, 64)+" of "+name+" wait to be put away", nil)
+		}
+	}
+	for _, l := range m.Lines {
+		b, err := s.bin(ctx, m.TenantID, l.BinID, m.ShopID)
+		if err != nil { return err }
+		if err := s.bins.put(ctx, m.TenantID, m.ShopID, b, l.ProductID, l.Qty); err != nil { return err }
+	}
+	return nil
+}
+
+// line builds a putaway line into the given bin or, without one, the suggested bin.
+func (s *PutawayService) line(ctx context.Context, tenantID, shopID string, p *models.Product, qty float64, binID string, occupied map[primitive.ObjectID]bool) (models.PutawayLine, error) {
+	l := models.PutawayLine{ ProductID: p.ID, ProductName: p.Name, ProductSKU: p.SKU, Unit: ifEmpty(p.Unit, "pcs"), Qty: qty }
+	var b *models.WarehouseLocation
+	var err error
+	if strings.TrimSpace(binID) != "" {
+		b, err = s.bin(ctx, tenantID, binID, shopID)
+	} else {
+		b, err = s.bins.Suggest(ctx, tenantID, shopID, p, occupied)
+	}
+	if err != nil { return l, err }
+	if b != nil { l.BinID, l.BinPath = b.ID.Hex(), b.Path }
+	return l, nil
+}
+
+// bin loads an active bin of the store.
+func (s *PutawayService) bin(ctx context.Context, tenantID, binID, shopID string) (*models.WarehouseLocation, error) {
+	b, err := s.bins.Get(ctx, tenantID, binID, shopID)
+	if err != nil { return nil, err }
+	if !b.IsActive { return nil, utils.BadRequest("BIN_INACTIVE", "Bin "+b.Path+" is not active", nil) }
+	return b, nil
+}
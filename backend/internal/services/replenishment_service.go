@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReplenishmentService works out what each store has to reorder and turns it into draft supplier orders.
+// A product is reordered when its stock plus what is still on order falls to the reorder point, the larger of
+// MinStock and the sales expected over the cover days; the order fills it up to the larger of MaxStock and the
+// reorder point.
+type ReplenishmentService struct {
+	products  *repositories.ProductRepository
+	orders    *repositories.OrderRepository
+	balances  *repositories.StockRepository
+	movements *repositories.StockMovementRepository
+	suppliers *repositories.SupplierRepository
+	stores    *repositories.StoreRepository
+	units     *MeasureUnitService
+	orderSvc  *OrderService
+	stock     *StockService
+}
+
+func NewReplenishmentService(products *repositories.ProductRepository, orders *repositories.OrderRepository, balances *repositories.StockRepository, movements *repositories.StockMovementRepository, suppliers *repositories.SupplierRepository, stores *repositories.StoreRepository, units *MeasureUnitService, orderSvc *OrderService, stock *StockService) *ReplenishmentService {
+	return &ReplenishmentService{products: products, orders: orders, balances: balances, movements: movements, suppliers: suppliers, stores: stores, units: units, orderSvc: orderSvc, stock: stock}
+}
+
+// Suggestions lists, per supplier, the products the store should reorder and how much of each.
+func (s *ReplenishmentService) Suggestions(ctx context.Context, tenantID string, req models.ReplenishmentRequest) (*models.ReplenishmentSuggestions, error) {
+	if req.ShopID == "" { return nil, utils.BadRequest("VALIDATION_ERROR", "Shop is required", nil) }
+	if req.SalesDays == 0 { req.SalesDays = 30 }
+	if req.SalesDays < 1 || req.SalesDays > 365 { return nil, utils.BadRequest("VALIDATION_ERROR", "sales_days must be between 1 and 365", nil) }
+	if req.CoverDays < 0 || req.CoverDays > 365 { return nil, utils.BadRequest("VALIDATION_ERROR", "cover_days must be between 0 and 365", nil) }
+	since := time.Now().UTC().AddDate(0, 0, -req.SalesDays)
+	sold, err := s.movements.SumBySource(ctx, tenantID, req.ShopID, []string{models.StockSourceSale, models.StockSourceSaleReturn}, since)
+	if err != nil { return nil, utils.Internal("REPLENISHMENT_FAILED", "Unable to read sales", err) }
+	ids := make([]primitive.ObjectID, 0, len(sold))
+	for pid, qty := range sold { if qty < 0 { ids = append(ids, pid) } }
+	products, err := s.products.ListReplenishable(ctx, tenantID, ids)
+	if err != nil { return nil, utils.Internal("REPLENISHMENT_FAILED", "Unable to list products", err) }
+	onHand, err := s.balances.ByShop(ctx, tenantID, req.ShopID)
+	if err != nil { return nil, utils.Internal("REPLENISHMENT_FAILED", "Unable to read stock", err) }
+	onOrder, err := s.orders.OnOrder(ctx, tenantID, req.ShopID)
+	if err != nil { return nil, utils.Internal("REPLENISHMENT_FAILED", "Unable to read open orders", err) }
+
+	out := &models.ReplenishmentSuggestions{ShopID: req.ShopID, SalesDays: req.SalesDays, CoverDays: req.CoverDays, Groups: []models.ReplenishmentGroup{}}
+	groups := map[string]*models.ReplenishmentGroup{}
+	for _, p := range products {
+		daily := 0.0
+		if qty := sold[p.ID]; qty < 0 { daily = roundCost(-qty / float64(req.SalesDays)) }
+		reorderPoint := math.Max(p.MinStock, models.RoundQty(daily*float64(req.CoverDays)))
+		target := math.Max(p.MaxStock, reorderPoint)
+		if target <= 0 { continue }
+		position := models.RoundQty(onHand[p.ID] + onOrder[p.ID])
+		if position > reorderPoint || position >= target { continue }
+		qty, err := s.roundUp(ctx, &p, target-position)
+		if err != nil { return nil, err }
+		line := models.ReplenishmentLine{
+			ProductID: p.ID.Hex(), ProductName: p.Name, ProductSKU: p.SKU, Unit: p.Unit,
+			Stock: onHand[p.ID], OnOrder: onOrder[p.ID], MinStock: p.MinStock, MaxStock: p.MaxStock, DailySales: daily,
+			ReorderPoint: reorderPoint, TargetStock: target, SuggestedQty: qty, SupplyPrice: p.CostPrice, Amount: roundMoney(qty * p.CostPrice),
+		}
+		supplierID := ""
+		if !p.SupplierID.IsZero() { supplierID = p.SupplierID.Hex() }
+		g := groups[supplierID]
+		if g == nil {
+			g = &models.ReplenishmentGroup{SupplierID: supplierID}
+			if sup, err := s.suppliers.Get(ctx, p.SupplierID, tenantID); err == nil { g.SupplierName = sup.Name }
+			groups[supplierID] = g
+		}
+		g.Items = append(g.Items, line)
+		g.TotalAmount = roundMoney(g.TotalAmount + line.Amount)
+	}
+	for _, g := range groups { out.Groups = append(out.Groups, *g) }
+	// suppliers by name, products without a supplier last
+	sort.Slice(out.Groups, func(i, j int) bool {
+		a, b := out.Groups[i], out.Groups[j]
+		if (a.SupplierID == "") != (b.SupplierID == "") { return b.SupplierID == "" }
+		return a.SupplierName < b.SupplierName
+	})
+	return out, nil
+}
+
+// CreateOrders creates one draft supplier order per supplier from the current suggestions, all or none. Products
+// without a supplier are left out.
+func (s *ReplenishmentService) CreateOrders(ctx context.Context, tenantID string, req models.ReplenishmentRequest, user models.OrderUser) (*models.ReplenishmentOrders, error) {
+	sug, err := s.Suggestions(ctx, tenantID, req)
+	if err != nil { return nil, err }
+	only := map[string]bool{}
+	for _, id := range req.SupplierIDs { only[id] = true }
+	shopName := req.ShopID
+	if oid, err := primitive.ObjectIDFromHex(req.ShopID); err == nil {
+		if st, err := s.stores.Get(ctx, oid); err == nil { shopName = st.Title }
+	}
+	out := &models.ReplenishmentOrders{Orders: []models.Order{}}
+	err = s.stock.Atomically(ctx, func(ctx context.Context) error {
+		out.Orders = out.Orders[:0]
+		for _, g := range sug.Groups {
+			if g.SupplierID == "" || (len(only) > 0 && !only[g.SupplierID]) { continue }
+			body := models.CreateOrderRequest{
+				Name: "Reorder " + shopName + " " + time.Now().UTC().Format("2006-01-02"), SupplierID: g.SupplierID, ShopID: req.ShopID, Type: "supplier_order",
+				Comment: "Suggested by replenishment", Items: make([]models.OrderItemInput, 0, len(g.Items)),
+			}
+			for _, it := range g.Items {
+				body.Items = append(body.Items, models.OrderItemInput{ProductID: it.ProductID, ProductName: it.ProductName, ProductSKU: it.ProductSKU, Quantity: it.SuggestedQty, UnitPrice: it.SupplyPrice, SupplyPrice: it.SupplyPrice, Unit: it.Unit})
+			}
+			o, err := s.orderSvc.Create(ctx, body, tenantID, user)
+			if err != nil { return err }
+			out.Orders = append(out.Orders, *o)
+		}
+		return nil
+	})
+	if err != nil { return nil, err }
+	if len(out.Orders) == 0 { return nil, utils.BadRequest("NOTHING_TO_ORDER", "No supplier has products to reorder", nil) }
+	return out, nil
+}
+
+// roundUp rounds a suggested quantity up to the decimals the product's base unit is counted in.
+func (s *ReplenishmentService) roundUp(ctx context.Context, p *models.Product, qty float64) (float64, error) {
+	precision := models.QtyDecimals
+	if p.Unit != "" {
+		u, err := s.units.unit(ctx, p.TenantID, p.Unit)
+		if err != nil { return 0, err }
+		if u != nil { precision = u.Precision }
+	}
+	scale := math.Pow10(precision)
+	return models.RoundQty(math.Ceil(models.RoundQty(qty)*scale-1e-6) / scale), nil
+}
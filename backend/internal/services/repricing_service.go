@@ -17,9 +17,10 @@ type RepricingService struct {
 	repo *repositories.RepricingRepository
 	store *repositories.StoreRepository
 	product *repositories.ProductRepository
+	stock *StockService
 }
 
-func NewRepricingService(repo *repositories.RepricingRepository, store *repositories.StoreRepository, product *repositories.ProductRepository) *RepricingService { return &RepricingService{repo: repo, store: store, product: product} }
+func NewRepricingService(repo *repositories.RepricingRepository, store *repositories.StoreRepository, product *repositories.ProductRepository, stock *StockService) *RepricingService { return &RepricingService{repo: repo, store: store, product: product, stock: stock} }
 
 func (s *RepricingService) List(ctx context.Context, p repositories.RepricingListParams) ([]models.Repricing, int64, error) {
 	items, total, err := s.repo.List(ctx, p)
@@ -45,7 +46,18 @@ func (s *RepricingService) Create(ctx context.Context, body models.CreateReprici
 	return m, nil
 }
 
+// Update runs in one transaction: approved prices and the APPROVED status are written together.
 func (s *RepricingService) Update(ctx context.Context, id string, body models.UpdateRepricingRequest, tenantID string, actor models.InventoryUser) (*models.Repricing, error) {
+	var out *models.Repricing
+	err := s.stock.Atomically(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = s.update(ctx, id, body, tenantID, actor)
+		return err
+	})
+	return out, err
+}
+
+func (s *RepricingService) update(ctx context.Context, id string, body models.UpdateRepricingRequest, tenantID string, actor models.InventoryUser) (*models.Repricing, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid repricing id", err) }
 	update := bson.M{}
@@ -53,17 +65,29 @@ func (s *RepricingService) Update(ctx context.Context, id string, body models.Up
 
 	var preparedItems []models.RepricingItem
 	if body.Items != nil {
+		cur, err := s.repo.Get(ctx, oid, tenantID)
+		if err != nil { return nil, utils.NotFound("REPRICING_NOT_FOUND", "Repricing not found", err) }
 		items := make([]models.RepricingItem, 0, len(body.Items))
 		total := 0.0
 		count := 0
 		for _, it := range body.Items {
 			pid, err := primitive.ObjectIDFromHex(it.ProductID); if err != nil { return nil, utils.BadRequest("INVALID_PRODUCT_ID", "Invalid product id in items", err) }
 			// ensure product exists
-			if _, err := s.product.Get(ctx, pid, tenantID); err != nil {
-				if _, e2 := s.product.GetByID(ctx, pid); e2 != nil { return nil, utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found for repricing", err) }
+			p, err := s.product.Get(ctx, pid, tenantID)
+			if err != nil {
+				p2, e2 := s.product.GetByID(ctx, pid)
+				if e2 != nil { return nil, utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found for repricing", err) }
+				p = p2
 			}
-			items = append(items, models.RepricingItem{ ProductID: pid, ProductName: it.ProductName, ProductSKU: it.ProductSKU, Barcode: it.Barcode, Currency: it.Currency, SupplyPrice: it.SupplyPrice, RetailPrice: it.RetailPrice, Qty: it.Qty })
-			total += it.RetailPrice * it.Qty
+			// qty is the shop's quantity being repriced; default to what it has on hand
+			qty := it.Qty
+			if qty <= 0 {
+				avail, err := s.stock.Available(ctx, p.TenantID, p.ID, cur.ShopID)
+				if err != nil { return nil, err }
+				qty = avail
+			}
+			items = append(items, models.RepricingItem{ ProductID: pid, ProductName: it.ProductName, ProductSKU: it.ProductSKU, Barcode: it.Barcode, Currency: it.Currency, SupplyPrice: it.SupplyPrice, RetailPrice: it.RetailPrice, Qty: qty })
+			total += it.RetailPrice * qty
 			count++
 		}
 		preparedItems = items
@@ -76,19 +100,19 @@ func (s *RepricingService) Update(ctx context.Context, id string, body models.Up
 		cur, err := s.repo.Get(ctx, oid, tenantID)
 		if err != nil { return nil, utils.NotFound("REPRICING_NOT_FOUND", "Repricing not found", err) }
 		if body.Action == "approve" && cur.Status == "NEW" {
+			now := time.Now().UTC()
+			if err := s.claim(ctx, oid, tenantID, bson.M{"status": "APPROVED", "finished_at": now, "finished_by": actor}); err != nil { return nil, err }
 			// if items were provided in the same request, apply only those; otherwise apply current stored items
 			selected := preparedItems
 			if len(selected) == 0 { selected = cur.Items }
 			for _, it := range selected {
 				p, err := s.product.Get(ctx, it.ProductID, tenantID); if err != nil { if p2, e2 := s.product.GetByID(ctx, it.ProductID); e2 == nil { p = p2 } else { return nil, utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found for repricing", err) } }
-				if err := s.product.UpdatePrices(ctx, p.ID, p.TenantID, it.SupplyPrice, it.RetailPrice); err != nil { return nil, utils.Internal("PRODUCT_PRICE_UPDATE_FAILED", "Failed to update product prices", err) }
+				// cost prices come from the costing engine, so a repricing changes the retail price only
+				if err := s.product.UpdatePrices(ctx, p.ID, p.TenantID, -1, it.RetailPrice); err != nil { return nil, utils.Internal("PRODUCT_PRICE_UPDATE_FAILED", "Failed to update product prices", err) }
 			}
-			update["status"] = "APPROVED"
-			now := time.Now().UTC(); update["finished_at"] = now; update["finished_by"] = actor
 		}
 		if body.Action == "reject" && cur.Status == "NEW" {
-			update["status"] = "REJECTED"
-			now := time.Now().UTC(); update["finished_at"] = now; update["finished_by"] = actor
+			if err := s.claim(ctx, oid, tenantID, bson.M{"status": "REJECTED", "finished_at": time.Now().UTC(), "finished_by": actor}); err != nil { return nil, err }
 		}
 	}
 
@@ -97,6 +121,15 @@ func (s *RepricingService) Update(ctx context.Context, id string, body models.Up
 	return m, nil
 }
 
+// claim finishes a NEW repricing with the given status fields before any price moves, so that two concurrent
+// approvals cannot both apply it.
+func (s *RepricingService) claim(ctx context.Context, id primitive.ObjectID, tenantID string, set bson.M) error {
+	ok, err := s.repo.UpdateIf(ctx, id, tenantID, bson.M{"status": "NEW"}, set)
+	if err != nil { return utils.Internal("REPRICING_UPDATE_FAILED", "Unable to update repricing", err) }
+	if !ok { return utils.Conflict("REPRICING_FINISHED", "The repricing was already approved or rejected", nil) }
+	return nil
+}
+
 func (s *RepricingService) Delete(ctx context.Context, id string, tenantID string) error {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil { return utils.BadRequest("INVALID_ID", "Invalid repricing id", err) }
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReservationService keeps the stock promised to pending documents. A store's available quantity is what it has on
+// hand less what live reservations hold; a document may only reserve, or take, what is available to it. Reservations
+// lapse after the tenant's TTL.
+type ReservationService struct {
+	repo     *repositories.ReservationRepository
+	balances *repositories.StockRepository
+	products *repositories.ProductRepository
+	tenants  *repositories.TenantRepository
+	units    *MeasureUnitService
+}
+
+func NewReservationService(repo *repositories.ReservationRepository, balances *repositories.StockRepository, products *repositories.ProductRepository, tenants *repositories.TenantRepository, units *MeasureUnitService) *ReservationService {
+	return &ReservationService{repo: repo, balances: balances, products: products, tenants: tenants, units: units}
+}
+
+func (s *ReservationService) List(ctx context.Context, tenantID string, f models.ReservationFilterRequest) ([]models.StockReservation, int64, error) {
+	p := repositories.ReservationListParams{TenantID: tenantID, ShopID: f.ShopID, SourceType: f.SourceType, SourceID: f.SourceID, Page: int64(ifZero(f.Page, 1)), Limit: int64(ifZero(f.Limit, 20))}
+	if f.ProductID != "" {
+		pid, err := primitive.ObjectIDFromHex(f.ProductID)
+		if err != nil { return nil, 0, utils.BadRequest("INVALID_ID", "Invalid product id", err) }
+		p.ProductID = &pid
+	}
+	items, total, err := s.repo.List(ctx, p)
+	if err != nil { return nil, 0, utils.Internal("RESERVATION_LIST_FAILED", "Unable to list reservations", err) }
+	return items, total, nil
+}
+
+// Create reserves stock for a document that does not reserve by itself, replacing what it held before.
+func (s *ReservationService) Create(ctx context.Context, tenantID string, body models.CreateReservationRequest, actor models.InventoryUser) ([]models.StockReservation, error) {
+	if strings.TrimSpace(body.ShopID) == "" { return nil, utils.BadRequest("STORE_REQUIRED", "Store is required", nil) }
+	if body.SourceType != models.ReservationSourceServiceOrder && body.SourceType != models.ReservationSourceManual {
+		return nil, utils.BadRequest("VALIDATION_ERROR", "source_type must be service_order or manual", nil)
+	}
+	if strings.TrimSpace(body.SourceID) == "" { return nil, utils.BadRequest("VALIDATION_ERROR", "source_id is required", nil) }
+	lines := make([]models.ReservationLine, 0, len(body.Items))
+	for _, it := range body.Items {
+		pid, err := primitive.ObjectIDFromHex(it.ProductID)
+		if err != nil { return nil, utils.BadRequest("INVALID_PRODUCT_ID", "Invalid product id in items", err) }
+		if it.Qty <= 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Quantity must be greater than 0", nil) }
+		p, err := s.products.Get(ctx, pid, tenantID)
+		if err != nil { return nil, utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found", err) }
+		q, err := s.units.convert(ctx, p, it.Unit, it.Qty)
+		if err != nil { return nil, err }
+		lines = append(lines, models.ReservationLine{ProductID: pid, Qty: q.qty})
+	}
+	src := models.StockSource{Type: body.SourceType, ID: body.SourceID, Actor: actor}
+	if err := s.reserve(ctx, tenantID, body.ShopID, src, strings.TrimSpace(body.SourceName), lines); err != nil { return nil, err }
+	items, _, err := s.repo.List(ctx, repositories.ReservationListParams{TenantID: tenantID, SourceType: body.SourceType, SourceID: body.SourceID, Limit: 200})
+	if err != nil { return nil, utils.Internal("RESERVATION_LIST_FAILED", "Unable to list reservations", err) }
+	return items, nil
+}
+
+func (s *ReservationService) Delete(ctx context.Context, tenantID, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil { return utils.BadRequest("INVALID_ID", "Invalid reservation id", err) }
+	if _, err := s.repo.Get(ctx, oid, tenantID); err != nil { return utils.NotFound("RESERVATION_NOT_FOUND", "Reservation not found", err) }
+	if err := s.repo.Delete(ctx, oid, tenantID); err != nil { return utils.Internal("RESERVATION_DELETE_FAILED", "Unable to delete reservation", err) }
+	return nil
+}
+
+// Release drops everything a document holds.
+func (s *ReservationService) Release(ctx context.Context, tenantID, sourceType, sourceID string) error {
+	if err := s.repo.Release(ctx, tenantID, sourceType, sourceID); err != nil { return utils.Internal("RESERVATION_RELEASE_FAILED", "Unable to release reservations", err) }
+	return nil
+}
+
+// reserve replaces what src holds in the store with lines. Each product must be available to src in full: on hand
+// less what other documents hold. The product's balance is locked while its free quantity is checked and the
+// reservation written, so two documents cannot both reserve the same last unit.
+func (s *ReservationService) reserve(ctx context.Context, tenantID, shopID string, src models.StockSource, name string, lines []models.ReservationLine) error {
+	qty := map[primitive.ObjectID]float64{}
+	order := []primitive.ObjectID{}
+	for _, l := range lines {
+		if l.Qty <= 0 { continue }
+		if _, ok := qty[l.ProductID]; !ok { order = append(order, l.ProductID) }
+		qty[l.ProductID] = models.RoundQty(qty[l.ProductID] + l.Qty)
+	}
+	now := time.Now().UTC()
+	expires := now.Add(s.ttl(ctx, tenantID))
+	items := make([]models.StockReservation, 0, len(order))
+	holder := primitive.NewObjectID().Hex()
+	for _, pid := range order {
+		if err := s.lock(ctx, tenantID, pid, shopID, holder); err != nil { return err }
+		defer func(pid primitive.ObjectID) { _ = s.balances.Unlock(ctx, tenantID, pid, shopID, holder) }(pid)
+		free, err := s.free(ctx, tenantID, pid, shopID, src)
+		if err != nil { return err }
+		if qty[pid] > free {
+			pname := pid.Hex()
+			if p, err := s.products.Get(ctx, pid, tenantID); err == nil { pname = p.Name }
+			return utils.Conflict("INSUFFICIENT_STOCK", "Only "+strconv.FormatFloat(free, 'f', -1, 64)+" of "+pname+" is available", nil)
+		}
+		items = append(items, models.StockReservation{TenantID: tenantID, ProductID: pid, ShopID: shopID, Qty: qty[pid], SourceType: src.Type, SourceID: src.ID, SourceName: name, CreatedBy: src.Actor, CreatedAt: now, ExpiresAt: expires})
+	}
+	if err := s.repo.Replace(ctx, tenantID, src.Type, src.ID, items); err != nil { return utils.Internal("RESERVATION_FAILED", "Unable to reserve stock", err) }
+	return nil
+}
+
+// reserveLockTTL bounds how long a crashed request can keep a balance locked.
+const reserveLockTTL = 10 * time.Second
+
+// lock waits, for up to a few seconds, until holder has the product's balance in the store to itself. A store without
+// a balance for the product has nothing to reserve, and the availability check refuses it unlocked.
+func (s *ReservationService) lock(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID, holder string) error {
+	for i := 0; i < 100; i++ {
+		ok, err := s.balances.Lock(ctx, tenantID, productID, shopID, holder, time.Now().UTC().Add(reserveLockTTL))
+		if err != nil { return utils.Internal("RESERVATION_FAILED", "Unable to reserve stock", err) }
+		if ok { return nil }
+		if onHand, err := s.balances.Get(ctx, tenantID, productID, shopID); err == nil && onHand == 0 { return nil }
+		time.Sleep(30 * time.Millisecond)
+	}
+	return utils.Conflict("RESERVATION_BUSY", "The stock is being reserved by another request, please retry", nil)
+}
+
+// free is what a store can still give src of a product: on hand less what other documents hold, never below zero.
+func (s *ReservationService) free(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, src models.StockSource) (float64, error) {
+	onHand, err := s.balances.Get(ctx, tenantID, productID, shopID)
+	if err != nil { return 0, utils.Internal("STOCK_READ_FAILED", "Unable to read stock balance", err) }
+	reserved, err := s.repo.Reserved(ctx, tenantID, productID, shopID, src.Type, src.ID)
+	if err != nil { return 0, utils.Internal("RESERVATION_READ_FAILED", "Unable to read reservations", err) }
+	if onHand <= reserved { return 0, nil }
+	return models.RoundQty(onHand - reserved), nil
+}
+
+// ttl is how long a reservation lives: the tenant's setting, or DefaultReservationTTLHours.
+func (s *ReservationService) ttl(ctx context.Context, tenantID string) time.Duration {
+	hours := models.DefaultReservationTTLHours
+	if oid, err := primitive.ObjectIDFromHex(tenantID); err == nil {
+		if t, err := s.tenants.Get(ctx, oid); err == nil && t.Settings.ReservationTTLHours > 0 { hours = t.Settings.ReservationTTLHours }
+	}
+	return time.Duration(hours) * time.Hour
+}
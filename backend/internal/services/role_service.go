@@ -83,17 +83,25 @@ func (s *RoleService) Permissions(ctx context.Context) ([]models.PermissionGroup
 			{Key: "products.brands", Name: "Brands"},
 			{Key: "products.warehouses", Name: "Warehouses"},
 			{Key: "products.parameters", Name: "Parameters"},
+			{Key: "products.units", Name: "Units of measure"},
 			{Key: "products.import", Name: "Import"},
 			{Key: "products.orders", Name: "Orders"},
 			{Key: "products.inventory", Name: "Inventory"},
 			{Key: "products.transfer", Name: "Transfer"},
 			{Key: "products.repricing", Name: "Repricing"},
 			{Key: "products.writeoff", Name: "Write-Off"},
+			{Key: "products.kits", Name: "Kit assembly"},
+			{Key: "products.consignment", Name: "Consignment"},
+			{Key: "products.cores", Name: "Core charges"},
+			{Key: "products.putaway", Name: "Putaway and picking"},
+			{Key: "products.cyclecount", Name: "Cycle counting"},
 			{Key: "products.suppliers", Name: "Suppliers"},
 		}},
 		{Key: "sales", Name: "Sales", Items: []models.PermissionItem{
 			{Key: "sales.new", Name: "New Sale"},
 			{Key: "sales.all", Name: "All Sales"},
+			{Key: "sales.prices", Name: "Price overrides"},
+			{Key: "sales.returns", Name: "Returns"},
 			{Key: "sales.cashbox.shifts", Name: "Cashbox shifts"},
 			{Key: "sales.cashbox.operations", Name: "Cashbox operations"},
 		}},
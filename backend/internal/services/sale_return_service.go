@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type SaleReturnService struct {
+	repo      *repositories.SaleReturnRepository
+	sales     *repositories.SaleRepository
+	shifts    *repositories.CashShiftRepository
+	writeOffs *repositories.WriteOffRepository
+	cashbox   *CashboxService
+	debts     *CustomerDebtService
+	cores     *CoreStockService
+	stock     *StockService
+}
+
+func NewSaleReturnService(repo *repositories.SaleReturnRepository, sales *repositories.SaleRepository, shifts *repositories.CashShiftRepository, writeOffs *repositories.WriteOffRepository, cashbox *CashboxService, debts *CustomerDebtService, cores *CoreStockService, stock *StockService) *SaleReturnService {
+	return &SaleReturnService{repo: repo, sales: sales, shifts: shifts, writeOffs: writeOffs, cashbox: cashbox, debts: debts, cores: cores, stock: stock}
+}
+
+func (s *SaleReturnService) List(ctx context.Context, f models.SaleReturnFilterRequest, tenantID string) ([]models.SaleReturn, int64, error) {
+	var fromPtr, toPtr *time.Time
+	if strings.TrimSpace(f.DateFrom) != "" { if t, err := time.Parse(time.RFC3339, f.DateFrom); err == nil { fromPtr = &t } }
+	if strings.TrimSpace(f.DateTo) != "" { if t, err := time.Parse(time.RFC3339, f.DateTo); err == nil { toPtr = &t } }
+	items, total, err := s.repo.List(ctx, repositories.SaleReturnListParams{
+		TenantID: tenantID, Page: int64(ifZero(f.Page, 1)), Limit: int64(ifZero(f.Limit, 20)),
+		SaleID: f.SaleID, ShopID: f.ShopID, ShiftID: f.ShiftID, Reason: f.Reason, DateFrom: fromPtr, DateTo: toPtr,
+	})
+	if err != nil { return nil, 0, utils.Internal("SALE_RETURN_LIST_FAILED", "Unable to list returns", err) }
+	return items, total, nil
+}
+
+func (s *SaleReturnService) Get(ctx context.Context, id string, tenantID string) (*models.SaleReturn, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid return id", err) }
+	m, err := s.repo.Get(ctx, oid, tenantID)
+	if err != nil { return nil, utils.NotFound("SALE_RETURN_NOT_FOUND", "Return not found", err) }
+	return m, nil
+}
+
+// ListBySale returns all returns made against a sale.
+func (s *SaleReturnService) ListBySale(ctx context.Context, saleID string, tenantID string) ([]models.SaleReturn, error) {
+	if _, err := primitive.ObjectIDFromHex(saleID); err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid sale id", err) }
+	items, err := s.repo.ListBySale(ctx, tenantID, saleID)
+	if err != nil { return nil, utils.Internal("SALE_RETURN_LIST_FAILED", "Unable to list returns", err) }
+	return items, nil
+}
+
+// Create takes goods back against a completed sale and refunds them in the cashier's open shift. A line can be
+// returned in several goes up to its sold quantity.
+func (s *SaleReturnService) Create(ctx context.Context, saleID string, body models.CreateSaleReturnRequest, tenantID string, actor models.InventoryUser) (*models.SaleReturn, error) {
+	if len(body.Items) == 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Items are required", nil) }
+	reason := strings.TrimSpace(body.Reason)
+	if reason == "" { return nil, utils.BadRequest("VALIDATION_ERROR", "Reason is required", nil) }
+	if strings.EqualFold(reason, models.SaleReturnReasonDefective) { reason = models.SaleReturnReasonDefective }
+	method := strings.ToLower(strings.TrimSpace(body.RefundMethod))
+	if method != "" && method != models.SalePaymentCash && method != models.SalePaymentCard && method != models.SalePaymentTransfer { return nil, utils.BadRequest("VALIDATION_ERROR", "Refund method must be cash, card or transfer", nil) }
+	oid, err := primitive.ObjectIDFromHex(saleID)
+	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid sale id", err) }
+
+	var out *models.SaleReturn
+	err = s.stock.Atomically(ctx, func(ctx context.Context) error {
+		sale, err := s.sales.Get(ctx, oid, tenantID)
+		if err != nil { return utils.NotFound("SALE_NOT_FOUND", "Sale not found", err) }
+		if sale.Status != "COMPLETED" { return utils.BadRequest("SALE_NOT_COMPLETED", "Only completed sales can be returned", nil) }
+		shift, err := s.shifts.GetOpen(ctx, tenantID, sale.ShopID, actor.ID)
+		if err == mongo.ErrNoDocuments { return utils.BadRequest("SHIFT_NOT_OPEN", "Open a cash shift in the sale's store before refunding", nil) }
+		if err != nil { return utils.Internal("CASH_SHIFT_READ_FAILED", "Unable to read cash shift", err) }
+
+		saleItems := make([]models.SaleItem, len(sale.Items))
+		copy(saleItems, sale.Items)
+		// the id is given up front: the stock movements of the return are logged under it before it is stored
+		m := &models.SaleReturn{ ID: primitive.NewObjectID(), TenantID: tenantID, ExternalID: generateExternalID(), SaleID: sale.ID.Hex(), SaleReceiptNumber: sale.ReceiptNumber, Reason: reason, Comment: body.Comment, ShopID: sale.ShopID, ShopName: sale.ShopName, ShiftID: shift.ID.Hex(), ShiftNumber: shift.Number, CustomerID: sale.CustomerID, Customer: sale.Customer, CreatedBy: actor }
+		for _, in := range body.Items {
+			idx, err := returnLine(saleItems, in)
+			if err != nil { return err }
+			it := &saleItems[idx]
+			if in.Qty <= 0 { return utils.BadRequest("VALIDATION_ERROR", "Quantity must be greater than 0", nil) }
+			if in.Qty > it.Qty-it.ReturnedQty+1e-9 { return utils.BadRequest("RETURN_QTY_EXCEEDS_SOLD", "Cannot return more "+it.ProductName+" than was sold and not yet returned", nil) }
+			serials, err := cleanSerials(in.Serials)
+			if err != nil { return err }
+			if !hasSerials(it.Serials, serials) { return utils.BadRequest("SERIAL_NOT_SOLD", "Returned serial numbers were not sold on this line of "+it.ProductName, nil) }
+			if len(it.Serials) > 0 && float64(len(serials)) != in.Qty { return utils.BadRequest("SERIALS_REQUIRED", "Give the serial number of every returned unit of "+it.ProductName, nil) }
+			it.ReturnedQty += in.Qty
+			unitPrice := 0.0
+			if it.Qty > 0 { unitPrice = it.Total / it.Qty }
+			line := models.SaleReturnItem{ Line: idx, ProductID: it.ProductID, VariantID: it.VariantID, ProductName: it.ProductName, ProductSKU: it.ProductSKU, ProductType: it.ProductType, Qty: in.Qty, Unit: it.Unit, UnitPrice: roundMoney(unitPrice), Amount: roundMoney(unitPrice * in.Qty), UnitCost: it.UnitCost, CostTotal: roundMoney(it.UnitCost * in.Qty), Serials: serials }
+			m.Items = append(m.Items, line)
+			m.Total += line.Amount
+			m.CostTotal += line.CostTotal
+		}
+		m.Total = roundMoney(m.Total)
+		m.CostTotal = roundMoney(m.CostTotal)
+
+		previous, err := s.repo.ListBySale(ctx, tenantID, m.SaleID)
+		if err != nil { return utils.Internal("SALE_RETURN_LIST_FAILED", "Unable to read previous returns", err) }
+		// the unpaid debt of a credit sale is settled first; only the rest is paid back
+		outstanding, err := s.debts.Outstanding(ctx, tenantID, m.SaleID)
+		if err != nil { return err }
+		m.CreditAmount = roundMoney(math.Min(m.Total, outstanding))
+		refundable := roundMoney(sale.PaidAmount - sale.RefundedAmount)
+		refund := math.Max(0, math.Min(m.Total-m.CreditAmount, refundable))
+		m.Refunds = refundSplit(sale, previous, method, refund)
+		m.RefundTotal = roundMoney(refund)
+
+		for _, r := range m.Refunds {
+			if r.Method != models.SalePaymentCash { continue }
+			rep, err := s.cashbox.report(ctx, shift, "X", nil)
+			if err != nil { return err }
+			if r.Amount > rep.Methods[0].Expected+0.005 { return utils.BadRequest("CASH_INSUFFICIENT", "Not enough cash in the drawer for the refund", nil) }
+		}
+		if err := s.claim(ctx, sale, saleItems, m); err != nil { return err }
+
+		if reason == models.SaleReturnReasonDefective {
+			if err := s.writeOffDefective(ctx, m, saleItems, actor); err != nil { return err }
+		} else {
+			if err := s.restock(ctx, m, saleItems, actor); err != nil { return err }
+			m.Restocked = true
+		}
+		if err := s.receiveCores(ctx, m); err != nil { return err }
+
+		ok, err := s.shifts.AttachReturn(ctx, shift.ID, tenantID, m.RefundTotal)
+		if err != nil { return utils.Internal("CASH_SHIFT_UPDATE_FAILED", "Unable to attach return to cash shift", err) }
+		if !ok { return utils.BadRequest("SHIFT_NOT_OPEN", "Open a cash shift in the sale's store before refunding", nil) }
+
+		out, err = s.repo.Create(ctx, m)
+		if err != nil { return utils.Internal("SALE_RETURN_CREATE_FAILED", "Unable to create return", err) }
+		return s.debts.Credit(ctx, tenantID, m.SaleID, m.CreditAmount, out.ID.Hex(), actor)
+	})
+	return out, err
+}
+
+// claim writes the returned quantities and amounts to the sale, before any goods move, only while the sale still
+// holds what was read. Of two concurrent returns against the same sale only one gets through; the other fails with
+// SALE_CHANGED instead of overwriting the first one's quantities.
+func (s *SaleReturnService) claim(ctx context.Context, sale *models.Sale, saleItems []models.SaleItem, m *models.SaleReturn) error {
+	status := "returned"
+	cores, hasCores := 0.0, false
+	for _, it := range saleItems {
+		if it.ReturnedQty+1e-9 < it.Qty { status = "partially_returned" }
+		if it.ProductType == models.SaleItemCore { cores, hasCores = cores+it.Qty-it.ReturnedQty, true }
+	}
+	update := bson.M{
+		"items": saleItems,
+		"return_status": status,
+		"returned_amount": roundMoney(sale.ReturnedAmount + m.Total),
+		"refunded_amount": roundMoney(sale.RefundedAmount + m.RefundTotal),
+	}
+	if hasCores { update["cores_outstanding"] = models.RoundQty(cores) }
+	// sales recorded before returns existed carry no returned fields; those read as zero
+	asRead := func(v float64) interface{} { if v == 0 { return bson.M{"$in": bson.A{0, nil}} }; return v }
+	expect := bson.M{"status": "COMPLETED", "returned_amount": asRead(sale.ReturnedAmount), "refunded_amount": asRead(sale.RefundedAmount)}
+	for _, it := range m.Items { expect["items."+strconv.Itoa(it.Line)+".returned_qty"] = asRead(sale.Items[it.Line].ReturnedQty) }
+	ok, err := s.sales.UpdateIf(ctx, sale.ID, sale.TenantID, expect, update)
+	if err != nil { return utils.Internal("SALE_UPDATE_FAILED", "Unable to update returned quantities", err) }
+	if !ok { return utils.Conflict("SALE_CHANGED", "The sale was changed by another request; reload it and try again", nil) }
+	return nil
+}
+
+// restock puts the returned goods back into the sale's store at the cost they were sold at. A virtual SET returns its
+// components and an assembled one itself; a SERVICE or a core charge returns nothing.
+// Units go back into the lots they were picked from, the last picked first.
+func (s *SaleReturnService) restock(ctx context.Context, m *models.SaleReturn, saleItems []models.SaleItem, actor models.InventoryUser) error {
+	// saleItems already count this return; returned tracks what earlier returns gave back per line
+	returned := map[int]float64{}
+	for _, it := range m.Items { returned[it.Line] -= it.Qty }
+	for line, q := range returned { returned[line] = saleItems[line].ReturnedQty + q }
+	for _, it := range m.Items {
+		sold := saleItems[it.Line]
+		before := returned[it.Line]
+		returned[it.Line] += it.Qty
+		switch {
+		case sold.ProductType == models.ProductKindService || sold.ProductType == models.SaleItemCore:
+		case sold.ProductType == models.ProductKindSet && !sold.Assembled:
+			for _, c := range sold.Components {
+				per := c.Qty / sold.Qty
+				if err := s.putBack(ctx, m, c.ProductID, c.Lots, models.RoundQty(per*before), models.RoundQty(per*it.Qty), c.UnitCost, nil, actor); err != nil { return err }
+			}
+		default:
+			if err := s.putBack(ctx, m, it.ProductID, sold.Lots, before, it.Qty, it.UnitCost, it.Serials, actor); err != nil { return err }
+		}
+	}
+	return nil
+}
+
+// putBack restocks qty units of a product. Walking the sale's lot picks from the last one, it skips the skip units
+// earlier returns already put back and returns the rest to their lots; units sold from stock without a lot go back
+// without one. The serials of a tracked product are handed out over the lots in order.
+func (s *SaleReturnService) putBack(ctx context.Context, m *models.SaleReturn, productID primitive.ObjectID, picks []models.StockLotPick, skip, qty float64, unitCost float64, serials []string, actor models.InventoryUser) error {
+	for i := len(picks) - 1; i >= 0 && qty > 0; i-- {
+		n := picks[i].Qty
+		if skip >= n { skip = models.RoundQty(skip - n); continue }
+		n = models.RoundQty(n - skip)
+		skip = 0
+		if n > qty { n = qty }
+		src := models.StockSource{ Type: models.StockSourceSaleReturn, ID: m.ID.Hex(), Origin: m.SaleID, Actor: actor, Lot: &models.StockLotRef{ ID: picks[i].LotID }, Party: m.CustomerID }
+		src.Serials, serials = splitSerials(serials, int(n))
+		if err := s.stock.Adjust(ctx, m.TenantID, productID, m.ShopID, n, unitCost, src); err != nil { return err }
+		qty = models.RoundQty(qty - n)
+	}
+	if qty <= 0 { return nil }
+	src := models.StockSource{ Type: models.StockSourceSaleReturn, ID: m.ID.Hex(), Origin: m.SaleID, Actor: actor, Serials: serials, Party: m.CustomerID }
+	return s.stock.Adjust(ctx, m.TenantID, productID, m.ShopID, qty, unitCost, src)
+}
+
+// receiveCores takes the dirty cores of the returned core charge lines into the store's core inventory. A part
+// returned unused in the same return brings its core back inside it, so as many of its core charges are refunded
+// without a core coming in.
+func (s *SaleReturnService) receiveCores(ctx context.Context, m *models.SaleReturn) error {
+	unused := map[primitive.ObjectID]float64{}
+	for _, it := range m.Items {
+		if it.ProductType != models.SaleItemCore && it.ProductType != models.ProductKindService { unused[it.ProductID] += it.Qty }
+	}
+	for i := range m.Items {
+		it := &m.Items[i]
+		if it.ProductType != models.SaleItemCore { continue }
+		n := it.Qty
+		if u := unused[it.ProductID]; u > 0 {
+			skip := math.Min(u, n)
+			unused[it.ProductID] = models.RoundQty(u - skip)
+			n = models.RoundQty(n - skip)
+		}
+		if n <= 0 { continue }
+		if err := s.cores.receive(ctx, m.TenantID, m.ShopID, it.ProductID, n); err != nil { return err }
+		it.CoresReceived = n
+	}
+	return nil
+}
+
+// writeOffDefective records defective returned goods as an approved write-off instead of putting them back on sale.
+func (s *SaleReturnService) writeOffDefective(ctx context.Context, m *models.SaleReturn, saleItems []models.SaleItem, actor models.InventoryUser) error {
+	items := []models.WriteOffItem{}
+	for _, it := range m.Items {
+		sold := saleItems[it.Line]
+		switch {
+		case sold.ProductType == models.ProductKindService || sold.ProductType == models.SaleItemCore:
+		case sold.ProductType == models.ProductKindSet && !sold.Assembled:
+			for _, c := range sold.Components {
+				items = append(items, models.WriteOffItem{ ProductID: c.ProductID, ProductName: c.ProductName, Qty: c.Qty / sold.Qty * it.Qty, Unit: "pcs", SupplyPrice: c.UnitCost, UnitCost: c.UnitCost })
+			}
+		default:
+			items = append(items, models.WriteOffItem{ ProductID: it.ProductID, ProductName: it.ProductName, ProductSKU: it.ProductSKU, Barcode: sold.Barcode, Qty: it.Qty, Unit: it.Unit, SupplyPrice: it.UnitCost, RetailPrice: it.UnitPrice, UnitCost: it.UnitCost, Serials: it.Serials })
+			// the units never come back into stock, so their serials go from sold straight to written off
+			src := models.StockSource{ Type: models.StockSourceSaleReturn, ID: m.ID.Hex(), Origin: m.SaleID, Actor: actor, Party: m.CustomerID }
+			if err := s.stock.MoveSerials(ctx, m.TenantID, it.ProductID, m.ShopID, it.Serials, models.SerialSold, models.SerialWrittenOff, src); err != nil { return err }
+		}
+	}
+	if len(items) == 0 { return nil }
+	now := time.Now().UTC()
+	wo := &models.WriteOff{ TenantID: m.TenantID, ExternalID: generateExternalID(), Name: "Defective return of sale " + strconv.FormatInt(m.SaleReceiptNumber, 10), ShopID: m.ShopID, ShopName: m.ShopName, ReasonName: models.SaleReturnReasonDefective, Status: "APPROVED", CreatedBy: actor, FinishedBy: actor, FinishedAt: &now, Items: items }
+	// the goods are written off at the cost they were sold at
+	for i, it := range items {
+		wo.Items[i].CostTotal = roundMoney(it.Qty * it.UnitCost)
+		wo.TotalQty += it.Qty
+		wo.TotalSupplyPrice += it.Qty * it.SupplyPrice
+		wo.TotalRetailPrice += it.Qty * it.RetailPrice
+		wo.TotalCost += wo.Items[i].CostTotal
+	}
+	created, err := s.writeOffs.Create(ctx, wo)
+	if err != nil { return utils.Internal("WRITEOFF_CREATE_FAILED", "Unable to record defective return", err) }
+	m.WriteOffID = created.ID.Hex()
+	return nil
+}
+
+// returnLine finds the sale line a returned item refers to: by index, or else the first line of the product and
+// variant that still has quantity left to return, its core charge line when the item returns a core.
+func returnLine(items []models.SaleItem, in models.SaleReturnItemInput) (int, error) {
+	if in.Line != nil {
+		if *in.Line < 0 || *in.Line >= len(items) { return 0, utils.BadRequest("VALIDATION_ERROR", "Sale line out of range", nil) }
+		return *in.Line, nil
+	}
+	pid, err := primitive.ObjectIDFromHex(in.ProductID)
+	if err != nil { return 0, utils.BadRequest("INVALID_PRODUCT_ID", "Invalid product id in items", err) }
+	vid := primitive.NilObjectID
+	if strings.TrimSpace(in.VariantID) != "" {
+		if vid, err = primitive.ObjectIDFromHex(in.VariantID); err != nil { return 0, utils.BadRequest("INVALID_VARIANT_ID", "Invalid variant id in items", err) }
+	}
+	found := -1
+	for i, it := range items {
+		if it.ProductID != pid || it.VariantID != vid || (it.ProductType == models.SaleItemCore) != in.Core { continue }
+		if found < 0 { found = i }
+		if it.ReturnedQty < it.Qty { return i, nil }
+	}
+	if found < 0 { return 0, utils.BadRequest("RETURN_ITEM_NOT_SOLD", "Product was not sold in this sale", nil) }
+	return found, nil
+}
+
+// refundSplit pays the refund back through the chosen method, or else through the sale's own payments in order,
+// each up to what it brought in (cash net of change) less what earlier returns already refunded through it.
+func refundSplit(sale *models.Sale, previous []models.SaleReturn, method string, amount float64) []models.SalePayment {
+	out := []models.SalePayment{}
+	if amount <= 0 { return out }
+	if method != "" { return append(out, models.SalePayment{ Method: method, Amount: roundMoney(amount) }) }
+	left := map[string]float64{}
+	order := []string{}
+	for _, p := range sale.Payments {
+		if _, ok := left[p.Method]; !ok { order = append(order, p.Method) }
+		left[p.Method] += p.Amount
+	}
+	left[models.SalePaymentCash] -= sale.ChangeAmount
+	for _, r := range previous {
+		for _, p := range r.Refunds { left[p.Method] -= p.Amount }
+	}
+	for _, m := range order {
+		if amount <= 0.005 { break }
+		take := left[m]
+		if take <= 0 { continue }
+		if take > amount { take = amount }
+		out = append(out, models.SalePayment{ Method: m, Amount: roundMoney(take) })
+		amount -= take
+	}
+	// refunds made through other methods may have used up the original ones
+	if amount > 0.005 {
+		if len(out) == 0 {
+			m := models.SalePaymentCash
+			if len(order) > 0 { m = order[0] }
+			return append(out, models.SalePayment{ Method: m, Amount: roundMoney(amount) })
+		}
+		out[0].Amount = roundMoney(out[0].Amount + amount)
+	}
+	return out
+}
@@ -0,0 +1,459 @@
+package services
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type SaleService struct {
+	repo      *repositories.SaleRepository
+	products  *repositories.ProductRepository
+	customers *repositories.CustomerRepository
+	stores    *repositories.StoreRepository
+	shifts    *repositories.CashShiftRepository
+	debts     *CustomerDebtService
+	stock     *StockService
+}
+
+func NewSaleService(repo *repositories.SaleRepository, products *repositories.ProductRepository, customers *repositories.CustomerRepository, stores *repositories.StoreRepository, shifts *repositories.CashShiftRepository, debts *CustomerDebtService, stock *StockService) *SaleService {
+	return &SaleService{repo: repo, products: products, customers: customers, stores: stores, shifts: shifts, debts: debts, stock: stock}
+}
+
+func (s *SaleService) List(ctx context.Context, f models.SaleFilterRequest, tenantID string) ([]models.Sale, int64, error) {
+	var fromPtr, toPtr *time.Time
+	if strings.TrimSpace(f.DateFrom) != "" { if t, err := time.Parse(time.RFC3339, f.DateFrom); err == nil { fromPtr = &t } }
+	if strings.TrimSpace(f.DateTo) != "" { if t, err := time.Parse(time.RFC3339, f.DateTo); err == nil { toPtr = &t } }
+	items, total, err := s.repo.List(ctx, repositories.SaleListParams{
+		TenantID: tenantID,
+		Page: int64(ifZero(f.Page, 1)),
+		Limit: int64(ifZero(f.Limit, 20)),
+		Search: f.Search,
+		Status: f.StatusID,
+		CustomerID: f.CustomerID,
+		ShopID: f.ShopID,
+		CreatedBy: f.CreatedBy,
+		PaymentStatus: f.PaymentStatus,
+		Type: f.Type,
+		SortBy: ifEmpty(f.SortBy, "created_at"),
+		SortOrder: sortOrderValue(f.SortOrder),
+		DateFrom: fromPtr,
+		DateTo: toPtr,
+	})
+	if err != nil { return nil, 0, utils.Internal("SALE_LIST_FAILED", "Unable to list sales", err) }
+	return items, total, nil
+}
+
+func (s *SaleService) Get(ctx context.Context, id string, tenantID string) (*models.Sale, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid sale id", err) }
+	m, err := s.repo.Get(ctx, oid, tenantID)
+	if err != nil { return nil, utils.NotFound("SALE_NOT_FOUND", "Sale not found", err) }
+	return m, nil
+}
+
+// Receipt returns the receipt of a completed sale.
+func (s *SaleService) Receipt(ctx context.Context, id string, tenantID string) (*models.SaleReceipt, error) {
+	m, err := s.Get(ctx, id, tenantID)
+	if err != nil { return nil, err }
+	if m.Receipt == nil { return nil, utils.NotFound("RECEIPT_NOT_FOUND", "Sale has no receipt until it is completed", nil) }
+	return m.Receipt, nil
+}
+
+func (s *SaleService) Create(ctx context.Context, body models.CreateSaleRequest, tenantID string, actor models.InventoryUser) (*models.Sale, error) {
+	if strings.TrimSpace(body.ShopID) == "" { return nil, utils.BadRequest("STORE_REQUIRED", "Store is required", nil) }
+	m := &models.Sale{ TenantID: tenantID, ExternalID: generateExternalID(), Type: "sale", Status: "NEW", ShopID: body.ShopID, Comment: body.Comment, CreatedBy: actor }
+	if st, err := s.stores.GetByIDHex(ctx, body.ShopID, tenantID); err == nil { m.ShopName = st.Title }
+	if strings.TrimSpace(body.CustomerID) != "" {
+		c, err := s.customer(ctx, body.CustomerID, tenantID)
+		if err != nil { return nil, err }
+		m.CustomerID = c.ID
+		m.Customer = c
+	}
+	items, err := s.buildItems(ctx, body.Items, tenantID)
+	if err != nil { return nil, err }
+	payments, err := validPayments(body.Payments)
+	if err != nil { return nil, err }
+	m.Items = items
+	m.Payments = payments
+	computeSaleTotals(m)
+
+	// a sale left NEW holds its goods in the store until it is completed or cancelled
+	var out *models.Sale
+	err = s.stock.Atomically(ctx, func(ctx context.Context) error {
+		created, err := s.repo.Create(ctx, m)
+		if err != nil { return utils.Internal("SALE_CREATE_FAILED", "Unable to create sale", err) }
+		if !body.Complete {
+			out = created
+			return s.reserve(ctx, created, actor)
+		}
+		out, err = s.complete(ctx, created, body.Installments, actor)
+		return err
+	})
+	return out, err
+}
+
+// Update edits a NEW sale and runs the complete/cancel actions. It runs in one transaction, so completing commits
+// the stock deductions together with the COMPLETED status and the receipt.
+func (s *SaleService) Update(ctx context.Context, id string, body models.UpdateSaleRequest, tenantID string, actor models.InventoryUser) (*models.Sale, error) {
+	var out *models.Sale
+	err := s.stock.Atomically(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = s.update(ctx, id, body, tenantID, actor)
+		return err
+	})
+	return out, err
+}
+
+func (s *SaleService) update(ctx context.Context, id string, body models.UpdateSaleRequest, tenantID string, actor models.InventoryUser) (*models.Sale, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid sale id", err) }
+	cur, err := s.repo.Get(ctx, oid, tenantID)
+	if err != nil { return nil, utils.NotFound("SALE_NOT_FOUND", "Sale not found", err) }
+	if cur.Status != "NEW" {
+		// repeated complete/cancel calls are no-ops
+		if (body.Action == "complete" && cur.Status == "COMPLETED") || (body.Action == "cancel" && cur.Status == "CANCELLED") { return cur, nil }
+		return nil, utils.BadRequest("SALE_LOCKED", "Only new sales can be changed", nil)
+	}
+
+	update := bson.M{}
+	if body.Comment != "" { update["comment"] = body.Comment; cur.Comment = body.Comment }
+	if body.CustomerID != nil {
+		cur.CustomerID, cur.Customer = "", models.SaleCustomer{}
+		if strings.TrimSpace(*body.CustomerID) != "" {
+			c, err := s.customer(ctx, *body.CustomerID, tenantID)
+			if err != nil { return nil, err }
+			cur.CustomerID, cur.Customer = c.ID, c
+		}
+		update["customer_id"] = cur.CustomerID
+		update["customer"] = cur.Customer
+	}
+	if body.Items != nil {
+		items, err := s.buildItems(ctx, body.Items, tenantID)
+		if err != nil { return nil, err }
+		cur.Items = items
+		update["items"] = items
+	}
+	if body.Payments != nil {
+		payments, err := validPayments(body.Payments)
+		if err != nil { return nil, err }
+		cur.Payments = payments
+		update["payments"] = payments
+	}
+	computeSaleTotals(cur)
+	update["subtotal"] = cur.Subtotal
+	update["discount_total"] = cur.DiscountTotal
+	update["total"] = cur.Total
+	update["paid_amount"] = cur.PaidAmount
+	update["change_amount"] = cur.ChangeAmount
+	update["payment_status"] = cur.PaymentStatus
+
+	switch body.Action {
+	case "complete":
+		if err := s.claim(ctx, cur, update); err != nil { return nil, err }
+		return s.complete(ctx, cur, body.Installments, actor)
+	case "cancel":
+		update["status"] = "CANCELLED"
+		if err := s.claim(ctx, cur, update); err != nil { return nil, err }
+		if err := s.stock.Release(ctx, tenantID, saleSource(cur, actor)); err != nil { return nil, err }
+	default:
+		if err := s.claim(ctx, cur, update); err != nil { return nil, err }
+		if body.Items != nil { if err := s.reserve(ctx, cur, actor); err != nil { return nil, err } }
+	}
+	return s.Get(ctx, id, tenantID)
+}
+
+// claim saves update only while the sale is still NEW, so that an edit, cancel or completion racing another one
+// fails instead of acting on a sale that was completed or cancelled meanwhile.
+func (s *SaleService) claim(ctx context.Context, m *models.Sale, update bson.M) error {
+	ok, err := s.repo.UpdateIf(ctx, m.ID, m.TenantID, bson.M{"status": "NEW"}, update)
+	if err != nil { return utils.Internal("SALE_UPDATE_FAILED", "Unable to update sale", err) }
+	if !ok { return utils.Conflict("SALE_CHANGED", "The sale was completed or cancelled by another request", nil) }
+	return nil
+}
+
+func (s *SaleService) Delete(ctx context.Context, id string, tenantID string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil { return utils.BadRequest("INVALID_ID", "Invalid sale id", err) }
+	cur, err := s.repo.Get(ctx, oid, tenantID)
+	if err != nil { return utils.NotFound("SALE_NOT_FOUND", "Sale not found", err) }
+	if cur.Status == "COMPLETED" { return utils.BadRequest("SALE_LOCKED", "Completed sales cannot be deleted", nil) }
+	return s.stock.Atomically(ctx, func(ctx context.Context) error {
+		if err := s.stock.Release(ctx, tenantID, saleSource(cur, models.InventoryUser{})); err != nil { return err }
+		if err := s.repo.Delete(ctx, oid, tenantID); err != nil { return utils.Internal("SALE_DELETE_FAILED", "Unable to delete sale", err) }
+		return nil
+	})
+}
+
+// complete takes the sold goods out of the store, fixes their cost and issues the receipt within the cashier's open
+// shift. The unpaid part of a credit sale becomes the customer's debt, repaid by plan. It must run inside a
+// transaction.
+func (s *SaleService) complete(ctx context.Context, m *models.Sale, plan []models.DebtInstallmentInput, actor models.InventoryUser) (*models.Sale, error) {
+	if len(m.Items) == 0 { return nil, utils.BadRequest("SALE_EMPTY", "Sale has no items", nil) }
+	shift, err := s.shifts.GetOpen(ctx, m.TenantID, m.ShopID, actor.ID)
+	if err == mongo.ErrNoDocuments { return nil, utils.BadRequest("SHIFT_NOT_OPEN", "Open a cash shift before completing sales", nil) }
+	if err != nil { return nil, utils.Internal("CASH_SHIFT_READ_FAILED", "Unable to read cash shift", err) }
+	var paid, cash float64
+	for _, p := range m.Payments {
+		paid += p.Amount
+		if p.Method == models.SalePaymentCash { cash += p.Amount }
+	}
+	if paid+0.005 < m.Total && m.CustomerID == "" { return nil, utils.BadRequest("SALE_UNDERPAID", "Payments do not cover the total; link a customer to sell on credit", nil) }
+	if change := paid - m.Total; change > cash+0.005 { return nil, utils.BadRequest("SALE_OVERPAID", "Only cash payments can exceed the total", nil) }
+	var schedule []models.DebtInstallment
+	if credit := m.Total - paid; credit > 0.005 {
+		schedule, err = debtSchedule(plan, roundMoney(credit), time.Now().UTC())
+		if err != nil { return nil, err }
+	}
+
+	// the sale leaves NEW before any stock moves, so two concurrent completions cannot both take the goods
+	if err := s.claim(ctx, m, bson.M{"status": "COMPLETED"}); err != nil { return nil, err }
+	src := saleSource(m, actor)
+	items := make([]models.SaleItem, len(m.Items))
+	copy(items, m.Items)
+	var costTotal, cores float64
+	for i := range items {
+		it := &items[i]
+		p, err := s.product(ctx, it.ProductID, m.TenantID)
+		if err != nil { return nil, err }
+		switch {
+		case it.ProductType == models.SaleItemCore:
+			// a deposit owed back when the dirty core comes in; nothing leaves the store
+			cores += it.Qty
+		case it.ProductType == models.ProductKindService:
+			it.UnitCost = p.CostPrice
+		case p.VirtualSet():
+			it.Components = make([]models.SaleComponent, 0, len(p.SetItems))
+			unit := 0.0
+			for _, si := range p.SetItems {
+				cp, err := s.product(ctx, si.ProductID, m.TenantID)
+				if err != nil { return nil, err }
+				qty := models.RoundQty(si.Quantity * it.Qty)
+				taken, err := s.take(ctx, cp, m.ShopID, qty, cp.CostPrice, src)
+				if err != nil { return nil, err }
+				it.Components = append(it.Components, models.SaleComponent{ ProductID: cp.ID, ProductName: cp.Name, Qty: qty, UnitCost: taken.UnitCost, Lots: taken.Lots })
+				unit += si.Quantity * taken.UnitCost
+			}
+			it.UnitCost = unit
+		default:
+			cost := p.CostPrice
+			for _, v := range p.Variants {
+				if v.ID == it.VariantID && it.VariantID != primitive.NilObjectID && v.CostPrice > 0 { cost = v.CostPrice }
+			}
+			// sold units are valued by the costing engine, the catalog cost only covering stock without cost history,
+			// and picked from the lots expiring first
+			lsrc := src
+			lsrc.Serials, lsrc.Party = it.Serials, m.CustomerID
+			taken, err := s.take(ctx, p, m.ShopID, it.Qty, cost, lsrc)
+			if err != nil { return nil, err }
+			it.UnitCost, it.Lots = taken.UnitCost, taken.Lots
+			it.Assembled = it.ProductType == models.ProductKindSet
+		}
+		it.CostTotal = roundMoney(it.UnitCost * it.Qty)
+		costTotal += it.CostTotal
+	}
+	m.Items = items
+	computeSaleTotals(m)
+	if err := s.stock.Release(ctx, m.TenantID, src); err != nil { return nil, err }
+
+	ok, err := s.shifts.AttachSale(ctx, shift.ID, m.TenantID, m.Total)
+	if err != nil { return nil, utils.Internal("CASH_SHIFT_UPDATE_FAILED", "Unable to attach sale to cash shift", err) }
+	if !ok { return nil, utils.BadRequest("SHIFT_NOT_OPEN", "Open a cash shift before completing sales", nil) }
+
+	number, err := s.repo.NextReceiptNumber(ctx, m.TenantID)
+	if err != nil { return nil, utils.Internal("SALE_RECEIPT_FAILED", "Unable to issue a receipt number", err) }
+	now := time.Now().UTC()
+	receipt := &models.SaleReceipt{ Number: number, IssuedAt: now, ShopName: m.ShopName, CashierName: actor.Name, CustomerName: m.Customer.Name, Lines: make([]models.SaleReceiptLine, 0, len(items)), Subtotal: m.Subtotal, DiscountTotal: m.DiscountTotal, Total: m.Total, Payments: m.Payments, PaidAmount: m.PaidAmount, ChangeAmount: m.ChangeAmount }
+	for _, it := range items {
+		name := it.ProductName
+		if it.VariantName != "" { name += " (" + it.VariantName + ")" }
+		line := models.SaleReceiptLine{ Name: name, Qty: it.Qty, Unit: it.Unit, UnitPrice: it.UnitPrice, Discount: it.DiscountAmount, Total: it.Total, Serials: it.Serials }
+		// the receipt shows the line as it was rung up
+		if it.InputUnit != "" { line.Qty, line.Unit, line.UnitPrice = it.InputQty, it.InputUnit, roundMoney((it.Total+it.DiscountAmount)/it.InputQty) }
+		receipt.Lines = append(receipt.Lines, line)
+	}
+
+	m.ReceiptNumber = number
+	var debtID string
+	if schedule != nil {
+		d, err := s.debts.Charge(ctx, m, schedule, actor)
+		if err != nil { return nil, err }
+		debtID = d.ID.Hex()
+	}
+
+	update := bson.M{
+		"items": items,
+		"status": "COMPLETED",
+		"payment_status": m.PaymentStatus,
+		"paid_amount": m.PaidAmount,
+		"change_amount": m.ChangeAmount,
+		"cost_total": roundMoney(costTotal),
+		"gross_profit": roundMoney(m.Total - costTotal),
+		"shift_id": shift.ID.Hex(),
+		"shift_number": shift.Number,
+		"receipt_number": number,
+		"receipt": receipt,
+		"completed_at": now,
+		"completed_by": actor,
+	}
+	if debtID != "" { update["debt_id"] = debtID }
+	if cores > 0 { update["cores_outstanding"] = models.RoundQty(cores) }
+	out, err := s.repo.Update(ctx, m.ID, m.TenantID, update)
+	if err != nil { return nil, utils.Internal("SALE_UPDATE_FAILED", "Unable to complete sale", err) }
+	return out, nil
+}
+
+// take removes sold units from the store, naming the product when it is short. Units other documents reserved are
+// not for sale.
+func (s *SaleService) take(ctx context.Context, p *models.Product, shopID string, qty float64, cost float64, src models.StockSource) (models.StockTaken, error) {
+	free, err := s.stock.Free(ctx, p.TenantID, p.ID, shopID, src)
+	if err != nil { return models.StockTaken{}, err }
+	if qty > free { return models.StockTaken{}, utils.Conflict("INSUFFICIENT_STOCK", "Not enough stock of "+p.Name, nil) }
+	taken, err := s.stock.Take(ctx, p.TenantID, p.ID, shopID, qty, cost, src)
+	if ae, ok := err.(*utils.AppError); ok && ae.Code == "INSUFFICIENT_STOCK" { return taken, utils.Conflict("INSUFFICIENT_STOCK", "Not enough stock of "+p.Name, nil) }
+	return taken, err
+}
+
+// reserve holds a NEW sale's goods in its store: the products and assembled sets sold and the components of the
+// virtual sets sold.
+func (s *SaleService) reserve(ctx context.Context, m *models.Sale, actor models.InventoryUser) error {
+	lines := make([]models.ReservationLine, 0, len(m.Items))
+	for _, it := range m.Items {
+		switch it.ProductType {
+		case models.ProductKindService, models.SaleItemCore:
+		case models.ProductKindSet:
+			p, err := s.product(ctx, it.ProductID, m.TenantID)
+			if err != nil { return err }
+			if !p.VirtualSet() {
+				lines = append(lines, models.ReservationLine{ ProductID: it.ProductID, Qty: it.Qty })
+				continue
+			}
+			for _, si := range p.SetItems { lines = append(lines, models.ReservationLine{ ProductID: si.ProductID, Qty: models.RoundQty(si.Quantity * it.Qty) }) }
+		default:
+			lines = append(lines, models.ReservationLine{ ProductID: it.ProductID, Qty: it.Qty })
+		}
+	}
+	return s.stock.Reserve(ctx, m.TenantID, m.ShopID, saleSource(m, actor), "Sale "+strconv.FormatInt(m.ExternalID, 10), lines)
+}
+
+func saleSource(m *models.Sale, actor models.InventoryUser) models.StockSource {
+	return models.StockSource{ Type: models.StockSourceSale, ID: m.ID.Hex(), Actor: actor }
+}
+
+// buildItems prices the requested lines from the catalog; an explicit unit price overrides the product price, which
+// the line keeps as its catalog price. Core charge lines are derived from the parts, so they are not requested.
+func (s *SaleService) buildItems(ctx context.Context, in []models.SaleItemInput, tenantID string) ([]models.SaleItem, error) {
+	items := make([]models.SaleItem, 0, len(in))
+	for _, it := range in {
+		pid, err := primitive.ObjectIDFromHex(it.ProductID)
+		if err != nil { return nil, utils.BadRequest("INVALID_PRODUCT_ID", "Invalid product id in items", err) }
+		if it.Qty <= 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Quantity must be greater than 0", nil) }
+		if it.DiscountPercent < 0 || it.DiscountPercent > 100 || it.DiscountAmount < 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Invalid discount", nil) }
+		p, err := s.product(ctx, pid, tenantID)
+		if err != nil { return nil, err }
+		q, err := s.stock.inUnit(ctx, p, it.Unit, it.Qty)
+		if err != nil { return nil, err }
+		line := models.SaleItem{ ProductID: p.ID, ProductName: p.Name, ProductSKU: p.SKU, Barcode: p.Barcode, ProductType: ifEmpty(p.ProductType, models.ProductKindProduct), Qty: q.qty, Unit: ifEmpty(p.Unit, "pcs"), InputQty: q.inputQty, InputUnit: q.inputUnit, UnitPrice: p.Price }
+		if strings.TrimSpace(it.VariantID) != "" {
+			vid, err := primitive.ObjectIDFromHex(it.VariantID)
+			if err != nil { return nil, utils.BadRequest("INVALID_VARIANT_ID", "Invalid variant id in items", err) }
+			found := false
+			for _, v := range p.Variants {
+				if v.ID != vid { continue }
+				found = true
+				line.VariantID, line.VariantName = v.ID, v.Name
+				line.ProductSKU = ifEmpty(v.SKU, p.SKU)
+				line.Barcode = ifEmpty(v.Barcode, p.Barcode)
+				if v.Price > 0 { line.UnitPrice = v.Price }
+			}
+			if !found { return nil, utils.BadRequest("VARIANT_NOT_FOUND", "Variant not found for "+p.Name, nil) }
+		}
+		gross := line.UnitPrice * line.Qty
+		// an explicit price is per unit entered; the handler has checked it may be given, and the line keeps the
+		// catalog price it replaces
+		if it.UnitPrice > 0 {
+			line.CatalogPrice = line.UnitPrice
+			line.UnitPrice, gross = q.price(it.UnitPrice), it.UnitPrice*it.Qty
+		}
+		// the serials are checked against the store's units when the sale completes
+		if line.Serials, err = cleanSerials(it.Serials); err != nil { return nil, err }
+		if len(line.Serials) > 0 && !p.SerialTracked { return nil, utils.BadRequest("SERIALS_NOT_TRACKED", p.Name+" is not tracked by serial number", nil) }
+		discount := it.DiscountAmount + gross*it.DiscountPercent/100
+		if discount > gross { discount = gross }
+		line.DiscountPercent = it.DiscountPercent
+		line.DiscountAmount = roundMoney(discount)
+		line.Total = roundMoney(gross - line.DiscountAmount)
+		items = append(items, line)
+		// a core-bearing part carries its refundable core charge on a line of its own, never discounted
+		if p.IsDirtyCore && p.CoreCharge > 0 && line.ProductType != models.ProductKindService {
+			items = append(items, models.SaleItem{ ProductID: p.ID, VariantID: line.VariantID, ProductName: p.Name + " core charge", ProductSKU: line.ProductSKU, ProductType: models.SaleItemCore, Qty: line.Qty, Unit: line.Unit, UnitPrice: p.CoreCharge, Total: roundMoney(p.CoreCharge * line.Qty) })
+		}
+	}
+	return items, nil
+}
+
+func (s *SaleService) product(ctx context.Context, id primitive.ObjectID, tenantID string) (*models.Product, error) {
+	p, err := s.products.Get(ctx, id, tenantID)
+	if err != nil { if p2, e2 := s.products.GetByID(ctx, id); e2 == nil { p = p2 } else { return nil, utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found for sale", err) } }
+	return p, nil
+}
+
+func (s *SaleService) customer(ctx context.Context, id string, tenantID string) (models.SaleCustomer, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil { return models.SaleCustomer{}, utils.BadRequest("INVALID_CUSTOMER_ID", "Invalid customer id", err) }
+	c, err := s.customers.Get(ctx, oid, tenantID)
+	if err != nil { return models.SaleCustomer{}, utils.NotFound("CUSTOMER_NOT_FOUND", "Customer not found", err) }
+	return models.SaleCustomer{ ID: c.ID.Hex(), Name: strings.TrimSpace(c.FirstName + " " + c.LastName), Phone: c.PhoneNumber }, nil
+}
+
+func validPayments(in []models.SalePayment) ([]models.SalePayment, error) {
+	out := make([]models.SalePayment, 0, len(in))
+	for _, p := range in {
+		p.Method = strings.ToLower(strings.TrimSpace(p.Method))
+		switch p.Method {
+		case models.SalePaymentCash, models.SalePaymentCard, models.SalePaymentTransfer:
+		default:
+			return nil, utils.BadRequest("INVALID_PAYMENT_METHOD", "Payment method must be cash, card or transfer", nil)
+		}
+		if p.Amount < 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Payment amount cannot be negative", nil) }
+		if p.Amount == 0 { continue }
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// computeSaleTotals derives the money totals and payment status from the lines and payments.
+func computeSaleTotals(m *models.Sale) {
+	var subtotal, discount, paid float64
+	for _, it := range m.Items {
+		subtotal += it.UnitPrice * it.Qty
+		discount += it.DiscountAmount
+	}
+	for _, p := range m.Payments { paid += p.Amount }
+	m.Subtotal = roundMoney(subtotal)
+	m.DiscountTotal = roundMoney(discount)
+	m.Total = roundMoney(subtotal - discount)
+	m.ChangeAmount = 0
+	if paid > m.Total { m.ChangeAmount = roundMoney(paid - m.Total); paid = m.Total }
+	m.PaidAmount = roundMoney(paid)
+	switch {
+	case m.PaidAmount >= m.Total:
+		m.PaymentStatus = "paid"
+	case m.PaidAmount > 0:
+		m.PaymentStatus = "partially_paid"
+	default:
+		m.PaymentStatus = "unpaid"
+	}
+}
+
+func roundMoney(v float64) float64 { return math.Round(v*100) / 100 }
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SerialService follows the physical units of serial-tracked products. StockService posts every balance change to
+// it; changes made by supplier orders, transfers, sales, returns and write-offs must name one serial per unit of a
+// tracked product, and each serial has to be where the change expects it.
+type SerialService struct {
+	repo     *repositories.SerialNumberRepository
+	products *repositories.ProductRepository
+}
+
+func NewSerialService(repo *repositories.SerialNumberRepository, products *repositories.ProductRepository) *SerialService {
+	return &SerialService{repo: repo, products: products}
+}
+
+// Lookup returns the units carrying a serial, each with its full history.
+func (s *SerialService) Lookup(ctx context.Context, tenantID, serial string) ([]models.SerialNumber, error) {
+	serial = strings.TrimSpace(serial)
+	if serial == "" { return nil, utils.BadRequest("VALIDATION_ERROR", "Serial number is required", nil) }
+	items, err := s.repo.FindBySerial(ctx, tenantID, serial)
+	if err != nil { return nil, utils.Internal("SERIAL_READ_FAILED", "Unable to read serial numbers", err) }
+	if len(items) == 0 { return nil, utils.NotFound("SERIAL_NOT_FOUND", "Serial number not found", nil) }
+	return items, nil
+}
+
+// List returns a product's serial numbers, optionally in one store and state.
+func (s *SerialService) List(ctx context.Context, tenantID, productID, shopID, status string, page, limit int64) ([]models.SerialNumber, int64, error) {
+	pid, err := primitive.ObjectIDFromHex(productID)
+	if err != nil { return nil, 0, utils.BadRequest("INVALID_ID", "Invalid product id", err) }
+	items, total, err := s.repo.List(ctx, repositories.SerialNumberListParams{TenantID: tenantID, ProductID: pid, ShopID: shopID, Status: status, Page: page, Limit: limit})
+	if err != nil { return nil, 0, utils.Internal("SERIAL_LIST_FAILED", "Unable to list serial numbers", err) }
+	return items, total, nil
+}
+
+// serialSources are the documents that must name the serials of the tracked units they move.
+var serialSources = map[string]bool{
+	models.StockSourceSupplierOrder: true,
+	models.StockSourceReturnOrder:   true,
+	models.StockSourceTransfer:      true,
+	models.StockSourceSale:          true,
+	models.StockSourceSaleReturn:    true,
+	models.StockSourceWriteOff:      true,
+	models.StockSourceAssembly:      true,
+	models.StockSourceDisassembly:   true,
+}
+
+// post moves the serials of src in or out of a store along with a change of delta units. It runs inside the stock
+// transaction.
+func (s *SerialService) post(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, delta float64, src models.StockSource) error {
+	if len(src.Serials) == 0 && !serialSources[src.Type] { return nil }
+	p, err := s.products.Get(ctx, productID, tenantID)
+	if err != nil { return utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found", err) }
+	if !p.SerialTracked {
+		if len(src.Serials) > 0 { return utils.BadRequest("SERIALS_NOT_TRACKED", p.Name+" is not tracked by serial number", nil) }
+		return nil
+	}
+	qty := delta
+	if qty < 0 { qty = -qty }
+	if len(src.Serials) == 0 { return utils.BadRequest("SERIALS_REQUIRED", "Serial numbers are required for "+p.Name, nil) }
+	if float64(len(src.Serials)) != qty { return utils.BadRequest("SERIAL_COUNT_MISMATCH", "Give one serial number per unit of "+p.Name, nil) }
+	ev := models.SerialEvent{ ShopID: shopID, SourceType: src.Type, SourceID: src.ID, Party: src.Party, Actor: src.Actor, At: time.Now().UTC() }
+	for _, serial := range src.Serials {
+		var ok bool
+		if delta > 0 {
+			ev.Status = models.SerialInStock
+			switch src.Type {
+			case models.StockSourceSaleReturn:
+				ok, err = s.repo.Enter(ctx, p, serial, []string{models.SerialSold}, false, ev)
+			case models.StockSourceTransfer:
+				ok, err = s.repo.Enter(ctx, p, serial, []string{models.SerialInTransit}, false, ev)
+			case models.StockSourceSupplierOrder:
+				ok, err = s.repo.Enter(ctx, p, serial, []string{models.SerialSold, models.SerialWrittenOff, models.SerialReturned}, true, ev)
+			default:
+				ok, err = s.repo.Enter(ctx, p, serial, []string{models.SerialWrittenOff, models.SerialReturned}, true, ev)
+			}
+		} else {
+			switch src.Type {
+			case models.StockSourceSale: ev.Status = models.SerialSold
+			case models.StockSourceTransfer: ev.Status = models.SerialInTransit
+			case models.StockSourceReturnOrder: ev.Status = models.SerialReturned
+			default: ev.Status = models.SerialWrittenOff
+			}
+			ok, err = s.repo.Leave(ctx, p, serial, shopID, ev)
+		}
+		if err != nil { return utils.Internal("SERIAL_UPDATE_FAILED", "Unable to update serial numbers", err) }
+		if !ok { return utils.Conflict("SERIAL_NOT_AVAILABLE", "Serial number "+serial+" of "+p.Name+" is not available for this operation", nil) }
+	}
+	return nil
+}
+
+// move changes the state of serials that are out of stock, such as units sold and returned defective, or units lost
+// in transit.
+func (s *SerialService) move(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, serials []string, from, to string, src models.StockSource) error {
+	ev := models.SerialEvent{ Status: to, ShopID: shopID, SourceType: src.Type, SourceID: src.ID, Party: src.Party, Actor: src.Actor, At: time.Now().UTC() }
+	for _, serial := range serials {
+		ok, err := s.repo.Move(ctx, tenantID, productID, serial, from, ev)
+		if err != nil { return utils.Internal("SERIAL_UPDATE_FAILED", "Unable to update serial numbers", err) }
+		if !ok { return utils.Conflict("SERIAL_NOT_AVAILABLE", "Serial number "+serial+" is not available for this operation", nil) }
+	}
+	return nil
+}
+
+// cleanSerials trims the given serial numbers and refuses blanks and repeats.
+func cleanSerials(in []string) ([]string, error) {
+	if len(in) == 0 { return nil, nil }
+	out := make([]string, 0, len(in))
+	seen := map[string]bool{}
+	for _, v := range in {
+		v = strings.TrimSpace(v)
+		if v == "" { return nil, utils.BadRequest("VALIDATION_ERROR", "Serial number cannot be empty", nil) }
+		if seen[v] { return nil, utils.BadRequest("SERIAL_DUPLICATE", "Serial number "+v+" is listed twice", nil) }
+		seen[v] = true
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// splitSerials hands the first n serials to one stock change and returns the rest.
+func splitSerials(serials []string, n int) ([]string, []string) {
+	if n >= len(serials) { return serials, nil }
+	return serials[:n], serials[n:]
+}
+
+// hasSerials tells whether every one of want is among have.
+func hasSerials(have, want []string) bool {
+	set := map[string]bool{}
+	for _, v := range have { set[v] = true }
+	for _, v := range want {
+		if !set[v] { return false }
+	}
+	return true
+}
@@ -13,10 +13,11 @@ type ShopServiceService struct {
 	repo      *repositories.ShopServiceRepository
 	customers *repositories.ShopCustomerRepository
 	units     *repositories.ShopUnitRepository
+	stock     *StockService
 }
 
-func NewShopServiceService(repo *repositories.ShopServiceRepository, customers *repositories.ShopCustomerRepository, units *repositories.ShopUnitRepository) *ShopServiceService {
-	return &ShopServiceService{ repo: repo, customers: customers, units: units }
+func NewShopServiceService(repo *repositories.ShopServiceRepository, customers *repositories.ShopCustomerRepository, units *repositories.ShopUnitRepository, stock *StockService) *ShopServiceService {
+	return &ShopServiceService{ repo: repo, customers: customers, units: units, stock: stock }
 }
 
 func (s *ShopServiceService) List(ctx context.Context, page, limit int64, search, tenantID, customerID string) ([]models.ShopServiceDTO, int64, error) {
@@ -82,7 +83,7 @@ func computeTotals(body *models.ShopService) {
 		item.LaborPrice = item.LaborCost // could apply markup later
 		laborTotal += item.LaborPrice
 		for _, p := range item.Parts {
-			partsTotal += float64(p.Quantity) * p.Price
+			partsTotal += p.Quantity * p.Price
 		}
 	}
 	var extrasTotal float64
@@ -141,6 +142,8 @@ func (s *ShopServiceService) Update(ctx context.Context, id string, body models.
 func (s *ShopServiceService) Delete(ctx context.Context, id string, tenantID string) error {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil { return utils.BadRequest("INVALID_ID", "Invalid service id", nil) }
+	// parts reserved for the work order go back to the store
+	if err := s.stock.Release(ctx, tenantID, models.StockSource{ Type: models.ReservationSourceServiceOrder, ID: id }); err != nil { return err }
 	if err := s.repo.Delete(ctx, oid, tenantID); err != nil { return utils.Internal("SHOPSERVICE_DELETE_FAILED", "Unable to delete service", err) }
 	return nil
 } 
\ No newline at end of file
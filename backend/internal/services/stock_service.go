@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StockService owns per-store stock balances and the stock movement ledger. Every change goes through it so that
+// each balance equals the sum of its movements and Product.Stock stays the total of the product's balances.
+// Balances and the product total only move through atomic in-place updates, rounded to models.QtyDecimals so
+// fractional quantities do not drift; a balance change, its ledger entry
+// and the product total commit in one transaction, together with the movement's valuation by the costing engine and
+// its effect on the product's lots, serial numbers, consignment batches and bins.
+type StockService struct {
+	repo      *repositories.StockRepository
+	movements *repositories.StockMovementRepository
+	products  *repositories.ProductRepository
+	costs     *CostingService
+	lots      *LotService
+	serials   *SerialService
+	units     *MeasureUnitService
+	reservations *ReservationService
+	consignments *ConsignmentService
+	bins      *BinService
+	tx        *repositories.Tx
+}
+
+func NewStockService(repo *repositories.StockRepository, movements *repositories.StockMovementRepository, products *repositories.ProductRepository, costs *CostingService, lots *LotService, serials *SerialService, units *MeasureUnitService, reservations *ReservationService, consignments *ConsignmentService, bins *BinService, tx *repositories.Tx) *StockService {
+	return &StockService{repo: repo, movements: movements, products: products, costs: costs, lots: lots, serials: serials, units: units, reservations: reservations, consignments: consignments, bins: bins, tx: tx}
+}
+
+// inUnit converts a document line quantity given in any of the product's units to the base unit stock is kept in.
+func (s *StockService) inUnit(ctx context.Context, p *models.Product, unit string, qty float64) (lineQty, error) {
+	return s.units.convert(ctx, p, unit, qty)
+}
+
+// Atomically runs fn in a transaction. Document approvals wrap their whole read-check-write sequence in it so the
+// status change commits together with the stock effects, or not at all.
+func (s *StockService) Atomically(ctx context.Context, fn func(ctx context.Context) error) error {
+	return runTx(ctx, s.tx, fn)
+}
+
+// runTx runs fn in a transaction and reports driver failures as an internal error.
+func runTx(ctx context.Context, tx *repositories.Tx, fn func(ctx context.Context) error) error {
+	if err := tx.Run(ctx, fn); err != nil {
+		if _, ok := err.(*utils.AppError); ok { return err }
+		return utils.Internal("TRANSACTION_FAILED", "Unable to complete the operation", err)
+	}
+	return nil
+}
+
+// Available returns the quantity of a product on hand in a store.
+func (s *StockService) Available(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string) (float64, error) {
+	if strings.TrimSpace(shopID) == "" { return 0, utils.BadRequest("STORE_REQUIRED", "Store is required", nil) }
+	qty, err := s.repo.Get(ctx, tenantID, productID, shopID)
+	if err != nil { return 0, utils.Internal("STOCK_READ_FAILED", "Unable to read stock balance", err) }
+	return qty, nil
+}
+
+// Free returns what a store can give the document src of a product: on hand less what other pending documents
+// have reserved.
+func (s *StockService) Free(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, src models.StockSource) (float64, error) {
+	if strings.TrimSpace(shopID) == "" { return 0, utils.BadRequest("STORE_REQUIRED", "Store is required", nil) }
+	return s.reservations.free(ctx, tenantID, productID, shopID, src)
+}
+
+// Reserve holds lines in a store for the pending document src, replacing what it held before. It fails with
+// INSUFFICIENT_STOCK when a product is not available to src in full.
+func (s *StockService) Reserve(ctx context.Context, tenantID, shopID string, src models.StockSource, name string, lines []models.ReservationLine) error {
+	return s.reservations.reserve(ctx, tenantID, shopID, src, name, lines)
+}
+
+// Release drops what the document src holds; documents call it when they are approved, rejected or deleted.
+func (s *StockService) Release(ctx context.Context, tenantID string, src models.StockSource) error {
+	return s.reservations.Release(ctx, tenantID, src.Type, src.ID)
+}
+
+// Adjust changes the quantity in a store by delta and records the movement. A decrease never takes the balance
+// below zero; the recorded delta is the change actually applied. unitCost values incoming units; outgoing units are
+// valued by the costing engine.
+func (s *StockService) Adjust(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, delta float64, unitCost float64, src models.StockSource) error {
+	if strings.TrimSpace(shopID) == "" { return utils.BadRequest("STORE_REQUIRED", "Store is required", nil) }
+	if delta == 0 { return nil }
+	return s.Atomically(ctx, func(ctx context.Context) error {
+		prev, qty, err := s.repo.Adjust(ctx, tenantID, productID, shopID, delta)
+		if err != nil { return utils.Internal("PRODUCT_STOCK_UPDATE_FAILED", "Failed to update product stock", err) }
+		if qty == prev { return nil }
+		_, err = s.apply(ctx, tenantID, productID, shopID, qty-prev, qty, unitCost, src)
+		return err
+	})
+}
+
+// Take removes qty from a store only when that much is on hand, failing with INSUFFICIENT_STOCK otherwise. The
+// check and the decrement are one conditional update, so two concurrent takes can never both pass on the same units.
+// It returns the unit cost the costing engine valued the taken units at, unitCost being used only for stock without
+// cost history, and the lots the units came from.
+func (s *StockService) Take(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, qty float64, unitCost float64, src models.StockSource) (models.StockTaken, error) {
+	if strings.TrimSpace(shopID) == "" { return models.StockTaken{}, utils.BadRequest("STORE_REQUIRED", "Store is required", nil) }
+	if qty <= 0 { return models.StockTaken{}, nil }
+	var out models.StockTaken
+	err := s.Atomically(ctx, func(ctx context.Context) error {
+		left, ok, err := s.repo.Take(ctx, tenantID, productID, shopID, qty)
+		if err != nil { return utils.Internal("PRODUCT_STOCK_UPDATE_FAILED", "Failed to update product stock", err) }
+		if !ok { return utils.Conflict("INSUFFICIENT_STOCK", "Not enough stock in the store", nil) }
+		out, err = s.apply(ctx, tenantID, productID, shopID, -qty, left, unitCost, src)
+		return err
+	})
+	return out, err
+}
+
+// Set overwrites the quantity in a store (inventory counts, manual corrections), records the difference as a
+// movement and returns the previous quantity.
+func (s *StockService) Set(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, qty float64, unitCost float64, src models.StockSource) (float64, error) {
+	if strings.TrimSpace(shopID) == "" { return 0, utils.BadRequest("STORE_REQUIRED", "Store is required", nil) }
+	if qty < 0 { return 0, utils.BadRequest("VALIDATION_ERROR", "Stock cannot be negative", nil) }
+	var prev float64
+	err := s.Atomically(ctx, func(ctx context.Context) error {
+		var err error
+		prev, err = s.repo.Set(ctx, tenantID, productID, shopID, qty)
+		if err != nil { return utils.Internal("PRODUCT_STOCK_UPDATE_FAILED", "Failed to update product stock", err) }
+		if qty == prev { return nil }
+		_, err = s.apply(ctx, tenantID, productID, shopID, qty-prev, qty, unitCost, src)
+		return err
+	})
+	return prev, err
+}
+
+// UnitCost is the current cost of one unit of a product in a store, or fallback when it has no cost yet.
+func (s *StockService) UnitCost(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, fallback float64) (float64, error) {
+	return s.costs.UnitCost(ctx, tenantID, productID, shopID, fallback)
+}
+
+// Valuation reports the cost of the stock on hand at a point in time, optionally for one store.
+func (s *StockService) Valuation(ctx context.Context, tenantID, shopID string, at time.Time) (*models.InventoryValuation, error) {
+	return s.costs.Valuation(ctx, tenantID, shopID, at)
+}
+
+// Lots lists a product's lots, optionally in one store and only those with stock left.
+func (s *StockService) Lots(ctx context.Context, tenantID, productID, shopID string, onlyOpen bool, page, limit int64) ([]models.StockLot, int64, error) {
+	return s.lots.List(ctx, tenantID, productID, shopID, onlyOpen, page, limit)
+}
+
+// Lot loads one lot of a product in a store.
+func (s *StockService) Lot(ctx context.Context, tenantID, lotID string, productID primitive.ObjectID, shopID string) (*models.StockLot, error) {
+	return s.lots.Get(ctx, tenantID, lotID, productID, shopID)
+}
+
+// Bin loads one bin of a store.
+func (s *StockService) Bin(ctx context.Context, tenantID, binID, shopID string) (*models.WarehouseLocation, error) {
+	return s.bins.Get(ctx, tenantID, binID, shopID)
+}
+
+// BinQty returns the quantity of a product in a bin.
+func (s *StockService) BinQty(ctx context.Context, tenantID string, binID, productID primitive.ObjectID) (float64, error) {
+	return s.bins.Qty(ctx, tenantID, binID, productID)
+}
+
+// BinContents returns what the bins at or under the given locations of a store hold, in bin path order, and the bins
+// themselves.
+func (s *StockService) BinContents(ctx context.Context, tenantID, shopID string, locationIDs []string) ([]models.WarehouseLocation, []models.BinStock, error) {
+	bins, err := s.bins.Under(ctx, tenantID, shopID, locationIDs)
+	if err != nil { return nil, nil, err }
+	rows, err := s.bins.Contents(ctx, tenantID, shopID, bins)
+	if err != nil { return nil, nil, err }
+	return bins, rows, nil
+}
+
+// Serials lists a product's serial numbers, optionally in one store and state.
+func (s *StockService) Serials(ctx context.Context, tenantID, productID, shopID, status string, page, limit int64) ([]models.SerialNumber, int64, error) {
+	return s.serials.List(ctx, tenantID, productID, shopID, status, page, limit)
+}
+
+// SerialLookup returns the life of a serial number.
+func (s *StockService) SerialLookup(ctx context.Context, tenantID, serial string) ([]models.SerialNumber, error) {
+	return s.serials.Lookup(ctx, tenantID, serial)
+}
+
+// MoveSerials changes the state of serials that are not in stock, without moving any stock.
+func (s *StockService) MoveSerials(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, serials []string, from, to string, src models.StockSource) error {
+	if len(serials) == 0 { return nil }
+	return s.serials.move(ctx, tenantID, productID, shopID, serials, from, to, src)
+}
+
+// ExpiringLots reports the lots with stock left that expire within days, optionally in one store.
+func (s *StockService) ExpiringLots(ctx context.Context, tenantID, shopID string, days int) ([]models.ExpiringLot, error) {
+	return s.lots.Expiring(ctx, tenantID, shopID, days)
+}
+
+// StoreStocks returns the per-store quantities of the given products: on hand, reserved by pending documents and
+// available.
+func (s *StockService) StoreStocks(ctx context.Context, tenantID string, productIDs []primitive.ObjectID) (map[primitive.ObjectID][]models.ProductStoreStock, error) {
+	balances, err := s.repo.ListByProducts(ctx, tenantID, productIDs)
+	if err != nil { return nil, utils.Internal("STOCK_READ_FAILED", "Unable to read stock balances", err) }
+	reserved, err := s.reservations.repo.ByProducts(ctx, tenantID, productIDs)
+	if err != nil { return nil, utils.Internal("RESERVATION_READ_FAILED", "Unable to read reservations", err) }
+	out := make(map[primitive.ObjectID][]models.ProductStoreStock, len(balances))
+	for pid, list := range balances {
+		rows := make([]models.ProductStoreStock, 0, len(list))
+		for _, b := range list {
+			r := reserved[pid][b.ShopID]
+			rows = append(rows, models.ProductStoreStock{ShopID: b.ShopID, Qty: b.Qty, Reserved: r, Available: math.Max(0, models.RoundQty(b.Qty-r))})
+		}
+		out[pid] = rows
+	}
+	return out, nil
+}
+
+// Movements lists a product's ledger entries, newest first.
+func (s *StockService) Movements(ctx context.Context, productID string, f models.StockMovementFilterRequest, tenantID string) ([]models.StockMovement, int64, error) {
+	pid, err := primitive.ObjectIDFromHex(productID)
+	if err != nil { return nil, 0, utils.BadRequest("INVALID_ID", "Invalid product id", err) }
+	var fromPtr, toPtr *time.Time
+	if strings.TrimSpace(f.DateFrom) != "" { if t, err := time.Parse(time.RFC3339, f.DateFrom); err == nil { fromPtr = &t } }
+	if strings.TrimSpace(f.DateTo) != "" { if t, err := time.Parse(time.RFC3339, f.DateTo); err == nil { toPtr = &t } }
+	items, total, err := s.movements.List(ctx, repositories.StockMovementListParams{
+		TenantID: tenantID, ProductID: pid, ShopID: f.ShopID, SourceType: f.SourceType, DateFrom: fromPtr, DateTo: toPtr,
+		Page: int64(ifZero(f.Page, 1)), Limit: int64(ifZero(f.Limit, 20)),
+	})
+	if err != nil { return nil, 0, utils.Internal("STOCK_MOVEMENT_LIST_FAILED", "Unable to list stock movements", err) }
+	return items, total, nil
+}
+
+// AsOf reconstructs a product's per-store stock at the given time by summing its ledger up to that moment.
+func (s *StockService) AsOf(ctx context.Context, productID string, at time.Time, tenantID string) (*models.StockAsOf, error) {
+	pid, err := primitive.ObjectIDFromHex(productID)
+	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid product id", err) }
+	sums, err := s.movements.SumByShop(ctx, tenantID, &pid, &at)
+	if err != nil { return nil, utils.Internal("STOCK_AS_OF_FAILED", "Unable to reconstruct stock", err) }
+	out := &models.StockAsOf{ProductID: productID, Date: at, StoreStocks: []models.ProductStoreStock{}}
+	for _, row := range sums {
+		qty := models.RoundQty(row.Qty)
+		if qty == 0 { continue }
+		out.StoreStocks = append(out.StoreStocks, models.ProductStoreStock{ShopID: row.ShopID, Qty: qty})
+		out.Total = models.RoundQty(out.Total + qty)
+	}
+	return out, nil
+}
+
+// CheckConsistency compares every balance of the tenant with the sum of its movements.
+func (s *StockService) CheckConsistency(ctx context.Context, tenantID string) (*models.StockConsistencyReport, error) {
+	balances, err := s.repo.ListAll(ctx, tenantID)
+	if err != nil { return nil, utils.Internal("STOCK_CONSISTENCY_FAILED", "Unable to check stock consistency", err) }
+	sums, err := s.movements.SumByShop(ctx, tenantID, nil, nil)
+	if err != nil { return nil, utils.Internal("STOCK_CONSISTENCY_FAILED", "Unable to check stock consistency", err) }
+
+	type key struct {
+		product primitive.ObjectID
+		shop    string
+	}
+	ledger := make(map[key]float64, len(sums))
+	for _, row := range sums { ledger[key{row.ProductID, row.ShopID}] = models.RoundQty(row.Qty) }
+
+	report := &models.StockConsistencyReport{Mismatches: []models.StockMismatch{}}
+	for _, b := range balances {
+		k := key{b.ProductID, b.ShopID}
+		report.Checked++
+		if ledger[k] != b.Qty {
+			report.Mismatches = append(report.Mismatches, models.StockMismatch{ProductID: b.ProductID.Hex(), ShopID: b.ShopID, Balance: b.Qty, Ledger: ledger[k]})
+		}
+		delete(ledger, k)
+	}
+	// movements whose balance document is missing
+	for k, qty := range ledger {
+		report.Checked++
+		if qty != 0 {
+			report.Mismatches = append(report.Mismatches, models.StockMismatch{ProductID: k.product.Hex(), ShopID: k.shop, Balance: 0, Ledger: qty})
+		}
+	}
+	return report, nil
+}
+
+// apply values a balance change, moves it through the lots, serial numbers, consignment batches and bins, records it in the ledger and shifts the product total
+// by the same delta. It returns the unit cost of the moved units and the lots they went into or came from.
+func (s *StockService) apply(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, delta, balance float64, unitCost float64, src models.StockSource) (models.StockTaken, error) {
+	c, err := s.costs.post(ctx, tenantID, productID, shopID, delta, balance, unitCost, src)
+	if err != nil { return models.StockTaken{}, err }
+	lots, err := s.lots.post(ctx, tenantID, productID, shopID, delta, balance, src)
+	if err != nil { return models.StockTaken{}, err }
+	if err := s.serials.post(ctx, tenantID, productID, shopID, delta, src); err != nil { return models.StockTaken{}, err }
+	if err := s.consignments.post(ctx, tenantID, productID, shopID, delta, src); err != nil { return models.StockTaken{}, err }
+	if err := s.bins.post(ctx, tenantID, productID, shopID, delta, src); err != nil { return models.StockTaken{}, err }
+	if err := s.record(ctx, tenantID, productID, shopID, delta, balance, c, lots, src); err != nil { return models.StockTaken{}, err }
+	if err := s.products.IncStock(ctx, productID, tenantID, delta); err != nil { return models.StockTaken{}, utils.Internal("PRODUCT_STOCK_UPDATE_FAILED", "Failed to update product stock", err) }
+	return models.StockTaken{UnitCost: c.unit, Lots: lots}, nil
+}
+
+// record appends a ledger entry. When the source carries no actor, the authenticated user of the request is used.
+func (s *StockService) record(ctx context.Context, tenantID string, productID primitive.ObjectID, shopID string, delta, balance float64, c costed, lots []models.StockLotPick, src models.StockSource) error {
+	actor := src.Actor
+	if actor.ID == "" {
+		if u, ok := ctx.Value("user").(*models.User); ok && u != nil { actor = models.InventoryUser{ID: u.ID.Hex(), Name: u.Name} }
+	}
+	m := &models.StockMovement{TenantID: tenantID, ProductID: productID, ShopID: shopID, Delta: delta, Balance: balance, UnitCost: c.unit, CostAmount: c.amount, BalanceValue: c.value, Lots: lots, Serials: src.Serials, SourceType: src.Type, SourceID: src.ID, Actor: actor}
+	if _, err := s.movements.Create(ctx, m); err != nil { return utils.Internal("STOCK_MOVEMENT_RECORD_FAILED", "Failed to record stock movement", err) }
+	return nil
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"shop/backend/internal/models"
+	"shop/backend/internal/repositories"
+	"shop/backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SupplierLedgerService keeps the accounts payable per supplier. Accepted orders, returns and payments are posted
+// by OrderService inside its own transactions, consignment settlements by ConsignmentSettlementService; openings and
+// adjustments are posted by hand.
+type SupplierLedgerService struct {
+	repo      *repositories.SupplierLedgerRepository
+	suppliers *repositories.SupplierRepository
+	tx        *repositories.Tx
+}
+
+func NewSupplierLedgerService(repo *repositories.SupplierLedgerRepository, suppliers *repositories.SupplierRepository, tx *repositories.Tx) *SupplierLedgerService {
+	return &SupplierLedgerService{repo: repo, suppliers: suppliers, tx: tx}
+}
+
+func (s *SupplierLedgerService) Ledger(ctx context.Context, supplierID, shopID string, page, limit int64, tenantID string) ([]models.SupplierLedgerEntry, int64, error) {
+	if _, err := primitive.ObjectIDFromHex(supplierID); err != nil { return nil, 0, utils.BadRequest("INVALID_ID", "Invalid supplier id", err) }
+	items, total, err := s.repo.List(ctx, tenantID, supplierID, shopID, page, limit)
+	if err != nil { return nil, 0, utils.Internal("SUPPLIER_LEDGER_FAILED", "Unable to read supplier ledger", err) }
+	return items, total, nil
+}
+
+// Statement builds the reconciliation statement for a period: the balance brought forward, every posting in the
+// period with a running balance, and the closing balance.
+func (s *SupplierLedgerService) Statement(ctx context.Context, supplierID, shopID string, from, to *time.Time, tenantID string) (*models.SupplierStatement, error) {
+	oid, err := primitive.ObjectIDFromHex(supplierID)
+	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid supplier id", err) }
+	sup, err := s.suppliers.Get(ctx, oid, tenantID)
+	if err != nil { return nil, utils.NotFound("SUPPLIER_NOT_FOUND", "Supplier not found", err) }
+	st := &models.SupplierStatement{ SupplierID: supplierID, SupplierName: sup.Name, SupplierINN: sup.INN, ShopID: shopID, From: from, To: to, Lines: []models.SupplierStatementLine{} }
+	if from != nil {
+		st.OpeningBalance, err = s.repo.SumBefore(ctx, tenantID, supplierID, shopID, *from)
+		if err != nil { return nil, utils.Internal("SUPPLIER_STATEMENT_FAILED", "Unable to build statement", err) }
+	}
+	entries, err := s.repo.Range(ctx, tenantID, supplierID, shopID, from, to)
+	if err != nil { return nil, utils.Internal("SUPPLIER_STATEMENT_FAILED", "Unable to build statement", err) }
+	balance := st.OpeningBalance
+	for _, e := range entries {
+		line := models.SupplierStatementLine{ Date: e.Date, Type: e.Type, Document: e.OrderName, Comment: e.Comment }
+		if e.Amount >= 0 { line.Credit = e.Amount } else { line.Debit = -e.Amount }
+		balance += e.Amount
+		line.Balance = roundMoney(balance)
+		st.TotalDebit += line.Debit
+		st.TotalCredit += line.Credit
+		st.Lines = append(st.Lines, line)
+	}
+	st.OpeningBalance = roundMoney(st.OpeningBalance)
+	st.TotalDebit = roundMoney(st.TotalDebit)
+	st.TotalCredit = roundMoney(st.TotalCredit)
+	st.ClosingBalance = roundMoney(balance)
+	return st, nil
+}
+
+// Opening records the balance owed to a supplier before the ledger was kept; a supplier has at most one.
+func (s *SupplierLedgerService) Opening(ctx context.Context, supplierID string, body models.SupplierLedgerPostRequest, tenantID string, actor models.InventoryUser) (*models.SupplierLedgerEntry, error) {
+	if body.Amount == 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Amount is required", nil) }
+	var out *models.SupplierLedgerEntry
+	err := runTx(ctx, s.tx, func(ctx context.Context) error {
+		exists, err := s.repo.HasEntry(ctx, tenantID, supplierID, models.SupplierEntryOpening)
+		if err != nil { return utils.Internal("SUPPLIER_LEDGER_FAILED", "Unable to read supplier ledger", err) }
+		if exists { return utils.Conflict("SUPPLIER_OPENING_EXISTS", "Supplier already has an opening balance; post an adjustment instead", nil) }
+		out, err = s.manual(ctx, supplierID, models.SupplierEntryOpening, body, tenantID, actor)
+		return err
+	})
+	return out, err
+}
+
+// Adjust posts a manual correction to the supplier's balance; a comment explaining it is required.
+func (s *SupplierLedgerService) Adjust(ctx context.Context, supplierID string, body models.SupplierLedgerPostRequest, tenantID string, actor models.InventoryUser) (*models.SupplierLedgerEntry, error) {
+	if body.Amount == 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Amount is required", nil) }
+	if strings.TrimSpace(body.Comment) == "" { return nil, utils.BadRequest("VALIDATION_ERROR", "Comment is required for adjustments", nil) }
+	var out *models.SupplierLedgerEntry
+	err := runTx(ctx, s.tx, func(ctx context.Context) error {
+		var err error
+		out, err = s.manual(ctx, supplierID, models.SupplierEntryAdjustment, body, tenantID, actor)
+		return err
+	})
+	return out, err
+}
+
+func (s *SupplierLedgerService) manual(ctx context.Context, supplierID, typ string, body models.SupplierLedgerPostRequest, tenantID string, actor models.InventoryUser) (*models.SupplierLedgerEntry, error) {
+	oid, err := primitive.ObjectIDFromHex(supplierID)
+	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid supplier id", err) }
+	if _, err := s.suppliers.Get(ctx, oid, tenantID); err != nil { return nil, utils.NotFound("SUPPLIER_NOT_FOUND", "Supplier not found", err) }
+	e := &models.SupplierLedgerEntry{ TenantID: tenantID, SupplierID: supplierID, ShopID: body.ShopID, Type: typ, Amount: roundMoney(body.Amount), Comment: strings.TrimSpace(body.Comment), CreatedBy: actor }
+	if body.Date != nil { e.Date = body.Date.UTC() }
+	return s.post(ctx, e)
+}
+
+// orderAccepted credits the supplier with an accepted order's goods, or debits it with a return order's.
+func (s *SupplierLedgerService) orderAccepted(ctx context.Context, o *models.Order, items []models.OrderItem, user models.OrderUser) error {
+	e := &models.SupplierLedgerEntry{ TenantID: o.TenantID, SupplierID: o.SupplierID, ShopID: o.ShopID, Type: models.SupplierEntryOrder, OrderID: o.ID.Hex(), OrderName: o.Name, CreatedBy: models.InventoryUser{ ID: user.ID, Name: user.Name } }
+	if strings.ToLower(o.Type) == "return_order" {
+		var amount float64
+		for _, it := range items {
+			qty := it.ReturnedQuantity
+			if qty <= 0 || qty > it.Quantity { qty = it.Quantity }
+			amount += qty * orderLinePrice(it)
+		}
+		e.Type, e.Amount = models.SupplierEntryReturn, -roundMoney(amount)
+	} else {
+		e.Amount = roundMoney(orderAmount(o, items))
+	}
+	if e.Amount == 0 { return nil }
+	_, err := s.post(ctx, e)
+	return err
+}
+
+// orderReceived credits the supplier with one receiving on a supplier order. An order received in a single
+// receiving is posted like orderAccepted, keeping its order-total fallback for lines without prices.
+func (s *SupplierLedgerService) orderReceived(ctx context.Context, o *models.Order, received []models.OrderItem, whole bool, user models.OrderUser) error {
+	if whole { return s.orderAccepted(ctx, o, received, user) }
+	var amount float64
+	for _, it := range received { amount += it.Quantity * orderLinePrice(it) }
+	e := &models.SupplierLedgerEntry{ TenantID: o.TenantID, SupplierID: o.SupplierID, ShopID: o.ShopID, Type: models.SupplierEntryOrder, Amount: roundMoney(amount), OrderID: o.ID.Hex(), OrderName: o.Name, Comment: "Partial receiving", CreatedBy: models.InventoryUser{ ID: user.ID, Name: user.Name } }
+	if e.Amount == 0 { return nil }
+	_, err := s.post(ctx, e)
+	return err
+}
+
+// orderPayment debits the supplier with a payment on an order; a payment on a return order is money the supplier
+// paid back, so it credits the supplier instead.
+func (s *SupplierLedgerService) orderPayment(ctx context.Context, o *models.Order, p models.OrderPayment, user models.OrderUser) error {
+	e := &models.SupplierLedgerEntry{ TenantID: o.TenantID, SupplierID: o.SupplierID, ShopID: o.ShopID, Type: models.SupplierEntryPayment, Amount: -roundMoney(p.Amount), OrderID: o.ID.Hex(), OrderName: o.Name, PaymentID: p.ID.Hex(), Method: p.PaymentMethod, Comment: p.Description, Date: p.PaymentDate, CreatedBy: models.InventoryUser{ ID: user.ID, Name: user.Name } }
+	if strings.ToLower(o.Type) == "return_order" { e.Amount = -e.Amount }
+	_, err := s.post(ctx, e)
+	return err
+}
+
+// consignmentSettled credits the supplier with the consignment goods an approved settlement found sold.
+func (s *SupplierLedgerService) consignmentSettled(ctx context.Context, m *models.ConsignmentSettlement, actor models.InventoryUser) error {
+	e := &models.SupplierLedgerEntry{ TenantID: m.TenantID, SupplierID: m.SupplierID, ShopID: m.ShopID, Type: models.SupplierEntryConsignment, Amount: roundMoney(m.TotalAmount), OrderID: m.ID.Hex(), OrderName: "Consignment settlement " + strconv.FormatInt(m.ExternalID, 10), Comment: m.From.Format("2006-01-02") + " to " + m.To.Format("2006-01-02"), CreatedBy: actor }
+	if e.Amount == 0 { return nil }
+	_, err := s.post(ctx, e)
+	return err
+}
+
+// post moves the supplier's balance and appends the posting. Orders whose supplier is not on file are not posted.
+func (s *SupplierLedgerService) post(ctx context.Context, e *models.SupplierLedgerEntry) (*models.SupplierLedgerEntry, error) {
+	oid, err := primitive.ObjectIDFromHex(e.SupplierID)
+	if err != nil { return nil, nil }
+	balance, err := s.suppliers.IncBalance(ctx, oid, e.TenantID, e.Amount)
+	if err == mongo.ErrNoDocuments { return nil, nil }
+	if err != nil { return nil, utils.Internal("SUPPLIER_LEDGER_POST_FAILED", "Unable to update supplier balance", err) }
+	e.Balance = roundMoney(balance)
+	created, err := s.repo.Create(ctx, e)
+	if err != nil { return nil, utils.Internal("SUPPLIER_LEDGER_POST_FAILED", "Unable to post to supplier ledger", err) }
+	return created, nil
+}
+
+// orderAmount is the supply value of the accepted lines, falling back to the order totals when lines carry no prices.
+func orderAmount(o *models.Order, items []models.OrderItem) float64 {
+	var sum float64
+	for _, it := range items { sum += it.Quantity * orderLinePrice(it) }
+	if sum > 0 { return sum }
+	if o.TotalSupplyPrice > 0 { return o.TotalSupplyPrice }
+	return o.TotalPrice
+}
+
+func orderLinePrice(it models.OrderItem) float64 { if it.SupplyPrice > 0 { return it.SupplyPrice }; return it.UnitPrice }
+
+// WriteSupplierStatementCSV writes the statement as CSV for spreadsheet export.
+func WriteSupplierStatementCSV(w io.Writer, st *models.SupplierStatement) error {
+	cw := csv.NewWriter(w)
+	money := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	date := func(t *time.Time) string { if t == nil { return "" }; return t.Format("2006-01-02") }
+	rows := [][]string{
+		{"Supplier", st.SupplierName, "INN", st.SupplierINN},
+		{"Period", date(st.From), date(st.To)},
+		{"Opening balance", money(st.OpeningBalance)},
+		{},
+		{"Date", "Type", "Document", "Comment", "Debit", "Credit", "Balance"},
+	}
+	for _, l := range st.Lines {
+		rows = append(rows, []string{l.Date.Format("2006-01-02"), l.Type, l.Document, l.Comment, money(l.Debit), money(l.Credit), money(l.Balance)})
+	}
+	rows = append(rows, []string{"Total", "", "", "", money(st.TotalDebit), money(st.TotalCredit), ""}, []string{"Closing balance", money(st.ClosingBalance)})
+	if err := cw.WriteAll(rows); err != nil { return err }
+	return cw.Error()
+}
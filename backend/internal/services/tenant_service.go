@@ -40,8 +40,12 @@ func (s *TenantService) Update(ctx context.Context, id string, t models.Tenant)
 	if t.Phone != "" { update["phone"] = t.Phone }
 	if t.Status != "" { update["status"] = t.Status }
 	if t.Plan != "" { update["plan"] = t.Plan }
+	if m := t.Settings.CostingMethod; m != "" && m != models.CostingAverage && m != models.CostingFIFO {
+		return nil, utils.BadRequest("VALIDATION_ERROR", "Costing method must be average or fifo", nil)
+	}
+	if t.Settings.ReservationTTLHours < 0 { return nil, utils.BadRequest("VALIDATION_ERROR", "Reservation TTL cannot be negative", nil) }
 	// Update settings if any relevant field provided
-	if t.Settings.Language != "" || t.Settings.Timezone != "" || t.Settings.Currency != "" || t.Settings.ExchangeRate != 0 || t.Settings.DateFormat != "" || len(t.Settings.Features) > 0 || len(t.Settings.Integrations) > 0 {
+	if t.Settings.Language != "" || t.Settings.Timezone != "" || t.Settings.Currency != "" || t.Settings.ExchangeRate != 0 || t.Settings.DateFormat != "" || t.Settings.CostingMethod != "" || t.Settings.ReservationTTLHours != 0 || len(t.Settings.Features) > 0 || len(t.Settings.Integrations) > 0 {
 		update["settings"] = t.Settings
 	}
 	return s.repo.Update(ctx, oid, update)
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"math"
 	"math/rand"
 	"strings"
 	"time"
@@ -18,10 +19,13 @@ type TransferService struct {
 	repo    *repositories.TransferRepository
 	stores  *repositories.StoreRepository
 	product *repositories.ProductRepository
+	stock   *StockService
+	writeOffs     *repositories.WriteOffRepository
+	importHistory *repositories.ImportHistoryRepository
 }
 
-func NewTransferService(repo *repositories.TransferRepository, stores *repositories.StoreRepository, product *repositories.ProductRepository) *TransferService {
-	return &TransferService{repo: repo, stores: stores, product: product}
+func NewTransferService(repo *repositories.TransferRepository, stores *repositories.StoreRepository, product *repositories.ProductRepository, stock *StockService, writeOffs *repositories.WriteOffRepository, importHistory *repositories.ImportHistoryRepository) *TransferService {
+	return &TransferService{repo: repo, stores: stores, product: product, stock: stock, writeOffs: writeOffs, importHistory: importHistory}
 }
 
 func (s *TransferService) List(ctx context.Context, f models.TransferFilterRequest, tenantID string) ([]models.Transfer, int64, error) {
@@ -77,57 +81,94 @@ func (s *TransferService) Create(ctx context.Context, body models.CreateTransfer
 	return m, nil
 }
 
+// Update runs in one transaction: sending or receiving moves stock together with the status change.
 func (s *TransferService) Update(ctx context.Context, id string, body models.UpdateTransferRequest, tenantID string, actor models.InventoryUser) (*models.Transfer, error) {
+	var out *models.Transfer
+	err := s.stock.Atomically(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = s.update(ctx, id, body, tenantID, actor)
+		return err
+	})
+	return out, err
+}
+
+func (s *TransferService) update(ctx context.Context, id string, body models.UpdateTransferRequest, tenantID string, actor models.InventoryUser) (*models.Transfer, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid transfer id", err) }
 	update := bson.M{}
 	if strings.TrimSpace(body.Name) != "" { update["name"] = body.Name }
 
-	// update items
+	// update items; only a NEW transfer can be edited, and it holds its lines in the departure store until it is sent
 	if body.Items != nil {
+		cur, err := s.repo.Get(ctx, oid, tenantID)
+		if err != nil { return nil, utils.NotFound("TRANSFER_NOT_FOUND", "Transfer not found", err) }
+		if cur.Status != "NEW" { return nil, utils.BadRequest("TRANSFER_LOCKED", "Only new transfers can be edited", nil) }
+		src := models.StockSource{ Type: models.StockSourceTransfer, ID: cur.ID.Hex(), Actor: actor }
 		items := make([]models.TransferItem, 0, len(body.Items))
+		lines := make([]models.ReservationLine, 0, len(body.Items))
+		used := map[primitive.ObjectID]float64{}
 		var totalQty, totalPrice float64
 		for _, it := range body.Items {
 			pid, err := primitive.ObjectIDFromHex(it.ProductID); if err != nil { return nil, utils.BadRequest("INVALID_PRODUCT_ID", "Invalid product id in items", err) }
 			p, err := s.product.Get(ctx, pid, tenantID)
 			if err != nil { if p2, e2 := s.product.GetByID(ctx, pid); e2 == nil { p = p2 } else { return nil, utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found for transfer", err) } }
-			// clamp qty by available stock
-			qty := it.Qty
-			if int(qty) > p.Stock { qty = float64(p.Stock) }
-			items = append(items, models.TransferItem{ ProductID: pid, ProductName: it.ProductName, ProductSKU: it.ProductSKU, Barcode: it.Barcode, Qty: qty, Unit: it.Unit, SupplyPrice: it.SupplyPrice, RetailPrice: it.RetailPrice })
-			totalQty += qty
-			totalPrice += qty * it.RetailPrice
+			// clamp qty by what the departure store has available: on hand less what other documents reserved
+			avail, err := s.stock.Free(ctx, p.TenantID, p.ID, cur.DepartureShopID, src)
+			if err != nil { return nil, err }
+			avail = math.Max(0, models.RoundQty(avail-used[pid]))
+			// lines are kept in the base unit, prices given per unit entered included
+			q, err := s.stock.inUnit(ctx, p, it.Unit, it.Qty)
+			if err != nil { return nil, err }
+			supply, retail := q.price(it.SupplyPrice), q.price(it.RetailPrice)
+			if q.qty > avail { q.qty, q.inputQty, q.inputUnit = avail, 0, "" }
+			used[pid] = models.RoundQty(used[pid] + q.qty)
+			lines = append(lines, models.ReservationLine{ ProductID: pid, Qty: q.qty })
+			serials, err := cleanSerials(it.Serials)
+			if err != nil { return nil, err }
+			items = append(items, models.TransferItem{ ProductID: pid, ProductName: it.ProductName, ProductSKU: it.ProductSKU, Barcode: it.Barcode, Qty: q.qty, Unit: q.unit, InputQty: q.inputQty, InputUnit: q.inputUnit, SupplyPrice: supply, RetailPrice: retail, Serials: serials })
+			totalQty += q.qty
+			totalPrice += q.qty * retail
 		}
 		update["items"] = items
-		update["total_qty"] = totalQty
+		update["total_qty"] = models.RoundQty(totalQty)
 		update["total_price"] = totalPrice
+		if err := s.stock.Reserve(ctx, tenantID, cur.DepartureShopID, src, ifEmpty(strings.TrimSpace(body.Name), cur.Name), lines); err != nil { return nil, err }
 	}
 
-	// approve / reject
-	if body.Action == "approve" || body.Action == "reject" {
+	// send / receive / approve (send and receive in full) / reject
+	switch body.Action {
+	case "send", "approve":
 		cur, err := s.repo.Get(ctx, oid, tenantID)
 		if err != nil { return nil, utils.NotFound("TRANSFER_NOT_FOUND", "Transfer not found", err) }
 		if cur.Status != "NEW" { return cur, nil }
+		// the status moves before the stock does, so a concurrent send of the same transfer fails instead of sending it twice
+		now := time.Now().UTC()
+		to := "SENT"
+		if body.Action == "approve" { to = "RECEIVED" }
+		if err := s.claim(ctx, cur, "NEW", bson.M{"status": to, "sent_at": now, "sent_by": actor}); err != nil { return nil, err }
+		if err := s.send(ctx, cur, tenantID, actor, update); err != nil { return nil, err }
+		update["status"] = "SENT"
+		update["sent_at"] = now
+		update["sent_by"] = actor
 		if body.Action == "approve" {
-			// reduce stock by departure store availability (global stock field used)
-			for _, it := range cur.Items {
-				p, err := s.product.Get(ctx, it.ProductID, tenantID)
-				if err != nil { if p2, e2 := s.product.GetByID(ctx, it.ProductID); e2 == nil { p = p2 } else { return nil, utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found for transfer", err) } }
-				if int(it.Qty) > p.Stock { return nil, utils.BadRequest("TRANSFER_QTY_EXCEEDS_STOCK", "Transfer qty exceeds current stock", nil) }
-				newStock := p.Stock - int(it.Qty)
-				if newStock < 0 { newStock = 0 }
-				if err := s.product.UpdateStock(ctx, p.ID, p.TenantID, newStock); err != nil { return nil, utils.Internal("PRODUCT_STOCK_UPDATE_FAILED", "Failed to update product stock", err) }
-			}
-			update["status"] = "APPROVED"
-			now := time.Now().UTC()
-			update["finished_at"] = now
-			update["finished_by"] = actor
-		} else {
-			update["status"] = "REJECTED"
-			now := time.Now().UTC()
-			update["finished_at"] = now
-			update["finished_by"] = actor
+			if err := s.receive(ctx, cur, nil, tenantID, actor, update); err != nil { return nil, err }
 		}
+	case "receive":
+		cur, err := s.repo.Get(ctx, oid, tenantID)
+		if err != nil { return nil, utils.NotFound("TRANSFER_NOT_FOUND", "Transfer not found", err) }
+		if cur.Status != "SENT" { return nil, utils.BadRequest("TRANSFER_NOT_SENT", "Only sent transfers can be received", nil) }
+		if err := s.claim(ctx, cur, "SENT", bson.M{"status": "RECEIVED"}); err != nil { return nil, err }
+		if err := s.receive(ctx, cur, body.Received, tenantID, actor, update); err != nil { return nil, err }
+	case "reject":
+		cur, err := s.repo.Get(ctx, oid, tenantID)
+		if err != nil { return nil, utils.NotFound("TRANSFER_NOT_FOUND", "Transfer not found", err) }
+		if cur.Status != "NEW" { return cur, nil }
+		if err := s.claim(ctx, cur, "NEW", bson.M{"status": "REJECTED"}); err != nil { return nil, err }
+		if err := s.stock.Release(ctx, tenantID, models.StockSource{ Type: models.StockSourceTransfer, ID: cur.ID.Hex() }); err != nil { return nil, err }
+		update["status"] = "REJECTED"
+		now := time.Now().UTC()
+		update["finished_at"] = now
+		update["finished_by"] = actor
 	}
 
 	m, err := s.repo.Update(ctx, oid, tenantID, update)
@@ -135,10 +176,185 @@ func (s *TransferService) Update(ctx context.Context, id string, body models.Upd
 	return m, nil
 }
 
+// claim moves the transfer out of the status it was read in; it fails when another request moved it first.
+func (s *TransferService) claim(ctx context.Context, cur *models.Transfer, from string, set bson.M) error {
+	ok, err := s.repo.UpdateIf(ctx, cur.ID, cur.TenantID, bson.M{"status": from}, set)
+	if err != nil { return utils.Internal("TRANSFER_UPDATE_FAILED", "Unable to update transfer", err) }
+	if !ok { return utils.Conflict("TRANSFER_CHANGED", "The transfer was changed by another request; reload it and try again", nil) }
+	return nil
+}
+
 func (s *TransferService) Delete(ctx context.Context, id string, tenantID string) error {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil { return utils.BadRequest("INVALID_ID", "Invalid transfer id", err) }
-	if err := s.repo.Delete(ctx, oid, tenantID); err != nil { return utils.Internal("TRANSFER_DELETE_FAILED", "Unable to delete transfer", err) }
+	cur, err := s.repo.Get(ctx, oid, tenantID)
+	if err != nil { return utils.NotFound("TRANSFER_NOT_FOUND", "Transfer not found", err) }
+	if cur.Status != "NEW" && cur.Status != "REJECTED" { return utils.BadRequest("TRANSFER_LOCKED", "Sent transfers cannot be deleted", nil) }
+	return s.stock.Atomically(ctx, func(ctx context.Context) error {
+		if err := s.stock.Release(ctx, tenantID, models.StockSource{ Type: models.StockSourceTransfer, ID: cur.ID.Hex() }); err != nil { return err }
+		if err := s.repo.Delete(ctx, oid, tenantID); err != nil { return utils.Internal("TRANSFER_DELETE_FAILED", "Unable to delete transfer", err) }
+		return nil
+	})
+}
+
+// send takes every line out of the departure store; the goods stay in transit until the transfer is received. Each
+// line keeps the cost it left at and the lots it was picked from, so the arrival store receives it at the same cost
+// into the same lots. Units other documents reserved are not sent, and the transfer's own reservation is released.
+func (s *TransferService) send(ctx context.Context, cur *models.Transfer, tenantID string, actor models.InventoryUser, update bson.M) error {
+	for i, it := range cur.Items {
+		src := models.StockSource{ Type: models.StockSourceTransfer, ID: cur.ID.Hex(), Actor: actor, Serials: it.Serials }
+		p, err := s.product.Get(ctx, it.ProductID, tenantID)
+		if err != nil { if p2, e2 := s.product.GetByID(ctx, it.ProductID); e2 == nil { p = p2 } else { return utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found for transfer", err) } }
+		free, err := s.stock.Free(ctx, p.TenantID, p.ID, cur.DepartureShopID, src)
+		if err != nil { return err }
+		if it.Qty > free { return utils.BadRequest("TRANSFER_QTY_EXCEEDS_STOCK", "Transfer qty exceeds the available stock of "+p.Name, nil) }
+		taken, err := s.stock.Take(ctx, p.TenantID, p.ID, cur.DepartureShopID, it.Qty, unitCost(it.SupplyPrice, p), src)
+		if err != nil {
+			if ae, ok := err.(*utils.AppError); ok && ae.Code == "INSUFFICIENT_STOCK" { return utils.BadRequest("TRANSFER_QTY_EXCEEDS_STOCK", "Transfer qty exceeds current stock", nil) }
+			return err
+		}
+		cur.Items[i].UnitCost, cur.Items[i].Lots = taken.UnitCost, taken.Lots
+	}
+	if err := s.stock.Release(ctx, tenantID, models.StockSource{ Type: models.StockSourceTransfer, ID: cur.ID.Hex() }); err != nil { return err }
+	update["items"] = cur.Items
+	return nil
+}
+
+// arrive credits qty units to the arrival store, lot by lot as they were picked at departure; units beyond the
+// picks arrive without a lot. Each arrival lot keeps the number, expiry and order of its departure lot. The serials
+// of src are handed out over the lots in order.
+func (s *TransferService) arrive(ctx context.Context, cur *models.Transfer, p *models.Product, picks []models.StockLotPick, qty float64, cost float64, src models.StockSource) error {
+	serials := src.Serials
+	for _, pk := range picks {
+		if qty <= 0 { break }
+		n := pk.Qty
+		if n > qty { n = qty }
+		ref := &models.StockLotRef{ Number: pk.Number, ExpirationDate: pk.ExpirationDate }
+		if lot, err := s.stock.Lot(ctx, p.TenantID, pk.LotID.Hex(), p.ID, cur.DepartureShopID); err == nil {
+			ref.OrderID, ref.OrderName, ref.SupplierID = lot.OrderID, lot.OrderName, lot.SupplierID
+		}
+		lsrc := src
+		lsrc.Lot = ref
+		lsrc.Serials, serials = splitSerials(serials, int(n))
+		if err := s.stock.Adjust(ctx, p.TenantID, p.ID, cur.ArrivalShopID, n, cost, lsrc); err != nil { return err }
+		qty = models.RoundQty(qty - n)
+	}
+	if qty <= 0 { return nil }
+	src.Serials = serials
+	return s.stock.Adjust(ctx, p.TenantID, p.ID, cur.ArrivalShopID, qty, cost, src)
+}
+
+// receive credits the arrival store with the received quantities and fills update with the RECEIVED state.
+// received lists counted quantities by product; lines not listed are received in full. A shortage is recorded as an
+// approved write-off and a surplus as an import record; neither moves stock again, the ledger already holds the
+// quantities actually sent and received.
+func (s *TransferService) receive(ctx context.Context, cur *models.Transfer, received []models.TransferReceivedInput, tenantID string, actor models.InventoryUser, update bson.M) error {
+	counted := map[primitive.ObjectID]float64{}
+	arrived := map[primitive.ObjectID][]string{}
+	for _, r := range received {
+		pid, err := primitive.ObjectIDFromHex(r.ProductID)
+		if err != nil { return utils.BadRequest("INVALID_PRODUCT_ID", "Invalid product id in received", err) }
+		if r.Qty < 0 { return utils.BadRequest("VALIDATION_ERROR", "Received quantity cannot be negative", nil) }
+		p, err := s.product.Get(ctx, pid, tenantID)
+		if err != nil { return utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found for transfer", err) }
+		q, err := s.stock.inUnit(ctx, p, r.Unit, r.Qty)
+		if err != nil { return err }
+		counted[pid] = q.qty
+		if r.Serials != nil {
+			serials, err := cleanSerials(r.Serials)
+			if err != nil { return err }
+			arrived[pid] = serials
+			counted[pid] = float64(len(serials))
+		}
+	}
+
+	src := models.StockSource{ Type: models.StockSourceTransfer, ID: cur.ID.Hex(), Actor: actor }
+	items := make([]models.TransferItem, 0, len(cur.Items))
+	shortage := []models.WriteOffItem{}
+	surplus := []models.ImportHistoryItemInput{}
+	var totalReceived float64
+	// a product on several lines is counted once: the count fills its lines in order up to what each sent, and
+	// the last line takes whatever is left, so an excess shows as that line's surplus
+	last := map[primitive.ObjectID]int{}
+	for i, it := range cur.Items { last[it.ProductID] = i }
+	for i, it := range cur.Items {
+		got := it.Qty
+		isLast := last[it.ProductID] == i
+		q, isCounted := counted[it.ProductID]
+		if isCounted {
+			got = q
+			if !isLast { got = math.Min(q, it.Qty) }
+		}
+		serials, listed := arrived[it.ProductID]
+		// serial-tracked units arrive by serial: only units that were sent, and all of them unless listed
+		if len(it.Serials) > 0 {
+			if !listed {
+				if got != float64(len(it.Serials)) { return utils.BadRequest("SERIALS_REQUIRED", "List the serial numbers that arrived of "+it.ProductName, nil) }
+				serials = it.Serials
+			} else {
+				// each line receives the listed serials it sent
+				mine, rest := []string{}, []string{}
+				for _, v := range serials {
+					if hasSerials(it.Serials, []string{v}) { mine = append(mine, v) } else { rest = append(rest, v) }
+				}
+				serials, arrived[it.ProductID] = mine, rest
+				got = float64(len(mine))
+			}
+		} else {
+			serials = nil
+		}
+		if isCounted { counted[it.ProductID] = models.RoundQty(q - got) }
+		if isLast && len(arrived[it.ProductID]) > 0 { return utils.BadRequest("SERIAL_NOT_SENT", "Received serial numbers were not sent on this transfer for "+it.ProductName, nil) }
+		p, err := s.product.Get(ctx, it.ProductID, tenantID)
+		if err != nil { if p2, e2 := s.product.GetByID(ctx, it.ProductID); e2 == nil { p = p2 } else { return utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found for transfer", err) } }
+		cost := it.UnitCost
+		if cost <= 0 { cost = unitCost(it.SupplyPrice, p) }
+		lsrc := src
+		lsrc.Serials = serials
+		if err := s.arrive(ctx, cur, p, it.Lots, got, cost, lsrc); err != nil { return err }
+		// units that did not arrive are lost in transit
+		lost := []string{}
+		for _, v := range it.Serials {
+			if !hasSerials(serials, []string{v}) { lost = append(lost, v) }
+		}
+		if err := s.stock.MoveSerials(ctx, p.TenantID, p.ID, cur.DepartureShopID, lost, models.SerialInTransit, models.SerialWrittenOff, src); err != nil { return err }
+		it.ReceivedSerials = serials
+
+		it.ReceivedQty = got
+		it.Discrepancy = models.RoundQty(got - it.Qty)
+		items = append(items, it)
+		totalReceived += got
+		if it.Discrepancy < 0 {
+			shortage = append(shortage, models.WriteOffItem{ ProductID: it.ProductID, ProductName: it.ProductName, ProductSKU: it.ProductSKU, Barcode: it.Barcode, Qty: -it.Discrepancy, Unit: ifEmpty(it.Unit, "pcs"), SupplyPrice: it.SupplyPrice, RetailPrice: it.RetailPrice, UnitCost: it.UnitCost, CostTotal: roundMoney(-it.Discrepancy * it.UnitCost), Serials: lost })
+		} else if it.Discrepancy > 0 {
+			surplus = append(surplus, models.ImportHistoryItemInput{ ProductID: it.ProductID.Hex(), ProductName: it.ProductName, ProductSKU: it.ProductSKU, Barcode: it.Barcode, Qty: it.Discrepancy, Unit: it.Unit })
+		}
+	}
+
+	now := time.Now().UTC()
+	if len(shortage) > 0 && s.writeOffs != nil {
+		wo := &models.WriteOff{ TenantID: tenantID, ExternalID: generateExternalID(), Name: "Transfer " + cur.Name + " shortage", ShopID: cur.DepartureShopID, ShopName: cur.DepartureShopName, ReasonName: "transfer_shortage", Status: "APPROVED", CreatedBy: actor, FinishedBy: actor, FinishedAt: &now, Items: shortage }
+		for _, it := range shortage {
+			wo.TotalQty += it.Qty
+			wo.TotalSupplyPrice += it.Qty * it.SupplyPrice
+			wo.TotalRetailPrice += it.Qty * it.RetailPrice
+			wo.TotalCost += it.CostTotal
+		}
+		created, err := s.writeOffs.Create(ctx, wo)
+		if err != nil { return utils.Internal("WRITEOFF_CREATE_FAILED", "Unable to record transfer shortage", err) }
+		update["shortage_writeoff_id"] = created.ID.Hex()
+	}
+	if len(surplus) > 0 && s.importHistory != nil {
+		created, err := NewImportHistoryService(s.importHistory).Create(ctx, tenantID, actor.ID, models.CreateImportHistoryRequest{ FileName: "Transfer " + cur.Name + " surplus", StoreID: cur.ArrivalShopID, StoreName: cur.ArrivalShopName, TotalRows: len(surplus), SuccessRows: len(surplus), Status: "completed", ImportType: "TRANSFER_SURPLUS", Items: surplus })
+		if err != nil { return err }
+		update["surplus_import_id"] = created.ID
+	}
+
+	update["items"] = items
+	update["total_received_qty"] = totalReceived
+	update["status"] = "RECEIVED"
+	update["finished_at"] = now
+	update["finished_by"] = actor
 	return nil
 }
 
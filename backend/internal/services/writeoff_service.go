@@ -17,10 +17,11 @@ type WriteOffService struct {
 	repo   *repositories.WriteOffRepository
 	store  *repositories.StoreRepository
 	product *repositories.ProductRepository
+	stock  *StockService
 }
 
-func NewWriteOffService(repo *repositories.WriteOffRepository, store *repositories.StoreRepository, product *repositories.ProductRepository) *WriteOffService {
-	return &WriteOffService{repo: repo, store: store, product: product}
+func NewWriteOffService(repo *repositories.WriteOffRepository, store *repositories.StoreRepository, product *repositories.ProductRepository, stock *StockService) *WriteOffService {
+	return &WriteOffService{repo: repo, store: store, product: product, stock: stock}
 }
 
 func (s *WriteOffService) List(ctx context.Context, f models.WriteOffFilterRequest, tenantID string) ([]models.WriteOff, int64, error) {
@@ -64,7 +65,18 @@ func (s *WriteOffService) Create(ctx context.Context, body models.CreateWriteOff
 	return m, nil
 }
 
+// Update runs in one transaction: an approval's stock decrements commit together with the APPROVED status.
 func (s *WriteOffService) Update(ctx context.Context, id string, body models.UpdateWriteOffRequest, tenantID string, actor models.InventoryUser) (*models.WriteOff, error) {
+	var out *models.WriteOff
+	err := s.stock.Atomically(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = s.update(ctx, id, body, tenantID, actor)
+		return err
+	})
+	return out, err
+}
+
+func (s *WriteOffService) update(ctx context.Context, id string, body models.UpdateWriteOffRequest, tenantID string, actor models.InventoryUser) (*models.WriteOff, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil { return nil, utils.BadRequest("INVALID_ID", "Invalid write-off id", err) }
 	update := bson.M{}
@@ -72,25 +84,43 @@ func (s *WriteOffService) Update(ctx context.Context, id string, body models.Upd
 
 	// handle items update and recalc totals
 	if body.Items != nil {
+		cur, err := s.repo.Get(ctx, oid, tenantID)
+		if err != nil { return nil, utils.NotFound("WRITEOFF_NOT_FOUND", "Write-off not found", err) }
 		items := make([]models.WriteOffItem, 0, len(body.Items))
 		var totalQty, totalSupply, totalRetail float64
 		for _, it := range body.Items {
 			if it.Qty <= 0 { continue }
 			pid, err := primitive.ObjectIDFromHex(it.ProductID); if err != nil { return nil, utils.BadRequest("INVALID_PRODUCT_ID", "Invalid product id in items", err) }
-			// validate against current stock (fallback without tenant if needed)
+			// validate against the shop's current stock (fallback without tenant if needed)
 			p, err := s.product.Get(ctx, pid, tenantID)
 			if err != nil {
 				if p2, e2 := s.product.GetByID(ctx, pid); e2 == nil { p = p2 } else { return nil, utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found for write-off", err) }
 			}
-			if int(it.Qty) > p.Stock { return nil, utils.BadRequest("WRITEOFF_QTY_EXCEEDS_STOCK", "Write-off quantity exceeds current stock", nil) }
-			unit := it.Unit; if unit == "" { unit = "pcs" }
-			items = append(items, models.WriteOffItem{ ProductID: pid, ProductName: it.ProductName, ProductSKU: it.ProductSKU, Barcode: it.Barcode, Qty: it.Qty, Unit: unit, SupplyPrice: it.SupplyPrice, RetailPrice: it.RetailPrice })
-			totalQty += it.Qty
-			totalSupply += it.Qty * it.SupplyPrice
-			totalRetail += it.Qty * it.RetailPrice
+			// lines are kept in the base unit, prices given per unit entered included
+			q, err := s.stock.inUnit(ctx, p, it.Unit, it.Qty)
+			if err != nil { return nil, err }
+			avail, err := s.stock.Available(ctx, p.TenantID, p.ID, cur.ShopID)
+			if err != nil { return nil, err }
+			// a line naming a lot is checked against what that lot holds
+			var lotNumber string
+			if it.LotID != "" {
+				lot, err := s.stock.Lot(ctx, p.TenantID, it.LotID, p.ID, cur.ShopID)
+				if err != nil { return nil, err }
+				avail, lotNumber = lot.Qty, lot.Number
+			}
+			if q.qty > avail { return nil, utils.BadRequest("WRITEOFF_QTY_EXCEEDS_STOCK", "Write-off quantity exceeds current stock", nil) }
+			// the serials of a tracked product are checked against the store's units on approval
+			serials, err := cleanSerials(it.Serials)
+			if err != nil { return nil, err }
+			unit := q.unit; if unit == "" { unit = "pcs" }
+			supply, retail := q.price(it.SupplyPrice), q.price(it.RetailPrice)
+			items = append(items, models.WriteOffItem{ ProductID: pid, ProductName: it.ProductName, ProductSKU: it.ProductSKU, Barcode: it.Barcode, Qty: q.qty, Unit: unit, InputQty: q.inputQty, InputUnit: q.inputUnit, SupplyPrice: supply, RetailPrice: retail, LotID: it.LotID, LotNumber: lotNumber, Serials: serials })
+			totalQty += q.qty
+			totalSupply += q.qty * supply
+			totalRetail += q.qty * retail
 		}
 		update["items"] = items
-		update["total_qty"] = totalQty
+		update["total_qty"] = models.RoundQty(totalQty)
 		update["total_supply_price"] = totalSupply
 		update["total_retail_price"] = totalRetail
 	}
@@ -101,26 +131,36 @@ func (s *WriteOffService) Update(ctx context.Context, id string, body models.Upd
 		cur, err := s.repo.Get(ctx, oid, tenantID)
 		if err != nil { return nil, utils.NotFound("WRITEOFF_NOT_FOUND", "Write-off not found", err) }
 		if body.Action == "approve" && cur.Status == "NEW" {
-			// decrement stock per item
-			for _, it := range cur.Items {
+			// the status moves first, so that of two concurrent approvals only one takes the stock
+			now := time.Now().UTC()
+			if err := s.claim(ctx, oid, tenantID, bson.M{"status": "APPROVED", "finished_at": now, "finished_by": actor}); err != nil { return nil, err }
+			// decrement the shop's stock per item, valued at the cost the costing engine takes it out at
+			items := append([]models.WriteOffItem(nil), cur.Items...)
+			var totalCost float64
+			for i, it := range cur.Items {
 				if it.Qty <= 0 { continue }
 				p, err := s.product.Get(ctx, it.ProductID, tenantID)
 				if err != nil { if p2, e2 := s.product.GetByID(ctx, it.ProductID); e2 == nil { p = p2 } else { return nil, utils.BadRequest("PRODUCT_NOT_FOUND", "Product not found for write-off", err) } }
-				if int(it.Qty) > p.Stock { return nil, utils.BadRequest("WRITEOFF_QTY_EXCEEDS_STOCK", "Write-off quantity exceeds current stock", nil) }
-				newStock := p.Stock - int(it.Qty)
-				if newStock < 0 { newStock = 0 }
-				// use product's own tenant id to ensure update filter matches
-				if err := s.product.UpdateStock(ctx, p.ID, p.TenantID, newStock); err != nil { return nil, utils.Internal("PRODUCT_STOCK_UPDATE_FAILED", "Failed to update product stock", err) }
+				// use product's own tenant id to ensure the balance matches; Take fails when the shop has less on hand
+				src := models.StockSource{ Type: models.StockSourceWriteOff, ID: cur.ID.Hex(), Actor: actor, Serials: it.Serials }
+				if it.LotID != "" {
+					lotID, err := primitive.ObjectIDFromHex(it.LotID)
+					if err != nil { return nil, utils.BadRequest("INVALID_LOT_ID", "Invalid lot id in items", err) }
+					src.Lot = &models.StockLotRef{ ID: lotID }
+				}
+				taken, err := s.stock.Take(ctx, p.TenantID, p.ID, cur.ShopID, it.Qty, unitCost(it.SupplyPrice, p), src)
+				if err != nil {
+					if ae, ok := err.(*utils.AppError); ok && ae.Code == "INSUFFICIENT_STOCK" { return nil, utils.BadRequest("WRITEOFF_QTY_EXCEEDS_STOCK", "Write-off quantity exceeds current stock", nil) }
+					return nil, err
+				}
+				items[i].UnitCost = taken.UnitCost
+				items[i].CostTotal = roundMoney(taken.UnitCost * it.Qty)
+				totalCost += items[i].CostTotal
 			}
-			update["status"] = "APPROVED"
-			now := time.Now().UTC()
-			update["finished_at"] = now
-			update["finished_by"] = actor
+			if body.Items == nil { update["items"] = items }
+			update["total_cost"] = roundMoney(totalCost)
 		} else if body.Action == "reject" && cur.Status == "NEW" {
-			update["status"] = "REJECTED"
-			now := time.Now().UTC()
-			update["finished_at"] = now
-			update["finished_by"] = actor
+			if err := s.claim(ctx, oid, tenantID, bson.M{"status": "REJECTED", "finished_at": time.Now().UTC(), "finished_by": actor}); err != nil { return nil, err }
 		}
 	}
 
@@ -129,6 +169,14 @@ func (s *WriteOffService) Update(ctx context.Context, id string, body models.Upd
 	return m, nil
 }
 
+// claim finishes a NEW write-off with the given status fields; it fails when another request finished it first.
+func (s *WriteOffService) claim(ctx context.Context, id primitive.ObjectID, tenantID string, set bson.M) error {
+	ok, err := s.repo.UpdateIf(ctx, id, tenantID, bson.M{"status": "NEW"}, set)
+	if err != nil { return utils.Internal("WRITEOFF_UPDATE_FAILED", "Unable to update write-off", err) }
+	if !ok { return utils.Conflict("WRITEOFF_FINISHED", "The write-off was already approved or rejected", nil) }
+	return nil
+}
+
 func (s *WriteOffService) Delete(ctx context.Context, id string, tenantID string) error {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil { return utils.BadRequest("INVALID_ID", "Invalid write-off id", err) }
@@ -11,6 +11,9 @@ type AppError struct {
 
 func (e *AppError) Error() string { return e.Message }
 
+// Unwrap exposes the cause, so driver error labels (e.g. transient transaction errors) survive wrapping.
+func (e *AppError) Unwrap() error { return e.Err }
+
 func NewAppError(code, msg string, status int, err error) *AppError { return &AppError{Code: code, Message: msg, Status: status, Err: err} }
 func BadRequest(code, msg string, err error) *AppError   { return NewAppError(code, msg, http.StatusBadRequest, err) }
 func Unauthorized(code, msg string, err error) *AppError { return NewAppError(code, msg, http.StatusUnauthorized, err) }
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// AccessClaims are the claims carried by a signed access token.
+type AccessClaims struct {
+	UserID   string `json:"uid"`
+	RoleID   string `json:"rid"`
+	TenantID string `json:"tid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func SignAccessToken(secret string, claims AccessClaims, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now().UTC()
+	exp := now.Add(ttl)
+	claims.Subject = claims.UserID
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(exp)
+	tok, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	return tok, exp, err
+}
+
+func ParseAccessToken(secret, tok string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	parsed, err := jwt.ParseWithClaims(tok, claims, func(t *jwt.Token) (interface{}, error) { return []byte(secret), nil }, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil { return nil, err }
+	if !parsed.Valid || claims.UserID == "" { return nil, errors.New("invalid token") }
+	return claims, nil
+}
+
+// NewOpaqueToken returns a random token and the sha256 hash that is stored server-side.
+func NewOpaqueToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil { return "", "", err }
+	tok := hex.EncodeToString(b)
+	return tok, HashOpaqueToken(tok), nil
+}
+
+func HashOpaqueToken(tok string) string {
+	sum := sha256.Sum256([]byte(tok))
+	return hex.EncodeToString(sum[:])
+}